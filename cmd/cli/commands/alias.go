@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
+	dmrm "github.com/docker/model-runner/pkg/inference/models"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newAliasCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage model aliases",
+		Long: "Manage model aliases. An alias is a stable name for a model, accepted wherever a model is, " +
+			"including the model field of OpenAI requests, so that the model behind it can be swapped " +
+			"without changing the applications that use it.",
+	}
+	c.AddCommand(newAliasSetCmd(), newAliasListCmd(), newAliasRemoveCmd())
+	return c
+}
+
+func newAliasSetCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "set ALIAS MODEL",
+		Short: "Point an alias at a model",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf(
+					"'docker model alias set' requires 2 arguments.\n\n" +
+						"Usage:  docker model alias set ALIAS MODEL\n\n" +
+						"See 'docker model alias set --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			alias, err := desktopClient.SetAlias(dmrm.NormalizeModelName(args[0]), dmrm.NormalizeModelName(args[1]))
+			if err != nil {
+				return handleClientError(err, "Failed to set alias")
+			}
+			cmd.Printf("Alias %q now points to %q\n", stripDefaultsFromModelName(alias.Alias), stripDefaultsFromModelName(alias.Model))
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	return c
+}
+
+func newAliasListCmd() *cobra.Command {
+	var format string
+	c := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List model aliases",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			aliases, err := desktopClient.Aliases()
+			if err != nil {
+				return handleClientError(err, "Failed to list aliases")
+			}
+			if format != "" {
+				output, err := formatter.Format(format, aliases)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
+			cmd.Print(aliasTable(aliases))
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	addFormatFlag(c, &format)
+	return c
+}
+
+func newAliasRemoveCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:     "rm ALIAS [ALIAS...]",
+		Aliases: []string{"remove"},
+		Short:   "Remove model aliases",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf(
+					"'docker model alias rm' requires at least 1 argument.\n\n" +
+						"Usage:  docker model alias rm ALIAS [ALIAS...]\n\n" +
+						"See 'docker model alias rm --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			var errs []error
+			for _, alias := range args {
+				if err := desktopClient.RemoveAlias(dmrm.NormalizeModelName(alias)); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove alias %q: %w", alias, err))
+					continue
+				}
+				cmd.Printf("Removed alias %q\n", alias)
+			}
+			return errors.Join(errs...)
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	return c
+}
+
+// aliasTable formats the aliases as a table.
+func aliasTable(aliases []dmrm.ModelAlias) string {
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+
+	table.SetHeader([]string{"ALIAS", "MODEL"})
+
+	table.SetBorder(false)
+	table.SetColumnSeparator("")
+	table.SetHeaderLine(false)
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, alias := range aliases {
+		table.Append([]string{stripDefaultsFromModelName(alias.Alias), stripDefaultsFromModelName(alias.Model)})
+	}
+
+	table.Render()
+	return buf.String()
+}
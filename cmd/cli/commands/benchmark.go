@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newBenchmarkCmd() *cobra.Command {
+	var request scheduling.BenchmarkRequest
+	var jsonFormat bool
+	c := &cobra.Command{
+		Use:   "benchmark [OPTIONS] MODEL [MODEL...]",
+		Short: "Measure the prompt processing and generation speed of models on this system",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf(
+					"'docker model benchmark' requires at least 1 argument.\n\n" +
+						"Usage:  docker model benchmark [OPTIONS] MODEL [MODEL...]\n\n" +
+						"See 'docker model benchmark --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer cancel()
+
+			var results []scheduling.BenchmarkResult
+			for _, arg := range args {
+				model := models.NormalizeModelName(arg)
+				if !jsonFormat {
+					cmd.PrintErrf("Benchmarking %s...\n", model)
+				}
+				result, err := desktopClient.Benchmark(ctx, model, request)
+				if err != nil {
+					return handleClientError(err, "Failed to benchmark "+model)
+				}
+				results = append(results, result)
+			}
+			if jsonFormat {
+				output, err := formatter.ToStandardJSON(results)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
+			cmd.Print(benchmarkTable(results))
+			return nil
+		},
+		ValidArgsFunction: completion.ModelNames(getDesktopClient, -1),
+	}
+	c.Flags().StringVar(&request.Backend, "backend", "", "Backend to load the models with (defaults to the default backend)")
+	c.Flags().IntVarP(&request.PromptTokens, "prompt-tokens", "p", 512, "Length of the prompt of the prompt processing test")
+	c.Flags().IntVarP(&request.GenerationTokens, "gen-tokens", "n", 128, "Number of tokens generated by the generation test")
+	c.Flags().IntVarP(&request.Repetitions, "repetitions", "r", 3, "Number of runs of each test")
+	c.Flags().BoolVar(&jsonFormat, "json", false, "Print the benchmark results as JSON")
+	return c
+}
+
+// benchmarkTable formats benchmark results as a table with a row per test, in
+// the style of llama-bench.
+func benchmarkTable(results []scheduling.BenchmarkResult) string {
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+
+	table.SetHeader([]string{"MODEL", "BACKEND", "TEST", "TOKENS/S", "TTFT", "RAM", "VRAM"})
+
+	table.SetBorder(false)
+	table.SetColumnSeparator("")
+	table.SetHeaderLine(false)
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+
+	table.SetColumnAlignment([]int{
+		tablewriter.ALIGN_LEFT,  // MODEL
+		tablewriter.ALIGN_LEFT,  // BACKEND
+		tablewriter.ALIGN_LEFT,  // TEST
+		tablewriter.ALIGN_RIGHT, // TOKENS/S
+		tablewriter.ALIGN_RIGHT, // TTFT
+		tablewriter.ALIGN_LEFT,  // RAM
+		tablewriter.ALIGN_LEFT,  // VRAM
+	})
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, result := range results {
+		for i, test := range result.Tests {
+			// The time to first token is measured by the prompt processing
+			// test, which comes first.
+			ttft := "-"
+			if i == 0 {
+				ttft = fmt.Sprintf("%.0f ms", result.TTFTMilliseconds)
+			}
+			table.Append([]string{
+				stripDefaultsFromModelName(result.Model),
+				result.Backend,
+				test.Name,
+				fmt.Sprintf("%.2f ± %.2f", test.TokensPerSecond, test.TokensPerSecondStdDev),
+				ttft,
+				formatPSMemory(result.RAMBytes),
+				formatPSMemory(result.VRAMBytes),
+			})
+		}
+	}
+
+	table.Render()
+	return buf.String()
+}
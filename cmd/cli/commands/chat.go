@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/model-runner/cmd/cli/desktop"
+	"github.com/spf13/cobra"
+)
+
+// chatSession is an interactive chat with a model that keeps the conversation
+// history, so that each prompt is answered in the context of previous turns.
+type chatSession struct {
+	// model is the model being chatted with.
+	model string
+	// system is the system prompt, if any.
+	system string
+	// messages are the user and assistant messages exchanged so far.
+	messages []desktop.OpenAIChatMessage
+	// images are data URLs of images to attach to the next message.
+	images []string
+}
+
+// chatSessionFile is the format in which conversations are saved. Messages
+// include the system prompt, so that saved conversations can be sent to the
+// chat completions API as-is.
+type chatSessionFile struct {
+	Model    string                      `json:"model"`
+	Messages []desktop.OpenAIChatMessage `json:"messages"`
+}
+
+func newChatSession(model string, images []string) *chatSession {
+	return &chatSession{model: model, images: images}
+}
+
+// conversation returns the messages to send to the model, ending with next.
+func (s *chatSession) conversation(next ...desktop.OpenAIChatMessage) []desktop.OpenAIChatMessage {
+	var messages []desktop.OpenAIChatMessage
+	if s.system != "" {
+		messages = append(messages, desktop.OpenAIChatMessage{Role: "system", Content: s.system})
+	}
+	messages = append(messages, s.messages...)
+	return append(messages, next...)
+}
+
+// send sends a prompt to the model and records the exchange in the history.
+// Failed or cancelled exchanges are not recorded.
+func (s *chatSession) send(ctx context.Context, cmd *cobra.Command, client *desktop.Client, prompt string) error {
+	message, err := newUserMessage(prompt, s.images)
+	if err != nil {
+		return err
+	}
+	reply, err := chatMessagesWithMarkdown(ctx, cmd, client, s.model, s.conversation(message))
+	if err != nil {
+		return err
+	}
+	s.messages = append(s.messages, message, desktop.OpenAIChatMessage{Role: "assistant", Content: reply})
+	s.images = nil
+	return nil
+}
+
+// handleCommand handles the session commands /reset, /save, /load and
+// /set system. It returns false if line isn't a session command.
+func (s *chatSession) handleCommand(cmd *cobra.Command, line string) bool {
+	command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	switch command {
+	case "/reset":
+		s.messages = nil
+		cmd.Println("Conversation history cleared.")
+	case "/save":
+		if arg == "" {
+			cmd.PrintErrln("Usage: /save <file>")
+		} else if err := s.save(arg); err != nil {
+			cmd.PrintErrln("Failed to save conversation:", err)
+		} else {
+			cmd.Printf("Conversation saved to %s.\n", arg)
+		}
+	case "/load":
+		if arg == "" {
+			cmd.PrintErrln("Usage: /load <file>")
+		} else if err := s.load(arg); err != nil {
+			cmd.PrintErrln("Failed to load conversation:", err)
+		} else {
+			cmd.Printf("Loaded %d messages from %s.\n", len(s.messages), arg)
+		}
+	case "/set":
+		setting, value, _ := strings.Cut(arg, " ")
+		if setting != "system" {
+			cmd.PrintErrln("Usage: /set system <prompt>")
+			break
+		}
+		s.system = strings.TrimSpace(value)
+		if s.system == "" {
+			cmd.Println("System prompt cleared.")
+		} else {
+			cmd.Println("System prompt set.")
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// save writes the conversation to a file.
+func (s *chatSession) save(path string) error {
+	data, err := json.MarshalIndent(chatSessionFile{
+		Model:    s.model,
+		Messages: s.conversation(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// load replaces the conversation with one read from a file. A leading system
+// message becomes the system prompt.
+func (s *chatSession) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file chatSessionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	system := ""
+	messages := file.Messages
+	if len(messages) > 0 && messages[0].Role == "system" {
+		content, ok := messages[0].Content.(string)
+		if !ok {
+			return errors.New("system prompt must be text")
+		}
+		system, messages = content, messages[1:]
+	}
+	for _, message := range messages {
+		if message.Role != "user" && message.Role != "assistant" {
+			return fmt.Errorf("unexpected message role %q", message.Role)
+		}
+	}
+	s.system, s.messages = system, messages
+	return nil
+}
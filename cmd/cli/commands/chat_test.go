@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/model-runner/cmd/cli/desktop"
+	"github.com/spf13/cobra"
+)
+
+func TestChatSessionCommands(t *testing.T) {
+	cmd := &cobra.Command{}
+	var output bytes.Buffer
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	session := newChatSession("ai/smollm2:latest", nil)
+	if session.handleCommand(cmd, "/unknown") {
+		t.Error("Expected /unknown not to be handled")
+	}
+
+	if !session.handleCommand(cmd, "/set system You are a pirate.") {
+		t.Fatal("Expected /set system to be handled")
+	}
+	session.messages = []desktop.OpenAIChatMessage{
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Ahoy!"},
+	}
+	want := []desktop.OpenAIChatMessage{
+		{Role: "system", Content: "You are a pirate."},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Ahoy!"},
+		{Role: "user", Content: "Bye"},
+	}
+	if got := session.conversation(desktop.OpenAIChatMessage{Role: "user", Content: "Bye"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("conversation() = %+v, want %+v", got, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "chat.json")
+	session.handleCommand(cmd, "/save "+path)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected conversation to be saved: %v", err)
+	}
+
+	session.handleCommand(cmd, "/reset")
+	if len(session.messages) != 0 || session.system != "You are a pirate." {
+		t.Errorf("Expected /reset to clear only the history, got %+v", session)
+	}
+
+	loaded := newChatSession("ai/smollm2:latest", nil)
+	loaded.handleCommand(cmd, "/load "+path)
+	if got := loaded.conversation(desktop.OpenAIChatMessage{Role: "user", Content: "Bye"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded conversation() = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"messages": [{"role": "tool", "content": "x"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.load(path); err == nil {
+		t.Error("Expected an error for an unexpected role")
+	}
+	if len(loaded.messages) != 2 {
+		t.Errorf("Expected a failed load to keep the conversation, got %+v", loaded.messages)
+	}
+
+	loaded.handleCommand(cmd, "/set system")
+	if loaded.system != "" {
+		t.Errorf("Expected /set system without a prompt to clear it, got %q", loaded.system)
+	}
+}
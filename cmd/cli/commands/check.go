@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/docker/go-units"
+	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/spf13/cobra"
+)
+
+func newCheckCmd() *cobra.Command {
+	var contextSize int64
+	var jsonFormat bool
+	c := &cobra.Command{
+		Use:   "check MODEL",
+		Short: "Check whether a model can run on this system before pulling it",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(
+					"'docker model check' requires 1 argument.\n\n" +
+						"Usage:  docker model check MODEL\n\n" +
+						"See 'docker model check --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			model := models.NormalizeModelName(args[0])
+			check, err := desktopClient.CheckModel(model, contextSize)
+			if err != nil {
+				return handleClientError(err, "Failed to check model "+model)
+			}
+			if jsonFormat {
+				output, err := formatter.ToStandardJSON(check)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
+			cmd.Print(formatModelCheck(model, check))
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	c.Flags().Int64Var(&contextSize, "context-size", 0, "context size to assume (defaults to the model's context size)")
+	c.Flags().BoolVar(&jsonFormat, "json", false, "Print the check result as JSON")
+	return c
+}
+
+func formatModelCheck(model string, check inference.ModelCheck) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Model:         %s\n", model)
+	if check.Architecture != "" {
+		fmt.Fprintf(&buf, "Architecture:  %s\n", check.Architecture)
+	}
+	if check.Parameters != "" {
+		fmt.Fprintf(&buf, "Parameters:    %s\n", check.Parameters)
+	}
+	if check.Quantization != "" {
+		fmt.Fprintf(&buf, "Quantization:  %s\n", check.Quantization)
+	}
+	fmt.Fprintf(&buf, "Backend:       %s\n", check.Backend)
+	fmt.Fprintf(&buf, "Context size:  %d\n", check.ContextSize)
+	fmt.Fprintf(&buf, "Required:      %s RAM, %s VRAM\n", formatCheckMemory(check.Required.RAM), formatCheckMemory(check.Required.VRAM))
+	fmt.Fprintf(&buf, "Available:     %s RAM, %s VRAM\n", formatCheckMemory(check.Available.RAM), formatCheckMemory(check.Available.VRAM))
+	fmt.Fprintf(&buf, "Verdict:       %s\n", check.Verdict)
+	return buf.String()
+}
+
+// formatCheckMemory formats a memory size, where a size of 1 indicates that
+// the size is unknown.
+func formatCheckMemory(size uint64) string {
+	if size == 1 {
+		return "unknown"
+	}
+	return units.CustomSize("%.2f%s", float64(size), 1000.0, []string{"B", "kB", "MB", "GB", "TB", "PB", "EB", "ZB", "YB"})
+}
@@ -1,9 +1,11 @@
 package completion
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/docker/model-runner/cmd/cli/desktop"
+	dmrm "github.com/docker/model-runner/pkg/inference/models"
 	"github.com/spf13/cobra"
 )
 
@@ -11,8 +13,29 @@ func NoComplete(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCo
 	return nil, cobra.ShellCompDirectiveNoFileComp
 }
 
-// ModelNames offers completion for models present within the local store.
+const (
+	// minimumRemoteQueryLength is the length a model reference must reach
+	// before the remote catalog is searched for completions.
+	minimumRemoteQueryLength = 3
+	// remoteCompletionLimit is the maximum number of completions offered from
+	// the remote catalog.
+	remoteCompletionLimit = 20
+)
+
+// ModelNames offers completion for models present within the local store, by
+// tag, both in full and as displayed without the default namespace and tag,
+// and by ID once an ID prefix is typed.
 func ModelNames(desktopClient func() *desktop.Client, limit int) cobra.CompletionFunc {
+	return modelReferences(desktopClient, limit, false)
+}
+
+// ModelReferences offers the completions of ModelNames, or, if no local model
+// matches, the matching models of the remote catalog.
+func ModelReferences(desktopClient func() *desktop.Client, limit int) cobra.CompletionFunc {
+	return modelReferences(desktopClient, limit, true)
+}
+
+func modelReferences(desktopClient func() *desktop.Client, limit int, remote bool) cobra.CompletionFunc {
 	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		// HACK: Invoke rootCmd's PersistentPreRunE, which is needed for context
 		// detection and client initialization. This function isn't invoked
@@ -26,14 +49,80 @@ func ModelNames(desktopClient func() *desktop.Client, limit int) cobra.Completio
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
-		var names []string
-		for _, m := range models {
-			names = append(names, m.Tags...)
+		names := localModelReferences(models, toComplete)
+		if remote && len(names) == 0 && len(toComplete) >= minimumRemoteQueryLength && !isModelIDPrefix(toComplete) {
+			// The catalog is best effort, as it may be unreachable offline.
+			if found, err := desktopClient().Search(toComplete, "", remoteCompletionLimit); err == nil {
+				for _, result := range found.Results {
+					names = append(names, result.Name+"\t"+result.Description)
+				}
+			}
 		}
 		return names, cobra.ShellCompDirectiveNoFileComp
 	}
 }
 
+// localModelReferences returns the references to local models starting with
+// toComplete, sorted. IDs are only offered once toComplete is an ID prefix,
+// in the forms accepted when resolving model IDs: short IDs for hexadecimal
+// prefixes and full IDs for prefixes starting with sha256:. They're described
+// by the model's first tag.
+func localModelReferences(models []dmrm.Model, toComplete string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name, description string) {
+		if seen[name] || !strings.HasPrefix(name, toComplete) {
+			return
+		}
+		seen[name] = true
+		if description != "" {
+			name += "\t" + description
+		}
+		names = append(names, name)
+	}
+	for _, m := range models {
+		for _, tag := range m.Tags {
+			add(tag, "")
+			add(displayName(tag), "")
+		}
+		if len(m.ID) < 19 || !isModelIDPrefix(toComplete) {
+			continue
+		}
+		var description string
+		if len(m.Tags) > 0 {
+			description = m.Tags[0]
+		}
+		if isFullModelIDPrefix(toComplete) {
+			add(m.ID, description)
+		} else {
+			add(m.ID[7:19], description)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// isModelIDPrefix returns whether a partial model reference is the prefix of
+// a model ID, with or without the sha256: prefix.
+func isModelIDPrefix(s string) bool {
+	if s == "" {
+		return false
+	}
+	return isFullModelIDPrefix(s) || strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// isFullModelIDPrefix returns whether a partial model reference is the prefix
+// of a model ID including its sha256: prefix.
+func isFullModelIDPrefix(s string) bool {
+	return strings.HasPrefix(s, "sha256:") || strings.HasPrefix("sha256:", s)
+}
+
+// displayName returns a tag as displayed, without the default ai/ namespace
+// and latest tag.
+func displayName(tag string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(tag, "ai/"), ":latest")
+}
+
 // ModelNamesAndTags offers completion that matches the base model name along with its tags.
 // If the model has multiple tags, match both the base model name and each tag.
 func ModelNamesAndTags(desktopClient func() *desktop.Client, limit int) cobra.CompletionFunc {
@@ -0,0 +1,35 @@
+package completion
+
+import (
+	"reflect"
+	"testing"
+
+	dmrm "github.com/docker/model-runner/pkg/inference/models"
+)
+
+func TestLocalModelReferences(t *testing.T) {
+	models := []dmrm.Model{
+		{ID: "sha256:0123456789abcdef0123", Tags: []string{"ai/smollm2:latest", "ai/smollm2:360M"}},
+		{ID: "sha256:01fedcba98765432fedc", Tags: []string{"myorg/gemma3:v1"}},
+		{ID: "sha256:abcdef0123456789abcd"},
+	}
+
+	for _, test := range []struct {
+		toComplete string
+		expected   []string
+	}{
+		{"", []string{"ai/smollm2:360M", "ai/smollm2:latest", "myorg/gemma3:v1", "smollm2", "smollm2:360M"}},
+		{"smo", []string{"smollm2", "smollm2:360M"}},
+		{"ai/", []string{"ai/smollm2:360M", "ai/smollm2:latest"}},
+		// Partial IDs complete to short IDs, or full IDs once prefixed.
+		{"01", []string{"0123456789ab\tai/smollm2:latest", "01fedcba9876\tmyorg/gemma3:v1"}},
+		{"abc", []string{"abcdef012345"}},
+		{"sha", []string{"sha256:0123456789abcdef0123\tai/smollm2:latest", "sha256:01fedcba98765432fedc\tmyorg/gemma3:v1", "sha256:abcdef0123456789abcd"}},
+		{"sha256:01f", []string{"sha256:01fedcba98765432fedc\tmyorg/gemma3:v1"}},
+		{"llama", nil},
+	} {
+		if names := localModelReferences(models, test.toComplete); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Expected completions %q for %q, got %q", test.expected, test.toComplete, names)
+		}
+	}
+}
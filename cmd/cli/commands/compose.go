@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/docker/model-runner/cmd/cli/pkg/types"
 	"github.com/spf13/pflag"
@@ -40,6 +42,9 @@ func newUpCommand() *cobra.Command {
 	var draftModel string
 	var numTokens int
 	var minAcceptanceRate float64
+	var mode string
+	var wait bool
+	var waitTimeout time.Duration
 	c := &cobra.Command{
 		Use: "up",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -98,6 +103,24 @@ func newUpCommand() *cobra.Command {
 				sendInfo("Successfully configured backend for model " + model)
 			}
 
+			// Dependent services shouldn't start until the models can answer,
+			// which is once their runners are ready rather than once the
+			// model runner accepts connections.
+			if wait {
+				ctx := cmd.Context()
+				if waitTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+					defer cancel()
+				}
+				for _, model := range models {
+					if err := waitForModelReady(ctx, backend, model, mode); err != nil {
+						_ = sendErrorf("Model %s did not become ready: %v", model, err)
+						return fmt.Errorf("model %s did not become ready: %w", model, err)
+					}
+				}
+			}
+
 			switch kind {
 			case types.ModelRunnerEngineKindDesktop:
 				_ = setenv("URL", "http://model-runner.docker.internal/engines/v1/")
@@ -120,6 +143,9 @@ func newUpCommand() *cobra.Command {
 	c.Flags().StringVar(&draftModel, "speculative-draft-model", "", "draft model for speculative decoding")
 	c.Flags().IntVar(&numTokens, "speculative-num-tokens", 0, "number of tokens to predict speculatively")
 	c.Flags().Float64Var(&minAcceptanceRate, "speculative-min-acceptance-rate", 0, "minimum acceptance rate for speculative decoding")
+	c.Flags().StringVar(&mode, "mode", "", "mode to load the model in, such as embedding (defaults to completion)")
+	c.Flags().BoolVar(&wait, "wait", true, "wait until the model is loaded and ready to serve requests")
+	c.Flags().DurationVar(&waitTimeout, "wait-timeout", 10*time.Minute, "maximum time to wait for the model to be ready (0 to wait indefinitely)")
 	_ = c.MarkFlagRequired("model")
 	return c
 }
@@ -176,7 +202,7 @@ func downloadModelsOnlyIfNotFound(desktopClient *desktop.Client, models []string
 			}
 			return false
 		}) {
-			_, _, err = desktopClient.Pull(model, false, func(s string) {
+			_, _, err = desktopClient.Pull(model, false, false, func(s string) {
 				_ = sendInfo(s)
 			})
 			if err != nil {
@@ -190,6 +216,35 @@ func downloadModelsOnlyIfNotFound(desktopClient *desktop.Client, models []string
 	return nil
 }
 
+// composeReadinessProgressInterval is the interval at which progress is
+// reported while waiting for a model to be ready.
+const composeReadinessProgressInterval = 10 * time.Second
+
+// waitForModelReady loads a runner for a model and waits until it's ready to
+// serve requests, reporting progress to compose in the meantime.
+func waitForModelReady(ctx context.Context, backend, model, mode string) error {
+	_ = sendInfo("Loading model " + model)
+	start := time.Now()
+	loaded := make(chan error, 1)
+	go func() {
+		loaded <- desktopClient.LoadRunner(ctx, backend, model, mode)
+	}()
+	ticker := time.NewTicker(composeReadinessProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-loaded:
+			if err != nil {
+				return err
+			}
+			_ = sendInfo(fmt.Sprintf("Model %s is ready (loaded in %s)", model, time.Since(start).Round(time.Second)))
+			return nil
+		case <-ticker.C:
+			_ = sendInfo(fmt.Sprintf("Waiting for model %s to be ready (%s elapsed)", model, time.Since(start).Round(time.Second)))
+		}
+	}
+}
+
 type jsonMessage struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
@@ -2,12 +2,17 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/docker/model-runner/cmd/cli/commands/completion"
 	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/models"
 	"github.com/docker/model-runner/pkg/inference/scheduling"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func newConfigureCmd() *cobra.Command {
@@ -15,9 +20,17 @@ func newConfigureCmd() *cobra.Command {
 	var draftModel string
 	var numTokens int
 	var minAcceptanceRate float64
+	var kvCache inference.KVCacheConfig
+	var loraAdapters []string
+	var chatTemplatePath string
+	var env []string
+	var tag string
+	var persist bool
+	var splitModel string
+	var splitPercent int
 
 	c := &cobra.Command{
-		Use:    "configure [--context-size=<n>] [--speculative-draft-model=<model>] MODEL [-- <runtime-flags...>]",
+		Use:    "configure [--context-size=<n>] [--cache-type-k=<type>] [--cache-type-v=<type>] [--draft-model=<model>] [--gpu=<index>...] [--tensor-split=<p,...>] [--lora-adapter=<model>[=<scale>]...] [--env=<key=value>...] [--mount=<path>...] [--replicas=<n>] [--parallel-slots=<n>] [--batch-size=<n>] [--split-model=<model> --split-percent=<n>] [--chat-template=<file>] [--persist] [--tag=<tag>] MODEL [-- <runtime-flags...>]",
 		Short:  "Configure runtime options for a model",
 		Hidden: true,
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -44,6 +57,48 @@ func newConfigureCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// A chat template and persisted runtime flags are stored in a
+			// variant of the model, along with the context size, if set.
+			if chatTemplatePath != "" || persist {
+				var request models.ModelConfigRequest
+				if chatTemplatePath != "" {
+					template, err := os.ReadFile(chatTemplatePath)
+					if err != nil {
+						return fmt.Errorf("reading chat template: %w", err)
+					}
+					chatTemplate := string(template)
+					request.ChatTemplate = &chatTemplate
+				}
+				if persist {
+					runtimeFlags := slices.Clone(opts.RuntimeFlags)
+					if runtimeFlags == nil {
+						runtimeFlags = []string{}
+					}
+					request.RuntimeFlags = &runtimeFlags
+					opts.RuntimeFlags = nil
+				}
+				if opts.ContextSize >= 0 {
+					contextSize := uint64(opts.ContextSize)
+					request.ContextSize = &contextSize
+				}
+				if tag != "" {
+					request.Tag = models.NormalizeModelName(tag)
+				}
+				if err := desktopClient.ConfigureModel(opts.Model, request); err != nil {
+					return err
+				}
+				cmd.Println("Model configured successfully")
+				if len(opts.RuntimeFlags) == 0 && draftModel == "" && kvCache == (inference.KVCacheConfig{}) && len(opts.GPUs) == 0 && len(opts.TensorSplit) == 0 && len(loraAdapters) == 0 && len(env) == 0 && len(opts.Mounts) == 0 && opts.Replicas == 0 && opts.ParallelSlots == 0 && opts.BatchSize == 0 {
+					return nil
+				}
+				opts.ContextSize = -1
+				if tag != "" {
+					opts.Model = request.Tag
+				}
+			} else if tag != "" {
+				return fmt.Errorf("--tag can only be used with --chat-template or --persist")
+			}
+
 			// Build the speculative config if any speculative flags are set
 			if draftModel != "" || numTokens > 0 || minAcceptanceRate > 0 {
 				opts.Speculative = &inference.SpeculativeDecodingConfig{
@@ -52,14 +107,69 @@ func newConfigureCmd() *cobra.Command {
 					MinAcceptanceRate: minAcceptanceRate,
 				}
 			}
+			if kvCache != (inference.KVCacheConfig{}) {
+				opts.KVCache = &kvCache
+			}
+			for _, kv := range env {
+				key, value, ok := strings.Cut(kv, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("invalid environment variable %q, expected key=value", kv)
+				}
+				if opts.Env == nil {
+					opts.Env = make(map[string]string)
+				}
+				opts.Env[key] = value
+			}
+			for _, adapter := range loraAdapters {
+				model, scale, hasScale := strings.Cut(adapter, "=")
+				loraAdapter := inference.LoRAAdapter{Model: models.NormalizeModelName(model)}
+				if hasScale {
+					value, err := strconv.ParseFloat(scale, 64)
+					if err != nil {
+						return fmt.Errorf("invalid LoRA adapter scale %q: %w", scale, err)
+					}
+					loraAdapter.Scale = &value
+				}
+				opts.LoRAAdapters = append(opts.LoRAAdapters, loraAdapter)
+			}
+			if cmd.Flags().Changed("split-percent") {
+				opts.TrafficSplit = &scheduling.TrafficSplit{Percent: splitPercent}
+				if splitModel != "" {
+					opts.TrafficSplit.Model = models.NormalizeModelName(splitModel)
+				}
+			} else if splitModel != "" {
+				return fmt.Errorf("--split-model requires --split-percent")
+			}
 			return desktopClient.ConfigureBackend(opts)
 		},
 		ValidArgsFunction: completion.ModelNames(getDesktopClient, -1),
 	}
 
 	c.Flags().Int64Var(&opts.ContextSize, "context-size", -1, "context size (in tokens)")
-	c.Flags().StringVar(&draftModel, "speculative-draft-model", "", "draft model for speculative decoding")
+	c.Flags().StringVar(&draftModel, "speculative-draft-model", "", "draft model for speculative decoding, loaded alongside MODEL (alias: --draft-model)")
 	c.Flags().IntVar(&numTokens, "speculative-num-tokens", 0, "number of tokens to predict speculatively")
 	c.Flags().Float64Var(&minAcceptanceRate, "speculative-min-acceptance-rate", 0, "minimum acceptance rate for speculative decoding")
+	c.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "draft-model" {
+			name = "speculative-draft-model"
+		}
+		return pflag.NormalizedName(name)
+	})
+	c.Flags().StringVar(&kvCache.TypeK, "cache-type-k", "", "KV cache data type for keys, such as f16 or q8_0")
+	c.Flags().StringVar(&kvCache.TypeV, "cache-type-v", "", "KV cache data type for values, such as f16 or q8_0 (quantized types require flash attention)")
+	c.Flags().BoolVar(&kvCache.NoOffload, "no-kv-offload", false, "keep the KV cache in system RAM instead of VRAM")
+	c.Flags().IntSliceVar(&opts.GPUs, "gpu", nil, "index of a GPU to run the model on (can be specified multiple times, defaults to placing the model by free VRAM)")
+	c.Flags().Float64SliceVar(&opts.TensorSplit, "tensor-split", nil, "comma-separated proportions in which to split the model across GPUs")
+	c.Flags().StringArrayVar(&loraAdapters, "lora-adapter", nil, "model containing a LoRA adapter to apply, optionally with its scale, which can be changed without reloading the model (can be specified multiple times)")
+	c.Flags().StringArrayVar(&env, "env", nil, "environment variable for the backend process, subject to the model runner's allow-list (can be specified multiple times)")
+	c.Flags().StringArrayVar(&opts.Mounts, "mount", nil, "absolute path to make readable by the backend process, subject to the model runner's allow-list (can be specified multiple times)")
+	c.Flags().IntVar(&opts.Replicas, "replicas", 0, "number of runners to load for the model, balancing requests across them, such as one per GPU")
+	c.Flags().IntVar(&opts.ParallelSlots, "parallel-slots", 0, "number of requests each runner serves in parallel (kept across restarts, along with the context size and batch size)")
+	c.Flags().IntVar(&opts.BatchSize, "batch-size", 0, "number of tokens each runner processes in a batch")
+	c.Flags().StringVar(&splitModel, "split-model", "", "model to route a percentage of the requests for MODEL to, e.g. to canary a new quantization")
+	c.Flags().IntVar(&splitPercent, "split-percent", 0, "percentage of the requests for MODEL to route to the split model (0 removes the split)")
+	c.Flags().StringVar(&chatTemplatePath, "chat-template", "", "Jinja chat template file to store in the model, along with the context size if set")
+	c.Flags().BoolVar(&persist, "persist", false, "store the runtime flags in the model, along with the context size if set, instead of applying them to the next load only (an empty list clears them)")
+	c.Flags().StringVar(&tag, "tag", "", "tag for the model with the new chat template or persisted runtime flags (defaults to replacing MODEL)")
 	return c
 }
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestConfigureDraftModelFlag(t *testing.T) {
+	for _, flag := range []string{"speculative-draft-model", "draft-model"} {
+		t.Run(flag, func(t *testing.T) {
+			cmd := newConfigureCmd()
+			if err := cmd.ParseFlags([]string{"--" + flag + "=ai/qwen3:0.6B"}); err != nil {
+				t.Fatalf("Failed to parse --%s: %v", flag, err)
+			}
+			draftModel, err := cmd.Flags().GetString("speculative-draft-model")
+			if err != nil {
+				t.Fatalf("Failed to get draft model: %v", err)
+			}
+			if draftModel != "ai/qwen3:0.6B" {
+				t.Errorf("Expected draft model ai/qwen3:0.6B, got %q", draftModel)
+			}
+		})
+	}
+}
@@ -5,12 +5,14 @@ import (
 
 	"github.com/docker/go-units"
 	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
 	"github.com/docker/model-runner/cmd/cli/desktop"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 func newDFCmd() *cobra.Command {
+	var format string
 	c := &cobra.Command{
 		Use:   "df",
 		Short: "Show Docker Model Runner disk usage",
@@ -19,11 +21,20 @@ func newDFCmd() *cobra.Command {
 			if err != nil {
 				return handleClientError(err, "Failed to list running models")
 			}
+			if format != "" {
+				output, err := formatter.Format(format, df)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
 			cmd.Print(diskUsageTable(df))
 			return nil
 		},
 		ValidArgsFunction: completion.NoComplete,
 	}
+	addFormatFlag(c, &format)
 	return c
 }
 
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/cmd/cli/desktop"
+	mockdesktop "github.com/docker/model-runner/cmd/cli/mocks"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFormatFlags(t *testing.T) {
+	responses := map[string]string{
+		inference.ModelsPrefix:                `[{"id": "sha256:0123456789abcdef0123", "tags": ["ai/smollm2:latest"], "created": 0}]`,
+		inference.InferencePrefix + "/status": `{"llama.cpp": "running"}`,
+		inference.InferencePrefix + "/ps":     `[{"backend_name": "llama.cpp", "model_name": "ai/smollm2:latest", "mode": "completion"}, {"backend_name": "llama.cpp", "model_name": "ai/smollm2:latest", "mode": "completion", "replica": 1}]`,
+		inference.InferencePrefix + "/df":     `{"models_disk_usage": 1000, "default_backend_disk_usage": 0}`,
+	}
+
+	tests := []struct {
+		name           string
+		cmd            func() *cobra.Command
+		args           []string
+		expectedErr    string
+		expectedOutput string
+	}{
+		{
+			name:           "list with template",
+			cmd:            newListCmd,
+			args:           []string{"--format", "{{.ID}}"},
+			expectedOutput: "sha256:0123456789abcdef0123\n",
+		},
+		{
+			name:           "list quiet",
+			cmd:            newListCmd,
+			args:           []string{"--quiet"},
+			expectedOutput: "0123456789ab\n",
+		},
+		{
+			name:        "list with format and quiet",
+			cmd:         newListCmd,
+			args:        []string{"--format", "json", "--quiet"},
+			expectedErr: "--format flag cannot be used with --json, --openai or --quiet flags",
+		},
+		{
+			name:        "list with format and json",
+			cmd:         newListCmd,
+			args:        []string{"--format", "json", "--json"},
+			expectedErr: "--format flag cannot be used with --json, --openai or --quiet flags",
+		},
+		{
+			name:           "ps with template",
+			cmd:            newPSCmd,
+			args:           []string{"--format", "{{.ModelName}} {{.Replica}}"},
+			expectedOutput: "ai/smollm2:latest 0\nai/smollm2:latest 1\n",
+		},
+		{
+			name:           "ps quiet",
+			cmd:            newPSCmd,
+			args:           []string{"--quiet"},
+			expectedOutput: "ai/smollm2:latest\n",
+		},
+		{
+			name:        "ps with format and quiet",
+			cmd:         newPSCmd,
+			args:        []string{"--format", "json", "--quiet"},
+			expectedErr: "--format flag cannot be used with --quiet flag",
+		},
+		{
+			name:           "df with template",
+			cmd:            newDFCmd,
+			args:           []string{"--format", "{{.ModelsDiskUsage}}"},
+			expectedOutput: "1000",
+		},
+		{
+			name:           "df with json",
+			cmd:            newDFCmd,
+			args:           []string{"--format", "json"},
+			expectedOutput: `"models_disk_usage": 1000`,
+		},
+		{
+			name:           "status with template",
+			cmd:            newStatusCmd,
+			args:           []string{"--format", "{{.Running}} {{.Backends}}"},
+			expectedOutput: "true map[llama.cpp:running]",
+		},
+		{
+			name:        "status with format and json",
+			cmd:         newStatusCmd,
+			args:        []string{"--format", "json", "--json"},
+			expectedErr: "--format flag cannot be used with --json flag",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := mockdesktop.NewMockDockerHttpClient(ctrl)
+			modelRunner = desktop.NewContextForMock(client)
+			desktopClient = desktop.New(modelRunner)
+
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				for route, body := range responses {
+					if req.URL.String() == modelRunner.URL(route) {
+						return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+					}
+				}
+				t.Fatalf("Unexpected request to %s", req.URL)
+				return nil, nil
+			}).AnyTimes()
+
+			cmd := test.cmd()
+			buf := new(bytes.Buffer)
+			cmd.SetOut(buf)
+			cmd.SetErr(buf)
+			cmd.SetArgs(test.args)
+
+			err := cmd.Execute()
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Contains(t, buf.String(), test.expectedOutput)
+		})
+	}
+}
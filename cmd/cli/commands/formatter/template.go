@@ -0,0 +1,66 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// JSONFormat is the --format value that prints the output as JSON.
+const JSONFormat = "json"
+
+// templateFuncs are the functions available in --format templates, named
+// after those of the Docker CLI.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"truncate": func(s string, length int) string {
+		if len(s) > length {
+			return s[:length]
+		}
+		return s
+	},
+}
+
+// Format returns the representation of v for a --format value, which is
+// either json, for its JSON representation, or a Go template. If v is a slice,
+// the template is executed for each of its elements, and each output is
+// printed on a line of its own.
+func Format(format string, v any) (string, error) {
+	if format == JSONFormat {
+		return ToStandardJSON(v)
+	}
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid format template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	execute := func(item any) error {
+		if err := tmpl.Execute(&buf, item); err != nil {
+			return fmt.Errorf("executing format template: %w", err)
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Slice {
+		for i := range value.Len() {
+			if err := execute(value.Index(i).Interface()); err != nil {
+				return "", err
+			}
+		}
+		return buf.String(), nil
+	}
+	if err := execute(v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,42 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	type model struct {
+		ID   string
+		Tags []string
+	}
+	models := []model{
+		{ID: "sha256:0123456789abcdef", Tags: []string{"ai/smollm2:latest", "ai/smollm2:360M"}},
+		{ID: "sha256:fedcba9876543210", Tags: nil},
+	}
+
+	// Templates are executed for each element of slices.
+	output, err := Format(`{{truncate .ID 19}} {{join .Tags ","}}`, models)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if expected := "sha256:0123456789ab ai/smollm2:latest,ai/smollm2:360M\nsha256:fedcba987654 \n"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	// Other values are formatted once.
+	if output, err := Format(`{{json .Tags}}`, models[0]); err != nil || output != `["ai/smollm2:latest","ai/smollm2:360M"]`+"\n" {
+		t.Errorf("Unexpected template output %q (%v)", output, err)
+	}
+
+	if output, err := Format(JSONFormat, models[1]); err != nil || !strings.Contains(output, `"ID": "sha256:fedcba9876543210"`) {
+		t.Errorf("Unexpected JSON output %q (%v)", output, err)
+	}
+
+	if _, err := Format(`{{.ID`, models); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+	if _, err := Format(`{{.Missing}}`, models); err == nil {
+		t.Error("Expected an error for a template referencing a missing field")
+	}
+}
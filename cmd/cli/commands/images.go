@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -131,6 +132,52 @@ func encodeImageToDataURL(filePath string) (string, error) {
 	return dataURL, nil
 }
 
+// loadImage encodes an image given by a file path or an http(s) URL to a data
+// URL.
+func loadImage(ctx context.Context, pathOrURL string) (string, error) {
+	if !strings.HasPrefix(pathOrURL, "http://") && !strings.HasPrefix(pathOrURL, "https://") {
+		return encodeImageToDataURL(normalizeFilePath(pathOrURL))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pathOrURL, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSizeBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(buf)) > MaxImageSizeBytes {
+		return "", fmt.Errorf("file size exceeds maximum limit (%d MB)", MaxImageSizeBytes/(1024*1024))
+	}
+	if !isImageFileByContentAndExtension(buf, req.URL.Path) {
+		return "", fmt.Errorf("invalid image type for URL: %s", pathOrURL)
+	}
+	contentType := http.DetectContentType(buf)
+	return fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(buf)), nil
+}
+
+// loadImages encodes images given by file paths or URLs to data URLs.
+func loadImages(ctx context.Context, pathsOrURLs []string) ([]string, error) {
+	dataURLs := make([]string, 0, len(pathsOrURLs))
+	for _, pathOrURL := range pathsOrURLs {
+		dataURL, err := loadImage(ctx, pathOrURL)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load image %q: %w", pathOrURL, err)
+		}
+		dataURLs = append(dataURLs, dataURL)
+	}
+	return dataURLs, nil
+}
+
 // processImagesInPrompt extracts images from the prompt, encodes them to data URLs,
 // and returns the cleaned prompt text and list of image data URLs
 func processImagesInPrompt(prompt string) (string, []string, error) {
@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -109,3 +113,52 @@ func TestProcessFileInclusions(t *testing.T) {
 		t.Errorf("ProcessFileInclusions() with non-existent file = %v, want %v", result2, expected2)
 	}
 }
+
+func TestLoadImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(imagePath, png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textPath, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Write(png)
+		case "/page.html":
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dataURLs, err := loadImages(t.Context(), []string{imagePath, server.URL + "/image.png"})
+	if err != nil {
+		t.Fatalf("loadImages failed: %v", err)
+	}
+	if len(dataURLs) != 2 {
+		t.Fatalf("Expected 2 data URLs, got %d", len(dataURLs))
+	}
+	for _, dataURL := range dataURLs {
+		if !strings.HasPrefix(dataURL, "data:image/png;base64,") {
+			t.Errorf("Expected PNG data URL, got %q", dataURL)
+		}
+	}
+
+	for _, invalid := range []string{
+		textPath,
+		filepath.Join(dir, "missing.png"),
+		server.URL + "/page.html",
+		server.URL + "/missing.png",
+	} {
+		if _, err := loadImages(t.Context(), []string{invalid}); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
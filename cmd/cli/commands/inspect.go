@@ -13,6 +13,8 @@ import (
 func newInspectCmd() *cobra.Command {
 	var openai bool
 	var remote bool
+	var format string
+	var quiet bool
 	c := &cobra.Command{
 		Use:   "inspect MODEL",
 		Short: "Display detailed information on one model",
@@ -33,7 +35,13 @@ func newInspectCmd() *cobra.Command {
 			if openai && remote {
 				return fmt.Errorf("--remote flag cannot be used with --openai flag")
 			}
-			inspectedModel, err := inspectModel(args, openai, remote, desktopClient)
+			if format != "" && quiet {
+				return fmt.Errorf("--format flag cannot be used with --quiet flag")
+			}
+			if openai && quiet {
+				return fmt.Errorf("--quiet flag cannot be used with --openai flag")
+			}
+			inspectedModel, err := inspectModel(args, openai, remote, format, quiet, desktopClient)
 			if err != nil {
 				return err
 			}
@@ -44,22 +52,30 @@ func newInspectCmd() *cobra.Command {
 	}
 	c.Flags().BoolVar(&openai, "openai", false, "List model in an OpenAI format")
 	c.Flags().BoolVarP(&remote, "remote", "r", false, "Show info for remote models")
+	c.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only show the model ID")
+	addFormatFlag(c, &format)
 	return c
 }
 
-func inspectModel(args []string, openai bool, remote bool, desktopClient *desktop.Client) (string, error) {
+func inspectModel(args []string, openai bool, remote bool, format string, quiet bool, desktopClient *desktop.Client) (string, error) {
 	// Normalize model name to add default org and tag if missing
 	modelName := models.NormalizeModelName(args[0])
+	if format == "" {
+		format = formatter.JSONFormat
+	}
 	if openai {
 		model, err := desktopClient.InspectOpenAI(modelName)
 		if err != nil {
 			return "", handleClientError(err, "Failed to get model "+modelName)
 		}
-		return formatter.ToStandardJSON(model)
+		return formatter.Format(format, model)
 	}
 	model, err := desktopClient.Inspect(modelName, remote)
 	if err != nil {
 		return "", handleClientError(err, "Failed to get model "+modelName)
 	}
-	return formatter.ToStandardJSON(model)
+	if quiet {
+		return model.ID + "\n", nil
+	}
+	return formatter.Format(format, model)
 }
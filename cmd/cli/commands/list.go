@@ -20,6 +20,7 @@ import (
 
 func newListCmd() *cobra.Command {
 	var jsonFormat, openai, quiet bool
+	var format string
 	c := &cobra.Command{
 		Use:     "list [OPTIONS]",
 		Aliases: []string{"ls"},
@@ -28,11 +29,14 @@ func newListCmd() *cobra.Command {
 			if openai && quiet {
 				return fmt.Errorf("--quiet flag cannot be used with --openai flag or OpenAI backend")
 			}
+			if format != "" && (jsonFormat || openai || quiet) {
+				return fmt.Errorf("--format flag cannot be used with --json, --openai or --quiet flags")
+			}
 
 			// If we're doing an automatic install, only show the installation
 			// status if it won't corrupt machine-readable output.
 			var standaloneInstallPrinter standalone.StatusPrinter
-			if !jsonFormat && !openai && !quiet {
+			if !jsonFormat && !openai && !quiet && format == "" {
 				standaloneInstallPrinter = cmd
 			}
 			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), standaloneInstallPrinter); err != nil {
@@ -42,7 +46,7 @@ func newListCmd() *cobra.Command {
 			if len(args) > 0 {
 				modelFilter = args[0]
 			}
-			models, err := listModels(openai, desktopClient, quiet, jsonFormat, modelFilter)
+			models, err := listModels(openai, desktopClient, quiet, jsonFormat, format, modelFilter)
 			if err != nil {
 				return err
 			}
@@ -54,10 +58,11 @@ func newListCmd() *cobra.Command {
 	c.Flags().BoolVar(&jsonFormat, "json", false, "List models in a JSON format")
 	c.Flags().BoolVar(&openai, "openai", false, "List models in an OpenAI format")
 	c.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only show model IDs")
+	addFormatFlag(c, &format)
 	return c
 }
 
-func listModels(openai bool, desktopClient *desktop.Client, quiet bool, jsonFormat bool, modelFilter string) (string, error) {
+func listModels(openai bool, desktopClient *desktop.Client, quiet bool, jsonFormat bool, format string, modelFilter string) (string, error) {
 	if openai {
 		models, err := desktopClient.ListOpenAI()
 		if err != nil {
@@ -99,6 +104,9 @@ func listModels(openai bool, desktopClient *desktop.Client, quiet bool, jsonForm
 	if jsonFormat {
 		return formatter.ToStandardJSON(models)
 	}
+	if format != "" {
+		return formatter.Format(format, models)
+	}
 	if quiet {
 		var modelIDs string
 		for _, m := range models {
@@ -171,14 +179,26 @@ func appendRow(table *tablewriter.Table, tag string, model dmrm.Model) {
 		}
 	}
 
+	// Models packaged without a creation time show when they were pulled.
+	created := ""
+	if model.Created > 0 {
+		created = units.HumanDuration(time.Since(time.Unix(model.Created, 0))) + " ago"
+	} else if model.Pulled > 0 {
+		created = units.HumanDuration(time.Since(time.Unix(model.Pulled, 0))) + " ago"
+	}
+	size := model.Config.Size
+	if model.Size > 0 {
+		size = units.BytesSize(float64(model.Size))
+	}
+
 	table.Append([]string{
 		displayTag,
 		model.Config.Parameters,
 		model.Config.Quantization,
 		model.Config.Architecture,
 		model.ID[7:19],
-		units.HumanDuration(time.Since(time.Unix(model.Created, 0))) + " ago",
+		created,
 		contextSize,
-		model.Config.Size,
+		size,
 	})
 }
@@ -27,9 +27,17 @@ import (
 func newLogsCmd() *cobra.Command {
 	var follow, noEngines bool
 	c := &cobra.Command{
-		Use:   "logs [OPTIONS]",
-		Short: "Fetch the Docker Model Runner logs",
+		Use:   "logs [OPTIONS] [MODEL]",
+		Short: "Fetch the Docker Model Runner logs, or the inference engine logs of a running model",
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if noEngines {
+					return errors.New("--no-engines can't be used with a model")
+				}
+				return printRunnerLogs(cmd, args[0], follow)
+			}
+
 			homeDir, err := os.UserHomeDir()
 			if err != nil {
 				return err
@@ -141,7 +149,7 @@ func newLogsCmd() *cobra.Command {
 
 			return g.Wait()
 		},
-		ValidArgsFunction: completion.NoComplete,
+		ValidArgsFunction: completion.ModelNames(getDesktopClient, 1),
 	}
 	c.Flags().BoolVarP(&follow, "follow", "f", false, "View logs with real-time streaming")
 	c.Flags().BoolVar(&noEngines, "no-engines", false, "Exclude inference engine logs from the output")
@@ -152,6 +160,25 @@ var timestampRe = regexp.MustCompile(`\[(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d
 
 const timeFmt = "2006-01-02T15:04:05.000000000Z"
 
+// printRunnerLogs prints the output of the inference engines of the runners
+// loaded for a model, following it until they're unloaded if follow is set.
+func printRunnerLogs(cmd *cobra.Command, model string, follow bool) error {
+	if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+		return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+	}
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer cancel()
+	logs, err := desktopClient.RunnerLogs(ctx, model, follow)
+	if err != nil {
+		return handleClientError(err, "Failed to get logs of "+model)
+	}
+	defer logs.Close()
+	if _, err := io.Copy(cmd.OutOrStdout(), logs); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read logs: %w", err)
+	}
+	return nil
+}
+
 func printTillFirstTimestamp(logScanner *bufio.Scanner) (time.Time, string) {
 	if logScanner == nil {
 		return time.Time{}, ""
@@ -150,6 +150,8 @@ func newPackagedCmd() *cobra.Command {
 	c.Flags().StringVar(&opts.fromModel, "from", "", "reference to an existing model to repackage")
 	c.Flags().StringVar(&opts.chatTemplatePath, "chat-template", "", "absolute path to chat template file (must be Jinja format)")
 	c.Flags().StringArrayVarP(&opts.licensePaths, "license", "l", nil, "absolute path to a license file")
+	c.Flags().BoolVar(&opts.requireLicenseAcceptance, "require-license-acceptance", false, "require users to accept the licenses before pulling the model")
+	c.Flags().BoolVar(&opts.reranker, "reranker", false, "mark the model as a reranker, served by the rerank endpoint")
 	c.Flags().StringArrayVar(&opts.dirTarPaths, "dir-tar", nil, "relative path to directory to package as tar (can be specified multiple times)")
 	c.Flags().BoolVar(&opts.push, "push", false, "push to registry (if not set, the model is loaded into the Model Runner content store)")
 	c.Flags().Uint64Var(&opts.contextSize, "context-size", 0, "context size in tokens")
@@ -165,7 +167,10 @@ type packageOptions struct {
 	licensePaths     []string
 	dirTarPaths      []string
 	push             bool
+	reranker         bool
 	tag              string
+
+	requireLicenseAcceptance bool
 }
 
 // builderInitResult contains the result of initializing a builder from various sources
@@ -294,6 +299,11 @@ func packageModel(cmd *cobra.Command, opts packageOptions) error {
 		pkg = pkg.WithContextSize(opts.contextSize)
 	}
 
+	if opts.reranker {
+		cmd.PrintErrln("Marking the model as a reranker")
+		pkg = pkg.WithReranker()
+	}
+
 	// Add license files
 	for _, path := range opts.licensePaths {
 		cmd.PrintErrf("Adding license file from %q\n", path)
@@ -302,6 +312,13 @@ func packageModel(cmd *cobra.Command, opts packageOptions) error {
 			return fmt.Errorf("add license file: %w", err)
 		}
 	}
+	if opts.requireLicenseAcceptance {
+		if len(opts.licensePaths) == 0 && opts.fromModel == "" {
+			return fmt.Errorf("--require-license-acceptance requires at least one --license")
+		}
+		cmd.PrintErrln("Requiring license acceptance")
+		pkg = pkg.WithLicenseAcceptanceRequired()
+	}
 
 	if opts.chatTemplatePath != "" {
 		cmd.PrintErrf("Adding chat template file from %q\n", opts.chatTemplatePath)
@@ -2,38 +2,113 @@ package commands
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/go-units"
 	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
 	"github.com/docker/model-runner/cmd/cli/desktop"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 func newPSCmd() *cobra.Command {
+	var crashed bool
+	var format string
+	var quiet bool
 	c := &cobra.Command{
 		Use:   "ps",
 		Short: "List running models",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ps, err := desktopClient.PS()
+			if format != "" && quiet {
+				return fmt.Errorf("--format flag cannot be used with --quiet flag")
+			}
+			ps, err := desktopClient.PS(crashed)
 			if err != nil {
 				return handleClientError(err, "Failed to list running models")
 			}
+			if format != "" {
+				output, err := formatter.Format(format, ps)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
+			if quiet {
+				cmd.Print(psModelNames(ps))
+				return nil
+			}
+			if crashed {
+				cmd.Print(formatCrashReports(ps))
+				return nil
+			}
 			cmd.Print(psTable(ps))
 			return nil
 		},
 		ValidArgsFunction: completion.NoComplete,
 	}
+	c.Flags().BoolVar(&crashed, "crashed", false, "List the models whose runners recently crashed, with their exit codes, command lines and output")
+	c.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only show the names of the running models")
+	addFormatFlag(c, &format)
 	return c
 }
 
+// formatCrashReports formats the crash reports of the crashed runners in ps,
+// most recent first.
+func formatCrashReports(ps []desktop.BackendStatus) string {
+	var buf bytes.Buffer
+	for i := len(ps) - 1; i >= 0; i-- {
+		crash := ps[i].Crash
+		if crash == nil {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "Model:      %s\n", stripDefaultsFromModelName(ps[i].ModelName))
+		fmt.Fprintf(&buf, "Backend:    %s\n", ps[i].BackendName)
+		fmt.Fprintf(&buf, "Mode:       %s\n", ps[i].Mode)
+		fmt.Fprintf(&buf, "Crashed:    %s ago\n", units.HumanDuration(max(time.Since(crash.Time), 0)))
+		if crash.ExitCode != nil {
+			fmt.Fprintf(&buf, "Exit code:  %d\n", *crash.ExitCode)
+		}
+		if len(crash.Command) > 0 {
+			fmt.Fprintf(&buf, "Command:    %s\n", strings.Join(crash.Command, " "))
+		}
+		if crash.Output != "" {
+			fmt.Fprintf(&buf, "Output:\n%s\n", strings.TrimRight(crash.Output, "\n"))
+		} else {
+			fmt.Fprintf(&buf, "Error:      %s\n", crash.Error)
+		}
+	}
+	if buf.Len() == 0 {
+		return "No crashed runners\n"
+	}
+	return buf.String()
+}
+
+// psModelNames lists the names of the models in ps, once each, in order.
+func psModelNames(ps []desktop.BackendStatus) string {
+	var buf bytes.Buffer
+	seen := make(map[string]bool, len(ps))
+	for _, status := range ps {
+		if !seen[status.ModelName] {
+			seen[status.ModelName] = true
+			fmt.Fprintln(&buf, status.ModelName)
+		}
+	}
+	return buf.String()
+}
+
 func psTable(ps []desktop.BackendStatus) string {
 	var buf bytes.Buffer
 	table := tablewriter.NewWriter(&buf)
 
-	table.SetHeader([]string{"MODEL NAME", "BACKEND", "MODE", "LAST USED"})
+	table.SetHeader([]string{"MODEL NAME", "BACKEND", "MODE", "RAM", "VRAM", "GPUS", "UPTIME", "REQUESTS", "TOKENS/S", "LAST USED"})
 
 	table.SetBorder(false)
 	table.SetColumnSeparator("")
@@ -45,6 +120,12 @@ func psTable(ps []desktop.BackendStatus) string {
 		tablewriter.ALIGN_LEFT, // MODEL
 		tablewriter.ALIGN_LEFT, // BACKEND
 		tablewriter.ALIGN_LEFT, // MODE
+		tablewriter.ALIGN_LEFT, // RAM
+		tablewriter.ALIGN_LEFT, // VRAM
+		tablewriter.ALIGN_LEFT, // GPUS
+		tablewriter.ALIGN_LEFT, // UPTIME
+		tablewriter.ALIGN_LEFT, // REQUESTS
+		tablewriter.ALIGN_LEFT, // TOKENS/S
 		tablewriter.ALIGN_LEFT, // LAST USED
 	})
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -57,6 +138,9 @@ func psTable(ps []desktop.BackendStatus) string {
 			// Strip default "ai/" prefix and ":latest" tag for display
 			modelName = stripDefaultsFromModelName(modelName)
 		}
+		if status.Replica > 0 {
+			modelName = fmt.Sprintf("%s (replica %d)", modelName, status.Replica)
+		}
 
 		var lastUsed string
 		if status.InUse {
@@ -80,6 +164,12 @@ func psTable(ps []desktop.BackendStatus) string {
 			modelName,
 			status.BackendName,
 			status.Mode,
+			formatPSMemory(status.RAMBytes),
+			formatPSMemory(status.VRAMBytes),
+			formatPSGPUs(status.GPUs),
+			formatPSUptime(status.UptimeSeconds),
+			formatPSRequests(status.InFlight, status.Queued),
+			formatPSTokensPerSecond(status.TokensPerSecond),
 			lastUsed,
 		})
 	}
@@ -87,3 +177,49 @@ func psTable(ps []desktop.BackendStatus) string {
 	table.Render()
 	return buf.String()
 }
+
+// formatPSMemory formats the memory allocated to a runner, which is zero if
+// it's unknown.
+func formatPSMemory(size uint64) string {
+	if size == 0 {
+		return "-"
+	}
+	return units.CustomSize("%.2f%s", float64(size), 1000.0, []string{"B", "kB", "MB", "GB", "TB", "PB", "EB", "ZB", "YB"})
+}
+
+// formatPSGPUs formats the indices of the GPUs a runner is placed on.
+func formatPSGPUs(gpus []int) string {
+	if len(gpus) == 0 {
+		return "-"
+	}
+	indices := make([]string, len(gpus))
+	for i, gpu := range gpus {
+		indices[i] = strconv.Itoa(gpu)
+	}
+	return strings.Join(indices, ",")
+}
+
+// formatPSUptime formats the time since a runner finished loading.
+func formatPSUptime(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return units.HumanDuration(time.Duration(seconds * float64(time.Second)))
+}
+
+// formatPSRequests formats the requests being served by a runner and waiting
+// for it.
+func formatPSRequests(inFlight, queued int) string {
+	if queued > 0 {
+		return fmt.Sprintf("%d active, %d queued", inFlight, queued)
+	}
+	return fmt.Sprintf("%d active", inFlight)
+}
+
+// formatPSTokensPerSecond formats a runner's completion throughput.
+func formatPSTokensPerSecond(tokensPerSecond float64) string {
+	if tokensPerSecond == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", tokensPerSecond)
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -13,6 +14,7 @@ import (
 
 func newPullCmd() *cobra.Command {
 	var ignoreRuntimeMemoryCheck bool
+	var acceptLicense bool
 
 	c := &cobra.Command{
 		Use:   "pull MODEL",
@@ -31,17 +33,18 @@ func newPullCmd() *cobra.Command {
 			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
 				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
 			}
-			return pullModel(cmd, desktopClient, args[0], ignoreRuntimeMemoryCheck)
+			return pullModel(cmd, desktopClient, args[0], ignoreRuntimeMemoryCheck, acceptLicense)
 		},
-		ValidArgsFunction: completion.NoComplete,
+		ValidArgsFunction: completion.ModelReferences(getDesktopClient, 1),
 	}
 
 	c.Flags().BoolVar(&ignoreRuntimeMemoryCheck, "ignore-runtime-memory-check", false, "Do not block pull if estimated runtime memory for model exceeds system resources.")
+	c.Flags().BoolVar(&acceptLicense, "accept-license", false, "Accept the model license without prompting, if the model requires license acceptance.")
 
 	return c
 }
 
-func pullModel(cmd *cobra.Command, desktopClient *desktop.Client, model string, ignoreRuntimeMemoryCheck bool) error {
+func pullModel(cmd *cobra.Command, desktopClient *desktop.Client, model string, ignoreRuntimeMemoryCheck, acceptLicense bool) error {
 	// Normalize model name to add default org and tag if missing
 	model = models.NormalizeModelName(model)
 	var progress func(string)
@@ -50,7 +53,20 @@ func pullModel(cmd *cobra.Command, desktopClient *desktop.Client, model string,
 	} else {
 		progress = RawProgress
 	}
-	response, progressShown, err := desktopClient.Pull(model, ignoreRuntimeMemoryCheck, progress)
+	response, progressShown, err := desktopClient.Pull(model, ignoreRuntimeMemoryCheck, acceptLicense, progress)
+
+	// If the model requires license acceptance, ask the user and retry.
+	var licenseErr *desktop.LicenseError
+	if errors.As(err, &licenseErr) {
+		accepted, promptErr := promptLicense(cmd, licenseErr)
+		if promptErr != nil {
+			return promptErr
+		}
+		if !accepted {
+			return fmt.Errorf("license of %s not accepted", model)
+		}
+		response, progressShown, err = desktopClient.Pull(model, ignoreRuntimeMemoryCheck, true, progress)
+	}
 
 	// Add a newline before any output (success or error) if progress was shown.
 	if progressShown {
@@ -65,6 +81,24 @@ func pullModel(cmd *cobra.Command, desktopClient *desktop.Client, model string,
 	return nil
 }
 
+// promptLicense shows the license of a model and asks the user to accept it.
+func promptLicense(cmd *cobra.Command, licenseErr *desktop.LicenseError) (bool, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false, fmt.Errorf("%w\nUse --accept-license to accept it", licenseErr)
+	}
+	cmd.Printf("The model %s is distributed under the following license:\n\n", licenseErr.Model)
+	cmd.Println(licenseErr.License)
+	cmd.Println()
+	cmd.Print("Do you accept the license? [y/N] ")
+
+	var input string
+	_, err := fmt.Scanln(&input)
+	if err != nil && err.Error() != "unexpected newline" {
+		return false, err
+	}
+	return input == "y" || input == "Y", nil
+}
+
 func TUIProgress(message string) {
 	fmt.Print("\r\033[K", message)
 }
@@ -2,11 +2,18 @@ package commands
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	dmrm "github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/metrics"
 	"github.com/spf13/cobra"
 )
 
@@ -77,5 +84,162 @@ func newRequestsCmd() *cobra.Command {
 	c.Flags().StringVar(&model, "model", "", "Specify the model to filter requests")
 	// Enable completion for the --model flag.
 	_ = c.RegisterFlagCompletionFunc("model", completion.ModelNames(getDesktopClient, 1))
+	c.AddCommand(newRequestsExportCmd(), newRequestsReplayCmd())
 	return c
 }
+
+func newRequestsExportCmd() *cobra.Command {
+	var model string
+	var output string
+	c := &cobra.Command{
+		Use:   "export [OPTIONS]",
+		Short: "Export captured requests+responses as JSON lines",
+		Long: "Export the requests+responses captured by Docker Model Runner as JSON lines, " +
+			"or the recent ones if capture isn't enabled, to replay them with 'docker model requests replay'.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+
+			body, err := desktopClient.ExportRequests(cmd.Context(), model)
+			if err != nil {
+				return handleClientError(err, "Failed to export requests")
+			}
+			defer body.Close()
+
+			w := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", output, err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if _, err := io.Copy(w, body); err != nil {
+				return fmt.Errorf("failed to export requests: %w", err)
+			}
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	c.Flags().StringVar(&model, "model", "", "Only export the requests of this model")
+	c.Flags().StringVarP(&output, "output", "o", "", "Write the requests to a file rather than the standard output")
+	return c
+}
+
+func newRequestsReplayCmd() *cobra.Command {
+	var model string
+	var output string
+	c := &cobra.Command{
+		Use:   "replay FILE",
+		Short: "Re-issue exported requests",
+		Long: "Re-issue the successful requests exported by 'docker model requests export', " +
+			"optionally against another model, e.g. to regression-test a new version of a model. " +
+			"The new requests+responses can be written to a file to compare them with the exported ones.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(
+					"'docker model requests replay' requires 1 argument.\n\n" +
+						"Usage:  docker model requests replay FILE\n\n" +
+						"See 'docker model requests replay --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := readRequestRecords(args[0])
+			if err != nil {
+				return err
+			}
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+
+			var out *json.Encoder
+			if output != "" {
+				f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", output, err)
+				}
+				defer f.Close()
+				out = json.NewEncoder(f)
+			}
+
+			var replayed, failed int
+			for _, record := range records {
+				// Only requests that succeeded are worth comparing.
+				if record.StatusCode == 0 || record.StatusCode >= http.StatusBadRequest {
+					continue
+				}
+				if model != "" {
+					record.Model = dmrm.NormalizeModelName(model)
+					record.Request = withRequestModel(record.Request, record.Model)
+				}
+				start := time.Now()
+				result, err := desktopClient.ReplayRequest(cmd.Context(), record)
+				if err != nil {
+					return handleClientError(err, "Failed to replay request "+record.ID)
+				}
+				replayed++
+				if result.StatusCode >= http.StatusBadRequest {
+					failed++
+				}
+				cmd.Printf("%d  %-8s %s %s\n", result.StatusCode, time.Since(start).Round(time.Millisecond),
+					record.Method, record.URL)
+				if out != nil {
+					if err := out.Encode(result); err != nil {
+						return fmt.Errorf("failed to write %s: %w", output, err)
+					}
+				}
+			}
+
+			cmd.Printf("Replayed %d requests, %d failed\n", replayed, failed)
+			if failed > 0 {
+				return fmt.Errorf("%d of %d replayed requests failed", failed, replayed)
+			}
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	c.Flags().StringVar(&model, "model", "", "Replay the requests against this model rather than their own")
+	c.Flags().StringVarP(&output, "output", "o", "", "Write the new requests+responses to a file as JSON lines")
+	return c
+}
+
+// readRequestRecords reads the requests+responses exported as JSON lines.
+func readRequestRecords(path string) ([]metrics.RequestResponsePair, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var records []metrics.RequestResponsePair
+	decoder := json.NewDecoder(f)
+	for {
+		var record metrics.RequestResponsePair
+		if err := decoder.Decode(&record); errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// withRequestModel returns the body of an OpenAI request with its model
+// replaced, or the body as-is if it can't be decoded.
+func withRequestModel(body, model string) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return body
+	}
+	fields["model"], _ = json.Marshal(model)
+	replaced, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return string(replaced)
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRequestRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	if err := os.WriteFile(path, []byte(
+		`{"id":"1","model":"ai/smollm2:latest","method":"POST","url":"/engines/v1/chat/completions","request":"{\"model\":\"ai/smollm2\"}","status_code":200}`+"\n"+
+			`{"id":"2","model":"ai/smollm2:latest","method":"POST","url":"/engines/v1/completions","request":"{}","error":"failed","status_code":500}`+"\n",
+	), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := readRequestRecords(path)
+	if err != nil {
+		t.Fatalf("readRequestRecords failed: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "1" || records[1].StatusCode != 500 {
+		t.Fatalf("Expected the 2 exported records, got %+v", records)
+	}
+
+	if got, want := withRequestModel(records[0].Request, "ai/smollm2:next"), `{"model":"ai/smollm2:next"}`; got != want {
+		t.Errorf("Expected the model to be replaced in %s, got %s", want, got)
+	}
+	if got := withRequestModel("not json", "ai/smollm2:next"); got != "not json" {
+		t.Errorf("Expected an undecodable request to be kept as-is, got %s", got)
+	}
+}
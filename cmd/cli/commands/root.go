@@ -95,6 +95,9 @@ func NewRootCmd(cli *command.DockerCli) *cobra.Command {
 		newVersionCmd(),
 		newStatusCmd(),
 		newPullCmd(),
+		newCheckCmd(),
+		newBenchmarkCmd(),
+		newSearchCmd(),
 		newPushCmd(),
 		newPackagedCmd(),
 		newListCmd(),
@@ -104,6 +107,7 @@ func NewRootCmd(cli *command.DockerCli) *cobra.Command {
 		newInspectCmd(),
 		newComposeCmd(),
 		newTagCmd(),
+		newAliasCmd(),
 		newInstallRunner(),
 		newUninstallRunner(),
 		newStartRunner(),
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 
@@ -87,9 +88,14 @@ func readMultilineInput(cmd *cobra.Command, scanner *bufio.Scanner) (string, err
 }
 
 // generateInteractiveWithReadline provides an enhanced interactive mode with readline support
-func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.Client, model string) error {
+// The images are attached to the first message.
+func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.Client, model string, imageURLs []string) error {
 	usage := func() {
 		fmt.Fprintln(os.Stderr, "Available Commands:")
+		fmt.Fprintln(os.Stderr, "  /set system <prompt>  Set the system prompt")
+		fmt.Fprintln(os.Stderr, "  /reset          Clear the conversation history")
+		fmt.Fprintln(os.Stderr, "  /save <file>    Save the conversation to a file")
+		fmt.Fprintln(os.Stderr, "  /load <file>    Load a conversation from a file")
 		fmt.Fprintln(os.Stderr, "  /bye            Exit")
 		fmt.Fprintln(os.Stderr, "  /?, /help       Help for a command")
 		fmt.Fprintln(os.Stderr, "  /? shortcuts    Help for keyboard shortcuts")
@@ -141,7 +147,7 @@ func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.
 	})
 	if err != nil {
 		// Fall back to basic input mode if readline initialization fails
-		return generateInteractiveBasic(cmd, desktopClient, model)
+		return generateInteractiveBasic(cmd, desktopClient, model, imageURLs)
 	}
 
 	// Disable history if the environment variable is set
@@ -154,6 +160,7 @@ func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.
 
 	var sb strings.Builder
 	var multiline bool
+	session := newChatSession(model, imageURLs)
 
 	// Add a helper function to handle file inclusion when @ is pressed
 	// We'll implement a basic version here that shows a message when @ is pressed
@@ -219,6 +226,8 @@ func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.
 			continue
 		case strings.HasPrefix(line, "/exit"), strings.HasPrefix(line, "/bye"):
 			return nil
+		case session.handleCommand(cmd, line):
+			continue
 		case strings.HasPrefix(line, "/"):
 			fmt.Printf("Unknown command '%s'. Type /? for help\n", strings.Fields(line)[0])
 			continue
@@ -245,7 +254,7 @@ func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.
 				}
 			}()
 
-			err := chatWithMarkdownContext(chatCtx, cmd, desktopClient, model, userInput)
+			err := session.send(chatCtx, cmd, desktopClient, userInput)
 
 			// Clean up signal handler
 			signal.Stop(sigChan)
@@ -269,9 +278,11 @@ func generateInteractiveWithReadline(cmd *cobra.Command, desktopClient *desktop.
 	}
 }
 
-// generateInteractiveBasic provides a basic interactive mode (fallback)
-func generateInteractiveBasic(cmd *cobra.Command, desktopClient *desktop.Client, model string) error {
+// generateInteractiveBasic provides a basic interactive mode (fallback). The
+// images are attached to the first message.
+func generateInteractiveBasic(cmd *cobra.Command, desktopClient *desktop.Client, model string, imageURLs []string) error {
 	scanner := bufio.NewScanner(os.Stdin)
+	session := newChatSession(model, imageURLs)
 	for {
 		userInput, err := readMultilineInput(cmd, scanner)
 		if err != nil {
@@ -285,7 +296,7 @@ func generateInteractiveBasic(cmd *cobra.Command, desktopClient *desktop.Client,
 			break
 		}
 
-		if strings.TrimSpace(userInput) == "" {
+		if strings.TrimSpace(userInput) == "" || session.handleCommand(cmd, userInput) {
 			continue
 		}
 
@@ -306,7 +317,7 @@ func generateInteractiveBasic(cmd *cobra.Command, desktopClient *desktop.Client,
 			}
 		}()
 
-		err = chatWithMarkdownContext(chatCtx, cmd, desktopClient, model, userInput)
+		err = session.send(chatCtx, cmd, desktopClient, userInput)
 
 		cancelChat()
 		signal.Stop(sigChan)
@@ -508,33 +519,46 @@ func renderMarkdown(content string) (string, error) {
 }
 
 // chatWithMarkdown performs chat and streams the response with selective markdown rendering.
-func chatWithMarkdown(cmd *cobra.Command, client *desktop.Client, model, prompt string) error {
-	return chatWithMarkdownContext(cmd.Context(), cmd, client, model, prompt)
+func chatWithMarkdown(cmd *cobra.Command, client *desktop.Client, model, prompt string, imageURLs []string) error {
+	return chatWithMarkdownContext(cmd.Context(), cmd, client, model, prompt, imageURLs)
 }
 
 // chatWithMarkdownContext performs chat with context support and streams the response with selective markdown rendering.
-func chatWithMarkdownContext(ctx context.Context, cmd *cobra.Command, client *desktop.Client, model, prompt string) error {
-	colorMode, _ := cmd.Flags().GetString("color")
-	useMarkdown := shouldUseMarkdown(colorMode)
-	debug, _ := cmd.Flags().GetBool("debug")
+func chatWithMarkdownContext(ctx context.Context, cmd *cobra.Command, client *desktop.Client, model, prompt string, imageURLs []string) error {
+	message, err := newUserMessage(prompt, imageURLs)
+	if err != nil {
+		return err
+	}
+	_, err = chatMessagesWithMarkdown(ctx, cmd, client, model, []desktop.OpenAIChatMessage{message})
+	return err
+}
 
+// newUserMessage creates a user message from a prompt, embedding the files and
+// images it references in addition to the given images.
+func newUserMessage(prompt string, imageURLs []string) (desktop.OpenAIChatMessage, error) {
 	// Process file inclusions first (files referenced with @ symbol)
 	prompt, err := processFileInclusions(prompt)
 	if err != nil {
-		return fmt.Errorf("failed to process file inclusions: %w", err)
+		return desktop.OpenAIChatMessage{}, fmt.Errorf("failed to process file inclusions: %w", err)
 	}
 
-	var imageURLs []string
-	cleanedPrompt, imgs, err := processImagesInPrompt(prompt)
+	cleanedPrompt, promptImageURLs, err := processImagesInPrompt(prompt)
 	if err != nil {
-		return fmt.Errorf("failed to process images: %w", err)
+		return desktop.OpenAIChatMessage{}, fmt.Errorf("failed to process images: %w", err)
 	}
-	prompt = cleanedPrompt
-	imageURLs = imgs
+	return desktop.NewUserMessage(cleanedPrompt, append(slices.Clone(imageURLs), promptImageURLs...)), nil
+}
+
+// chatMessagesWithMarkdown sends a conversation to the model and streams the
+// response with selective markdown rendering. It returns the reply.
+func chatMessagesWithMarkdown(ctx context.Context, cmd *cobra.Command, client *desktop.Client, model string, messages []desktop.OpenAIChatMessage) (string, error) {
+	colorMode, _ := cmd.Flags().GetString("color")
+	useMarkdown := shouldUseMarkdown(colorMode)
+	debug, _ := cmd.Flags().GetBool("debug")
 
 	if !useMarkdown {
 		// Simple case: just stream as plain text
-		return client.ChatWithContext(ctx, model, prompt, imageURLs, func(content string) {
+		return client.ChatMessages(ctx, model, messages, func(content string) {
 			cmd.Print(content)
 		}, false)
 	}
@@ -542,7 +566,7 @@ func chatWithMarkdownContext(ctx context.Context, cmd *cobra.Command, client *de
 	// For markdown: use streaming buffer to render code blocks as they complete
 	markdownBuffer := NewStreamingMarkdownBuffer()
 
-	err = client.ChatWithContext(ctx, model, prompt, imageURLs, func(content string) {
+	reply, err := client.ChatMessages(ctx, model, messages, func(content string) {
 		// Use the streaming markdown buffer to intelligently render content
 		rendered, err := markdownBuffer.AddContent(content, true)
 		if err != nil {
@@ -556,7 +580,7 @@ func chatWithMarkdownContext(ctx context.Context, cmd *cobra.Command, client *de
 		}
 	}, true)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Flush any remaining content from the markdown buffer
@@ -564,7 +588,7 @@ func chatWithMarkdownContext(ctx context.Context, cmd *cobra.Command, client *de
 		cmd.Print(remaining)
 	}
 
-	return nil
+	return reply, nil
 }
 
 func newRunCmd() *cobra.Command {
@@ -572,6 +596,7 @@ func newRunCmd() *cobra.Command {
 	var ignoreRuntimeMemoryCheck bool
 	var colorMode string
 	var detach bool
+	var images []string
 
 	const cmdArgs = "MODEL [PROMPT]"
 	c := &cobra.Command{
@@ -621,6 +646,10 @@ func newRunCmd() *cobra.Command {
 
 			// Check if this is an NVIDIA NIM image
 			if isNIMImage(model) {
+				if len(images) > 0 {
+					return errors.New("--image is not supported for NVIDIA NIM models")
+				}
+
 				// NIM images are handled differently - they run as Docker containers
 				// Create a Docker client
 				dockerCLI := getDockerCLI()
@@ -680,13 +709,30 @@ func newRunCmd() *cobra.Command {
 				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
 			}
 
-			_, err := desktopClient.Inspect(model, false)
+			info, err := desktopClient.Inspect(model, false)
 			if err != nil {
 				if !errors.Is(err, desktop.ErrNotFound) {
 					return handleClientError(err, "Failed to inspect model")
 				}
 				cmd.Println("Unable to find model '" + model + "' locally. Pulling from the server.")
-				if err := pullModel(cmd, desktopClient, model, ignoreRuntimeMemoryCheck); err != nil {
+				if err := pullModel(cmd, desktopClient, model, ignoreRuntimeMemoryCheck, false); err != nil {
+					return err
+				}
+				if len(images) > 0 {
+					if info, err = desktopClient.Inspect(model, false); err != nil {
+						return handleClientError(err, "Failed to inspect model")
+					}
+				}
+			}
+
+			// Images can only be attached for models with a multimodal
+			// projector.
+			var imageURLs []string
+			if len(images) > 0 {
+				if !info.Multimodal {
+					return fmt.Errorf("model %s does not support images: it has no multimodal projector", model)
+				}
+				if imageURLs, err = loadImages(cmd.Context(), images); err != nil {
 					return err
 				}
 			}
@@ -707,7 +753,7 @@ func newRunCmd() *cobra.Command {
 			}
 
 			if prompt != "" {
-				if err := chatWithMarkdown(cmd, desktopClient, model, prompt); err != nil {
+				if err := chatWithMarkdown(cmd, desktopClient, model, prompt, imageURLs); err != nil {
 					return handleClientError(err, "Failed to generate a response")
 				}
 				cmd.Println()
@@ -716,13 +762,13 @@ func newRunCmd() *cobra.Command {
 
 			// Use enhanced readline-based interactive mode when terminal is available
 			if term.IsTerminal(int(os.Stdin.Fd())) {
-				return generateInteractiveWithReadline(cmd, desktopClient, model)
+				return generateInteractiveWithReadline(cmd, desktopClient, model, imageURLs)
 			}
 
 			// Fall back to basic mode if not a terminal
-			return generateInteractiveBasic(cmd, desktopClient, model)
+			return generateInteractiveBasic(cmd, desktopClient, model, imageURLs)
 		},
-		ValidArgsFunction: completion.ModelNames(getDesktopClient, 1),
+		ValidArgsFunction: completion.ModelReferences(getDesktopClient, 1),
 	}
 	c.Args = func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
@@ -740,6 +786,8 @@ func newRunCmd() *cobra.Command {
 	c.Flags().BoolVar(&ignoreRuntimeMemoryCheck, "ignore-runtime-memory-check", false, "Do not block pull if estimated runtime memory for model exceeds system resources.")
 	c.Flags().StringVar(&colorMode, "color", "auto", "Use colored output (auto|yes|no)")
 	c.Flags().BoolVarP(&detach, "detach", "d", false, "Load the model in the background without interaction")
+	c.Flags().StringArrayVar(&images, "image", nil, "Attach an image file or URL to the prompt (can be repeated, requires a multimodal model)")
+	c.MarkFlagsMutuallyExclusive("detach", "image")
 
 	return c
 }
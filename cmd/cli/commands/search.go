@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
+	"github.com/docker/model-runner/pkg/distribution/search"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newSearchCmd() *cobra.Command {
+	var source string
+	var limit int
+	var jsonFormat bool
+	c := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search for models on Docker Hub and Hugging Face",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(
+					"'docker model search' requires 1 argument.\n\n" +
+						"Usage:  docker model search QUERY\n\n" +
+						"See 'docker model search --help' for more information",
+				)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd); err != nil {
+				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
+			}
+			response, err := desktopClient.Search(args[0], source, limit)
+			if err != nil {
+				return handleClientError(err, "Failed to search for models")
+			}
+			if jsonFormat {
+				output, err := formatter.ToStandardJSON(response)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
+				return nil
+			}
+			for _, err := range response.Errors {
+				cmd.PrintErrln("Warning:", err)
+			}
+			if len(response.Results) == 0 {
+				cmd.Printf("No models found matching %q\n", args[0])
+				return nil
+			}
+			cmd.Print(searchTable(response.Results))
+			return nil
+		},
+		ValidArgsFunction: completion.NoComplete,
+	}
+	c.Flags().StringVar(&source, "source", "", "Source to search: dockerhub, huggingface or all (defaults to dockerhub)")
+	c.Flags().IntVar(&limit, "limit", 0, fmt.Sprintf("Maximum number of results (defaults to %d)", search.DefaultLimit))
+	c.Flags().BoolVar(&jsonFormat, "json", false, "Print the results as JSON")
+	return c
+}
+
+func searchTable(results []search.Result) string {
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+
+	table.SetHeader([]string{"NAME", "DESCRIPTION", "DOWNLOADS", "QUANTIZATIONS"})
+
+	table.SetBorder(false)
+	table.SetColumnSeparator("")
+	table.SetHeaderLine(false)
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+	table.SetAutoWrapText(false)
+
+	table.SetColumnAlignment([]int{
+		tablewriter.ALIGN_LEFT,  // NAME
+		tablewriter.ALIGN_LEFT,  // DESCRIPTION
+		tablewriter.ALIGN_RIGHT, // DOWNLOADS
+		tablewriter.ALIGN_LEFT,  // QUANTIZATIONS
+	})
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, result := range results {
+		table.Append([]string{
+			result.Name,
+			truncateDescription(result.Description, 50),
+			units.CustomSize("%.4g%s", float64(result.Downloads), 1000.0, []string{"", "K", "M", "B"}),
+			strings.Join(result.Quantizations, ", "),
+		})
+	}
+
+	table.Render()
+	return buf.String()
+}
+
+// truncateDescription shortens a description to at most n runes.
+func truncateDescription(description string, n int) string {
+	runes := []rune(strings.TrimSpace(description))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:n-3])) + "..."
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/search"
+)
+
+func TestSearchTable(t *testing.T) {
+	output := searchTable([]search.Result{
+		{
+			Name:          "ai/llama3.2",
+			Description:   "Solid LLaMA language model for a wide range of tasks and chat applications",
+			Downloads:     1500000,
+			Quantizations: []string{"Q4_K_M", "Q8_0"},
+		},
+		{Name: "hf.co/org/repo-gguf", Downloads: 42},
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %q", output)
+	}
+	for _, want := range []string{"ai/llama3.2", "Solid LLaMA language model for a wide range of...", "1.5M", "Q4_K_M, Q8_0"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("Expected %q in row %q", want, lines[1])
+		}
+	}
+	if !strings.Contains(lines[2], "hf.co/org/repo-gguf") || !strings.Contains(lines[2], "42") {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
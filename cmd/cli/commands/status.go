@@ -8,16 +8,21 @@ import (
 
 	"github.com/docker/cli/cli-plugins/hooks"
 	"github.com/docker/model-runner/cmd/cli/commands/completion"
+	"github.com/docker/model-runner/cmd/cli/commands/formatter"
 	"github.com/docker/model-runner/cmd/cli/desktop"
 	"github.com/spf13/cobra"
 )
 
 func newStatusCmd() *cobra.Command {
 	var formatJson bool
+	var format string
 	c := &cobra.Command{
 		Use:   "status",
 		Short: "Check if the Docker Model Runner is running",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "" && formatJson {
+				return fmt.Errorf("--format flag cannot be used with --json flag")
+			}
 			standalone, err := ensureStandaloneRunnerAvailable(cmd.Context(), cmd)
 			if err != nil {
 				return fmt.Errorf("unable to initialize standalone model runner: %w", err)
@@ -36,8 +41,18 @@ func newStatusCmd() *cobra.Command {
 				cmd.PrintErrln(fmt.Errorf("failed to parse status response: %w", err))
 			}
 
-			if formatJson {
+			if formatJson || format == formatter.JSONFormat {
 				return jsonStatus(standalone, status, backendStatus)
+			} else if format != "" {
+				s, err := getRunnerStatus(standalone, status, backendStatus)
+				if err != nil {
+					return err
+				}
+				output, err := formatter.Format(format, s)
+				if err != nil {
+					return err
+				}
+				cmd.Print(output)
 			} else {
 				textStatus(cmd, status, backendStatus)
 			}
@@ -47,6 +62,7 @@ func newStatusCmd() *cobra.Command {
 		ValidArgsFunction: completion.NoComplete,
 	}
 	c.Flags().BoolVar(&formatJson, "json", false, "Format output in JSON")
+	addFormatFlag(c, &format)
 	return c
 }
 
@@ -61,17 +77,32 @@ func textStatus(cmd *cobra.Command, status desktop.Status, backendStatus map[str
 		}
 	} else {
 		cmd.Println("Docker Model Runner is not running")
-		hooks.PrintNextSteps(cmd.OutOrStdout(), []string{enableViaCLI, enableViaGUI})
+		hooks.PrintNextSteps(cmd.OutOrStdout(), notRunningNextSteps())
 		osExit(1)
 	}
 }
 
+// runnerStatus is the machine-readable status of the model runner.
+type runnerStatus struct {
+	Running  bool              `json:"running"`
+	Backends map[string]string `json:"backends"`
+	Endpoint string            `json:"endpoint"`
+}
+
 func jsonStatus(standalone *standaloneRunner, status desktop.Status, backendStatus map[string]string) error {
-	type Status struct {
-		Running  bool              `json:"running"`
-		Backends map[string]string `json:"backends"`
-		Endpoint string            `json:"endpoint"`
+	s, err := getRunnerStatus(standalone, status, backendStatus)
+	if err != nil {
+		return err
 	}
+	marshal, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(marshal))
+	return nil
+}
+
+func getRunnerStatus(standalone *standaloneRunner, status desktop.Status, backendStatus map[string]string) (runnerStatus, error) {
 	var endpoint string
 	kind := modelRunner.EngineKind()
 	switch kind {
@@ -92,19 +123,13 @@ func jsonStatus(standalone *standaloneRunner, status desktop.Status, backendStat
 
 		endpoint = fmt.Sprintf("http://%s:%d/engines/v1/", standalone.gatewayIP, standalone.gatewayPort)
 	default:
-		return fmt.Errorf("unhandled engine kind: %v", kind)
+		return runnerStatus{}, fmt.Errorf("unhandled engine kind: %v", kind)
 	}
-	s := Status{
+	return runnerStatus{
 		Running:  status.Running,
 		Backends: backendStatus,
 		Endpoint: endpoint,
-	}
-	marshal, err := json.Marshal(s)
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(marshal))
-	return nil
+	}, nil
 }
 
 var osExit = os.Exit
@@ -26,6 +26,7 @@ func TestStatus(t *testing.T) {
 		name           string
 		doResponse     *http.Response
 		doErr          error
+		attempts       int
 		expectExit     bool
 		expectedErr    error
 		expectedOutput string
@@ -39,9 +40,11 @@ func TestStatus(t *testing.T) {
 			expectedOutput: "Docker Model Runner is running\n",
 		},
 		{
-			name:        "not running",
-			doResponse:  &http.Response{StatusCode: http.StatusServiceUnavailable, Body: mockBody},
-			doErr:       nil,
+			name:       "not running",
+			doResponse: &http.Response{StatusCode: http.StatusServiceUnavailable, Body: mockBody},
+			doErr:      nil,
+			// Requests are retried while the model runner is unavailable.
+			attempts:    4,
 			expectExit:  true,
 			expectedErr: nil,
 			expectedOutput: func() string {
@@ -84,7 +87,7 @@ func TestStatus(t *testing.T) {
 			req, err := http.NewRequest(http.MethodGet, modelRunner.URL(inference.ModelsPrefix), nil)
 			require.NoError(t, err)
 			req.Header.Set("User-Agent", "docker-model-cli/"+desktop.Version)
-			client.EXPECT().Do(req).Return(test.doResponse, test.doErr)
+			client.EXPECT().Do(req).Return(test.doResponse, test.doErr).Times(max(test.attempts, 1))
 
 			if test.doResponse != nil && test.doResponse.StatusCode == http.StatusOK {
 				req, err = http.NewRequest(http.MethodGet, modelRunner.URL(inference.InferencePrefix+"/status"), nil)
@@ -12,6 +12,9 @@ import (
 func newUnloadCmd() *cobra.Command {
 	var all bool
 	var backend string
+	var mode string
+	var gpu int
+	var idleOnly bool
 
 	const cmdArgs = "(MODEL [MODEL ...] [--backend BACKEND] | --all)"
 	c := &cobra.Command{
@@ -23,19 +26,31 @@ func newUnloadCmd() *cobra.Command {
 			for i, model := range modelArgs {
 				normalizedModels[i] = models.NormalizeModelName(model)
 			}
-			unloadResp, err := desktopClient.Unload(desktop.UnloadRequest{All: all, Backend: backend, Models: normalizedModels})
+			request := desktop.UnloadRequest{All: all, Backend: backend, Models: normalizedModels, Mode: mode, IdleOnly: idleOnly}
+			if cmd.Flags().Changed("gpu") {
+				request.GPU = &gpu
+			}
+			unloadResp, err := desktopClient.Unload(request)
 			if err != nil {
 				return handleClientError(err, "Failed to unload models")
 			}
-			unloaded := unloadResp.UnloadedRunners
-			if unloaded == 0 {
+			if unloadResp.UnloadedRunners == 0 && unloadResp.DrainingRunners == 0 {
 				if all {
 					cmd.Println("No models are running.")
 				} else {
 					cmd.Println("No such model(s) running.")
 				}
-			} else {
-				cmd.Printf("Unloaded %d model(s).\n", unloaded)
+				return nil
+			}
+			for _, runner := range unloadResp.Runners {
+				cmd.Printf("Unloaded %s (%s, %s mode).\n", stripDefaultsFromModelName(runner.ModelName), runner.BackendName, runner.Mode)
+			}
+			// Older model runners only report the number of unloaded runners.
+			if len(unloadResp.Runners) == 0 && unloadResp.UnloadedRunners > 0 {
+				cmd.Printf("Unloaded %d model(s).\n", unloadResp.UnloadedRunners)
+			}
+			if unloadResp.DrainingRunners > 0 {
+				cmd.Printf("%d model(s) in use will be unloaded once their requests complete.\n", unloadResp.DrainingRunners)
 			}
 			return nil
 		},
@@ -63,5 +78,8 @@ func newUnloadCmd() *cobra.Command {
 	}
 	c.Flags().BoolVar(&all, "all", false, "Unload all running models")
 	c.Flags().StringVar(&backend, "backend", "", "Optional backend to target")
+	c.Flags().StringVar(&mode, "mode", "", "Only unload runners in this mode, such as completion or embedding")
+	c.Flags().IntVar(&gpu, "gpu", 0, "Only unload runners using the GPU with this index")
+	c.Flags().BoolVar(&idleOnly, "idle-only", false, "Only unload runners that aren't handling requests, rather than unloading the others once their requests complete")
 	return c
 }
@@ -8,7 +8,9 @@ import (
 
 	"github.com/docker/cli/cli-plugins/hooks"
 	"github.com/docker/model-runner/cmd/cli/desktop"
+	"github.com/docker/model-runner/cmd/cli/pkg/types"
 	"github.com/docker/model-runner/pkg/inference/backends/vllm"
+	"github.com/spf13/cobra"
 )
 
 const (
@@ -20,15 +22,40 @@ const (
 	enableViaCLI = "Enable Docker Model Runner via the CLI → docker desktop enable model-runner"
 	enableViaGUI = "Enable Docker Model Runner via the GUI → Go to Settings->AI->Enable Docker Model Runner"
 	enableVLLM   = "It looks like you're trying to use a model for vLLM → docker model install-runner --vllm"
+	startRunner  = "Start Docker Model Runner via the CLI → docker model start-runner"
+	checkHost    = "Check that the model runner at MODEL_RUNNER_HOST is running and reachable"
+	startDocker  = "Start Docker Desktop via the CLI → docker desktop start"
 )
 
 var notRunningErr = fmt.Errorf("Docker Model Runner is not running. Please start it and try again.\n")
 
+var dockerNotRunningErr = fmt.Errorf("Docker is not running. Please start Docker Desktop and try again.\n")
+
+// notRunningNextSteps returns the steps to get the model runner running, which
+// depend on the engine hosting it.
+func notRunningNextSteps() []string {
+	if modelRunner == nil {
+		return []string{enableViaCLI, enableViaGUI}
+	}
+	switch modelRunner.EngineKind() {
+	case types.ModelRunnerEngineKindMoby, types.ModelRunnerEngineKindCloud:
+		return []string{startRunner}
+	case types.ModelRunnerEngineKindMobyManual:
+		return []string{checkHost}
+	default:
+		return []string{enableViaCLI, enableViaGUI}
+	}
+}
+
 func handleClientError(err error, message string) error {
-	if errors.Is(err, desktop.ErrServiceUnavailable) {
+	if errors.Is(err, desktop.ErrDockerNotRunning) {
+		var buf bytes.Buffer
+		hooks.PrintNextSteps(&buf, []string{startDocker})
+		return fmt.Errorf("%w\n%s", dockerNotRunningErr, strings.TrimRight(buf.String(), "\n"))
+	} else if errors.Is(err, desktop.ErrServiceUnavailable) {
 		err = notRunningErr
 		var buf bytes.Buffer
-		hooks.PrintNextSteps(&buf, []string{enableViaCLI, enableViaGUI})
+		hooks.PrintNextSteps(&buf, notRunningNextSteps())
 		return fmt.Errorf("%w\n%s", err, strings.TrimRight(buf.String(), "\n"))
 	} else if strings.Contains(err.Error(), vllm.StatusNotFound.Error()) {
 		// Handle `run` error.
@@ -60,3 +87,9 @@ func stripDefaultsFromModelName(model string) string {
 	// For other cases (ai/ with custom tag, custom org with :latest, etc.), keep as-is
 	return model
 }
+
+// addFormatFlag adds the --format flag, which replaces a command's default
+// output with JSON or the output of a Go template.
+func addFormatFlag(c *cobra.Command, format *string) {
+	c.Flags().StringVar(format, "format", "", `Format output as "json" or with a Go template, such as '{{json .}}'`)
+}
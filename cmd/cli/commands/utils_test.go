@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/docker/model-runner/cmd/cli/desktop"
 	"github.com/docker/model-runner/pkg/inference/models"
 )
 
@@ -72,6 +74,21 @@ func TestNormalizeModelName(t *testing.T) {
 			input:    "gemma3:latest",
 			expected: "ai/gemma3:latest",
 		},
+		{
+			name:     "digest reference",
+			input:    "gemma3@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			expected: "ai/gemma3@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		{
+			name:     "registry with digest reference",
+			input:    "docker.io/library/model@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			expected: "docker.io/library/model@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		{
+			name:     "model ID",
+			input:    "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			expected: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
 	}
 
 	for _, tt := range tests {
@@ -194,3 +211,17 @@ func TestNormalizeModelNameConsistency(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleClientErrorOffline(t *testing.T) {
+	defer func(original *desktop.ModelRunnerContext) { modelRunner = original }(modelRunner)
+	modelRunner = desktop.NewContextForMock(nil)
+
+	err := handleClientError(desktop.ErrDockerNotRunning, "Failed to list models")
+	if !strings.Contains(err.Error(), "Docker is not running") || !strings.Contains(err.Error(), startDocker) {
+		t.Errorf("Expected a Docker not running error with next steps, got %q", err)
+	}
+	err = handleClientError(desktop.ErrServiceUnavailable, "Failed to list models")
+	if !strings.Contains(err.Error(), "Docker Model Runner is not running") || !strings.Contains(err.Error(), enableViaCLI) {
+		t.Errorf("Expected a model runner not running error with next steps, got %q", err)
+	}
+}
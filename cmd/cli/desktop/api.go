@@ -7,6 +7,17 @@ type ProgressMessage struct {
 	Total   uint64 `json:"total"`
 	Pulled  uint64 `json:"pulled"` // Deprecated: use Layer.Current
 	Layer   Layer  `json:"layer"`  // Current layer information
+	// RateLimit is the registry's pull quota, set on progress messages that
+	// report it rather than download progress.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// RateLimit is the pull quota of a registry.
+type RateLimit struct {
+	Registry  string `json:"registry"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	RetryIn   int    `json:"retry_in,omitempty"` // Seconds until a rate-limited pull is retried
 }
 
 type Layer struct {
@@ -45,9 +56,10 @@ type OpenAIChatResponse struct {
 	Model   string `json:"model"`
 	Choices []struct {
 		Delta struct {
-			Content          string `json:"content"`
-			Role             string `json:"role,omitempty"`
-			ReasoningContent string `json:"reasoning_content,omitempty"`
+			Content          string     `json:"content"`
+			Role             string     `json:"role,omitempty"`
+			ReasoningContent string     `json:"reasoning_content,omitempty"`
+			ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		Index        int    `json:"index"`
 		FinishReason string `json:"finish_reason"`
@@ -58,3 +70,16 @@ type OpenAIChatResponse struct {
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage,omitempty"`
 }
+
+// ToolCall represents a call of a function tool by the model. When streaming,
+// a tool call is split across deltas sharing its index: the first carries the
+// ID and function name, and the arguments follow in fragments.
+type ToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
@@ -151,6 +151,11 @@ func DetectContext(ctx context.Context, cli *command.DockerCli) (*ModelRunnerCon
 			return nil, fmt.Errorf("unable to create model runner client: %w", err)
 		}
 		client = dockerClient.HTTPClient()
+	} else if apiKey := os.Getenv("MODEL_RUNNER_API_KEY"); apiKey != "" && kind == types.ModelRunnerEngineKindMobyManual {
+		client = &http.Client{Transport: &apiKeyTransport{
+			apiKey:    apiKey,
+			transport: http.DefaultTransport,
+		}}
 	} else {
 		client = http.DefaultClient
 	}
@@ -223,3 +228,16 @@ func (u *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error
 
 	return u.transport.RoundTrip(reqClone)
 }
+
+// apiKeyTransport authenticates requests to a model runner that requires API
+// keys.
+type apiKeyTransport struct {
+	apiKey    string
+	transport http.RoundTripper
+}
+
+func (a *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqClone := req.Clone(req.Context())
+	reqClone.Header.Set("Authorization", "Bearer "+a.apiKey)
+	return a.transport.RoundTrip(reqClone)
+}
@@ -8,17 +8,21 @@ import (
 	"fmt"
 	"html"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/docker/go-units"
+	"github.com/docker/model-runner/cmd/cli/pkg/types"
 	"github.com/docker/model-runner/pkg/distribution/distribution"
 	"github.com/docker/model-runner/pkg/inference"
 	dmrm "github.com/docker/model-runner/pkg/inference/models"
 	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/docker/model-runner/pkg/metrics"
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
@@ -27,6 +31,23 @@ import (
 var (
 	ErrNotFound           = errors.New("model not found")
 	ErrServiceUnavailable = errors.New("service unavailable")
+	// ErrDockerNotRunning indicates that Docker Desktop can't be reached, as
+	// opposed to ErrServiceUnavailable, which indicates that Docker Model
+	// Runner isn't enabled or running.
+	ErrDockerNotRunning = errors.New("Docker is not running")
+)
+
+const (
+	// maximumRequestAttempts is the number of times a request is attempted
+	// while the model runner responds that it's unavailable, which it does
+	// transiently while starting up or installing backends.
+	maximumRequestAttempts = 4
+	// initialRetryDelay is the delay before the first retry of a request,
+	// which doubles with each retry.
+	initialRetryDelay = 200 * time.Millisecond
+	// maximumRetryDelay caps the delay before retrying a request, including
+	// delays requested through Retry-After headers.
+	maximumRetryDelay = 5 * time.Second
 )
 
 type otelErrorSilencer struct{}
@@ -97,9 +118,25 @@ func (c *Client) Status() Status {
 	}
 }
 
-func (c *Client) Pull(model string, ignoreRuntimeMemoryCheck bool, progress func(string)) (string, bool, error) {
+// LicenseError indicates that a model can't be pulled until its license is
+// accepted.
+type LicenseError struct {
+	Model string
+	// License is the text of the license to accept.
+	License string
+}
+
+func (e *LicenseError) Error() string {
+	return fmt.Sprintf("pulling %s requires accepting its license", e.Model)
+}
+
+func (c *Client) Pull(model string, ignoreRuntimeMemoryCheck, acceptLicense bool, progress func(string)) (string, bool, error) {
 	model = dmrm.NormalizeModelName(model)
-	jsonData, err := json.Marshal(dmrm.ModelCreateRequest{From: model, IgnoreRuntimeMemoryCheck: ignoreRuntimeMemoryCheck})
+	jsonData, err := json.Marshal(dmrm.ModelCreateRequest{
+		From:                     model,
+		IgnoreRuntimeMemoryCheck: ignoreRuntimeMemoryCheck,
+		AcceptLicense:            acceptLicense,
+	})
 	if err != nil {
 		return "", false, fmt.Errorf("error marshaling request: %w", err)
 	}
@@ -115,8 +152,20 @@ func (c *Client) Pull(model string, ignoreRuntimeMemoryCheck bool, progress func
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnavailableForLegalReasons {
+		body, _ := io.ReadAll(resp.Body)
+		return "", false, &LicenseError{Model: model, License: strings.TrimSpace(string(body))}
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		// Some errors, such as a lack of disk space, are JSON with a message
+		// for display.
+		var jsonErr struct {
+			Message string `json:"message"`
+		}
+		if resp.Header.Get("Content-Type") == "application/json" && json.Unmarshal(body, &jsonErr) == nil && jsonErr.Message != "" {
+			body = []byte(jsonErr.Message)
+		}
 		return "", false, fmt.Errorf("pulling %s failed with status %s: %s", model, resp.Status, string(body))
 	}
 
@@ -140,6 +189,14 @@ func (c *Client) Pull(model string, ignoreRuntimeMemoryCheck bool, progress func
 		// Handle different message types
 		switch progressMsg.Type {
 		case "progress":
+			// Show the registry's remaining pull quota, or the wait for its
+			// rate limit
+			if progressMsg.RateLimit != nil {
+				progress(progressMsg.Message)
+				progressShown = true
+				continue
+			}
+
 			// Update the current progress for this layer
 			layerID := progressMsg.Layer.ID
 			layerProgress[layerID] = progressMsg.Layer.Current
@@ -347,6 +404,46 @@ func (c *Client) Chat(model, prompt string, imageURLs []string, outputFunc func(
 
 // ChatWithContext performs a chat request with context support for cancellation and streams the response content with selective markdown rendering.
 func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imageURLs []string, outputFunc func(string), shouldUseMarkdown bool) error {
+	_, err := c.ChatMessages(ctx, model, []OpenAIChatMessage{NewUserMessage(prompt, imageURLs)}, outputFunc, shouldUseMarkdown)
+	return err
+}
+
+// NewUserMessage creates a user chat message with the given prompt and images.
+func NewUserMessage(prompt string, imageURLs []string) OpenAIChatMessage {
+	if len(imageURLs) == 0 {
+		// Simple text-only message
+		return OpenAIChatMessage{Role: "user", Content: prompt}
+	}
+
+	// Multimodal message with images
+	contentParts := make([]ContentPart, 0, len(imageURLs)+1)
+
+	// Add all images first
+	for _, imageURL := range imageURLs {
+		contentParts = append(contentParts, ContentPart{
+			Type: "image_url",
+			ImageURL: &ImageURL{
+				URL: imageURL,
+			},
+		})
+	}
+
+	// Add text prompt if present
+	if prompt != "" {
+		contentParts = append(contentParts, ContentPart{
+			Type: "text",
+			Text: prompt,
+		})
+	}
+
+	return OpenAIChatMessage{Role: "user", Content: contentParts}
+}
+
+// ChatMessages performs a chat request for a conversation and streams the
+// response content with selective markdown rendering. It returns the content
+// of the assistant's reply, excluding any reasoning, so that it can be added
+// to the conversation.
+func (c *Client) ChatMessages(ctx context.Context, model string, messages []OpenAIChatMessage, outputFunc func(string), shouldUseMarkdown bool) (string, error) {
 	model = dmrm.NormalizeModelName(model)
 	if !strings.Contains(strings.Trim(model, "/"), "/") {
 		// Do an extra API call to check if the model parameter isn't a model ID.
@@ -355,50 +452,15 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 		}
 	}
 
-	// Build the message content - either simple string or multimodal array
-	var messageContent interface{}
-	if len(imageURLs) > 0 {
-		// Multimodal message with images
-		contentParts := make([]ContentPart, 0, len(imageURLs))
-
-		// Add all images first
-		for _, imageURL := range imageURLs {
-			contentParts = append(contentParts, ContentPart{
-				Type: "image_url",
-				ImageURL: &ImageURL{
-					URL: imageURL,
-				},
-			})
-		}
-
-		// Add text prompt if present
-		if prompt != "" {
-			contentParts = append(contentParts, ContentPart{
-				Type: "text",
-				Text: prompt,
-			})
-		}
-
-		messageContent = contentParts
-	} else {
-		// Simple text-only message
-		messageContent = prompt
-	}
-
 	reqBody := OpenAIChatRequest{
-		Model: model,
-		Messages: []OpenAIChatMessage{
-			{
-				Role:    "user",
-				Content: messageContent,
-			},
-		},
-		Stream: true,
+		Model:    model,
+		Messages: messages,
+		Stream:   true,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("error marshaling request: %w", err)
+		return "", fmt.Errorf("error marshaling request: %w", err)
 	}
 
 	completionsPath := inference.InferencePrefix + "/v1/chat/completions"
@@ -410,13 +472,13 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 		bytes.NewReader(jsonData),
 	)
 	if err != nil {
-		return c.handleQueryError(err, completionsPath)
+		return "", c.handleQueryError(err, completionsPath)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("error response: status=%d body=%s", resp.StatusCode, body)
+		return "", fmt.Errorf("error response: status=%d body=%s", resp.StatusCode, body)
 	}
 
 	type chatPrinterState int
@@ -435,12 +497,14 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 		TotalTokens      int `json:"total_tokens"`
 	}
 
+	var reply strings.Builder
+	var toolCalls []ToolCall
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		// Check if context was cancelled
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return "", ctx.Err()
 		default:
 		}
 
@@ -461,7 +525,7 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 
 		var streamResp OpenAIChatResponse
 		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-			return fmt.Errorf("error parsing stream response: %w", err)
+			return "", fmt.Errorf("error parsing stream response: %w", err)
 		}
 
 		if streamResp.Usage != nil {
@@ -495,13 +559,26 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 					outputFunc("\n\n--\n\n")
 				}
 				printerState = chatPrinterContent
+				reply.WriteString(chunk)
 				outputFunc(chunk)
 			}
+			toolCalls = mergeToolCalls(toolCalls, streamResp.Choices[0].Delta.ToolCalls)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading response stream: %w", err)
+		return "", fmt.Errorf("error reading response stream: %w", err)
+	}
+
+	// Tool calls are printed once complete, as their arguments are streamed
+	// in fragments.
+	for i, toolCall := range toolCalls {
+		if i > 0 {
+			outputFunc("\n")
+		} else if printerState != chatPrinterNone {
+			outputFunc("\n\n")
+		}
+		outputFunc(fmt.Sprintf("Tool call: %s(%s)", toolCall.Function.Name, toolCall.Function.Arguments))
 	}
 
 	if finalUsage != nil {
@@ -517,7 +594,32 @@ func (c *Client) ChatWithContext(ctx context.Context, model, prompt string, imag
 		outputFunc(usageFmt.Sprint(usageInfo))
 	}
 
-	return nil
+	return reply.String(), nil
+}
+
+// mergeToolCalls merges streamed tool call deltas into the tool calls
+// received so far, concatenating the argument fragments of each call.
+func mergeToolCalls(toolCalls []ToolCall, deltas []ToolCall) []ToolCall {
+	for _, delta := range deltas {
+		if delta.Index < 0 {
+			continue
+		}
+		for len(toolCalls) <= delta.Index {
+			toolCalls = append(toolCalls, ToolCall{Index: len(toolCalls), Type: "function"})
+		}
+		toolCall := &toolCalls[delta.Index]
+		if delta.ID != "" {
+			toolCall.ID = delta.ID
+		}
+		if delta.Type != "" {
+			toolCall.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			toolCall.Function.Name = delta.Function.Name
+		}
+		toolCall.Function.Arguments += delta.Function.Arguments
+	}
+	return toolCalls
 }
 
 func (c *Client) Remove(modelArgs []string, force bool) (string, error) {
@@ -564,6 +666,9 @@ func (c *Client) Remove(modelArgs []string, force bool) (string, error) {
 					if msg.Deleted != nil {
 						modelRemoved += fmt.Sprintf("Deleted: %s\n", *msg.Deleted)
 					}
+					if msg.Warning != nil {
+						modelRemoved += fmt.Sprintf("Warning: %s\n", *msg.Warning)
+					}
 				}
 			}
 		} else {
@@ -584,14 +689,40 @@ type BackendStatus struct {
 	ModelName string `json:"model_name"`
 	// Mode is the mode the backend is operating in
 	Mode string `json:"mode"`
+	// Replica distinguishes the runners loaded for the same model
+	Replica int `json:"replica,omitempty"`
 	// LastUsed represents when this backend was last used (if it's idle)
 	LastUsed time.Time `json:"last_used,omitempty"`
 	// InUse indicates whether this backend is currently handling a request
 	InUse bool `json:"in_use,omitempty"`
+	// RAMBytes and VRAMBytes are the memory allocated to the runner, if known
+	RAMBytes  uint64 `json:"ram_bytes,omitempty"`
+	VRAMBytes uint64 `json:"vram_bytes,omitempty"`
+	// GPUs are the indices of the GPUs on which the runner's VRAM is allocated
+	GPUs []int `json:"gpus,omitempty"`
+	// LoadedAt is when the runner finished loading
+	LoadedAt time.Time `json:"loaded_at,omitempty"`
+	// LoadSeconds is the time the runner took to load
+	LoadSeconds float64 `json:"load_seconds,omitempty"`
+	// UptimeSeconds is the time since the runner finished loading
+	UptimeSeconds float64 `json:"uptime_seconds,omitempty"`
+	// InFlight is the number of requests being served by the runner
+	InFlight int `json:"in_flight,omitempty"`
+	// Queued is the number of requests waiting for the runner to admit them
+	Queued int `json:"queued,omitempty"`
+	// TokensPerSecond is a moving average of the runner's completion throughput
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	// Crash describes why the backend exited, if it crashed
+	Crash *scheduling.CrashReport `json:"crash,omitempty"`
 }
 
-func (c *Client) PS() ([]BackendStatus, error) {
+// PS lists the running backends, followed by the most recently crashed ones
+// if includeCrashes is set.
+func (c *Client) PS(includeCrashes bool) ([]BackendStatus, error) {
 	psPath := inference.InferencePrefix + "/ps"
+	if includeCrashes {
+		psPath += "?include_crashes=true"
+	}
 	resp, err := c.doRequest(http.MethodGet, psPath, nil)
 	if err != nil {
 		return []BackendStatus{}, c.handleQueryError(err, psPath)
@@ -640,14 +771,19 @@ func (c *Client) DF() (DiskUsage, error) {
 
 // UnloadRequest to be imported from docker/model-runner when https://github.com/docker/model-runner/pull/46 is merged.
 type UnloadRequest struct {
-	All     bool     `json:"all"`
-	Backend string   `json:"backend"`
-	Models  []string `json:"models"`
+	All      bool     `json:"all"`
+	Backend  string   `json:"backend"`
+	Models   []string `json:"models"`
+	Mode     string   `json:"mode,omitempty"`
+	GPU      *int     `json:"gpu,omitempty"`
+	IdleOnly bool     `json:"idle_only,omitempty"`
 }
 
 // UnloadResponse to be imported from docker/model-runner when https://github.com/docker/model-runner/pull/46 is merged.
 type UnloadResponse struct {
-	UnloadedRunners int `json:"unloaded_runners"`
+	UnloadedRunners int             `json:"unloaded_runners"`
+	Runners         []BackendStatus `json:"runners,omitempty"`
+	DrainingRunners int             `json:"draining_runners,omitempty"`
 }
 
 func (c *Client) Unload(req UnloadRequest) (UnloadResponse, error) {
@@ -705,6 +841,202 @@ func (c *Client) ConfigureBackend(request scheduling.ConfigureRequest) error {
 	return nil
 }
 
+// CheckModel assesses whether a model can run on the current hardware without
+// pulling it. A zero context size uses the model's default.
+func (c *Client) CheckModel(model string, contextSize int64) (inference.ModelCheck, error) {
+	query := url.Values{"model": {model}}
+	if contextSize > 0 {
+		query.Set("context-size", strconv.FormatInt(contextSize, 10))
+	}
+	checkPath := inference.InferencePrefix + "/_check?" + query.Encode()
+	resp, err := c.doRequest(http.MethodGet, checkPath, nil)
+	if err != nil {
+		return inference.ModelCheck{}, c.handleQueryError(err, checkPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return inference.ModelCheck{}, errors.Wrap(ErrNotFound, model)
+		}
+		return inference.ModelCheck{}, fmt.Errorf("checking %s failed with status %s: %s", model, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var check inference.ModelCheck
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		return inference.ModelCheck{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return check, nil
+}
+
+// LoadRunner loads a runner for a model with a backend in the given mode (or
+// completion mode if empty), and returns once the runner is ready to serve
+// requests.
+func (c *Client) LoadRunner(ctx context.Context, backend, model, mode string) error {
+	model = dmrm.NormalizeModelName(model)
+	loadPath := inference.InferencePrefix + "/" + backend + "/" + model + "/load"
+	if mode != "" {
+		loadPath += "?" + url.Values{"mode": {mode}}.Encode()
+	}
+	resp, err := c.doRequestWithAuthContext(ctx, http.MethodPost, loadPath, nil)
+	if err != nil {
+		return c.handleQueryError(err, loadPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("loading %s failed with status %s: %s", model, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Benchmark loads a model and measures its prompt processing and generation
+// throughput, which can take a while for large models.
+func (c *Client) Benchmark(ctx context.Context, model string, request scheduling.BenchmarkRequest) (scheduling.BenchmarkResult, error) {
+	model = dmrm.NormalizeModelName(model)
+	benchmarkPath := inference.InferencePrefix + "/" + model + "/benchmark"
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return scheduling.BenchmarkResult{}, fmt.Errorf("error marshaling request: %w", err)
+	}
+	resp, err := c.doRequestWithAuthContext(ctx, http.MethodPost, benchmarkPath, bytes.NewReader(jsonData))
+	if err != nil {
+		return scheduling.BenchmarkResult{}, c.handleQueryError(err, benchmarkPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return scheduling.BenchmarkResult{}, errors.Wrap(ErrNotFound, model)
+		}
+		return scheduling.BenchmarkResult{}, fmt.Errorf("benchmarking %s failed with status %s: %s", model, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result scheduling.BenchmarkResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return scheduling.BenchmarkResult{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return result, nil
+}
+
+// Search searches for models matching query in the given source, which may be
+// empty to search the default source.
+func (c *Client) Search(query, source string, limit int) (dmrm.ModelSearchResponse, error) {
+	params := url.Values{"q": {query}}
+	if source != "" {
+		params.Set("source", source)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	searchPath := inference.ModelsPrefix + "/search?" + params.Encode()
+	resp, err := c.doRequest(http.MethodGet, searchPath, nil)
+	if err != nil {
+		return dmrm.ModelSearchResponse{}, c.handleQueryError(err, searchPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return dmrm.ModelSearchResponse{}, fmt.Errorf("searching for %q failed with status %s: %s", query, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var response dmrm.ModelSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return dmrm.ModelSearchResponse{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return response, nil
+}
+
+// ConfigureModel creates a lightweight variant of a model with the given chat
+// template and context size, without re-downloading its weights.
+func (c *Client) ConfigureModel(model string, request dmrm.ModelConfigRequest) error {
+	configPath := inference.ModelsPrefix + "/" + model + "/config"
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	resp, err := c.doRequest(http.MethodPatch, configPath, bytes.NewReader(jsonData))
+	if err != nil {
+		return c.handleQueryError(err, configPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s (%s)", strings.TrimSpace(string(body)), resp.Status)
+	}
+
+	return nil
+}
+
+// Aliases returns the model aliases, sorted by name.
+func (c *Client) Aliases() ([]dmrm.ModelAlias, error) {
+	aliasesPath := inference.ModelsPrefix + "/_aliases"
+	resp, err := c.doRequest(http.MethodGet, aliasesPath, nil)
+	if err != nil {
+		return nil, c.handleQueryError(err, aliasesPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("listing aliases failed with status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var aliases []dmrm.ModelAlias
+	if err := json.NewDecoder(resp.Body).Decode(&aliases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return aliases, nil
+}
+
+// SetAlias points an alias at a model, creating the alias if needed.
+func (c *Client) SetAlias(alias, model string) (dmrm.ModelAlias, error) {
+	aliasPath := inference.ModelsPrefix + "/_aliases/" + alias
+	jsonData, err := json.Marshal(dmrm.ModelAliasRequest{Model: model})
+	if err != nil {
+		return dmrm.ModelAlias{}, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	resp, err := c.doRequest(http.MethodPut, aliasPath, bytes.NewReader(jsonData))
+	if err != nil {
+		return dmrm.ModelAlias{}, c.handleQueryError(err, aliasPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return dmrm.ModelAlias{}, fmt.Errorf("%s (%s)", strings.TrimSpace(string(body)), resp.Status)
+	}
+
+	var result dmrm.ModelAlias
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return dmrm.ModelAlias{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return result, nil
+}
+
+// RemoveAlias removes an alias, leaving the model it stands for as is.
+func (c *Client) RemoveAlias(alias string) error {
+	aliasPath := inference.ModelsPrefix + "/_aliases/" + alias
+	resp, err := c.doRequest(http.MethodDelete, aliasPath, nil)
+	if err != nil {
+		return c.handleQueryError(err, aliasPath)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s (%s)", strings.TrimSpace(string(body)), resp.Status)
+	}
+	return nil
+}
+
 // Requests returns a response body and a cancel function to ensure proper cleanup.
 func (c *Client) Requests(modelFilter string, streaming bool, includeExisting bool) (io.ReadCloser, func(), error) {
 	path := c.modelRunner.URL(inference.InferencePrefix + "/requests")
@@ -762,6 +1094,79 @@ func (c *Client) Requests(modelFilter string, streaming bool, includeExisting bo
 	return resp.Body, cancel, nil
 }
 
+// ExportRequests returns the request and response pairs captured by the model
+// runner, or the recent ones if capture is disabled, as JSON lines, optionally
+// restricted to those of a model. The caller must close the returned body.
+func (c *Client) ExportRequests(ctx context.Context, model string) (io.ReadCloser, error) {
+	exportPath := inference.InferencePrefix + "/requests/export"
+	if model != "" {
+		exportPath += "?model=" + url.QueryEscape(dmrm.NormalizeModelName(model))
+	}
+	resp, err := c.doRequestWithAuthContext(ctx, http.MethodGet, exportPath, nil)
+	if err != nil {
+		return nil, c.handleQueryError(err, exportPath)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to export requests: %s (%s)", strings.TrimSpace(string(body)), resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// ReplayRequest re-issues a captured request and returns the new request and
+// response pair, which keeps the ID of the captured one so that they can be
+// compared. Requests that fail with an error status aren't an error.
+func (c *Client) ReplayRequest(ctx context.Context, record metrics.RequestResponsePair) (metrics.RequestResponsePair, error) {
+	replayed := metrics.RequestResponsePair{
+		ID:        record.ID,
+		Model:     record.Model,
+		Method:    record.Method,
+		URL:       record.URL,
+		Request:   record.Request,
+		Timestamp: time.Now().Unix(),
+		UserAgent: "docker-model-cli/" + Version,
+	}
+	resp, err := c.doRequestWithAuthContext(ctx, record.Method, record.URL, strings.NewReader(record.Request))
+	if err != nil {
+		return replayed, c.handleQueryError(err, record.URL)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return replayed, fmt.Errorf("failed to read response body: %w", err)
+	}
+	replayed.StatusCode = resp.StatusCode
+	if resp.StatusCode >= http.StatusBadRequest {
+		replayed.Error = strings.TrimSpace(string(body))
+	} else {
+		replayed.Response = string(body)
+	}
+	return replayed, nil
+}
+
+// RunnerLogs returns the recent output of the backends of the runners loaded
+// for a model. If follow is set, the output keeps streaming until the runners
+// are unloaded or ctx is cancelled. The caller must close the returned body.
+func (c *Client) RunnerLogs(ctx context.Context, model string, follow bool) (io.ReadCloser, error) {
+	model = dmrm.NormalizeModelName(model)
+	logsPath := inference.InferencePrefix + "/" + model + "/logs"
+	if follow {
+		logsPath += "?follow=true"
+	}
+	resp, err := c.doRequestWithAuthContext(ctx, http.MethodGet, logsPath, nil)
+	if err != nil {
+		return nil, c.handleQueryError(err, logsPath)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to get logs of %s: %s", model, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
 func (c *Client) Purge() error {
 	purgePath := inference.ModelsPrefix + "/purge"
 	resp, err := c.doRequest(http.MethodDelete, purgePath, nil)
@@ -799,26 +1204,69 @@ func (c *Client) doRequestWithAuthContext(ctx context.Context, method, path stri
 
 	req.Header.Set("User-Agent", "docker-model-cli/"+Version)
 
-	resp, err := c.modelRunner.Client().Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode == http.StatusServiceUnavailable {
+	// Requests are retried with backoff while the model runner is unavailable,
+	// unless their body can't be replayed.
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := c.modelRunner.Client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
 		resp.Body.Close()
-		return nil, ErrServiceUnavailable
+		if attempt == maximumRequestAttempts || (body != nil && req.GetBody == nil) {
+			return nil, ErrServiceUnavailable
+		}
+
+		wait := retryAfter(resp.Header.Get("Retry-After"), delay)
+		delay *= 2
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("error replaying request: %w", err)
+			}
+		}
 	}
+}
 
-	return resp, nil
+// retryAfter returns the delay before retrying a request, which is the delay
+// requested by the server through a Retry-After header in seconds if longer
+// than the backoff delay, capped at maximumRetryDelay.
+func retryAfter(header string, delay time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && time.Duration(seconds)*time.Second > delay {
+		delay = time.Duration(seconds) * time.Second
+	}
+	return min(delay, maximumRetryDelay)
 }
 
 func (c *Client) handleQueryError(err error, path string) error {
 	if errors.Is(err, ErrServiceUnavailable) {
 		return ErrServiceUnavailable
 	}
+	if isConnectionFailure(err) {
+		// Docker Desktop proxies requests to the model runner, so failing
+		// to connect means that Docker itself isn't running, whereas other
+		// engines serve the model runner directly.
+		if c.modelRunner.EngineKind() == types.ModelRunnerEngineKindDesktop {
+			return ErrDockerNotRunning
+		}
+		return ErrServiceUnavailable
+	}
 	return fmt.Errorf("error querying %s: %w", path, err)
 }
 
+// isConnectionFailure returns whether an error indicates that nothing is
+// listening at the model runner's address, or at the Docker socket.
+func isConnectionFailure(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, fs.ErrNotExist)
+}
+
 // normalizeHuggingFaceModelName converts Hugging Face model names to lowercase
 func normalizeHuggingFaceModelName(model string) string {
 	if strings.HasPrefix(model, "hf.co/") {
@@ -2,12 +2,19 @@ package desktop
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
 	"testing"
 
 	mockdesktop "github.com/docker/model-runner/cmd/cli/mocks"
+	"github.com/docker/model-runner/cmd/cli/pkg/types"
 	"github.com/docker/model-runner/pkg/inference/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,7 +43,7 @@ func TestPullHuggingFaceModel(t *testing.T) {
 		Body:       io.NopCloser(bytes.NewBufferString(`{"type":"success","message":"Model pulled successfully"}`)),
 	}, nil)
 
-	_, _, err := client.Pull(modelName, false, func(s string) {})
+	_, _, err := client.Pull(modelName, false, false, func(s string) {})
 	assert.NoError(t, err)
 }
 
@@ -67,6 +74,68 @@ func TestChatHuggingFaceModel(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestChatMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mockdesktop.NewMockDockerHttpClient(ctrl)
+	mockContext := NewContextForMock(mockClient)
+	client := New(mockContext)
+
+	messages := []OpenAIChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello!"},
+		NewUserMessage("How are you?", nil),
+	}
+	mockClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		var reqBody OpenAIChatRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		require.NoError(t, err)
+		require.Len(t, reqBody.Messages, 4)
+		assert.Equal(t, "system", reqBody.Messages[0].Role)
+		assert.Equal(t, "How are you?", reqBody.Messages[3].Content)
+	}).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(bytes.NewBufferString(
+			"data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"Hmm.\"}}]}\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"Fine, \"}}]}\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"thanks.\"}}]}\n" +
+				"data: [DONE]\n")),
+	}, nil)
+
+	reply, err := client.ChatMessages(t.Context(), "ai/smollm2", messages, func(s string) {}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Fine, thanks.", reply)
+}
+
+func TestChatMessagesToolCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mockdesktop.NewMockDockerHttpClient(ctrl)
+	mockContext := NewContextForMock(mockClient)
+	client := New(mockContext)
+
+	mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(bytes.NewBufferString(
+			"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":null,\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"\"}}]}}]}\n" +
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n" +
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"Paris\\\"}\"}}]}}]}\n" +
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":1,\"id\":\"call_2\",\"type\":\"function\",\"function\":{\"name\":\"get_time\",\"arguments\":\"{}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n" +
+				"data: [DONE]\n")),
+	}, nil)
+
+	var output strings.Builder
+	reply, err := client.ChatMessages(t.Context(), "ai/smollm2", []OpenAIChatMessage{NewUserMessage("Weather?", nil)}, func(s string) {
+		output.WriteString(s)
+	}, false)
+	require.NoError(t, err)
+	assert.Empty(t, reply)
+	assert.Equal(t, "Tool call: get_weather({\"city\":\"Paris\"})\nTool call: get_time({})", output.String())
+}
+
 func TestInspectHuggingFaceModel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -123,7 +192,7 @@ func TestNonHuggingFaceModel(t *testing.T) {
 		Body:       io.NopCloser(bytes.NewBufferString(`{"type":"success","message":"Model pulled successfully"}`)),
 	}, nil)
 
-	_, _, err := client.Pull(modelName, false, func(s string) {})
+	_, _, err := client.Pull(modelName, false, false, func(s string) {})
 	assert.NoError(t, err)
 }
 
@@ -225,3 +294,81 @@ func TestInspectOpenAIHuggingFaceModel(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedLowercase, model.ID)
 }
+
+func TestRetryServiceUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mockdesktop.NewMockDockerHttpClient(ctrl)
+	client := New(NewContextForMock(mockClient))
+
+	// The request body is replayed when the request is retried.
+	var bodies []string
+	recordBody := func(req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+	gomock.InOrder(
+		mockClient.EXPECT().Do(gomock.Any()).Do(recordBody).Return(&http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil),
+		mockClient.EXPECT().Do(gomock.Any()).Do(recordBody).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil),
+	)
+
+	resp, err := client.doRequest(http.MethodPost, "/test", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestRetryServiceUnavailableCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mockdesktop.NewMockDockerHttpClient(ctrl)
+	client := New(NewContextForMock(mockClient))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(*http.Request) (*http.Response, error) {
+		cancel()
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	_, err := client.doRequestWithAuthContext(ctx, http.MethodGet, "/test", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOfflineDetection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	refused := &url.Error{Op: "Get", URL: "http://localhost/models", Err: &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}}
+	missing := &url.Error{Op: "Get", URL: "http://localhost/models", Err: &net.OpError{Op: "dial", Net: "unix", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ENOENT}}}
+
+	for _, test := range []struct {
+		name     string
+		kind     types.ModelRunnerEngineKind
+		err      error
+		expected error
+	}{
+		{"desktop socket refused", types.ModelRunnerEngineKindDesktop, refused, ErrDockerNotRunning},
+		{"desktop socket missing", types.ModelRunnerEngineKindDesktop, missing, ErrDockerNotRunning},
+		{"standalone runner refused", types.ModelRunnerEngineKindMoby, refused, ErrServiceUnavailable},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mockClient := mockdesktop.NewMockDockerHttpClient(ctrl)
+			modelRunner := NewContextForMock(mockClient)
+			modelRunner.kind = test.kind
+			client := New(modelRunner)
+
+			mockClient.EXPECT().Do(gomock.Any()).Return(nil, test.err)
+			_, err := client.List()
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-containerregistry/pkg/name"
 
 	"github.com/docker/model-runner/pkg/distribution/builder"
+	"github.com/docker/model-runner/pkg/distribution/conformance"
 	"github.com/docker/model-runner/pkg/distribution/distribution"
 	"github.com/docker/model-runner/pkg/distribution/packaging"
 	"github.com/docker/model-runner/pkg/distribution/registry"
 	"github.com/docker/model-runner/pkg/distribution/tarball"
+	"github.com/docker/model-runner/pkg/distribution/types"
 )
 
 // stringSliceFlag is a flag that can be specified multiple times to collect multiple string values
@@ -33,13 +43,21 @@ const (
 )
 
 var (
-	storePath string
-	showHelp  bool
-	showVer   bool
+	storePath     string
+	blobCache     string
+	requireDigest bool
+	pushChunkSize int64
+	tokenStore    string
+	showHelp      bool
+	showVer       bool
 )
 
 func init() {
 	flag.StringVar(&storePath, "store-path", defaultStorePath, "Path to the model store")
+	flag.StringVar(&blobCache, "blob-cache", "", "Path to a blob cache shared with other stores on the same file system")
+	flag.BoolVar(&requireDigest, "require-digest", false, "Refuse models referenced by mutable tags rather than by digest")
+	flag.Int64Var(&pushChunkSize, "push-chunk-size", 0, "Size in bytes of the chunks in which layers are pushed (0 for the default, -1 to push layers whole)")
+	flag.StringVar(&tokenStore, "token-store", defaultTokenStore(), "Path to the file of OAuth2 refresh tokens saved by login (empty to disable)")
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.BoolVar(&showVer, "version", false, "Show version")
 }
@@ -68,6 +86,12 @@ func main() {
 	clientOpts := []distribution.Option{
 		distribution.WithStoreRootPath(absStorePath),
 		distribution.WithUserAgent("model-distribution-tool/" + version),
+		distribution.WithBlobCache(blobCache),
+		distribution.WithPushChunkSize(pushChunkSize),
+		distribution.WithTokenStore(newTokenStore()),
+	}
+	if requireDigest {
+		clientOpts = append(clientOpts, distribution.WithRequireDigest())
 	}
 
 	if username := os.Getenv("DOCKER_USERNAME"); username != "" {
@@ -95,10 +119,16 @@ func main() {
 		exitCode = cmdPackage(args)
 	case "push":
 		exitCode = cmdPush(client, args)
+	case "cp":
+		exitCode = cmdCp(client, args)
 	case "list":
 		exitCode = cmdList(client, args)
 	case "get":
 		exitCode = cmdGet(client, args)
+	case "inspect":
+		exitCode = cmdInspect(client, args)
+	case "diff":
+		exitCode = cmdDiff(client, args)
 	case "get-path":
 		exitCode = cmdGetPath(client, args)
 	case "rm":
@@ -109,6 +139,20 @@ func main() {
 		exitCode = cmdLoad(client, args)
 	case "bundle":
 		exitCode = cmdBundle(client, args)
+	case "store":
+		exitCode = cmdStore(client, args)
+	case "verify":
+		exitCode = cmdVerify(client, args)
+	case "dedupe":
+		exitCode = cmdDedupe(absStorePath, args)
+	case "prune":
+		exitCode = cmdPrune(client, args)
+	case "conformance":
+		exitCode = cmdConformance(args)
+	case "login":
+		exitCode = cmdLogin(newTokenStore(), args)
+	case "logout":
+		exitCode = cmdLogout(newTokenStore(), args)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		printUsage()
@@ -125,22 +169,49 @@ func printUsage() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  pull <reference>                Pull a model from a registry")
 	fmt.Println("  package <source> <reference>    Package a model file as an OCI artifact and push it to a registry")
-	fmt.Println("                                  (use --licenses to add license files, --mmproj for multimodal projector, --dir-tar for directories)")
+	fmt.Println("                                  (use --licenses to add license files, --mmproj for multimodal projector, --lora for LoRA adapters, --base-model for a base model dependency, --dir-tar for directories, --label for labels)")
 	fmt.Println("  push <tag>                      Push a model from the content store to the registry")
-	fmt.Println("  list                            List all models")
-	fmt.Println("  get <reference>                 Get a model by reference")
+	fmt.Println("  cp <source> <tag>               Copy a model between registries without storing it locally")
+	fmt.Println("  list                            List all models (use --filter label=<key>[=<value>] to select models)")
+	fmt.Println("  get <reference>                 Get a model by reference (same as inspect)")
+	fmt.Println("  inspect <reference>             Show the manifest, config, layers and annotations of a model")
+	fmt.Println("                                  (use --format json for machine-readable output)")
+	fmt.Println("  diff <ref-a> <ref-b>            Show the layers two models share and the layers that differ")
 	fmt.Println("  get-path <reference>            Get the local file path for a model")
 	fmt.Println("  rm <reference>                  Remove a model by reference")
-	fmt.Println("  bundle <reference>              Create a runtime bundle for model")
+	fmt.Println("  prune [repository...]           Remove older tags of each repository and models left without tags")
+	fmt.Println("                                  (use --keep-last N and --keep-tagged latest,stable to select the tags to keep, --dry-run to preview)")
+	fmt.Println("  bundle <reference>              Create a runtime bundle for model, or reuse the cached one")
+	fmt.Println("                                  (use --refresh to recreate it)")
+	fmt.Println("  store stats                     Show blob deduplication statistics for the local store")
+	fmt.Println("  store repair                    Remove models with missing or corrupt blobs and quarantine corrupt files")
+	fmt.Println("  verify <reference>              Re-verify the digests of all blobs of a stored model")
+	fmt.Println("  dedupe [store-path...]          Hardlink the blobs of the store, and any other stores, to the blob cache")
+	fmt.Println("  conformance <repository>        Run the artifact format conformance suite against a registry repository")
+	fmt.Println("  login <registry>                Save an OAuth2 refresh token for a registry to the token store")
+	fmt.Println("                                  (use --device-auth-url, --token-url and --client-id for a device code login, --identity-token-stdin for an existing token)")
+	fmt.Println("  logout <registry>               Remove the refresh token for a registry from the token store")
 	fmt.Println("\nExamples:")
 	fmt.Println("  model-distribution-tool --store-path ./models pull registry.example.com/models/llama:v1.0")
 	fmt.Println("  model-distribution-tool package ./model.gguf registry.example.com/models/llama:v1.0 --licenses ./license1.txt --licenses ./license2.txt")
 	fmt.Println("  model-distribution-tool package ./model.gguf registry.example.com/models/llama:v1.0 --mmproj ./model.mmproj")
 	fmt.Println("  model-distribution-tool package ./model.gguf registry.example.com/models/llama:v1.0 --dir-tar ./config --dir-tar ./templates")
 	fmt.Println("  model-distribution-tool push registry.example.com/models/llama:v1.0")
+	fmt.Println("  model-distribution-tool cp staging.example.com/models/llama:v1.0 registry.example.com/models/llama:v1.0")
+	fmt.Println("  model-distribution-tool --require-digest pull registry.example.com/models/llama@sha256:<digest>")
 	fmt.Println("  model-distribution-tool list")
+	fmt.Println("  model-distribution-tool list --filter label=team=nlp")
+	fmt.Println("  model-distribution-tool inspect registry.example.com/models/llama:v1.0 --format json")
+	fmt.Println("  model-distribution-tool diff registry.example.com/models/llama:v1.0 registry.example.com/models/llama:v1.1")
 	fmt.Println("  model-distribution-tool rm registry.example.com/models/llama:v1.0")
+	fmt.Println("  model-distribution-tool prune --keep-last 5 --keep-tagged latest,stable registry.example.com/models/nightly")
 	fmt.Println("  model-distribution-tool bundle registry.example.com/models/llama:v1.0")
+	fmt.Println("  model-distribution-tool store stats")
+	fmt.Println("  model-distribution-tool verify registry.example.com/models/llama:v1.0")
+	fmt.Println("  model-distribution-tool --blob-cache ~/.cache/models dedupe ~/.docker/models")
+	fmt.Println("  model-distribution-tool conformance localhost:5000/conformance")
+	fmt.Println("  model-distribution-tool login --device-auth-url https://sso.example.com/device --token-url https://sso.example.com/token --client-id models registry.example.com")
+	fmt.Println("  echo $TOKEN | model-distribution-tool login --identity-token-stdin ghcr.io")
 }
 
 func cmdPull(client *distribution.Client, args []string) int {
@@ -167,20 +238,41 @@ func cmdPackage(args []string) int {
 	var (
 		licensePaths stringSliceFlag
 		dirTarPaths  stringSliceFlag
+		loraPaths    stringSliceFlag
+		labels       stringSliceFlag
 		contextSize  uint64
 		file         string
 		tag          string
 		mmproj       string
+		baseModel    string
 		chatTemplate string
+		deltaBase    string
+		quantize     string
+		whisper      bool
+		voice        bool
+		diffusion    bool
+		compress     bool
+		overrides    types.Config
 	)
 
 	fs.Var(&licensePaths, "licenses", "Paths to license files (can be specified multiple times)")
 	fs.Var(&dirTarPaths, "dir-tar", "Relative paths to directories to package as tar (can be specified multiple times)")
 	fs.Uint64Var(&contextSize, "context-size", 0, "Context size in tokens")
 	fs.StringVar(&mmproj, "mmproj", "", "Path to Multimodal Projector file")
+	fs.Var(&loraPaths, "lora", "Paths to LoRA adapter files in GGUF format (can be specified multiple times)")
+	fs.StringVar(&baseModel, "base-model", "", "Reference of the base model this model depends on (tag or digest)")
 	fs.StringVar(&file, "file", "", "Write archived model to the given file")
 	fs.StringVar(&tag, "tag", "", "Push model to the given registry tag")
 	fs.StringVar(&chatTemplate, "chat-template", "", "Jinja chat template file")
+	fs.StringVar(&deltaBase, "delta-base", "", "Path to a GGUF file, such as that of the base model, to push a delta against (requires --tag)")
+	fs.StringVar(&overrides.Architecture, "override-architecture", "", "Override the architecture read from the GGUF header")
+	fs.StringVar(&overrides.Parameters, "override-parameters", "", "Override the parameter count read from the GGUF header")
+	fs.StringVar(&overrides.Quantization, "override-quantization", "", "Override the quantization read from the GGUF header")
+	fs.StringVar(&quantize, "quantize", "", "Quantize a GGUF model to the given type (e.g. Q4_K_M) using llama-quantize")
+	fs.BoolVar(&whisper, "whisper", false, "Package the file as a whisper.cpp speech-to-text model")
+	fs.BoolVar(&diffusion, "diffusion", false, "Package the GGUF or safetensors file as a stable-diffusion.cpp image generation model")
+	fs.BoolVar(&compress, "zstd", false, "Compress license, chat template and config archive layers with zstd (weights stay uncompressed)")
+	fs.Var(&labels, "label", "Label in key=value form, stored as a manifest annotation (can be specified multiple times)")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool package [OPTIONS] <path-to-model-or-directory>\n\n")
@@ -189,6 +281,16 @@ func cmdPackage(args []string) int {
 		fmt.Fprintf(os.Stderr, "  model-distribution-tool package model.gguf --tag registry/model:tag\n\n")
 		fmt.Fprintf(os.Stderr, "  # Safetensors model:\n")
 		fmt.Fprintf(os.Stderr, "  model-distribution-tool package ./qwen-model-dir --tag registry/model:tag\n\n")
+		fmt.Fprintf(os.Stderr, "  # Quantized GGUF model (set LLAMA_QUANTIZE_PATH to override the quantize tool):\n")
+		fmt.Fprintf(os.Stderr, "  model-distribution-tool package --quantize Q4_K_M model-f16.gguf --tag registry/model:tag\n\n")
+		fmt.Fprintf(os.Stderr, "  # Whisper speech-to-text model:\n")
+		fmt.Fprintf(os.Stderr, "  model-distribution-tool package --whisper ggml-base.en.bin --tag registry/model:tag\n\n")
+		fmt.Fprintf(os.Stderr, "  # Stable diffusion image generation model:\n")
+		fmt.Fprintf(os.Stderr, "  model-distribution-tool package --diffusion sd-v1-5-q8_0.gguf --tag registry/model:tag\n\n")
+		fmt.Fprintf(os.Stderr, "  # ONNX embedding or classification model, with its tokenizer.json:\n")
+		fmt.Fprintf(os.Stderr, "  model-distribution-tool package ./all-MiniLM-L6-v2-onnx --tag registry/model:tag\n\n")
+		fmt.Fprintf(os.Stderr, "  # Piper text-to-speech voice, with its config in en_US-lessac-medium.onnx.json:\n")
+		fmt.Fprintf(os.Stderr, "  model-distribution-tool package en_US-lessac-medium.onnx --tag registry/model:tag\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fs.PrintDefaults()
 	}
@@ -208,8 +310,10 @@ func cmdPackage(args []string) int {
 
 	source := args[0]
 	var isSafetensors bool
-	var configArchive string      // For safetensors config
+	var isONNX bool
+	var configArchive string      // For safetensors and ONNX config
 	var safetensorsPaths []string // For safetensors model files
+	var onnxPath string           // For ONNX model files
 
 	// Check if source exists
 	sourceInfo, err := os.Stat(source)
@@ -218,8 +322,19 @@ func cmdPackage(args []string) int {
 		return 1
 	}
 
-	// Handle directory-based packaging (for safetensors models)
-	if sourceInfo.IsDir() {
+	// Handle directory-based packaging (for safetensors and ONNX models)
+	if sourceInfo.IsDir() && packaging.IsONNXDirectory(source) {
+		fmt.Printf("Detected directory with ONNX model, scanning for its tokenizer...\n")
+		var err error
+		onnxPath, configArchive, err = packaging.PackageONNXFromDirectory(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning directory: %v\n", err)
+			return 1
+		}
+		isONNX = true
+		defer os.Remove(configArchive)
+		fmt.Printf("Found ONNX model %s\n", filepath.Base(onnxPath))
+	} else if sourceInfo.IsDir() {
 		fmt.Printf("Detected directory, scanning for safetensors model...\n")
 		var err error
 		safetensorsPaths, configArchive, err = packaging.PackageFromDirectory(source)
@@ -237,8 +352,15 @@ func cmdPackage(args []string) int {
 			fmt.Printf("Created temporary config archive from directory\n")
 		}
 	} else {
-		// Handle single file (GGUF model)
-		if strings.HasSuffix(strings.ToLower(source), ".gguf") {
+		// Handle single file (GGUF, whisper, diffusion model or piper voice)
+		if whisper {
+			fmt.Println("Using whisper model file")
+		} else if diffusion {
+			fmt.Println("Using diffusion model file")
+		} else if strings.HasSuffix(strings.ToLower(source), ".onnx") {
+			voice = true
+			fmt.Println("Detected piper voice file")
+		} else if strings.HasSuffix(strings.ToLower(source), ".gguf") {
 			isSafetensors = false
 			fmt.Println("Detected GGUF model file")
 		} else {
@@ -253,11 +375,38 @@ func cmdPackage(args []string) int {
 		return 1
 	}
 
+	if (isSafetensors || isONNX) && quantize != "" {
+		fmt.Fprintf(os.Stderr, "Error: --quantize is only supported for GGUF models\n")
+		return 1
+	}
+
+	if deltaBase != "" && (isSafetensors || isONNX || whisper || voice || diffusion || tag == "") {
+		fmt.Fprintf(os.Stderr, "Error: --delta-base is only supported for GGUF models pushed with --tag\n")
+		return 1
+	}
+
+	if whisper && (isSafetensors || isONNX || quantize != "") {
+		fmt.Fprintf(os.Stderr, "Error: --whisper requires a single model file and can't be combined with --quantize\n")
+		return 1
+	}
+
+	if diffusion && (isSafetensors || isONNX || whisper || quantize != "") {
+		fmt.Fprintf(os.Stderr, "Error: --diffusion requires a single model file and can't be combined with --whisper or --quantize\n")
+		return 1
+	}
+
+	if voice && quantize != "" {
+		fmt.Fprintf(os.Stderr, "Error: --quantize is only supported for GGUF models\n")
+		return 1
+	}
+
 	ctx := context.Background()
 
 	// Prepare registry client options
 	registryClientOpts := []registry.ClientOption{
 		registry.WithUserAgent("model-distribution-tool/" + version),
+		registry.WithPushChunkSize(pushChunkSize),
+		registry.WithTokenStore(newTokenStore()),
 	}
 
 	// Add auth if available
@@ -301,12 +450,59 @@ func cmdPackage(args []string) int {
 				return 1
 			}
 		}
+	} else if isONNX {
+		fmt.Println("Creating ONNX model")
+		b, err = builder.FromONNX(onnxPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating model from ONNX file: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Adding config archive: %s\n", configArchive)
+		b, err = b.WithConfigArchive(configArchive)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding config archive: %v\n", err)
+			return 1
+		}
+	} else if diffusion {
+		fmt.Println("Creating diffusion model")
+		b, err = builder.FromDiffusion(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating model from diffusion file: %v\n", err)
+			return 1
+		}
+	} else if voice {
+		fmt.Println("Creating piper voice model")
+		b, err = builder.FromVoice(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating model from voice file: %v\n", err)
+			return 1
+		}
+	} else if whisper {
+		fmt.Println("Creating whisper model")
+		b, err = builder.FromWhisper(source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating model from whisper file: %v\n", err)
+			return 1
+		}
 	} else {
 		b, err = builder.FromGGUF(source)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating model from gguf: %v\n", err)
 			return 1
 		}
+
+		if quantize != "" {
+			if quantizePath := os.Getenv("LLAMA_QUANTIZE_PATH"); quantizePath != "" {
+				builder.QuantizeBinary = quantizePath
+			}
+			fmt.Println("Quantizing model to:", quantize)
+			b, err = b.WithQuantization(quantize)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error quantizing model: %v\n", err)
+				return 1
+			}
+			defer b.Cleanup()
+		}
 	}
 
 	// Add all license files as layers
@@ -324,6 +520,18 @@ func cmdPackage(args []string) int {
 		b = b.WithContextSize(contextSize)
 	}
 
+	if overrides.Architecture != "" || overrides.Parameters != "" || overrides.Quantization != "" {
+		fmt.Println("Overriding model metadata")
+		b = b.WithConfigOverrides(overrides)
+	}
+
+	if cfg, err := b.Model().Config(); err == nil && cfg.Format == types.FormatGGUF {
+		fmt.Printf("Architecture: %s, Parameters: %s, Quantization: %s\n", cfg.Architecture, cfg.Parameters, cfg.Quantization)
+		if cfg.ContextSize != nil {
+			fmt.Printf("Context size: %d\n", *cfg.ContextSize)
+		}
+	}
+
 	if mmproj != "" {
 		fmt.Println("Adding multimodal projector file:", mmproj)
 		b, err = b.WithMultimodalProjector(mmproj)
@@ -333,6 +541,37 @@ func cmdPackage(args []string) int {
 		}
 	}
 
+	for _, path := range loraPaths {
+		fmt.Println("Adding LoRA adapter file:", path)
+		b, err = b.WithLoRAAdapter(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding LoRA adapter layer for %s: %v\n", path, err)
+			return 1
+		}
+	}
+
+	if baseModel != "" {
+		// Resolve the base model digest from the registry so that the
+		// dependency is pinned even when referenced by tag.
+		fmt.Println("Resolving base model:", baseModel)
+		base, err := registryClient.Model(ctx, baseModel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading base model %s: %v\n", baseModel, err)
+			return 1
+		}
+		digest, err := base.Digest()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting base model digest: %v\n", err)
+			return 1
+		}
+		fmt.Println("Base model digest:", digest)
+		b, err = b.WithBaseModel(types.BaseModel{Digest: digest.String(), Reference: baseModel})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting base model: %v\n", err)
+			return 1
+		}
+	}
+
 	if chatTemplate != "" {
 		fmt.Println("Adding chat template file:", chatTemplate)
 		b, err = b.WithChatTemplateFile(chatTemplate)
@@ -346,7 +585,7 @@ func cmdPackage(args []string) int {
 	if len(dirTarPaths) > 0 {
 		// Determine base directory for resolving relative paths
 		var baseDir string
-		if isSafetensors {
+		if isSafetensors || isONNX {
 			baseDir = source
 		} else {
 			// For GGUF, use the directory containing the GGUF file
@@ -370,6 +609,35 @@ func cmdPackage(args []string) int {
 		}
 	}
 
+	if compress {
+		fmt.Println("Compressing non-weight layers with zstd")
+		b, err = b.WithZstd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error compressing layers: %v\n", err)
+			return 1
+		}
+		defer b.Cleanup()
+	}
+
+	if deltaBase != "" {
+		fmt.Println("Computing delta against:", deltaBase)
+		b, err = b.WithDelta(deltaBase)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error computing delta against %s: %v\n", deltaBase, err)
+			return 1
+		}
+		defer b.Cleanup()
+	}
+
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		b, err = b.WithAnnotation(key, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding label %q: %v\n", label, err)
+			return 1
+		}
+	}
+
 	// Push the image
 	if err := b.Build(ctx, target, os.Stdout); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing model to registry: %v\n", err)
@@ -447,12 +715,42 @@ func cmdPush(client *distribution.Client, args []string) int {
 	return 0
 }
 
+func cmdCp(client *distribution.Client, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: expected source and destination reference arguments\n")
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool cp <source-reference> <destination-tag>\n")
+		return 1
+	}
+
+	source, tag := args[0], args[1]
+	if err := client.CopyModel(context.Background(), source, tag, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error copying model: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Successfully copied model %s to %s\n", source, tag)
+	return 0
+}
+
 func cmdList(client *distribution.Client, args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var filterArgs stringSliceFlag
+	fs.Var(&filterArgs, "filter", "Filter models, e.g. label=team=nlp (can be specified multiple times)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	filters, err := distribution.ParseModelFilters(filterArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	models, err := client.ListModels()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error listing models: %v\n", err)
 		return 1
 	}
+	models = distribution.FilterModels(models, filters)
 
 	if len(models) == 0 {
 		fmt.Println("No models found")
@@ -468,6 +766,13 @@ func cmdList(client *distribution.Client, args []string) int {
 		}
 		fmt.Printf("%d. ID: %s\n", i+1, id)
 		fmt.Printf("   Tags: %s\n", strings.Join(model.Tags(), ", "))
+		if annotations := model.Annotations(); len(annotations) > 0 {
+			var labels []string
+			for _, key := range slices.Sorted(maps.Keys(annotations)) {
+				labels = append(labels, key+"="+annotations[key])
+			}
+			fmt.Printf("   Annotations: %s\n", strings.Join(labels, ", "))
+		}
 
 		ggufPaths, err := model.GGUFPaths()
 		if err == nil {
@@ -480,49 +785,192 @@ func cmdList(client *distribution.Client, args []string) int {
 	return 0
 }
 
+// cmdGet prints a model like cmdInspect, and is kept for compatibility.
 func cmdGet(client *distribution.Client, args []string) int {
-	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Error: missing reference argument\n")
-		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool get <reference>\n")
+	return cmdInspect(client, args)
+}
+
+func cmdInspect(client *distribution.Client, args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	format := fs.String("format", "table", "Output format (json or table)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool inspect <reference> [--format json|table]\n")
+	}
+	// Allow flags both before and after the reference.
+	var refs []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 1
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		refs = append(refs, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(refs) != 1 {
+		fmt.Fprintf(os.Stderr, "Error: expected exactly one reference argument\n")
+		fs.Usage()
+		return 1
+	}
+	if *format != "json" && *format != "table" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format %q\n", *format)
+		fs.Usage()
 		return 1
 	}
 
-	reference := args[0]
-
-	model, err := client.GetModel(reference)
+	inspection, err := client.InspectModel(refs[0])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting model: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error inspecting model: %v\n", err)
 		return 1
 	}
 
-	fmt.Printf("Model: %s\n", reference)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(inspection); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding model: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printInspection(os.Stdout, inspection)
+	return 0
+}
 
-	id, err := model.ID()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting model ID %v\n", err)
-		return 1
+// printInspection writes inspection to w as tables.
+func printInspection(w io.Writer, inspection distribution.ModelInspection) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", inspection.ID)
+	fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(inspection.Tags, ", "))
+	fmt.Fprintf(tw, "Format:\t%s\n", inspection.Config.Format)
+	fmt.Fprintf(tw, "Architecture:\t%s\n", inspection.Config.Architecture)
+	fmt.Fprintf(tw, "Parameters:\t%s\n", inspection.Config.Parameters)
+	fmt.Fprintf(tw, "Quantization:\t%s\n", inspection.Config.Quantization)
+	fmt.Fprintf(tw, "Size:\t%s\n", inspection.Config.Size)
+	if inspection.Metadata.Source != "" {
+		fmt.Fprintf(tw, "Source:\t%s\n", inspection.Metadata.Source)
 	}
-	fmt.Printf("ID: %s\n", id)
+	tw.Flush()
 
-	ggufPaths, err := model.GGUFPaths()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting gguf path %v\n", err)
-		return 1
+	fmt.Fprintln(w, "\nLayers:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MEDIA TYPE\tSIZE\tDIGEST")
+	for _, layer := range inspection.Layers {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", layer.MediaType, layer.Size, layer.Digest)
+	}
+	tw.Flush()
+
+	printAnnotations(w, "Annotations", inspection.Annotations)
+	for _, layer := range inspection.Layers {
+		printAnnotations(w, "Annotations of "+layer.Digest, layer.Annotations)
+	}
+}
+
+// printAnnotations writes the annotations, sorted by key, under the title, if
+// there are any.
+func printAnnotations(w io.Writer, title string, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
 	}
-	fmt.Print("   GGUF Paths:\n")
-	for _, path := range ggufPaths {
-		fmt.Printf("\t%s\n", path)
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		fmt.Fprintf(w, "  %s=%s\n", key, annotations[key])
 	}
+}
 
-	cfg, err := model.Config()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading model config: %v\n", err)
+// layerDiff compares the layers of two models.
+type layerDiff struct {
+	// shared are the layers of both models.
+	shared []distribution.LayerInspection
+	// onlyA are the layers of the first model only.
+	onlyA []distribution.LayerInspection
+	// onlyB are the layers of the second model only.
+	onlyB []distribution.LayerInspection
+}
+
+// diffLayers compares the layers of a and b by digest, keeping manifest order.
+func diffLayers(a, b distribution.ModelInspection) layerDiff {
+	inA := make(map[string]bool, len(a.Layers))
+	for _, layer := range a.Layers {
+		inA[layer.Digest] = true
+	}
+	inB := make(map[string]bool, len(b.Layers))
+	for _, layer := range b.Layers {
+		inB[layer.Digest] = true
+	}
+
+	var diff layerDiff
+	for _, layer := range a.Layers {
+		if inB[layer.Digest] {
+			diff.shared = append(diff.shared, layer)
+		} else {
+			diff.onlyA = append(diff.onlyA, layer)
+		}
+	}
+	for _, layer := range b.Layers {
+		if !inA[layer.Digest] {
+			diff.onlyB = append(diff.onlyB, layer)
+		}
+	}
+	return diff
+}
+
+// layersSize returns the total size of layers in bytes.
+func layersSize(layers []distribution.LayerInspection) int64 {
+	var size int64
+	for _, layer := range layers {
+		size += layer.Size
+	}
+	return size
+}
+
+func cmdDiff(client *distribution.Client, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: expected two reference arguments\n")
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool diff <reference-a> <reference-b>\n")
 		return 1
 	}
-	fmt.Printf("Format: %s\n", cfg.Format)
-	fmt.Printf("Architecture: %s\n", cfg.Architecture)
-	fmt.Printf("Parameters: %s\n", cfg.Parameters)
-	fmt.Printf("Quantization: %s\n", cfg.Quantization)
+
+	var inspections [2]distribution.ModelInspection
+	for i, reference := range args {
+		inspection, err := client.InspectModel(reference)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error inspecting model: %v\n", err)
+			return 1
+		}
+		inspections[i] = inspection
+	}
+	a, b := inspections[0], inspections[1]
+
+	fmt.Printf("A: %s (%s)\n", args[0], a.ID)
+	fmt.Printf("B: %s (%s)\n", args[1], b.ID)
+	if a.ID == b.ID {
+		fmt.Println("The models are identical")
+		return 0
+	}
+	if a.ConfigDigest == b.ConfigDigest {
+		fmt.Println("Config: identical")
+	} else {
+		fmt.Printf("Config: changed (%s -> %s)\n", a.ConfigDigest, b.ConfigDigest)
+	}
+
+	diff := diffLayers(a, b)
+	fmt.Println("\nLayers:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, group := range []struct {
+		marker string
+		layers []distribution.LayerInspection
+	}{{"=", diff.shared}, {"-", diff.onlyA}, {"+", diff.onlyB}} {
+		for _, layer := range group.layers {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", group.marker, layer.MediaType, layer.Size, layer.Digest)
+		}
+	}
+	tw.Flush()
+
+	fmt.Printf("\nShared:    %d layer(s), %d bytes\n", len(diff.shared), layersSize(diff.shared))
+	fmt.Printf("Only in A: %d layer(s), %d bytes\n", len(diff.onlyA), layersSize(diff.onlyA))
+	fmt.Printf("Only in B: %d layer(s), %d bytes\n", len(diff.onlyB), layersSize(diff.onlyB))
 	return 0
 }
 
@@ -551,6 +999,58 @@ func cmdGetPath(client *distribution.Client, args []string) int {
 	return 0
 }
 
+func cmdPrune(client *distribution.Client, args []string) int {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	keepLast := fs.Int("keep-last", -1, "Number of newest tags to keep in each repository (required)")
+	keepTagged := fs.String("keep-tagged", "", "Comma-separated tags to always keep, e.g. latest,stable")
+	dryRun := fs.Bool("dry-run", false, "Show what would be removed without removing it")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool prune --keep-last N [--keep-tagged TAGS] [--dry-run] [repository...]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *keepLast < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --keep-last is required\n")
+		fs.Usage()
+		return 1
+	}
+
+	policy := distribution.PrunePolicy{
+		KeepLast:     *keepLast,
+		Repositories: fs.Args(),
+		DryRun:       *dryRun,
+	}
+	if *keepTagged != "" {
+		policy.KeepTags = strings.Split(*keepTagged, ",")
+	}
+	report, err := client.Prune(policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error pruning models: %v\n", err)
+		return 1
+	}
+
+	untagged, deleted := "Untagged", "Deleted"
+	if *dryRun {
+		untagged, deleted = "Would untag", "Would delete"
+	}
+	for _, tag := range report.Untagged {
+		fmt.Printf("%s: %s\n", untagged, tag)
+	}
+	for _, id := range report.Deleted {
+		fmt.Printf("%s: %s\n", deleted, id)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	if len(report.Untagged) == 0 {
+		fmt.Println("Nothing to prune")
+	}
+	return 0
+}
+
 func cmdRm(client *distribution.Client, args []string) int {
 	var force bool
 	fs := flag.NewFlagSet("rm", flag.ExitOnError)
@@ -598,11 +1098,24 @@ func cmdTag(client *distribution.Client, args []string) int {
 }
 
 func cmdBundle(client *distribution.Client, args []string) int {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	var refresh bool
+	fs.BoolVar(&refresh, "refresh", false, "Recreate the bundle even if one already exists")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	args = fs.Args()
 	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool bundle <reference>\n")
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool bundle [--refresh] <reference>\n")
 		return 1
 	}
-	bundle, err := client.GetBundle(args[0])
+	var bundle types.ModelBundle
+	var err error
+	if refresh {
+		bundle, err = client.RefreshBundle(args[0])
+	} else {
+		bundle, err = client.GetBundle(args[0])
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting model bundle: %v\n", err)
 		return 1
@@ -611,3 +1124,279 @@ func cmdBundle(client *distribution.Client, args []string) int {
 	fmt.Fprint(os.Stdout, bundle.RootDir())
 	return 0
 }
+
+func cmdVerify(client *distribution.Client, args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Error: missing reference argument\n")
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool verify <reference>\n")
+		return 1
+	}
+
+	reference := args[0]
+	if err := client.VerifyModel(reference); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to verify model: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Successfully verified model: %s\n", reference)
+	return 0
+}
+
+func cmdStore(client *distribution.Client, args []string) int {
+	if len(args) == 1 && args[0] == "repair" {
+		return cmdStoreRepair(client)
+	}
+	if len(args) != 1 || args[0] != "stats" {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool store stats|repair\n")
+		return 1
+	}
+	stats, err := client.DedupStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting store stats: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Models:         %d\n", stats.Models)
+	fmt.Printf("Blobs:          %d\n", stats.Blobs)
+	fmt.Printf("Logical bytes:  %d\n", stats.LogicalBytes)
+	fmt.Printf("Physical bytes: %d\n", stats.PhysicalBytes)
+	fmt.Printf("Saved bytes:    %d\n", stats.SavedBytes)
+
+	if len(stats.TopSharedBlobs) == 0 {
+		fmt.Println("No shared blobs")
+		return 0
+	}
+	fmt.Println("Top shared blobs:")
+	for i, blob := range stats.TopSharedBlobs {
+		fmt.Printf("%d. %s\n", i+1, blob.Digest)
+		fmt.Printf("   Size: %d, References: %d\n", blob.Size, blob.References)
+		if len(blob.Models) > 0 {
+			fmt.Printf("   Models: %s\n", strings.Join(blob.Models, ", "))
+		}
+	}
+	return 0
+}
+
+func cmdStoreRepair(client *distribution.Client) int {
+	report, err := client.RepairStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error repairing store: %v\n", err)
+		return 1
+	}
+
+	if report.IndexRebuilt {
+		fmt.Println("Rebuilt unreadable models index (model tags were lost)")
+	}
+	for _, file := range report.QuarantinedFiles {
+		fmt.Printf("Quarantined: %s\n", file)
+	}
+	for _, id := range report.RemovedModels {
+		fmt.Printf("Removed model: %s\n", id)
+	}
+	if !report.IndexRebuilt && len(report.RemovedModels) == 0 {
+		fmt.Println("No problems found")
+	}
+	return 0
+}
+
+func cmdDedupe(absStorePath string, args []string) int {
+	if blobCache == "" {
+		fmt.Fprintf(os.Stderr, "Error: --blob-cache is required\n")
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool --blob-cache <path> dedupe [store-path...]\n")
+		return 1
+	}
+	absBlobCache, err := filepath.Abs(blobCache)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving blob cache path: %v\n", err)
+		return 1
+	}
+
+	stores := make([]distribution.StoreConfig, 0, len(args))
+	for i, path := range args {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving store path: %v\n", err)
+			return 1
+		}
+		stores = append(stores, distribution.StoreConfig{Name: fmt.Sprintf("store%d", i+1), RootPath: absPath})
+	}
+	client, err := distribution.NewClient(
+		distribution.WithStoreRootPath(absStorePath),
+		distribution.WithStores(stores...),
+		distribution.WithBlobCache(absBlobCache),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating client: %v\n", err)
+		return 1
+	}
+
+	report, err := client.Dedupe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error deduplicating stores: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Blobs:       %d\n", report.Blobs)
+	fmt.Printf("Cached:      %d\n", report.Cached)
+	fmt.Printf("Linked:      %d\n", report.Linked)
+	fmt.Printf("Saved bytes: %d\n", report.SavedBytes)
+	return 0
+}
+
+func cmdConformance(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool conformance <repository>\n")
+		return 1
+	}
+
+	opts := conformance.Options{
+		Repository: args[0],
+		UserAgent:  "model-distribution-tool/" + version,
+	}
+	if username := os.Getenv("DOCKER_USERNAME"); username != "" {
+		if password := os.Getenv("DOCKER_PASSWORD"); password != "" {
+			opts.Username = username
+			opts.Password = password
+		}
+	}
+
+	report, err := conformance.Run(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running conformance suite: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Conformance results for %s:\n", report.Reference)
+	for _, res := range report.Results {
+		switch {
+		case res.Err == nil:
+			fmt.Printf("  PASS  %s\n", res.Step)
+		case errors.Is(res.Err, conformance.ErrSkipped):
+			fmt.Printf("  SKIP  %s\n", res.Step)
+		default:
+			fmt.Printf("  FAIL  %s: %v\n", res.Step, res.Err)
+		}
+	}
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// defaultTokenStore returns the default path of the token store, in the
+// user's configuration directory.
+func defaultTokenStore() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "model-distribution-tool", "tokens.json")
+}
+
+// newTokenStore returns the token store, or nil if it's disabled.
+func newTokenStore() registry.TokenStore {
+	if tokenStore == "" {
+		return nil
+	}
+	return registry.NewFileTokenStore(tokenStore)
+}
+
+func cmdLogin(tokens registry.TokenStore, args []string) int {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	deviceAuthURL := fs.String("device-auth-url", "", "Device authorization endpoint of the registry's identity provider")
+	tokenURL := fs.String("token-url", "", "Token endpoint of the registry's identity provider")
+	clientID := fs.String("client-id", "", "OAuth2 client ID registered with the identity provider")
+	scope := fs.String("scope", "offline_access", "Space-separated scopes to request, which must allow refresh tokens")
+	identityTokenStdin := fs.Bool("identity-token-stdin", false, "Read an existing refresh token from stdin instead")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool login (--device-auth-url URL --token-url URL --client-id ID | --identity-token-stdin) <registry>\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	if tokens == nil {
+		fmt.Fprintf(os.Stderr, "Error: --token-store is required\n")
+		return 1
+	}
+	reg, err := name.NewRegistry(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing registry: %v\n", err)
+		return 1
+	}
+
+	if *identityTokenStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading token: %v\n", err)
+			return 1
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "Error: no token read from stdin\n")
+			return 1
+		}
+		if err := tokens.SetToken(reg.RegistryStr(), token); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving token: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Saved token for %s\n", reg.RegistryStr())
+		return 0
+	}
+
+	if *deviceAuthURL == "" || *tokenURL == "" || *clientID == "" {
+		fmt.Fprintf(os.Stderr, "Error: --device-auth-url, --token-url and --client-id are required\n")
+		fs.Usage()
+		return 1
+	}
+	client := registry.NewClient(
+		registry.WithUserAgent("model-distribution-tool/"+version),
+		registry.WithTokenStore(tokens),
+	)
+	auth := registry.DeviceAuth{
+		DeviceAuthorizationURL: *deviceAuthURL,
+		TokenURL:               *tokenURL,
+		ClientID:               *clientID,
+		Scopes:                 strings.Fields(*scope),
+	}
+	err = client.DeviceLogin(context.Background(), reg.RegistryStr(), auth, func(code registry.DeviceCode) {
+		if code.VerificationURIComplete != "" {
+			fmt.Printf("Open %s to log in to %s\n", code.VerificationURIComplete, reg.RegistryStr())
+		} else {
+			fmt.Printf("Open %s and enter the code %s to log in to %s\n", code.VerificationURI, code.UserCode, reg.RegistryStr())
+		}
+		fmt.Println("Waiting for authorization...")
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error logging in: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Logged in to %s\n", reg.RegistryStr())
+	return 0
+}
+
+func cmdLogout(tokens registry.TokenStore, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: model-distribution-tool logout <registry>\n")
+		return 1
+	}
+	if tokens == nil {
+		fmt.Fprintf(os.Stderr, "Error: --token-store is required\n")
+		return 1
+	}
+	reg, err := name.NewRegistry(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing registry: %v\n", err)
+		return 1
+	}
+	if err := tokens.DeleteToken(reg.RegistryStr()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error removing token: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Logged out of %s\n", reg.RegistryStr())
+	return 0
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/registry"
 )
 
 // TestMainHelp tests the help command
@@ -110,6 +111,11 @@ func TestMainList(t *testing.T) {
 	if exitCode != 0 {
 		t.Errorf("List command failed with exit code: %d", exitCode)
 	}
+
+	// Test the list command with an invalid filter
+	if exitCode := cmdList(client, []string{"--filter", "owner=me"}); exitCode != 1 {
+		t.Errorf("List command with an invalid filter should fail")
+	}
 }
 
 // TestMainGet tests the get command
@@ -177,3 +183,123 @@ func TestMainPush(t *testing.T) {
 		t.Errorf("Push command with invalid arguments should fail")
 	}
 }
+
+// TestMainInspect tests the inspect command
+func TestMainInspect(t *testing.T) {
+	client, err := distribution.NewClient(distribution.WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"model:a", "model:b"},
+		{"model:latest", "--format", "yaml"},
+		{"--format", "json", "missing:latest"},
+	} {
+		if exitCode := cmdInspect(client, args); exitCode != 1 {
+			t.Errorf("Inspect command with arguments %q should fail", args)
+		}
+	}
+}
+
+// TestDiffLayers tests comparing the layers of two models
+func TestDiffLayers(t *testing.T) {
+	weights := distribution.LayerInspection{Digest: "sha256:weights", Size: 100}
+	templateA := distribution.LayerInspection{Digest: "sha256:template-a", Size: 1}
+	templateB := distribution.LayerInspection{Digest: "sha256:template-b", Size: 2}
+	license := distribution.LayerInspection{Digest: "sha256:license", Size: 3}
+
+	diff := diffLayers(
+		distribution.ModelInspection{Layers: []distribution.LayerInspection{weights, templateA}},
+		distribution.ModelInspection{Layers: []distribution.LayerInspection{weights, templateB, license}},
+	)
+	if len(diff.shared) != 1 || diff.shared[0].Digest != weights.Digest {
+		t.Errorf("Expected the weights to be shared, got %+v", diff.shared)
+	}
+	if len(diff.onlyA) != 1 || diff.onlyA[0].Digest != templateA.Digest {
+		t.Errorf("Expected the first template only in A, got %+v", diff.onlyA)
+	}
+	if len(diff.onlyB) != 2 || layersSize(diff.onlyB) != 5 {
+		t.Errorf("Expected the second template and license only in B, got %+v", diff.onlyB)
+	}
+
+	// Test the diff command with invalid arguments
+	client, err := distribution.NewClient(distribution.WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if exitCode := cmdDiff(client, []string{"model:a"}); exitCode != 1 {
+		t.Errorf("Diff command with invalid arguments should fail")
+	}
+}
+
+// TestMainCp tests the cp command
+func TestMainCp(t *testing.T) {
+	client, err := distribution.NewClient(distribution.WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Test the cp command with invalid arguments
+	if exitCode := cmdCp(client, []string{"registry.example.com/model:v1"}); exitCode != 1 {
+		t.Errorf("Cp command with invalid arguments should fail")
+	}
+}
+
+// TestMainPrune tests the prune command
+func TestMainPrune(t *testing.T) {
+	client, err := distribution.NewClient(distribution.WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// --keep-last is required
+	if exitCode := cmdPrune(client, []string{}); exitCode != 1 {
+		t.Errorf("Prune command without --keep-last should fail")
+	}
+	if exitCode := cmdPrune(client, []string{"--keep-last", "1", "INVALID"}); exitCode != 1 {
+		t.Errorf("Prune command with an invalid repository should fail")
+	}
+	if exitCode := cmdPrune(client, []string{"--keep-last", "1", "--keep-tagged", "latest,stable", "--dry-run"}); exitCode != 0 {
+		t.Errorf("Prune command failed with exit code: %d", exitCode)
+	}
+}
+
+// TestMainLogin tests the login and logout commands
+func TestMainLogin(t *testing.T) {
+	tokens := registry.NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+	// A token store is required
+	if exitCode := cmdLogin(nil, []string{"--identity-token-stdin", "ghcr.io"}); exitCode != 1 {
+		t.Errorf("Login command without a token store should fail")
+	}
+	// The device code flow endpoints are required
+	if exitCode := cmdLogin(tokens, []string{"ghcr.io"}); exitCode != 1 {
+		t.Errorf("Login command without endpoints should fail")
+	}
+
+	// Save a token read from stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	w.WriteString("refresh-token\n")
+	w.Close()
+	if exitCode := cmdLogin(tokens, []string{"--identity-token-stdin", "docker.io"}); exitCode != 0 {
+		t.Fatalf("Login command failed with exit code: %d", exitCode)
+	}
+	if token, err := tokens.Token("index.docker.io"); err != nil || token != "refresh-token" {
+		t.Errorf("Expected the token to be saved, got %q, %v", token, err)
+	}
+
+	if exitCode := cmdLogout(tokens, []string{"docker.io"}); exitCode != 0 {
+		t.Fatalf("Logout command failed with exit code: %d", exitCode)
+	}
+	if token, err := tokens.Token("index.docker.io"); err != nil || token != "" {
+		t.Errorf("Expected the token to be removed, got %q, %v", token, err)
+	}
+}
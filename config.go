@@ -0,0 +1,572 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/docker/model-runner/pkg/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+// duration is a time.Duration written as a string such as "30s" in the
+// configuration file and in GET /config responses.
+type duration time.Duration
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("negative duration %s", text)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// settings is the configuration of the model runner. It's read from the YAML
+// configuration file, if any, and overridden by environment variables, and
+// reported by GET /config.
+type settings struct {
+	Store      storeSettings                `yaml:"store" json:"store"`
+	Listen     listenSettings               `yaml:"listen" json:"listen"`
+	Backends   backendSettings              `yaml:"backends" json:"backends"`
+	Preload    []string                     `yaml:"preload" json:"preload"`
+	Scheduling schedulingSettings           `yaml:"scheduling" json:"scheduling"`
+	CORS       corsSettings                 `yaml:"cors" json:"cors"`
+	Metrics    metricsSettings              `yaml:"metrics" json:"metrics"`
+	Auth       authSettings                 `yaml:"auth" json:"auth"`
+	AccessLog  accessLogSettings            `yaml:"access-log" json:"access-log"`
+	Capture    captureSettings              `yaml:"capture" json:"capture"`
+	Guardrails []scheduling.GuardrailConfig `yaml:"guardrails" json:"guardrails"`
+	Injection  injectionSettings            `yaml:"injection" json:"injection"`
+	Images     imageSettings                `yaml:"images" json:"images"`
+}
+
+// storeSettings configures the model store.
+type storeSettings struct {
+	Path       string                     `yaml:"path" json:"path"`
+	Repair     bool                       `yaml:"repair" json:"repair"`
+	CatalogURL string                     `yaml:"catalog-url" json:"catalog-url"`
+	Additional []distribution.StoreConfig `yaml:"additional" json:"additional"`
+	Writable   string                     `yaml:"writable" json:"writable"`
+	ReadOnly   bool                       `yaml:"read-only" json:"read-only"`
+	BlobCache  string                     `yaml:"blob-cache" json:"blob-cache"`
+
+	RequireDigest bool `yaml:"require-digest" json:"require-digest"`
+
+	PushChunkSize string `yaml:"push-chunk-size" json:"push-chunk-size"`
+	TokenStore    string `yaml:"token-store" json:"token-store"`
+	DiskHeadroom  string `yaml:"disk-headroom" json:"disk-headroom"`
+
+	HardlinkBundles bool `yaml:"hardlink-bundles" json:"hardlink-bundles"`
+}
+
+// listenSettings configures the listener. The TCP port takes precedence over
+// the Unix socket.
+type listenSettings struct {
+	Port   string      `yaml:"port" json:"port"`
+	Socket string      `yaml:"socket" json:"socket"`
+	TLS    tlsSettings `yaml:"tls" json:"tls"`
+}
+
+// tlsSettings configures TLS on the TCP listener.
+type tlsSettings struct {
+	Cert     string `yaml:"cert" json:"cert"`
+	Key      string `yaml:"key" json:"key"`
+	ClientCA string `yaml:"client-ca" json:"client-ca"`
+}
+
+// backendSettings configures the inference backends.
+type backendSettings struct {
+	LlamaCpp           llamaCppSettings   `yaml:"llama.cpp" json:"llama.cpp"`
+	WhisperCpp         whisperCppSettings `yaml:"whisper.cpp" json:"whisper.cpp"`
+	Piper              piperSettings      `yaml:"piper" json:"piper"`
+	StableDiffusionCpp sdCppSettings      `yaml:"stable-diffusion.cpp" json:"stable-diffusion.cpp"`
+	ONNX               onnxSettings       `yaml:"onnx" json:"onnx"`
+	Mock               bool               `yaml:"mock" json:"mock"`
+}
+
+// llamaCppSettings configures the llama.cpp backend.
+type llamaCppSettings struct {
+	ServerPath    string   `yaml:"server-path" json:"server-path"`
+	ServerVersion string   `yaml:"server-version" json:"server-version"`
+	Variant       string   `yaml:"variant" json:"variant"`
+	Update        bool     `yaml:"update" json:"update"`
+	Args          []string `yaml:"args" json:"args"`
+}
+
+// whisperCppSettings configures the whisper.cpp backend.
+type whisperCppSettings struct {
+	ServerPath string `yaml:"server-path" json:"server-path"`
+}
+
+// piperSettings configures the piper backend.
+type piperSettings struct {
+	ServerPath string `yaml:"server-path" json:"server-path"`
+}
+
+// sdCppSettings configures the stable-diffusion.cpp backend.
+type sdCppSettings struct {
+	ServerPath string `yaml:"server-path" json:"server-path"`
+}
+
+// onnxSettings configures the ONNX Runtime backend.
+type onnxSettings struct {
+	ServerPath string `yaml:"server-path" json:"server-path"`
+}
+
+// schedulingSettings configures the scheduler.
+type schedulingSettings struct {
+	RunnerIdleTimeout     duration `yaml:"runner-idle-timeout" json:"runner-idle-timeout"`
+	DeepSleepTimeout      duration `yaml:"deep-sleep-timeout" json:"deep-sleep-timeout"`
+	DrainTimeout          duration `yaml:"drain-timeout" json:"drain-timeout"`
+	MaxConcurrentRequests int      `yaml:"max-concurrent-requests" json:"max-concurrent-requests"`
+	MaxRunnerRestarts     int      `yaml:"max-runner-restarts" json:"max-runner-restarts"`
+	RuntimeMemoryCheck    bool     `yaml:"runtime-memory-check" json:"runtime-memory-check"`
+	// Fallbacks map models to the models that requests are retried against,
+	// in order, when they fail to load.
+	Fallbacks map[string][]string `yaml:"fallbacks" json:"fallbacks,omitempty"`
+}
+
+// corsSettings configures cross-origin requests.
+type corsSettings struct {
+	AllowedOrigins []string `yaml:"allowed-origins" json:"allowed-origins"`
+}
+
+// metricsSettings configures the /metrics endpoint.
+type metricsSettings struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// authSettings configures API key authentication on the TCP listener.
+type authSettings struct {
+	APIKeyFile string `yaml:"api-key-file" json:"api-key-file"`
+}
+
+// accessLogSettings configures the access log of inference requests.
+type accessLogSettings struct {
+	Path     string `yaml:"path" json:"path"`
+	MaxSize  string `yaml:"max-size" json:"max-size"`
+	MaxFiles int    `yaml:"max-files" json:"max-files"`
+	Prompts  bool   `yaml:"prompts" json:"prompts"`
+}
+
+// captureSettings configures the capture of inference requests and responses
+// for export and replay.
+type captureSettings struct {
+	Dir      string `yaml:"dir" json:"dir"`
+	MaxSize  string `yaml:"max-size" json:"max-size"`
+	MaxFiles int    `yaml:"max-files" json:"max-files"`
+}
+
+// injectionSettings configures the allow-list for per-model environment
+// variables and mounts.
+type injectionSettings struct {
+	AllowedEnvPrefixes []string `yaml:"allowed-env-prefixes" json:"allowed-env-prefixes"`
+	AllowedMountDirs   []string `yaml:"allowed-mount-dirs" json:"allowed-mount-dirs"`
+}
+
+// imageSettings configures the preprocessing of the images of requests to
+// vision-language models.
+type imageSettings struct {
+	AllowedSchemes       []string `yaml:"allowed-schemes" json:"allowed-schemes"`
+	MaxSize              string   `yaml:"max-size" json:"max-size"`
+	MaxDimension         int      `yaml:"max-dimension" json:"max-dimension"`
+	AllowPrivateNetworks bool     `yaml:"allow-private-networks" json:"allow-private-networks"`
+}
+
+// defaultSettings returns the settings used without a configuration file or
+// environment variables.
+func defaultSettings(userHomeDir string) settings {
+	return settings{
+		Store: storeSettings{
+			Path: filepath.Join(userHomeDir, ".docker", "models"),
+		},
+		Listen: listenSettings{
+			Socket: "model-runner.sock",
+		},
+		Backends: backendSettings{
+			LlamaCpp: llamaCppSettings{
+				ServerPath: "/Applications/Docker.app/Contents/Resources/model-runner/bin",
+				Update:     true,
+			},
+		},
+		Scheduling: schedulingSettings{
+			DrainTimeout:      duration(30 * time.Second),
+			MaxRunnerRestarts: 3,
+		},
+		Metrics: metricsSettings{
+			Enabled: true,
+		},
+	}
+}
+
+// loadConfigFile reads settings from a YAML configuration file on top of s.
+// Unknown keys are rejected, so that typos don't go unnoticed.
+func (s *settings) loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables that are set.
+func (s *settings) applyEnv() error {
+	setString := func(name string, value *string) {
+		if v := os.Getenv(name); v != "" {
+			*value = v
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			*value = v == "1"
+		}
+	}
+	setList := func(name string, value *[]string, split func(string) []string) {
+		if v := os.Getenv(name); v != "" {
+			*value = split(v)
+		}
+	}
+	setDuration := func(name string, value *duration) error {
+		if v := os.Getenv(name); v != "" {
+			if err := value.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("invalid %s %q: must be a non-negative duration", name, v)
+			}
+		}
+		return nil
+	}
+	setInt := func(name string, value *int, minimum int) error {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < minimum {
+				return fmt.Errorf("invalid %s %q: must be an integer of at least %d", name, v, minimum)
+			}
+			*value = n
+		}
+		return nil
+	}
+
+	setString("MODELS_PATH", &s.Store.Path)
+	setBool("MODEL_RUNNER_REPAIR_STORE", &s.Store.Repair)
+	setString("MODEL_CATALOG_URL", &s.Store.CatalogURL)
+	if v := os.Getenv("MODEL_RUNNER_STORES"); v != "" {
+		stores, err := parseStores(v)
+		if err != nil {
+			return fmt.Errorf("invalid MODEL_RUNNER_STORES %q: %w", v, err)
+		}
+		s.Store.Additional = stores
+	}
+	setString("MODEL_RUNNER_WRITABLE_STORE", &s.Store.Writable)
+	setBool("MODEL_RUNNER_READ_ONLY_STORE", &s.Store.ReadOnly)
+	setString("MODEL_RUNNER_BLOB_CACHE", &s.Store.BlobCache)
+	setBool("MODEL_RUNNER_REQUIRE_DIGEST", &s.Store.RequireDigest)
+	setString("MODEL_RUNNER_PUSH_CHUNK_SIZE", &s.Store.PushChunkSize)
+	setString("MODEL_RUNNER_DISK_HEADROOM", &s.Store.DiskHeadroom)
+	setString("MODEL_RUNNER_TOKEN_STORE", &s.Store.TokenStore)
+	setBool("MODEL_RUNNER_HARDLINK_BUNDLES", &s.Store.HardlinkBundles)
+
+	setString("MODEL_RUNNER_PORT", &s.Listen.Port)
+	setString("MODEL_RUNNER_SOCK", &s.Listen.Socket)
+	setString("MODEL_RUNNER_TLS_CERT", &s.Listen.TLS.Cert)
+	setString("MODEL_RUNNER_TLS_KEY", &s.Listen.TLS.Key)
+	setString("MODEL_RUNNER_TLS_CLIENT_CA", &s.Listen.TLS.ClientCA)
+
+	setString("LLAMA_SERVER_PATH", &s.Backends.LlamaCpp.ServerPath)
+	if v, ok := os.LookupEnv("LLAMA_SERVER_VERSION"); ok {
+		s.Backends.LlamaCpp.ServerVersion = v
+	}
+	setString("LLAMACPP_VARIANT", &s.Backends.LlamaCpp.Variant)
+	if _, ok := os.LookupEnv("DISABLE_SERVER_UPDATE"); ok {
+		s.Backends.LlamaCpp.Update = false
+	}
+	setList("LLAMA_ARGS", &s.Backends.LlamaCpp.Args, splitArgs)
+	setString("WHISPER_SERVER_PATH", &s.Backends.WhisperCpp.ServerPath)
+	setString("PIPER_SERVER_PATH", &s.Backends.Piper.ServerPath)
+	setString("SD_SERVER_PATH", &s.Backends.StableDiffusionCpp.ServerPath)
+	setString("ONNX_SERVER_PATH", &s.Backends.ONNX.ServerPath)
+	setBool("MODEL_RUNNER_MOCK_BACKEND", &s.Backends.Mock)
+
+	setList("MODEL_RUNNER_PRELOAD", &s.Preload, splitList)
+
+	if err := setDuration("MODEL_RUNNER_IDLE_TIMEOUT", &s.Scheduling.RunnerIdleTimeout); err != nil {
+		return err
+	}
+	if err := setDuration("MODEL_RUNNER_DEEP_SLEEP_TIMEOUT", &s.Scheduling.DeepSleepTimeout); err != nil {
+		return err
+	}
+	if err := setDuration("MODEL_RUNNER_DRAIN_TIMEOUT", &s.Scheduling.DrainTimeout); err != nil {
+		return err
+	}
+	if err := setInt("MODEL_RUNNER_MAX_CONCURRENT_REQUESTS", &s.Scheduling.MaxConcurrentRequests, 0); err != nil {
+		return err
+	}
+	if err := setInt("MODEL_RUNNER_MAX_RUNNER_RESTARTS", &s.Scheduling.MaxRunnerRestarts, 0); err != nil {
+		return err
+	}
+	setBool("MODEL_RUNNER_RUNTIME_MEMORY_CHECK", &s.Scheduling.RuntimeMemoryCheck)
+
+	setList("MODEL_RUNNER_ALLOWED_ORIGINS", &s.CORS.AllowedOrigins, middleware.ParseOrigins)
+	if v, ok := os.LookupEnv("DISABLE_METRICS"); ok {
+		s.Metrics.Enabled = v != "1"
+	}
+	setString("MODEL_RUNNER_API_KEY_FILE", &s.Auth.APIKeyFile)
+
+	setString("MODEL_RUNNER_ACCESS_LOG", &s.AccessLog.Path)
+	setString("MODEL_RUNNER_ACCESS_LOG_MAX_SIZE", &s.AccessLog.MaxSize)
+	if err := setInt("MODEL_RUNNER_ACCESS_LOG_MAX_FILES", &s.AccessLog.MaxFiles, 1); err != nil {
+		return err
+	}
+	setBool("MODEL_RUNNER_ACCESS_LOG_PROMPTS", &s.AccessLog.Prompts)
+	setString("MODEL_RUNNER_CAPTURE_DIR", &s.Capture.Dir)
+	setString("MODEL_RUNNER_CAPTURE_MAX_SIZE", &s.Capture.MaxSize)
+	if err := setInt("MODEL_RUNNER_CAPTURE_MAX_FILES", &s.Capture.MaxFiles, 1); err != nil {
+		return err
+	}
+
+	setList("MODEL_RUNNER_ALLOWED_ENV_PREFIXES", &s.Injection.AllowedEnvPrefixes, func(v string) []string {
+		return strings.Split(v, ",")
+	})
+	setList("MODEL_RUNNER_ALLOWED_MOUNT_DIRS", &s.Injection.AllowedMountDirs, filepath.SplitList)
+
+	setList("MODEL_RUNNER_IMAGE_SCHEMES", &s.Images.AllowedSchemes, splitList)
+	setString("MODEL_RUNNER_MAX_IMAGE_SIZE", &s.Images.MaxSize)
+	if err := setInt("MODEL_RUNNER_MAX_IMAGE_DIMENSION", &s.Images.MaxDimension, 0); err != nil {
+		return err
+	}
+	setBool("MODEL_RUNNER_IMAGE_ALLOW_PRIVATE_NETWORKS", &s.Images.AllowPrivateNetworks)
+	return nil
+}
+
+// normalize canonicalizes settings that are matched case-insensitively.
+func (s *settings) normalize() {
+	// Image URL schemes are matched against lower-cased schemes.
+	for i, scheme := range s.Images.AllowedSchemes {
+		s.Images.AllowedSchemes[i] = strings.ToLower(scheme)
+	}
+}
+
+// validate checks settings that aren't checked when they're parsed.
+func (s *settings) validate() error {
+	if s.Scheduling.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("invalid scheduling.max-concurrent-requests %d: must be non-negative", s.Scheduling.MaxConcurrentRequests)
+	}
+	if s.Scheduling.MaxRunnerRestarts < 0 {
+		return fmt.Errorf("invalid scheduling.max-runner-restarts %d: must be non-negative", s.Scheduling.MaxRunnerRestarts)
+	}
+	for model, fallbacks := range s.Scheduling.Fallbacks {
+		if model == "" || slices.Contains(fallbacks, "") {
+			return fmt.Errorf("invalid scheduling.fallbacks: models can't be empty")
+		}
+	}
+	for i, guardrail := range s.Guardrails {
+		if err := guardrail.Validate(); err != nil {
+			return fmt.Errorf("invalid guardrails[%d]: %w", i, err)
+		}
+	}
+	if err := middleware.ValidateOrigins(s.CORS.AllowedOrigins); err != nil {
+		return fmt.Errorf("invalid cors.allowed-origins: %w", err)
+	}
+	if _, err := s.Store.pushChunkSize(); err != nil {
+		return err
+	}
+	if _, err := s.Store.diskHeadroom(); err != nil {
+		return err
+	}
+	if _, err := s.AccessLog.maxSize(); err != nil {
+		return err
+	}
+	if _, err := s.Capture.maxSize(); err != nil {
+		return err
+	}
+	if _, err := s.Images.maxSize(); err != nil {
+		return err
+	}
+	if s.Images.MaxDimension < 0 {
+		return fmt.Errorf("invalid images.max-dimension %d: must be non-negative", s.Images.MaxDimension)
+	}
+	if s.AccessLog.MaxFiles < 0 {
+		return fmt.Errorf("invalid access-log.max-files %d: must be positive", s.AccessLog.MaxFiles)
+	}
+	if s.Capture.MaxFiles < 0 {
+		return fmt.Errorf("invalid capture.max-files %d: must be positive", s.Capture.MaxFiles)
+	}
+	for _, arg := range s.Backends.LlamaCpp.Args {
+		if slices.Contains(disallowedLlamaCppArgs, arg) {
+			return fmt.Errorf("invalid backends.llama.cpp.args: cannot override the %s argument as it is controlled by the model runner", arg)
+		}
+	}
+	return nil
+}
+
+// pushChunkSize returns the size of the chunks in which layers are pushed,
+// zero for the default, or a negative size if chunked pushes are disabled
+// with a size of 0.
+func (s storeSettings) pushChunkSize() (int64, error) {
+	if s.PushChunkSize == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(s.PushChunkSize)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid store.push-chunk-size %q: must be a size", s.PushChunkSize)
+	}
+	if size == 0 {
+		return -1, nil
+	}
+	return size, nil
+}
+
+// diskHeadroom returns the free space pulls must leave on the volume of the
+// store they write to.
+func (s storeSettings) diskHeadroom() (uint64, error) {
+	if s.DiskHeadroom == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(s.DiskHeadroom)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid store.disk-headroom %q: must be a size", s.DiskHeadroom)
+	}
+	return uint64(size), nil
+}
+
+// maxSize returns the size beyond which the access log is rotated, or zero for
+// the default.
+func (a accessLogSettings) maxSize() (int64, error) {
+	if a.MaxSize == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(a.MaxSize)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid access-log.max-size %q: must be a positive size", a.MaxSize)
+	}
+	return size, nil
+}
+
+// maxSize returns the size beyond which the capture file of a model is rotated,
+// or zero for the default.
+func (c captureSettings) maxSize() (int64, error) {
+	if c.MaxSize == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(c.MaxSize)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid capture.max-size %q: must be a positive size", c.MaxSize)
+	}
+	return size, nil
+}
+
+// maxSize returns the maximum size of images, or zero for the default.
+func (i imageSettings) maxSize() (int64, error) {
+	if i.MaxSize == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(i.MaxSize)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid images.max-size %q: must be a positive size", i.MaxSize)
+	}
+	return size, nil
+}
+
+// parseStores parses a comma-separated list of additional stores, each given
+// as name=path, with a :ro suffix for read-only stores.
+func parseStores(v string) ([]distribution.StoreConfig, error) {
+	var stores []distribution.StoreConfig
+	for _, element := range splitList(v) {
+		name, path, ok := strings.Cut(element, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("store %q must be given as name=path", element)
+		}
+		store := distribution.StoreConfig{Name: name, RootPath: path}
+		if p, ok := strings.CutSuffix(path, ":ro"); ok {
+			store.RootPath = p
+			store.ReadOnly = true
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+// splitList splits a comma-separated list, dropping empty elements.
+func splitList(v string) (list []string) {
+	for _, element := range strings.Split(v, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+// reload returns the settings with those that can change at runtime replaced
+// by the reloaded ones: the llama.cpp arguments, preloaded models, idle
+// timeouts and allowed origins. It also returns the names of the other
+// settings that changed, which only take effect on restart.
+func (s settings) reload(reloaded settings) (settings, []string) {
+	applied := s
+	applied.Backends.LlamaCpp.Args = reloaded.Backends.LlamaCpp.Args
+	applied.Preload = reloaded.Preload
+	applied.Scheduling.RunnerIdleTimeout = reloaded.Scheduling.RunnerIdleTimeout
+	applied.Scheduling.DeepSleepTimeout = reloaded.Scheduling.DeepSleepTimeout
+	applied.CORS.AllowedOrigins = reloaded.CORS.AllowedOrigins
+	return applied, changedSettings("", reflect.ValueOf(applied), reflect.ValueOf(reloaded))
+}
+
+// changedSettings returns the names of the settings that differ between two
+// values of the same settings struct, qualified by prefix.
+func changedSettings(prefix string, a, b reflect.Value) []string {
+	var changed []string
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type.NumField() > 0 {
+			changed = append(changed, changedSettings(name, a.Field(i), b.Field(i))...)
+		} else if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// loadSettings returns the effective settings: the defaults, overridden by the
+// configuration file named by MODEL_RUNNER_CONFIG, if any, and then by
+// environment variables.
+func loadSettings(userHomeDir string) (settings, error) {
+	s := defaultSettings(userHomeDir)
+	if path := os.Getenv("MODEL_RUNNER_CONFIG"); path != "" {
+		if err := s.loadConfigFile(path); err != nil {
+			return settings{}, fmt.Errorf("reading configuration file %s: %w", path, err)
+		}
+	}
+	if err := s.applyEnv(); err != nil {
+		return settings{}, err
+	}
+	s.normalize()
+	if err := s.validate(); err != nil {
+		return settings{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+)
+
+func TestLoadSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+store:
+  path: /var/lib/models
+listen:
+  port: "13434"
+backends:
+  llama.cpp:
+    args: ["--threads", "4"]
+preload:
+  - ai/smollm2
+scheduling:
+  runner-idle-timeout: 15m
+  drain-timeout: 1m
+  fallbacks:
+    ai/llama3.3: [ai/llama3.2, ai/smollm2]
+cors:
+  allowed-origins: ["https://*.example.com"]
+metrics:
+  enabled: false
+capture:
+  dir: /var/lib/model-runner/capture
+  max-size: 10MB
+images:
+  allowed-schemes: [Data, HTTPS]
+guardrails:
+  - models: [ai/smollm2]
+    blocklist: ["(?i)password"]
+    max-prompt-length: 8000
+  - scrub-pii: true
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MODEL_RUNNER_CONFIG", path)
+	t.Setenv("MODEL_RUNNER_DRAIN_TIMEOUT", "2m")
+	t.Setenv("MODEL_RUNNER_PRELOAD", "ai/qwen3, ai/gemma3")
+
+	s, err := loadSettings("/home/user")
+	if err != nil {
+		t.Fatalf("loadSettings() error = %v", err)
+	}
+	if s.Store.Path != "/var/lib/models" || s.Listen.Port != "13434" || s.Metrics.Enabled {
+		t.Errorf("Expected settings from the configuration file, got %+v", s)
+	}
+	if s.Listen.Socket != "model-runner.sock" || !s.Backends.LlamaCpp.Update {
+		t.Errorf("Expected defaults for unset settings, got %+v", s)
+	}
+	if !reflect.DeepEqual(s.Backends.LlamaCpp.Args, []string{"--threads", "4"}) {
+		t.Errorf("Expected llama.cpp arguments from the configuration file, got %v", s.Backends.LlamaCpp.Args)
+	}
+	if time.Duration(s.Scheduling.RunnerIdleTimeout) != 15*time.Minute {
+		t.Errorf("Expected a 15m runner idle timeout, got %v", time.Duration(s.Scheduling.RunnerIdleTimeout))
+	}
+	if time.Duration(s.Scheduling.DrainTimeout) != 2*time.Minute {
+		t.Errorf("Expected the environment to override the drain timeout, got %v", time.Duration(s.Scheduling.DrainTimeout))
+	}
+	if !reflect.DeepEqual(s.Scheduling.Fallbacks, map[string][]string{"ai/llama3.3": {"ai/llama3.2", "ai/smollm2"}}) {
+		t.Errorf("Expected fallback models from the configuration file, got %v", s.Scheduling.Fallbacks)
+	}
+	if s.Capture.Dir != "/var/lib/model-runner/capture" {
+		t.Errorf("Expected the capture directory from the configuration file, got %q", s.Capture.Dir)
+	}
+	if size, _ := s.Capture.maxSize(); size != 10*1024*1024 {
+		t.Errorf("Expected a 10MB capture file size from the configuration file, got %d", size)
+	}
+	if !reflect.DeepEqual(s.Images.AllowedSchemes, []string{"data", "https"}) {
+		t.Errorf("Expected lower-cased image URL schemes, got %v", s.Images.AllowedSchemes)
+	}
+	if len(s.Guardrails) != 2 || !reflect.DeepEqual(s.Guardrails[0].Models, []string{"ai/smollm2"}) ||
+		s.Guardrails[0].MaxPromptLength != 8000 || !s.Guardrails[1].ScrubPII {
+		t.Errorf("Expected guardrails from the configuration file, got %+v", s.Guardrails)
+	}
+	if !reflect.DeepEqual(s.Preload, []string{"ai/qwen3", "ai/gemma3"}) {
+		t.Errorf("Expected the environment to override preloaded models, got %v", s.Preload)
+	}
+
+	// Durations are reported as strings.
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Failed to encode settings: %v", err)
+	}
+	if !strings.Contains(string(data), `"drain-timeout":"2m0s"`) {
+		t.Errorf("Expected a readable drain timeout, got %s", data)
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		config string
+		env    map[string]string
+	}{
+		"UnknownKey":         {config: "metrics:\n  enable: false\n"},
+		"InvalidDuration":    {config: "scheduling:\n  drain-timeout: soon\n"},
+		"InvalidOrigin":      {config: "cors:\n  allowed-origins: [example.com]\n"},
+		"InvalidSize":        {config: "access-log:\n  max-size: big\n"},
+		"InvalidCaptureSize": {config: "capture:\n  max-size: big\n"},
+		"InvalidChunkSize":   {config: "store:\n  push-chunk-size: big\n"},
+		"InvalidHeadroom":    {config: "store:\n  disk-headroom: -1GB\n"},
+		"DisallowedArg":      {config: "backends:\n  llama.cpp:\n    args: [--host, 0.0.0.0]\n"},
+		"InvalidImageSize":   {config: "images:\n  max-size: 0\n"},
+		"EmptyFallback":      {config: "scheduling:\n  fallbacks:\n    ai/llama3.3: [\"\"]\n"},
+		"EmptyGuardrail":     {config: "guardrails:\n  - models: [ai/smollm2]\n"},
+		"InvalidBlocklist":   {config: "guardrails:\n  - blocklist: [\"(\"]\n"},
+		"InvalidEnvInt":      {env: map[string]string{"MODEL_RUNNER_MAX_CONCURRENT_REQUESTS": "-1"}},
+		"InvalidEnvOrigin":   {env: map[string]string{"MODEL_RUNNER_ALLOWED_ORIGINS": "*,http://foo.com"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if test.config != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(test.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("MODEL_RUNNER_CONFIG", path)
+			}
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			if _, err := loadSettings("/home/user"); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	current := defaultSettings("/home/user")
+	reloaded := defaultSettings("/home/user")
+	reloaded.Preload = []string{"ai/smollm2"}
+	reloaded.Scheduling.RunnerIdleTimeout = duration(time.Hour)
+	reloaded.Scheduling.DrainTimeout = duration(time.Hour)
+	reloaded.Listen.Port = "13434"
+	reloaded.Listen.TLS.Cert = "/etc/model-runner/cert.pem"
+
+	applied, ignored := current.reload(reloaded)
+	if !reflect.DeepEqual(applied.Preload, reloaded.Preload) || applied.Scheduling.RunnerIdleTimeout != duration(time.Hour) {
+		t.Errorf("Expected the settings that can change at runtime to be applied, got %+v", applied)
+	}
+	if applied.Scheduling.DrainTimeout != current.Scheduling.DrainTimeout || applied.Listen != current.Listen {
+		t.Errorf("Expected the other settings to be kept, got %+v", applied)
+	}
+	if expected := []string{"listen.port", "listen.tls.cert", "scheduling.drain-timeout"}; !reflect.DeepEqual(ignored, expected) {
+		t.Errorf("Expected the ignored settings %v, got %v", expected, ignored)
+	}
+}
+
+func TestParseStores(t *testing.T) {
+	stores, err := parseStores("shared=/mnt/models:ro, team=/srv/models")
+	if err != nil {
+		t.Fatalf("parseStores() error = %v", err)
+	}
+	want := []distribution.StoreConfig{
+		{Name: "shared", RootPath: "/mnt/models", ReadOnly: true},
+		{Name: "team", RootPath: "/srv/models"},
+	}
+	if !reflect.DeepEqual(stores, want) {
+		t.Errorf("Expected %+v, got %+v", want, stores)
+	}
+	if _, err := parseStores("/mnt/models"); err == nil {
+		t.Error("Expected an error for a store without a name")
+	}
+}
@@ -2,267 +2,279 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
-	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/docker/model-runner/pkg/distribution/transport/resumable"
-	"github.com/docker/model-runner/pkg/gpuinfo"
-	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
-	"github.com/docker/model-runner/pkg/inference/backends/vllm"
 	"github.com/docker/model-runner/pkg/inference/config"
 	"github.com/docker/model-runner/pkg/inference/memory"
-	"github.com/docker/model-runner/pkg/inference/models"
 	"github.com/docker/model-runner/pkg/inference/scheduling"
 	"github.com/docker/model-runner/pkg/metrics"
-	"github.com/docker/model-runner/pkg/routing"
+	"github.com/docker/model-runner/pkg/middleware"
+	"github.com/docker/model-runner/pkg/server"
+	"github.com/docker/model-runner/pkg/tracing/otlp"
 	"github.com/sirupsen/logrus"
 )
 
 var log = logrus.New()
 
-// V1AliasHandler provides an alias from /v1/ to /engines/v1/ paths
-type V1AliasHandler struct {
-	scheduler http.Handler
-}
-
-func (h *V1AliasHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Modify the URL path to prepend /engines/ before /v1/
-	originalPath := r.URL.Path
-	newPath := inference.InferencePrefix + originalPath // originalPath is like "/v1/models", so result is "/engines/v1/models"
-
-	// Create a clone of the request with the modified path
-	r2 := new(http.Request)
-	*r2 = *r
-	r2.URL = new(url.URL)
-	*r2.URL = *r.URL
-	r2.URL.Path = newPath
-
-	// Pass the modified request to the scheduler
-	h.scheduler.ServeHTTP(w, r2)
-}
-
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	sockName := os.Getenv("MODEL_RUNNER_SOCK")
-	if sockName == "" {
-		sockName = "model-runner.sock"
-	}
-
 	userHomeDir, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatalf("Failed to get user home directory: %v", err)
 	}
 
-	modelPath := os.Getenv("MODELS_PATH")
-	if modelPath == "" {
-		modelPath = filepath.Join(userHomeDir, ".docker", "models")
+	// Read the configuration file, if any, and override it with environment
+	// variables.
+	settings, err := loadSettings(userHomeDir)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	_, disableServerUpdate := os.LookupEnv("DISABLE_SERVER_UPDATE")
-	if disableServerUpdate {
+	if !settings.Backends.LlamaCpp.Update {
 		llamacpp.ShouldUpdateServerLock.Lock()
 		llamacpp.ShouldUpdateServer = false
 		llamacpp.ShouldUpdateServerLock.Unlock()
 	}
 
-	desiredServerVersion, ok := os.LookupEnv("LLAMA_SERVER_VERSION")
-	if ok {
-		llamacpp.SetDesiredServerVersion(desiredServerVersion)
-	}
-
-	llamaServerPath := os.Getenv("LLAMA_SERVER_PATH")
-	if llamaServerPath == "" {
-		llamaServerPath = "/Applications/Docker.app/Contents/Resources/model-runner/bin"
+	if settings.Backends.LlamaCpp.ServerVersion != "" {
+		llamacpp.SetDesiredServerVersion(settings.Backends.LlamaCpp.ServerVersion)
 	}
 
-	gpuInfo := gpuinfo.New(llamaServerPath)
-
-	sysMemInfo, err := memory.NewSystemMemoryInfo(log, gpuInfo)
-	if err != nil {
-		log.Fatalf("unable to initialize system memory info: %v", err)
+	if variant := settings.Backends.LlamaCpp.Variant; variant != "" {
+		if err := llamacpp.SetDesiredServerVariant(variant); err != nil {
+			log.Fatalf("Invalid llama.cpp variant: %v", err)
+		}
 	}
 
-	memEstimator := memory.NewEstimator(sysMemInfo)
-
-	// Create a proxy-aware HTTP transport
-	// Use a safe type assertion with fallback, and explicitly set Proxy to http.ProxyFromEnvironment
-	var baseTransport *http.Transport
-	if t, ok := http.DefaultTransport.(*http.Transport); ok {
-		baseTransport = t.Clone()
-	} else {
-		baseTransport = &http.Transport{}
+	if settings.Scheduling.RuntimeMemoryCheck {
+		memory.SetRuntimeMemoryCheck(true)
 	}
-	baseTransport.Proxy = http.ProxyFromEnvironment
-
-	modelManager := models.NewManager(
-		log,
-		models.ClientConfig{
-			StoreRootPath: modelPath,
-			Logger:        log.WithFields(logrus.Fields{"component": "model-manager"}),
-			Transport:     resumable.New(baseTransport),
-		},
-		nil,
-		memEstimator,
-	)
-
-	log.Infof("LLAMA_SERVER_PATH: %s", llamaServerPath)
-
-	// Create llama.cpp configuration from environment variables
-	llamaCppConfig := createLlamaCppConfigFromEnv()
 
-	llamaCppBackend, err := llamacpp.New(
-		log,
-		modelManager,
-		log.WithFields(logrus.Fields{"component": llamacpp.Name}),
-		llamaServerPath,
-		func() string {
+	cfg := server.Config{
+		Log:             log,
+		ModelPath:       settings.Store.Path,
+		LlamaServerPath: settings.Backends.LlamaCpp.ServerPath,
+		LlamaServerUpdatePath: func() string {
 			wd, _ := os.Getwd()
 			d := filepath.Join(wd, "updated-inference", "bin")
 			_ = os.MkdirAll(d, 0o755)
 			return d
 		}(),
-		llamaCppConfig,
-	)
-	if err != nil {
-		log.Fatalf("unable to initialize %s backend: %v", llamacpp.Name, err)
+		LlamaCppConfig:        createLlamaCppConfig(settings.Backends.LlamaCpp.Args),
+		WhisperServerPath:     settings.Backends.WhisperCpp.ServerPath,
+		PiperServerPath:       settings.Backends.Piper.ServerPath,
+		SDServerPath:          settings.Backends.StableDiffusionCpp.ServerPath,
+		ONNXServerPath:        settings.Backends.ONNX.ServerPath,
+		CatalogURL:            settings.Store.CatalogURL,
+		RepairStore:           settings.Store.Repair,
+		Stores:                settings.Store.Additional,
+		WritableStore:         settings.Store.Writable,
+		ReadOnlyStore:         settings.Store.ReadOnly,
+		BlobCache:             settings.Store.BlobCache,
+		RequireDigest:         settings.Store.RequireDigest,
+		HardlinkBundles:       settings.Store.HardlinkBundles,
+		TokenStore:            settings.Store.TokenStore,
+		MockBackend:           settings.Backends.Mock,
+		InjectionPolicy:       scheduling.DefaultInjectionPolicy,
+		ImagePolicy:           scheduling.DefaultImagePolicy,
+		DisableMetrics:        !settings.Metrics.Enabled,
+		PreloadModels:         settings.Preload,
+		RunnerIdleTimeout:     time.Duration(settings.Scheduling.RunnerIdleTimeout),
+		DeepSleepTimeout:      time.Duration(settings.Scheduling.DeepSleepTimeout),
+		MaxConcurrentRequests: settings.Scheduling.MaxConcurrentRequests,
+		MaxRunnerRestarts:     settings.Scheduling.MaxRunnerRestarts,
+		DrainTimeout:          time.Duration(settings.Scheduling.DrainTimeout),
+		FallbackModels:        settings.Scheduling.Fallbacks,
+		Guardrails:            settings.Guardrails,
+		AllowedOrigins:        settings.CORS.AllowedOrigins,
+		Settings:              settings,
 	}
 
-	if os.Getenv("MODEL_RUNNER_RUNTIME_MEMORY_CHECK") == "1" {
-		memory.SetRuntimeMemoryCheck(true)
+	// Configure the allow-list for per-model environment variables and mounts.
+	if len(settings.Injection.AllowedEnvPrefixes) > 0 {
+		cfg.InjectionPolicy.EnvPrefixes = settings.Injection.AllowedEnvPrefixes
+	}
+	if len(settings.Injection.AllowedMountDirs) > 0 {
+		cfg.InjectionPolicy.MountRoots = settings.Injection.AllowedMountDirs
 	}
 
-	memEstimator.SetDefaultBackend(llamaCppBackend)
+	// Configure the preprocessing of images sent to vision-language models.
+	if len(settings.Images.AllowedSchemes) > 0 {
+		cfg.ImagePolicy.AllowedSchemes = settings.Images.AllowedSchemes
+	}
+	if size, _ := settings.Images.maxSize(); size > 0 {
+		cfg.ImagePolicy.MaxSize = size
+	}
+	if settings.Images.MaxDimension > 0 {
+		cfg.ImagePolicy.MaxDimension = settings.Images.MaxDimension
+	}
+	cfg.ImagePolicy.AllowPrivateNetworks = settings.Images.AllowPrivateNetworks
+
+	// Log inference requests, if configured, rotating the log beyond its
+	// maximum size.
+	cfg.AccessLog = scheduling.AccessLogConfig{
+		Path:     settings.AccessLog.Path,
+		MaxFiles: settings.AccessLog.MaxFiles,
+		Prompts:  settings.AccessLog.Prompts,
+	}
+	cfg.AccessLog.MaxSize, _ = settings.AccessLog.maxSize()
+	// Capture inference requests and responses, if configured, rotating the
+	// capture files beyond their maximum size.
+	cfg.Capture = metrics.CaptureConfig{
+		Dir:      settings.Capture.Dir,
+		MaxFiles: settings.Capture.MaxFiles,
+	}
+	cfg.Capture.MaxSize, _ = settings.Capture.maxSize()
+	cfg.PushChunkSize, _ = settings.Store.pushChunkSize()
+	cfg.DiskHeadroom, _ = settings.Store.diskHeadroom()
+
+	// Require API keys on the TCP listener, if configured. The Unix socket is
+	// protected by its file permissions instead.
+	if keyFile := settings.Auth.APIKeyFile; keyFile != "" {
+		if settings.Listen.Port == "" {
+			log.Warnf("Ignoring the API key file without a TCP port")
+		} else {
+			keys, err := middleware.LoadAPIKeys(keyFile)
+			if err != nil {
+				log.Fatalf("Invalid API key file %q: %v", keyFile, err)
+			}
+			cfg.APIKeys = keys
+		}
+	}
 
-	vllmBackend, err := vllm.New(
-		log,
-		modelManager,
-		log.WithFields(logrus.Fields{"component": vllm.Name}),
-		nil,
-	)
+	// Export traces over OTLP, if an endpoint is configured.
+	shutdownTracing, err := otlp.Setup(ctx)
 	if err != nil {
-		log.Fatalf("unable to initialize %s backend: %v", vllm.Name, err)
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
+	if otlp.Enabled() {
+		log.Infoln("Exporting traces over OTLP")
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Warnf("Failed to flush traces: %v", err)
+		}
+	}()
 
-	scheduler := scheduling.NewScheduler(
-		log,
-		map[string]inference.Backend{llamacpp.Name: llamaCppBackend, vllm.Name: vllmBackend},
-		llamaCppBackend,
-		modelManager,
-		http.DefaultClient,
-		nil,
-		metrics.NewTracker(
-			http.DefaultClient,
-			log.WithField("component", "metrics"),
-			"",
-			false,
-		),
-		sysMemInfo,
-	)
-
-	router := routing.NewNormalizedServeMux()
-
-	// Register path prefixes to forward all HTTP methods (including OPTIONS) to components
-	// Components handle method routing internally
-	// Register both with and without trailing slash to avoid redirects
-	router.Handle(inference.ModelsPrefix, modelManager)
-	router.Handle(inference.ModelsPrefix+"/", modelManager)
-	router.Handle(inference.InferencePrefix+"/", scheduler)
-	// Add /v1 as an alias for /engines/v1
-	router.Handle("/v1/", &V1AliasHandler{scheduler: scheduler})
+	// Reload the settings that can change at runtime on request, so that the
+	// reported settings are those in effect.
+	current := settings
+	cfg.Reload = func() (server.Config, error) {
+		reloaded, err := loadSettings(userHomeDir)
+		if err != nil {
+			return server.Config{}, fmt.Errorf("invalid configuration: %w", err)
+		}
+		applied, ignored := current.reload(reloaded)
+		if len(ignored) > 0 {
+			log.Warnf("Ignoring changed settings that require a restart: %s", strings.Join(ignored, ", "))
+		}
+		current = applied
+		return server.Config{
+			LlamaCppConfig:    createLlamaCppConfig(applied.Backends.LlamaCpp.Args),
+			PreloadModels:     applied.Preload,
+			RunnerIdleTimeout: time.Duration(applied.Scheduling.RunnerIdleTimeout),
+			DeepSleepTimeout:  time.Duration(applied.Scheduling.DeepSleepTimeout),
+			AllowedOrigins:    applied.CORS.AllowedOrigins,
+			Settings:          applied,
+		}, nil
+	}
 
-	// Add metrics endpoint if enabled
-	if os.Getenv("DISABLE_METRICS") != "1" {
-		metricsHandler := metrics.NewAggregatedMetricsHandler(
-			log.WithField("component", "metrics"),
-			scheduler,
-		)
-		router.Handle("/metrics", metricsHandler)
-		log.Info("Metrics endpoint enabled at /metrics")
-	} else {
-		log.Info("Metrics endpoint disabled")
+	srv, err := server.New(cfg, server.Hooks{})
+	if err != nil {
+		log.Fatalf("Failed to initialize server: %v", err)
 	}
 
-	server := &http.Server{Handler: router}
-	serverErrors := make(chan error, 1)
+	// Reload the configuration on SIGHUP.
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	defer signal.Stop(reloadSignals)
+	go func() {
+		for range reloadSignals {
+			log.Infoln("Reloading configuration")
+			if err := srv.Reload(ctx); err != nil {
+				log.Errorf("Failed to reload configuration: %v", err)
+			}
+		}
+	}()
 
 	// Check if we should use TCP port instead of Unix socket
-	tcpPort := os.Getenv("MODEL_RUNNER_PORT")
-	if tcpPort != "" {
+	var ln net.Listener
+	if tcpPort := settings.Listen.Port; tcpPort != "" {
 		// Use TCP port
-		addr := ":" + tcpPort
 		log.Infof("Listening on TCP port %s", tcpPort)
-		server.Addr = addr
-		go func() {
-			serverErrors <- server.ListenAndServe()
-		}()
+		ln, err = net.Listen("tcp", ":"+tcpPort)
+		if err != nil {
+			log.Fatalf("Failed to listen on TCP port %s: %v", tcpPort, err)
+		}
+
+		// Terminate TLS, optionally requiring client certificates, if
+		// configured.
+		if t := settings.Listen.TLS; t.Cert != "" || t.Key != "" || t.ClientCA != "" {
+			tlsConfig, err := server.TLSConfig(t.Cert, t.Key, t.ClientCA)
+			if err != nil {
+				log.Fatalf("Invalid TLS configuration: %v", err)
+			}
+			ln = tls.NewListener(ln, tlsConfig)
+			if t.ClientCA != "" {
+				log.Infoln("Serving TLS and requiring client certificates")
+			} else {
+				log.Infoln("Serving TLS")
+			}
+		}
 	} else {
 		// Use Unix socket
+		sockName := settings.Listen.Socket
 		if err := os.Remove(sockName); err != nil {
 			if !os.IsNotExist(err) {
 				log.Fatalf("Failed to remove existing socket: %v", err)
 			}
 		}
-		ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: sockName, Net: "unix"})
+		ln, err = net.ListenUnix("unix", &net.UnixAddr{Name: sockName, Net: "unix"})
 		if err != nil {
 			log.Fatalf("Failed to listen on socket: %v", err)
 		}
-		go func() {
-			serverErrors <- server.Serve(ln)
-		}()
 	}
 
-	schedulerErrors := make(chan error, 1)
-	go func() {
-		schedulerErrors <- scheduler.Run(ctx)
-	}()
-
-	select {
-	case err := <-serverErrors:
-		if err != nil {
-			log.Errorf("Server error: %v", err)
-		}
-	case <-ctx.Done():
-		log.Infoln("Shutdown signal received")
-		log.Infoln("Shutting down the server")
-		if err := server.Close(); err != nil {
-			log.Errorf("Server shutdown error: %v", err)
-		}
-		log.Infoln("Waiting for the scheduler to stop")
-		if err := <-schedulerErrors; err != nil {
-			log.Errorf("Scheduler error: %v", err)
-		}
+	if err := srv.Serve(ctx, ln); err != nil {
+		log.Errorf("%v", err)
 	}
 	log.Infoln("Docker Model Runner stopped")
 }
 
+// disallowedLlamaCppArgs are the llama.cpp arguments controlled by the model
+// runner.
+var disallowedLlamaCppArgs = []string{"--model", "--host", "--embeddings", "--mmproj"}
+
 // createLlamaCppConfigFromEnv creates a LlamaCppConfig from environment variables
 func createLlamaCppConfigFromEnv() config.BackendConfig {
-	// Check if any configuration environment variables are set
-	argsStr := os.Getenv("LLAMA_ARGS")
+	return createLlamaCppConfig(splitArgs(os.Getenv("LLAMA_ARGS")))
+}
 
-	// If no environment variables are set, use default configuration
-	if argsStr == "" {
+// createLlamaCppConfig creates a LlamaCppConfig with custom arguments
+func createLlamaCppConfig(args []string) config.BackendConfig {
+	// If no arguments are set, use default configuration
+	if len(args) == 0 {
 		return nil // nil will cause the backend to use its default configuration
 	}
 
-	// Split the string by spaces, respecting quoted arguments
-	args := splitArgs(argsStr)
-
 	// Check for disallowed arguments
-	disallowedArgs := []string{"--model", "--host", "--embeddings", "--mmproj"}
 	for _, arg := range args {
-		for _, disallowed := range disallowedArgs {
+		for _, disallowed := range disallowedLlamaCppArgs {
 			if arg == disallowed {
-				log.Fatalf("LLAMA_ARGS cannot override the %s argument as it is controlled by the model runner", disallowed)
+				log.Fatalf("llama.cpp arguments cannot override the %s argument as it is controlled by the model runner", disallowed)
 			}
 		}
 	}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package diskusage
+
+import "golang.org/x/sys/unix"
+
+// Free returns the number of bytes available to unprivileged users on the
+// file system containing path.
+func Free(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -7,9 +7,12 @@ import (
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
+	"github.com/docker/model-runner/pkg/distribution/internal/diffusion"
 	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
 	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/internal/onnx"
 	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/internal/piper"
 	"github.com/docker/model-runner/pkg/distribution/internal/safetensors"
 	"github.com/docker/model-runner/pkg/distribution/types"
 )
@@ -17,7 +20,9 @@ import (
 // Builder builds a model artifact
 type Builder struct {
 	model          types.ModelArtifact
-	originalLayers []v1.Layer // Snapshot of layers when created from existing model
+	originalLayers []v1.Layer   // Snapshot of layers when created from existing model
+	tempDirs       []string     // Temporary directories holding generated layer files
+	deltas         []deltaLayer // Deltas written alongside the model
 }
 
 // FromGGUF returns a *Builder that builds a model artifacts from a GGUF file
@@ -42,6 +47,55 @@ func FromSafetensors(safetensorsPaths []string) (*Builder, error) {
 	}, nil
 }
 
+// FromWhisper returns a *Builder that builds model artifacts from a whisper.cpp
+// speech-to-text model file
+func FromWhisper(path string) (*Builder, error) {
+	mdl, err := gguf.NewWhisperModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		model: mdl,
+	}, nil
+}
+
+// FromVoice returns a *Builder that builds model artifacts from a piper
+// text-to-speech voice file, whose config is read from next to it
+func FromVoice(path string) (*Builder, error) {
+	mdl, err := piper.NewModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		model: mdl,
+	}, nil
+}
+
+// FromDiffusion returns a *Builder that builds model artifacts from a
+// stable-diffusion.cpp image generation checkpoint
+func FromDiffusion(path string) (*Builder, error) {
+	mdl, err := diffusion.NewModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		model: mdl,
+	}, nil
+}
+
+// FromONNX returns a *Builder that builds model artifacts from an ONNX
+// embedding or classification model. Its tokenizer must be added with
+// WithConfigArchive.
+func FromONNX(path string) (*Builder, error) {
+	mdl, err := onnx.NewModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{
+		model: mdl,
+	}, nil
+}
+
 // FromModel returns a *Builder that builds model artifacts from an existing model artifact
 func FromModel(mdl types.ModelArtifact) (*Builder, error) {
 	// Capture original layers for comparison
@@ -64,14 +118,83 @@ func (b *Builder) WithLicense(path string) (*Builder, error) {
 	return &Builder{
 		model:          mutate.AppendLayers(b.model, licenseLayer),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
 	}, nil
 }
 
+// WithLicenseAcceptanceRequired marks the licenses of the artifact as requiring
+// acceptance before the artifact can be pulled.
+func (b *Builder) WithLicenseAcceptanceRequired() *Builder {
+	return &Builder{
+		model:          mutate.LicenseAcceptanceRequired(b.model),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}
+}
+
+// WithReranker marks the model as a reranker, which is served in reranking
+// mode.
+func (b *Builder) WithReranker() *Builder {
+	return &Builder{
+		model:          mutate.Reranker(b.model),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}
+}
+
 func (b *Builder) WithContextSize(size uint64) *Builder {
 	return &Builder{
 		model:          mutate.ContextSize(b.model, size),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}
+}
+
+// WithConfigOverrides overrides the architecture, parameters, and quantization
+// recorded in the model config. Empty fields in overrides are left unchanged.
+func (b *Builder) WithConfigOverrides(overrides types.Config) *Builder {
+	return &Builder{
+		model: mutate.ConfigOverrides(b.model, types.Config{
+			Architecture: overrides.Architecture,
+			Parameters:   overrides.Parameters,
+			Quantization: overrides.Quantization,
+		}),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}
+}
+
+// WithBaseModel records a dependency on the base model with the given manifest
+// digest. The reference, if set, is where the base model can be pulled from.
+func (b *Builder) WithBaseModel(base types.BaseModel) (*Builder, error) {
+	if _, err := v1.NewHash(base.Digest); err != nil {
+		return nil, fmt.Errorf("invalid base model digest %q: %w", base.Digest, err)
+	}
+	return &Builder{
+		model:          mutate.BaseModel(b.model, base),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}, nil
+}
+
+// WithAnnotation adds an annotation to the manifest of the artifact, such as a
+// label used to organize models. The key must not be empty.
+func (b *Builder) WithAnnotation(key, value string) (*Builder, error) {
+	if key == "" {
+		return nil, fmt.Errorf("annotation key must not be empty")
 	}
+	return &Builder{
+		model:          mutate.Annotations(b.model, map[string]string{key: value}),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}, nil
 }
 
 // WithMultimodalProjector adds a Multimodal projector file to the artifact
@@ -83,6 +206,22 @@ func (b *Builder) WithMultimodalProjector(path string) (*Builder, error) {
 	return &Builder{
 		model:          mutate.AppendLayers(b.model, mmprojLayer),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
+	}, nil
+}
+
+// WithLoRAAdapter adds a LoRA adapter file (in GGUF format) to the artifact
+func (b *Builder) WithLoRAAdapter(path string) (*Builder, error) {
+	adapterLayer, err := partial.NewLayer(path, types.MediaTypeLoRAAdapter)
+	if err != nil {
+		return nil, fmt.Errorf("lora adapter layer from %q: %w", path, err)
+	}
+	return &Builder{
+		model:          mutate.AppendLayers(b.model, adapterLayer),
+		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
 	}, nil
 }
 
@@ -95,6 +234,8 @@ func (b *Builder) WithChatTemplateFile(path string) (*Builder, error) {
 	return &Builder{
 		model:          mutate.AppendLayers(b.model, templateLayer),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
 	}, nil
 }
 
@@ -108,7 +249,7 @@ func (b *Builder) WithConfigArchive(path string) (*Builder, error) {
 
 	for _, layer := range layers {
 		mediaType, err := layer.MediaType()
-		if err == nil && mediaType == types.MediaTypeVLLMConfigArchive {
+		if err == nil && (mediaType == types.MediaTypeVLLMConfigArchive || mediaType == types.MediaTypeVLLMConfigArchiveZstd) {
 			return nil, fmt.Errorf("model already has a config archive layer")
 		}
 	}
@@ -120,6 +261,8 @@ func (b *Builder) WithConfigArchive(path string) (*Builder, error) {
 	return &Builder{
 		model:          mutate.AppendLayers(b.model, configLayer),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
 	}, nil
 }
 
@@ -133,6 +276,8 @@ func (b *Builder) WithDirTar(path string) (*Builder, error) {
 	return &Builder{
 		model:          mutate.AppendLayers(b.model, dirTarLayer),
 		originalLayers: b.originalLayers,
+		tempDirs:       b.tempDirs,
+		deltas:         b.deltas,
 	}, nil
 }
 
@@ -146,9 +291,32 @@ func (b *Builder) Model() types.ModelArtifact {
 	return b.model
 }
 
-// Build finalizes the artifact and writes it to the given target, reporting progress to the given writer
+// DeltaTarget is a Target that also stores deltas for the models written to
+// it.
+type DeltaTarget interface {
+	Target
+	WriteDelta(ctx context.Context, mdl types.ModelArtifact, delta v1.Layer, annotations map[string]string) error
+}
+
+// Build finalizes the artifact and writes it to the given target, reporting progress to the given writer.
+// Deltas added with WithDelta are written once the model is, and require a DeltaTarget.
 func (b *Builder) Build(ctx context.Context, target Target, pw io.Writer) error {
-	return target.Write(ctx, b.model, pw)
+	if len(b.deltas) == 0 {
+		return target.Write(ctx, b.model, pw)
+	}
+	deltaTarget, ok := target.(DeltaTarget)
+	if !ok {
+		return fmt.Errorf("target doesn't support deltas")
+	}
+	if err := target.Write(ctx, b.model, pw); err != nil {
+		return err
+	}
+	for _, d := range b.deltas {
+		if err := deltaTarget.WriteDelta(ctx, b.model, d.layer, d.annotations); err != nil {
+			return fmt.Errorf("writing delta: %w", err)
+		}
+	}
+	return nil
 }
 
 // HasOnlyConfigChanges returns true if the builder was created from an existing model
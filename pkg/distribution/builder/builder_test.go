@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/docker/model-runner/pkg/distribution/builder"
+	"github.com/docker/model-runner/pkg/distribution/packaging"
 	"github.com/docker/model-runner/pkg/distribution/types"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
@@ -75,6 +78,110 @@ func TestWithMultimodalProjectorInvalidPath(t *testing.T) {
 	}
 }
 
+func TestWithLoRAAdapter(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+
+	// Adapters keep the order in which they were added
+	for i := 0; i < 2; i++ {
+		b, err = b.WithLoRAAdapter(filepath.Join("..", "assets", "dummy.gguf"))
+		if err != nil {
+			t.Fatalf("Failed to add LoRA adapter: %v", err)
+		}
+	}
+
+	manifest, err := b.Model().Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if len(manifest.Layers) != 3 {
+		t.Fatalf("Expected 3 layers, got %d", len(manifest.Layers))
+	}
+	for _, l := range manifest.Layers[1:] {
+		if l.MediaType != types.MediaTypeLoRAAdapter {
+			t.Errorf("Expected layer with media type %s, got %s", types.MediaTypeLoRAAdapter, l.MediaType)
+		}
+	}
+
+	if _, err := b.WithLoRAAdapter("nonexistent/path/to/adapter.gguf"); err == nil {
+		t.Error("Expected error when adding LoRA adapter with invalid path")
+	}
+}
+
+func TestFromWhisper(t *testing.T) {
+	b, err := builder.FromWhisper(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from whisper model: %v", err)
+	}
+
+	manifest, err := b.Model().Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != types.MediaTypeWhisperGGUF {
+		t.Fatalf("Expected a single layer with media type %s, got %+v", types.MediaTypeWhisperGGUF, manifest.Layers)
+	}
+
+	cfg, err := b.Model().Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Format != types.FormatWhisper {
+		t.Errorf("Expected format %s, got %s", types.FormatWhisper, cfg.Format)
+	}
+	if cfg.ContextSize != nil {
+		t.Errorf("Expected no context size, got %d", *cfg.ContextSize)
+	}
+
+	if _, err := builder.FromWhisper("nonexistent/path/to/model.bin"); err == nil {
+		t.Error("Expected error when creating whisper model with invalid path")
+	}
+}
+
+func TestFromONNX(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"model.onnx":     "\x08\x07onnx",
+		"config.json":    `{"architectures": ["BertModel"]}`,
+		"tokenizer.json": "{}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	onnxPath, configArchive, err := packaging.PackageONNXFromDirectory(dir)
+	if err != nil {
+		t.Fatalf("Failed to package ONNX directory: %v", err)
+	}
+	defer os.Remove(configArchive)
+
+	b, err := builder.FromONNX(onnxPath)
+	if err != nil {
+		t.Fatalf("Failed to create builder from ONNX model: %v", err)
+	}
+	if b, err = b.WithConfigArchive(configArchive); err != nil {
+		t.Fatalf("Failed to add config archive: %v", err)
+	}
+
+	manifest, err := b.Model().Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if len(manifest.Layers) != 2 || manifest.Layers[0].MediaType != types.MediaTypeONNX ||
+		manifest.Layers[1].MediaType != types.MediaTypeVLLMConfigArchive {
+		t.Fatalf("Expected ONNX and config archive layers, got %+v", manifest.Layers)
+	}
+	cfg, err := b.Model().Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Format != types.FormatONNX || cfg.Architecture != "BertModel" || cfg.Classifier {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
 func TestWithMultimodalProjectorChaining(t *testing.T) {
 	// Create a builder from a GGUF file
 	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
@@ -336,3 +443,245 @@ type mockFailingModel struct {
 func (m *mockFailingModel) Layers() ([]v1.Layer, error) {
 	return nil, fmt.Errorf("simulated layers error")
 }
+
+func TestWithQuantization(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake quantize tool requires a POSIX shell")
+	}
+
+	// Install a fake quantize tool that copies its input to its output
+	tool := filepath.Join(t.TempDir(), "llama-quantize")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\ncp \"$1\" \"$2\"\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write fake quantize tool: %v", err)
+	}
+	orig := builder.QuantizeBinary
+	builder.QuantizeBinary = tool
+	defer func() { builder.QuantizeBinary = orig }()
+
+	source, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+	sourceLayers, err := source.Model().Layers()
+	if err != nil {
+		t.Fatalf("Failed to get source layers: %v", err)
+	}
+	sourceDigest, err := sourceLayers[0].Digest()
+	if err != nil {
+		t.Fatalf("Failed to get source digest: %v", err)
+	}
+
+	b, err := source.WithQuantization("Q4_K_M")
+	if err != nil {
+		t.Fatalf("Failed to quantize model: %v", err)
+	}
+	defer b.Cleanup()
+
+	b, err = b.WithLicense(filepath.Join("..", "assets", "license.txt"))
+	if err != nil {
+		t.Fatalf("Failed to add license: %v", err)
+	}
+
+	target := &fakeTarget{}
+	if err := b.Build(t.Context(), target, nil); err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+
+	config, err := target.artifact.Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if config.QuantizedFrom == nil {
+		t.Fatal("Expected quantization info in config")
+	}
+	if config.QuantizedFrom.SourceDigest != sourceDigest.String() {
+		t.Errorf("Expected source digest %s, got %s", sourceDigest, config.QuantizedFrom.SourceDigest)
+	}
+	if config.QuantizedFrom.Target != "Q4_K_M" {
+		t.Errorf("Expected target Q4_K_M, got %s", config.QuantizedFrom.Target)
+	}
+
+	manifest, err := target.artifact.Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if len(manifest.Layers) != 2 || manifest.Layers[0].MediaType != types.MediaTypeGGUF {
+		t.Fatalf("Expected GGUF and license layers, got %+v", manifest.Layers)
+	}
+}
+
+func TestWithQuantizationAfterOtherChanges(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+	b, err = b.WithLicense(filepath.Join("..", "assets", "license.txt"))
+	if err != nil {
+		t.Fatalf("Failed to add license: %v", err)
+	}
+	if _, err := b.WithQuantization("Q4_K_M"); err == nil {
+		t.Error("Expected error when quantizing after other changes")
+	}
+}
+
+func TestWithConfigOverrides(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+
+	b = b.WithConfigOverrides(types.Config{Quantization: "Q4_K_M"})
+
+	config, err := b.Model().Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if config.Quantization != "Q4_K_M" {
+		t.Errorf("Expected quantization Q4_K_M, got %s", config.Quantization)
+	}
+	// Fields without overrides keep the values read from the GGUF header
+	if config.Architecture != "llama" {
+		t.Errorf("Expected architecture llama, got %s", config.Architecture)
+	}
+}
+
+func TestWithReranker(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+
+	config, err := b.WithReranker().Model().Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if !config.Reranker {
+		t.Error("Expected the model to be marked as a reranker")
+	}
+	if config.Architecture != "llama" {
+		t.Errorf("Expected architecture llama, got %s", config.Architecture)
+	}
+}
+
+func TestWithBaseModel(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+
+	if _, err := b.WithBaseModel(types.BaseModel{Digest: "not-a-digest"}); err == nil {
+		t.Fatal("Expected error for invalid base model digest")
+	}
+
+	base := types.BaseModel{
+		Digest:    "sha256:" + strings.Repeat("a", 64),
+		Reference: "registry.example.com/models/base:v1",
+	}
+	b, err = b.WithBaseModel(base)
+	if err != nil {
+		t.Fatalf("Failed to set base model: %v", err)
+	}
+	cfg, err := b.Model().Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.BaseModel == nil || *cfg.BaseModel != base {
+		t.Errorf("Expected base model %+v, got %+v", base, cfg.BaseModel)
+	}
+}
+
+func TestWithAnnotation(t *testing.T) {
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+	b, err = b.WithAnnotation("team", "nlp")
+	if err != nil {
+		t.Fatalf("Failed to add annotation: %v", err)
+	}
+	b, err = b.WithAnnotation("stage", "prod")
+	if err != nil {
+		t.Fatalf("Failed to add annotation: %v", err)
+	}
+
+	// Annotations are kept when layers are added afterwards
+	b, err = b.WithLicense(filepath.Join("..", "assets", "license.txt"))
+	if err != nil {
+		t.Fatalf("Failed to add license: %v", err)
+	}
+
+	manifest, err := b.Model().Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if manifest.Annotations["team"] != "nlp" || manifest.Annotations["stage"] != "prod" {
+		t.Errorf("Expected team and stage annotations, got %v", manifest.Annotations)
+	}
+
+	if _, err := b.WithAnnotation("", "value"); err == nil {
+		t.Error("Expected error when adding an annotation with an empty key")
+	}
+}
+
+type fakeDeltaTarget struct {
+	fakeTarget
+	deltas []map[string]string
+}
+
+func (ft *fakeDeltaTarget) WriteDelta(ctx context.Context, mdl types.ModelArtifact, delta v1.Layer, annotations map[string]string) error {
+	if ft.artifact != mdl {
+		return fmt.Errorf("delta written before its model")
+	}
+	ft.deltas = append(ft.deltas, annotations)
+	return nil
+}
+
+func TestWithDelta(t *testing.T) {
+	// Use a modified copy of the dummy model as the base
+	data, err := os.ReadFile(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to read model: %v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	basePath := filepath.Join(t.TempDir(), "base.gguf")
+	if err := os.WriteFile(basePath, data, 0o644); err != nil {
+		t.Fatalf("Failed to write base: %v", err)
+	}
+
+	b, err := builder.FromGGUF(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create builder from GGUF: %v", err)
+	}
+	b, err = b.WithDelta(basePath)
+	if err != nil {
+		t.Fatalf("Failed to compute delta: %v", err)
+	}
+	defer b.Cleanup()
+
+	if err := b.Build(t.Context(), &fakeTarget{}, nil); err == nil {
+		t.Error("Expected an error building deltas to a target without delta support")
+	}
+
+	target := &fakeDeltaTarget{}
+	if err := b.Build(t.Context(), target, nil); err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	if len(target.deltas) != 1 {
+		t.Fatalf("Expected 1 delta, got %d", len(target.deltas))
+	}
+	layers, err := target.artifact.Layers()
+	if err != nil {
+		t.Fatalf("Failed to get layers: %v", err)
+	}
+	diffID, err := layers[0].DiffID()
+	if err != nil {
+		t.Fatalf("Failed to get DiffID: %v", err)
+	}
+	if got := target.deltas[0][types.AnnotationDeltaTarget]; got != diffID.String() {
+		t.Errorf("Expected delta target %s, got %s", diffID, got)
+	}
+
+	if _, err := b.WithDelta(filepath.Join("..", "assets", "dummy.gguf")); err == nil {
+		t.Error("Expected an error computing a delta against an identical base")
+	}
+}
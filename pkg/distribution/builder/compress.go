@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcr "github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// compressibleMediaTypes are the media types of the layers compressed by
+// WithZstd. Weights are left uncompressed so that they can be memory-mapped
+// from the store.
+var compressibleMediaTypes = []ggcr.MediaType{
+	types.MediaTypeLicense,
+	types.MediaTypeChatTemplate,
+	types.MediaTypeVLLMConfigArchive,
+}
+
+// WithZstd compresses the license, chat template and config archive layers of
+// the model with zstd, replacing them with layers of the corresponding +zstd
+// media types, which are moved after the other layers. It applies to the layers
+// added so far. The compressed files are written to a temporary directory that
+// is removed by Cleanup.
+func (b *Builder) WithZstd() (*Builder, error) {
+	layers, err := b.model.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get model layers: %w", err)
+	}
+
+	var tempDir string
+	var compressed []v1.Layer
+	mdl := b.model
+	for _, mt := range compressibleMediaTypes {
+		removed := false
+		for _, layer := range layers {
+			if layerMT, err := layer.MediaType(); err != nil || layerMT != mt {
+				continue
+			}
+			if tempDir == "" {
+				if tempDir, err = os.MkdirTemp("", "model-zstd-*"); err != nil {
+					return nil, fmt.Errorf("create temporary directory: %w", err)
+				}
+			}
+			path := filepath.Join(tempDir, fmt.Sprintf("layer-%d.zst", len(compressed)))
+			if err := compressLayer(layer, path); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, err
+			}
+			zstdLayer, err := partial.NewLayer(path, types.Zstd(mt))
+			if err != nil {
+				os.RemoveAll(tempDir)
+				return nil, fmt.Errorf("compressed layer: %w", err)
+			}
+			compressed = append(compressed, zstdLayer)
+			removed = true
+		}
+		if removed {
+			mdl = mutate.RemoveLayers(mdl, mt)
+		}
+	}
+	if len(compressed) == 0 {
+		return b, nil
+	}
+
+	return &Builder{
+		model:          mutate.AppendLayers(mdl, compressed...),
+		originalLayers: b.originalLayers,
+		tempDirs:       append(b.tempDirs, tempDir),
+		deltas:         b.deltas,
+	}, nil
+}
+
+// compressLayer writes the zstd compressed content of the layer to path.
+func compressLayer(layer v1.Layer, path string) error {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("open layer: %w", err)
+	}
+	defer rc.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create compressed file: %w", err)
+	}
+	defer f.Close()
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(zw, rc); err != nil {
+		zw.Close()
+		return fmt.Errorf("compress layer: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress layer: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,106 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/delta"
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// deltaLayer is a delta along with the annotations that identify the layers
+// it applies to and reconstructs.
+type deltaLayer struct {
+	layer       v1.Layer
+	annotations map[string]string
+}
+
+// WithDelta adds a delta that reconstructs the GGUF file of the model from the
+// GGUF file at basePath, such as that of the model it was fine-tuned from, so
+// that clients that already have the base download the delta rather than the
+// whole file. Deltas are written alongside the model, as artifacts referring
+// to it, so the model must be built to a DeltaTarget, such as a registry. The
+// delta is written to a temporary directory that is removed by Cleanup.
+func (b *Builder) WithDelta(basePath string) (*Builder, error) {
+	layers, err := b.model.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get model layers: %w", err)
+	}
+	var target *partial.Layer
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil || mediaType != types.MediaTypeGGUF {
+			continue
+		}
+		if target != nil {
+			return nil, fmt.Errorf("deltas of sharded GGUF models are not supported")
+		}
+		if target, _ = layer.(*partial.Layer); target == nil {
+			return nil, fmt.Errorf("unexpected GGUF layer type %T", layer)
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("model has no GGUF layer to compute a delta for")
+	}
+	base, err := partial.NewLayer(basePath, types.MediaTypeGGUF)
+	if err != nil {
+		return nil, fmt.Errorf("delta base from %q: %w", basePath, err)
+	}
+	if base.Descriptor.Digest == target.Descriptor.Digest {
+		return nil, fmt.Errorf("delta base %q is identical to the model", basePath)
+	}
+
+	tempDir, err := os.MkdirTemp("", "model-delta-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+	path := filepath.Join(tempDir, "delta.zst")
+	if err := writeDelta(base, target, path); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	layer, err := partial.NewLayer(path, types.MediaTypeGGUFDelta)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("delta layer: %w", err)
+	}
+	return &Builder{
+		model:          b.model,
+		originalLayers: b.originalLayers,
+		tempDirs:       append(b.tempDirs, tempDir),
+		deltas: append(b.deltas, deltaLayer{
+			layer: layer,
+			annotations: map[string]string{
+				types.AnnotationDeltaBase:   base.Descriptor.Digest.String(),
+				types.AnnotationDeltaTarget: target.Descriptor.Digest.String(),
+			},
+		}),
+	}, nil
+}
+
+// writeDelta writes the delta from base to target to the file at path.
+func writeDelta(base, target *partial.Layer, path string) error {
+	baseFile, err := os.Open(base.Path)
+	if err != nil {
+		return fmt.Errorf("open delta base: %w", err)
+	}
+	defer baseFile.Close()
+	targetFile, err := os.Open(target.Path)
+	if err != nil {
+		return fmt.Errorf("open model file: %w", err)
+	}
+	defer targetFile.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create delta file: %w", err)
+	}
+	if err := delta.Diff(baseFile, base.Descriptor.Size, targetFile, target.Descriptor.Size, out, 0); err != nil {
+		out.Close()
+		return fmt.Errorf("compute delta: %w", err)
+	}
+	return out.Close()
+}
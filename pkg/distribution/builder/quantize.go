@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// QuantizeBinary is the llama.cpp quantize tool invoked by WithQuantization.
+// It may be an absolute path or a name resolved via PATH.
+var QuantizeBinary = "llama-quantize"
+
+// WithQuantization quantizes the GGUF model to the given target type (e.g. Q4_K_M)
+// using llama.cpp's quantize tool. The quantized GGUF replaces the source layer and
+// the config records the source digest and quantization settings. It must be applied
+// to a builder created with FromGGUF before any other changes are made. The quantized
+// file is written to a temporary directory that is removed by Cleanup.
+func (b *Builder) WithQuantization(target string) (*Builder, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("quantization target is required")
+	}
+	if _, ok := b.model.(*gguf.Model); !ok {
+		return nil, fmt.Errorf("quantization must be applied to a GGUF model before other changes")
+	}
+
+	layers, err := b.model.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get model layers: %w", err)
+	}
+	if len(layers) != 1 {
+		return nil, fmt.Errorf("quantization of sharded GGUF models is not supported")
+	}
+	source, ok := layers[0].(*partial.Layer)
+	if !ok {
+		return nil, fmt.Errorf("unexpected GGUF layer type %T", layers[0])
+	}
+	sourceDigest, err := source.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("get source digest: %w", err)
+	}
+	sourceConfig, err := b.model.Config()
+	if err != nil {
+		return nil, fmt.Errorf("get source config: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "model-quantize-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp directory: %w", err)
+	}
+	output := filepath.Join(tempDir, fmt.Sprintf("model-%s.gguf", strings.ToLower(target)))
+	if err := runQuantize(context.Background(), source.Path, output, target); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	mdl, err := gguf.NewModel(output)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("create model from quantized gguf: %w", err)
+	}
+	return &Builder{
+		model: mutate.QuantizedFrom(mdl, types.QuantizationInfo{
+			SourceDigest:       sourceDigest.String(),
+			SourceQuantization: sourceConfig.Quantization,
+			Target:             target,
+		}),
+		originalLayers: b.originalLayers,
+		tempDirs:       append(b.tempDirs, tempDir),
+	}, nil
+}
+
+// Cleanup removes any temporary files created by the builder. It should be called
+// once the artifact has been built.
+func (b *Builder) Cleanup() error {
+	for _, dir := range b.tempDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("remove %q: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// runQuantize invokes the quantize tool to convert input to the target type.
+func runQuantize(ctx context.Context, input, output, target string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, QuantizeBinary, input, output, target)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("quantize to %s: %w: %s", target, err, msg)
+		}
+		return fmt.Errorf("quantize to %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+// Package conformance implements an end-to-end conformance suite for the model
+// artifact format. It packages a set of known test vectors, pushes them to a
+// registry, pulls them back, unpacks a runtime bundle, serves it, and
+// validates that digests, media types, and metadata survive the round trip. Third-party
+// registries can use it to verify compatibility.
+package conformance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sirupsen/logrus"
+
+	"github.com/docker/model-runner/pkg/distribution/builder"
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// DefaultTag is the tag pushed to the repository under test.
+const DefaultTag = "conformance"
+
+// Step names, in the order they run.
+const (
+	StepPackage = "package"
+	StepPush    = "push"
+	StepPull    = "pull"
+	StepVerify  = "verify"
+	StepBundle  = "bundle"
+	StepServe   = "serve"
+)
+
+// ErrSkipped is recorded for steps that didn't run because an earlier step
+// failed.
+var ErrSkipped = errors.New("skipped after earlier failure")
+
+// Options configures a conformance run.
+type Options struct {
+	// Repository is the repository to push the test artifact to, e.g.
+	// "registry.example.com/conformance". Required.
+	Repository string
+	// Tag is the tag to push. Defaults to DefaultTag.
+	Tag string
+	// Transport is the HTTP transport used to talk to the registry.
+	Transport http.RoundTripper
+	// UserAgent is the User-Agent sent to the registry.
+	UserAgent string
+	// Username and Password are optional registry credentials.
+	Username string
+	Password string
+	// Logger is the logger passed to the distribution client and to the
+	// scheduler that serves the bundle.
+	Logger *logrus.Entry
+}
+
+// Result is the outcome of a single step.
+type Result struct {
+	// Step is the step name.
+	Step string
+	// Err is nil if the step passed.
+	Err error
+}
+
+// Report is the outcome of a conformance run.
+type Report struct {
+	// Reference is the reference the artifact was pushed to.
+	Reference string
+	// Results holds the result of every step, in order.
+	Results []Result
+}
+
+// Passed returns true if every step passed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// run holds the state shared between steps.
+type run struct {
+	opts      Options
+	vectors   Vectors
+	dir       string
+	reference string
+	packaged  types.ModelArtifact
+	client    *distribution.Client
+	bundle    types.ModelBundle
+}
+
+// Run runs the conformance suite. It returns an error only if the suite
+// couldn't be set up; step failures are recorded in the report.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Repository == "" {
+		return nil, errors.New("repository is required")
+	}
+	if opts.Tag == "" {
+		opts.Tag = DefaultTag
+	}
+	if opts.Transport == nil {
+		opts.Transport = registry.DefaultTransport
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = registry.DefaultUserAgent
+	}
+
+	vectors, err := LoadVectors()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "model-conformance-*")
+	if err != nil {
+		return nil, fmt.Errorf("create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writeVectorFiles(vectors, dir); err != nil {
+		return nil, err
+	}
+
+	r := &run{
+		opts:      opts,
+		vectors:   vectors,
+		dir:       dir,
+		reference: opts.Repository + ":" + opts.Tag,
+	}
+	steps := []struct {
+		name string
+		fn   func(context.Context) error
+	}{
+		{StepPackage, r.pack},
+		{StepPush, r.push},
+		{StepPull, r.pull},
+		{StepVerify, r.verify},
+		{StepBundle, r.unpack},
+		{StepServe, r.serve},
+	}
+
+	report := &Report{Reference: r.reference}
+	failed := false
+	for _, step := range steps {
+		if failed {
+			report.Results = append(report.Results, Result{Step: step.name, Err: ErrSkipped})
+			continue
+		}
+		err := step.fn(ctx)
+		if err != nil {
+			failed = true
+		}
+		report.Results = append(report.Results, Result{Step: step.name, Err: err})
+	}
+	return report, nil
+}
+
+// pack builds the model artifact from the vectors and checks the resulting
+// layers.
+func (r *run) pack(context.Context) error {
+	var b *builder.Builder
+	for i, l := range r.vectors.Layers {
+		path := filepath.Join(r.dir, l.File)
+		var err error
+		switch l.MediaType {
+		case types.MediaTypeGGUF:
+			if i != 0 {
+				return fmt.Errorf("GGUF vector %q must be the first layer", l.File)
+			}
+			b, err = builder.FromGGUF(path)
+		case types.MediaTypeLicense:
+			b, err = b.WithLicense(path)
+		case types.MediaTypeChatTemplate:
+			b, err = b.WithChatTemplateFile(path)
+		default:
+			return fmt.Errorf("unsupported vector media type %q", l.MediaType)
+		}
+		if err != nil {
+			return fmt.Errorf("add %q: %w", l.File, err)
+		}
+	}
+	if b == nil {
+		return errors.New("no layers in vectors")
+	}
+	if r.vectors.Config.ContextSize != nil {
+		b = b.WithContextSize(*r.vectors.Config.ContextSize)
+	}
+	r.packaged = b.Model()
+
+	return r.checkLayers(r.packaged)
+}
+
+// push pushes the packaged artifact to the registry.
+func (r *run) push(ctx context.Context) error {
+	target, err := r.registryClient().NewTarget(r.reference)
+	if err != nil {
+		return err
+	}
+	return target.Write(ctx, r.packaged, nil)
+}
+
+// pull pulls the artifact into a fresh store and checks that the manifest
+// digest is unchanged.
+func (r *run) pull(ctx context.Context) error {
+	logger := r.logger()
+	client, err := distribution.NewClient(
+		distribution.WithStoreRootPath(filepath.Join(r.dir, "store")),
+		distribution.WithTransport(r.opts.Transport),
+		distribution.WithUserAgent(r.opts.UserAgent),
+		distribution.WithLogger(logger),
+		distribution.WithRegistryAuth(r.opts.Username, r.opts.Password),
+	)
+	if err != nil {
+		return fmt.Errorf("create distribution client: %w", err)
+	}
+	r.client = client
+
+	if err := client.PullModel(ctx, r.reference, nil); err != nil {
+		return err
+	}
+	pulled, err := client.GetModel(r.reference)
+	if err != nil {
+		return err
+	}
+	id, err := pulled.ID()
+	if err != nil {
+		return fmt.Errorf("get pulled model ID: %w", err)
+	}
+	return r.checkID(id)
+}
+
+// verify fetches the manifest and config from the registry and checks media
+// types, digests, and metadata.
+func (r *run) verify(ctx context.Context) error {
+	remote, err := r.registryClient().Model(ctx, r.reference)
+	if err != nil {
+		return err
+	}
+	id, err := remote.ID()
+	if err != nil {
+		return fmt.Errorf("get remote model ID: %w", err)
+	}
+	if err := r.checkID(id); err != nil {
+		return err
+	}
+
+	manifest, err := remote.Manifest()
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+	if manifest.Config.MediaType != types.MediaTypeModelConfigV01 {
+		return fmt.Errorf("config media type: got %q, want %q", manifest.Config.MediaType, types.MediaTypeModelConfigV01)
+	}
+	if err := r.checkLayers(remote); err != nil {
+		return err
+	}
+
+	cfg, err := remote.Config()
+	if err != nil {
+		return fmt.Errorf("get config: %w", err)
+	}
+	if err := checkConfig(cfg, r.vectors.Config); err != nil {
+		return err
+	}
+
+	// The config file also records the uncompressed layer digests.
+	configFile, err := remote.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("get config file: %w", err)
+	}
+	if len(configFile.RootFS.DiffIDs) != len(r.vectors.Layers) {
+		return fmt.Errorf("diff IDs: got %d, want %d", len(configFile.RootFS.DiffIDs), len(r.vectors.Layers))
+	}
+	for i, diffID := range configFile.RootFS.DiffIDs {
+		if diffID.String() != r.vectors.Layers[i].Digest {
+			return fmt.Errorf("diff ID %d: got %s, want %s", i, diffID, r.vectors.Layers[i].Digest)
+		}
+	}
+
+	descriptor, err := remote.Descriptor()
+	if err != nil {
+		return fmt.Errorf("get descriptor: %w", err)
+	}
+	want, err := r.packaged.Descriptor()
+	if err != nil {
+		return fmt.Errorf("get packaged descriptor: %w", err)
+	}
+	if descriptor.Created == nil || want.Created == nil || !descriptor.Created.Equal(*want.Created) {
+		return fmt.Errorf("created timestamp did not round-trip: got %v, want %v", descriptor.Created, want.Created)
+	}
+	return nil
+}
+
+// unpack creates a runtime bundle from the pulled model and checks its
+// contents against the vectors.
+func (r *run) unpack(context.Context) error {
+	bundle, err := r.client.GetBundle(r.reference)
+	if err != nil {
+		return err
+	}
+	r.bundle = bundle
+
+	for _, l := range r.vectors.Layers {
+		var path string
+		switch l.MediaType {
+		case types.MediaTypeGGUF:
+			path = bundle.GGUFPath()
+		case types.MediaTypeChatTemplate:
+			path = bundle.ChatTemplatePath()
+		default:
+			continue // not part of the runtime bundle
+		}
+		if path == "" {
+			return fmt.Errorf("bundle is missing %q", l.File)
+		}
+		digest, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		if digest != l.Digest {
+			return fmt.Errorf("bundle file for %q: got digest %s, want %s", l.File, digest, l.Digest)
+		}
+	}
+	return checkConfig(bundle.RuntimeConfig(), r.vectors.Config)
+}
+
+// logger returns the logger passed to the distribution client, which discards
+// its output by default.
+func (r *run) logger() *logrus.Entry {
+	if r.opts.Logger != nil {
+		return r.opts.Logger
+	}
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return logrus.NewEntry(l)
+}
+
+func (r *run) registryClient() *registry.Client {
+	opts := []registry.ClientOption{
+		registry.WithTransport(r.opts.Transport),
+		registry.WithUserAgent(r.opts.UserAgent),
+	}
+	if r.opts.Username != "" && r.opts.Password != "" {
+		opts = append(opts, registry.WithAuthConfig(r.opts.Username, r.opts.Password))
+	}
+	return registry.NewClient(opts...)
+}
+
+// checkID checks that id matches the ID of the packaged artifact.
+func (r *run) checkID(id string) error {
+	want, err := r.packaged.ID()
+	if err != nil {
+		return fmt.Errorf("get packaged ID: %w", err)
+	}
+	if id != want {
+		return fmt.Errorf("manifest digest: got %s, want %s", id, want)
+	}
+	return nil
+}
+
+// checkLayers checks the layer descriptors of img against the vectors.
+func (r *run) checkLayers(img v1.Image) error {
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("get layers: %w", err)
+	}
+	if len(layers) != len(r.vectors.Layers) {
+		return fmt.Errorf("layers: got %d, want %d", len(layers), len(r.vectors.Layers))
+	}
+	for i, layer := range layers {
+		want := r.vectors.Layers[i]
+		mt, err := layer.MediaType()
+		if err != nil {
+			return fmt.Errorf("get media type of layer %d: %w", i, err)
+		}
+		if mt != want.MediaType {
+			return fmt.Errorf("layer %d media type: got %q, want %q", i, mt, want.MediaType)
+		}
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("get digest of layer %d: %w", i, err)
+		}
+		if digest.String() != want.Digest {
+			return fmt.Errorf("layer %d digest: got %s, want %s", i, digest, want.Digest)
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return fmt.Errorf("get size of layer %d: %w", i, err)
+		}
+		if size != want.Size {
+			return fmt.Errorf("layer %d size: got %d, want %d", i, size, want.Size)
+		}
+	}
+	return nil
+}
+
+// checkConfig compares the fields that are set in want with got.
+func checkConfig(got, want types.Config) error {
+	fields := []struct {
+		name      string
+		got, want string
+	}{
+		{"format", string(got.Format), string(want.Format)},
+		{"architecture", got.Architecture, want.Architecture},
+		{"parameters", got.Parameters, want.Parameters},
+		{"quantization", got.Quantization, want.Quantization},
+		{"size", got.Size, want.Size},
+	}
+	for _, f := range fields {
+		if f.want != "" && f.got != f.want {
+			return fmt.Errorf("config %s: got %q, want %q", f.name, f.got, f.want)
+		}
+	}
+	if want.ContextSize != nil {
+		if got.ContextSize == nil {
+			return fmt.Errorf("config context_size: missing, want %d", *want.ContextSize)
+		}
+		if *got.ContextSize != *want.ContextSize {
+			return fmt.Errorf("config context_size: got %d, want %d", *got.ContextSize, *want.ContextSize)
+		}
+	}
+	return nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %q: %w", path, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,116 @@
+package conformance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestVectorsMatchFiles(t *testing.T) {
+	v, err := LoadVectors()
+	if err != nil {
+		t.Fatalf("Failed to load vectors: %v", err)
+	}
+	if len(v.Layers) == 0 {
+		t.Fatal("Expected at least one layer vector")
+	}
+	for _, l := range v.Layers {
+		data, err := vectorFS.ReadFile("vectors/" + l.File)
+		if err != nil {
+			t.Fatalf("Failed to read %q: %v", l.File, err)
+		}
+		sum := sha256.Sum256(data)
+		if got := "sha256:" + hex.EncodeToString(sum[:]); got != l.Digest {
+			t.Errorf("Vector %q: digest %s does not match file digest %s", l.File, l.Digest, got)
+		}
+		if int64(len(data)) != l.Size {
+			t.Errorf("Vector %q: size %d does not match file size %d", l.File, l.Size, len(data))
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	report, err := Run(context.Background(), Options{Repository: u.Host + "/conformance"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	expectedSteps := []string{StepPackage, StepPush, StepPull, StepVerify, StepBundle, StepServe}
+	if len(report.Results) != len(expectedSteps) {
+		t.Fatalf("Expected %d results, got %d", len(expectedSteps), len(report.Results))
+	}
+	for i, res := range report.Results {
+		if res.Step != expectedSteps[i] {
+			t.Errorf("Result %d: expected step %q, got %q", i, expectedSteps[i], res.Step)
+		}
+		if res.Err != nil {
+			t.Errorf("Step %q failed: %v", res.Step, res.Err)
+		}
+	}
+	if !report.Passed() {
+		t.Error("Expected report to pass")
+	}
+}
+
+func TestRunDetectsRewrittenMediaType(t *testing.T) {
+	// A registry that rewrites layer media types in manifests it serves.
+	reg := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.Contains(r.URL.Path, "/manifests/") {
+			reg.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		body := strings.ReplaceAll(rec.Body.String(), "application/vnd.docker.ai.license", "text/plain")
+		w.Header().Del("Content-Length")
+		w.Header().Del("Docker-Content-Digest")
+		w.WriteHeader(rec.Code)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	report, err := Run(context.Background(), Options{Repository: u.Host + "/conformance"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Passed() {
+		t.Fatal("Expected report to fail")
+	}
+
+	var failed string
+	for _, res := range report.Results {
+		if res.Err != nil && !errors.Is(res.Err, ErrSkipped) {
+			failed = res.Step
+			break
+		}
+	}
+	if failed != StepPull {
+		t.Errorf("Expected %q step to fail first, got %q", StepPull, failed)
+	}
+	if last := report.Results[len(report.Results)-1]; !errors.Is(last.Err, ErrSkipped) {
+		t.Errorf("Expected later steps to be skipped, got %v", last.Err)
+	}
+}
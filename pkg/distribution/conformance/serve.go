@@ -0,0 +1,188 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/docker/model-runner/pkg/metrics"
+)
+
+// serveBackendName is the name of the backend that serves the bundle.
+const serveBackendName = "conformance"
+
+// serveBackend is an inference backend whose runners load the bundled GGUF
+// file of their model with the shared model manager, like llama.cpp does, and
+// answer chat completions with the architecture recorded in it. It
+// doesn't run the weights, so that registries can be checked without an
+// inference engine.
+type serveBackend struct {
+	modelManager *models.Manager
+}
+
+func (b *serveBackend) Name() string {
+	return serveBackendName
+}
+
+func (b *serveBackend) UsesExternalModelManagement() bool {
+	return false
+}
+
+func (b *serveBackend) Install(context.Context, *http.Client) error {
+	return nil
+}
+
+func (b *serveBackend) Run(ctx context.Context, socket, model string, modelRef string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	bundle, err := b.modelManager.GetBundle(model)
+	if err != nil {
+		return fmt.Errorf("get bundle: %w", err)
+	}
+	gguf, err := parser.ParseGGUFFile(bundle.GGUFPath())
+	if err != nil {
+		return fmt.Errorf("parse bundled GGUF: %w", err)
+	}
+	architecture := gguf.Metadata().Architecture
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data":   []map[string]string{{"id": modelRef, "object": "model"}},
+		})
+	})
+	mux.HandleFunc("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "chat.completion",
+			"model":  modelRef,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": architecture},
+				"finish_reason": "stop",
+			}},
+		})
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+	<-ctx.Done()
+	return nil
+}
+
+func (b *serveBackend) Status() string {
+	return "running"
+}
+
+func (b *serveBackend) GetDiskUsage() (int64, error) {
+	return 0, nil
+}
+
+func (b *serveBackend) GetRequiredMemoryForModel(context.Context, string, *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	return inference.RequiredMemory{RAM: 1024 * 1024}, nil
+}
+
+// serveMemoryInfo reports enough memory for the runner of serveBackend.
+type serveMemoryInfo struct{}
+
+func (serveMemoryInfo) HaveSufficientMemory(inference.RequiredMemory) (bool, error) {
+	return true, nil
+}
+
+func (serveMemoryInfo) GetTotalMemory() inference.RequiredMemory {
+	return inference.RequiredMemory{RAM: 1024 * 1024 * 1024}
+}
+
+func (serveMemoryInfo) GetGPUMemory() []uint64 {
+	return nil
+}
+
+func (serveMemoryInfo) GetFreeGPUMemory() []uint64 {
+	return nil
+}
+
+// serve serves the pulled model through a scheduler, which resolves it with a
+// model manager on the pulled store and loads a runner for it with
+// serveBackend, and checks that a chat completion round-trips with the
+// architecture of the bundled weights. The runner's socket is kept in the work
+// directory, so serve must not run alongside another scheduler in the process.
+func (r *run) serve(ctx context.Context) error {
+	defaultSocketPath := scheduling.RunnerSocketPath
+	scheduling.RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(r.dir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	defer func() { scheduling.RunnerSocketPath = defaultSocketPath }()
+
+	logger := r.logger()
+	manager := models.NewManager(logger, models.ClientConfig{
+		StoreRootPath: filepath.Join(r.dir, "store"),
+		Logger:        logger,
+	}, nil, nil)
+	backend := &serveBackend{modelManager: manager}
+	s := scheduling.NewScheduler(logger, map[string]inference.Backend{serveBackendName: backend}, backend,
+		manager, http.DefaultClient, nil, metrics.NewTracker(http.DefaultClient, logger, r.opts.UserAgent, true), serveMemoryInfo{})
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Run(runCtx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	select {
+	case <-s.Ready():
+	case err := <-done:
+		return fmt.Errorf("run scheduler: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"model":    r.reference,
+		"messages": []map[string]string{{"role": "user", "content": "What is your architecture?"}},
+	})
+	if err != nil {
+		return err
+	}
+	request := httptest.NewRequestWithContext(ctx, http.MethodPost, inference.InferencePrefix+"/v1/chat/completions", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return fmt.Errorf("chat completion: got status %d: %s", recorder.Code, bytes.TrimSpace(recorder.Body.Bytes()))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &completion); err != nil {
+		return fmt.Errorf("decode chat completion: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return errors.New("chat completion has no choices")
+	}
+	if arch := completion.Choices[0].Message.Content; arch != r.bundle.RuntimeConfig().Architecture {
+		return fmt.Errorf("served GGUF architecture %q does not match runtime config %q",
+			arch, r.bundle.RuntimeConfig().Architecture)
+	}
+	return nil
+}
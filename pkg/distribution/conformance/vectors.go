@@ -0,0 +1,66 @@
+package conformance
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+//go:embed vectors
+var vectorFS embed.FS
+
+// LayerVector describes a file packaged by the conformance suite and the
+// descriptor it must produce.
+type LayerVector struct {
+	// File is the name of the file within the vectors directory.
+	File string `json:"file"`
+	// MediaType is the expected layer media type.
+	MediaType ggcrtypes.MediaType `json:"media_type"`
+	// Digest is the expected layer digest.
+	Digest string `json:"digest"`
+	// Size is the expected layer size in bytes.
+	Size int64 `json:"size"`
+}
+
+// Vectors are the inputs of the conformance suite along with the digests,
+// media types, and model config they are expected to produce.
+type Vectors struct {
+	// Layers are the expected layers, in manifest order.
+	Layers []LayerVector `json:"layers"`
+	// Config is the expected model config. Only the fields that are set are
+	// compared.
+	Config types.Config `json:"config"`
+}
+
+// LoadVectors returns the test vectors shipped with the suite.
+func LoadVectors() (Vectors, error) {
+	data, err := vectorFS.ReadFile("vectors/vectors.json")
+	if err != nil {
+		return Vectors{}, fmt.Errorf("read vectors: %w", err)
+	}
+	var v Vectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		return Vectors{}, fmt.Errorf("decode vectors: %w", err)
+	}
+	return v, nil
+}
+
+// writeVectorFiles writes the files referenced by v to dir.
+func writeVectorFiles(v Vectors, dir string) error {
+	for _, l := range v.Layers {
+		data, err := vectorFS.ReadFile("vectors/" + l.File)
+		if err != nil {
+			return fmt.Errorf("read vector file %q: %w", l.File, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, l.File), data, 0644); err != nil {
+			return fmt.Errorf("write vector file %q: %w", l.File, err)
+		}
+	}
+	return nil
+}
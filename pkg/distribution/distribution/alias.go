@@ -0,0 +1,68 @@
+package distribution
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// Aliases returns the aliases of the store to which models are written by
+// default, mapping alias names to the references of the models they stand
+// for. Aliases resolve wherever a model reference is looked up, so that
+// applications can use a stable name while the model behind it is swapped.
+func (c *Client) Aliases() (map[string]string, error) {
+	aliases, err := c.store.Aliases()
+	if err != nil {
+		return nil, fmt.Errorf("reading aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// alias returns the reference of the model the alias with the given name
+// stands for, if it exists.
+func (c *Client) alias(alias string) (string, bool) {
+	aliases, err := c.store.Aliases()
+	if err != nil {
+		c.log.Warnln("Failed to read aliases:", err)
+		return "", false
+	}
+	reference, ok := aliases[alias]
+	return reference, ok
+}
+
+// SetAlias points an alias at the model with the given reference, which must
+// be in the store, creating the alias if needed. Alias names have the form of
+// tags and can't shadow the tag of a model, nor stand for another alias.
+func (c *Client) SetAlias(alias, reference string) error {
+	c.log.Infoln("Setting alias:", utils.SanitizeForLog(alias), "model:", utils.SanitizeForLog(reference))
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
+	if _, err := name.NewTag(alias); err != nil {
+		return fmt.Errorf("%w: %q is not a valid tag", ErrInvalidAlias, alias)
+	}
+	if _, _, err := c.findModel(alias); err == nil {
+		return fmt.Errorf("%w: %q is the tag of a model", ErrInvalidAlias, alias)
+	} else if !errors.Is(err, ErrModelNotFound) {
+		return err
+	}
+	if _, _, err := c.findModel(reference); err != nil {
+		if _, ok := c.alias(reference); ok {
+			return fmt.Errorf("%w: %q is an alias", ErrInvalidAlias, reference)
+		}
+		return fmt.Errorf("alias target: %w", err)
+	}
+	return c.store.SetAlias(alias, reference)
+}
+
+// RemoveAlias removes an alias. The model it stands for is left as is.
+func (c *Client) RemoveAlias(alias string) error {
+	c.log.Infoln("Removing alias:", utils.SanitizeForLog(alias))
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
+	return c.store.RemoveAlias(alias)
+}
@@ -0,0 +1,62 @@
+package distribution
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	client := newPruneClient(t, "ai/model:v1", "ai/model:v2")
+
+	if err := client.SetAlias("ai/default-chat:latest", "ai/model:v1"); err != nil {
+		t.Fatalf("Failed to set alias: %v", err)
+	}
+	resolved := func() string {
+		t.Helper()
+		mdl, err := client.GetModel("ai/default-chat:latest")
+		if err != nil {
+			t.Fatalf("Failed to get model by alias: %v", err)
+		}
+		return mdl.Tags()[0]
+	}
+	if tag := resolved(); tag != "ai/model:v1" {
+		t.Errorf("Expected the alias to resolve to ai/model:v1, got %s", tag)
+	}
+
+	// Swapping the model behind the alias doesn't change its name.
+	if err := client.SetAlias("ai/default-chat:latest", "ai/model:v2"); err != nil {
+		t.Fatalf("Failed to set alias: %v", err)
+	}
+	if tag := resolved(); tag != "ai/model:v2" {
+		t.Errorf("Expected the alias to resolve to ai/model:v2, got %s", tag)
+	}
+
+	// Tagging through an alias tags the model it stands for.
+	if err := client.Tag("ai/default-chat:latest", "ai/model:stable"); err != nil {
+		t.Fatalf("Failed to tag model by alias: %v", err)
+	}
+	if _, err := client.GetModel("ai/model:stable"); err != nil {
+		t.Errorf("Expected the model to be tagged: %v", err)
+	}
+
+	// Aliases can't shadow tags, stand for other aliases or missing models.
+	if err := client.SetAlias("ai/model:v1", "ai/model:v2"); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("Expected ErrInvalidAlias for an alias shadowing a tag, got %v", err)
+	}
+	if err := client.SetAlias("ai/other:latest", "ai/default-chat:latest"); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("Expected ErrInvalidAlias for an alias of an alias, got %v", err)
+	}
+	if err := client.SetAlias("ai/other:latest", "ai/missing:latest"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for an alias of a missing model, got %v", err)
+	}
+
+	if err := client.RemoveAlias("ai/default-chat:latest"); err != nil {
+		t.Fatalf("Failed to remove alias: %v", err)
+	}
+	if _, err := client.GetModel("ai/default-chat:latest"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound after removing the alias, got %v", err)
+	}
+	if err := client.RemoveAlias("ai/default-chat:latest"); !errors.Is(err, ErrAliasNotFound) {
+		t.Errorf("Expected ErrAliasNotFound, got %v", err)
+	}
+}
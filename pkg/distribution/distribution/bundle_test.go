@@ -6,9 +6,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/docker/model-runner/pkg/distribution/internal/diffusion"
 	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
 	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
 	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/internal/piper"
 	"github.com/docker/model-runner/pkg/distribution/types"
 )
 
@@ -63,6 +65,20 @@ func TestBundle(t *testing.T) {
 		t.Fatalf("Failed to write model to store: %v", err)
 	}
 
+	// Load model with LoRA adapter
+	loraLayer, err := partial.NewLayer(filepath.Join("..", "assets", "dummy.mmproj"), types.MediaTypeLoRAAdapter)
+	if err != nil {
+		t.Fatalf("Failed to create LoRA adapter layer: %v", err)
+	}
+	loraMdl := mutate.AppendLayers(mdl, loraLayer)
+	loraMdlID, err := loraMdl.ID()
+	if err != nil {
+		t.Fatalf("Failed to get model ID: %v", err)
+	}
+	if err := client.store.Write(loraMdl, []string{"some-model-with-lora"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
 	// Load sharded dummy model from asset directory
 	shardedMdl, err := gguf.NewModel(filepath.Join("..", "assets", "dummy-00001-of-00002.gguf"))
 	if err != nil {
@@ -76,9 +92,60 @@ func TestBundle(t *testing.T) {
 		t.Fatalf("Failed to write model to store: %v", err)
 	}
 
+	// Load whisper.cpp speech-to-text model
+	whisperMdl, err := gguf.NewWhisperModel(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create whisper model: %v", err)
+	}
+	whisperMdlID, err := whisperMdl.ID()
+	if err != nil {
+		t.Fatalf("Failed to get model ID: %v", err)
+	}
+	if err := client.store.Write(whisperMdl, []string{"some-whisper-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
+	// Load piper text-to-speech voice, whose config is next to it
+	voicePath := filepath.Join(t.TempDir(), "voice.onnx")
+	if err := os.WriteFile(voicePath, []byte("onnx"), 0o644); err != nil {
+		t.Fatalf("Failed to write voice: %v", err)
+	}
+	if err := os.WriteFile(voicePath+".json", []byte(`{"audio": {"sample_rate": 22050}}`), 0o644); err != nil {
+		t.Fatalf("Failed to write voice config: %v", err)
+	}
+	voiceMdl, err := piper.NewModel(voicePath)
+	if err != nil {
+		t.Fatalf("Failed to create voice model: %v", err)
+	}
+	voiceMdlID, err := voiceMdl.ID()
+	if err != nil {
+		t.Fatalf("Failed to get model ID: %v", err)
+	}
+	if err := client.store.Write(voiceMdl, []string{"some-voice-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
+	// Load stable-diffusion.cpp image generation model
+	diffusionMdl, err := diffusion.NewModel(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create diffusion model: %v", err)
+	}
+	diffusionMdlID, err := diffusionMdl.ID()
+	if err != nil {
+		t.Fatalf("Failed to get model ID: %v", err)
+	}
+	if err := client.store.Write(diffusionMdl, []string{"some-diffusion-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
 	type testCase struct {
 		ref           string
 		expectedFiles map[string]string //
+		expectedGGUF  string
+		expectedLoRA  int
+		whisper       bool
+		voice         bool
+		diffusion     bool
 		description   string
 		expectedErr   error
 	}
@@ -97,8 +164,9 @@ func TestBundle(t *testing.T) {
 			},
 		},
 		{
-			ref:         shardedGGUFID,
-			description: "sharded GGUF by ID",
+			ref:          shardedGGUFID,
+			description:  "sharded GGUF by ID",
+			expectedGGUF: "model-00001-of-00002.gguf",
 			expectedFiles: map[string]string{
 				"model/model-00001-of-00002.gguf": filepath.Join("..", "assets", "dummy-00001-of-00002.gguf"),
 				"model/model-00002-of-00002.gguf": filepath.Join("..", "assets", "dummy-00002-of-00002.gguf"),
@@ -112,6 +180,40 @@ func TestBundle(t *testing.T) {
 				"model/model.mmproj": filepath.Join("..", "assets", "dummy.mmproj"),
 			},
 		},
+		{
+			ref:          loraMdlID,
+			description:  "model with LoRA adapter",
+			expectedLoRA: 1,
+			expectedFiles: map[string]string{
+				"model/model.gguf":         filepath.Join("..", "assets", "dummy.gguf"),
+				"model/adapter-00001.lora": filepath.Join("..", "assets", "dummy.mmproj"),
+			},
+		},
+		{
+			ref:         whisperMdlID,
+			description: "whisper model",
+			whisper:     true,
+			expectedFiles: map[string]string{
+				"model/model.whisper": filepath.Join("..", "assets", "dummy.gguf"),
+			},
+		},
+		{
+			ref:         voiceMdlID,
+			description: "piper voice",
+			voice:       true,
+			expectedFiles: map[string]string{
+				"model/model.onnx":      voicePath,
+				"model/model.onnx.json": voicePath + ".json",
+			},
+		},
+		{
+			ref:         diffusionMdlID,
+			description: "diffusion model",
+			diffusion:   true,
+			expectedFiles: map[string]string{
+				"model/model.diffusion": filepath.Join("..", "assets", "dummy.gguf"),
+			},
+		},
 		{
 			ref:         templateMdlID,
 			description: "model with template file",
@@ -131,6 +233,24 @@ func TestBundle(t *testing.T) {
 			if tc.expectedErr != nil {
 				return
 			}
+			if tc.expectedGGUF != "" && filepath.Base(bundle.GGUFPath()) != tc.expectedGGUF {
+				t.Fatalf("Expected GGUF path to point to %s, got %s", tc.expectedGGUF, bundle.GGUFPath())
+			}
+			if len(bundle.LoRAAdapterPaths()) != tc.expectedLoRA {
+				t.Fatalf("Expected %d LoRA adapters, got %v", tc.expectedLoRA, bundle.LoRAAdapterPaths())
+			}
+			if (bundle.WhisperPath() != "") != tc.whisper {
+				t.Fatalf("Expected whisper model %t, got path %q", tc.whisper, bundle.WhisperPath())
+			}
+			if (bundle.VoicePath() != "") != tc.voice {
+				t.Fatalf("Expected voice %t, got path %q", tc.voice, bundle.VoicePath())
+			}
+			if (bundle.DiffusionPath() != "") != tc.diffusion {
+				t.Fatalf("Expected diffusion model %t, got path %q", tc.diffusion, bundle.DiffusionPath())
+			}
+			if (tc.whisper || tc.diffusion) && bundle.GGUFPath() != "" {
+				t.Fatalf("Expected no GGUF path for whisper model, got %s", bundle.GGUFPath())
+			}
 			for expectedName, shouldMatchContent := range tc.expectedFiles {
 				got, err := os.ReadFile(filepath.Join(bundle.RootDir(), expectedName))
 				if err != nil {
@@ -147,3 +267,117 @@ func TestBundle(t *testing.T) {
 		})
 	}
 }
+
+func TestAcquireBundle(t *testing.T) {
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	mdl, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	if err := client.store.Write(mdl, []string{"some-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
+	bundle, release, err := client.AcquireBundle("some-model")
+	if err != nil {
+		t.Fatalf("Failed to acquire bundle: %v", err)
+	}
+
+	// Bundles are cached rather than recreated
+	cached, err := client.GetBundle("some-model")
+	if err != nil {
+		t.Fatalf("Failed to get bundle: %v", err)
+	}
+	if cached != bundle {
+		t.Errorf("Expected the cached bundle to be returned")
+	}
+
+	// Bundles in use can't be refreshed
+	if _, err := client.RefreshBundle("some-model"); !errors.Is(err, ErrBundleInUse) {
+		t.Errorf("Expected ErrBundleInUse refreshing a bundle in use, got %v", err)
+	}
+
+	// Deleting the model keeps the bundle until it's released
+	if _, err := client.DeleteModel("some-model", true); err != nil {
+		t.Fatalf("Failed to delete model: %v", err)
+	}
+	if _, err := os.Stat(bundle.GGUFPath()); err != nil {
+		t.Errorf("Expected bundle of deleted model to remain while in use: %v", err)
+	}
+	release()
+	if _, err := os.Stat(bundle.RootDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected bundle to be removed once released, got %v", err)
+	}
+}
+
+func TestRefreshBundle(t *testing.T) {
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	mdl, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	if err := client.store.Write(mdl, []string{"some-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+
+	bundle, err := client.GetBundle("some-model")
+	if err != nil {
+		t.Fatalf("Failed to get bundle: %v", err)
+	}
+	configPath := filepath.Join(bundle.RootDir(), "config.json")
+	if err := os.Remove(configPath); err != nil {
+		t.Fatalf("Failed to remove runtime config: %v", err)
+	}
+
+	if _, err := client.RefreshBundle("some-model"); err != nil {
+		t.Fatalf("Failed to refresh bundle: %v", err)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		t.Errorf("Expected refreshed bundle to have a runtime config: %v", err)
+	}
+}
+
+func TestHardlinkBundles(t *testing.T) {
+	storeDir := t.TempDir()
+	client, err := NewClient(WithStoreRootPath(storeDir), WithHardlinkBundles(true))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	mdl, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	if err := client.store.Write(mdl, []string{"some-model"}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+	layers, err := mdl.Layers()
+	if err != nil {
+		t.Fatalf("Failed to get layers: %v", err)
+	}
+	digest, err := layers[0].Digest()
+	if err != nil {
+		t.Fatalf("Failed to get layer digest: %v", err)
+	}
+
+	bundle, err := client.GetBundle("some-model")
+	if err != nil {
+		t.Fatalf("Failed to get bundle: %v", err)
+	}
+	bundled, err := os.Lstat(bundle.GGUFPath())
+	if err != nil {
+		t.Fatalf("Failed to stat bundled GGUF file: %v", err)
+	}
+	blob, err := os.Stat(filepath.Join(storeDir, "blobs", digest.Algorithm, digest.Hex))
+	if err != nil {
+		t.Fatalf("Failed to stat blob: %v", err)
+	}
+	if bundled.Mode()&os.ModeSymlink != 0 || !os.SameFile(bundled, blob) {
+		t.Errorf("Expected bundled GGUF file to be a hardlink to the blob")
+	}
+}
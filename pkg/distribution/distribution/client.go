@@ -7,26 +7,66 @@ import (
 	"io"
 	"net/http"
 	"slices"
+	"time"
 
+	"github.com/docker/model-runner/pkg/diskusage"
 	"github.com/docker/model-runner/pkg/internal/utils"
+	"github.com/docker/model-runner/pkg/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/docker/model-runner/pkg/distribution/huggingface"
 	"github.com/docker/model-runner/pkg/distribution/internal/progress"
 	"github.com/docker/model-runner/pkg/distribution/internal/store"
 	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/resolver"
 	"github.com/docker/model-runner/pkg/distribution/tarball"
+	"github.com/docker/model-runner/pkg/distribution/transport/ratelimit"
 	"github.com/docker/model-runner/pkg/distribution/types"
 	"github.com/docker/model-runner/pkg/inference/platform"
 )
 
+// tracer traces model pulls and pushes.
+var tracer = otel.Tracer("github.com/docker/model-runner/pkg/distribution/distribution")
+
 // Client provides model distribution functionality
 type Client struct {
-	store    *store.LocalStore
-	log      *logrus.Entry
-	registry *registry.Client
+	// store is the store to which models are written by default.
+	store *store.LocalStore
+	// stores are all stores, in lookup order.
+	stores []namedStore
+	// readOnly indicates that models can't be pulled, loaded or modified.
+	readOnly bool
+	// requireDigest indicates that models must be referenced by digest.
+	requireDigest bool
+	log           *logrus.Entry
+	registry      *registry.Client
+	huggingface   *huggingface.Client
+	resolver      resolver.Resolver
+	// rateLimits tracks the pull quotas reported by registries.
+	rateLimits *ratelimit.Tracker
+	// diskHeadroom is the free space pulls leave on the volume of a store.
+	diskHeadroom uint64
+	// freeSpace returns the free space on the volume containing a path.
+	freeSpace func(path string) (uint64, error)
+}
+
+// StoreGeneration returns a counter of the changes made to the models, tags
+// and aliases of the stores through this client, so that callers can cache
+// what they derive from them until it changes.
+func (c *Client) StoreGeneration() uint64 {
+	var generation uint64
+	for _, s := range c.stores {
+		generation += s.Generation()
+	}
+	return generation
 }
 
-// GetStorePath returns the root path where models are stored
+// GetStorePath returns the root path of the store to which models are
+// written by default
 func (c *Client) GetStorePath() string {
 	return c.store.RootPath()
 }
@@ -42,6 +82,18 @@ type options struct {
 	userAgent     string
 	username      string
 	password      string
+	resolver      resolver.Resolver
+	repairOnOpen  bool
+	stores        []StoreConfig
+	writableStore string
+	readOnly      bool
+	blobCachePath string
+	requireDigest bool
+	pushChunkSize int64
+	tokenStore    registry.TokenStore
+	diskHeadroom  uint64
+	// hardlinkBundles requires bundles to hardlink model files.
+	hardlinkBundles bool
 }
 
 // WithStoreRootPath sets the store root path
@@ -90,6 +142,51 @@ func WithRegistryAuth(username, password string) Option {
 	}
 }
 
+// WithNameResolver sets the resolver used to map model names to registry
+// references before pulling.
+func WithNameResolver(r resolver.Resolver) Option {
+	return func(o *options) {
+		if r != nil {
+			o.resolver = r
+		}
+	}
+}
+
+// WithRepairOnOpen enables repairing the store when the client is created.
+// Models whose manifest or blobs are missing or corrupt are removed, so that
+// they can be pulled again, and corrupt files are quarantined.
+func WithRepairOnOpen(repair bool) Option {
+	return func(o *options) {
+		o.repairOnOpen = repair
+	}
+}
+
+// WithPushChunkSize sets the size of the chunks in which layers are uploaded
+// when pushing models, so that an interrupted upload resumes rather than
+// restarts. Zero keeps the default and a negative size disables chunking.
+func WithPushChunkSize(size int64) Option {
+	return func(o *options) {
+		o.pushChunkSize = size
+	}
+}
+
+// WithTokenStore authenticates to registries with the OAuth2 refresh tokens
+// in store, saving the tokens registries rotate back to it.
+func WithTokenStore(store registry.TokenStore) Option {
+	return func(o *options) {
+		o.tokenStore = store
+	}
+}
+
+// WithDiskHeadroom sets the free space, in bytes, that pulls must leave on
+// the volume of the store they write to. Pulls that would leave less fail
+// with a DiskSpaceError before downloading anything.
+func WithDiskHeadroom(bytes uint64) Option {
+	return func(o *options) {
+		o.diskHeadroom = bytes
+	}
+}
+
 func defaultOptions() *options {
 	return &options{
 		logger:    logrus.NewEntry(logrus.StandardLogger()),
@@ -109,17 +206,40 @@ func NewClient(opts ...Option) (*Client, error) {
 		return nil, fmt.Errorf("store root path is required")
 	}
 
-	s, err := store.New(store.Options{
-		RootPath: options.storeRootPath,
-	})
+	stores, s, err := openStores(options)
 	if err != nil {
 		return nil, fmt.Errorf("initializing store: %w", err)
 	}
+	if options.repairOnOpen && !options.readOnly {
+		for _, s := range stores {
+			if s.ReadOnly() {
+				continue
+			}
+			report, err := s.Repair()
+			if err != nil {
+				return nil, fmt.Errorf("repairing store %q: %w", s.name, err)
+			}
+			if report.IndexRebuilt {
+				options.logger.Warnf("Rebuilt unreadable models index of store %q, model tags were lost", s.name)
+			}
+			for _, id := range report.RemovedModels {
+				options.logger.Warnf("Removed corrupt model %s from store %q", id, s.name)
+			}
+			for _, file := range report.QuarantinedFiles {
+				options.logger.Warnf("Quarantined corrupt store file %s", file)
+			}
+		}
+	}
 
-	// Create registry client options
+	// Create registry client options. Registry requests are traced as spans
+	// of the pulls and pushes they're part of, and wait for rate limits
+	// rather than failing.
+	rateLimits := ratelimit.NewTracker()
 	registryOpts := []registry.ClientOption{
-		registry.WithTransport(options.transport),
+		registry.WithTransport(ratelimit.New(otelhttp.NewTransport(options.transport), rateLimits)),
 		registry.WithUserAgent(options.userAgent),
+		registry.WithPushChunkSize(options.pushChunkSize),
+		registry.WithTokenStore(options.tokenStore),
 	}
 
 	// Add auth if credentials are provided
@@ -129,17 +249,70 @@ func NewClient(opts ...Option) (*Client, error) {
 
 	options.logger.Infoln("Successfully initialized store")
 	return &Client{
-		store:    s,
-		log:      options.logger,
-		registry: registry.NewClient(registryOpts...),
+		store:         s,
+		stores:        stores,
+		readOnly:      options.readOnly,
+		requireDigest: options.requireDigest,
+		log:           options.logger,
+		registry:      registry.NewClient(registryOpts...),
+		huggingface:   huggingface.NewClient(options.transport, ""),
+		resolver:      options.resolver,
+		rateLimits:    rateLimits,
+		diskHeadroom:  options.diskHeadroom,
+		freeSpace:     diskusage.Free,
 	}, nil
 }
 
 // PullModel pulls a model from a registry and returns the local file path
-func (c *Client) PullModel(ctx context.Context, reference string, progressWriter io.Writer) error {
+func (c *Client) PullModel(ctx context.Context, reference string, progressWriter io.Writer, opts ...PullOption) (err error) {
+	ctx, span := tracer.Start(ctx, "distribution.PullModel", trace.WithAttributes(attribute.String("model.reference", reference)))
+	defer func() { tracing.End(span, err) }()
+
 	c.log.Infoln("Starting model pull:", utils.SanitizeForLog(reference))
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
+	if err := c.checkReference(reference); err != nil {
+		return err
+	}
+	var pullOpts pullOptions
+	for _, opt := range opts {
+		opt(&pullOpts)
+	}
+	if progressWriter != nil {
+		progressWriter = &syncWriter{w: progressWriter}
+		ctx = c.observeRateLimits(ctx, progressWriter)
+	}
+	dst := c.store
+	if pullOpts.store != "" {
+		if dst, err = findStore(c.stores, pullOpts.store); err != nil {
+			return err
+		}
+	}
 
-	remoteModel, err := c.registry.Model(ctx, reference)
+	// Names may be mapped to registry references by a configured resolver,
+	// and Hugging Face references may select a specific GGUF file within a
+	// repository, so resolve them to the registry tag for that file. The
+	// model is still tagged locally with the reference as given.
+	remoteReference, err := resolver.Resolve(ctx, c.resolver, reference)
+	if err != nil {
+		return err
+	}
+	if remoteReference != reference {
+		c.log.Infoln("Resolved model name to:", utils.SanitizeForLog(remoteReference))
+	}
+	if huggingface.IsReference(remoteReference) {
+		resolved, err := c.huggingface.Resolve(ctx, remoteReference)
+		if err != nil {
+			return fmt.Errorf("resolving Hugging Face reference: %w", err)
+		}
+		if resolved != remoteReference {
+			c.log.Infoln("Resolved Hugging Face reference to:", utils.SanitizeForLog(resolved))
+		}
+		remoteReference = resolved
+	}
+
+	remoteModel, err := c.registry.Model(ctx, remoteReference)
 	if err != nil {
 		return fmt.Errorf("reading model from registry: %w", err)
 	}
@@ -156,11 +329,33 @@ func (c *Client) PullModel(ctx context.Context, reference string, progressWriter
 		return fmt.Errorf("getting remote image digest: %w", err)
 	}
 	c.log.Infoln("Remote model digest:", remoteDigest.String())
-
-	// Check if model exists in local store
-	localModel, err := c.store.Read(remoteDigest.String())
+	span.SetAttributes(attribute.String("model.digest", remoteDigest.String()))
+
+	// A digest reference pins the model rather than naming it, so the model
+	// isn't tagged with it. It's looked up by digest instead.
+	tags := []string{reference}
+	pinned := IsDigestReference(reference)
+	if pinned {
+		tags = nil
+	}
+
+	// Check if model exists in a local store. A model in a read-only store
+	// can't be tagged, so it's pulled again unless it's already tagged.
+	// Models are only looked up in a store selected for the pull.
+	var localStore *store.LocalStore
+	var localModel *store.Model
+	if pullOpts.store != "" {
+		localStore = dst
+		localModel, err = dst.Read(remoteDigest.String())
+	} else {
+		localStore, localModel, err = c.find(remoteDigest.String())
+	}
+	if err == nil && localStore.ReadOnly() && !pinned && !slices.Contains(localModel.Tags(), reference) {
+		err = ErrModelNotFound
+	}
 	if err == nil {
 		c.log.Infoln("Model found in local store:", utils.SanitizeForLog(reference))
+		span.SetAttributes(attribute.Bool("model.cached", true))
 		cfg, err := localModel.Config()
 		if err != nil {
 			return fmt.Errorf("getting cached model config: %w", err)
@@ -174,17 +369,28 @@ func (c *Client) PullModel(ctx context.Context, reference string, progressWriter
 		}
 
 		// Ensure model has the correct tag
-		if err := c.store.AddTags(remoteDigest.String(), []string{reference}); err != nil {
-			return fmt.Errorf("tagging model: %w", err)
+		if !localStore.ReadOnly() && len(tags) > 0 {
+			if err := localStore.AddTags(remoteDigest.String(), tags); err != nil {
+				return fmt.Errorf("tagging model: %w", err)
+			}
 		}
-		return nil
+		return c.pullBaseModels(ctx, dst, cfg, progressWriter)
 	} else {
 		c.log.Infoln("Model not found in local store, pulling from remote:", utils.SanitizeForLog(reference))
 	}
 
 	// Model doesn't exist in local store or digests don't match, pull from remote
 
-	if err = c.store.Write(remoteModel, []string{reference}, progressWriter); err != nil {
+	if err := c.checkLicenses(reference, remoteModel, pullOpts.acceptLicense); err != nil {
+		return err
+	}
+
+	if err := c.checkDiskSpace(reference, dst, remoteModel); err != nil {
+		return err
+	}
+	c.applyDeltas(ctx, dst, remoteReference, remoteModel)
+
+	if err = dst.WriteContext(ctx, remoteModel, tags, progressWriter); err != nil {
 		if writeErr := progress.WriteError(progressWriter, fmt.Sprintf("Error: %s", err.Error())); writeErr != nil {
 			c.log.Warnf("Failed to write error message: %v", writeErr)
 			// If we fail to write error message, don't try again
@@ -192,6 +398,24 @@ func (c *Client) PullModel(ctx context.Context, reference string, progressWriter
 		}
 		return fmt.Errorf("writing image to store: %w", err)
 	}
+	if err := dst.UpdateMetadata(remoteDigest.String(), func(m *types.Metadata) {
+		m.Source = remoteReference
+		m.PullDigest = remoteDigest.String()
+	}); err != nil {
+		c.log.Warnf("Failed to record source of model %s: %v", utils.SanitizeForLog(reference), err)
+	}
+
+	cfg, err := remoteModel.Config()
+	if err != nil {
+		return fmt.Errorf("getting model config: %w", err)
+	}
+	if err := c.pullBaseModels(ctx, dst, cfg, progressWriter); err != nil {
+		if writeErr := progress.WriteError(progressWriter, fmt.Sprintf("Error: %s", err.Error())); writeErr != nil {
+			c.log.Warnf("Failed to write error message: %v", writeErr)
+			progressWriter = nil
+		}
+		return fmt.Errorf("pulling base model: %w", err)
+	}
 
 	if err := progress.WriteSuccess(progressWriter, "Model pulled successfully"); err != nil {
 		c.log.Warnf("Failed to write success message: %v", err)
@@ -205,6 +429,9 @@ func (c *Client) PullModel(ctx context.Context, reference string, progressWriter
 // LoadModel loads the model from the reader to the store
 func (c *Client) LoadModel(r io.Reader, progressWriter io.Writer) (string, error) {
 	c.log.Infoln("Starting model load")
+	if c.readOnly {
+		return "", ErrReadOnlyStore
+	}
 
 	tr := tarball.NewReader(r)
 	for {
@@ -245,34 +472,113 @@ func (c *Client) LoadModel(r io.Reader, progressWriter io.Writer) (string, error
 	return digest.String(), nil
 }
 
-// ListModels returns all available models
+// ListModels returns all available models. A model in several stores is
+// listed once, from the first store containing it.
 func (c *Client) ListModels() ([]types.Model, error) {
 	c.log.Infoln("Listing available models")
-	modelInfos, err := c.store.List()
+	var result []types.Model
+	seen := make(map[string]bool)
+	for _, s := range c.stores {
+		modelInfos, err := s.List()
+		if err != nil {
+			c.log.Errorln("Failed to list models:", err)
+			return nil, fmt.Errorf("listing models in store %q: %w", s.name, err)
+		}
+
+		for _, modelInfo := range modelInfos {
+			if seen[modelInfo.ID] {
+				continue
+			}
+			seen[modelInfo.ID] = true
+			// Read the models
+			model, err := s.Read(modelInfo.ID)
+			if err != nil {
+				c.log.Warnf("Failed to read model with ID %s: %v", modelInfo.ID, err)
+				continue
+			}
+			result = append(result, model)
+		}
+	}
+	if result == nil {
+		result = []types.Model{}
+	}
+
+	c.log.Infoln("Successfully listed models, count:", len(result))
+	return result, nil
+}
+
+// ModelChanges describes the models changed in the store since a cursor.
+type ModelChanges struct {
+	// Cursor is the cursor to pass to the next call to ListModelChanges.
+	Cursor string
+	// Reset indicates that the provided cursor could not be honored, so
+	// Models contains all models and the caller should discard its state.
+	Reset bool
+	// Models are the models created or retagged since the cursor.
+	Models []types.Model
+	// Deleted are the IDs of the models deleted since the cursor.
+	Deleted []string
+}
+
+// ListModelChanges returns the models changed since the given cursor,
+// including deletions. An empty cursor returns all models.
+func (c *Client) ListModelChanges(cursor string) (ModelChanges, error) {
+	cursors, ok := splitCursors(cursor, len(c.stores))
+	if !ok {
+		cursors = make([]string, len(c.stores))
+	}
+	result, err := c.listModelChanges(cursors)
 	if err != nil {
-		c.log.Errorln("Failed to list models:", err)
-		return nil, fmt.Errorf("listing models: %w", err)
+		return ModelChanges{}, err
 	}
+	if result.Reset && cursor != "" {
+		// Report all models, as at least one store can't honor its cursor.
+		if result, err = c.listModelChanges(make([]string, len(c.stores))); err != nil {
+			return ModelChanges{}, err
+		}
+	}
+	result.Reset = result.Reset || !ok
+	return result, nil
+}
 
-	result := make([]types.Model, 0, len(modelInfos))
-	for _, modelInfo := range modelInfos {
-		// Read the models
-		model, err := c.store.Read(modelInfo.ID)
+// listModelChanges returns the models changed in each store since its
+// cursor.
+func (c *Client) listModelChanges(cursors []string) (ModelChanges, error) {
+	result := ModelChanges{Models: []types.Model{}}
+	newCursors := make([]string, len(c.stores))
+	seen := make(map[string]bool)
+	for i, s := range c.stores {
+		changes, err := s.Changes(cursors[i])
 		if err != nil {
-			c.log.Warnf("Failed to read model with ID %s: %v", modelInfo.ID, err)
-			continue
+			return ModelChanges{}, fmt.Errorf("listing model changes: %w", err)
+		}
+		newCursors[i] = changes.Cursor
+		result.Reset = result.Reset || changes.Reset
+		result.Deleted = append(result.Deleted, changes.Deleted...)
+		for _, modelInfo := range changes.Models {
+			if seen[modelInfo.ID] {
+				continue
+			}
+			seen[modelInfo.ID] = true
+			model, err := s.Read(modelInfo.ID)
+			if err != nil {
+				c.log.Warnf("Failed to read model with ID %s: %v", modelInfo.ID, err)
+				continue
+			}
+			result.Models = append(result.Models, model)
 		}
-		result = append(result, model)
 	}
-
-	c.log.Infoln("Successfully listed models, count:", len(result))
+	result.Cursor = joinCursors(newCursors)
 	return result, nil
 }
 
 // GetModel returns a model by reference
 func (c *Client) GetModel(reference string) (types.Model, error) {
 	c.log.Infoln("Getting model by reference:", utils.SanitizeForLog(reference))
-	model, err := c.store.Read(reference)
+	if err := c.checkReference(reference); err != nil {
+		return nil, err
+	}
+	_, model, err := c.find(reference)
 	if err != nil {
 		c.log.Errorln("Failed to get model:", err, "reference:", utils.SanitizeForLog(reference))
 		return nil, fmt.Errorf("get model '%q': %w", reference, err)
@@ -284,7 +590,7 @@ func (c *Client) GetModel(reference string) (types.Model, error) {
 // IsModelInStore checks if a model with the given reference is in the local store
 func (c *Client) IsModelInStore(reference string) (bool, error) {
 	c.log.Infoln("Checking model by reference:", utils.SanitizeForLog(reference))
-	if _, err := c.store.Read(reference); errors.Is(err, ErrModelNotFound) {
+	if _, _, err := c.find(reference); errors.Is(err, ErrModelNotFound) {
 		return false, nil
 	} else if err != nil {
 		return false, err
@@ -295,13 +601,17 @@ func (c *Client) IsModelInStore(reference string) (bool, error) {
 type DeleteModelAction struct {
 	Untagged *string `json:"Untagged,omitempty"`
 	Deleted  *string `json:"Deleted,omitempty"`
+	Warning  *string `json:"Warning,omitempty"`
 }
 
 type DeleteModelResponse []DeleteModelAction
 
 // DeleteModel deletes a model
 func (c *Client) DeleteModel(reference string, force bool) (*DeleteModelResponse, error) {
-	mdl, err := c.store.Read(reference)
+	if c.readOnly {
+		return &DeleteModelResponse{}, ErrReadOnlyStore
+	}
+	s, mdl, err := c.find(reference)
 	if err != nil {
 		return &DeleteModelResponse{}, err
 	}
@@ -315,7 +625,7 @@ func (c *Client) DeleteModel(reference string, force bool) (*DeleteModelResponse
 
 	if isTag {
 		c.log.Infoln("Untagging model:", reference)
-		tags, err := c.store.RemoveTags([]string{reference})
+		tags, err := s.RemoveTags([]string{reference})
 		if err != nil {
 			c.log.Errorln("Failed to untag model:", err, "tag:", reference)
 			return &DeleteModelResponse{}, fmt.Errorf("untagging model: %w", err)
@@ -336,8 +646,13 @@ func (c *Client) DeleteModel(reference string, force bool) (*DeleteModelResponse
 		)
 	}
 
+	dependents, err := c.dependents(id)
+	if err != nil {
+		c.log.Warnf("Failed to check for dependent models: %v", err)
+	}
+
 	c.log.Infoln("Deleting model:", id)
-	deletedID, tags, err := c.store.Delete(id)
+	deletedID, tags, err := s.Delete(id)
 	if err != nil {
 		c.log.Errorln("Failed to delete model:", err, "tag:", reference)
 		return &DeleteModelResponse{}, fmt.Errorf("deleting model: %w", err)
@@ -347,17 +662,37 @@ func (c *Client) DeleteModel(reference string, force bool) (*DeleteModelResponse
 		resp = append(resp, DeleteModelAction{Untagged: &t})
 	}
 	resp = append(resp, DeleteModelAction{Deleted: &deletedID})
+	for _, dependent := range dependents {
+		warning := fmt.Sprintf("model %s depends on deleted model %s and must be pulled again to use it", dependent, deletedID)
+		c.log.Warnln(warning)
+		resp = append(resp, DeleteModelAction{Warning: &warning})
+	}
 	return &resp, nil
 }
 
 // Tag adds a tag to a model
 func (c *Client) Tag(source string, target string) error {
 	c.log.Infoln("Tagging model, source:", source, "target:", utils.SanitizeForLog(target))
-	return c.store.AddTags(source, []string{target})
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
+	s, mdl, err := c.find(source)
+	if err != nil {
+		return err
+	}
+	// The source may be an alias, so tag the model it resolved to.
+	id, err := mdl.ID()
+	if err != nil {
+		return fmt.Errorf("getting model ID: %w", err)
+	}
+	return s.AddTags(id, []string{target})
 }
 
 // PushModel pushes a tagged model from the content store to the registry.
 func (c *Client) PushModel(ctx context.Context, tag string, progressWriter io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "distribution.PushModel", trace.WithAttributes(attribute.String("model.reference", tag)))
+	defer func() { tracing.End(span, err) }()
+
 	// Parse the tag
 	target, err := c.registry.NewTarget(tag)
 	if err != nil {
@@ -365,7 +700,7 @@ func (c *Client) PushModel(ctx context.Context, tag string, progressWriter io.Wr
 	}
 
 	// Get the model from the store
-	mdl, err := c.store.Read(tag)
+	_, mdl, err := c.find(tag)
 	if err != nil {
 		return fmt.Errorf("reading model: %w", err)
 	}
@@ -388,16 +723,154 @@ func (c *Client) PushModel(ctx context.Context, tag string, progressWriter io.Wr
 	return nil
 }
 
+// CopyModel copies a model from one registry reference to a tag, possibly in
+// another registry, streaming its blobs without writing them to the store.
+// Blobs already in the destination repository are skipped, and the others are
+// mounted from the source repository rather than uploaded where the registry
+// supports it, typically within the same registry. The manifest, and so the
+// model digest, is kept.
+func (c *Client) CopyModel(ctx context.Context, source, tag string, progressWriter io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "distribution.CopyModel", trace.WithAttributes(
+		attribute.String("model.source", source),
+		attribute.String("model.reference", tag),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	target, err := c.registry.NewTarget(tag)
+	if err != nil {
+		return fmt.Errorf("new tag: %w", err)
+	}
+	mdl, err := c.registry.Model(ctx, source)
+	if err != nil {
+		return fmt.Errorf("reading model from registry: %w", err)
+	}
+
+	c.log.Infoln("Copying model:", utils.SanitizeForLog(source), "to", utils.SanitizeForLog(tag))
+	if err := target.Write(ctx, mdl, progressWriter); err != nil {
+		c.log.Errorln("Failed to copy model:", err, "reference:", utils.SanitizeForLog(tag))
+		if writeErr := progress.WriteError(progressWriter, fmt.Sprintf("Error: %s", err.Error())); writeErr != nil {
+			c.log.Warnf("Failed to write error message: %v", writeErr)
+		}
+		return fmt.Errorf("copying model: %w", err)
+	}
+
+	c.log.Infoln("Successfully copied model to:", utils.SanitizeForLog(tag))
+	if err := progress.WriteSuccess(progressWriter, "Model copied successfully"); err != nil {
+		c.log.Warnf("Failed to write success message: %v", err)
+	}
+	return nil
+}
+
 // WriteLightweightModel writes a model to the store without transferring layer data.
 // This is used for config-only modifications where the layer data hasn't changed.
 // The layers must already exist in the store.
 func (c *Client) WriteLightweightModel(mdl types.ModelArtifact, tags []string) error {
 	c.log.Infoln("Writing lightweight model variant")
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
 	return c.store.WriteLightweight(mdl, tags)
 }
 
+// DedupStats describes how blobs are shared between models in the store.
+type DedupStats = store.DedupStats
+
+// BlobStats describes a single blob in the store.
+type BlobStats = store.BlobStats
+
+// DedupStats returns blob deduplication statistics for the store to which
+// models are written by default.
+func (c *Client) DedupStats() (DedupStats, error) {
+	stats, err := c.store.DedupStats()
+	if err != nil {
+		return DedupStats{}, fmt.Errorf("computing dedup stats: %w", err)
+	}
+	return stats, nil
+}
+
+// RunnerSettings are the settings of the runners of a model that are kept in
+// the store, so that they survive restarts.
+type RunnerSettings = store.RunnerSettings
+
+// RunnerSettings returns the runner settings kept in the store to which models
+// are written by default.
+func (c *Client) RunnerSettings() ([]RunnerSettings, error) {
+	settings, err := c.store.RunnerSettings()
+	if err != nil {
+		return nil, fmt.Errorf("reading runner settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetRunnerSettings keeps the runner settings of a model in the store to which
+// models are written by default, replacing those kept for the same backend,
+// model and mode.
+func (c *Client) SetRunnerSettings(settings RunnerSettings) error {
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
+	if err := c.store.SetRunnerSettings(settings); err != nil {
+		return fmt.Errorf("keeping runner settings: %w", err)
+	}
+	return nil
+}
+
+// MarkModelUsed records that the model with the given reference was used.
+// Usage isn't recorded for models in read-only stores.
+func (c *Client) MarkModelUsed(reference string) error {
+	s, _, err := c.find(reference)
+	if err != nil {
+		return fmt.Errorf("marking model used: %w", err)
+	}
+	if s.ReadOnly() {
+		return nil
+	}
+	if err := s.UpdateMetadata(reference, func(m *types.Metadata) {
+		t := time.Now().UTC()
+		m.LastUsed = &t
+	}); err != nil {
+		return fmt.Errorf("marking model used: %w", err)
+	}
+	return nil
+}
+
+// VerifyModel re-hashes all blobs of the model with the given reference and
+// returns an error describing every blob that is missing or corrupted.
+func (c *Client) VerifyModel(reference string) error {
+	c.log.Infoln("Verifying model:", utils.SanitizeForLog(reference))
+	s, _, err := c.find(reference)
+	if err != nil {
+		return fmt.Errorf("verifying model: %w", err)
+	}
+	if err := s.Verify(reference); err != nil {
+		return fmt.Errorf("verifying model: %w", err)
+	}
+	return nil
+}
+
+// RepairReport describes the changes made by RepairStore.
+type RepairReport = store.RepairReport
+
+// RepairStore removes models whose manifest or blobs are missing or corrupt
+// from the store to which models are written by default, and quarantines
+// corrupt files.
+func (c *Client) RepairStore() (RepairReport, error) {
+	c.log.Infoln("Repairing store")
+	if c.readOnly {
+		return RepairReport{}, ErrReadOnlyStore
+	}
+	report, err := c.store.Repair()
+	if err != nil {
+		return report, fmt.Errorf("repairing store: %w", err)
+	}
+	return report, nil
+}
+
 func (c *Client) ResetStore() error {
 	c.log.Infoln("Resetting store")
+	if c.readOnly {
+		return ErrReadOnlyStore
+	}
 	if err := c.store.Reset(); err != nil {
 		c.log.Errorln("Failed to reset store:", err)
 		return fmt.Errorf("resetting store: %w", err)
@@ -405,16 +878,64 @@ func (c *Client) ResetStore() error {
 	return nil
 }
 
-// GetBundle returns a types.Bundle containing the model, creating one as necessary
+// GetBundle returns a types.Bundle containing the model, creating one as
+// necessary. Bundles of models in read-only stores are created in the store to
+// which models are written by default.
 func (c *Client) GetBundle(ref string) (types.ModelBundle, error) {
-	return c.store.BundleForModel(ref)
+	if err := c.checkReference(ref); err != nil {
+		return nil, err
+	}
+	s, _, err := c.find(ref)
+	if err != nil {
+		return nil, fmt.Errorf("find model content: %w", err)
+	}
+	if s.ReadOnly() {
+		return c.store.BundleForModelFrom(s, ref)
+	}
+	return s.BundleForModel(ref)
+}
+
+// AcquireBundle returns a bundle like GetBundle, and holds a reference to it
+// until release is called, so that deleting the model while the bundle is in
+// use, for example by a running backend, only removes the bundle once it's
+// released.
+func (c *Client) AcquireBundle(ref string) (bundle types.ModelBundle, release func(), err error) {
+	if err := c.checkReference(ref); err != nil {
+		return nil, nil, err
+	}
+	s, _, err := c.find(ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("find model content: %w", err)
+	}
+	if s.ReadOnly() {
+		return c.store.AcquireBundleFrom(s, ref)
+	}
+	return s.AcquireBundleFrom(s, ref)
+}
+
+// RefreshBundle recreates the bundle of the model, for example after a
+// change to how bundles are created. It fails with ErrBundleInUse if the
+// bundle is held by AcquireBundle.
+func (c *Client) RefreshBundle(ref string) (types.ModelBundle, error) {
+	if err := c.checkReference(ref); err != nil {
+		return nil, err
+	}
+	s, _, err := c.find(ref)
+	if err != nil {
+		return nil, fmt.Errorf("find model content: %w", err)
+	}
+	c.log.Infoln("Refreshing bundle for model:", utils.SanitizeForLog(ref))
+	if s.ReadOnly() {
+		return c.store.RefreshBundleFrom(s, ref)
+	}
+	return s.RefreshBundleFrom(s, ref)
 }
 
 func GetSupportedFormats() []types.Format {
 	if platform.SupportsVLLM() {
-		return []types.Format{types.FormatGGUF, types.FormatSafetensors}
+		return []types.Format{types.FormatGGUF, types.FormatSafetensors, types.FormatWhisper, types.FormatPiper, types.FormatDiffusion, types.FormatONNX}
 	}
-	return []types.Format{types.FormatGGUF}
+	return []types.Format{types.FormatGGUF, types.FormatWhisper, types.FormatPiper, types.FormatDiffusion, types.FormatONNX}
 }
 
 func checkCompat(image types.ModelArtifact) error {
@@ -9,11 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -26,6 +28,7 @@ import (
 	"github.com/docker/model-runner/pkg/distribution/internal/progress"
 	"github.com/docker/model-runner/pkg/distribution/internal/safetensors"
 	mdregistry "github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/resolver"
 	"github.com/docker/model-runner/pkg/inference/platform"
 )
 
@@ -105,6 +108,40 @@ func TestClientPullModel(t *testing.T) {
 		}
 	})
 
+	t.Run("pull with name resolver", func(t *testing.T) {
+		resolverClient, err := NewClient(
+			WithStoreRootPath(t.TempDir()),
+			WithNameResolver(resolver.Static{"internal/chat-prod": tag}),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		if err := resolverClient.PullModel(context.Background(), "internal/chat-prod:latest", nil); err != nil {
+			t.Fatalf("Failed to pull model: %v", err)
+		}
+
+		// The model is tagged locally with the short name, not the resolved reference
+		model, err := resolverClient.GetModel("internal/chat-prod:latest")
+		if err != nil {
+			t.Fatalf("Failed to get model by short name: %v", err)
+		}
+		if _, err := resolverClient.GetModel(tag); !errors.Is(err, ErrModelNotFound) {
+			t.Errorf("Expected resolved reference not to be tagged locally, got %v", err)
+		}
+		modelPaths, err := model.GGUFPaths()
+		if err != nil {
+			t.Fatalf("Failed to get model path: %v", err)
+		}
+		pulledContent, err := os.ReadFile(modelPaths[0])
+		if err != nil {
+			t.Fatalf("Failed to read pulled model: %v", err)
+		}
+		if string(pulledContent) != string(modelContent) {
+			t.Errorf("Pulled model content doesn't match original")
+		}
+	})
+
 	t.Run("pull with progress writer", func(t *testing.T) {
 		// Create a buffer to capture progress output
 		var progressBuffer bytes.Buffer
@@ -1236,3 +1273,78 @@ func randomFile(size int64) (string, error) {
 
 	return f.Name(), nil
 }
+
+func TestClientCopyModel(t *testing.T) {
+	source := httptest.NewServer(registry.New())
+	defer source.Close()
+	// Record whether the destination is asked to mount blobs from the source
+	var mounted atomic.Bool
+	handler := registry.New()
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mount") != "" {
+			mounted.Store(true)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer destination.Close()
+
+	sourceURL, err := url.Parse(source.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	destinationURL, err := url.Parse(destination.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	sourceTag := sourceURL.Host + "/staging/model:v1"
+	if err := writeToRegistry(testGGUFFile, sourceTag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	digest := func(reference string) string {
+		t.Helper()
+		ref, err := name.ParseReference(reference)
+		if err != nil {
+			t.Fatalf("Failed to parse reference: %v", err)
+		}
+		desc, err := remote.Get(ref)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", reference, err)
+		}
+		return desc.Digest.String()
+	}
+
+	// Copy to another registry.
+	destinationTag := destinationURL.Host + "/prod/model:v1"
+	if err := client.CopyModel(t.Context(), sourceTag, destinationTag, nil); err != nil {
+		t.Fatalf("Failed to copy model: %v", err)
+	}
+	if digest(destinationTag) != digest(sourceTag) {
+		t.Errorf("Expected the copy to keep the model digest")
+	}
+	if models, err := client.ListModels(); err != nil || len(models) != 0 {
+		t.Errorf("Expected the store to remain empty, got %d models, %v", len(models), err)
+	}
+	if !mounted.Load() {
+		t.Error("Expected a blob mount to be attempted")
+	}
+
+	// Copy to another repository of the same registry.
+	promotedTag := sourceURL.Host + "/prod/model:v1"
+	if err := client.CopyModel(t.Context(), sourceTag, promotedTag, nil); err != nil {
+		t.Fatalf("Failed to copy model: %v", err)
+	}
+	if digest(promotedTag) != digest(sourceTag) {
+		t.Errorf("Expected the copy to keep the model digest")
+	}
+
+	// Missing sources fail.
+	if err := client.CopyModel(t.Context(), sourceURL.Host+"/staging/model:v2", promotedTag, nil); err == nil {
+		t.Error("Expected an error copying a missing model")
+	}
+}
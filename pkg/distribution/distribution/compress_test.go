@@ -0,0 +1,116 @@
+package distribution
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/docker/model-runner/pkg/distribution/builder"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestPullModelWithZstdLayers(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	licensePath := filepath.Join("..", "assets", "license.txt")
+	licenseText, err := os.ReadFile(licensePath)
+	if err != nil {
+		t.Fatalf("Failed to read license: %v", err)
+	}
+	templatePath := filepath.Join("..", "assets", "template.jinja")
+	templateText, err := os.ReadFile(templatePath)
+	if err != nil {
+		t.Fatalf("Failed to read chat template: %v", err)
+	}
+
+	b, err := builder.FromGGUF(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create builder: %v", err)
+	}
+	if b, err = b.WithLicense(licensePath); err != nil {
+		t.Fatalf("Failed to add license: %v", err)
+	}
+	if b, err = b.WithChatTemplateFile(templatePath); err != nil {
+		t.Fatalf("Failed to add chat template: %v", err)
+	}
+	if b, err = b.WithZstd(); err != nil {
+		t.Fatalf("Failed to compress layers: %v", err)
+	}
+	defer b.Cleanup()
+
+	manifest, err := b.Model().Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	var mediaTypes []string
+	for _, l := range manifest.Layers {
+		mediaTypes = append(mediaTypes, string(l.MediaType))
+	}
+	expected := []string{string(types.MediaTypeGGUF), string(types.MediaTypeLicenseZstd), string(types.MediaTypeChatTemplateZstd)}
+	if len(mediaTypes) != len(expected) {
+		t.Fatalf("Expected layers %v, got %v", expected, mediaTypes)
+	}
+	for i := range expected {
+		if mediaTypes[i] != expected[i] {
+			t.Fatalf("Expected layers %v, got %v", expected, mediaTypes)
+		}
+	}
+
+	tag := registryURL.Host + "/compressed:v1"
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	if err := remote.Write(ref, b.Model()); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.PullModel(context.Background(), tag, nil); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+
+	mdl, err := client.GetModel(tag)
+	if err != nil {
+		t.Fatalf("Failed to get model: %v", err)
+	}
+	licenses, err := mdl.Licenses()
+	if err != nil {
+		t.Fatalf("Failed to read licenses: %v", err)
+	}
+	if len(licenses) != 1 || licenses[0] != string(licenseText) {
+		t.Errorf("Expected license %q, got %q", licenseText, licenses)
+	}
+
+	bundle, err := client.GetBundle(tag)
+	if err != nil {
+		t.Fatalf("Failed to get bundle: %v", err)
+	}
+	got, err := os.ReadFile(bundle.ChatTemplatePath())
+	if err != nil {
+		t.Fatalf("Failed to read bundled chat template: %v", err)
+	}
+	if string(got) != string(templateText) {
+		t.Errorf("Expected decompressed chat template %q, got %q", templateText, got)
+	}
+
+	// The store keeps the compressed layers, so the model can be pushed as is
+	if err := client.store.Verify(tag); err != nil {
+		t.Errorf("Expected store to verify: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package distribution
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/static"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// ModelConfigUpdate describes changes to the runtime configuration of a model.
+// Nil fields are left unchanged.
+type ModelConfigUpdate struct {
+	// ChatTemplate, if set, replaces the chat template of the model.
+	ChatTemplate *string
+	// ContextSize, if set, replaces the context size of the model.
+	ContextSize *uint64
+	// RuntimeFlags, if set, replaces the runtime flags of the model. An empty
+	// slice clears them.
+	RuntimeFlags *[]string
+}
+
+// ConfigureModel creates a lightweight variant of the model with the given
+// reference by applying update, and applies tags to it. The weights of the
+// model are shared with the variant, so only the config, the manifest, and the
+// chat template (if changed) are written to the store. It returns the ID of
+// the variant.
+func (c *Client) ConfigureModel(reference string, update ModelConfigUpdate, tags []string) (string, error) {
+	if update.ChatTemplate == nil && update.ContextSize == nil && update.RuntimeFlags == nil {
+		return "", errors.New("no configuration changes requested")
+	}
+
+	c.log.Infoln("Configuring model:", utils.SanitizeForLog(reference))
+	if c.readOnly {
+		return "", ErrReadOnlyStore
+	}
+	s, mdl, err := c.find(reference)
+	if err != nil {
+		return "", fmt.Errorf("reading model: %w", err)
+	}
+
+	var variant types.ModelArtifact = mdl
+	if update.ContextSize != nil {
+		variant = mutate.ContextSize(variant, *update.ContextSize)
+	}
+	if update.RuntimeFlags != nil {
+		variant = mutate.RuntimeFlags(variant, *update.RuntimeFlags)
+	}
+	if update.ChatTemplate != nil {
+		// The template layer is the only new blob, so write it to the store
+		// before the lightweight write checks for it.
+		content := []byte(*update.ChatTemplate)
+		layer := static.NewLayer(content, types.MediaTypeChatTemplate)
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return "", fmt.Errorf("chat template digest: %w", err)
+		}
+		if err := s.WriteBlob(diffID, bytes.NewReader(content)); err != nil {
+			return "", fmt.Errorf("writing chat template: %w", err)
+		}
+		variant = mutate.RemoveLayers(variant, types.MediaTypeChatTemplate)
+		variant = mutate.RemoveLayers(variant, types.MediaTypeChatTemplateZstd)
+		variant = mutate.AppendLayers(variant, layer)
+	}
+
+	// The variant shares the blobs of the model, so it's written to the same
+	// store.
+	c.log.Infoln("Writing lightweight model variant")
+	if err := s.WriteLightweight(variant, tags); err != nil {
+		return "", fmt.Errorf("writing model variant: %w", err)
+	}
+	id, err := variant.ID()
+	if err != nil {
+		return "", fmt.Errorf("getting model variant ID: %w", err)
+	}
+	return id, nil
+}
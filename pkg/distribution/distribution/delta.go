@@ -0,0 +1,93 @@
+package distribution
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/delta"
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// applyDeltas reconstructs the layers of mdl that dst doesn't have from the
+// deltas the registry has for mdl against layers dst does have, such as
+// those of the base of a fine-tune, so that they needn't be downloaded in
+// full. Deltas are only an optimization, so layers that can't be
+// reconstructed are downloaded as usual.
+func (c *Client) applyDeltas(ctx context.Context, dst *store.LocalStore, reference string, mdl v1.Image) {
+	digest, err := mdl.Digest()
+	if err != nil {
+		return
+	}
+	deltas, err := c.registry.Deltas(ctx, reference, digest)
+	if err != nil {
+		c.log.Warnf("Failed to list deltas of model %s: %v", utils.SanitizeForLog(reference), err)
+		return
+	}
+	if len(deltas) == 0 {
+		return
+	}
+	layers, err := mdl.Layers()
+	if err != nil {
+		return
+	}
+	needed := make(map[v1.Hash]bool)
+	for _, layer := range layers {
+		if diffID, err := layer.DiffID(); err == nil {
+			needed[diffID] = true
+		}
+	}
+
+	for _, d := range deltas {
+		if !needed[d.Target] {
+			continue
+		}
+		if has, err := dst.HasBlob(d.Target); err != nil || has {
+			continue
+		}
+		if has, err := dst.HasBlob(d.Base); err != nil || !has {
+			continue
+		}
+		if err := applyDelta(dst, d); err != nil {
+			c.log.Warnf("Failed to apply delta for layer %s, downloading it instead: %v", d.Target, err)
+			continue
+		}
+		c.log.Infof("Reconstructed layer %s from a delta against %s", d.Target, d.Base)
+		needed[d.Target] = false
+	}
+}
+
+// applyDelta writes the target layer of d to dst, reconstructed from its
+// base layer. The layer is verified against its DiffID as it's written.
+func applyDelta(dst *store.LocalStore, d registry.Delta) error {
+	base, err := dst.OpenBlob(d.Base)
+	if err != nil {
+		return fmt.Errorf("opening base layer: %w", err)
+	}
+	defer base.Close()
+	info, err := base.Stat()
+	if err != nil {
+		return fmt.Errorf("opening base layer: %w", err)
+	}
+	rc, err := d.Layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("downloading delta: %w", err)
+	}
+	defer rc.Close()
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(delta.Apply(base, info.Size(), rc, pw))
+	}()
+	err = dst.WriteBlob(d.Target, pr)
+	// Stop applying the delta if writing failed.
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	return err
+}
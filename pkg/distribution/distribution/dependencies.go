@@ -0,0 +1,113 @@
+package distribution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/progress"
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// maxBaseModelDepth bounds the length of a base model chain, guarding against
+// cycles in malformed artifacts.
+const maxBaseModelDepth = 8
+
+// pullBaseModels pulls the chain of base models that cfg depends on to dst,
+// skipping any that are already in a store.
+func (c *Client) pullBaseModels(ctx context.Context, dst *store.LocalStore, cfg types.Config, progressWriter io.Writer) error {
+	for depth := 0; cfg.BaseModel != nil; depth++ {
+		if depth >= maxBaseModelDepth {
+			return fmt.Errorf("base model chain exceeds %d models", maxBaseModelDepth)
+		}
+		base := *cfg.BaseModel
+
+		if _, local, err := c.find(base.Digest); err == nil {
+			// Dependencies of models in the store have already been pulled,
+			// unless they were deleted since, so keep walking the chain.
+			cfg, err = local.Config()
+			if err != nil {
+				return fmt.Errorf("getting base model config: %w", err)
+			}
+			continue
+		} else if !errors.Is(err, ErrModelNotFound) {
+			return fmt.Errorf("checking for base model %s: %w", base.Digest, err)
+		}
+
+		if base.Reference == "" {
+			return fmt.Errorf("base model %s is not in the local store and has no reference to pull it from", base.Digest)
+		}
+		ref, err := name.ParseReference(base.Reference)
+		if err != nil {
+			return fmt.Errorf("parsing base model reference %q: %w", base.Reference, err)
+		}
+		// Pull by digest so that the dependency can't drift if the
+		// reference is a mutable tag.
+		digestRef := ref.Context().Digest(base.Digest).String()
+		c.log.Infoln("Pulling base model:", utils.SanitizeForLog(digestRef))
+
+		remoteModel, err := c.registry.Model(ctx, digestRef)
+		if err != nil {
+			return fmt.Errorf("reading base model from registry: %w", err)
+		}
+		if err := checkCompat(remoteModel); err != nil {
+			return err
+		}
+
+		// Only tag the base model if it was referenced by tag.
+		var tags []string
+		if _, ok := ref.(name.Tag); ok {
+			tags = []string{base.Reference}
+		}
+		if err := dst.Write(remoteModel, tags, progressWriter); err != nil {
+			return fmt.Errorf("writing base model to store: %w", err)
+		}
+		if err := progress.WriteSuccess(progressWriter, fmt.Sprintf("Pulled base model %s", base.Digest)); err != nil {
+			c.log.Warnf("Failed to write progress: %v", err)
+			progressWriter = nil
+		}
+
+		cfg, err = remoteModel.Config()
+		if err != nil {
+			return fmt.Errorf("getting base model config: %w", err)
+		}
+	}
+	return nil
+}
+
+// dependents returns the IDs of the models in the stores that declare id as
+// their base model.
+func (c *Client) dependents(id string) ([]string, error) {
+	var ids []string
+	for _, s := range c.stores {
+		entries, err := s.List()
+		if err != nil {
+			return nil, fmt.Errorf("listing models: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.ID == id || slices.Contains(ids, entry.ID) {
+				continue
+			}
+			mdl, err := s.Read(entry.ID)
+			if err != nil {
+				c.log.Warnf("Failed to read model %s: %v", entry.ID, err)
+				continue
+			}
+			cfg, err := mdl.Config()
+			if err != nil {
+				c.log.Warnf("Failed to read config of model %s: %v", entry.ID, err)
+				continue
+			}
+			if cfg.BaseModel != nil && cfg.BaseModel.Digest == id {
+				ids = append(ids, entry.ID)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,136 @@
+package distribution
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestPullModelWithBaseModel(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	// Push the base model
+	base, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	baseTag := registryURL.Host + "/base:v1"
+	baseRef, err := name.ParseReference(baseTag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	if err := remote.Write(baseRef, base); err != nil {
+		t.Fatalf("Failed to push base model: %v", err)
+	}
+	baseDigest, err := base.Digest()
+	if err != nil {
+		t.Fatalf("Failed to get base model digest: %v", err)
+	}
+
+	// Push a model that depends on it
+	dependent := mutate.BaseModel(base, types.BaseModel{Digest: baseDigest.String(), Reference: baseTag})
+	dependentTag := registryURL.Host + "/dependent:v1"
+	dependentRef, err := name.ParseReference(dependentTag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	if err := remote.Write(dependentRef, dependent); err != nil {
+		t.Fatalf("Failed to push dependent model: %v", err)
+	}
+	dependentDigest, err := dependent.Digest()
+	if err != nil {
+		t.Fatalf("Failed to get dependent model digest: %v", err)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if err := client.PullModel(context.Background(), dependentTag, nil); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+
+	// The base model is pulled by digest and tagged with its reference
+	if _, err := client.GetModel(baseDigest.String()); err != nil {
+		t.Fatalf("Expected base model to be in store: %v", err)
+	}
+	if _, err := client.GetModel(baseTag); err != nil {
+		t.Errorf("Expected base model to be tagged %s: %v", baseTag, err)
+	}
+
+	// Deleting the base model warns about the dependent model
+	resp, err := client.DeleteModel(baseDigest.String(), true)
+	if err != nil {
+		t.Fatalf("Failed to delete base model: %v", err)
+	}
+	var warning string
+	for _, action := range *resp {
+		if action.Warning != nil {
+			warning = *action.Warning
+		}
+	}
+	if !strings.Contains(warning, dependentDigest.String()) {
+		t.Errorf("Expected warning about dependent model %s, got %q", dependentDigest, warning)
+	}
+
+	// Pulling the dependent model again restores the base model
+	if err := client.PullModel(context.Background(), dependentTag, nil); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+	if _, err := client.GetModel(baseDigest.String()); err != nil {
+		t.Errorf("Expected base model to be pulled again: %v", err)
+	}
+}
+
+func TestPullModelWithUnreachableBaseModel(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	mdl, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	// The base model has no reference and is not in the local store
+	dependent := mutate.BaseModel(mdl, types.BaseModel{Digest: "sha256:" + strings.Repeat("0", 64)})
+	tag := registryURL.Host + "/dependent:v1"
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	if err := remote.Write(ref, dependent); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	err = client.PullModel(context.Background(), tag, nil)
+	if err == nil || !strings.Contains(err.Error(), "base model") {
+		t.Fatalf("Expected base model error, got %v", err)
+	}
+	if errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected error not to be reported as the model not being found")
+	}
+}
@@ -0,0 +1,51 @@
+package distribution
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// WithRequireDigest refuses mutable references, so that deployments have
+// reproducible model inputs. Models must be pulled and used by digest
+// reference, such as ai/gemma3@sha256:<digest>, or by model ID, and other
+// references fail with ErrDigestRequired. Listing, tagging and deleting
+// models are unaffected.
+func WithRequireDigest() Option {
+	return func(o *options) {
+		o.requireDigest = true
+	}
+}
+
+// IsDigestReference reports whether reference pins a model by digest, as in
+// ai/gemma3@sha256:<digest>.
+func IsDigestReference(reference string) bool {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return false
+	}
+	_, ok := ref.(name.Digest)
+	return ok
+}
+
+// IsModelID reports whether reference is a model ID, as in sha256:<digest>.
+func IsModelID(reference string) bool {
+	_, err := v1.NewHash(reference)
+	return err == nil
+}
+
+// IsImmutableReference reports whether reference always refers to the same
+// model, because it's a digest reference or a model ID.
+func IsImmutableReference(reference string) bool {
+	return IsModelID(reference) || IsDigestReference(reference)
+}
+
+// checkReference returns ErrDigestRequired if the client requires digest
+// references and reference is mutable.
+func (c *Client) checkReference(reference string) error {
+	if c.requireDigest && !IsImmutableReference(reference) {
+		return fmt.Errorf("%w: %q", ErrDigestRequired, reference)
+	}
+	return nil
+}
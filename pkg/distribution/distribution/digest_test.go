@@ -0,0 +1,98 @@
+package distribution
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestIsImmutableReference(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	immutable := []string{
+		"ai/gemma3@" + digest,
+		"registry.example.com/ai/gemma3@" + digest,
+		digest,
+	}
+	mutable := []string{"ai/gemma3:latest", "ai/gemma3", "ai/gemma3@sha256:invalid", "sha256:invalid"}
+	for _, reference := range immutable {
+		if !IsImmutableReference(reference) {
+			t.Errorf("Expected %q to be immutable", reference)
+		}
+	}
+	for _, reference := range mutable {
+		if IsImmutableReference(reference) {
+			t.Errorf("Expected %q to be mutable", reference)
+		}
+	}
+}
+
+func TestPullByDigest(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	tag := registryURL.Host + "/testmodel:v1"
+	if err := writeToRegistry(testGGUFFile, tag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	desc, err := remote.Head(ref)
+	if err != nil {
+		t.Fatalf("Failed to get model digest: %v", err)
+	}
+	pinned := registryURL.Host + "/testmodel@" + desc.Digest.String()
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()), WithRequireDigest())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Mutable tags are refused.
+	if err := client.PullModel(t.Context(), tag, nil); !errors.Is(err, ErrDigestRequired) {
+		t.Errorf("Expected ErrDigestRequired pulling a tag, got %v", err)
+	}
+
+	// Digest references are pulled without tagging the model with them.
+	if err := client.PullModel(t.Context(), pinned, nil); err != nil {
+		t.Fatalf("Failed to pull model by digest: %v", err)
+	}
+	model, err := client.GetModel(pinned)
+	if err != nil {
+		t.Fatalf("Failed to get model by digest: %v", err)
+	}
+	if tags := model.Tags(); len(tags) != 0 {
+		t.Errorf("Expected no tags, got %v", tags)
+	}
+	if _, err := client.GetModel(desc.Digest.String()); err != nil {
+		t.Errorf("Failed to get model by ID: %v", err)
+	}
+	if _, err := client.GetBundle(pinned); err != nil {
+		t.Errorf("Failed to get bundle by digest: %v", err)
+	}
+
+	// Pulling again uses the stored model.
+	if err := client.PullModel(t.Context(), pinned, nil); err != nil {
+		t.Errorf("Failed to pull model by digest again: %v", err)
+	}
+
+	// Tags are refused even for stored models.
+	if err := client.Tag(pinned, "testmodel:v1"); err != nil {
+		t.Fatalf("Failed to tag model: %v", err)
+	}
+	if _, err := client.GetModel("testmodel:v1"); !errors.Is(err, ErrDigestRequired) {
+		t.Errorf("Expected ErrDigestRequired getting a model by tag, got %v", err)
+	}
+	if _, err := client.GetBundle("testmodel:v1"); !errors.Is(err, ErrDigestRequired) {
+		t.Errorf("Expected ErrDigestRequired getting a bundle by tag, got %v", err)
+	}
+}
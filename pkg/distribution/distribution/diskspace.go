@@ -0,0 +1,37 @@
+package distribution
+
+import (
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// checkDiskSpace returns a DiskSpaceError if the volume of dst doesn't have
+// room for the layers of mdl that must be downloaded, plus the configured
+// headroom. The check is skipped if the free space can't be determined.
+func (c *Client) checkDiskSpace(reference string, dst *store.LocalStore, mdl v1.Image) error {
+	missing, err := dst.MissingSize(mdl)
+	if err != nil {
+		return fmt.Errorf("computing download size: %w", err)
+	}
+	free, err := c.freeSpace(dst.RootPath())
+	if err != nil {
+		c.log.Warnf("Failed to get free disk space for %s: %v", dst.RootPath(), err)
+		return nil
+	}
+	required := uint64(missing) + c.diskHeadroom
+	if required > free {
+		c.log.Warnf("Insufficient disk space to pull model %s: %d bytes required, %d available",
+			utils.SanitizeForLog(reference), required, free)
+		return &DiskSpaceError{
+			Reference: reference,
+			Path:      dst.RootPath(),
+			Required:  required,
+			Available: free,
+		}
+	}
+	return nil
+}
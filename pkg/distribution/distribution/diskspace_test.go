@@ -0,0 +1,64 @@
+package distribution
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestPullModelInsufficientDiskSpace(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	tag := registryURL.Host + "/testmodel:v1"
+	if err := writeToRegistry(testGGUFFile, tag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+	info, err := os.Stat(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to stat model file: %v", err)
+	}
+	size := uint64(info.Size())
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()), WithDiskHeadroom(1000))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// The pull fails before downloading if the layers and the headroom
+	// don't fit.
+	client.freeSpace = func(string) (uint64, error) { return size + 999, nil }
+	err = client.PullModel(t.Context(), tag, nil)
+	var spaceErr *DiskSpaceError
+	if !errors.As(err, &spaceErr) || !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("Expected a DiskSpaceError, got %v", err)
+	}
+	if spaceErr.Required != size+1000 || spaceErr.Available != size+999 || spaceErr.Path != client.GetStorePath() {
+		t.Errorf("Unexpected error %+v", spaceErr)
+	}
+	if _, err := client.GetModel(tag); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected nothing to be pulled, got %v", err)
+	}
+
+	client.freeSpace = func(string) (uint64, error) { return size + 1000, nil }
+	if err := client.PullModel(t.Context(), tag, nil); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+
+	// Layers already in the store take no space.
+	other := registryURL.Host + "/testmodel:v2"
+	if err := writeToRegistry(testGGUFFile, other); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+	client.freeSpace = func(string) (uint64, error) { return 1000, nil }
+	if err := client.PullModel(t.Context(), other, nil); err != nil {
+		t.Errorf("Expected a model sharing its layers to be pulled: %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/docker/go-units"
+
 	"github.com/docker/model-runner/pkg/distribution/internal/store"
 	"github.com/docker/model-runner/pkg/distribution/registry"
 	"github.com/docker/model-runner/pkg/distribution/types"
@@ -12,12 +14,26 @@ import (
 var (
 	ErrInvalidReference     = registry.ErrInvalidReference
 	ErrModelNotFound        = store.ErrModelNotFound // model not found in store
+	ErrAliasNotFound        = store.ErrAliasNotFound // alias not found in store
+	ErrInvalidAlias         = errors.New("invalid alias")
+	ErrInvalidCursor        = store.ErrInvalidCursor  // malformed change cursor
+	ErrDigestMismatch       = store.ErrDigestMismatch // blob content doesn't match its digest
+	ErrStoreLocked          = store.ErrStoreLocked    // store locked by another process
+	ErrReadOnlyStore        = store.ErrReadOnly       // store is read-only
+	ErrUnknownStore         = errors.New("unknown store")
+	ErrNoBlobCache          = store.ErrNoBlobCache // no blob cache to dedupe against
+	ErrBundleInUse          = store.ErrBundleInUse // bundle held by a running backend
+	ErrInvalidFilter        = errors.New("invalid filter")
+	ErrDigestRequired       = errors.New("digest reference required")
+	ErrInvalidPrunePolicy   = errors.New("invalid prune policy")
 	ErrUnsupportedMediaType = errors.New(fmt.Sprintf(
 		"client supports only models of type %q and older - try upgrading",
 		types.MediaTypeModelConfigV01,
 	))
-	ErrUnsupportedFormat = errors.New("safetensors models are not currently supported - this runner only supports GGUF format models")
-	ErrConflict          = errors.New("resource conflict")
+	ErrUnsupportedFormat  = errors.New("safetensors models are not currently supported - this runner only supports GGUF format models")
+	ErrConflict           = errors.New("resource conflict")
+	ErrLicenseNotAccepted = errors.New("license not accepted")
+	ErrInsufficientSpace  = errors.New("insufficient disk space")
 )
 
 // ReferenceError represents an error related to an invalid model reference
@@ -38,3 +54,43 @@ func (e *ReferenceError) Unwrap() error {
 func (e *ReferenceError) Is(target error) bool {
 	return target == ErrInvalidReference
 }
+
+// LicenseError indicates that a model requires accepting its licenses before
+// it can be pulled.
+type LicenseError struct {
+	Reference string
+	// Licenses are the texts of the licenses to accept.
+	Licenses []string
+}
+
+func (e *LicenseError) Error() string {
+	return fmt.Sprintf("model %q requires accepting its license", e.Reference)
+}
+
+// Is implements error matching for LicenseError
+func (e *LicenseError) Is(target error) bool {
+	return target == ErrLicenseNotAccepted
+}
+
+// DiskSpaceError indicates that the volume of a store doesn't have enough
+// free space for a model to be pulled into it.
+type DiskSpaceError struct {
+	Reference string `json:"reference"`
+	// Path is the root path of the store.
+	Path string `json:"path"`
+	// Required is the number of bytes the pull requires, including the
+	// configured headroom.
+	Required uint64 `json:"required"`
+	// Available is the number of bytes free on the volume.
+	Available uint64 `json:"available"`
+}
+
+func (e *DiskSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space to pull model %q: %s required, %s available in %s",
+		e.Reference, units.BytesSize(float64(e.Required)), units.BytesSize(float64(e.Available)), e.Path)
+}
+
+// Is implements error matching for DiskSpaceError
+func (e *DiskSpaceError) Is(target error) bool {
+	return target == ErrInsufficientSpace
+}
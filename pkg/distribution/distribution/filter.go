@@ -0,0 +1,71 @@
+package distribution
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// ModelFilter selects models when listing them.
+type ModelFilter struct {
+	// Label is the annotation key the model must have.
+	Label string
+	// Value is the value the annotation must have, if HasValue is set.
+	Value string
+	// HasValue indicates that the annotation must have Value, rather than
+	// merely exist.
+	HasValue bool
+}
+
+// ParseModelFilter parses a filter of the form label=<key> or
+// label=<key>=<value>, as in "label=team=nlp".
+func ParseModelFilter(s string) (ModelFilter, error) {
+	kind, arg, ok := strings.Cut(s, "=")
+	if !ok || arg == "" {
+		return ModelFilter{}, fmt.Errorf("%w %q: expected label=<key>[=<value>]", ErrInvalidFilter, s)
+	}
+	if kind != "label" {
+		return ModelFilter{}, fmt.Errorf("%w %q: unsupported filter %q", ErrInvalidFilter, s, kind)
+	}
+	key, value, hasValue := strings.Cut(arg, "=")
+	if key == "" {
+		return ModelFilter{}, fmt.Errorf("%w %q: empty label key", ErrInvalidFilter, s)
+	}
+	return ModelFilter{Label: key, Value: value, HasValue: hasValue}, nil
+}
+
+// ParseModelFilters parses filters with ParseModelFilter.
+func ParseModelFilters(filters []string) ([]ModelFilter, error) {
+	parsed := make([]ModelFilter, 0, len(filters))
+	for _, s := range filters {
+		f, err := ParseModelFilter(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+// Match reports whether the model matches the filter.
+func (f ModelFilter) Match(model types.Model) bool {
+	value, ok := model.Annotations()[f.Label]
+	return ok && (!f.HasValue || value == f.Value)
+}
+
+// FilterModels returns the models matching every filter.
+func FilterModels(models []types.Model, filters []ModelFilter) []types.Model {
+	if len(filters) == 0 {
+		return models
+	}
+	var matched []types.Model
+	for _, model := range models {
+		mismatch := func(f ModelFilter) bool { return !f.Match(model) }
+		if !slices.ContainsFunc(filters, mismatch) {
+			matched = append(matched, model)
+		}
+	}
+	return matched
+}
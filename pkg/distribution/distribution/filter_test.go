@@ -0,0 +1,75 @@
+package distribution
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+)
+
+func TestParseModelFilter(t *testing.T) {
+	for _, tc := range []struct {
+		filter   string
+		expected ModelFilter
+	}{
+		{"label=team", ModelFilter{Label: "team"}},
+		{"label=team=nlp", ModelFilter{Label: "team", Value: "nlp", HasValue: true}},
+		{"label=team=", ModelFilter{Label: "team", HasValue: true}},
+		{"label=url=http://a=b", ModelFilter{Label: "url", Value: "http://a=b", HasValue: true}},
+	} {
+		f, err := ParseModelFilter(tc.filter)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", tc.filter, err)
+		} else if f != tc.expected {
+			t.Errorf("Expected %+v for %q, got %+v", tc.expected, tc.filter, f)
+		}
+	}
+
+	for _, filter := range []string{"", "label", "label=", "label==nlp", "owner=me"} {
+		if _, err := ParseModelFilter(filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %q, got %v", filter, err)
+		}
+	}
+}
+
+func TestFilterModels(t *testing.T) {
+	model, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	labeled := mutate.Annotations(model, map[string]string{"team": "nlp"})
+	client, err := NewClient(
+		WithStoreRootPath(newStoreWithModel(t, labeled, "labeled:latest")),
+		WithStores(StoreConfig{Name: "other", RootPath: newStoreWithModel(t, model, "unlabeled:latest")}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+
+	for filter, expected := range map[string]int{
+		"label=team":     1,
+		"label=team=nlp": 1,
+		"label=team=cv":  0,
+		"label=stage":    0,
+	} {
+		f, err := ParseModelFilter(filter)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", filter, err)
+		}
+		matched := FilterModels(models, []ModelFilter{f})
+		if len(matched) != expected {
+			t.Errorf("Expected %d models for %q, got %d", expected, filter, len(matched))
+		}
+		if len(matched) == 1 && matched[0].Tags()[0] != "labeled:latest" {
+			t.Errorf("Expected the labeled model for %q, got %v", filter, matched[0].Tags())
+		}
+	}
+	if matched := FilterModels(models, nil); len(matched) != 2 {
+		t.Errorf("Expected all models without filters, got %d", len(matched))
+	}
+}
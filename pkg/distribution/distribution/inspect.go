@@ -0,0 +1,94 @@
+package distribution
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// ModelInspection describes a stored model in full, for tools that parse
+// model metadata.
+type ModelInspection struct {
+	// ID is the model ID.
+	ID string `json:"id"`
+	// Tags are the tags of the model.
+	Tags []string `json:"tags"`
+	// Config is the model configuration.
+	Config types.Config `json:"config"`
+	// ConfigDigest is the digest of the config file.
+	ConfigDigest string `json:"config_digest"`
+	// Metadata describes the provenance and usage of the model.
+	Metadata types.Metadata `json:"metadata"`
+	// Layers describes the layers of the model, in manifest order.
+	Layers []LayerInspection `json:"layers"`
+	// Annotations are the manifest annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Manifest is the raw manifest.
+	Manifest json.RawMessage `json:"manifest"`
+	// RawConfig is the raw config file.
+	RawConfig json.RawMessage `json:"raw_config"`
+}
+
+// LayerInspection describes a layer of a model.
+type LayerInspection struct {
+	// MediaType is the media type of the layer.
+	MediaType string `json:"media_type"`
+	// Size is the size of the layer in bytes.
+	Size int64 `json:"size"`
+	// Digest is the digest of the layer.
+	Digest string `json:"digest"`
+	// Annotations are the layer annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// InspectModel returns the manifest, config and layers of the model with the
+// given reference.
+func (c *Client) InspectModel(reference string) (ModelInspection, error) {
+	_, mdl, err := c.find(reference)
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get model %q: %w", reference, err)
+	}
+
+	id, err := mdl.ID()
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get model ID: %w", err)
+	}
+	cfg, err := mdl.Config()
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get model config: %w", err)
+	}
+	rawManifest, err := mdl.RawManifest()
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get raw manifest: %w", err)
+	}
+	rawConfig, err := mdl.RawConfigFile()
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get raw config: %w", err)
+	}
+	manifest, err := mdl.Manifest()
+	if err != nil {
+		return ModelInspection{}, fmt.Errorf("get manifest: %w", err)
+	}
+
+	inspection := ModelInspection{
+		ID:           id,
+		Tags:         mdl.Tags(),
+		Config:       cfg,
+		ConfigDigest: manifest.Config.Digest.String(),
+		Metadata:     mdl.Metadata(),
+		Layers:       make([]LayerInspection, 0, len(manifest.Layers)),
+		Annotations:  manifest.Annotations,
+		Manifest:     rawManifest,
+		RawConfig:    rawConfig,
+	}
+	for _, layer := range manifest.Layers {
+		inspection.Layers = append(inspection.Layers, LayerInspection{
+			MediaType:   string(layer.MediaType),
+			Size:        layer.Size,
+			Digest:      layer.Digest.String(),
+			Annotations: layer.Annotations,
+		})
+	}
+	return inspection, nil
+}
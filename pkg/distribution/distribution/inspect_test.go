@@ -0,0 +1,44 @@
+package distribution
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestInspectModel(t *testing.T) {
+	client, err := NewClient(WithStoreRootPath(newSharedStore(t, "inspect/model:v1")))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	inspection, err := client.InspectModel("inspect/model:v1")
+	if err != nil {
+		t.Fatalf("Failed to inspect model: %v", err)
+	}
+	if inspection.Config.Format != types.FormatGGUF {
+		t.Errorf("Expected the GGUF format, got %q", inspection.Config.Format)
+	}
+	if len(inspection.Layers) != 1 || inspection.Layers[0].MediaType != string(types.MediaTypeGGUF) || inspection.Layers[0].Size == 0 {
+		t.Errorf("Expected one GGUF layer, got %+v", inspection.Layers)
+	}
+
+	// The raw manifest is included as is.
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(inspection.Manifest, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if len(manifest.Layers) != 1 || manifest.Layers[0].Digest != inspection.Layers[0].Digest {
+		t.Errorf("Expected the manifest to list the layer, got %+v", manifest.Layers)
+	}
+
+	if _, err := client.InspectModel("inspect/model:v2"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package distribution
+
+import (
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// PullOption represents an option for pulling a model
+type PullOption func(*pullOptions)
+
+// pullOptions holds the configuration for a pull
+type pullOptions struct {
+	acceptLicense bool
+	store         string
+}
+
+// WithAcceptLicense accepts the licenses of the pulled model if its config
+// requires license acceptance. The acceptance is recorded in the store so that
+// subsequent pulls of models with the same licenses don't require it.
+func WithAcceptLicense(accept bool) PullOption {
+	return func(o *pullOptions) {
+		o.acceptLicense = accept
+	}
+}
+
+// checkLicenses ensures that the licenses of a model whose config requires
+// license acceptance have been accepted, recording the acceptance if accept
+// is set. If they haven't been accepted, it returns a *LicenseError
+// containing the license texts.
+func (c *Client) checkLicenses(reference string, mdl types.ModelArtifact, accept bool) error {
+	cfg, err := mdl.Config()
+	if err != nil {
+		return fmt.Errorf("getting model config: %w", err)
+	}
+	if !cfg.LicenseAcceptanceRequired {
+		return nil
+	}
+
+	layers, err := mdl.Layers()
+	if err != nil {
+		return fmt.Errorf("getting model layers: %w", err)
+	}
+	var licenses []v1.Layer
+	var digests []v1.Hash
+	for _, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			return fmt.Errorf("getting layer media type: %w", err)
+		}
+		if mt != types.MediaTypeLicense && mt != types.MediaTypeLicenseZstd {
+			continue
+		}
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("getting license digest: %w", err)
+		}
+		licenses = append(licenses, layer)
+		digests = append(digests, digest)
+	}
+
+	if accept {
+		if err := c.store.AcceptLicenses(digests); err != nil {
+			return fmt.Errorf("recording license acceptance: %w", err)
+		}
+		return nil
+	}
+	accepted, err := c.store.LicensesAccepted(digests)
+	if err != nil {
+		return fmt.Errorf("checking license acceptance: %w", err)
+	}
+	if accepted {
+		return nil
+	}
+
+	texts := make([]string, 0, len(licenses))
+	for _, layer := range licenses {
+		text, err := readLicense(layer)
+		if err != nil {
+			return err
+		}
+		texts = append(texts, text)
+	}
+	return &LicenseError{Reference: reference, Licenses: texts}
+}
+
+// readLicense reads the text of a license layer.
+func readLicense(layer v1.Layer) (string, error) {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return "", fmt.Errorf("reading license: %w", err)
+	}
+	defer rc.Close()
+	text, err := io.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("reading license: %w", err)
+	}
+	return string(text), nil
+}
@@ -0,0 +1,89 @@
+package distribution
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestPullModelWithLicenseAcceptance(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	licensePath := filepath.Join("..", "assets", "license.txt")
+	licenseText, err := os.ReadFile(licensePath)
+	if err != nil {
+		t.Fatalf("Failed to read license: %v", err)
+	}
+	licenseLayer, err := partial.NewLayer(licensePath, types.MediaTypeLicense)
+	if err != nil {
+		t.Fatalf("Failed to create license layer: %v", err)
+	}
+	mdl, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	licensed := mutate.LicenseAcceptanceRequired(mutate.AppendLayers(mdl, licenseLayer))
+
+	// Push two models with the same license
+	var tags []string
+	for _, repo := range []string{"licensed", "licensed-other"} {
+		tag := registryURL.Host + "/" + repo + ":v1"
+		ref, err := name.ParseReference(tag)
+		if err != nil {
+			t.Fatalf("Failed to parse reference: %v", err)
+		}
+		if err := remote.Write(ref, licensed); err != nil {
+			t.Fatalf("Failed to push model: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Pulling without accepting the license fails with the license text
+	err = client.PullModel(context.Background(), tags[0], nil)
+	var licenseErr *LicenseError
+	if !errors.As(err, &licenseErr) {
+		t.Fatalf("Expected license error, got %v", err)
+	}
+	if !errors.Is(err, ErrLicenseNotAccepted) {
+		t.Errorf("Expected error to match ErrLicenseNotAccepted")
+	}
+	if len(licenseErr.Licenses) != 1 || licenseErr.Licenses[0] != string(licenseText) {
+		t.Errorf("Expected license text %q, got %q", licenseText, licenseErr.Licenses)
+	}
+	if _, err := client.GetModel(tags[0]); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected model not to be pulled, got %v", err)
+	}
+
+	// Accepting the license pulls the model
+	if err := client.PullModel(context.Background(), tags[0], nil, WithAcceptLicense(true)); err != nil {
+		t.Fatalf("Failed to pull model with accepted license: %v", err)
+	}
+
+	// The acceptance is remembered for models with the same license
+	if err := client.PullModel(context.Background(), tags[1], nil); err != nil {
+		t.Fatalf("Expected accepted license to be remembered: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package distribution
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+// PrunePolicy selects the tags removed by Prune. The tags of each repository
+// are ordered from newest to oldest by when their model was pulled.
+type PrunePolicy struct {
+	// KeepLast is the number of newest tags to keep in each repository.
+	KeepLast int
+	// KeepTags are tags that are always kept, such as "latest" or "stable".
+	KeepTags []string
+	// Repositories restricts pruning to the given repositories. All
+	// repositories are pruned if it's empty.
+	Repositories []string
+	// DryRun reports what would be removed without changing the store.
+	DryRun bool
+}
+
+// PruneReport describes the changes made by Prune.
+type PruneReport struct {
+	// Untagged are the removed tags.
+	Untagged []string `json:"untagged"`
+	// Deleted are the IDs of the models deleted because their last tag was
+	// removed.
+	Deleted []string `json:"deleted"`
+	// Warnings describe models left broken by deleting models they depend on.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// prunableTag is a tag considered for pruning.
+type prunableTag struct {
+	tag     name.Tag
+	modelID string
+	pulled  time.Time
+}
+
+// Prune removes older tags of each repository from the writable stores,
+// following policy, so that stores to which tags are continuously pulled,
+// such as nightly builds, don't grow without bound. Models left without tags
+// are deleted, while models that never had tags, such as those pulled by
+// digest, are kept.
+func (c *Client) Prune(policy PrunePolicy) (PruneReport, error) {
+	report := PruneReport{Untagged: []string{}, Deleted: []string{}}
+	if policy.KeepLast < 0 {
+		return report, fmt.Errorf("%w: keep-last must not be negative", ErrInvalidPrunePolicy)
+	}
+	if c.readOnly {
+		return report, ErrReadOnlyStore
+	}
+	repositories := make([]string, 0, len(policy.Repositories))
+	for _, r := range policy.Repositories {
+		repo, err := name.NewRepository(r)
+		if err != nil {
+			return report, fmt.Errorf("%w: repository %q: %v", ErrInvalidPrunePolicy, r, err)
+		}
+		repositories = append(repositories, repo.Name())
+	}
+
+	for _, s := range c.stores {
+		if s.ReadOnly() {
+			continue
+		}
+		if err := c.pruneStore(s, policy, repositories, &report); err != nil {
+			return report, fmt.Errorf("pruning store %q: %w", s.name, err)
+		}
+	}
+	return report, nil
+}
+
+// pruneStore prunes a single store, updating report.
+func (c *Client) pruneStore(s namedStore, policy PrunePolicy, repositories []string, report *PruneReport) error {
+	entries, err := s.List()
+	if err != nil {
+		return fmt.Errorf("listing models: %w", err)
+	}
+
+	// Group the tags by repository.
+	byRepository := make(map[string][]prunableTag)
+	remaining := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		remaining[entry.ID] = len(entry.Tags)
+		for _, t := range entry.Tags {
+			tag, err := name.NewTag(t)
+			if err != nil {
+				continue
+			}
+			repo := tag.Context().Name()
+			if len(repositories) > 0 && !slices.Contains(repositories, repo) {
+				continue
+			}
+			byRepository[repo] = append(byRepository[repo], prunableTag{
+				tag:     tag,
+				modelID: entry.ID,
+				pulled:  pulledAt(entry),
+			})
+		}
+	}
+
+	// Select the tags to remove.
+	var removed []string
+	var candidates []string
+	for _, tags := range byRepository {
+		slices.SortFunc(tags, func(a, b prunableTag) int {
+			if n := b.pulled.Compare(a.pulled); n != 0 {
+				return n
+			}
+			return cmp.Compare(b.tag.TagStr(), a.tag.TagStr())
+		})
+		kept := 0
+		for _, t := range tags {
+			if slices.Contains(policy.KeepTags, t.tag.TagStr()) {
+				continue
+			}
+			if kept < policy.KeepLast {
+				kept++
+				continue
+			}
+			removed = append(removed, t.tag.String())
+			remaining[t.modelID]--
+			if remaining[t.modelID] == 0 {
+				candidates = append(candidates, t.modelID)
+			}
+		}
+	}
+	slices.Sort(removed)
+	slices.Sort(candidates)
+
+	if policy.DryRun {
+		report.Untagged = append(report.Untagged, removed...)
+		report.Deleted = append(report.Deleted, candidates...)
+		return nil
+	}
+	if len(removed) > 0 {
+		c.log.Infof("Pruning %d tags from store %q", len(removed), s.name)
+		untagged, err := s.RemoveTags(removed)
+		report.Untagged = append(report.Untagged, untagged...)
+		if err != nil {
+			return fmt.Errorf("untagging models: %w", err)
+		}
+	}
+	for _, id := range candidates {
+		dependents, err := c.dependents(id)
+		if err != nil {
+			c.log.Warnf("Failed to check for dependent models: %v", err)
+		}
+		c.log.Infoln("Deleting pruned model:", id)
+		if _, _, err := s.Delete(id); err != nil {
+			return fmt.Errorf("deleting model %s: %w", id, err)
+		}
+		report.Deleted = append(report.Deleted, id)
+		for _, dependent := range dependents {
+			warning := fmt.Sprintf("model %s depends on deleted model %s and must be pulled again to use it", dependent, id)
+			c.log.Warnln(warning)
+			report.Warnings = append(report.Warnings, warning)
+		}
+	}
+	return nil
+}
+
+// pulledAt returns when the model was written to the store, or created if
+// that's unknown.
+func pulledAt(entry store.IndexEntry) time.Time {
+	if entry.Metadata.Pulled != nil {
+		return *entry.Metadata.Pulled
+	}
+	if entry.Metadata.Created != nil {
+		return *entry.Metadata.Created
+	}
+	return time.Time{}
+}
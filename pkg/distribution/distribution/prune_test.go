@@ -0,0 +1,134 @@
+package distribution
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// newPruneClient creates a client whose store holds a model for each of
+// tags, pulled an hour apart in the given order.
+func newPruneClient(t *testing.T, tags ...string) *Client {
+	t.Helper()
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	base, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	pulled := time.Now().Add(-time.Duration(len(tags)) * time.Hour)
+	for i, tag := range tags {
+		mdl := mutate.Annotations(base, map[string]string{"build": fmt.Sprint(i)})
+		if err := client.store.Write(mdl, []string{tag}, nil); err != nil {
+			t.Fatalf("Failed to write model: %v", err)
+		}
+		at := pulled.Add(time.Duration(i) * time.Hour)
+		if err := client.store.UpdateMetadata(tag, func(m *types.Metadata) { m.Pulled = &at }); err != nil {
+			t.Fatalf("Failed to update metadata: %v", err)
+		}
+	}
+	return client
+}
+
+// storedTags returns the sorted tags of all models in the client's store.
+func storedTags(t *testing.T, client *Client) []string {
+	t.Helper()
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+	var tags []string
+	for _, mdl := range models {
+		tags = append(tags, mdl.Tags()...)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+func TestPrune(t *testing.T) {
+	client := newPruneClient(t,
+		"ai/nightly:20250101",
+		"ai/nightly:latest",
+		"ai/nightly:20250102",
+		"ai/nightly:20250103",
+		"ai/nightly:20250104",
+		"ai/other:v1",
+	)
+
+	// A dry run reports the changes without making them.
+	policy := PrunePolicy{KeepLast: 2, KeepTags: []string{"latest"}, Repositories: []string{"ai/nightly"}, DryRun: true}
+	report, err := client.Prune(policy)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if expected := []string{"ai/nightly:20250101", "ai/nightly:20250102"}; !slices.Equal(report.Untagged, expected) {
+		t.Errorf("Expected %v to be untagged, got %v", expected, report.Untagged)
+	}
+	if len(report.Deleted) != 2 {
+		t.Errorf("Expected 2 models to be deleted, got %v", report.Deleted)
+	}
+	if tags := storedTags(t, client); len(tags) != 6 {
+		t.Errorf("Expected the dry run to leave the store unchanged, got %v", tags)
+	}
+
+	// The older tags of the selected repository are removed, along with
+	// their models.
+	policy.DryRun = false
+	report, err = client.Prune(policy)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if len(report.Untagged) != 2 || len(report.Deleted) != 2 {
+		t.Errorf("Expected 2 tags and models to be removed, got %+v", report)
+	}
+	expected := []string{"ai/nightly:20250103", "ai/nightly:20250104", "ai/nightly:latest", "ai/other:v1"}
+	if tags := storedTags(t, client); !slices.Equal(tags, expected) {
+		t.Errorf("Expected tags %v, got %v", expected, tags)
+	}
+
+	// All repositories are pruned by default.
+	if _, err := client.Prune(PrunePolicy{KeepLast: 0}); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if tags := storedTags(t, client); len(tags) != 0 {
+		t.Errorf("Expected all tags to be removed, got %v", tags)
+	}
+}
+
+func TestPruneKeepsModelsWithOtherTags(t *testing.T) {
+	client := newPruneClient(t, "ai/nightly:20250101", "ai/nightly:20250102")
+	if err := client.Tag("ai/nightly:20250101", "ai/release:v1"); err != nil {
+		t.Fatalf("Failed to tag model: %v", err)
+	}
+
+	report, err := client.Prune(PrunePolicy{KeepLast: 1, Repositories: []string{"ai/nightly"}})
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if len(report.Untagged) != 1 || len(report.Deleted) != 0 {
+		t.Errorf("Expected the tag to be removed but not the model, got %+v", report)
+	}
+	if _, err := client.GetModel("ai/release:v1"); err != nil {
+		t.Errorf("Expected the model to be kept: %v", err)
+	}
+}
+
+func TestPruneInvalidPolicy(t *testing.T) {
+	client := newPruneClient(t)
+	for _, policy := range []PrunePolicy{
+		{KeepLast: -1},
+		{Repositories: []string{"INVALID"}},
+	} {
+		if _, err := client.Prune(policy); !errors.Is(err, ErrInvalidPrunePolicy) {
+			t.Errorf("Expected ErrInvalidPrunePolicy for %+v, got %v", policy, err)
+		}
+	}
+}
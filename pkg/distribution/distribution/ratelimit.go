@@ -0,0 +1,54 @@
+package distribution
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/progress"
+	"github.com/docker/model-runner/pkg/distribution/transport/ratelimit"
+)
+
+// RateLimits returns the pull quotas last reported by registries, such as
+// Docker Hub's pull rate limit, ordered by registry.
+func (c *Client) RateLimits() []ratelimit.Quota {
+	return c.rateLimits.Quotas()
+}
+
+// observeRateLimits returns a copy of ctx with which registry requests report
+// the quotas they observe, and their waits for rate limits, to w.
+func (c *Client) observeRateLimits(ctx context.Context, w io.Writer) context.Context {
+	return ratelimit.WithObserver(ctx, func(e ratelimit.Event) {
+		q := e.Quota
+		rateLimit := progress.RateLimit{Registry: q.Registry, Limit: q.Limit, Remaining: q.Remaining}
+		var msg string
+		switch {
+		case q.Limited:
+			rateLimit.RetryIn = int(math.Ceil(e.Wait.Seconds()))
+			msg = fmt.Sprintf("Rate limited by %s, retrying in %ds", q.Registry, rateLimit.RetryIn)
+			c.log.Warnln(msg)
+		case q.Limit > 0:
+			msg = fmt.Sprintf("%d of %d pulls remaining on %s", q.Remaining, q.Limit, q.Registry)
+		default:
+			msg = fmt.Sprintf("%d pulls remaining on %s", q.Remaining, q.Registry)
+		}
+		if err := progress.WriteRateLimit(w, msg, rateLimit); err != nil {
+			c.log.Warnf("Writing progress: %v", err)
+		}
+	})
+}
+
+// syncWriter serializes writes to a writer shared by concurrent goroutines,
+// such as progress reporters and registry requests.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,62 @@
+package distribution
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestPullModelRateLimited(t *testing.T) {
+	// The registry rejects the first manifest request, as Docker Hub does
+	// once the pull quota is used up.
+	handler := registry.New()
+	var manifests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			w.Header().Set("RateLimit-Limit", "100;w=21600")
+			if manifests.Add(1) == 1 {
+				w.Header().Set("RateLimit-Remaining", "0;w=21600")
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("RateLimit-Remaining", "99;w=21600")
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	tag := registryURL.Host + "/testmodel:v1"
+	if err := writeToRegistry(testGGUFFile, tag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+
+	client, err := NewClient(WithStoreRootPath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	var out bytes.Buffer
+	if err := client.PullModel(t.Context(), tag, &out); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+	if !strings.Contains(out.String(), "Rate limited by "+registryURL.Host) {
+		t.Errorf("Expected the wait to be reported, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), `"remaining":99`) {
+		t.Errorf("Expected the remaining quota to be reported, got %s", out.String())
+	}
+
+	quotas := client.RateLimits()
+	if len(quotas) != 1 || quotas[0].Registry != registryURL.Host || quotas[0].Limit != 100 || quotas[0].Remaining != 99 {
+		t.Errorf("Unexpected quotas %+v", quotas)
+	}
+}
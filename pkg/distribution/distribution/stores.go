@@ -0,0 +1,219 @@
+package distribution
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+// DefaultStoreName is the name of the store set with WithStoreRootPath.
+const DefaultStoreName = "default"
+
+// StoreConfig configures a model store in addition to the default store.
+type StoreConfig struct {
+	// Name identifies the store, for example when selecting it for a pull.
+	Name string `json:"name" yaml:"name"`
+	// RootPath is the root path of the store.
+	RootPath string `json:"path" yaml:"path"`
+	// ReadOnly opens an existing store that's never modified, such as one
+	// shared on a read-only network file system.
+	ReadOnly bool `json:"read-only,omitempty" yaml:"read-only,omitempty"`
+}
+
+// namedStore is a store of a Client.
+type namedStore struct {
+	name string
+	*store.LocalStore
+}
+
+// WithStores adds model stores in which models are looked up, in order, after
+// the default store. Models are written to the default store, unless another
+// is designated with WithWritableStore or selected for a pull with
+// WithPullStore. A tag in an earlier store shadows the same tag in later ones.
+func WithStores(stores ...StoreConfig) Option {
+	return func(o *options) {
+		o.stores = append(o.stores, stores...)
+	}
+}
+
+// WithWritableStore designates the store, by name, to which models are
+// written by default.
+func WithWritableStore(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.writableStore = name
+		}
+	}
+}
+
+// WithReadOnlyStore opens every store read-only, for immutable deployments
+// such as models baked into a container image. Models are still served, but
+// pulling, loading, tagging, configuring and deleting them fails with
+// ErrReadOnlyStore. The stores must already exist.
+func WithReadOnlyStore() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// WithBlobCache sets a blob cache directory shared with the stores of other
+// clients, such as those of Docker Desktop and a standalone model runner.
+// Blobs are hardlinked between the stores and the cache rather than copied,
+// so a model in several stores takes up the space of one. The cache must be on
+// the same file system as the stores, or it's ignored.
+func WithBlobCache(path string) Option {
+	return func(o *options) {
+		o.blobCachePath = path
+	}
+}
+
+// WithHardlinkBundles requires the model files of runtime bundles to be
+// hardlinks to the store's blobs, so that backends such as llama.cpp mmap the
+// blobs directly and loading a model takes no extra disk space. Creating a
+// bundle fails, rather than falling back to symlinks, if it can't be
+// hardlinked, such as for models in a store on another file system.
+func WithHardlinkBundles(require bool) Option {
+	return func(o *options) {
+		o.hardlinkBundles = require
+	}
+}
+
+// WithPullStore selects the store, by name, to which a model is pulled,
+// instead of the designated writable store.
+func WithPullStore(name string) PullOption {
+	return func(o *pullOptions) {
+		o.store = name
+	}
+}
+
+// openStores opens the default store and any additional stores, returning
+// them in lookup order along with the designated writable store, which is
+// read-only if the client is.
+func openStores(o *options) ([]namedStore, *store.LocalStore, error) {
+	configs := append([]StoreConfig{{Name: DefaultStoreName, RootPath: o.storeRootPath}}, o.stores...)
+	stores := make([]namedStore, 0, len(configs))
+	for _, config := range configs {
+		if config.Name == "" || strings.ContainsAny(config.Name, ",") {
+			return nil, nil, fmt.Errorf("invalid store name %q", config.Name)
+		}
+		if config.RootPath == "" {
+			return nil, nil, fmt.Errorf("store %q: root path is required", config.Name)
+		}
+		for _, s := range stores {
+			if s.name == config.Name {
+				return nil, nil, fmt.Errorf("duplicate store name %q", config.Name)
+			}
+		}
+		s, err := store.New(store.Options{
+			RootPath: config.RootPath,
+			ReadOnly: config.ReadOnly || o.readOnly,
+
+			BlobCachePath:   o.blobCachePath,
+			HardlinkBundles: o.hardlinkBundles,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("store %q: %w", config.Name, err)
+		}
+		stores = append(stores, namedStore{name: config.Name, LocalStore: s})
+	}
+
+	if o.readOnly {
+		return stores, stores[0].LocalStore, nil
+	}
+	writable := DefaultStoreName
+	if o.writableStore != "" {
+		writable = o.writableStore
+	}
+	s, err := findStore(stores, writable)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stores, s, nil
+}
+
+// findStore returns the store with the given name, which must be writable.
+func findStore(stores []namedStore, name string) (*store.LocalStore, error) {
+	for _, s := range stores {
+		if s.name == name {
+			if s.ReadOnly() {
+				return nil, fmt.Errorf("store %q: %w", name, ErrReadOnlyStore)
+			}
+			return s.LocalStore, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownStore, name)
+}
+
+// find returns the first store containing the model with the given reference,
+// along with the model. A reference that matches no model may be an alias,
+// which is resolved to the model it stands for.
+func (c *Client) find(reference string) (*store.LocalStore, *store.Model, error) {
+	s, mdl, err := c.findModel(reference)
+	if !errors.Is(err, ErrModelNotFound) {
+		return s, mdl, err
+	}
+	if target, ok := c.alias(reference); ok {
+		return c.findModel(target)
+	}
+	return nil, nil, err
+}
+
+// findModel implements find for references other than aliases.
+func (c *Client) findModel(reference string) (*store.LocalStore, *store.Model, error) {
+	for _, s := range c.stores {
+		mdl, err := s.Read(reference)
+		if err == nil {
+			return s.LocalStore, mdl, nil
+		}
+		if !errors.Is(err, ErrModelNotFound) {
+			return nil, nil, fmt.Errorf("store %q: %w", s.name, err)
+		}
+	}
+	return nil, nil, ErrModelNotFound
+}
+
+// joinCursors combines the change cursors of the stores into one cursor.
+func joinCursors(cursors []string) string {
+	return strings.Join(cursors, ",")
+}
+
+// splitCursors splits a cursor returned by joinCursors into the cursors of n
+// stores. It reports false if the cursor doesn't cover n stores, for example
+// because stores were added since.
+func splitCursors(cursor string, n int) ([]string, bool) {
+	if cursor == "" {
+		return make([]string, n), true
+	}
+	cursors := strings.Split(cursor, ",")
+	return cursors, len(cursors) == n
+}
+
+// DedupeReport describes the changes made by Dedupe.
+type DedupeReport = store.DedupeReport
+
+// Dedupe retro-fits the writable stores to the blob cache set with
+// WithBlobCache, replacing blobs that are already cached with hardlinks to
+// them and adding the others to the cache. It returns the combined report.
+func (c *Client) Dedupe() (DedupeReport, error) {
+	var report DedupeReport
+	if c.readOnly {
+		return report, ErrReadOnlyStore
+	}
+	for _, s := range c.stores {
+		if s.ReadOnly() {
+			continue
+		}
+		c.log.Infof("Deduplicating store %q", s.name)
+		r, err := s.Dedupe()
+		report.Blobs += r.Blobs
+		report.Cached += r.Cached
+		report.Linked += r.Linked
+		report.SavedBytes += r.SavedBytes
+		if err != nil {
+			return report, fmt.Errorf("store %q: %w", s.name, err)
+		}
+	}
+	return report, nil
+}
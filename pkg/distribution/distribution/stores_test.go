@@ -0,0 +1,244 @@
+package distribution
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// newSharedStore creates a store containing the test model tagged tag.
+func newSharedStore(t *testing.T, tag string) string {
+	t.Helper()
+	model, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	return newStoreWithModel(t, model, tag)
+}
+
+// newStoreWithModel creates a store containing model tagged tag.
+func newStoreWithModel(t *testing.T, model types.ModelArtifact, tag string) string {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := store.New(store.Options{RootPath: dir})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err := s.Write(model, []string{tag}, nil); err != nil {
+		t.Fatalf("Failed to write model to store: %v", err)
+	}
+	return dir
+}
+
+func TestReadOnlySharedStore(t *testing.T) {
+	sharedDir := newSharedStore(t, "shared/model:v1")
+	userDir := t.TempDir()
+	client, err := NewClient(
+		WithStoreRootPath(userDir),
+		WithStores(StoreConfig{Name: "shared", RootPath: sharedDir, ReadOnly: true}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Models are looked up in the shared store.
+	if _, err := client.GetModel("shared/model:v1"); err != nil {
+		t.Fatalf("Failed to get model from the shared store: %v", err)
+	}
+	if found, err := client.IsModelInStore("shared/model:v1"); err != nil || !found {
+		t.Errorf("Expected the model to be found, got %v, %v", found, err)
+	}
+	if err := client.MarkModelUsed("shared/model:v1"); err != nil {
+		t.Errorf("Expected usage of a read-only model to be ignored, got %v", err)
+	}
+
+	// The shared store isn't modified.
+	if err := client.Tag("shared/model:v1", "mine:latest"); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore tagging a shared model, got %v", err)
+	}
+	if _, err := client.DeleteModel("shared/model:v1", false); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore deleting a shared model, got %v", err)
+	}
+
+	// Its bundles are created in the writable store.
+	bundle, err := client.GetBundle("shared/model:v1")
+	if err != nil {
+		t.Fatalf("Failed to get bundle: %v", err)
+	}
+	if !strings.HasPrefix(bundle.RootDir(), userDir) {
+		t.Errorf("Expected the bundle in %s, got %s", userDir, bundle.RootDir())
+	}
+	if _, err := os.Stat(filepath.Join(sharedDir, "bundles")); !os.IsNotExist(err) {
+		t.Errorf("Expected no bundles in the shared store, got %v", err)
+	}
+
+	// Pulls can't select the shared store.
+	if err := client.PullModel(t.Context(), "shared/model:v1", nil, WithPullStore("shared")); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore pulling to the shared store, got %v", err)
+	}
+	if err := client.PullModel(t.Context(), "shared/model:v1", nil, WithPullStore("other")); !errors.Is(err, ErrUnknownStore) {
+		t.Errorf("Expected ErrUnknownStore pulling to an unknown store, got %v", err)
+	}
+
+	// Nor can it be designated as the writable store.
+	if _, err := NewClient(
+		WithStoreRootPath(userDir),
+		WithStores(StoreConfig{Name: "shared", RootPath: sharedDir, ReadOnly: true}),
+		WithWritableStore("shared"),
+	); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore designating a read-only writable store, got %v", err)
+	}
+}
+
+func TestPullToSelectedStore(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	tag := registryURL.Host + "/testmodel:v1"
+	if err := writeToRegistry(testGGUFFile, tag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+
+	sharedDir := newSharedStore(t, "shared/model:v1")
+	teamDir := t.TempDir()
+	client, err := NewClient(
+		WithStoreRootPath(t.TempDir()),
+		WithStores(
+			StoreConfig{Name: "shared", RootPath: sharedDir, ReadOnly: true},
+			StoreConfig{Name: "team", RootPath: teamDir},
+		),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	cursor, err := client.ListModelChanges("")
+	if err != nil {
+		t.Fatalf("Failed to list model changes: %v", err)
+	}
+
+	// The model is pulled to the selected store.
+	if err := client.PullModel(t.Context(), tag, nil, WithPullStore("team")); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+	team, err := store.New(store.Options{RootPath: teamDir})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	if _, err := team.Read(tag); err != nil {
+		t.Errorf("Expected the model in the selected store: %v", err)
+	}
+	if _, err := client.store.Read(tag); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected the model not to be in the default store, got %v", err)
+	}
+
+	// Models are listed from all stores.
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("Expected 2 models, got %d", len(models))
+	}
+
+	// Changes are tracked across stores.
+	changes, err := client.ListModelChanges(cursor.Cursor)
+	if err != nil {
+		t.Fatalf("Failed to list model changes: %v", err)
+	}
+	if changes.Reset || len(changes.Models) != 1 || changes.Cursor == cursor.Cursor {
+		t.Errorf("Expected the pulled model as the only change, got %+v", changes)
+	}
+	if changes, err := client.ListModelChanges("invalid"); err != nil || !changes.Reset {
+		t.Errorf("Expected a cursor for other stores to reset, got %+v, %v", changes, err)
+	}
+}
+
+func TestLookupFallsThroughStores(t *testing.T) {
+	model, err := gguf.NewModel(testGGUFFile)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	client, err := NewClient(
+		WithStoreRootPath(newStoreWithModel(t, model, "model:user")),
+		WithStores(StoreConfig{Name: "shared", RootPath: newStoreWithModel(t, model, "model:shared"), ReadOnly: true}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// A model in several stores is listed once, from the first store.
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+	if len(models) != 1 || !slices.Equal(models[0].Tags(), []string{"model:user"}) {
+		t.Errorf("Expected the model from the default store, got %d models", len(models))
+	}
+
+	// Tags missing from a store fall through to the next.
+	if _, err := client.GetModel("model:shared"); err != nil {
+		t.Errorf("Failed to get model by a tag in the shared store: %v", err)
+	}
+	if _, err := client.GetModel("model:other"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	dir := newSharedStore(t, "baked/model:v1")
+	client, err := NewClient(WithStoreRootPath(dir), WithReadOnlyStore())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Models are still served.
+	if _, err := client.GetModel("baked/model:v1"); err != nil {
+		t.Fatalf("Failed to get model: %v", err)
+	}
+	if _, err := client.GetBundle("baked/model:v1"); err != nil {
+		t.Errorf("Failed to get bundle: %v", err)
+	}
+	if err := client.MarkModelUsed("baked/model:v1"); err != nil {
+		t.Errorf("Expected usage to be ignored, got %v", err)
+	}
+
+	// But they can't be modified.
+	contextSize := uint64(4096)
+	for name, err := range map[string]error{
+		"pull":   client.PullModel(t.Context(), "baked/model:v2", nil),
+		"tag":    client.Tag("baked/model:v1", "baked/model:v2"),
+		"reset":  client.ResetStore(),
+		"delete": func() error { _, err := client.DeleteModel("baked/model:v1", true); return err }(),
+		"load":   func() error { _, err := client.LoadModel(strings.NewReader(""), nil); return err }(),
+		"configure": func() error {
+			_, err := client.ConfigureModel("baked/model:v1", ModelConfigUpdate{ContextSize: &contextSize}, nil)
+			return err
+		}(),
+	} {
+		if !errors.Is(err, ErrReadOnlyStore) {
+			t.Errorf("Expected ErrReadOnlyStore for %s, got %v", name, err)
+		}
+	}
+	if _, err := client.GetModel("baked/model:v2"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected the store to be unchanged, got %v", err)
+	}
+
+	// The store must exist.
+	if _, err := NewClient(WithStoreRootPath(filepath.Join(t.TempDir(), "missing")), WithReadOnlyStore()); err == nil {
+		t.Error("Expected an error opening a missing read-only store")
+	}
+}
@@ -0,0 +1,242 @@
+// Package huggingface resolves Hugging Face model references to the GGUF file
+// that should be pulled from the hf.co OCI registry.
+package huggingface
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// Registry is the Hugging Face OCI registry host.
+	Registry = "hf.co"
+	// DefaultAPIURL is the base URL of the Hugging Face Hub API.
+	DefaultAPIURL = "https://huggingface.co"
+	// defaultQuantization is the quantization the hf.co registry selects when
+	// no tag is specified and the repository contains multiple GGUF files.
+	defaultQuantization = "Q4_K_M"
+	// defaultTag is the tag applied to references without an explicit tag.
+	defaultTag = "latest"
+)
+
+var (
+	// ErrAmbiguousFile indicates that a reference matches more than one GGUF
+	// file in the repository.
+	ErrAmbiguousFile = errors.New("reference matches multiple GGUF files")
+	// ErrFileNotFound indicates that a reference doesn't match any GGUF file in
+	// the repository.
+	ErrFileNotFound = errors.New("no matching GGUF file in repository")
+)
+
+// quantPattern matches a quantization label within a GGUF file name, e.g.
+// "Q4_K_M" in "model-Q4_K_M.gguf".
+var quantPattern = regexp.MustCompile(`(?i)(?:^|[-_.])((?:I?Q[0-9]+(?:_[A-Z0-9]+)*)|BF16|F16|F32)(?:[-.]|$)`)
+
+// shardPattern matches the shard suffix of split GGUF file names.
+var shardPattern = regexp.MustCompile(`(?i)-[0-9]{5}-of-[0-9]{5}\.gguf$`)
+
+// Client resolves Hugging Face references using the Hub API.
+type Client struct {
+	httpClient *http.Client
+	apiURL     string
+}
+
+// NewClient creates a new Client using the given transport. If apiURL is
+// empty, DefaultAPIURL is used.
+func NewClient(transport http.RoundTripper, apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{
+		httpClient: &http.Client{Transport: transport},
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+	}
+}
+
+// IsReference returns true if the reference points at the Hugging Face registry.
+func IsReference(reference string) bool {
+	return strings.HasPrefix(strings.ToLower(reference), Registry+"/")
+}
+
+// Reference is a parsed Hugging Face model reference.
+type Reference struct {
+	// Repository is the repository in org/name form.
+	Repository string
+	// File is the GGUF file within the repository, if one was specified.
+	File string
+	// Tag is the requested tag, usually a quantization label.
+	Tag string
+}
+
+// ParseReference parses references of the forms hf.co/org/repo[:tag] and
+// hf.co/org/repo/file.gguf[:tag].
+func ParseReference(reference string) (Reference, error) {
+	if !IsReference(reference) {
+		return Reference{}, fmt.Errorf("not a Hugging Face reference: %q", reference)
+	}
+	path := reference[len(Registry)+1:]
+
+	tag := defaultTag
+	if idx := strings.LastIndex(path, ":"); idx > strings.LastIndex(path, "/") {
+		path, tag = path[:idx], path[idx+1:]
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Reference{}, fmt.Errorf("invalid Hugging Face reference %q: expected hf.co/org/repo", reference)
+	}
+	ref := Reference{
+		Repository: parts[0] + "/" + parts[1],
+		Tag:        tag,
+	}
+	if len(parts) > 2 {
+		ref.File = strings.Join(parts[2:], "/")
+		if !strings.HasSuffix(strings.ToLower(ref.File), ".gguf") {
+			return Reference{}, fmt.Errorf("invalid Hugging Face reference %q: file must be a .gguf file", reference)
+		}
+	}
+	return ref, nil
+}
+
+// Resolve maps a Hugging Face reference to the registry reference that selects
+// the intended GGUF file. References naming a file are rewritten to the tag for
+// the file's quantization. An error listing the available files is returned if
+// the selection is ambiguous or doesn't match any file.
+func (c *Client) Resolve(ctx context.Context, reference string) (string, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := c.ListGGUFFiles(ctx, ref.Repository)
+	if err != nil {
+		if ref.File != "" {
+			return "", err
+		}
+		// Without a file selection the registry can still resolve the
+		// reference itself, so listing files is best effort.
+		return reference, nil
+	}
+	groups := groupFiles(files)
+
+	if ref.File != "" {
+		if !containsFold(files, ref.File) {
+			return "", fmt.Errorf("%w: %q not found in %s, available files: %s",
+				ErrFileNotFound, ref.File, ref.Repository, strings.Join(files, ", "))
+		}
+		quant := QuantizationFromFilename(ref.File)
+		if quant == "" {
+			return "", fmt.Errorf("cannot determine quantization of %q", ref.File)
+		}
+		if matches := groups[quant]; len(matches) > 1 {
+			return "", fmt.Errorf("%w: quantization %s of %q is shared by %s",
+				ErrAmbiguousFile, quant, ref.File, strings.Join(matches, ", "))
+		}
+		return Registry + "/" + ref.Repository + ":" + quant, nil
+	}
+
+	if ref.Tag != defaultTag {
+		matches, ok := groups[strings.ToUpper(ref.Tag)]
+		if !ok {
+			return "", fmt.Errorf("%w: no file with quantization %q in %s, available files: %s",
+				ErrFileNotFound, ref.Tag, ref.Repository, strings.Join(files, ", "))
+		}
+		if len(matches) > 1 {
+			return "", fmt.Errorf("%w: quantization %q matches %s, specify a file with hf.co/%s/<file>.gguf",
+				ErrAmbiguousFile, ref.Tag, strings.Join(matches, ", "), ref.Repository)
+		}
+		return reference, nil
+	}
+
+	if len(groups) > 1 {
+		if _, ok := groups[defaultQuantization]; !ok {
+			return "", fmt.Errorf("%w: %s contains %s, specify a quantization tag or file",
+				ErrAmbiguousFile, ref.Repository, strings.Join(files, ", "))
+		}
+	}
+	return reference, nil
+}
+
+// ListGGUFFiles returns the GGUF model files in a repository, excluding
+// multimodal projector files.
+func (c *Client) ListGGUFFiles(ctx context.Context, repository string) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/api/models/%s", c.apiURL, (&url.URL{Path: repository}).EscapedPath())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("listing files in %s: %w", repository, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing files in %s: unexpected status %s", repository, resp.Status)
+	}
+
+	var info struct {
+		Siblings []struct {
+			Filename string `json:"rfilename"`
+		} `json:"siblings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decoding repository info: %w", err)
+	}
+
+	var files []string
+	for _, s := range info.Siblings {
+		lower := strings.ToLower(s.Filename)
+		if !strings.HasSuffix(lower, ".gguf") || strings.Contains(lower, "mmproj") {
+			continue
+		}
+		files = append(files, s.Filename)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// QuantizationFromFilename extracts the quantization label from a GGUF file
+// name, returning an empty string if none is found.
+func QuantizationFromFilename(filename string) string {
+	name := filename[strings.LastIndex(filename, "/")+1:]
+	name = shardPattern.ReplaceAllString(name, ".gguf")
+	matches := quantPattern.FindAllStringSubmatch(name, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	// The quantization label is conventionally the last one in the name.
+	return strings.ToUpper(matches[len(matches)-1][1])
+}
+
+// groupFiles groups GGUF files by quantization label, collapsing the shards of
+// split files into a single entry.
+func groupFiles(files []string) map[string][]string {
+	groups := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		base := shardPattern.ReplaceAllString(f, ".gguf")
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		quant := QuantizationFromFilename(f)
+		groups[quant] = append(groups[quant], base)
+	}
+	return groups
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
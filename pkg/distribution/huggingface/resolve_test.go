@@ -0,0 +1,151 @@
+package huggingface
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      Reference
+		wantErr   bool
+	}{
+		{
+			reference: "hf.co/org/repo",
+			want:      Reference{Repository: "org/repo", Tag: "latest"},
+		},
+		{
+			reference: "hf.co/org/repo:q4_k_m",
+			want:      Reference{Repository: "org/repo", Tag: "q4_k_m"},
+		},
+		{
+			reference: "hf.co/org/repo/model-q8_0.gguf:latest",
+			want:      Reference{Repository: "org/repo", File: "model-q8_0.gguf", Tag: "latest"},
+		},
+		{
+			reference: "hf.co/org/repo/subdir/model-q8_0.gguf",
+			want:      Reference{Repository: "org/repo", File: "subdir/model-q8_0.gguf", Tag: "latest"},
+		},
+		{reference: "hf.co/org", wantErr: true},
+		{reference: "hf.co/org/repo/readme.md", wantErr: true},
+		{reference: "ai/smollm2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, err := ParseReference(tt.reference)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantizationFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"Llama-3.2-1B-Instruct-Q4_K_M.gguf":   "Q4_K_M",
+		"qwen2.5-0.5b-instruct-q8_0.gguf":     "Q8_0",
+		"model.IQ4_XS.gguf":                   "IQ4_XS",
+		"model-f16.gguf":                      "F16",
+		"Q6_K/model-Q6_K-00001-of-00002.gguf": "Q6_K",
+		"Qwen3-8B-BF16.gguf":                  "BF16",
+		"model.gguf":                          "",
+	}
+	for filename, want := range tests {
+		if got := QuantizationFromFilename(filename); got != want {
+			t.Errorf("QuantizationFromFilename(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func newTestServer(t *testing.T, siblings ...string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/models/org/repo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"siblings":[`))
+		for i, s := range siblings {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte(`{"rfilename":"` + s + `"}`))
+		}
+		w.Write([]byte(`]}`))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(http.DefaultTransport, server.URL)
+}
+
+func TestResolve(t *testing.T) {
+	client := newTestServer(t,
+		"README.md",
+		"model-Q4_K_M.gguf",
+		"model-Q8_0.gguf",
+		"model-F16-00001-of-00002.gguf",
+		"model-F16-00002-of-00002.gguf",
+		"other-Q8_0.gguf",
+		"mmproj-model-f16.gguf",
+	)
+
+	tests := []struct {
+		reference string
+		want      string
+		wantErr   error
+	}{
+		{reference: "hf.co/org/repo:latest", want: "hf.co/org/repo:latest"},
+		{reference: "hf.co/org/repo:q4_k_m", want: "hf.co/org/repo:q4_k_m"},
+		{reference: "hf.co/org/repo:f16", want: "hf.co/org/repo:f16"},
+		{reference: "hf.co/org/repo/model-q4_k_m.gguf:latest", want: "hf.co/org/repo:Q4_K_M"},
+		{reference: "hf.co/org/repo:q8_0", wantErr: ErrAmbiguousFile},
+		{reference: "hf.co/org/repo/model-q8_0.gguf", wantErr: ErrAmbiguousFile},
+		{reference: "hf.co/org/repo:q2_k", wantErr: ErrFileNotFound},
+		{reference: "hf.co/org/repo/missing-q4_k_m.gguf", wantErr: ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, err := client.Resolve(t.Context(), tt.reference)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAmbiguousDefault(t *testing.T) {
+	client := newTestServer(t, "model-Q5_K_M.gguf", "model-Q8_0.gguf")
+
+	if _, err := client.Resolve(t.Context(), "hf.co/org/repo:latest"); !errors.Is(err, ErrAmbiguousFile) {
+		t.Fatalf("expected ambiguous error, got %v", err)
+	}
+}
+
+func TestResolveUnavailableAPI(t *testing.T) {
+	client := newTestServer(t)
+
+	// Listing failures are not fatal unless a specific file was requested.
+	got, err := client.Resolve(t.Context(), "hf.co/other/repo:q4_k_m")
+	if err != nil || got != "hf.co/other/repo:q4_k_m" {
+		t.Fatalf("Resolve() = %q, %v", got, err)
+	}
+	if _, err := client.Resolve(t.Context(), "hf.co/other/repo/model-q4_k_m.gguf"); err == nil {
+		t.Fatal("expected error when file listing is unavailable")
+	}
+}
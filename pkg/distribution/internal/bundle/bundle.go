@@ -17,8 +17,16 @@ type Bundle struct {
 	mmprojPath       string
 	ggufFile         string // path to GGUF file (first shard when model is split among files)
 	safetensorsFile  string // path to safetensors file (first shard when model is split among files)
+	whisperFile      string // path to whisper.cpp model file
+	voiceFile        string // path to piper voice file, next to which its config is stored
+	diffusionFile    string // path to stable-diffusion.cpp checkpoint
+	onnxFile         string // path to ONNX model, next to which its tokenizer is stored
+	loraAdapters     []string
 	runtimeConfig    types.Config
 	chatTemplatePath string
+	// hardlinksOnly requires model files to be hardlinked from the store
+	// while unpacking.
+	hardlinksOnly bool
 }
 
 // RootDir return the path to the bundle root directory
@@ -51,6 +59,50 @@ func (b *Bundle) ChatTemplatePath() string {
 	return filepath.Join(b.dir, ModelSubdir, b.chatTemplatePath)
 }
 
+// LoRAAdapterPaths returns the paths to LoRA adapter files, in the order they should be applied.
+func (b *Bundle) LoRAAdapterPaths() []string {
+	paths := make([]string, len(b.loraAdapters))
+	for i, name := range b.loraAdapters {
+		paths[i] = filepath.Join(b.dir, ModelSubdir, name)
+	}
+	return paths
+}
+
+// WhisperPath returns the path to a whisper.cpp model file or "" if none is present.
+func (b *Bundle) WhisperPath() string {
+	if b.whisperFile == "" {
+		return ""
+	}
+	return filepath.Join(b.dir, ModelSubdir, b.whisperFile)
+}
+
+// VoicePath returns the path to a piper voice file or "" if none is present. The
+// voice's config is next to it, with a .json suffix appended, where piper
+// looks for it by default.
+func (b *Bundle) VoicePath() string {
+	if b.voiceFile == "" {
+		return ""
+	}
+	return filepath.Join(b.dir, ModelSubdir, b.voiceFile)
+}
+
+// DiffusionPath returns the path to a stable-diffusion.cpp checkpoint or "" if none is present.
+func (b *Bundle) DiffusionPath() string {
+	if b.diffusionFile == "" {
+		return ""
+	}
+	return filepath.Join(b.dir, ModelSubdir, b.diffusionFile)
+}
+
+// ONNXPath returns the path to an ONNX embedding or classification model or ""
+// if none is present. Its tokenizer and config files are next to it.
+func (b *Bundle) ONNXPath() string {
+	if b.onnxFile == "" {
+		return ""
+	}
+	return filepath.Join(b.dir, ModelSubdir, b.onnxFile)
+}
+
 // SafetensorsPath returns the path to model safetensors file. If the model is sharded this will be the path to the first shard.
 func (b *Bundle) SafetensorsPath() string {
 	if b.safetensorsFile == "" {
@@ -31,8 +31,28 @@ func Parse(rootDir string) (*Bundle, error) {
 		return nil, err
 	}
 
+	whisperPath, err := findWhisperFile(modelDir)
+	if err != nil {
+		return nil, err
+	}
+
+	voicePath, err := findVoiceFile(modelDir)
+	if err != nil {
+		return nil, err
+	}
+
+	diffusionPath, err := findDiffusionFile(modelDir)
+	if err != nil {
+		return nil, err
+	}
+
+	onnxPath, err := findONNXFile(modelDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure at least one model weight format is present
-	if ggufPath == "" && safetensorsPath == "" {
+	if ggufPath == "" && safetensorsPath == "" && whisperPath == "" && voicePath == "" && diffusionPath == "" && onnxPath == "" {
 		return nil, fmt.Errorf("no supported model weights found (neither GGUF nor safetensors)")
 	}
 
@@ -44,6 +64,10 @@ func Parse(rootDir string) (*Bundle, error) {
 	if err != nil {
 		return nil, err
 	}
+	loraAdapters, err := findLoRAAdapterFiles(modelDir)
+	if err != nil {
+		return nil, err
+	}
 
 	// Runtime config stays at bundle root
 	cfg, err := parseRuntimeConfig(rootDir)
@@ -55,8 +79,13 @@ func Parse(rootDir string) (*Bundle, error) {
 		mmprojPath:       mmprojPath,
 		ggufFile:         ggufPath,
 		safetensorsFile:  safetensorsPath,
+		whisperFile:      whisperPath,
+		voiceFile:        voicePath,
+		diffusionFile:    diffusionPath,
+		onnxFile:         onnxPath,
 		runtimeConfig:    cfg,
 		chatTemplatePath: templatePath,
+		loraAdapters:     loraAdapters,
 	}, nil
 }
 
@@ -97,6 +126,83 @@ func findSafetensorsFile(modelDir string) (string, error) {
 	return filepath.Base(safetensors[0]), nil
 }
 
+func findWhisperFile(modelDir string) (string, error) {
+	whisperPaths, err := filepath.Glob(filepath.Join(modelDir, "[^.]*.whisper"))
+	if err != nil {
+		return "", fmt.Errorf("find whisper files: %w", err)
+	}
+	if len(whisperPaths) == 0 {
+		// Whisper files are only present in speech-to-text models
+		return "", nil
+	}
+	if len(whisperPaths) > 1 {
+		return "", fmt.Errorf("found multiple .whisper files, but only 1 is supported")
+	}
+	return filepath.Base(whisperPaths[0]), nil
+}
+
+// findONNXFiles returns the .onnx files of a bundle that are piper voices,
+// which have their config next to them, and those that aren't.
+func findONNXFiles(modelDir string) (voices []string, models []string, err error) {
+	paths, err := filepath.Glob(filepath.Join(modelDir, "[^.]*.onnx"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path + ".json"); err == nil {
+			voices = append(voices, path)
+		} else {
+			models = append(models, path)
+		}
+	}
+	return voices, models, nil
+}
+
+func findVoiceFile(modelDir string) (string, error) {
+	voicePaths, _, err := findONNXFiles(modelDir)
+	if err != nil {
+		return "", fmt.Errorf("find voice files: %w", err)
+	}
+	if len(voicePaths) == 0 {
+		// Voice files are only present in text-to-speech models
+		return "", nil
+	}
+	if len(voicePaths) > 1 {
+		return "", fmt.Errorf("found multiple .onnx files, but only 1 is supported")
+	}
+	return filepath.Base(voicePaths[0]), nil
+}
+
+func findONNXFile(modelDir string) (string, error) {
+	_, onnxPaths, err := findONNXFiles(modelDir)
+	if err != nil {
+		return "", fmt.Errorf("find ONNX files: %w", err)
+	}
+	if len(onnxPaths) == 0 {
+		// ONNX files are only present in ONNX Runtime models
+		return "", nil
+	}
+	if len(onnxPaths) > 1 {
+		return "", fmt.Errorf("found multiple .onnx files, but only 1 is supported")
+	}
+	return filepath.Base(onnxPaths[0]), nil
+}
+
+func findDiffusionFile(modelDir string) (string, error) {
+	diffusionPaths, err := filepath.Glob(filepath.Join(modelDir, "[^.]*.diffusion"))
+	if err != nil {
+		return "", fmt.Errorf("find diffusion files: %w", err)
+	}
+	if len(diffusionPaths) == 0 {
+		// Diffusion files are only present in image generation models
+		return "", nil
+	}
+	if len(diffusionPaths) > 1 {
+		return "", fmt.Errorf("found multiple .diffusion files, but only 1 is supported")
+	}
+	return filepath.Base(diffusionPaths[0]), nil
+}
+
 func findMultiModalProjectorFile(modelDir string) (string, error) {
 	mmprojPaths, err := filepath.Glob(filepath.Join(modelDir, "[^.]*.mmproj"))
 	if err != nil {
@@ -124,3 +230,15 @@ func findChatTemplateFile(modelDir string) (string, error) {
 	}
 	return filepath.Base(templatePaths[0]), nil
 }
+
+func findLoRAAdapterFiles(modelDir string) ([]string, error) {
+	adapterPaths, err := filepath.Glob(filepath.Join(modelDir, "[^.]*.lora"))
+	if err != nil {
+		return nil, fmt.Errorf("find LoRA adapter files: %w", err)
+	}
+	var names []string
+	for _, p := range adapterPaths {
+		names = append(names, filepath.Base(p))
+	}
+	return names, nil
+}
@@ -93,6 +93,44 @@ func TestParse_WithGGUF(t *testing.T) {
 	}
 }
 
+func TestParse_WithLoRAAdapters(t *testing.T) {
+	tempDir := t.TempDir()
+
+	modelDir := filepath.Join(tempDir, ModelSubdir)
+	if err := os.MkdirAll(modelDir, 0755); err != nil {
+		t.Fatalf("Failed to create model directory: %v", err)
+	}
+
+	// Adapters are written out of order to check they are returned sorted
+	for _, name := range []string{"model.gguf", "adapter-00002.lora", "adapter-00001.lora"} {
+		if err := os.WriteFile(filepath.Join(modelDir, name), []byte("dummy content"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"format":"gguf"}`), 0644); err != nil {
+		t.Fatalf("Failed to create config.json: %v", err)
+	}
+
+	bundle, err := Parse(tempDir)
+	if err != nil {
+		t.Fatalf("Expected successful parse, got error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(modelDir, "adapter-00001.lora"),
+		filepath.Join(modelDir, "adapter-00002.lora"),
+	}
+	got := bundle.LoRAAdapterPaths()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d LoRA adapters, got %v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected adapter %d to be %s, got %s", i, expected[i], got[i])
+		}
+	}
+}
+
 func TestParse_WithSafetensors(t *testing.T) {
 	// Create a temporary directory for the test bundle
 	tempDir := t.TempDir()
@@ -189,3 +227,50 @@ func TestParse_WithBothFormats(t *testing.T) {
 		t.Errorf("Expected safetensorsFile to be 'model.safetensors', got: %s", bundle.safetensorsFile)
 	}
 }
+
+func TestParse_ONNXModelAndVoice(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		voiceFile string
+		onnxFile  string
+	}{
+		{
+			name:      "piper voice with its config",
+			files:     []string{"model.onnx", "model.onnx.json"},
+			voiceFile: "model.onnx",
+		},
+		{
+			name:     "ONNX model with its tokenizer",
+			files:    []string{"model.onnx", "tokenizer.json"},
+			onnxFile: "model.onnx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			modelDir := filepath.Join(tempDir, ModelSubdir)
+			if err := os.MkdirAll(modelDir, 0755); err != nil {
+				t.Fatalf("Failed to create model directory: %v", err)
+			}
+			for _, name := range tt.files {
+				if err := os.WriteFile(filepath.Join(modelDir, name), []byte("{}"), 0644); err != nil {
+					t.Fatalf("Failed to create %s: %v", name, err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(tempDir, "config.json"), []byte(`{"format": "onnx"}`), 0644); err != nil {
+				t.Fatalf("Failed to create config.json: %v", err)
+			}
+
+			bundle, err := Parse(tempDir)
+			if err != nil {
+				t.Fatalf("Expected successful parse, got error: %v", err)
+			}
+			if bundle.voiceFile != tt.voiceFile || bundle.onnxFile != tt.onnxFile {
+				t.Errorf("Expected voice file %q and ONNX file %q, got %q and %q",
+					tt.voiceFile, tt.onnxFile, bundle.voiceFile, bundle.onnxFile)
+			}
+		})
+	}
+}
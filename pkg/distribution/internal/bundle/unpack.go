@@ -9,14 +9,32 @@ import (
 	"path/filepath"
 
 	"github.com/docker/model-runner/pkg/distribution/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
 )
 
+// UnpackOption configures Unpack.
+type UnpackOption func(*Bundle)
+
+// WithHardlinksOnly makes Unpack fail rather than fall back to symlinks when
+// model files can't be hardlinked from the store, for example because the
+// bundle is on another file system. The files of the bundle are then the
+// store's blobs themselves, so that loading a model takes no extra disk space
+// and the backend can mmap it directly from blob storage.
+func WithHardlinksOnly() UnpackOption {
+	return func(b *Bundle) {
+		b.hardlinksOnly = true
+	}
+}
+
 // Unpack creates and return a Bundle by unpacking files and config from model into dir.
-func Unpack(dir string, model types.Model) (*Bundle, error) {
+func Unpack(dir string, model types.Model, opts ...UnpackOption) (*Bundle, error) {
 	bundle := &Bundle{
 		dir: dir,
 	}
+	for _, opt := range opts {
+		opt(bundle)
+	}
 
 	// Create model subdirectory upfront - all unpack operations will use it
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
@@ -37,6 +55,22 @@ func Unpack(dir string, model types.Model) (*Bundle, error) {
 		if err := unpackSafetensors(bundle, model); err != nil {
 			return nil, fmt.Errorf("unpack safetensors files: %w", err)
 		}
+	case types.FormatWhisper:
+		if err := unpackWhisper(bundle, model); err != nil {
+			return nil, fmt.Errorf("unpack whisper file: %w", err)
+		}
+	case types.FormatPiper:
+		if err := unpackVoice(bundle, model); err != nil {
+			return nil, fmt.Errorf("unpack voice files: %w", err)
+		}
+	case types.FormatDiffusion:
+		if err := unpackDiffusion(bundle, model); err != nil {
+			return nil, fmt.Errorf("unpack diffusion file: %w", err)
+		}
+	case types.FormatONNX:
+		if err := unpackONNX(bundle, model); err != nil {
+			return nil, fmt.Errorf("unpack ONNX file: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("no supported model weights found (neither GGUF nor safetensors)")
 	}
@@ -48,6 +82,10 @@ func Unpack(dir string, model types.Model) (*Bundle, error) {
 		}
 	}
 
+	if err := unpackLoRAAdapters(bundle, model); err != nil {
+		return nil, fmt.Errorf("add LoRA adapters to runtime bundle: %w", err)
+	}
+
 	if hasLayerWithMediaType(model, types.MediaTypeChatTemplate) {
 		if err := unpackTemplate(bundle, model); err != nil {
 			return nil, fmt.Errorf("add chat template file to runtime bundle: %w", err)
@@ -87,6 +125,30 @@ func detectModelFormat(model types.Model) types.Format {
 		return types.FormatSafetensors
 	}
 
+	// Check for whisper.cpp files
+	whisperPath, err := model.WhisperPath()
+	if err == nil && whisperPath != "" {
+		return types.FormatWhisper
+	}
+
+	// Check for piper voices
+	voicePath, err := model.VoicePath()
+	if err == nil && voicePath != "" {
+		return types.FormatPiper
+	}
+
+	// Check for stable-diffusion.cpp checkpoints
+	diffusionPath, err := model.DiffusionPath()
+	if err == nil && diffusionPath != "" {
+		return types.FormatDiffusion
+	}
+
+	// Check for ONNX Runtime models
+	onnxPath, err := model.ONNXPath()
+	if err == nil && onnxPath != "" {
+		return types.FormatONNX
+	}
+
 	return ""
 }
 
@@ -136,24 +198,95 @@ func unpackGGUFs(bundle *Bundle, mdl types.Model) error {
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
 
 	if len(ggufPaths) == 1 {
-		if err := unpackFile(filepath.Join(modelDir, "model.gguf"), ggufPaths[0]); err != nil {
+		if err := unpackFile(bundle, filepath.Join(modelDir, "model.gguf"), ggufPaths[0]); err != nil {
 			return err
 		}
 		bundle.ggufFile = "model.gguf"
 		return err
 	}
 
+	// Shards are materialized side by side using the llama.cpp split naming
+	// scheme so that the runtime can discover the rest from the first shard.
 	for i := range ggufPaths {
 		name := fmt.Sprintf("model-%05d-of-%05d.gguf", i+1, len(ggufPaths))
-		if err := unpackFile(filepath.Join(modelDir, name), ggufPaths[i]); err != nil {
+		if err := unpackFile(bundle, filepath.Join(modelDir, name), ggufPaths[i]); err != nil {
 			return err
 		}
-		bundle.ggufFile = name
+		if i == 0 {
+			bundle.ggufFile = name
+		}
 	}
 
 	return nil
 }
 
+func unpackWhisper(bundle *Bundle, mdl types.Model) error {
+	path, err := mdl.WhisperPath()
+	if err != nil {
+		return fmt.Errorf("get whisper file for model: %w", err)
+	}
+
+	modelDir := filepath.Join(bundle.dir, ModelSubdir)
+	if err := unpackFile(bundle, filepath.Join(modelDir, "model.whisper"), path); err != nil {
+		return err
+	}
+	bundle.whisperFile = "model.whisper"
+	return nil
+}
+
+func unpackVoice(bundle *Bundle, mdl types.Model) error {
+	path, err := mdl.VoicePath()
+	if err != nil {
+		return fmt.Errorf("get voice file for model: %w", err)
+	}
+	configPath, err := mdl.VoiceConfigPath()
+	if err != nil {
+		return fmt.Errorf("get voice config file for model: %w", err)
+	}
+
+	modelDir := filepath.Join(bundle.dir, ModelSubdir)
+	if err := unpackFile(bundle, filepath.Join(modelDir, "model.onnx"), path); err != nil {
+		return err
+	}
+	if err := unpackFile(bundle, filepath.Join(modelDir, "model.onnx.json"), configPath); err != nil {
+		return err
+	}
+	bundle.voiceFile = "model.onnx"
+	return nil
+}
+
+func unpackDiffusion(bundle *Bundle, mdl types.Model) error {
+	path, err := mdl.DiffusionPath()
+	if err != nil {
+		return fmt.Errorf("get diffusion file for model: %w", err)
+	}
+
+	// stable-diffusion.cpp detects the format of the checkpoint from its
+	// contents, so the name needn't keep the original extension.
+	modelDir := filepath.Join(bundle.dir, ModelSubdir)
+	if err := unpackFile(bundle, filepath.Join(modelDir, "model.diffusion"), path); err != nil {
+		return err
+	}
+	bundle.diffusionFile = "model.diffusion"
+	return nil
+}
+
+func unpackONNX(bundle *Bundle, mdl types.Model) error {
+	path, err := mdl.ONNXPath()
+	if err != nil {
+		return fmt.Errorf("get ONNX file for model: %w", err)
+	}
+
+	// The tokenizer and config files of the model are extracted from its
+	// config archive into the same directory.
+	modelDir := filepath.Join(bundle.dir, ModelSubdir)
+	if err := unpackFile(bundle, filepath.Join(modelDir, "model.onnx"), path); err != nil {
+		return err
+	}
+	bundle.onnxFile = "model.onnx"
+	return nil
+}
+
 func unpackMultiModalProjector(bundle *Bundle, mdl types.Model) error {
 	path, err := mdl.MMPROJPath()
 	if err != nil {
@@ -162,13 +295,31 @@ func unpackMultiModalProjector(bundle *Bundle, mdl types.Model) error {
 
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
 
-	if err = unpackFile(filepath.Join(modelDir, "model.mmproj"), path); err != nil {
+	if err = unpackFile(bundle, filepath.Join(modelDir, "model.mmproj"), path); err != nil {
 		return err
 	}
 	bundle.mmprojPath = "model.mmproj"
 	return nil
 }
 
+func unpackLoRAAdapters(bundle *Bundle, mdl types.Model) error {
+	paths, err := mdl.LoRAAdapterPaths()
+	if err != nil {
+		return fmt.Errorf("get LoRA adapter files for model: %w", err)
+	}
+
+	modelDir := filepath.Join(bundle.dir, ModelSubdir)
+
+	for i, path := range paths {
+		name := fmt.Sprintf("adapter-%05d.lora", i+1)
+		if err := unpackFile(bundle, filepath.Join(modelDir, name), path); err != nil {
+			return err
+		}
+		bundle.loraAdapters = append(bundle.loraAdapters, name)
+	}
+	return nil
+}
+
 func unpackTemplate(bundle *Bundle, mdl types.Model) error {
 	path, err := mdl.ChatTemplatePath()
 	if err != nil {
@@ -177,7 +328,17 @@ func unpackTemplate(bundle *Bundle, mdl types.Model) error {
 
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
 
-	if err = unpackFile(filepath.Join(modelDir, "template.jinja"), path); err != nil {
+	// Compressed templates are decompressed into the bundle rather than linked.
+	layer, err := zstdLayer(mdl, types.MediaTypeChatTemplateZstd)
+	if err != nil {
+		return err
+	}
+	if layer != nil {
+		err = decompressFile(filepath.Join(modelDir, "template.jinja"), layer)
+	} else {
+		err = unpackFile(bundle, filepath.Join(modelDir, "template.jinja"), path)
+	}
+	if err != nil {
 		return err
 	}
 	bundle.chatTemplatePath = "template.jinja"
@@ -197,7 +358,7 @@ func unpackSafetensors(bundle *Bundle, mdl types.Model) error {
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
 
 	if len(safetensorsPaths) == 1 {
-		if err := unpackFile(filepath.Join(modelDir, "model.safetensors"), safetensorsPaths[0]); err != nil {
+		if err := unpackFile(bundle, filepath.Join(modelDir, "model.safetensors"), safetensorsPaths[0]); err != nil {
 			return err
 		}
 		bundle.safetensorsFile = "model.safetensors"
@@ -207,7 +368,7 @@ func unpackSafetensors(bundle *Bundle, mdl types.Model) error {
 	// Handle sharded safetensors files
 	for i := range safetensorsPaths {
 		name := fmt.Sprintf("model-%05d-of-%05d.safetensors", i+1, len(safetensorsPaths))
-		if err := unpackFile(filepath.Join(modelDir, name), safetensorsPaths[i]); err != nil {
+		if err := unpackFile(bundle, filepath.Join(modelDir, name), safetensorsPaths[i]); err != nil {
 			return err
 		}
 		if i == 0 {
@@ -226,6 +387,23 @@ func unpackConfigArchive(bundle *Bundle, mdl types.Model) error {
 
 	modelDir := filepath.Join(bundle.dir, ModelSubdir)
 
+	// Compressed archives are decompressed as they are extracted.
+	layer, err := zstdLayer(mdl, types.MediaTypeVLLMConfigArchiveZstd)
+	if err != nil {
+		return err
+	}
+	if layer != nil {
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return fmt.Errorf("get uncompressed config archive: %w", err)
+		}
+		defer rc.Close()
+		if err := extractTarArchiveFromReader(rc, modelDir); err != nil {
+			return fmt.Errorf("extract config archive: %w", err)
+		}
+		return nil
+	}
+
 	// Extract the tar archive into the model subdirectory
 	// This prevents config.json conflicts with the runtime config at bundle root
 	if err := extractTarArchive(archivePath, modelDir); err != nil {
@@ -235,6 +413,43 @@ func unpackConfigArchive(bundle *Bundle, mdl types.Model) error {
 	return nil
 }
 
+// zstdLayer returns the layer of the model with the given zstd compressed
+// media type, or nil if it has none.
+func zstdLayer(mdl types.Model, mediaType ggcrtypes.MediaType) (v1.Layer, error) {
+	artifact, ok := mdl.(types.ModelArtifact)
+	if !ok {
+		return nil, nil
+	}
+	layers, err := artifact.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get model layers: %w", err)
+	}
+	for _, layer := range layers {
+		if mt, err := layer.MediaType(); err == nil && mt == mediaType {
+			return layer, nil
+		}
+	}
+	return nil, nil
+}
+
+// decompressFile writes the uncompressed content of the layer to bundlePath.
+func decompressFile(bundlePath string, layer v1.Layer) error {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("get uncompressed layer: %w", err)
+	}
+	defer rc.Close()
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", bundlePath, err)
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return fmt.Errorf("decompress %s: %w", bundlePath, err)
+	}
+	return f.Close()
+}
+
 func unpackDirTarArchives(bundle *Bundle, mdl types.Model) error {
 	// Cast to ModelArtifact to access Layers() method
 	artifact, ok := mdl.(types.ModelArtifact)
@@ -406,6 +621,20 @@ func extractFile(tr io.Reader, target string, mode os.FileMode) error {
 	return nil
 }
 
-func unpackFile(bundlePath string, srcPath string) error {
-	return os.Link(srcPath, bundlePath)
+// unpackFile hard links srcPath into the bundle, falling back to a symbolic
+// link if srcPath is on another file system, such as a shared store.
+// unpackFile exposes the file at srcPath, in the store, at bundlePath. It is
+// hardlinked, so that backends mmap the blob itself, falling back to a
+// symlink if the bundle is on another file system, unless the bundle is
+// unpacked WithHardlinksOnly. Files are never copied.
+func unpackFile(bundle *Bundle, bundlePath string, srcPath string) error {
+	if err := os.Link(srcPath, bundlePath); err != nil {
+		if bundle.hardlinksOnly {
+			return fmt.Errorf("hardlink %s into bundle: %w", filepath.Base(bundlePath), err)
+		}
+		if symlinkErr := os.Symlink(srcPath, bundlePath); symlinkErr != nil {
+			return err
+		}
+	}
+	return nil
 }
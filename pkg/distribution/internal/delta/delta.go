@@ -0,0 +1,345 @@
+// Package delta computes and applies binary deltas between versions of a
+// file, such as a fine-tuned GGUF file and the GGUF file of its base model,
+// so that a new version can be downloaded as the differences from a version
+// that's already local.
+//
+// A delta is a zstd stream of a header, giving the sizes of the base and the
+// target, followed by instructions that either copy a range of the base or
+// insert literal data. Ranges of the base are found rsync-style, by matching
+// blocks of the base at any offset of the target with a rolling checksum, so
+// that inserted or removed data, such as changed GGUF metadata, doesn't
+// prevent the tensors that follow from being matched.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// DefaultBlockSize is the size of the blocks of the base that are
+	// matched in the target.
+	DefaultBlockSize = 64 << 10
+
+	// maxLiteral is the largest literal instruction, which bounds the
+	// memory used to compute deltas.
+	maxLiteral = 4 << 20
+
+	opCopy    = 'C'
+	opLiteral = 'L'
+	opEnd     = 'E'
+)
+
+// magic identifies deltas and their version.
+var magic = []byte("MDLDELTA\x01")
+
+// ErrInvalidDelta indicates that a delta is malformed or doesn't apply to
+// the given base.
+var ErrInvalidDelta = errors.New("invalid delta")
+
+// Diff writes to w a delta that reconstructs target from base, matching
+// blocks of blockSize bytes, or DefaultBlockSize if it's zero.
+func Diff(base io.ReaderAt, baseSize int64, target io.ReaderAt, targetSize int64, w io.Writer, blockSize int) error {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	idx, err := indexBlocks(base, baseSize, blockSize)
+	if err != nil {
+		return fmt.Errorf("indexing base: %w", err)
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	e := &encoder{w: bufio.NewWriter(zw)}
+	e.header(baseSize, targetSize)
+
+	win := &window{r: target, size: targetSize}
+	B := int64(blockSize)
+	var pos, literal int64
+	var sum rollingSum
+	rolling := false
+	for pos+B <= targetSize {
+		if !rolling {
+			block, err := win.slice(literal, pos, pos+B)
+			if err != nil {
+				return err
+			}
+			sum = newRollingSum(block)
+			rolling = true
+		}
+		if candidates, ok := idx.weak[sum.value()]; ok {
+			block, err := win.slice(literal, pos, pos+B)
+			if err != nil {
+				return err
+			}
+			strong := sha256.Sum256(block)
+			if n, ok := idx.match(candidates, strong); ok {
+				if err := e.literal(win, literal, pos); err != nil {
+					return err
+				}
+				e.copy(int64(n)*B, B)
+				pos += B
+				literal = pos
+				rolling = false
+				continue
+			}
+		}
+		if pos-literal >= maxLiteral {
+			if err := e.literal(win, literal, pos); err != nil {
+				return err
+			}
+			literal = pos
+		}
+		if pos+B == targetSize {
+			break
+		}
+		data, err := win.slice(literal, pos, pos+B+1)
+		if err != nil {
+			return err
+		}
+		sum.roll(data[0], data[B], blockSize)
+		pos++
+	}
+	if err := e.literal(win, literal, targetSize); err != nil {
+		return err
+	}
+	e.end()
+	if e.err != nil {
+		return e.err
+	}
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Apply writes to w the target reconstructed from base by delta.
+func Apply(base io.ReaderAt, baseSize int64, delta io.Reader, w io.Writer) error {
+	zr, err := zstd.NewReader(delta)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	r := bufio.NewReader(zr)
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, magic) {
+		return fmt.Errorf("%w: bad header", ErrInvalidDelta)
+	}
+	wantBase, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+	}
+	if int64(wantBase) != baseSize {
+		return fmt.Errorf("%w: expected a base of %d bytes, got %d", ErrInvalidDelta, wantBase, baseSize)
+	}
+	targetSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+	}
+
+	var written uint64
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+		}
+		switch op {
+		case opCopy:
+			offset, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+			}
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+			}
+			if offset+length > uint64(baseSize) {
+				return fmt.Errorf("%w: copy beyond the end of the base", ErrInvalidDelta)
+			}
+			if _, err := io.Copy(w, io.NewSectionReader(base, int64(offset), int64(length))); err != nil {
+				return err
+			}
+			written += length
+		case opLiteral:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidDelta, err)
+			}
+			if n, err := io.CopyN(w, r, int64(length)); err != nil {
+				if n < int64(length) && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+					return fmt.Errorf("%w: truncated literal", ErrInvalidDelta)
+				}
+				return err
+			}
+			written += length
+		case opEnd:
+			if written != targetSize {
+				return fmt.Errorf("%w: reconstructed %d bytes, expected %d", ErrInvalidDelta, written, targetSize)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%w: unknown instruction %q", ErrInvalidDelta, op)
+		}
+	}
+}
+
+// blockIndex indexes the blocks of the base by their checksums.
+type blockIndex struct {
+	// weak maps rolling checksums to the numbers of the blocks with them.
+	weak map[uint32][]int
+	// strong holds the SHA-256 of each block, by number.
+	strong [][sha256.Size]byte
+}
+
+// indexBlocks indexes the complete blocks of base.
+func indexBlocks(base io.ReaderAt, size int64, blockSize int) (*blockIndex, error) {
+	idx := &blockIndex{weak: make(map[uint32][]int)}
+	block := make([]byte, blockSize)
+	for off := int64(0); off+int64(blockSize) <= size; off += int64(blockSize) {
+		if _, err := base.ReadAt(block, off); err != nil {
+			return nil, err
+		}
+		n := len(idx.strong)
+		idx.strong = append(idx.strong, sha256.Sum256(block))
+		sum := newRollingSum(block).value()
+		idx.weak[sum] = append(idx.weak[sum], n)
+	}
+	return idx, nil
+}
+
+// match returns the number of a candidate block with the given SHA-256.
+func (idx *blockIndex) match(candidates []int, strong [sha256.Size]byte) (int, bool) {
+	for _, n := range candidates {
+		if idx.strong[n] == strong {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// rollingSum is rsync's rolling checksum of a block.
+type rollingSum struct {
+	a, b uint32
+}
+
+func newRollingSum(block []byte) rollingSum {
+	var s rollingSum
+	n := uint32(len(block))
+	for i, c := range block {
+		s.a += uint32(c)
+		s.b += (n - uint32(i)) * uint32(c)
+	}
+	return s
+}
+
+// roll moves the block one byte forward, removing out and adding in.
+func (s *rollingSum) roll(out, in byte, blockSize int) {
+	s.a += uint32(in) - uint32(out)
+	s.b += s.a - uint32(blockSize)*uint32(out)
+}
+
+func (s rollingSum) value() uint32 {
+	return s.a&0xffff | s.b<<16
+}
+
+// window buffers the part of the target being matched, from the start of
+// the pending literal to the end of the current block.
+type window struct {
+	r    io.ReaderAt
+	size int64
+	off  int64
+	buf  []byte
+}
+
+// slice returns the target from start to end, keeping the data from keep
+// onwards buffered. keep mustn't be before a previous keep.
+func (w *window) slice(keep, start, end int64) ([]byte, error) {
+	if end <= w.off+int64(len(w.buf)) {
+		return w.buf[start-w.off : end-w.off], nil
+	}
+	// Drop the data before keep, and read ahead up to the maximum literal
+	// size, so that reads are batched.
+	w.buf = append(w.buf[:0], w.buf[keep-w.off:]...)
+	w.off = keep
+	readEnd := min(max(end, keep+maxLiteral+DefaultBlockSize), w.size)
+	from := w.off + int64(len(w.buf))
+	w.buf = append(w.buf, make([]byte, readEnd-from)...)
+	if _, err := w.r.ReadAt(w.buf[from-w.off:], from); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading target: %w", err)
+	}
+	return w.buf[start-w.off : end-w.off], nil
+}
+
+// encoder writes instructions, merging copies of adjacent ranges.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+	// pending is a copy that may be extended by the next one.
+	pendingOffset, pendingLength int64
+}
+
+func (e *encoder) header(baseSize, targetSize int64) {
+	e.write(magic)
+	e.uvarint(uint64(baseSize))
+	e.uvarint(uint64(targetSize))
+}
+
+func (e *encoder) copy(offset, length int64) {
+	if e.pendingLength > 0 && e.pendingOffset+e.pendingLength == offset {
+		e.pendingLength += length
+		return
+	}
+	e.flushCopy()
+	e.pendingOffset, e.pendingLength = offset, length
+}
+
+func (e *encoder) flushCopy() {
+	if e.pendingLength == 0 {
+		return
+	}
+	e.write([]byte{opCopy})
+	e.uvarint(uint64(e.pendingOffset))
+	e.uvarint(uint64(e.pendingLength))
+	e.pendingLength = 0
+}
+
+// literal writes the target from start to end as literal data.
+func (e *encoder) literal(win *window, start, end int64) error {
+	if start == end {
+		return nil
+	}
+	data, err := win.slice(start, start, end)
+	if err != nil {
+		return err
+	}
+	e.flushCopy()
+	e.write([]byte{opLiteral})
+	e.uvarint(uint64(len(data)))
+	e.write(data)
+	return nil
+}
+
+func (e *encoder) end() {
+	e.flushCopy()
+	e.write([]byte{opEnd})
+}
+
+func (e *encoder) uvarint(v uint64) {
+	e.write(binary.AppendUvarint(nil, v))
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
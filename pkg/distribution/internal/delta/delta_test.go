@@ -0,0 +1,84 @@
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+func roundTrip(t *testing.T, base, target []byte, blockSize int) []byte {
+	t.Helper()
+	var delta bytes.Buffer
+	if err := Diff(bytes.NewReader(base), int64(len(base)), bytes.NewReader(target), int64(len(target)), &delta, blockSize); err != nil {
+		t.Fatalf("Failed to compute delta: %v", err)
+	}
+	var out bytes.Buffer
+	if err := Apply(bytes.NewReader(base), int64(len(base)), bytes.NewReader(delta.Bytes()), &out); err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), target) {
+		t.Fatalf("Reconstructed %d bytes that don't match the %d byte target", out.Len(), len(target))
+	}
+	return delta.Bytes()
+}
+
+func TestDiffApply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const blockSize = 1024
+	base := randomBytes(r, 1<<20)
+
+	t.Run("Identical", func(t *testing.T) {
+		if delta := roundTrip(t, base, base, blockSize); len(delta) > 256 {
+			t.Errorf("Expected a small delta, got %d bytes", len(delta))
+		}
+	})
+
+	t.Run("ShiftedAndPatched", func(t *testing.T) {
+		// Longer metadata shifts the data that follows, and a few blocks
+		// are changed, as when some tensors are fine-tuned.
+		target := append([]byte("longer metadata"), base...)
+		copy(target[200_000:], randomBytes(r, 3*blockSize))
+		copy(target[700_000:], randomBytes(r, blockSize))
+		delta := roundTrip(t, base, target, blockSize)
+		if len(delta) > 8*blockSize {
+			t.Errorf("Expected a delta of a few blocks, got %d bytes", len(delta))
+		}
+	})
+
+	t.Run("Unrelated", func(t *testing.T) {
+		roundTrip(t, base, randomBytes(r, 10*maxLiteral/4+123), blockSize)
+	})
+
+	t.Run("Small", func(t *testing.T) {
+		roundTrip(t, base, []byte("tiny"), blockSize)
+		roundTrip(t, nil, base[:5000], blockSize)
+		roundTrip(t, base, nil, blockSize)
+	})
+}
+
+func TestApplyInvalid(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	base := randomBytes(r, 64<<10)
+	target := append(base[:32<<10:32<<10], randomBytes(r, 100)...)
+	var delta bytes.Buffer
+	if err := Diff(bytes.NewReader(base), int64(len(base)), bytes.NewReader(target), int64(len(target)), &delta, 4096); err != nil {
+		t.Fatalf("Failed to compute delta: %v", err)
+	}
+
+	// A delta only applies to the base it was computed from.
+	err := Apply(bytes.NewReader(base[:1000]), 1000, bytes.NewReader(delta.Bytes()), &bytes.Buffer{})
+	if !errors.Is(err, ErrInvalidDelta) {
+		t.Errorf("Expected ErrInvalidDelta for another base, got %v", err)
+	}
+	err = Apply(bytes.NewReader(base), int64(len(base)), bytes.NewReader([]byte("not a delta")), &bytes.Buffer{})
+	if err == nil {
+		t.Error("Expected an error for a malformed delta")
+	}
+}
@@ -0,0 +1,88 @@
+package diffusion
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// NewModel creates a model from a stable-diffusion.cpp image generation
+// checkpoint, in GGUF or safetensors format. Checkpoints aren't split, so the
+// file becomes a single layer.
+func NewModel(path string) (*Model, error) {
+	isGGUF, err := checkCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := partial.NewLayer(path, types.MediaTypeDiffusion)
+	if err != nil {
+		return nil, fmt.Errorf("create diffusion layer: %w", err)
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("get diffusion layer diffID: %w", err)
+	}
+
+	config := types.Config{
+		Format:       types.FormatDiffusion,
+		Architecture: "stable-diffusion",
+	}
+	if isGGUF {
+		// The header is parsed on a best effort basis, as the metadata of
+		// diffusion checkpoints is sparser than that of llama.cpp models.
+		if gguf, err := parser.ParseGGUFFile(path); err == nil {
+			config.Quantization = strings.TrimSpace(gguf.Metadata().FileType.String())
+			config.Size = strings.TrimSpace(gguf.Metadata().Size.String())
+		}
+	}
+
+	created := time.Now()
+	return &Model{
+		configFile: types.ConfigFile{
+			Config: config,
+			Descriptor: types.Descriptor{
+				Created: &created,
+			},
+			RootFS: v1.RootFS{
+				Type:    "rootfs",
+				DiffIDs: []v1.Hash{diffID},
+			},
+		},
+		layers: []v1.Layer{layer},
+	}, nil
+}
+
+// checkCheckpoint checks that a file is a GGUF or safetensors file, the
+// formats stable-diffusion.cpp loads single-file checkpoints in, and returns
+// whether it's a GGUF file.
+func checkCheckpoint(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("open diffusion checkpoint: %w", err)
+	}
+	defer f.Close()
+
+	// GGUF files start with their magic number, and safetensors files with
+	// the little-endian size of their JSON header.
+	var header [9]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return false, fmt.Errorf("read diffusion checkpoint: %w", err)
+	}
+	if string(header[:4]) == "GGUF" {
+		return true, nil
+	}
+	if size := binary.LittleEndian.Uint64(header[:8]); size > 0 && size < 100*1024*1024 && header[8] == '{' {
+		return false, nil
+	}
+	return false, fmt.Errorf("%s is neither a GGUF nor a safetensors file", path)
+}
@@ -0,0 +1,67 @@
+package diffusion_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/diffusion"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestNewModel(t *testing.T) {
+	t.Run("GGUF", func(t *testing.T) {
+		mdl, err := diffusion.NewModel(filepath.Join("..", "..", "assets", "dummy.gguf"))
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		cfg, err := mdl.Config()
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if cfg.Format != types.FormatDiffusion || cfg.Architecture != "stable-diffusion" || cfg.ContextSize != nil {
+			t.Errorf("Unexpected config %+v", cfg)
+		}
+		manifest, err := mdl.Manifest()
+		if err != nil {
+			t.Fatalf("Failed to get manifest: %v", err)
+		}
+		if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != types.MediaTypeDiffusion {
+			t.Errorf("Expected a single diffusion layer, got %+v", manifest.Layers)
+		}
+	})
+
+	t.Run("safetensors", func(t *testing.T) {
+		header := []byte(`{"__metadata__":{}}`)
+		data := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+		path := filepath.Join(t.TempDir(), "sd.safetensors")
+		if err := os.WriteFile(path, append(data, header...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mdl, err := diffusion.NewModel(path)
+		if err != nil {
+			t.Fatalf("Failed to create model: %v", err)
+		}
+		cfg, err := mdl.Config()
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if cfg.Format != types.FormatDiffusion || cfg.Quantization != "" {
+			t.Errorf("Unexpected config %+v", cfg)
+		}
+	})
+}
+
+func TestNewModelInvalidCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sd.ckpt")
+	if err := os.WriteFile(path, []byte("PK\x03\x04 pickled checkpoint"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := diffusion.NewModel(path); err == nil {
+		t.Error("Expected an error")
+	}
+	if _, err := diffusion.NewModel(filepath.Join(t.TempDir(), "missing.gguf")); err == nil {
+		t.Error("Expected an error")
+	}
+}
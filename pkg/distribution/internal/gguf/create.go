@@ -10,12 +10,13 @@ import (
 
 	"github.com/docker/model-runner/pkg/distribution/internal/partial"
 	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/internal/ggufshard"
 )
 
 func NewModel(path string) (*Model, error) {
-	shards := parser.CompleteShardGGUFFilename(path)
-	if len(shards) == 0 {
-		shards = []string{path} // single file
+	shards, err := ggufshard.Resolve(path)
+	if err != nil {
+		return nil, err
 	}
 	layers := make([]v1.Layer, len(shards))
 	diffIDs := make([]v1.Hash, len(shards))
@@ -48,6 +49,42 @@ func NewModel(path string) (*Model, error) {
 	}, nil
 }
 
+// NewWhisperModel creates a model from a whisper.cpp speech-to-text model file.
+// Whisper models aren't split, so the file becomes a single layer.
+func NewWhisperModel(path string) (*Model, error) {
+	layer, err := partial.NewLayer(path, types.MediaTypeWhisperGGUF)
+	if err != nil {
+		return nil, fmt.Errorf("create whisper layer: %w", err)
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("get whisper layer diffID: %w", err)
+	}
+
+	// The header is parsed on a best effort basis, as not every whisper.cpp
+	// model carries the metadata llama.cpp models do.
+	config := configFromFile(path)
+	config.Format = types.FormatWhisper
+	if config.Architecture == "" {
+		config.Architecture = "whisper"
+	}
+
+	created := time.Now()
+	return &Model{
+		configFile: types.ConfigFile{
+			Config: config,
+			Descriptor: types.Descriptor{
+				Created: &created,
+			},
+			RootFS: v1.RootFS{
+				Type:    "rootfs",
+				DiffIDs: []v1.Hash{diffID},
+			},
+		},
+		layers: []v1.Layer{layer},
+	}, nil
+}
+
 func configFromFile(path string) types.Config {
 	gguf, err := parser.ParseGGUFFile(path)
 	if err != nil {
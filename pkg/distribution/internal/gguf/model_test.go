@@ -1,7 +1,12 @@
 package gguf_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
@@ -179,3 +184,109 @@ func TestGGUFShards(t *testing.T) {
 		})
 	})
 }
+
+func TestGGUFMissingShard(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("..", "..", "assets", "dummy-00001-of-00002.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to read shard: %v", err)
+	}
+	first := filepath.Join(dir, "dummy-00001-of-00002.gguf")
+	if err := os.WriteFile(first, src, 0644); err != nil {
+		t.Fatalf("Failed to write shard: %v", err)
+	}
+
+	_, err = gguf.NewModel(first)
+	if err == nil {
+		t.Fatal("Expected error for missing shard")
+	}
+	if !strings.Contains(err.Error(), "dummy-00002-of-00002.gguf") {
+		t.Errorf("Expected error to name the missing shard, got: %v", err)
+	}
+}
+
+// TestGGUFContextSize checks that the training context length is recorded as
+// metadata only, so that runners use the configured context size unless the
+// packager overrides it.
+func TestGGUFContextSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	writeTestGGUF(t, path, map[string]any{
+		"general.architecture": "qwen2",
+		"qwen2.context_length": uint32(32768),
+		"tokenizer.ggml.model": "gpt2",
+	})
+
+	mdl, err := gguf.NewModel(path)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	cfg, err := mdl.Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Architecture != "qwen2" {
+		t.Errorf("Unexpected architecture: got %s expected %s", cfg.Architecture, "qwen2")
+	}
+	if cfg.ContextSize != nil {
+		t.Errorf("Expected no context size, got %d", *cfg.ContextSize)
+	}
+	if cfg.GGUF["qwen2.context_length"] != "32768" {
+		t.Errorf("Unexpected context length: got %s expected %s", cfg.GGUF["qwen2.context_length"], "32768")
+	}
+	if cfg.GGUF["tokenizer.ggml.model"] != "gpt2" {
+		t.Errorf("Unexpected tokenizer: got %s expected %s", cfg.GGUF["tokenizer.ggml.model"], "gpt2")
+	}
+}
+
+func TestGGUFWithoutContextSize(t *testing.T) {
+	mdl, err := gguf.NewModel(filepath.Join("..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	cfg, err := mdl.Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.ContextSize != nil {
+		t.Errorf("Expected no context size, got %d", *cfg.ContextSize)
+	}
+}
+
+// writeTestGGUF writes a tensor-less GGUF v3 file with the given string and
+// uint32 metadata values.
+func writeTestGGUF(t *testing.T, path string, metadata map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint64(len(s)))
+		buf.WriteString(s)
+	}
+
+	buf.WriteString("GGUF")
+	binary.Write(&buf, binary.LittleEndian, uint32(3))             // version
+	binary.Write(&buf, binary.LittleEndian, uint64(0))             // tensor count
+	binary.Write(&buf, binary.LittleEndian, uint64(len(metadata))) // metadata count
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeString(key)
+		switch v := metadata[key].(type) {
+		case string:
+			binary.Write(&buf, binary.LittleEndian, uint32(8))
+			writeString(v)
+		case uint32:
+			binary.Write(&buf, binary.LittleEndian, uint32(4))
+			binary.Write(&buf, binary.LittleEndian, v)
+		default:
+			t.Fatalf("unsupported metadata type %T", v)
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write GGUF file: %v", err)
+	}
+}
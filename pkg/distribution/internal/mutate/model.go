@@ -3,6 +3,7 @@ package mutate
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	ggcrpartial "github.com/google/go-containerregistry/pkg/v1/partial"
@@ -17,6 +18,14 @@ type model struct {
 	appended        []v1.Layer
 	configMediaType ggcr.MediaType
 	contextSize     *uint64
+	runtimeFlags    *[]string
+	quantizedFrom   *types.QuantizationInfo
+	configOverrides *types.Config
+	baseModel       *types.BaseModel
+	licenseRequired bool
+	reranker        bool
+	removed         ggcr.MediaType
+	annotations     map[string]string
 }
 
 func (m *model) Descriptor() (types.Descriptor, error) {
@@ -98,6 +107,19 @@ func (m *model) Layers() ([]v1.Layer, error) {
 	if err != nil {
 		return nil, err
 	}
+	if m.removed != "" {
+		var kept []v1.Layer
+		for _, l := range ls {
+			mt, err := l.MediaType()
+			if err != nil {
+				return nil, fmt.Errorf("get layer media type: %w", err)
+			}
+			if mt != m.removed {
+				kept = append(kept, l)
+			}
+		}
+		ls = kept
+	}
 	return append(ls, m.appended...), nil
 }
 
@@ -109,6 +131,16 @@ func (m *model) Manifest() (*v1.Manifest, error) {
 	if m.configMediaType != "" {
 		manifest.Config.MediaType = m.configMediaType
 	}
+	// Keep the annotations of the base model, which ManifestForLayers drops.
+	base, err := m.base.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("get base manifest: %w", err)
+	}
+	if len(base.Annotations) > 0 || len(m.annotations) > 0 {
+		manifest.Annotations = make(map[string]string, len(base.Annotations)+len(m.annotations))
+		maps.Copy(manifest.Annotations, base.Annotations)
+		maps.Copy(manifest.Annotations, m.annotations)
+	}
 	return manifest, nil
 }
 
@@ -117,16 +149,58 @@ func (m *model) RawConfigFile() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	for _, l := range m.appended {
-		diffID, err := l.DiffID()
+	if m.removed != "" {
+		// Rebuild the diff IDs from the remaining layers.
+		ls, err := m.Layers()
 		if err != nil {
 			return nil, err
 		}
-		cf.RootFS.DiffIDs = append(cf.RootFS.DiffIDs, diffID)
+		cf.RootFS.DiffIDs = nil
+		for _, l := range ls {
+			diffID, err := l.DiffID()
+			if err != nil {
+				return nil, err
+			}
+			cf.RootFS.DiffIDs = append(cf.RootFS.DiffIDs, diffID)
+		}
+	} else {
+		for _, l := range m.appended {
+			diffID, err := l.DiffID()
+			if err != nil {
+				return nil, err
+			}
+			cf.RootFS.DiffIDs = append(cf.RootFS.DiffIDs, diffID)
+		}
 	}
 	if m.contextSize != nil {
 		cf.Config.ContextSize = m.contextSize
 	}
+	if m.runtimeFlags != nil {
+		cf.Config.RuntimeFlags = *m.runtimeFlags
+	}
+	if m.quantizedFrom != nil {
+		cf.Config.QuantizedFrom = m.quantizedFrom
+	}
+	if m.baseModel != nil {
+		cf.Config.BaseModel = m.baseModel
+	}
+	if m.licenseRequired {
+		cf.Config.LicenseAcceptanceRequired = true
+	}
+	if m.reranker {
+		cf.Config.Reranker = true
+	}
+	if o := m.configOverrides; o != nil {
+		if o.Architecture != "" {
+			cf.Config.Architecture = o.Architecture
+		}
+		if o.Parameters != "" {
+			cf.Config.Parameters = o.Parameters
+		}
+		if o.Quantization != "" {
+			cf.Config.Quantization = o.Quantization
+		}
+	}
 	raw, err := json.Marshal(cf)
 	if err != nil {
 		return nil, err
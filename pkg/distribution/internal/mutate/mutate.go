@@ -14,6 +14,14 @@ func AppendLayers(mdl types.ModelArtifact, layers ...v1.Layer) types.ModelArtifa
 	}
 }
 
+// RemoveLayers removes all layers with the given media type.
+func RemoveLayers(mdl types.ModelArtifact, mt ggcr.MediaType) types.ModelArtifact {
+	return &model{
+		base:    mdl,
+		removed: mt,
+	}
+}
+
 func ConfigMediaType(mdl types.ModelArtifact, mt ggcr.MediaType) types.ModelArtifact {
 	return &model{
 		base:            mdl,
@@ -27,3 +35,57 @@ func ContextSize(mdl types.ModelArtifact, cs uint64) types.ModelArtifact {
 		contextSize: &cs,
 	}
 }
+
+// RuntimeFlags replaces the runtime flags in the model config. An empty
+// slice clears them.
+func RuntimeFlags(mdl types.ModelArtifact, flags []string) types.ModelArtifact {
+	return &model{
+		base:         mdl,
+		runtimeFlags: &flags,
+	}
+}
+
+func QuantizedFrom(mdl types.ModelArtifact, info types.QuantizationInfo) types.ModelArtifact {
+	return &model{
+		base:          mdl,
+		quantizedFrom: &info,
+	}
+}
+
+func ConfigOverrides(mdl types.ModelArtifact, overrides types.Config) types.ModelArtifact {
+	return &model{
+		base:            mdl,
+		configOverrides: &overrides,
+	}
+}
+
+func BaseModel(mdl types.ModelArtifact, base types.BaseModel) types.ModelArtifact {
+	return &model{
+		base:      mdl,
+		baseModel: &base,
+	}
+}
+
+func LicenseAcceptanceRequired(mdl types.ModelArtifact) types.ModelArtifact {
+	return &model{
+		base:            mdl,
+		licenseRequired: true,
+	}
+}
+
+// Reranker marks the model as a reranker.
+func Reranker(mdl types.ModelArtifact) types.ModelArtifact {
+	return &model{
+		base:     mdl,
+		reranker: true,
+	}
+}
+
+// Annotations adds annotations to the manifest, replacing those of the base
+// model with the same keys.
+func Annotations(mdl types.ModelArtifact, annotations map[string]string) types.ModelArtifact {
+	return &model{
+		base:        mdl,
+		annotations: annotations,
+	}
+}
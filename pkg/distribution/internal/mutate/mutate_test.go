@@ -112,3 +112,46 @@ func TestContextSize(t *testing.T) {
 		t.Fatalf("Expected context size of 2096 got %d", *cfg2.ContextSize)
 	}
 }
+
+func TestRemoveLayers(t *testing.T) {
+	mdl1, err := gguf.NewModel(filepath.Join("..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	mdl2 := mutate.AppendLayers(mdl1,
+		static.NewLayer([]byte("old template"), types.MediaTypeChatTemplate),
+	)
+
+	// Remove the template layer
+	mdl3 := mutate.RemoveLayers(mdl2, types.MediaTypeChatTemplate)
+	manifest, err := mdl3.Manifest()
+	if err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+	if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != types.MediaTypeGGUF {
+		t.Fatalf("Expected only the GGUF layer, got %+v", manifest.Layers)
+	}
+	rawCfg, err := mdl3.RawConfigFile()
+	if err != nil {
+		t.Fatalf("Failed to get raw config file: %v", err)
+	}
+	var cfg types.ConfigFile
+	if err := json.Unmarshal(rawCfg, &cfg); err != nil {
+		t.Fatalf("Failed to unmarshal config file: %v", err)
+	}
+	if len(cfg.RootFS.DiffIDs) != 1 || cfg.RootFS.DiffIDs[0] != manifest.Layers[0].Digest {
+		t.Fatalf("Expected diff ids to match remaining layers, got %v", cfg.RootFS.DiffIDs)
+	}
+
+	// Replace it with a new one
+	mdl4 := mutate.AppendLayers(mdl3,
+		static.NewLayer([]byte("new template"), types.MediaTypeChatTemplate),
+	)
+	manifest, err = mdl4.Manifest()
+	if err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+	if len(manifest.Layers) != 2 {
+		t.Fatalf("Expected 2 layers, got %d", len(manifest.Layers))
+	}
+}
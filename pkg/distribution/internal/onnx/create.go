@@ -0,0 +1,104 @@
+package onnx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// hfConfig is the subset of the Hugging Face config of an exported model that
+// is recorded in the model config.
+type hfConfig struct {
+	Architectures []string `json:"architectures"`
+}
+
+// NewModel creates a model from an embedding or classification model in ONNX
+// format. The Hugging Face config next to it, if any, determines whether it's
+// a classifier. Its tokenizer isn't part of the model, and is added with its
+// other config files as a config archive.
+func NewModel(path string) (*Model, error) {
+	if err := checkONNX(path); err != nil {
+		return nil, err
+	}
+
+	config := types.Config{
+		Format: types.FormatONNX,
+	}
+	hf, err := readHFConfig(filepath.Join(filepath.Dir(path), "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(hf.Architectures) > 0 {
+		config.Architecture = hf.Architectures[0]
+		config.Classifier = strings.HasSuffix(hf.Architectures[0], "ForSequenceClassification")
+	}
+
+	layer, err := partial.NewLayer(path, types.MediaTypeONNX)
+	if err != nil {
+		return nil, fmt.Errorf("create ONNX layer: %w", err)
+	}
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("get ONNX layer diffID: %w", err)
+	}
+
+	created := time.Now()
+	return &Model{
+		configFile: types.ConfigFile{
+			Config: config,
+			Descriptor: types.Descriptor{
+				Created: &created,
+			},
+			RootFS: v1.RootFS{
+				Type:    "rootfs",
+				DiffIDs: []v1.Hash{diffID},
+			},
+		},
+		layers: []v1.Layer{layer},
+	}, nil
+}
+
+// checkONNX checks that a file looks like an ONNX model, which is a protobuf
+// message that starts with its IR version, field number 1.
+func checkONNX(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open ONNX model: %w", err)
+	}
+	defer f.Close()
+
+	var header [1]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return fmt.Errorf("read ONNX model: %w", err)
+	}
+	if header[0] != 0x08 {
+		return fmt.Errorf("%s is not an ONNX model", path)
+	}
+	return nil
+}
+
+// readHFConfig reads the Hugging Face config of a model, which is optional.
+func readHFConfig(path string) (hfConfig, error) {
+	var config hfConfig
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, fmt.Errorf("read model config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("parse model config %q: %w", path, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,81 @@
+package onnx_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/onnx"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// writeModel writes an ONNX model and, if given, its Hugging Face config to a
+// temporary directory.
+func writeModel(t *testing.T, contents, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.onnx")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if config != "" {
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestNewModel(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		architecture string
+		classifier   bool
+	}{
+		{name: "no config"},
+		{name: "embedding model", config: `{"architectures": ["BertModel"]}`, architecture: "BertModel"},
+		{
+			name:         "classification model",
+			config:       `{"architectures": ["DistilBertForSequenceClassification"], "id2label": {"0": "NEGATIVE", "1": "POSITIVE"}}`,
+			architecture: "DistilBertForSequenceClassification",
+			classifier:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl, err := onnx.NewModel(writeModel(t, "\x08\x07onnx", tt.config))
+			if err != nil {
+				t.Fatalf("Failed to create model: %v", err)
+			}
+			cfg, err := mdl.Config()
+			if err != nil {
+				t.Fatalf("Failed to get config: %v", err)
+			}
+			if cfg.Format != types.FormatONNX || cfg.Architecture != tt.architecture || cfg.Classifier != tt.classifier {
+				t.Errorf("Unexpected config %+v", cfg)
+			}
+			manifest, err := mdl.Manifest()
+			if err != nil {
+				t.Fatalf("Failed to get manifest: %v", err)
+			}
+			if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != types.MediaTypeONNX {
+				t.Errorf("Expected a single ONNX layer, got %+v", manifest.Layers)
+			}
+		})
+	}
+}
+
+func TestNewModelInvalid(t *testing.T) {
+	for name, path := range map[string]string{
+		"not ONNX":       writeModel(t, "GGUF", ""),
+		"invalid config": writeModel(t, "\x08\x07onnx", "not json"),
+		"missing":        filepath.Join(t.TempDir(), "model.onnx"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := onnx.NewModel(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
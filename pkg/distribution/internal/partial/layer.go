@@ -6,6 +6,9 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/v1"
 	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
 )
 
 var _ v1.Layer = &Layer{}
@@ -41,11 +44,33 @@ func (l Layer) DiffID() (v1.Hash, error) {
 }
 
 func (l Layer) Compressed() (io.ReadCloser, error) {
-	return l.Uncompressed()
+	return os.Open(l.Path)
 }
 
+// Uncompressed returns the content of the file, decompressing it if the
+// layer is zstd compressed.
 func (l Layer) Uncompressed() (io.ReadCloser, error) {
-	return os.Open(l.Path)
+	f, err := os.Open(l.Path)
+	if err != nil || !types.IsZstd(l.Descriptor.MediaType) {
+		return f, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &zstdReadCloser{Decoder: zr, f: f}, nil
+}
+
+// zstdReadCloser decompresses a file, closing it along with the decoder.
+type zstdReadCloser struct {
+	*zstd.Decoder
+	f *os.File
+}
+
+func (r *zstdReadCloser) Close() error {
+	r.Decoder.Close()
+	return r.f.Close()
 }
 
 func (l Layer) Size() (int64, error) {
@@ -3,6 +3,8 @@ package partial
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"slices"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/partial"
@@ -85,7 +87,7 @@ func MMPROJPath(i WithLayers) (string, error) {
 }
 
 func ChatTemplatePath(i WithLayers) (string, error) {
-	paths, err := layerPathsByMediaType(i, types.MediaTypeChatTemplate)
+	paths, err := layerPathsByMediaType(i, types.MediaTypeChatTemplate, types.MediaTypeChatTemplateZstd)
 	if err != nil {
 		return "", fmt.Errorf("get chat template layer paths: %w", err)
 	}
@@ -99,12 +101,124 @@ func ChatTemplatePath(i WithLayers) (string, error) {
 	return paths[0], err
 }
 
+func LoRAAdapterPaths(i WithLayers) ([]string, error) {
+	return layerPathsByMediaType(i, types.MediaTypeLoRAAdapter)
+}
+
+// LicensePaths returns the paths of the license files, which are zstd
+// compressed for layers of type types.MediaTypeLicenseZstd. Use Licenses to
+// read them.
+func LicensePaths(i WithLayers) ([]string, error) {
+	return layerPathsByMediaType(i, types.MediaTypeLicense, types.MediaTypeLicenseZstd)
+}
+
+// Licenses returns the texts of the licenses, decompressing them as needed.
+func Licenses(i WithLayers) ([]string, error) {
+	layers, err := i.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get layers: %w", err)
+	}
+	var licenses []string
+	for _, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil || (mt != types.MediaTypeLicense && mt != types.MediaTypeLicenseZstd) {
+			continue
+		}
+		rc, err := l.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("open license: %w", err)
+		}
+		text, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read license: %w", err)
+		}
+		licenses = append(licenses, string(text))
+	}
+	return licenses, nil
+}
+
+func WhisperPath(i WithLayers) (string, error) {
+	paths, err := layerPathsByMediaType(i, types.MediaTypeWhisperGGUF)
+	if err != nil {
+		return "", fmt.Errorf("get whisper layer paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("model does not contain any layer of type %q", types.MediaTypeWhisperGGUF)
+	}
+	if len(paths) > 1 {
+		return "", fmt.Errorf("found %d files of type %q, expected exactly 1",
+			len(paths), types.MediaTypeWhisperGGUF)
+	}
+	return paths[0], err
+}
+
+func VoicePath(i WithLayers) (string, error) {
+	paths, err := layerPathsByMediaType(i, types.MediaTypePiperVoice)
+	if err != nil {
+		return "", fmt.Errorf("get voice layer paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("model does not contain any layer of type %q", types.MediaTypePiperVoice)
+	}
+	if len(paths) > 1 {
+		return "", fmt.Errorf("found %d files of type %q, expected exactly 1",
+			len(paths), types.MediaTypePiperVoice)
+	}
+	return paths[0], err
+}
+
+func VoiceConfigPath(i WithLayers) (string, error) {
+	paths, err := layerPathsByMediaType(i, types.MediaTypePiperVoiceConfig)
+	if err != nil {
+		return "", fmt.Errorf("get voice config layer paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("model does not contain any layer of type %q", types.MediaTypePiperVoiceConfig)
+	}
+	if len(paths) > 1 {
+		return "", fmt.Errorf("found %d files of type %q, expected exactly 1",
+			len(paths), types.MediaTypePiperVoiceConfig)
+	}
+	return paths[0], err
+}
+
+func DiffusionPath(i WithLayers) (string, error) {
+	paths, err := layerPathsByMediaType(i, types.MediaTypeDiffusion)
+	if err != nil {
+		return "", fmt.Errorf("get diffusion layer paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("model does not contain any layer of type %q", types.MediaTypeDiffusion)
+	}
+	if len(paths) > 1 {
+		return "", fmt.Errorf("found %d files of type %q, expected exactly 1",
+			len(paths), types.MediaTypeDiffusion)
+	}
+	return paths[0], err
+}
+
+func ONNXPath(i WithLayers) (string, error) {
+	paths, err := layerPathsByMediaType(i, types.MediaTypeONNX)
+	if err != nil {
+		return "", fmt.Errorf("get ONNX layer paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("model does not contain any layer of type %q", types.MediaTypeONNX)
+	}
+	if len(paths) > 1 {
+		return "", fmt.Errorf("found %d files of type %q, expected exactly 1",
+			len(paths), types.MediaTypeONNX)
+	}
+	return paths[0], err
+}
+
 func SafetensorsPaths(i WithLayers) ([]string, error) {
 	return layerPathsByMediaType(i, types.MediaTypeSafetensors)
 }
 
 func ConfigArchivePath(i WithLayers) (string, error) {
-	paths, err := layerPathsByMediaType(i, types.MediaTypeVLLMConfigArchive)
+	paths, err := layerPathsByMediaType(i, types.MediaTypeVLLMConfigArchive, types.MediaTypeVLLMConfigArchiveZstd)
 	if err != nil {
 		return "", fmt.Errorf("get config archive layer paths: %w", err)
 	}
@@ -118,8 +232,9 @@ func ConfigArchivePath(i WithLayers) (string, error) {
 	return paths[0], err
 }
 
-// layerPathsByMediaType is a generic helper function that finds a layer by media type and returns its path
-func layerPathsByMediaType(i WithLayers, mediaType ggcr.MediaType) ([]string, error) {
+// layerPathsByMediaType is a generic helper function that finds layers by media type and returns their paths.
+// The first media type names the layers in errors, and the others are its compressed variants.
+func layerPathsByMediaType(i WithLayers, mediaType ggcr.MediaType, variants ...ggcr.MediaType) ([]string, error) {
 	layers, err := i.Layers()
 	if err != nil {
 		return nil, fmt.Errorf("get layers: %w", err)
@@ -127,7 +242,7 @@ func layerPathsByMediaType(i WithLayers, mediaType ggcr.MediaType) ([]string, er
 	var paths []string
 	for _, l := range layers {
 		mt, err := l.MediaType()
-		if err != nil || mt != mediaType {
+		if err != nil || (mt != mediaType && !slices.Contains(variants, mt)) {
 			continue
 		}
 		layer, ok := l.(*Layer)
@@ -0,0 +1,102 @@
+package piper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcr "github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/partial"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// voiceConfig is the subset of a piper voice config that is recorded in the
+// model config.
+type voiceConfig struct {
+	Audio struct {
+		SampleRate int    `json:"sample_rate"`
+		Quality    string `json:"quality"`
+	} `json:"audio"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	NumSpeakers int `json:"num_speakers"`
+}
+
+// NewModel creates a model from a piper text-to-speech voice in ONNX format.
+// Piper expects the voice's config next to it, with a .json suffix appended
+// (e.g. en_US-lessac-medium.onnx.json), so the config is read from there.
+func NewModel(path string) (*Model, error) {
+	configPath := path + ".json"
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read voice config: %w", err)
+	}
+	var voice voiceConfig
+	if err := json.Unmarshal(data, &voice); err != nil {
+		return nil, fmt.Errorf("parse voice config %q: %w", configPath, err)
+	}
+	if voice.Audio.SampleRate <= 0 {
+		return nil, fmt.Errorf("voice config %q has no sample rate", configPath)
+	}
+
+	var layers []v1.Layer
+	var diffIDs []v1.Hash
+	for _, file := range []struct {
+		path      string
+		mediaType ggcr.MediaType
+	}{
+		{path: path, mediaType: types.MediaTypePiperVoice},
+		{path: configPath, mediaType: types.MediaTypePiperVoiceConfig},
+	} {
+		layer, err := partial.NewLayer(file.path, file.mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("create voice layer from %q: %w", file.path, err)
+		}
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return nil, fmt.Errorf("get voice layer diffID: %w", err)
+		}
+		layers = append(layers, layer)
+		diffIDs = append(diffIDs, diffID)
+	}
+
+	created := time.Now()
+	return &Model{
+		configFile: types.ConfigFile{
+			Config: configFromVoice(voice),
+			Descriptor: types.Descriptor{
+				Created: &created,
+			},
+			RootFS: v1.RootFS{
+				Type:    "rootfs",
+				DiffIDs: diffIDs,
+			},
+		},
+		layers: layers,
+	}, nil
+}
+
+// configFromVoice returns the model config of a voice. Piper voices have no
+// context, and their quality takes the place of the quantization.
+func configFromVoice(voice voiceConfig) types.Config {
+	config := types.Config{
+		Format:       types.FormatPiper,
+		Architecture: "piper",
+		Quantization: strings.TrimSpace(voice.Audio.Quality),
+		Piper: map[string]string{
+			"sample_rate": fmt.Sprint(voice.Audio.SampleRate),
+		},
+	}
+	if voice.Language.Code != "" {
+		config.Piper["language"] = voice.Language.Code
+	}
+	if voice.NumSpeakers > 0 {
+		config.Piper["num_speakers"] = fmt.Sprint(voice.NumSpeakers)
+	}
+	return config
+}
@@ -0,0 +1,67 @@
+package piper_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/piper"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// writeVoice writes a voice file and its config to a temporary directory.
+func writeVoice(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "en_US-test-medium.onnx")
+	if err := os.WriteFile(path, []byte("onnx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if config != "" {
+		if err := os.WriteFile(path+".json", []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestNewModel(t *testing.T) {
+	mdl, err := piper.NewModel(writeVoice(t,
+		`{"audio": {"sample_rate": 22050, "quality": "medium"}, "language": {"code": "en_US"}, "num_speakers": 1}`))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	cfg, err := mdl.Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.Format != types.FormatPiper || cfg.Quantization != "medium" || cfg.ContextSize != nil {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if cfg.Piper["sample_rate"] != "22050" || cfg.Piper["language"] != "en_US" || cfg.Piper["num_speakers"] != "1" {
+		t.Errorf("Unexpected piper metadata %v", cfg.Piper)
+	}
+
+	manifest, err := mdl.Manifest()
+	if err != nil {
+		t.Fatalf("Failed to get manifest: %v", err)
+	}
+	if len(manifest.Layers) != 2 || manifest.Layers[0].MediaType != types.MediaTypePiperVoice ||
+		manifest.Layers[1].MediaType != types.MediaTypePiperVoiceConfig {
+		t.Errorf("Expected voice and voice config layers, got %+v", manifest.Layers)
+	}
+}
+
+func TestNewModelInvalidConfig(t *testing.T) {
+	for name, config := range map[string]string{
+		"missing":        "",
+		"invalid":        "not json",
+		"no sample rate": `{"audio": {"quality": "medium"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := piper.NewModel(writeVoice(t, config)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
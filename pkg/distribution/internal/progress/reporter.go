@@ -29,6 +29,18 @@ type Message struct {
 	Total   uint64 `json:"total"`
 	Pulled  uint64 `json:"pulled"` // Deprecated: use Layer.Current
 	Layer   Layer  `json:"layer"`  // Current layer information
+	// RateLimit is the pull quota of the registry, reported by progress
+	// messages sent when it's observed or when a pull waits for it.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// RateLimit is the pull quota of a registry.
+type RateLimit struct {
+	Registry  string `json:"registry"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	// RetryIn is the number of seconds until a rate-limited pull is retried.
+	RetryIn int `json:"retry_in,omitempty"`
 }
 
 type Reporter struct {
@@ -139,6 +151,16 @@ func WriteProgress(w io.Writer, msg string, imageSize, layerSize, current uint64
 	})
 }
 
+// WriteRateLimit writes a progress message reporting the pull quota of a
+// registry
+func WriteRateLimit(w io.Writer, msg string, rateLimit RateLimit) error {
+	return write(w, Message{
+		Type:      "progress",
+		Message:   msg,
+		RateLimit: &rateLimit,
+	})
+}
+
 // WriteSuccess writes a success message
 func WriteSuccess(w io.Writer, message string) error {
 	return write(w, Message{
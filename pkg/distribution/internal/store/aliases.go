@@ -0,0 +1,59 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Aliases returns the aliases recorded in the index, mapping alias names to
+// the references of the models they stand for.
+func (s *LocalStore) Aliases() (map[string]string, error) {
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, fmt.Errorf("reading models file: %w", err)
+	}
+	if index.Aliases == nil {
+		return map[string]string{}, nil
+	}
+	return index.Aliases, nil
+}
+
+// SetAlias points the alias with the given name at a model reference,
+// creating the alias if needed. The caller validates the name and reference.
+func (s *LocalStore) SetAlias(name, reference string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models file: %w", err)
+	}
+	index.Aliases = maps.Clone(index.Aliases)
+	if index.Aliases == nil {
+		index.Aliases = make(map[string]string)
+	}
+	index.Aliases[name] = reference
+	return s.writeIndexFile(index)
+}
+
+// RemoveAlias removes the alias with the given name.
+func (s *LocalStore) RemoveAlias(name string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models file: %w", err)
+	}
+	if _, ok := index.Aliases[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrAliasNotFound, name)
+	}
+	delete(index.Aliases, name)
+	return s.writeIndexFile(index)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -8,8 +9,13 @@ import (
 	"strings"
 
 	"github.com/docker/model-runner/pkg/distribution/internal/progress"
+	mdtypes "github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/tracing"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -79,37 +85,119 @@ type blob interface {
 }
 
 // writeLayer writes the layer blob to the store.
-// It returns true when a new blob was created and the blob's DiffID.
-func (s *LocalStore) writeLayer(layer blob, updates chan<- v1.Update) (bool, v1.Hash, error) {
-	hash, err := layer.DiffID()
+// It returns true when a new blob was created and the hash the blob is stored under.
+func (s *LocalStore) writeLayer(ctx context.Context, layer blob, updates chan<- v1.Update) (created bool, hash v1.Hash, err error) {
+	hash, err = blobHash(layer)
 	if err != nil {
 		return false, v1.Hash{}, fmt.Errorf("get file hash: %w", err)
 	}
+	ctx, span := tracer.Start(ctx, "store.FetchBlob", trace.WithAttributes(attribute.String("blob.diff_id", hash.String())))
+	defer func() { tracing.End(span, err) }()
+
 	hasBlob, err := s.hasBlob(hash)
 	if err != nil {
 		return false, v1.Hash{}, fmt.Errorf("check blob existence: %w", err)
 	}
+	span.SetAttributes(attribute.Bool("blob.cached", hasBlob))
 	if hasBlob {
 		// TODO: write something to the progress channel (we probably need to redo progress reporting a little bit)
 		return false, hash, nil
 	}
+	size := uncompressedSize(layer, hash)
+	linked, err := s.linkFromCache(hash, size)
+	if err != nil {
+		return false, v1.Hash{}, err
+	}
+	span.SetAttributes(attribute.Bool("blob.linked", linked))
+	if linked {
+		return true, hash, nil
+	}
 
-	lr, err := layer.Uncompressed()
+	lr, err := blobContents(layer)
 	if err != nil {
 		return false, v1.Hash{}, fmt.Errorf("get blob contents: %w", err)
 	}
 	defer lr.Close()
 	r := progress.NewReader(lr, updates)
 
-	if err := s.WriteBlob(hash, r); err != nil {
+	if err := s.writeBlob(ctx, hash, r, size); err != nil {
 		return false, hash, err
 	}
 	return true, hash, nil
 }
 
+// zstdBlob is a layer of a zstd compressed media type, such as
+// types.MediaTypeLicenseZstd. Such layers are stored compressed, keyed by
+// their digest, and decompressed when read, whereas other layers are stored
+// uncompressed so that weights can be memory-mapped.
+type zstdBlob interface {
+	MediaType() (ggcrtypes.MediaType, error)
+	Digest() (v1.Hash, error)
+	Compressed() (io.ReadCloser, error)
+}
+
+// asZstdBlob returns the layer as a zstdBlob if it is zstd compressed.
+func asZstdBlob(layer blob) (zstdBlob, bool) {
+	l, ok := layer.(zstdBlob)
+	if !ok {
+		return nil, false
+	}
+	mt, err := l.MediaType()
+	return l, err == nil && mdtypes.IsZstd(mt)
+}
+
+// blobHash returns the hash the layer is stored under.
+func blobHash(layer blob) (v1.Hash, error) {
+	if l, ok := asZstdBlob(layer); ok {
+		return l.Digest()
+	}
+	return layer.DiffID()
+}
+
+// blobContents returns the content of the layer as it is stored.
+func blobContents(layer blob) (io.ReadCloser, error) {
+	if l, ok := asZstdBlob(layer); ok {
+		return l.Compressed()
+	}
+	return layer.Uncompressed()
+}
+
+// uncompressedSize returns the size of the uncompressed content of the layer
+// if it is known up front, or -1 otherwise. It is only known for layers that
+// are stored uncompressed, i.e. whose digest matches their DiffID.
+func uncompressedSize(layer blob, diffID v1.Hash) int64 {
+	l, ok := layer.(interface {
+		Digest() (v1.Hash, error)
+		Size() (int64, error)
+	})
+	if !ok {
+		return -1
+	}
+	if digest, err := l.Digest(); err != nil || digest != diffID {
+		return -1
+	}
+	size, err := l.Size()
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
 // WriteBlob writes the blob to the store, reporting progress to the given channel.
 // If the blob is already in the store, it is a no-op and the blob is not consumed from the reader.
+// The content is verified against diffID as it is written, and ErrDigestMismatch is returned if it
+// doesn't match, in which case nothing is written to the store.
 func (s *LocalStore) WriteBlob(diffID v1.Hash, r io.Reader) error {
+	return s.writeBlob(context.Background(), diffID, r, -1)
+}
+
+// writeBlob implements WriteBlob. If size isn't -1, writing fails as soon as
+// the content exceeds it. The verification of the content is traced as a span
+// of the trace in ctx.
+func (s *LocalStore) writeBlob(ctx context.Context, diffID v1.Hash, r io.Reader, size int64) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	hasBlob, err := s.hasBlob(diffID)
 	if err != nil {
 		return fmt.Errorf("check blob existence: %w", err)
@@ -122,21 +210,49 @@ func (s *LocalStore) WriteBlob(diffID v1.Hash, r io.Reader) error {
 	if err != nil {
 		return fmt.Errorf("get blob path: %w", err)
 	}
-	f, err := createFile(incompletePath(path))
+	// Remove any incomplete file left behind by a crashed pull. Concurrent
+	// writers never share it, since each writes to its own temporary file.
+	_ = os.Remove(incompletePath(path))
+	f, err := createIncompleteFile(path)
 	if err != nil {
 		return fmt.Errorf("create blob file: %w", err)
 	}
-	defer os.Remove(incompletePath(path))
+	defer os.Remove(f.Name())
 	defer f.Close()
 
-	if _, err := io.Copy(f, r); err != nil {
+	// Hash the content before writing it so that content exceeding the
+	// expected size never reaches the disk.
+	dw, err := newDigestWriter(diffID, size)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(dw, f), r); err != nil {
+		return fmt.Errorf("copy blob %q to store: %w", diffID.String(), err)
+	}
+	_, span := tracer.Start(ctx, "store.VerifyBlob", trace.WithAttributes(attribute.String("blob.diff_id", diffID.String())))
+	err = dw.verify()
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("copy blob %q to store: %w", diffID.String(), err)
 	}
 
 	f.Close() // Rename will fail on Windows if the file is still open.
-	if err := os.Rename(incompletePath(path), path); err != nil {
+
+	// Another writer may have finalized the same blob in the meantime.
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if hasBlob, err := s.hasBlob(diffID); err != nil {
+		return fmt.Errorf("check blob existence: %w", err)
+	} else if hasBlob {
+		return nil
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
 		return fmt.Errorf("rename blob file: %w", err)
 	}
+	s.addToCache(diffID)
 	return nil
 }
 
@@ -149,6 +265,20 @@ func (s *LocalStore) removeBlob(hash v1.Hash) error {
 	return os.Remove(path)
 }
 
+// HasBlob reports whether the blob with the given DiffID is in the store.
+func (s *LocalStore) HasBlob(hash v1.Hash) (bool, error) {
+	return s.hasBlob(hash)
+}
+
+// OpenBlob opens the blob with the given DiffID for reading.
+func (s *LocalStore) OpenBlob(hash v1.Hash) (*os.File, error) {
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return nil, fmt.Errorf("get blob path: %w", err)
+	}
+	return os.Open(path)
+}
+
 func (s *LocalStore) hasBlob(hash v1.Hash) (bool, error) {
 	path, err := s.blobPath(hash)
 	if err != nil {
@@ -160,19 +290,50 @@ func (s *LocalStore) hasBlob(hash v1.Hash) (bool, error) {
 	return false, nil
 }
 
-// createFile is a wrapper around os.Create that creates any parent directories as needed.
-func createFile(path string) (*os.File, error) {
-	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
-		return nil, fmt.Errorf("create parent directory %q: %w", filepath.Dir(path), err)
+// MissingSize returns the total size of the layers of mdl that are neither in
+// the store nor in its blob cache, and so must be downloaded to write mdl.
+// Sizes are as given by the manifest, so compressed layers take more space
+// once written.
+func (s *LocalStore) MissingSize(mdl v1.Image) (int64, error) {
+	layers, err := mdl.Layers()
+	if err != nil {
+		return 0, fmt.Errorf("getting layers: %w", err)
+	}
+	var missing int64
+	for _, layer := range layers {
+		hash, err := blobHash(layer)
+		if err != nil {
+			return 0, fmt.Errorf("get file hash: %w", err)
+		}
+		if hasBlob, err := s.hasBlob(hash); err != nil {
+			return 0, fmt.Errorf("check blob existence: %w", err)
+		} else if hasBlob || s.isCached(hash) {
+			continue
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return 0, fmt.Errorf("getting layer size: %w", err)
+		}
+		missing += size
 	}
-	return os.Create(path)
+	return missing, nil
 }
 
-// incompletePath returns the path to the incomplete file for the given path.
+// incompletePath returns the path to the incomplete file for the given path
+// used by previous versions, which shared it between concurrent writers.
 func incompletePath(path string) string {
 	return path + ".incomplete"
 }
 
+// createIncompleteFile creates a uniquely named incomplete file next to the
+// given path, creating any parent directories as needed.
+func createIncompleteFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, fmt.Errorf("create parent directory %q: %w", filepath.Dir(path), err)
+	}
+	return os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".incomplete-*")
+}
+
 // writeConfigFile writes the model config JSON file to the blob store and reports whether the file was newly created.
 func (s *LocalStore) writeConfigFile(mdl v1.Image) (bool, error) {
 	hash, err := mdl.ConfigName()
@@ -2,10 +2,12 @@ package store
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -105,9 +107,9 @@ func TestBlobs(t *testing.T) {
 
 	t.Run("WriteBlob reuses existing blob", func(t *testing.T) {
 		// simulate existing blob
-		hash := v1.Hash{
-			Algorithm: "sha256",
-			Hex:       "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		hash, _, err := v1.SHA256(bytes.NewReader([]byte("some-data")))
+		if err != nil {
+			t.Fatalf("error calculating hash: %v", err)
 		}
 
 		if err := store.WriteBlob(hash, bytes.NewReader([]byte("some-data"))); err != nil {
@@ -133,6 +135,52 @@ func TestBlobs(t *testing.T) {
 			t.Fatalf("unexpected blob content: got %v expected %s", string(content), "some-data")
 		}
 	})
+
+	t.Run("WriteBlob with digest mismatch", func(t *testing.T) {
+		hash, _, err := v1.SHA256(bytes.NewReader([]byte("expected data")))
+		if err != nil {
+			t.Fatalf("error calculating hash: %v", err)
+		}
+
+		err = store.WriteBlob(hash, bytes.NewReader([]byte("corrupt data!")))
+		if !errors.Is(err, ErrDigestMismatch) {
+			t.Fatalf("expected ErrDigestMismatch, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "digest mismatch at offset 13") {
+			t.Fatalf("expected mismatch offset in error, got %v", err)
+		}
+
+		// ensure neither the blob nor the incomplete file is left behind
+		blobPath, err := store.blobPath(hash)
+		if err != nil {
+			t.Fatalf("error getting blob path: %v", err)
+		}
+		if _, err := os.Stat(blobPath); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected blob file not to exist")
+		}
+		if matches, _ := filepath.Glob(blobPath + ".incomplete*"); len(matches) != 0 {
+			t.Fatalf("expected incomplete blob files not to exist, got %v", matches)
+		}
+	})
+
+	t.Run("writeBlob fails fast when exceeding size", func(t *testing.T) {
+		content := []byte("expected data")
+		hash, size, err := v1.SHA256(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("error calculating hash: %v", err)
+		}
+
+		// The reader fails if it's read beyond the expected size plus one
+		// chunk, so the write must fail before the reader is drained.
+		r := io.MultiReader(bytes.NewReader(content), bytes.NewReader([]byte("extra")), &errorReader{})
+		var mismatch *DigestMismatchError
+		if err := store.writeBlob(context.Background(), hash, r, size); !errors.As(err, &mismatch) {
+			t.Fatalf("expected DigestMismatchError, got %v", err)
+		}
+		if mismatch.Offset != size {
+			t.Fatalf("expected mismatch at offset %d, got %d", size, mismatch.Offset)
+		}
+	})
 }
 
 var _ io.Reader = &errorReader{}
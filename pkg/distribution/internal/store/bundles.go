@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
@@ -15,6 +16,22 @@ const (
 	bundlesDir = "bundles"
 )
 
+// bundleCache caches the bundles of the store by model digest, so that they
+// are only parsed once, and counts the references to each so that the bundle
+// of a model deleted while in use is only removed once it's released.
+type bundleCache struct {
+	mu      sync.Mutex
+	entries map[v1.Hash]*bundleEntry
+}
+
+type bundleEntry struct {
+	bundle types.ModelBundle
+	refs   int
+	// deleted indicates that the model was deleted while the bundle was in
+	// use, so that it's removed once released.
+	deleted bool
+}
+
 // manifestPath returns the path to the manifest file for the given hash.
 func (s *LocalStore) bundlePath(hash v1.Hash) string {
 	return filepath.Join(s.rootPath, bundlesDir, hash.Algorithm, hash.Hex)
@@ -22,24 +39,108 @@ func (s *LocalStore) bundlePath(hash v1.Hash) string {
 
 // BundleForModel returns a runtime bundle for the given model
 func (s *LocalStore) BundleForModel(ref string) (types.ModelBundle, error) {
-	mdl, err := s.Read(ref)
+	return s.BundleForModelFrom(s, ref)
+}
+
+// BundleForModelFrom returns a runtime bundle in this store for the given
+// model in src, which may be another store, such as a read-only one in which
+// bundles can't be created.
+func (s *LocalStore) BundleForModelFrom(src *LocalStore, ref string) (types.ModelBundle, error) {
+	bdl, _, err := s.bundleForModel(src, ref, false, false)
+	return bdl, err
+}
+
+// AcquireBundleFrom returns a runtime bundle like BundleForModelFrom, and
+// holds a reference to it until release is called. The bundle of a model that
+// is deleted while referenced is removed once the last reference is released.
+func (s *LocalStore) AcquireBundleFrom(src *LocalStore, ref string) (bdl types.ModelBundle, release func(), err error) {
+	return s.bundleForModel(src, ref, false, true)
+}
+
+// RefreshBundleFrom recreates the runtime bundle in this store for the given
+// model in src. It returns ErrBundleInUse if the bundle is referenced.
+func (s *LocalStore) RefreshBundleFrom(src *LocalStore, ref string) (types.ModelBundle, error) {
+	bdl, _, err := s.bundleForModel(src, ref, true, false)
+	return bdl, err
+}
+
+// bundleForModel returns the bundle for the given model in src, from the cache
+// if possible, recreating it if refresh is set. If acquire is set, it holds a
+// reference to the bundle until the returned function is called.
+func (s *LocalStore) bundleForModel(src *LocalStore, ref string, refresh, acquire bool) (types.ModelBundle, func(), error) {
+	mdl, err := src.Read(ref)
 	if err != nil {
-		return nil, fmt.Errorf("find model content: %w", err)
+		return nil, nil, fmt.Errorf("find model content: %w", err)
 	}
 	dgst, err := mdl.Digest()
 	if err != nil {
-		return nil, fmt.Errorf("get model ID: %w", err)
+		return nil, nil, fmt.Errorf("get model ID: %w", err)
 	}
-	path := s.bundlePath(dgst)
-	if bdl, err := bundle.Parse(path); err != nil {
-		// create for first time or replace bad/corrupted bundle
-		return s.createBundle(path, mdl)
-	} else {
-		return bdl, nil
+
+	s.bundles.mu.Lock()
+	defer s.bundles.mu.Unlock()
+	if s.bundles.entries == nil {
+		s.bundles.entries = make(map[v1.Hash]*bundleEntry)
 	}
+	entry := s.bundles.entries[dgst]
+	if entry != nil && refresh && entry.refs > 0 {
+		return nil, nil, ErrBundleInUse
+	}
+	// Bundles may be removed behind the cache's back, for example by another
+	// process resetting the store.
+	if entry == nil || refresh || !bundleExists(entry.bundle) {
+		path := s.bundlePath(dgst)
+		bdl, err := bundle.Parse(path)
+		var mb types.ModelBundle = bdl
+		if err != nil || refresh {
+			// create for first time or replace bad/corrupted bundle
+			if mb, err = s.createBundle(path, mdl); err != nil {
+				return nil, nil, err
+			}
+		}
+		if entry == nil {
+			entry = &bundleEntry{}
+			s.bundles.entries[dgst] = entry
+		}
+		entry.bundle = mb
+	}
+	// The model may have been pulled again since it was deleted.
+	entry.deleted = false
+	if !acquire {
+		return entry.bundle, nil, nil
+	}
+	entry.refs++
+	var once sync.Once
+	return entry.bundle, func() { once.Do(func() { s.releaseBundle(dgst, entry) }) }, nil
+}
+
+// releaseBundle releases a reference to the bundle of the model with the
+// given digest, removing the bundle if it was the last reference to the bundle
+// of a deleted model.
+func (s *LocalStore) releaseBundle(hash v1.Hash, entry *bundleEntry) {
+	s.bundles.mu.Lock()
+	defer s.bundles.mu.Unlock()
+	entry.refs--
+	if entry.refs > 0 || !entry.deleted {
+		return
+	}
+	if s.bundles.entries[hash] == entry {
+		delete(s.bundles.entries, hash)
+	}
+	if err := os.RemoveAll(s.bundlePath(hash)); err != nil {
+		fmt.Printf("Warning: failed to remove bundle %q: %v\n", hash, err)
+	}
+}
+
+// bundleExists reports whether the directory of the bundle still exists.
+func bundleExists(bdl types.ModelBundle) bool {
+	fi, err := os.Stat(bdl.RootDir())
+	return err == nil && fi.IsDir()
 }
 
 // createBundle unpacks the bundle to path, replacing existing bundle if one is found
+// Bundles are runtime caches rather than models, so they're created even in
+// read-only stores, if the file system allows.
 func (s *LocalStore) createBundle(path string, mdl *Model) (types.ModelBundle, error) {
 	if err := os.RemoveAll(path); err != nil {
 		return nil, fmt.Errorf("remove %s: %w", path, err)
@@ -47,13 +148,28 @@ func (s *LocalStore) createBundle(path string, mdl *Model) (types.ModelBundle, e
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, fmt.Errorf("create bundle directory: %w", err)
 	}
-	bdl, err := bundle.Unpack(path, mdl)
+	var opts []bundle.UnpackOption
+	if s.hardlinkBundles {
+		opts = append(opts, bundle.WithHardlinksOnly())
+	}
+	bdl, err := bundle.Unpack(path, mdl, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unpack bundle: %w", err)
 	}
 	return bdl, nil
 }
 
+// removeBundle removes the bundle of the model with the given digest, which
+// is being deleted. If the bundle is in use, it's removed once released.
 func (s *LocalStore) removeBundle(hash v1.Hash) error {
+	s.bundles.mu.Lock()
+	defer s.bundles.mu.Unlock()
+	if entry, ok := s.bundles.entries[hash]; ok {
+		if entry.refs > 0 {
+			entry.deleted = true
+			return nil
+		}
+		delete(s.bundles.entries, hash)
+	}
 	return os.RemoveAll(s.bundlePath(hash))
 }
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// ErrNoBlobCache is returned by Dedupe when the store has no blob cache.
+var ErrNoBlobCache = errors.New("no blob cache configured")
+
+// DedupeReport describes the changes made by Dedupe.
+type DedupeReport struct {
+	// Blobs is the number of blobs in the store.
+	Blobs int `json:"blobs"`
+	// Cached is the number of blobs added to the blob cache.
+	Cached int `json:"cached"`
+	// Linked is the number of blobs replaced with a link to the blob cache.
+	Linked int `json:"linked"`
+	// SavedBytes is the size of the blobs replaced with a link.
+	SavedBytes int64 `json:"saved_bytes"`
+}
+
+// cachedBlobPath returns the path to the blob for the given hash in the blob
+// cache, which has the same layout as the store's blobs directory.
+func (s *LocalStore) cachedBlobPath(hash v1.Hash) (string, error) {
+	if err := validateHash(hash); err != nil {
+		return "", fmt.Errorf("unsafe hash: %w", err)
+	}
+	return filepath.Join(s.blobCachePath, hash.Algorithm, hash.Hex), nil
+}
+
+// isCached reports whether the blob with the given hash is in the blob
+// cache, from which it can be linked rather than downloaded.
+func (s *LocalStore) isCached(hash v1.Hash) bool {
+	if s.blobCachePath == "" {
+		return false
+	}
+	path, err := s.cachedBlobPath(hash)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// linkFromCache adds the blob with the given hash to the store by hardlinking
+// it from the blob cache, once its content is verified. It reports false if
+// there's no blob cache, the blob isn't cached, or it can't be linked, for
+// example because the cache is on another file system, in which case the blob
+// must be written as usual.
+func (s *LocalStore) linkFromCache(hash v1.Hash, size int64) (bool, error) {
+	if s.blobCachePath == "" {
+		return false, nil
+	}
+	cachePath, err := s.cachedBlobPath(hash)
+	if err != nil {
+		return false, nil
+	}
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return false, fmt.Errorf("get blob path: %w", err)
+	}
+	if err := verifyFile(cachePath, hash, size); err != nil {
+		return false, nil
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if hasBlob, err := s.hasBlob(hash); err != nil {
+		return false, fmt.Errorf("check blob existence: %w", err)
+	} else if hasBlob {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return false, nil
+	}
+	return os.Link(cachePath, path) == nil, nil
+}
+
+// addToCache hardlinks the blob with the given hash into the blob cache, so
+// that other stores using the cache can link it rather than download it
+// again. The cache is best-effort, so failures are ignored.
+func (s *LocalStore) addToCache(hash v1.Hash) {
+	if s.blobCachePath == "" {
+		return
+	}
+	_, _ = s.linkToCache(hash)
+}
+
+// linkToCache hardlinks the blob with the given hash into the blob cache. It
+// returns the path of the cached blob, and an error satisfying
+// errors.Is(err, fs.ErrExist) if the blob is already cached.
+func (s *LocalStore) linkToCache(hash v1.Hash) (string, error) {
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return "", err
+	}
+	cachePath, err := s.cachedBlobPath(hash)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0777); err != nil {
+		return cachePath, fmt.Errorf("create blob cache directory: %w", err)
+	}
+	return cachePath, os.Link(path, cachePath)
+}
+
+// replaceFile atomically replaces the file at path with a hardlink to target.
+func replaceFile(path, target string) error {
+	tmp := path + ".dedupe"
+	_ = os.Remove(tmp)
+	if err := os.Link(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Dedupe retro-fits the store to the blob cache: blobs that are already
+// cached are replaced with hardlinks to the cached blobs, and the others are
+// added to the cache. Cached blobs are verified before they're linked, and a
+// corrupt cached blob is replaced with the store's. The store and the cache
+// must be on the same file system.
+func (s *LocalStore) Dedupe() (DedupeReport, error) {
+	var report DedupeReport
+	if s.blobCachePath == "" {
+		return report, ErrNoBlobCache
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return report, err
+	}
+	defer unlock()
+
+	algorithms, err := os.ReadDir(s.blobsDir())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return report, nil
+		}
+		return report, fmt.Errorf("read blobs directory: %w", err)
+	}
+	for _, algorithm := range algorithms {
+		if !algorithm.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.blobsDir(), algorithm.Name()))
+		if err != nil {
+			return report, fmt.Errorf("read blobs directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.Contains(entry.Name(), ".") {
+				continue
+			}
+			hash := v1.Hash{Algorithm: algorithm.Name(), Hex: entry.Name()}
+			if validateHash(hash) != nil {
+				continue
+			}
+			report.Blobs++
+			if err := s.dedupeBlob(hash, &report); err != nil {
+				return report, fmt.Errorf("dedupe blob %q: %w", hash.String(), err)
+			}
+		}
+	}
+	return report, nil
+}
+
+// dedupeBlob links the blob with the given hash to the blob cache, updating
+// report. The store must be locked.
+func (s *LocalStore) dedupeBlob(hash v1.Hash, report *DedupeReport) error {
+	cachePath, err := s.linkToCache(hash)
+	if err == nil {
+		report.Cached++
+		return nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("link blob into cache: %w", err)
+	}
+
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	cached, err := os.Stat(cachePath)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(info, cached) {
+		return nil
+	}
+	if err := verifyFile(cachePath, hash, info.Size()); err != nil {
+		// Replace the corrupt cached blob with the store's.
+		if err := replaceFile(cachePath, path); err != nil {
+			return fmt.Errorf("replace cached blob: %w", err)
+		}
+		report.Cached++
+		return nil
+	}
+	if err := replaceFile(path, cachePath); err != nil {
+		return fmt.Errorf("link blob from cache: %w", err)
+	}
+	report.Linked++
+	report.SavedBytes += info.Size()
+	return nil
+}
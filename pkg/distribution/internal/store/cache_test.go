@@ -0,0 +1,149 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// layerPath returns the path of the first layer blob of mdl below root, which
+// is either a store's blobs directory or a blob cache.
+func layerPath(t *testing.T, root string, mdl types.ModelArtifact) string {
+	t.Helper()
+	layers, err := mdl.Layers()
+	if err != nil {
+		t.Fatalf("Get layers failed: %v", err)
+	}
+	diffID, err := layers[0].DiffID()
+	if err != nil {
+		t.Fatalf("Get diff ID failed: %v", err)
+	}
+	return filepath.Join(root, diffID.Algorithm, diffID.Hex)
+}
+
+// assertSameFile fails the test unless a and b are hardlinks to the same file.
+func assertSameFile(t *testing.T, a, b string) {
+	t.Helper()
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if !os.SameFile(aInfo, bInfo) {
+		t.Errorf("Expected %s and %s to be the same file", a, b)
+	}
+}
+
+func TestBlobCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	mdl := newTestModel(t)
+
+	// Blobs written to a store are added to the cache.
+	firstDir := t.TempDir()
+	first, err := store.New(store.Options{RootPath: firstDir, BlobCachePath: cacheDir})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+	if err := first.Write(mdl, []string{"cached-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	assertSameFile(t, layerPath(t, filepath.Join(firstDir, "blobs"), mdl), layerPath(t, cacheDir, mdl))
+
+	// Blobs in the cache are linked into other stores.
+	secondDir := t.TempDir()
+	second, err := store.New(store.Options{RootPath: secondDir, BlobCachePath: cacheDir})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+	if err := second.Write(mdl, []string{"cached-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	assertSameFile(t, layerPath(t, filepath.Join(secondDir, "blobs"), mdl), layerPath(t, cacheDir, mdl))
+	if err := second.Verify("cached-model:latest"); err != nil {
+		t.Errorf("Expected the linked model to verify, got %v", err)
+	}
+
+	// Corrupt cached blobs are written as usual. The cached blob is replaced
+	// rather than overwritten, which would corrupt the stores linking it.
+	if err := os.Remove(layerPath(t, cacheDir, mdl)); err != nil {
+		t.Fatalf("Remove cached blob failed: %v", err)
+	}
+	if err := os.WriteFile(layerPath(t, cacheDir, mdl), []byte("corrupt"), 0644); err != nil {
+		t.Fatalf("Corrupt cached blob failed: %v", err)
+	}
+	third, err := store.New(store.Options{RootPath: t.TempDir(), BlobCachePath: cacheDir})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+	if err := third.Write(mdl, []string{"cached-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := third.Verify("cached-model:latest"); err != nil {
+		t.Errorf("Expected the written model to verify, got %v", err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	cacheDir := t.TempDir()
+	mdl := newTestModel(t)
+
+	// Stores written without a cache have their own copies of the blobs.
+	var dirs []string
+	for range 2 {
+		dir := t.TempDir()
+		s, err := store.New(store.Options{RootPath: dir})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		if err := s.Write(mdl, []string{"dedupe-model:latest"}, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if _, err := s.Dedupe(); !errors.Is(err, store.ErrNoBlobCache) {
+			t.Errorf("Expected ErrNoBlobCache, got %v", err)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	var reports []store.DedupeReport
+	for _, dir := range dirs {
+		s, err := store.New(store.Options{RootPath: dir, BlobCachePath: cacheDir})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		report, err := s.Dedupe()
+		if err != nil {
+			t.Fatalf("Dedupe failed: %v", err)
+		}
+		reports = append(reports, report)
+		if _, err := s.Read("dedupe-model:latest"); err != nil {
+			t.Errorf("Expected the model to remain readable, got %v", err)
+		}
+	}
+
+	// The first store's blobs are cached, and the second's replaced with links.
+	if reports[0].Blobs == 0 || reports[0].Cached != reports[0].Blobs || reports[0].Linked != 0 {
+		t.Errorf("Expected all blobs of the first store to be cached, got %+v", reports[0])
+	}
+	if reports[1].Linked != reports[1].Blobs || reports[1].Cached != 0 || reports[1].SavedBytes == 0 {
+		t.Errorf("Expected all blobs of the second store to be linked, got %+v", reports[1])
+	}
+	for _, dir := range dirs {
+		assertSameFile(t, layerPath(t, filepath.Join(dir, "blobs"), mdl), layerPath(t, cacheDir, mdl))
+	}
+
+	// Deduplicating again changes nothing.
+	s, err := store.New(store.Options{RootPath: dirs[1], BlobCachePath: cacheDir})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+	if report, err := s.Dedupe(); err != nil || report.Cached != 0 || report.Linked != 0 {
+		t.Errorf("Expected no changes, got %+v, %v", report, err)
+	}
+}
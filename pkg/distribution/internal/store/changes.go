@@ -0,0 +1,143 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxTombstones is the maximum number of deleted models remembered by the
+// index. Clients with cursors older than the oldest tombstone must resync.
+const maxTombstones = 1000
+
+// Tombstone records the deletion of a model from the store.
+type Tombstone struct {
+	// ID is the ID of the deleted model.
+	ID string `json:"id"`
+	// Sequence is the change sequence number of the deletion.
+	Sequence uint64 `json:"sequence"`
+}
+
+// Changes describes the models changed in the store since a cursor.
+type Changes struct {
+	// Cursor is the cursor to pass to the next call to Changes.
+	Cursor string
+	// Reset indicates that the provided cursor could not be honored, so
+	// Models contains all models in the store and the client should discard
+	// its state.
+	Reset bool
+	// Models are the models created or retagged since the cursor.
+	Models []IndexEntry
+	// Deleted are the IDs of the models deleted since the cursor.
+	Deleted []string
+}
+
+// newEpoch returns a random epoch identifying the lifetime of an index.
+func newEpoch() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("generating index epoch: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// withChangesFrom returns a copy of i with change sequence numbers assigned
+// relative to prev, the index it replaces. Models that are new or whose tags
+// or files changed get a new sequence number and removed models are recorded
+// as tombstones.
+func (i Index) withChangesFrom(prev Index) Index {
+	result := Index{
+		Epoch:      prev.Epoch,
+		Sequence:   prev.Sequence,
+		Horizon:    prev.Horizon,
+		Models:     make([]IndexEntry, 0, len(i.Models)),
+		Tombstones: slices.Clone(prev.Tombstones),
+		Aliases:    prev.Aliases,
+	}
+	if result.Epoch == "" {
+		result.Epoch = newEpoch()
+	}
+
+	previous := make(map[string]IndexEntry, len(prev.Models))
+	for _, entry := range prev.Models {
+		previous[entry.ID] = entry
+	}
+	current := make(map[string]bool, len(i.Models))
+	for _, entry := range i.Models {
+		current[entry.ID] = true
+		old, ok := previous[entry.ID]
+		if ok && slices.Equal(old.Tags, entry.Tags) && slices.Equal(old.Files, entry.Files) {
+			entry.Sequence = old.Sequence
+		} else {
+			result.Sequence++
+			entry.Sequence = result.Sequence
+		}
+		result.Models = append(result.Models, entry)
+	}
+
+	// Drop tombstones of models that were re-added, then record removals.
+	result.Tombstones = slices.DeleteFunc(result.Tombstones, func(t Tombstone) bool {
+		return current[t.ID]
+	})
+	for _, entry := range prev.Models {
+		if !current[entry.ID] {
+			result.Sequence++
+			result.Tombstones = append(result.Tombstones, Tombstone{ID: entry.ID, Sequence: result.Sequence})
+		}
+	}
+	if n := len(result.Tombstones) - maxTombstones; n > 0 {
+		result.Horizon = result.Tombstones[n-1].Sequence
+		result.Tombstones = slices.Delete(result.Tombstones, 0, n)
+	}
+	return result
+}
+
+// cursor returns the cursor for the current state of the index.
+func (i Index) cursor() string {
+	return i.Epoch + ":" + strconv.FormatUint(i.Sequence, 10)
+}
+
+// Changes returns the models changed since the given cursor. An empty cursor
+// returns all models along with the current cursor. If the cursor belongs to
+// a previous lifetime of the store (e.g. before it was reset) or is older than
+// the oldest remembered deletion, all models are returned with Reset set.
+func (s *LocalStore) Changes(cursor string) (Changes, error) {
+	index, err := s.readIndex()
+	if err != nil {
+		return Changes{}, fmt.Errorf("reading models index: %w", err)
+	}
+	changes := Changes{Cursor: index.cursor()}
+
+	var since uint64
+	if cursor != "" {
+		epoch, seq, ok := strings.Cut(cursor, ":")
+		if !ok {
+			return Changes{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		since, err = strconv.ParseUint(seq, 10, 64)
+		if err != nil {
+			return Changes{}, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		if epoch != index.Epoch || since > index.Sequence || since < index.Horizon {
+			changes.Reset = true
+			since = 0
+		}
+	}
+
+	for _, entry := range index.Models {
+		if entry.Sequence > since || since == 0 {
+			changes.Models = append(changes.Models, entry)
+		}
+	}
+	if since > 0 {
+		for _, t := range index.Tombstones {
+			if t.Sequence > since {
+				changes.Deleted = append(changes.Deleted, t.ID)
+			}
+		}
+	}
+	return changes, nil
+}
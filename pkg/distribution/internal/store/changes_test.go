@@ -0,0 +1,130 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+func TestChanges(t *testing.T) {
+	s, err := store.New(store.Options{RootPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+
+	// An empty cursor returns everything, even for an empty store.
+	initial, err := s.Changes("")
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(initial.Models) != 0 || len(initial.Deleted) != 0 || initial.Reset {
+		t.Fatalf("Expected no changes for empty store, got %+v", initial)
+	}
+
+	mdl := newTestModel(t)
+	if err := s.Write(mdl, []string{"changes-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	digest, err := mdl.Digest()
+	if err != nil {
+		t.Fatalf("Digest failed: %v", err)
+	}
+	id := digest.String()
+
+	t.Run("created", func(t *testing.T) {
+		changes, err := s.Changes(initial.Cursor)
+		if err != nil {
+			t.Fatalf("Changes failed: %v", err)
+		}
+		if changes.Reset {
+			t.Error("Expected no reset")
+		}
+		if len(changes.Models) != 1 || changes.Models[0].ID != id {
+			t.Errorf("Expected created model %s, got %+v", id, changes.Models)
+		}
+		if changes.Cursor == initial.Cursor {
+			t.Error("Expected cursor to advance")
+		}
+	})
+
+	created, err := s.Changes("")
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		changes, err := s.Changes(created.Cursor)
+		if err != nil {
+			t.Fatalf("Changes failed: %v", err)
+		}
+		if len(changes.Models) != 0 || len(changes.Deleted) != 0 {
+			t.Errorf("Expected no changes, got %+v", changes)
+		}
+		if changes.Cursor != created.Cursor {
+			t.Errorf("Expected cursor %s, got %s", created.Cursor, changes.Cursor)
+		}
+	})
+
+	if err := s.AddTags(id, []string{"changes-model:other"}); err != nil {
+		t.Fatalf("AddTags failed: %v", err)
+	}
+
+	t.Run("retagged", func(t *testing.T) {
+		changes, err := s.Changes(created.Cursor)
+		if err != nil {
+			t.Fatalf("Changes failed: %v", err)
+		}
+		if len(changes.Models) != 1 || len(changes.Models[0].Tags) != 2 {
+			t.Errorf("Expected retagged model, got %+v", changes.Models)
+		}
+	})
+
+	retagged, err := s.Changes("")
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if _, _, err := s.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	t.Run("deleted", func(t *testing.T) {
+		changes, err := s.Changes(retagged.Cursor)
+		if err != nil {
+			t.Fatalf("Changes failed: %v", err)
+		}
+		if len(changes.Models) != 0 {
+			t.Errorf("Expected no models, got %+v", changes.Models)
+		}
+		if len(changes.Deleted) != 1 || changes.Deleted[0] != id {
+			t.Errorf("Expected deleted model %s, got %v", id, changes.Deleted)
+		}
+	})
+
+	t.Run("reset after store reset", func(t *testing.T) {
+		if err := s.Reset(); err != nil {
+			t.Fatalf("Reset failed: %v", err)
+		}
+		if err := s.Write(mdl, []string{"changes-model:latest"}, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		changes, err := s.Changes(retagged.Cursor)
+		if err != nil {
+			t.Fatalf("Changes failed: %v", err)
+		}
+		if !changes.Reset {
+			t.Error("Expected reset for cursor from previous store lifetime")
+		}
+		if len(changes.Models) != 1 || len(changes.Deleted) != 0 {
+			t.Errorf("Expected full listing, got %+v", changes)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		for _, cursor := range []string{"no-separator", "epoch:not-a-number"} {
+			if _, err := s.Changes(cursor); !errors.Is(err, store.ErrInvalidCursor) {
+				t.Errorf("Expected ErrInvalidCursor for %q, got %v", cursor, err)
+			}
+		}
+	})
+}
@@ -5,3 +5,23 @@ import (
 )
 
 var ErrModelNotFound = errors.New("model not found")
+
+// ErrInvalidCursor is returned by Changes when the cursor is malformed.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrDigestMismatch is returned when the content of a blob doesn't match its
+// digest.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// ErrStoreLocked is returned when the store lock can't be acquired because
+// another process holds it for too long.
+var ErrStoreLocked = errors.New("store is locked by another process")
+
+// ErrAliasNotFound is returned when an alias doesn't exist.
+var ErrAliasNotFound = errors.New("alias not found")
+
+// ErrReadOnly is returned when modifying a read-only store.
+var ErrReadOnly = errors.New("store is read-only")
+
+// ErrBundleInUse is returned when refreshing a bundle that is in use.
+var ErrBundleInUse = errors.New("bundle is in use")
@@ -6,13 +6,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
 )
 
 // Index represents the index of all models in the store
 type Index struct {
-	Models []IndexEntry `json:"models"`
+	// Version is the version of the index format. Indexes without a version
+	// are version 1.
+	Version int          `json:"version,omitempty"`
+	Models  []IndexEntry `json:"models"`
+	// Epoch identifies the lifetime of the index. It changes when the store
+	// is reset, invalidating all change cursors.
+	Epoch string `json:"epoch,omitempty"`
+	// Sequence is the sequence number of the latest change to the index.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// Tombstones record the most recently deleted models.
+	Tombstones []Tombstone `json:"tombstones,omitempty"`
+	// Horizon is the sequence number of the latest forgotten tombstone.
+	Horizon uint64 `json:"horizon,omitempty"`
+	// Aliases map alias names to the references of the models they stand
+	// for.
+	Aliases map[string]string `json:"aliases,omitempty"`
 }
 
 func (i Index) Tag(reference string, tag string) (Index, error) {
@@ -93,6 +111,26 @@ func (s *LocalStore) indexPath() string {
 
 // writeIndex writes the index to the index file
 func (s *LocalStore) writeIndex(index Index) error {
+	// Track changes relative to the index being replaced
+	prev, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models index: %w", err)
+	}
+	return s.writeIndexFile(index.withChangesFrom(prev))
+}
+
+// restoreIndex restores a previously read index, e.g. when rolling back a
+// failed write. The restored index gets a new epoch, so clients that observed
+// the rolled back changes resync.
+func (s *LocalStore) restoreIndex(index Index) error {
+	index.Epoch = newEpoch()
+	return s.writeIndexFile(index)
+}
+
+// writeIndexFile writes the index to the index file as-is.
+func (s *LocalStore) writeIndexFile(index Index) error {
+	index.Version = indexVersion
+
 	// Marshal the models index
 	modelsData, err := json.MarshalIndent(index, "", "  ")
 	if err != nil {
@@ -104,6 +142,8 @@ func (s *LocalStore) writeIndex(index Index) error {
 		return fmt.Errorf("writing models file: %w", err)
 	}
 
+	s.generation.Add(1)
+
 	if err := s.ensureLayout(); err != nil {
 		return fmt.Errorf("ensuring layout file exists: %w", err)
 	}
@@ -138,6 +178,11 @@ type IndexEntry struct {
 	Tags []string `json:"tags"`
 	// Files are the files associated with the model.
 	Files []string `json:"files"`
+	// Sequence is the change sequence number of the latest change to the
+	// model's entry.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// Metadata describes the provenance and usage of the model.
+	Metadata types.Metadata `json:"metadata"`
 }
 
 func (e IndexEntry) HasTag(tag string) bool {
@@ -190,11 +235,8 @@ func (e IndexEntry) Tag(tag name.Tag) IndexEntry {
 	if e.hasTag(tag) {
 		return e
 	}
-	return IndexEntry{
-		ID:    e.ID,
-		Tags:  append(e.Tags, tag.String()),
-		Files: e.Files,
-	}
+	e.Tags = append(slices.Clone(e.Tags), tag.String())
+	return e
 }
 
 func (e IndexEntry) UnTag(tag name.Tag) IndexEntry {
@@ -209,9 +251,6 @@ func (e IndexEntry) UnTag(tag name.Tag) IndexEntry {
 		}
 		tags = append(tags, e.Tags[i])
 	}
-	return IndexEntry{
-		ID:    e.ID,
-		Tags:  tags,
-		Files: e.Files,
-	}
+	e.Tags = tags
+	return e
 }
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Licenses records the licenses that have been accepted by the user.
+type Licenses struct {
+	// Accepted are the digests of the accepted license layers.
+	Accepted []string `json:"accepted"`
+}
+
+// licensesPath returns the path to the licenses file
+func (s *LocalStore) licensesPath() string {
+	return filepath.Join(s.rootPath, "licenses.json")
+}
+
+// readLicenses reads the licenses file. A missing file means that no licenses
+// have been accepted.
+func (s *LocalStore) readLicenses() (Licenses, error) {
+	data, err := os.ReadFile(s.licensesPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return Licenses{}, nil
+	} else if err != nil {
+		return Licenses{}, fmt.Errorf("reading licenses file: %w", err)
+	}
+	var licenses Licenses
+	if err := json.Unmarshal(data, &licenses); err != nil {
+		return Licenses{}, fmt.Errorf("unmarshaling licenses: %w", err)
+	}
+	return licenses, nil
+}
+
+// LicensesAccepted reports whether all licenses with the given layer digests
+// have been accepted.
+func (s *LocalStore) LicensesAccepted(digests []v1.Hash) (bool, error) {
+	licenses, err := s.readLicenses()
+	if err != nil {
+		return false, err
+	}
+	for _, digest := range digests {
+		if !slices.Contains(licenses.Accepted, digest.String()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// AcceptLicenses records the acceptance of the licenses with the given layer
+// digests.
+func (s *LocalStore) AcceptLicenses(digests []v1.Hash) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	licenses, err := s.readLicenses()
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if !slices.Contains(licenses.Accepted, digest.String()) {
+			licenses.Accepted = append(licenses.Accepted, digest.String())
+		}
+	}
+	data, err := json.MarshalIndent(licenses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling licenses: %w", err)
+	}
+	if err := writeFile(s.licensesPath(), data); err != nil {
+		return fmt.Errorf("writing licenses file: %w", err)
+	}
+	return nil
+}
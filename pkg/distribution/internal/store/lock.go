@@ -0,0 +1,73 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockTimeout is how long to wait for another process to release the store
+// lock before giving up.
+var lockTimeout = 30 * time.Second
+
+const (
+	// lockFileName is the name of the file used to lock the store.
+	lockFileName = "store.lock"
+	// lockInitialBackoff and lockMaxBackoff bound the delay between attempts
+	// to acquire the store lock.
+	lockInitialBackoff = 10 * time.Millisecond
+	lockMaxBackoff     = 500 * time.Millisecond
+)
+
+// lockPath returns the path to the lock file
+func (s *LocalStore) lockPath() string {
+	return filepath.Join(s.rootPath, lockFileName)
+}
+
+// lock acquires the store lock, which serializes mutations of the index and
+// the finalization of blobs between goroutines and processes sharing the
+// store. The lock is advisory, so readers don't need to hold it; all files
+// are replaced atomically. The returned function releases the lock.
+func (s *LocalStore) lock() (func(), error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	s.mu.Lock()
+	if err := os.MkdirAll(s.rootPath, 0o755); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+	f, err := os.OpenFile(s.lockPath(), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("open store lock: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	backoff := lockInitialBackoff
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			s.mu.Unlock()
+			return nil, fmt.Errorf("lock store: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			s.mu.Unlock()
+			return nil, ErrStoreLocked
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, lockMaxBackoff)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		s.mu.Unlock()
+	}, nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// testDigest returns a digest derived from i.
+func testDigest(i int) v1.Hash {
+	sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+	return v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+}
+
+func TestLock(t *testing.T) {
+	rootPath := t.TempDir()
+	first, err := New(Options{RootPath: rootPath})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+
+	t.Run("concurrent mutations", func(t *testing.T) {
+		// Separate stores on the same root path behave like separate
+		// processes, since they hold the lock through separate files.
+		const writers = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s, err := New(Options{RootPath: rootPath})
+				if err != nil {
+					errs <- err
+					return
+				}
+				errs <- s.AcceptLicenses([]v1.Hash{testDigest(i)})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("AcceptLicenses failed: %v", err)
+			}
+		}
+
+		digests := make([]v1.Hash, writers)
+		for i := range digests {
+			digests[i] = testDigest(i)
+		}
+		accepted, err := first.LicensesAccepted(digests)
+		if err != nil {
+			t.Fatalf("LicensesAccepted failed: %v", err)
+		}
+		if !accepted {
+			t.Error("Expected all concurrently accepted licenses to be recorded")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+		lockTimeout = 50 * time.Millisecond
+
+		unlock, err := first.lock()
+		if err != nil {
+			t.Fatalf("lock failed: %v", err)
+		}
+		defer unlock()
+
+		second := &LocalStore{rootPath: rootPath}
+		if err := second.AcceptLicenses([]v1.Hash{testDigest(-1)}); !errors.Is(err, ErrStoreLocked) {
+			t.Fatalf("Expected ErrStoreLocked, got %v", err)
+		}
+	})
+}
@@ -21,6 +21,16 @@ func (s *LocalStore) manifestPath(hash v1.Hash) string {
 
 // WriteManifest writes the model's manifest to the store
 func (s *LocalStore) WriteManifest(hash v1.Hash, raw []byte) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.writeManifest(hash, raw)
+}
+
+// writeManifest implements WriteManifest. The caller must hold the store lock.
+func (s *LocalStore) writeManifest(hash v1.Hash, raw []byte) error {
 	manifest, err := v1.ParseManifest(bytes.NewReader(raw))
 	if err != nil {
 		return fmt.Errorf("parse manifest: %w", err)
@@ -44,7 +54,10 @@ func (s *LocalStore) WriteManifest(hash v1.Hash, raw []byte) error {
 		return fmt.Errorf("reading models: %w", err)
 	}
 
-	if err := s.writeIndex(idx.Add(newEntryForManifest(hash, manifest))); err != nil {
+	entry := newEntryForManifest(hash, manifest)
+	entry.Metadata = s.metadataForManifest(manifest)
+	entry.Metadata.Pulled = now()
+	if err := s.writeIndex(idx.Add(entry)); err != nil {
 		// Best effort rollback to avoid leaving an orphaned manifest on disk.
 		if removeErr := s.removeManifest(hash); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
 			return errors.Join(
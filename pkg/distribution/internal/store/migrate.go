@@ -0,0 +1,122 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// indexVersion is the current version of the index format.
+const indexVersion = 2
+
+// indexMigrations migrate the index from the version they're keyed by to the
+// next version.
+var indexMigrations = map[int]func(s *LocalStore, index Index) (Index, error){
+	1: migrateIndexV1,
+}
+
+// migrateIndex migrates the index to the current format, if necessary. The
+// caller must hold the store lock.
+func (s *LocalStore) migrateIndex() error {
+	index, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models index: %w", err)
+	}
+	version := max(index.Version, 1)
+	if version >= indexVersion {
+		return nil
+	}
+	for ; version < indexVersion; version++ {
+		migrate, ok := indexMigrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from index version %d", version)
+		}
+		if index, err = migrate(s, index); err != nil {
+			return fmt.Errorf("migrating index from version %d: %w", version, err)
+		}
+	}
+	// Migrations don't change the models, so change cursors remain valid.
+	if err := s.writeIndexFile(index); err != nil {
+		return fmt.Errorf("writing models index: %w", err)
+	}
+	return nil
+}
+
+// migrateIndexV1 adds metadata to the entries of a version 1 index. The time
+// a model was pulled is approximated by the modification time of its
+// manifest, and its source is unknown.
+func migrateIndexV1(s *LocalStore, index Index) (Index, error) {
+	for i, entry := range index.Models {
+		digest, err := v1.NewHash(entry.ID)
+		if err != nil {
+			continue
+		}
+		raw, err := os.ReadFile(s.manifestPath(digest))
+		if err != nil {
+			// Leave broken models to Repair.
+			continue
+		}
+		manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+		if err != nil {
+			continue
+		}
+		entry.Metadata = s.metadataForManifest(manifest)
+		if info, err := os.Stat(s.manifestPath(digest)); err == nil {
+			pulled := info.ModTime().UTC()
+			entry.Metadata.Pulled = &pulled
+		}
+		index.Models[i] = entry
+	}
+	return index, nil
+}
+
+// metadataForManifest derives the metadata of a model from its manifest and
+// config, which must be in the store.
+func (s *LocalStore) metadataForManifest(manifest *v1.Manifest) types.Metadata {
+	metadata := types.Metadata{Size: manifest.Config.Size}
+	for _, layer := range manifest.Layers {
+		metadata.Size += layer.Size
+	}
+	if path, err := s.blobPath(manifest.Config.Digest); err == nil {
+		if raw, err := os.ReadFile(path); err == nil {
+			var cf types.ConfigFile
+			if json.Unmarshal(raw, &cf) == nil && cf.Descriptor.Created != nil {
+				created := cf.Descriptor.Created.UTC()
+				metadata.Created = &created
+			}
+		}
+	}
+	return metadata
+}
+
+// UpdateMetadata updates the metadata of the model with the given reference.
+func (s *LocalStore) UpdateMetadata(reference string, update func(*types.Metadata)) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models index: %w", err)
+	}
+	_, i, ok := index.Find(reference)
+	if !ok {
+		return ErrModelNotFound
+	}
+	update(&index.Models[i].Metadata)
+	return s.writeIndex(index)
+}
+
+// now returns the current time for metadata.
+func now() *time.Time {
+	t := time.Now().UTC()
+	return &t
+}
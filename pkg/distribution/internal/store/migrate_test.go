@@ -0,0 +1,109 @@
+package store_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+func TestIndexMetadata(t *testing.T) {
+	rootPath := t.TempDir()
+	s, err := store.New(store.Options{RootPath: rootPath})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+	mdl := newTestModel(t)
+	if err := s.Write(mdl, []string{"metadata-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	manifest, err := mdl.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest failed: %v", err)
+	}
+	expectedSize := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		expectedSize += layer.Size
+	}
+
+	checkMetadata := func(t *testing.T, s *store.LocalStore) types.Metadata {
+		t.Helper()
+		model, err := s.Read("metadata-model:latest")
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		metadata := model.Metadata()
+		if metadata.Size != expectedSize {
+			t.Errorf("Expected size %d, got %d", expectedSize, metadata.Size)
+		}
+		if metadata.Pulled == nil {
+			t.Error("Expected pulled time")
+		}
+		if metadata.Created == nil {
+			t.Error("Expected created time")
+		}
+		return metadata
+	}
+
+	t.Run("written", func(t *testing.T) {
+		checkMetadata(t, s)
+	})
+
+	t.Run("updated and preserved by tagging", func(t *testing.T) {
+		if err := s.UpdateMetadata("metadata-model:latest", func(m *types.Metadata) {
+			m.Source = "registry.example.com/metadata-model:latest"
+		}); err != nil {
+			t.Fatalf("UpdateMetadata failed: %v", err)
+		}
+		if err := s.AddTags("metadata-model:latest", []string{"metadata-model:other"}); err != nil {
+			t.Fatalf("AddTags failed: %v", err)
+		}
+		if metadata := checkMetadata(t, s); metadata.Source != "registry.example.com/metadata-model:latest" {
+			t.Errorf("Expected source to be preserved, got %q", metadata.Source)
+		}
+	})
+
+	t.Run("migrated from version 1", func(t *testing.T) {
+		// Rewrite the index in the version 1 format, without metadata.
+		indexPath := filepath.Join(rootPath, "models.json")
+		data, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("Reading index failed: %v", err)
+		}
+		var index map[string]any
+		if err := json.Unmarshal(data, &index); err != nil {
+			t.Fatalf("Unmarshaling index failed: %v", err)
+		}
+		delete(index, "version")
+		for _, model := range index["models"].([]any) {
+			delete(model.(map[string]any), "metadata")
+		}
+		if data, err = json.Marshal(index); err != nil {
+			t.Fatalf("Marshaling index failed: %v", err)
+		}
+		if err := os.WriteFile(indexPath, data, 0644); err != nil {
+			t.Fatalf("Writing index failed: %v", err)
+		}
+
+		migrated, err := store.New(store.Options{RootPath: rootPath})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		checkMetadata(t, migrated)
+
+		data, err = os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatalf("Reading index failed: %v", err)
+		}
+		var migratedIndex store.Index
+		if err := json.Unmarshal(data, &migratedIndex); err != nil {
+			t.Fatalf("Unmarshaling index failed: %v", err)
+		}
+		if migratedIndex.Version != 2 {
+			t.Errorf("Expected index version 2, got %d", migratedIndex.Version)
+		}
+	})
+}
@@ -22,9 +22,10 @@ type Model struct {
 	rawConfigFile []byte
 	layers        []v1.Layer
 	tags          []string
+	metadata      mdtypes.Metadata
 }
 
-func (s *LocalStore) newModel(digest v1.Hash, tags []string) (*Model, error) {
+func (s *LocalStore) newModel(digest v1.Hash, tags []string, metadata mdtypes.Metadata) (*Model, error) {
 	rawManifest, err := os.ReadFile(s.manifestPath(digest))
 	if err != nil {
 		return nil, fmt.Errorf("read manifest: %w", err)
@@ -62,6 +63,7 @@ func (s *LocalStore) newModel(digest v1.Hash, tags []string) (*Model, error) {
 		rawConfigFile: rawConfigFile,
 		tags:          tags,
 		layers:        layers,
+		metadata:      metadata,
 	}, err
 }
 
@@ -130,6 +132,38 @@ func (m *Model) ChatTemplatePath() (string, error) {
 	return mdpartial.ChatTemplatePath(m)
 }
 
+func (m *Model) LoRAAdapterPaths() ([]string, error) {
+	return mdpartial.LoRAAdapterPaths(m)
+}
+
+func (m *Model) WhisperPath() (string, error) {
+	return mdpartial.WhisperPath(m)
+}
+
+func (m *Model) VoicePath() (string, error) {
+	return mdpartial.VoicePath(m)
+}
+
+func (m *Model) VoiceConfigPath() (string, error) {
+	return mdpartial.VoiceConfigPath(m)
+}
+
+func (m *Model) DiffusionPath() (string, error) {
+	return mdpartial.DiffusionPath(m)
+}
+
+func (m *Model) ONNXPath() (string, error) {
+	return mdpartial.ONNXPath(m)
+}
+
+func (m *Model) LicensePaths() ([]string, error) {
+	return mdpartial.LicensePaths(m)
+}
+
+func (m *Model) Licenses() ([]string, error) {
+	return mdpartial.Licenses(m)
+}
+
 func (m *Model) SafetensorsPaths() ([]string, error) {
 	return mdpartial.SafetensorsPaths(m)
 }
@@ -142,6 +176,14 @@ func (m *Model) Tags() []string {
 	return m.tags
 }
 
+func (m *Model) Metadata() mdtypes.Metadata {
+	return m.metadata
+}
+
+func (m *Model) Annotations() map[string]string {
+	return m.manifest.Annotations
+}
+
 func (m *Model) ID() (string, error) {
 	return mdpartial.ID(m)
 }
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	quarantineDir = "quarantine"
+)
+
+// RepairReport describes the changes made by Repair.
+type RepairReport struct {
+	// IndexRebuilt indicates that the index was unreadable and was rebuilt
+	// from the manifests in the store. Tags are lost when this happens.
+	IndexRebuilt bool `json:"index_rebuilt"`
+	// RemovedModels are the IDs of the models removed from the store because
+	// their manifest or blobs were missing or corrupt.
+	RemovedModels []string `json:"removed_models"`
+	// QuarantinedFiles are the paths, relative to the store root, of the
+	// corrupt files moved to the quarantine directory.
+	QuarantinedFiles []string `json:"quarantined_files"`
+}
+
+// quarantine moves a corrupt file of the store to the quarantine directory,
+// where it remains available for inspection until the store is reset.
+func (s *LocalStore) quarantine(path string) (string, error) {
+	rel, err := filepath.Rel(s.rootPath, path)
+	if err != nil {
+		return "", fmt.Errorf("get relative path: %w", err)
+	}
+	dest := filepath.Join(s.rootPath, quarantineDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", fmt.Errorf("create quarantine directory: %w", err)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return "", fmt.Errorf("quarantine %s: %w", rel, err)
+	}
+	return rel, nil
+}
+
+// rebuildIndex creates an index containing an untagged entry for each valid
+// manifest in the store.
+func (s *LocalStore) rebuildIndex() (Index, error) {
+	index := Index{Models: []IndexEntry{}}
+	for algorithm := range allowedAlgorithms {
+		dir := filepath.Join(s.rootPath, manifestsDir, algorithm)
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return Index{}, fmt.Errorf("reading manifests directory: %w", err)
+		}
+		for _, entry := range entries {
+			digest := v1.Hash{Algorithm: algorithm, Hex: entry.Name()}
+			if validateHash(digest) != nil {
+				continue
+			}
+			raw, err := os.ReadFile(s.manifestPath(digest))
+			if err != nil {
+				continue
+			}
+			manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			entry := newEntryForManifest(digest, manifest)
+			entry.Metadata = s.metadataForManifest(manifest)
+			index = index.Add(entry)
+		}
+	}
+	return index, nil
+}
+
+// checkManifest reads the manifest of the model with the given ID and checks
+// that it matches its digest.
+func (s *LocalStore) checkManifest(digest v1.Hash) (*v1.Manifest, error) {
+	raw, err := os.ReadFile(s.manifestPath(digest))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	w, err := newDigestWriter(digest, int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+	w.Write(raw)
+	if err := w.verify(); err != nil {
+		return nil, fmt.Errorf("verify manifest: %w", err)
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// repairIndex reads the index, quarantining and rebuilding it if it's
+// unreadable.
+func (s *LocalStore) repairIndex(report *RepairReport) (Index, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return Index{}, err
+	}
+	defer unlock()
+
+	index, err := s.readIndex()
+	if err == nil {
+		return index, nil
+	}
+	rel, err := s.quarantine(s.indexPath())
+	if err != nil {
+		return Index{}, err
+	}
+	report.QuarantinedFiles = append(report.QuarantinedFiles, rel)
+	if index, err = s.rebuildIndex(); err != nil {
+		return Index{}, fmt.Errorf("rebuilding models index: %w", err)
+	}
+	// The rebuilt index starts a new lifetime so that clients resync.
+	if err := s.restoreIndex(index); err != nil {
+		return Index{}, fmt.Errorf("writing models index: %w", err)
+	}
+	report.IndexRebuilt = true
+	return index, nil
+}
+
+// Repair scans the index, manifests and blobs of the store and removes the
+// models whose manifest or blobs are missing or corrupt, so that they can be
+// pulled again. Corrupt files are moved to a quarantine directory. If the
+// index itself is unreadable, it is quarantined and rebuilt from the
+// manifests in the store.
+func (s *LocalStore) Repair() (RepairReport, error) {
+	report := RepairReport{RemovedModels: []string{}, QuarantinedFiles: []string{}}
+
+	index, err := s.repairIndex(&report)
+	if err != nil {
+		return report, err
+	}
+
+	// Blobs are shared between models, so only check each blob once.
+	corrupt := make(map[string]bool)
+	checked := make(map[string]bool)
+	checkBlob := func(desc v1.Descriptor) error {
+		file := desc.Digest.String()
+		if checked[file] {
+			if corrupt[file] {
+				return fmt.Errorf("blob %s is corrupt", file)
+			}
+			return nil
+		}
+		checked[file] = true
+		err := s.verifyBlob(desc.Digest, desc.Size)
+		if errors.Is(err, ErrDigestMismatch) {
+			corrupt[file] = true
+			path, pathErr := s.blobPath(desc.Digest)
+			if pathErr != nil {
+				return pathErr
+			}
+			rel, qErr := s.quarantine(path)
+			if qErr != nil {
+				return errors.Join(err, qErr)
+			}
+			report.QuarantinedFiles = append(report.QuarantinedFiles, rel)
+		} else if err != nil {
+			corrupt[file] = true
+		}
+		return err
+	}
+
+	var broken []IndexEntry
+	for _, entry := range index.Models {
+		digest, err := v1.NewHash(entry.ID)
+		if err != nil {
+			broken = append(broken, entry)
+			continue
+		}
+		manifest, err := s.checkManifest(digest)
+		if errors.Is(err, ErrDigestMismatch) {
+			if rel, qErr := s.quarantine(s.manifestPath(digest)); qErr == nil {
+				report.QuarantinedFiles = append(report.QuarantinedFiles, rel)
+			}
+		}
+		if err != nil {
+			broken = append(broken, entry)
+			continue
+		}
+		descriptors := append([]v1.Descriptor{manifest.Config}, manifest.Layers...)
+		for _, desc := range descriptors {
+			if err := checkBlob(desc); err != nil {
+				broken = append(broken, entry)
+				break
+			}
+		}
+	}
+	if len(broken) == 0 {
+		return report, nil
+	}
+
+	// Blobs are verified without holding the store lock, since that can take a
+	// while. Apply the removals to the current index while holding it.
+	unlock, err := s.lock()
+	if err != nil {
+		return report, err
+	}
+	defer unlock()
+	if index, err = s.readIndex(); err != nil {
+		return report, fmt.Errorf("reading models index: %w", err)
+	}
+
+	// Remove the broken models along with any blobs that are no longer
+	// referenced by the remaining models.
+	for _, entry := range broken {
+		index = index.Remove(entry.ID)
+		report.RemovedModels = append(report.RemovedModels, entry.ID)
+		if digest, err := v1.NewHash(entry.ID); err == nil {
+			_ = s.removeManifest(digest)
+			_ = s.removeBundle(digest)
+		}
+	}
+	referenced := make(map[string]bool)
+	for _, entry := range index.Models {
+		for _, file := range entry.Files {
+			referenced[file] = true
+		}
+	}
+	for _, entry := range broken {
+		for _, file := range entry.Files {
+			if referenced[file] || corrupt[file] {
+				continue
+			}
+			if hash, err := v1.NewHash(file); err == nil {
+				_ = s.removeBlob(hash)
+			}
+		}
+	}
+	if err := s.writeIndex(index); err != nil {
+		return report, fmt.Errorf("writing models index: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,113 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+func TestRepair(t *testing.T) {
+	t.Run("healthy store", func(t *testing.T) {
+		s, err := store.New(store.Options{RootPath: t.TempDir()})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		if err := s.Write(newTestModel(t), []string{"repair-model:latest"}, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+
+		report, err := s.Repair()
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		if report.IndexRebuilt || len(report.RemovedModels) != 0 || len(report.QuarantinedFiles) != 0 {
+			t.Errorf("Expected no repairs, got %+v", report)
+		}
+		if _, err := s.Read("repair-model:latest"); err != nil {
+			t.Errorf("Expected model to remain readable, got %v", err)
+		}
+	})
+
+	t.Run("truncated blob", func(t *testing.T) {
+		rootPath := t.TempDir()
+		s, err := store.New(store.Options{RootPath: rootPath})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		mdl := newTestModel(t)
+		if err := s.Write(mdl, []string{"repair-model:latest"}, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		layers, err := mdl.Layers()
+		if err != nil {
+			t.Fatalf("Layers failed: %v", err)
+		}
+		digest, err := layers[0].Digest()
+		if err != nil {
+			t.Fatalf("Digest failed: %v", err)
+		}
+		blobPath := filepath.Join(rootPath, "blobs", digest.Algorithm, digest.Hex)
+		if err := os.Truncate(blobPath, 10); err != nil {
+			t.Fatalf("Truncate failed: %v", err)
+		}
+
+		report, err := s.Repair()
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		if len(report.RemovedModels) != 1 {
+			t.Errorf("Expected one removed model, got %v", report.RemovedModels)
+		}
+		quarantined := filepath.Join("blobs", digest.Algorithm, digest.Hex)
+		if len(report.QuarantinedFiles) != 1 || report.QuarantinedFiles[0] != quarantined {
+			t.Errorf("Expected quarantined blob %s, got %v", quarantined, report.QuarantinedFiles)
+		}
+		if _, err := os.Stat(filepath.Join(rootPath, "quarantine", quarantined)); err != nil {
+			t.Errorf("Expected blob in quarantine: %v", err)
+		}
+		if _, err := s.Read("repair-model:latest"); !errors.Is(err, store.ErrModelNotFound) {
+			t.Errorf("Expected ErrModelNotFound after repair, got %v", err)
+		}
+
+		// The model can be written again.
+		if err := s.Write(mdl, []string{"repair-model:latest"}, nil); err != nil {
+			t.Fatalf("Write after repair failed: %v", err)
+		}
+		if err := s.Verify("repair-model:latest"); err != nil {
+			t.Errorf("Expected rewritten model to verify, got %v", err)
+		}
+	})
+
+	t.Run("unreadable index", func(t *testing.T) {
+		rootPath := t.TempDir()
+		s, err := store.New(store.Options{RootPath: rootPath})
+		if err != nil {
+			t.Fatalf("Create store failed: %v", err)
+		}
+		mdl := newTestModel(t)
+		if err := s.Write(mdl, []string{"repair-model:latest"}, nil); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(rootPath, "models.json"), []byte("{truncated"), 0644); err != nil {
+			t.Fatalf("Writing index failed: %v", err)
+		}
+
+		report, err := s.Repair()
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		if !report.IndexRebuilt {
+			t.Error("Expected index to be rebuilt")
+		}
+		digest, err := mdl.Digest()
+		if err != nil {
+			t.Fatalf("Digest failed: %v", err)
+		}
+		if _, err := s.Read(digest.String()); err != nil {
+			t.Errorf("Expected model to be readable by ID after rebuild, got %v", err)
+		}
+	})
+}
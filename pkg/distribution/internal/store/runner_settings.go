@@ -0,0 +1,97 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// RunnerSettings are the settings of the runners of a model that are kept in
+// the store, so that they survive restarts.
+type RunnerSettings struct {
+	// Backend is the name of the backend the settings apply to.
+	Backend string `json:"backend"`
+	// Model is the ID of the model the settings apply to.
+	Model string `json:"model"`
+	// Mode is the mode of the runners the settings apply to.
+	Mode string `json:"mode"`
+	// ContextSize is the context size of the runners.
+	ContextSize int64 `json:"context-size,omitempty"`
+	// ParallelSlots is the number of requests each runner serves in parallel.
+	ParallelSlots int `json:"parallel-slots,omitempty"`
+	// BatchSize is the number of tokens each runner processes in a batch.
+	BatchSize int `json:"batch-size,omitempty"`
+}
+
+// empty reports whether the settings don't set anything.
+func (s RunnerSettings) empty() bool {
+	return s.ContextSize <= 0 && s.ParallelSlots == 0 && s.BatchSize == 0
+}
+
+// runnerSettingsPath returns the path to the runner settings file
+func (s *LocalStore) runnerSettingsPath() string {
+	return filepath.Join(s.rootPath, "runner-settings.json")
+}
+
+// readRunnerSettings reads the runner settings file. A missing file means that
+// no settings are kept.
+func (s *LocalStore) readRunnerSettings() ([]RunnerSettings, error) {
+	data, err := os.ReadFile(s.runnerSettingsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading runner settings file: %w", err)
+	}
+	var settings []RunnerSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("unmarshaling runner settings: %w", err)
+	}
+	return settings, nil
+}
+
+// RunnerSettings returns the runner settings kept in the store.
+func (s *LocalStore) RunnerSettings() ([]RunnerSettings, error) {
+	return s.readRunnerSettings()
+}
+
+// SetRunnerSettings keeps the runner settings of a model, replacing those
+// kept for the same backend, model and mode. Settings that don't set anything
+// remove those kept instead.
+func (s *LocalStore) SetRunnerSettings(settings RunnerSettings) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	kept, err := s.readRunnerSettings()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(kept, func(k RunnerSettings) bool {
+		return k.Backend == settings.Backend && k.Model == settings.Model && k.Mode == settings.Mode
+	})
+	switch {
+	case i >= 0 && kept[i] == settings:
+		return nil
+	case i >= 0 && settings.empty():
+		kept = slices.Delete(kept, i, i+1)
+	case i >= 0:
+		kept[i] = settings
+	case settings.empty():
+		return nil
+	default:
+		kept = append(kept, settings)
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling runner settings: %w", err)
+	}
+	if err := writeFile(s.runnerSettingsPath(), data); err != nil {
+		return fmt.Errorf("writing runner settings file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package store_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+func TestRunnerSettings(t *testing.T) {
+	rootPath := filepath.Join(t.TempDir(), "settings-store")
+	s, err := store.New(store.Options{RootPath: rootPath})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	completion := store.RunnerSettings{Backend: "llama.cpp", Model: "sha256:abc", Mode: "completion", ContextSize: 8192, ParallelSlots: 4}
+	embedding := store.RunnerSettings{Backend: "llama.cpp", Model: "sha256:abc", Mode: "embedding", BatchSize: 2048}
+	for _, settings := range []store.RunnerSettings{completion, embedding} {
+		if err := s.SetRunnerSettings(settings); err != nil {
+			t.Fatalf("SetRunnerSettings failed: %v", err)
+		}
+	}
+
+	// Settings for the same backend, model and mode replace those kept, and
+	// settings that don't set anything remove them.
+	completion.ContextSize = 16384
+	if err := s.SetRunnerSettings(completion); err != nil {
+		t.Fatalf("SetRunnerSettings failed: %v", err)
+	}
+	if err := s.SetRunnerSettings(store.RunnerSettings{Backend: "llama.cpp", Model: "sha256:abc", Mode: "embedding", ContextSize: -1}); err != nil {
+		t.Fatalf("SetRunnerSettings failed: %v", err)
+	}
+
+	// The settings survive reopening the store.
+	s, err = store.New(store.Options{RootPath: rootPath})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	settings, err := s.RunnerSettings()
+	if err != nil {
+		t.Fatalf("RunnerSettings failed: %v", err)
+	}
+	if !slices.Equal(settings, []store.RunnerSettings{completion}) {
+		t.Errorf("Expected %+v to be kept, got %+v", completion, settings)
+	}
+}
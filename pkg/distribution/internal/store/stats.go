@@ -0,0 +1,119 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// maxTopSharedBlobs is the number of shared blobs reported in DedupStats.TopSharedBlobs.
+const maxTopSharedBlobs = 10
+
+// BlobStats describes a single blob in the store.
+type BlobStats struct {
+	// Digest is the blob digest.
+	Digest string `json:"digest"`
+	// Size is the size of the blob on disk in bytes.
+	Size int64 `json:"size"`
+	// References is the number of models referencing the blob.
+	References int `json:"references"`
+	// Models are the IDs of the models referencing the blob.
+	Models []string `json:"models,omitempty"`
+}
+
+// DedupStats summarizes how blobs are shared between models in the store.
+type DedupStats struct {
+	// Models is the number of models in the store.
+	Models int `json:"models"`
+	// Blobs is the number of distinct blobs referenced by models.
+	Blobs int `json:"blobs"`
+	// LogicalBytes is the sum of the sizes of all models.
+	LogicalBytes int64 `json:"logical_bytes"`
+	// PhysicalBytes is the number of bytes used by distinct blobs on disk.
+	PhysicalBytes int64 `json:"physical_bytes"`
+	// SavedBytes is the number of bytes saved by sharing blobs between models.
+	SavedBytes int64 `json:"saved_bytes"`
+	// BlobReferences contains per-blob reference counts, sorted by digest.
+	BlobReferences []BlobStats `json:"blob_references"`
+	// TopSharedBlobs contains the shared blobs saving the most space.
+	TopSharedBlobs []BlobStats `json:"top_shared_blobs"`
+}
+
+// DedupStats computes blob deduplication statistics for the store.
+func (s *LocalStore) DedupStats() (DedupStats, error) {
+	idx, err := s.readIndex()
+	if err != nil {
+		return DedupStats{}, fmt.Errorf("reading models file: %w", err)
+	}
+
+	blobs := make(map[string]*BlobStats)
+	for _, model := range idx.Models {
+		for _, file := range model.Files {
+			blob, ok := blobs[file]
+			if !ok {
+				size, err := s.blobSize(file)
+				if err != nil {
+					return DedupStats{}, err
+				}
+				blob = &BlobStats{Digest: file, Size: size}
+				blobs[file] = blob
+			}
+			blob.References++
+			blob.Models = append(blob.Models, model.ID)
+		}
+	}
+
+	stats := DedupStats{
+		Models:         len(idx.Models),
+		Blobs:          len(blobs),
+		BlobReferences: make([]BlobStats, 0, len(blobs)),
+		TopSharedBlobs: []BlobStats{},
+	}
+	for _, blob := range blobs {
+		stats.LogicalBytes += blob.Size * int64(blob.References)
+		stats.PhysicalBytes += blob.Size
+		stats.BlobReferences = append(stats.BlobReferences, *blob)
+	}
+	stats.SavedBytes = stats.LogicalBytes - stats.PhysicalBytes
+
+	sort.Slice(stats.BlobReferences, func(i, j int) bool {
+		return stats.BlobReferences[i].Digest < stats.BlobReferences[j].Digest
+	})
+
+	for _, blob := range stats.BlobReferences {
+		if blob.References > 1 {
+			stats.TopSharedBlobs = append(stats.TopSharedBlobs, blob)
+		}
+	}
+	sort.SliceStable(stats.TopSharedBlobs, func(i, j int) bool {
+		a, b := stats.TopSharedBlobs[i], stats.TopSharedBlobs[j]
+		return a.Size*int64(a.References-1) > b.Size*int64(b.References-1)
+	})
+	if len(stats.TopSharedBlobs) > maxTopSharedBlobs {
+		stats.TopSharedBlobs = stats.TopSharedBlobs[:maxTopSharedBlobs]
+	}
+
+	return stats, nil
+}
+
+// blobSize returns the on-disk size of the blob with the given digest, or zero
+// if the blob is missing.
+func (s *LocalStore) blobSize(digest string) (int64, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return 0, fmt.Errorf("parse blob hash %q: %w", digest, err)
+	}
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return 0, fmt.Errorf("get blob path: %w", err)
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("stat blob %q: %w", digest, err)
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/mutate"
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+func TestDedupStats(t *testing.T) {
+	s, err := store.New(store.Options{
+		RootPath: filepath.Join(t.TempDir(), "dedup-store"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	t.Run("EmptyStore", func(t *testing.T) {
+		stats, err := s.DedupStats()
+		if err != nil {
+			t.Fatalf("DedupStats failed: %v", err)
+		}
+		if stats.Models != 0 || stats.LogicalBytes != 0 || stats.PhysicalBytes != 0 {
+			t.Fatalf("Expected empty stats, got %+v", stats)
+		}
+	})
+
+	// Write a model and a variant that only differs in its config, so that
+	// both reference the same GGUF and license blobs.
+	base := newTestModel(t)
+	if err := s.Write(base, []string{"dedup-base:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	variant := mutate.ContextSize(base, 2048)
+	if err := s.Write(variant, []string{"dedup-variant:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	layers, err := base.Layers()
+	if err != nil {
+		t.Fatalf("Failed to get layers: %v", err)
+	}
+	var sharedBytes int64
+	for _, layer := range layers {
+		size, err := layer.Size()
+		if err != nil {
+			t.Fatalf("Failed to get layer size: %v", err)
+		}
+		sharedBytes += size
+	}
+	ggufDigest, err := layers[0].Digest()
+	if err != nil {
+		t.Fatalf("Failed to get layer digest: %v", err)
+	}
+
+	stats, err := s.DedupStats()
+	if err != nil {
+		t.Fatalf("DedupStats failed: %v", err)
+	}
+	if stats.Models != 2 {
+		t.Errorf("Expected 2 models, got %d", stats.Models)
+	}
+	// Two shared layers plus one config blob per model
+	if stats.Blobs != 4 {
+		t.Errorf("Expected 4 blobs, got %d", stats.Blobs)
+	}
+	if stats.SavedBytes != sharedBytes {
+		t.Errorf("Expected %d saved bytes, got %d", sharedBytes, stats.SavedBytes)
+	}
+	if stats.LogicalBytes-stats.PhysicalBytes != stats.SavedBytes {
+		t.Errorf("Inconsistent stats: %+v", stats)
+	}
+	if len(stats.TopSharedBlobs) != 2 {
+		t.Fatalf("Expected 2 shared blobs, got %d", len(stats.TopSharedBlobs))
+	}
+	if stats.TopSharedBlobs[0].Digest != ggufDigest.String() {
+		t.Errorf("Expected GGUF blob to be the top shared blob, got %s", stats.TopSharedBlobs[0].Digest)
+	}
+	if stats.TopSharedBlobs[0].References != 2 {
+		t.Errorf("Expected 2 references, got %d", stats.TopSharedBlobs[0].References)
+	}
+}
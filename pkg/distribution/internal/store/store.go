@@ -1,17 +1,24 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.opentelemetry.io/otel"
 
 	"github.com/docker/model-runner/pkg/distribution/internal/progress"
 )
 
+// tracer traces the blob writes of the store.
+var tracer = otel.Tracer("github.com/docker/model-runner/pkg/distribution/internal/store")
+
 const (
 	// CurrentVersion is the current version of the store layout
 	CurrentVersion = "1.0.0"
@@ -20,6 +27,20 @@ const (
 // LocalStore implements the Store interface for local storage
 type LocalStore struct {
 	rootPath string
+	// readOnly indicates that the store is never modified, so that it can be
+	// shared, for example on a read-only network file system.
+	readOnly bool
+	// blobCachePath is the directory of the blob cache shared with other
+	// stores, if any.
+	blobCachePath string
+	// mu serializes the acquisition of the store lock within the process.
+	mu sync.Mutex
+	// bundles caches the runtime bundles of the store.
+	bundles bundleCache
+	// hardlinkBundles requires the model files of bundles to be hardlinks.
+	hardlinkBundles bool
+	// generation counts the writes of the index by this process.
+	generation atomic.Uint64
 }
 
 // RootPath returns the root path of the store
@@ -27,21 +48,64 @@ func (s *LocalStore) RootPath() string {
 	return s.rootPath
 }
 
+// Generation returns a counter of the changes this process made to the models,
+// tags and aliases of the store, so that callers can cache what they derive
+// from them until it changes.
+func (s *LocalStore) Generation() uint64 {
+	return s.generation.Load()
+}
+
+// ReadOnly reports whether the store is read-only.
+func (s *LocalStore) ReadOnly() bool {
+	return s.readOnly
+}
+
 // Options represents options for creating a store
 type Options struct {
 	RootPath string
+	// ReadOnly opens an existing store without initializing, migrating or
+	// locking it. Any modification of its models fails with ErrReadOnly.
+	ReadOnly bool
+	// BlobCachePath is an optional directory shared by stores on the same
+	// file system. Blobs written to the store are hardlinked into it, and
+	// blobs already in it are hardlinked into the store instead of being
+	// written again.
+	BlobCachePath string
+	// HardlinkBundles requires the model files of runtime bundles to be
+	// hardlinked from the store's blobs, failing to create bundles on
+	// another file system rather than falling back to symlinks.
+	HardlinkBundles bool
 }
 
 // New creates a new LocalStore
 func New(opts Options) (*LocalStore, error) {
 	store := &LocalStore{
 		rootPath: opts.RootPath,
+		readOnly: opts.ReadOnly,
+
+		blobCachePath: opts.BlobCachePath,
+
+		hardlinkBundles: opts.HardlinkBundles,
+	}
+	if store.readOnly {
+		if _, err := store.readLayout(); err != nil {
+			return nil, fmt.Errorf("opening read-only store: %w", err)
+		}
+		return store, nil
 	}
 
 	// Initialize store if it doesn't exist
+	unlock, err := store.lock()
+	if err != nil {
+		return nil, fmt.Errorf("initializing store: %w", err)
+	}
+	defer unlock()
 	if err := store.initialize(); err != nil {
 		return nil, fmt.Errorf("initializing store: %w", err)
 	}
+	if err := store.migrateIndex(); err != nil {
+		return nil, fmt.Errorf("initializing store: %w", err)
+	}
 
 	return store, nil
 }
@@ -50,12 +114,22 @@ func New(opts Options) (*LocalStore, error) {
 // It removes all files and subdirectories within the store's root path, but preserves the root directory itself.
 // This allows the method to work correctly when the store directory is a mounted volume (e.g., in Docker Engine).
 func (s *LocalStore) Reset() error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	entries, err := os.ReadDir(s.rootPath)
 	if err != nil {
 		return fmt.Errorf("reading store directory: %w", err)
 	}
 
 	for _, entry := range entries {
+		// Keep the lock file, which other processes may be waiting on.
+		if entry.Name() == lockFileName {
+			continue
+		}
 		entryPath := filepath.Join(s.rootPath, entry.Name())
 		if err := os.RemoveAll(entryPath); err != nil {
 			return fmt.Errorf("removing %s: %w", entryPath, err)
@@ -65,7 +139,8 @@ func (s *LocalStore) Reset() error {
 	return s.initialize()
 }
 
-// initialize creates the store directory structure if it doesn't exist
+// initialize creates the store directory structure if it doesn't exist. The
+// caller must hold the store lock.
 func (s *LocalStore) initialize() error {
 	// Check if layout.json exists, create if not
 	if err := s.ensureLayout(); err != nil {
@@ -95,6 +170,12 @@ func (s *LocalStore) List() ([]IndexEntry, error) {
 
 // Delete deletes a model by reference
 func (s *LocalStore) Delete(ref string) (string, []string, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return "", nil, err
+	}
+	defer unlock()
+
 	idx, err := s.readIndex()
 	if err != nil {
 		return "", nil, fmt.Errorf("reading models file: %w", err)
@@ -153,6 +234,16 @@ func (s *LocalStore) Delete(ref string) (string, []string, error) {
 
 // AddTags adds tags to an existing model
 func (s *LocalStore) AddTags(ref string, newTags []string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.addTags(ref, newTags)
+}
+
+// addTags implements AddTags. The caller must hold the store lock.
+func (s *LocalStore) addTags(ref string, newTags []string) error {
 	index, err := s.readIndex()
 	if err != nil {
 		return fmt.Errorf("reading models file: %w", err)
@@ -169,6 +260,12 @@ func (s *LocalStore) AddTags(ref string, newTags []string) error {
 
 // RemoveTags removes tags from models
 func (s *LocalStore) RemoveTags(tags []string) ([]string, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	index, err := s.readIndex()
 	if err != nil {
 		return nil, fmt.Errorf("reading modelss index: %w", err)
@@ -200,12 +297,16 @@ func (s *LocalStore) Version() string {
 }
 
 // Write writes a model to the store
-func (s *LocalStore) Write(mdl v1.Image, tags []string, w io.Writer) (err error) {
-	initialIndex, err := s.readIndex()
-	if err != nil {
-		return fmt.Errorf("reading models index: %w", err)
-	}
+func (s *LocalStore) Write(mdl v1.Image, tags []string, w io.Writer) error {
+	return s.WriteContext(context.Background(), mdl, tags, w)
+}
 
+// WriteContext is like Write, but traces the fetch and verification of each
+// blob as spans of the trace in ctx.
+func (s *LocalStore) WriteContext(ctx context.Context, mdl v1.Image, tags []string, w io.Writer) (err error) {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	type cleanupFunc func() error
 	var cleanups []cleanupFunc
 	success := false
@@ -270,7 +371,7 @@ func (s *LocalStore) Write(mdl v1.Image, tags []string, w io.Writer) (err error)
 			progressChan = pr.Updates()
 		}
 
-		created, diffID, err := s.writeLayer(layer, progressChan)
+		created, diffID, err := s.writeLayer(ctx, layer, progressChan)
 
 		if progressChan != nil {
 			close(progressChan)
@@ -305,40 +406,8 @@ func (s *LocalStore) Write(mdl v1.Image, tags []string, w io.Writer) (err error)
 		})
 	}
 
-	// Write the manifest
-	digest, err := mdl.Digest()
-	if err != nil {
-		return fmt.Errorf("get digest: %w", err)
-	}
-	rm, err := mdl.RawManifest()
-	if err != nil {
-		return fmt.Errorf("get raw manifest: %w", err)
-	}
-	manifestExists := false
-	if _, statErr := os.Stat(s.manifestPath(digest)); statErr == nil {
-		manifestExists = true
-	} else if !errors.Is(statErr, os.ErrNotExist) {
-		return fmt.Errorf("stat manifest: %w", statErr)
-	}
-	if err := s.WriteManifest(digest, rm); err != nil {
-		return fmt.Errorf("write manifest: %w", err)
-	}
-	if !manifestExists {
-		cleanups = append(cleanups, func() error {
-			if err := s.removeManifest(digest); err != nil && !errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("remove manifest: %w", err)
-			}
-			return nil
-		})
-	}
-	cleanups = append(cleanups, func() error {
-		if err := s.writeIndex(initialIndex); err != nil {
-			return fmt.Errorf("restore models index: %w", err)
-		}
-		return nil
-	})
-	if err := s.AddTags(digest.String(), tags); err != nil {
-		return fmt.Errorf("adding tags: %w", err)
+	if err := s.writeManifestAndTag(mdl, tags); err != nil {
+		return err
 	}
 	success = true
 	return nil
@@ -347,11 +416,9 @@ func (s *LocalStore) Write(mdl v1.Image, tags []string, w io.Writer) (err error)
 // WriteLightweight writes only the manifest and config for a model, assuming layers already exist in the store.
 // This is used for config-only modifications where the layer data hasn't changed.
 func (s *LocalStore) WriteLightweight(mdl v1.Image, tags []string) (err error) {
-	initialIndex, err := s.readIndex()
-	if err != nil {
-		return fmt.Errorf("reading models index: %w", err)
+	if s.readOnly {
+		return ErrReadOnly
 	}
-
 	type cleanupFunc func() error
 	var cleanups []cleanupFunc
 	success := false
@@ -414,7 +481,17 @@ func (s *LocalStore) WriteLightweight(mdl v1.Image, tags []string) (err error) {
 		})
 	}
 
-	// Write the manifest
+	if err := s.writeManifestAndTag(mdl, tags); err != nil {
+		return err
+	}
+	success = true
+	return nil
+}
+
+// writeManifestAndTag writes the manifest of a model whose blobs have been
+// written and tags it. Both happen while holding the store lock so that a
+// failure can be rolled back without clobbering concurrent changes.
+func (s *LocalStore) writeManifestAndTag(mdl v1.Image, tags []string) (err error) {
 	digest, err := mdl.Digest()
 	if err != nil {
 		return fmt.Errorf("get digest: %w", err)
@@ -423,33 +500,42 @@ func (s *LocalStore) WriteLightweight(mdl v1.Image, tags []string) (err error) {
 	if err != nil {
 		return fmt.Errorf("get raw manifest: %w", err)
 	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	initialIndex, err := s.readIndex()
+	if err != nil {
+		return fmt.Errorf("reading models index: %w", err)
+	}
 	manifestExists := false
 	if _, statErr := os.Stat(s.manifestPath(digest)); statErr == nil {
 		manifestExists = true
 	} else if !errors.Is(statErr, os.ErrNotExist) {
 		return fmt.Errorf("stat manifest: %w", statErr)
 	}
-	if err := s.WriteManifest(digest, rm); err != nil {
+	if err := s.writeManifest(digest, rm); err != nil {
 		return fmt.Errorf("write manifest: %w", err)
 	}
-	if !manifestExists {
-		cleanups = append(cleanups, func() error {
-			if err := s.removeManifest(digest); err != nil && !errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("remove manifest: %w", err)
+	if err := s.addTags(digest.String(), tags); err != nil {
+		err = fmt.Errorf("adding tags: %w", err)
+		var rollbackErrors []error
+		if !manifestExists {
+			if removeErr := s.removeManifest(digest); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				rollbackErrors = append(rollbackErrors, fmt.Errorf("remove manifest: %w", removeErr))
 			}
-			return nil
-		})
-	}
-	cleanups = append(cleanups, func() error {
-		if err := s.writeIndex(initialIndex); err != nil {
-			return fmt.Errorf("restore models index: %w", err)
 		}
-		return nil
-	})
-	if err := s.AddTags(digest.String(), tags); err != nil {
-		return fmt.Errorf("adding tags: %w", err)
+		if restoreErr := s.restoreIndex(initialIndex); restoreErr != nil {
+			rollbackErrors = append(rollbackErrors, fmt.Errorf("restore models index: %w", restoreErr))
+		}
+		if len(rollbackErrors) > 0 {
+			return errors.Join(err, fmt.Errorf("rollback cleanup errors: %w", errors.Join(rollbackErrors...)))
+		}
+		return err
 	}
-	success = true
 	return nil
 }
 
@@ -467,7 +553,7 @@ func (s *LocalStore) Read(reference string) (*Model, error) {
 			if err != nil {
 				return nil, fmt.Errorf("parsing hash: %w", err)
 			}
-			return s.newModel(hash, model.Tags)
+			return s.newModel(hash, model.Tags, model.Metadata)
 		}
 	}
 
@@ -1,6 +1,7 @@
 package store_test
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -17,6 +18,10 @@ import (
 	"github.com/docker/model-runner/pkg/distribution/internal/store"
 	"github.com/docker/model-runner/pkg/distribution/types"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // TestStoreAPI tests the store API directly
@@ -697,6 +702,38 @@ func TestWriteHandlesExistingBlobsGracefully(t *testing.T) {
 	}
 }
 
+func TestWriteContextTracesBlobs(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	s, err := store.New(store.Options{RootPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "pull")
+	if err := s.WriteContext(ctx, newTestModel(t), []string{"traced:latest"}, nil); err != nil {
+		t.Fatalf("WriteContext failed: %v", err)
+	}
+	parent.End()
+
+	// Each of the two layers is fetched and verified as part of the trace.
+	counts := make(map[string]int)
+	for _, span := range recorder.Ended() {
+		if span.Name() == "pull" {
+			continue
+		}
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("Expected span %s to be part of the trace", span.Name())
+		}
+		counts[span.Name()]++
+	}
+	if counts["store.FetchBlob"] != 2 || counts["store.VerifyBlob"] != 2 {
+		t.Errorf("Expected 2 blob fetch and verify spans, got %v", counts)
+	}
+}
+
 // TestStoreWithMultimodalProjector tests storing and retrieving models with multimodal projector files
 func TestStoreWithMultimodalProjector(t *testing.T) {
 	// Create a temporary directory for the test store
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// DigestMismatchError indicates that the content of a blob doesn't match its
+// digest.
+type DigestMismatchError struct {
+	// Expected is the digest the blob should have.
+	Expected v1.Hash
+	// Actual is the digest of the content, or empty if the content exceeded
+	// the expected size before it could be fully hashed.
+	Actual string
+	// Offset is the offset at which the mismatch was detected.
+	Offset int64
+}
+
+func (e *DigestMismatchError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("digest mismatch at offset %d: content of %s exceeds its expected size", e.Offset, e.Expected)
+	}
+	return fmt.Sprintf("digest mismatch at offset %d: expected %s, got %s", e.Offset, e.Expected, e.Actual)
+}
+
+// Is implements error matching for DigestMismatchError
+func (e *DigestMismatchError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+// newHasher returns a hash for the given algorithm.
+func newHasher(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+}
+
+// digestWriter hashes the content written to it so that it can be verified
+// while it is being streamed to disk. If the expected size is known, writes
+// fail as soon as it is exceeded.
+type digestWriter struct {
+	expected v1.Hash
+	// size is the expected size, or -1 if unknown.
+	size   int64
+	offset int64
+	hasher hash.Hash
+}
+
+func newDigestWriter(expected v1.Hash, size int64) (*digestWriter, error) {
+	hasher, err := newHasher(expected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &digestWriter{expected: expected, size: size, hasher: hasher}, nil
+}
+
+func (w *digestWriter) Write(p []byte) (int, error) {
+	if w.size >= 0 && w.offset+int64(len(p)) > w.size {
+		return 0, &DigestMismatchError{Expected: w.expected, Offset: w.size}
+	}
+	w.hasher.Write(p)
+	w.offset += int64(len(p))
+	return len(p), nil
+}
+
+// verify checks the digest of the content written so far.
+func (w *digestWriter) verify() error {
+	actual := v1.Hash{Algorithm: w.expected.Algorithm, Hex: hex.EncodeToString(w.hasher.Sum(nil))}
+	if actual != w.expected || (w.size >= 0 && w.offset != w.size) {
+		return &DigestMismatchError{Expected: w.expected, Actual: actual.String(), Offset: w.offset}
+	}
+	return nil
+}
+
+// verifyBlob re-hashes the blob with the given digest. A size of -1 indicates
+// that the expected size is unknown.
+func (s *LocalStore) verifyBlob(digest v1.Hash, size int64) error {
+	path, err := s.blobPath(digest)
+	if err != nil {
+		return fmt.Errorf("get blob path: %w", err)
+	}
+	return verifyFile(path, digest, size)
+}
+
+// verifyFile re-hashes the file at path, which holds the blob with the given
+// digest. A size of -1 indicates that the expected size is unknown.
+func verifyFile(path string, digest v1.Hash, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open blob %q: %w", digest.String(), err)
+	}
+	defer f.Close()
+
+	w, err := newDigestWriter(digest, size)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("verify blob %q: %w", digest.String(), err)
+	}
+	if err := w.verify(); err != nil {
+		return fmt.Errorf("verify blob %q: %w", digest.String(), err)
+	}
+	return nil
+}
+
+// Verify re-hashes the config and layer blobs of the model with the given
+// reference and returns an error describing every blob that is missing or
+// doesn't match its digest.
+func (s *LocalStore) Verify(reference string) error {
+	mdl, err := s.Read(reference)
+	if err != nil {
+		return err
+	}
+	manifest, err := mdl.Manifest()
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	var errs []error
+	if err := s.verifyBlob(manifest.Config.Digest, manifest.Config.Size); err != nil {
+		errs = append(errs, err)
+	}
+	for _, layer := range manifest.Layers {
+		if err := s.verifyBlob(layer.Digest, layer.Size); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/store"
+)
+
+func TestVerify(t *testing.T) {
+	rootPath := t.TempDir()
+	s, err := store.New(store.Options{RootPath: rootPath})
+	if err != nil {
+		t.Fatalf("Create store failed: %v", err)
+	}
+
+	mdl := newTestModel(t)
+	if err := s.Write(mdl, []string{"verify-model:latest"}, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		if err := s.Verify("verify-model:latest"); err != nil {
+			t.Fatalf("Expected model to verify, got %v", err)
+		}
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		if err := s.Verify("unknown-model:latest"); !errors.Is(err, store.ErrModelNotFound) {
+			t.Fatalf("Expected ErrModelNotFound, got %v", err)
+		}
+	})
+
+	t.Run("corrupted layer", func(t *testing.T) {
+		layers, err := mdl.Layers()
+		if err != nil {
+			t.Fatalf("Layers failed: %v", err)
+		}
+		digest, err := layers[0].Digest()
+		if err != nil {
+			t.Fatalf("Digest failed: %v", err)
+		}
+		path := filepath.Join(rootPath, "blobs", digest.Algorithm, digest.Hex)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Reading blob failed: %v", err)
+		}
+		content[len(content)-1] ^= 0xff
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Writing blob failed: %v", err)
+		}
+
+		err = s.Verify("verify-model:latest")
+		if !errors.Is(err, store.ErrDigestMismatch) {
+			t.Fatalf("Expected ErrDigestMismatch, got %v", err)
+		}
+		if !strings.Contains(err.Error(), digest.String()) {
+			t.Errorf("Expected error to name blob %s, got %v", digest, err)
+		}
+	})
+}
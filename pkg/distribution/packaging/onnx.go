@@ -0,0 +1,50 @@
+package packaging
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// onnxTokenizerFile is the tokenizer ONNX Runtime models are served with.
+const onnxTokenizerFile = "tokenizer.json"
+
+// PackageONNXFromDirectory scans a directory, such as one exported by Hugging
+// Face Optimum, for an ONNX model and its config files, creating a temporary
+// tar archive of the config files. The model is the single .onnx file at the
+// top level of the directory, and the config files, which must include its
+// tokenizer, are collected as for safetensors models.
+// It returns the path to the ONNX file, the path to the temporary config
+// archive, and any error encountered.
+func PackageONNXFromDirectory(dirPath string) (onnxPath string, tempConfigArchive string, err error) {
+	onnxPaths, configFiles, err := scanDirectory(dirPath, ".onnx")
+	if err != nil {
+		return "", "", err
+	}
+	if len(onnxPaths) == 0 {
+		return "", "", fmt.Errorf("no ONNX files found in directory: %s", dirPath)
+	}
+	if len(onnxPaths) > 1 {
+		return "", "", fmt.Errorf("found %d ONNX files in directory %s, but only 1 is supported", len(onnxPaths), dirPath)
+	}
+	if !slices.Contains(configFiles, onnxTokenizerFile) {
+		return "", "", fmt.Errorf("no %s found in directory: %s", onnxTokenizerFile, dirPath)
+	}
+
+	// Sort config files for reproducible tar archive
+	sort.Strings(configFiles)
+	tempConfigArchive, err = createTempConfigArchive(dirPath, configFiles)
+	if err != nil {
+		return "", "", fmt.Errorf("create config archive: %w", err)
+	}
+	return onnxPaths[0], tempConfigArchive, nil
+}
+
+// IsONNXDirectory returns whether a directory holds an ONNX model, rather
+// than safetensors files.
+func IsONNXDirectory(dirPath string) bool {
+	onnxPaths, _ := filepath.Glob(filepath.Join(dirPath, "*.onnx"))
+	safetensorsPaths, _ := filepath.Glob(filepath.Join(dirPath, "*.safetensors"))
+	return len(onnxPaths) > 0 && len(safetensorsPaths) == 0
+}
@@ -0,0 +1,59 @@
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPackageONNXFromDirectory(t *testing.T) {
+	writeFiles := func(t *testing.T, files ...string) string {
+		t.Helper()
+		dir := t.TempDir()
+		for _, name := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+				t.Fatalf("Failed to create test file %s: %v", name, err)
+			}
+		}
+		return dir
+	}
+
+	t.Run("model with tokenizer", func(t *testing.T) {
+		dir := writeFiles(t, "model.onnx", "config.json", "tokenizer.json", "tokenizer_config.json")
+		if !IsONNXDirectory(dir) {
+			t.Error("Expected an ONNX directory")
+		}
+		onnxPath, tempConfigArchive, err := PackageONNXFromDirectory(dir)
+		if err != nil {
+			t.Fatalf("PackageONNXFromDirectory failed: %v", err)
+		}
+		defer os.Remove(tempConfigArchive)
+		if onnxPath != filepath.Join(dir, "model.onnx") {
+			t.Errorf("Expected model.onnx, got %s", onnxPath)
+		}
+		archiveFiles, err := readTarArchive(tempConfigArchive)
+		if err != nil {
+			t.Fatalf("Failed to read tar archive: %v", err)
+		}
+		if expected := []string{"config.json", "tokenizer.json", "tokenizer_config.json"}; !slices.Equal(archiveFiles, expected) {
+			t.Errorf("Expected %v in archive, got %v", expected, archiveFiles)
+		}
+	})
+
+	for name, files := range map[string][]string{
+		"no tokenizer":    {"model.onnx", "config.json"},
+		"no model":        {"tokenizer.json"},
+		"multiple models": {"model.onnx", "model_quantized.onnx", "tokenizer.json"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := PackageONNXFromDirectory(writeFiles(t, files...)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if IsONNXDirectory(writeFiles(t, "model.safetensors", "model.onnx")) {
+		t.Error("Expected a directory with safetensors files not to be an ONNX directory")
+	}
+}
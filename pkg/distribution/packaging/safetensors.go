@@ -2,8 +2,11 @@ package packaging
 
 import (
 	"archive/tar"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -13,64 +16,152 @@ import (
 // configExtensions defines the file extensions that should be treated as config files
 var configExtensions = []string{".md", ".txt", ".json", ".vocab", ".jinja"}
 
+// safetensorsIndexFile is the name of the index mapping tensors to the
+// safetensors shards that contain them.
+const safetensorsIndexFile = "model.safetensors.index.json"
+
 // PackageFromDirectory scans a directory for safetensors files and config files,
 // creating a temporary tar archive of the config files.
+// Safetensors files are collected from the top level of the directory, while
+// config files are also collected from nested directories (e.g. tokenizer/)
+// and archived with their relative paths preserved. If the directory contains
+// a model.safetensors.index.json file, it is validated against the shards present.
 // It returns the paths to safetensors files, path to temporary config archive (if created),
 // and any error encountered.
 func PackageFromDirectory(dirPath string) (safetensorsPaths []string, tempConfigArchive string, err error) {
-	// Read directory contents (only top level, no subdirectories)
-	entries, err := os.ReadDir(dirPath)
+	safetensorsPaths, configFiles, err := scanDirectory(dirPath, ".safetensors")
 	if err != nil {
-		return nil, "", fmt.Errorf("read directory: %w", err)
+		return nil, "", err
+	}
+
+	if len(safetensorsPaths) == 0 {
+		return nil, "", fmt.Errorf("no safetensors files found in directory: %s", dirPath)
+	}
+
+	// Sort to ensure reproducible artifacts
+	sort.Strings(safetensorsPaths)
+
+	// Fail early if shards referenced by the index are missing
+	if err := validateSafetensorsIndex(dirPath, safetensorsPaths); err != nil {
+		return nil, "", err
 	}
 
-	var configFiles []string
+	// Create temporary tar archive with config files if any exist
+	if len(configFiles) > 0 {
+		// Sort config files for reproducible tar archive
+		sort.Strings(configFiles)
+
+		tempConfigArchive, err = createTempConfigArchive(dirPath, configFiles)
+		if err != nil {
+			return nil, "", fmt.Errorf("create config archive: %w", err)
+		}
+	}
+
+	return safetensorsPaths, tempConfigArchive, nil
+}
+
+// scanDirectory collects the weight files with the given extension at the top
+// level of a directory, and the config files throughout it, relative to the
+// directory.
+func scanDirectory(dirPath, weightsExtension string) (weightPaths []string, configFiles []string, err error) {
+	err = filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return fmt.Errorf("compute relative path: %w", err)
+		}
 
-	for _, entry := range entries {
 		if entry.IsDir() {
-			continue // Skip subdirectories
+			// Skip hidden directories such as .git or .cache
+			if relPath != "." && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip symlinks and other non-regular files
+		if !entry.Type().IsRegular() {
+			return nil
 		}
 
 		name := entry.Name()
-		fullPath := filepath.Join(dirPath, name)
 
-		// Collect safetensors files
-		lower := strings.ToLower(name)
-		if strings.HasSuffix(lower, ".safetensors") {
-			safetensorsPaths = append(safetensorsPaths, fullPath)
+		// Collect top-level weight files
+		if strings.HasSuffix(strings.ToLower(name), weightsExtension) {
+			if filepath.Dir(relPath) == "." {
+				weightPaths = append(weightPaths, path)
+			}
+			return nil
 		}
 
 		// Collect config files
 		if isConfigFile(name) {
-			configFiles = append(configFiles, fullPath)
+			configFiles = append(configFiles, relPath)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read directory: %w", err)
 	}
+	return weightPaths, configFiles, nil
+}
 
-	if len(safetensorsPaths) == 0 {
-		return nil, "", fmt.Errorf("no safetensors files found in directory: %s", dirPath)
+// validateSafetensorsIndex checks that every shard referenced by the
+// model.safetensors.index.json file in dirPath is present in safetensorsPaths.
+// It does nothing if the directory has no index file.
+func validateSafetensorsIndex(dirPath string, safetensorsPaths []string) error {
+	data, err := os.ReadFile(filepath.Join(dirPath, safetensorsIndexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("read %s: %w", safetensorsIndexFile, err)
 	}
 
-	// Sort to ensure reproducible artifacts
-	sort.Strings(safetensorsPaths)
+	var index struct {
+		WeightMap map[string]string `json:"weight_map"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("parse %s: %w", safetensorsIndexFile, err)
+	}
 
-	// Create temporary tar archive with config files if any exist
-	if len(configFiles) > 0 {
-		// Sort config files for reproducible tar archive
-		sort.Strings(configFiles)
+	present := make(map[string]bool, len(safetensorsPaths))
+	for _, path := range safetensorsPaths {
+		present[filepath.Base(path)] = true
+	}
 
-		tempConfigArchive, err = CreateTempConfigArchive(configFiles)
-		if err != nil {
-			return nil, "", fmt.Errorf("create config archive: %w", err)
+	var missing []string
+	seen := make(map[string]bool)
+	for _, shard := range index.WeightMap {
+		if seen[shard] {
+			continue
+		}
+		seen[shard] = true
+		if !present[shard] {
+			missing = append(missing, shard)
 		}
 	}
-
-	return safetensorsPaths, tempConfigArchive, nil
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%s references %d missing safetensors shard(s): %s",
+			safetensorsIndexFile, len(missing), strings.Join(missing, ", "))
+	}
+	return nil
 }
 
 // CreateTempConfigArchive creates a temporary tar archive containing the specified config files.
 // It returns the path to the temporary tar file and any error encountered.
 // The caller is responsible for removing the temporary file when done.
 func CreateTempConfigArchive(configFiles []string) (string, error) {
+	return createTempConfigArchive("", configFiles)
+}
+
+// createTempConfigArchive creates a temporary tar archive containing the specified config files.
+// If baseDir is empty, files are archived by basename. Otherwise, configFiles are paths relative
+// to baseDir and are archived with their relative paths preserved.
+func createTempConfigArchive(baseDir string, configFiles []string) (string, error) {
 	// Create temp file
 	tmpFile, err := os.CreateTemp("", "vllm-config-*.tar")
 	if err != nil {
@@ -89,9 +180,14 @@ func CreateTempConfigArchive(configFiles []string) (string, error) {
 	// Create tar writer
 	tw := tar.NewWriter(tmpFile)
 
-	// Add each config file to tar (preserving just filename, not full path)
+	// Add each config file to tar
 	for _, filePath := range configFiles {
-		if err := addFileToTar(tw, filePath); err != nil {
+		name := filepath.Base(filePath)
+		if baseDir != "" {
+			name = filepath.ToSlash(filePath)
+			filePath = filepath.Join(baseDir, filePath)
+		}
+		if err := addFileToTar(tw, filePath, name); err != nil {
 			tw.Close()
 			tmpFile.Close()
 			return "", err
@@ -113,8 +209,8 @@ func CreateTempConfigArchive(configFiles []string) (string, error) {
 	return tmpPath, nil
 }
 
-// addFileToTar adds a single file to the tar archive with only its basename (not full path)
-func addFileToTar(tw *tar.Writer, filePath string) error {
+// addFileToTar adds a single file to the tar archive under the given name
+func addFileToTar(tw *tar.Writer, filePath, name string) error {
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -128,9 +224,9 @@ func addFileToTar(tw *tar.Writer, filePath string) error {
 		return fmt.Errorf("stat file %s: %w", filePath, err)
 	}
 
-	// Create tar header (use only basename, not full path)
+	// Create tar header
 	header := &tar.Header{
-		Name:    filepath.Base(filePath),
+		Name:    name,
 		Size:    fileInfo.Size(),
 		Mode:    int64(fileInfo.Mode()),
 		ModTime: fileInfo.ModTime(),
@@ -210,7 +210,7 @@ func TestPackageFromDirectory_OnlySafetensorsFiles(t *testing.T) {
 	}
 }
 
-func TestPackageFromDirectory_SkipsSubdirectories(t *testing.T) {
+func TestPackageFromDirectory_IncludesNestedConfigFiles(t *testing.T) {
 	// Create temporary directory
 	tempDir := t.TempDir()
 
@@ -227,21 +227,22 @@ func TestPackageFromDirectory_SkipsSubdirectories(t *testing.T) {
 		}
 	}
 
-	// Create subdirectory with files that should be ignored
-	subDir := filepath.Join(tempDir, "subdir")
-	if err := os.Mkdir(subDir, 0755); err != nil {
-		t.Fatalf("Failed to create subdirectory: %v", err)
+	// Create nested directories with config files and a nested safetensors
+	// file that should not be collected as a model weight
+	nestedFiles := map[string]string{
+		"tokenizer/tokenizer.json":        `{"version": "1.0"}`,
+		"tokenizer/extra/vocab.txt":       "a\nb",
+		"subdir/ignored.safetensors":      "should be ignored",
+		".cache/huggingface/ignored.json": `{"ignored": true}`,
 	}
 
-	subFiles := map[string]string{
-		"ignored.safetensors": "should be ignored",
-		"ignored.json":        `{"ignored": true}`,
-	}
-
-	for name, content := range subFiles {
-		path := filepath.Join(subDir, name)
+	for name, content := range nestedFiles {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
 		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-			t.Fatalf("Failed to create test file in subdir %s: %v", name, err)
+			t.Fatalf("Failed to create test file %s: %v", name, err)
 		}
 	}
 
@@ -256,7 +257,7 @@ func TestPackageFromDirectory_SkipsSubdirectories(t *testing.T) {
 		defer os.Remove(tempConfigArchive)
 	}
 
-	// Verify only root-level files were processed
+	// Verify only root-level safetensors files were collected
 	if len(safetensorsPaths) != 1 {
 		t.Errorf("Expected 1 safetensors file from root directory, got %d", len(safetensorsPaths))
 	}
@@ -266,11 +267,91 @@ func TestPackageFromDirectory_SkipsSubdirectories(t *testing.T) {
 		t.Fatalf("Failed to read tar archive: %v", err)
 	}
 
-	if len(archiveFiles) != 1 || archiveFiles[0] != "config.json" {
-		t.Errorf("Expected only config.json from root directory, got %v", archiveFiles)
+	expectedFiles := []string{"config.json", "tokenizer/extra/vocab.txt", "tokenizer/tokenizer.json"}
+	sort.Strings(archiveFiles)
+	if strings.Join(archiveFiles, ",") != strings.Join(expectedFiles, ",") {
+		t.Errorf("Expected archive files %v, got %v", expectedFiles, archiveFiles)
 	}
 }
 
+func TestPackageFromDirectory_ValidatesIndex(t *testing.T) {
+	index := `{
+		"metadata": {"total_size": 100},
+		"weight_map": {
+			"layer.0.weight": "model-00001-of-00003.safetensors",
+			"layer.1.weight": "model-00002-of-00003.safetensors",
+			"layer.2.weight": "model-00003-of-00003.safetensors",
+			"layer.3.weight": "model-00003-of-00003.safetensors"
+		}
+	}`
+
+	t.Run("all shards present", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"model.safetensors.index.json":     index,
+			"model-00001-of-00003.safetensors": "shard 1",
+			"model-00002-of-00003.safetensors": "shard 2",
+			"model-00003-of-00003.safetensors": "shard 3",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file %s: %v", name, err)
+			}
+		}
+
+		safetensorsPaths, tempConfigArchive, err := PackageFromDirectory(tempDir)
+		if err != nil {
+			t.Fatalf("PackageFromDirectory failed: %v", err)
+		}
+		if tempConfigArchive != "" {
+			defer os.Remove(tempConfigArchive)
+		}
+		if len(safetensorsPaths) != 3 {
+			t.Errorf("Expected 3 safetensors files, got %d", len(safetensorsPaths))
+		}
+	})
+
+	t.Run("missing shards", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"model.safetensors.index.json":     index,
+			"model-00002-of-00003.safetensors": "shard 2",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file %s: %v", name, err)
+			}
+		}
+
+		_, _, err := PackageFromDirectory(tempDir)
+		if err == nil {
+			t.Fatal("Expected error for missing shards")
+		}
+		for _, shard := range []string{"model-00001-of-00003.safetensors", "model-00003-of-00003.safetensors"} {
+			if !strings.Contains(err.Error(), shard) {
+				t.Errorf("Expected error to list missing shard %s, got: %v", shard, err)
+			}
+		}
+	})
+
+	t.Run("invalid index", func(t *testing.T) {
+		tempDir := t.TempDir()
+		files := map[string]string{
+			"model.safetensors.index.json": "{not json",
+			"model.safetensors":            "weights",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test file %s: %v", name, err)
+			}
+		}
+
+		if _, _, err := PackageFromDirectory(tempDir); err == nil {
+			t.Fatal("Expected error for invalid index file")
+		}
+	})
+}
+
 // Helper function to read tar archive and return list of file names
 func readTarArchive(archivePath string) ([]string, error) {
 	file, err := os.Open(archivePath)
@@ -2,10 +2,12 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -26,10 +28,13 @@ var (
 )
 
 type Client struct {
-	transport http.RoundTripper
-	userAgent string
-	keychain  authn.Keychain
-	auth      authn.Authenticator
+	transport     http.RoundTripper
+	userAgent     string
+	keychain      authn.Keychain
+	auth          authn.Authenticator
+	pushChunkSize int64
+	pushRetries   int
+	tokens        TokenStore
 }
 
 type ClientOption func(*Client)
@@ -63,13 +68,24 @@ func WithAuthConfig(username, password string) ClientOption {
 
 func NewClient(opts ...ClientOption) *Client {
 	client := &Client{
-		transport: remote.DefaultTransport,
-		userAgent: DefaultUserAgent,
-		keychain:  authn.DefaultKeychain,
+		transport:     remote.DefaultTransport,
+		userAgent:     DefaultUserAgent,
+		keychain:      authn.DefaultKeychain,
+		pushChunkSize: DefaultPushChunkSize,
+		pushRetries:   DefaultPushRetries,
 	}
 	for _, opt := range opts {
 		opt(client)
 	}
+	if client.tokens != nil {
+		keychain := &tokenKeychain{
+			store:      client.tokens,
+			fallback:   client.keychain,
+			registries: make(map[string]string),
+		}
+		client.keychain = keychain
+		client.transport = &tokenTransport{base: client.transport, keychain: keychain}
+	}
 	return client
 }
 
@@ -107,6 +123,10 @@ func (c *Client) Model(ctx context.Context, reference string) (types.ModelArtifa
 		if strings.Contains(errStr, "NAME_UNKNOWN") {
 			return nil, NewRegistryError(reference, "NAME_UNKNOWN", "Repository not found", err)
 		}
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusTooManyRequests {
+			return nil, NewRegistryError(reference, "TOOMANYREQUESTS", "Registry rate limit exceeded, try again later", err)
+		}
 		return nil, NewRegistryError(reference, "UNKNOWN", err.Error(), err)
 	}
 
@@ -157,11 +177,14 @@ func (c *Client) BearerToken(ctx context.Context, reference string) (string, err
 }
 
 type Target struct {
-	reference name.Reference
-	transport http.RoundTripper
-	userAgent string
-	keychain  authn.Keychain
-	auth      authn.Authenticator
+	reference     name.Reference
+	transport     http.RoundTripper
+	userAgent     string
+	keychain      authn.Keychain
+	auth          authn.Authenticator
+	pushChunkSize int64
+	pushRetries   int
+	pushBackoff   func(retry int) time.Duration
 }
 
 func (c *Client) NewTarget(tag string) (*Target, error) {
@@ -170,11 +193,14 @@ func (c *Client) NewTarget(tag string) (*Target, error) {
 		return nil, fmt.Errorf("invalid tag: %q: %w", tag, err)
 	}
 	return &Target{
-		reference: ref,
-		transport: c.transport,
-		userAgent: c.userAgent,
-		keychain:  c.keychain,
-		auth:      c.auth,
+		reference:     ref,
+		transport:     c.transport,
+		userAgent:     c.userAgent,
+		keychain:      c.keychain,
+		auth:          c.auth,
+		pushChunkSize: c.pushChunkSize,
+		pushRetries:   c.pushRetries,
+		pushBackoff:   pushBackoff,
 	}, nil
 }
 
@@ -194,13 +220,26 @@ func (t *Target) Write(ctx context.Context, model types.ModelArtifact, progressW
 	}
 	pr := progress.NewProgressReporter(progressWriter, progress.PushMsg, imageSize, nil)
 	defer pr.Wait()
+	updates := pr.Updates()
 
 	// Set up authentication options
 	authOpts := []remote.Option{
 		remote.WithContext(ctx),
 		remote.WithTransport(t.transport),
 		remote.WithUserAgent(t.userAgent),
-		remote.WithProgress(pr.Updates()),
+	}
+
+	// Upload the layers in chunks, so that an interrupted upload of a large
+	// layer resumes rather than restarts. Progress is then reported here,
+	// rather than by remote.Write, which only pushes the rest.
+	var uploaded int64
+	if t.pushChunkSize > 0 {
+		defer close(updates)
+		if uploaded, err = t.uploadLayers(ctx, layers, updates, imageSize); err != nil {
+			return fmt.Errorf("write to registry %q: %w", t.reference.String(), err)
+		}
+	} else {
+		authOpts = append(authOpts, remote.WithProgress(updates))
 	}
 
 	// Use direct auth if provided, otherwise fall back to keychain
@@ -213,5 +252,9 @@ func (t *Target) Write(ctx context.Context, model types.ModelArtifact, progressW
 	if err := remote.Write(t.reference, model, authOpts...); err != nil {
 		return fmt.Errorf("write to registry %q: %w", t.reference.String(), err)
 	}
+	if t.pushChunkSize > 0 && uploaded < imageSize {
+		// Report the layers mounted by remote.Write.
+		updates <- v1.Update{Total: imageSize, Complete: imageSize}
+	}
 	return nil
 }
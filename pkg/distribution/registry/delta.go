@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ggcr "github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// Delta is a layer that reconstructs a layer of a model from a layer of
+// another model, such as the base it was fine-tuned from.
+type Delta struct {
+	// Base is the DiffID of the layer the delta applies to.
+	Base v1.Hash
+	// Target is the DiffID of the layer the delta reconstructs.
+	Target v1.Hash
+	// Layer is the delta, of media type types.MediaTypeGGUFDelta.
+	Layer v1.Layer
+}
+
+// Deltas returns the deltas the registry has for the model with the given
+// manifest digest in the repository of reference. Deltas are stored as
+// artifacts that refer to the model, and are listed with the referrers API,
+// or the referrers tag schema for registries that don't support it.
+func (c *Client) Deltas(ctx context.Context, reference string, digest v1.Hash) ([]Delta, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return nil, NewReferenceError(reference, err)
+	}
+
+	// Set up authentication options
+	authOpts := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithTransport(c.transport),
+		remote.WithUserAgent(c.userAgent),
+	}
+
+	// Use direct auth if provided, otherwise fall back to keychain
+	if c.auth != nil {
+		authOpts = append(authOpts, remote.WithAuth(c.auth))
+	} else {
+		authOpts = append(authOpts, remote.WithAuthFromKeychain(c.keychain))
+	}
+
+	repo := ref.Context()
+	index, err := remote.Referrers(repo.Digest(digest.String()),
+		append(authOpts, remote.WithFilter("artifactType", string(types.MediaTypeGGUFDeltaConfig)))...)
+	if err != nil {
+		return nil, fmt.Errorf("listing referrers: %w", err)
+	}
+	referrers, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("reading referrers: %w", err)
+	}
+
+	var deltas []Delta
+	for _, desc := range referrers.Manifests {
+		artifact, err := remote.Image(repo.Digest(desc.Digest.String()), authOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("reading delta artifact %s: %w", desc.Digest, err)
+		}
+		manifest, err := artifact.Manifest()
+		if err != nil {
+			return nil, fmt.Errorf("reading delta artifact %s: %w", desc.Digest, err)
+		}
+		for _, l := range manifest.Layers {
+			if l.MediaType != types.MediaTypeGGUFDelta {
+				continue
+			}
+			base, err := v1.NewHash(l.Annotations[types.AnnotationDeltaBase])
+			if err != nil {
+				continue
+			}
+			target, err := v1.NewHash(l.Annotations[types.AnnotationDeltaTarget])
+			if err != nil {
+				continue
+			}
+			layer, err := artifact.LayerByDigest(l.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("reading delta %s: %w", l.Digest, err)
+			}
+			deltas = append(deltas, Delta{Base: base, Target: target, Layer: layer})
+		}
+	}
+	return deltas, nil
+}
+
+// WriteDelta writes an artifact holding delta, a layer that reconstructs a
+// layer of mdl from the layer of another model, identified by annotations as
+// in types.AnnotationDeltaBase and types.AnnotationDeltaTarget. The artifact
+// refers to mdl as its subject, so mdl must be written to the target first.
+func (t *Target) WriteDelta(ctx context.Context, mdl types.ModelArtifact, delta v1.Layer, annotations map[string]string) error {
+	raw, err := mdl.RawManifest()
+	if err != nil {
+		return fmt.Errorf("getting model manifest: %w", err)
+	}
+	digest, size, err := v1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("getting model digest: %w", err)
+	}
+	mediaType, err := mdl.MediaType()
+	if err != nil {
+		return fmt.Errorf("getting model media type: %w", err)
+	}
+
+	artifact := mutate.ConfigMediaType(mutate.MediaType(empty.Image, ggcr.OCIManifestSchema1), types.MediaTypeGGUFDeltaConfig)
+	artifact, err = mutate.Append(artifact, mutate.Addendum{
+		Layer:       delta,
+		MediaType:   types.MediaTypeGGUFDelta,
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("creating delta artifact: %w", err)
+	}
+	artifact = mutate.Subject(artifact, v1.Descriptor{MediaType: mediaType, Digest: digest, Size: size}).(v1.Image)
+	artifactDigest, err := artifact.Digest()
+	if err != nil {
+		return fmt.Errorf("getting delta artifact digest: %w", err)
+	}
+
+	// Set up authentication options
+	authOpts := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithTransport(t.transport),
+		remote.WithUserAgent(t.userAgent),
+	}
+
+	// Use direct auth if provided, otherwise fall back to keychain
+	if t.auth != nil {
+		authOpts = append(authOpts, remote.WithAuth(t.auth))
+	} else {
+		authOpts = append(authOpts, remote.WithAuthFromKeychain(t.keychain))
+	}
+
+	ref := t.reference.Context().Digest(artifactDigest.String())
+	if err := remote.Write(ref, artifact, authOpts...); err != nil {
+		return fmt.Errorf("write delta to registry %q: %w", ref.String(), err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// DeviceAuth configures the OAuth 2.0 device authorization grant (RFC 8628)
+// of the identity provider behind a registry.
+type DeviceAuth struct {
+	// DeviceAuthorizationURL is the endpoint that issues device codes.
+	DeviceAuthorizationURL string
+	// TokenURL is the endpoint that issues tokens.
+	TokenURL string
+	// ClientID identifies the client to the identity provider.
+	ClientID string
+	// Scopes are the requested scopes, which must allow refresh tokens to be
+	// issued, as offline_access does for most providers.
+	Scopes []string
+}
+
+// DeviceCode is the code the user enters at the verification URI to
+// authorize the client.
+type DeviceCode struct {
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+}
+
+// deviceCodeResponse is the response of the device authorization endpoint.
+type deviceCodeResponse struct {
+	DeviceCode
+	Code string `json:"device_code"`
+	// Interval is the number of seconds between token requests, 5 if unset.
+	Interval *int `json:"interval"`
+}
+
+// deviceTokenResponse is the response of the token endpoint.
+type deviceTokenResponse struct {
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// DeviceLogin authorizes the client to access registry through the device
+// authorization grant, calling prompt with the code the user enters at the
+// verification URI, then waits for the user and saves the refresh token
+// issued to the token store.
+func (c *Client) DeviceLogin(ctx context.Context, registry string, auth DeviceAuth, prompt func(DeviceCode)) error {
+	if c.tokens == nil {
+		return ErrNoTokenStore
+	}
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return NewReferenceError(registry, err)
+	}
+	client := &http.Client{Transport: c.transport}
+
+	var code deviceCodeResponse
+	form := url.Values{"client_id": {auth.ClientID}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+	status, err := postForm(ctx, client, auth.DeviceAuthorizationURL, form, &code)
+	if err != nil {
+		return fmt.Errorf("requesting device code: %w", err)
+	}
+	if status != http.StatusOK || code.Code == "" {
+		return fmt.Errorf("requesting device code: unexpected status %d", status)
+	}
+	prompt(code.DeviceCode)
+
+	interval := 5 * time.Second
+	if code.Interval != nil {
+		interval = time.Duration(*code.Interval) * time.Second
+	}
+	form = url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {code.Code},
+		"client_id":   {auth.ClientID},
+	}
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+		var token deviceTokenResponse
+		status, err := postForm(ctx, client, auth.TokenURL, form, &token)
+		if err != nil {
+			return fmt.Errorf("requesting token: %w", err)
+		}
+		switch {
+		case status == http.StatusOK && token.RefreshToken != "":
+			if err := c.tokens.SetToken(reg.RegistryStr(), token.RefreshToken); err != nil {
+				return fmt.Errorf("saving token: %w", err)
+			}
+			return nil
+		case status == http.StatusOK:
+			return errors.New("no refresh token issued, check the requested scopes")
+		case token.Error == "authorization_pending":
+		case token.Error == "slow_down":
+			interval += 5 * time.Second
+		case token.Error == "access_denied" || token.Error == "expired_token":
+			return ErrDeviceLoginDenied
+		default:
+			return fmt.Errorf("requesting token: %s: %s", token.Error, token.Description)
+		}
+	}
+}
+
+// postForm posts form to endpoint, decoding the JSON response into v and
+// returning the response status.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("decoding response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -11,6 +11,9 @@ var (
 	ErrInvalidReference     = errors.New("invalid model reference")
 	ErrModelNotFound        = errors.New("model not found")
 	ErrUnauthorized         = errors.New("unauthorized access to model")
+	ErrRateLimited          = errors.New("registry rate limit exceeded")
+	ErrNoTokenStore         = errors.New("no token store configured")
+	ErrDeviceLoginDenied    = errors.New("device login denied or expired")
 	ErrUnsupportedMediaType = errors.New(fmt.Sprintf(
 		"client supports only models of type %q and older - try upgrading",
 		types.MediaTypeModelConfigV01,
@@ -61,6 +64,8 @@ func (e Error) Is(target error) bool {
 		return e.Code == "MANIFEST_UNKNOWN" || e.Code == "NAME_UNKNOWN"
 	case ErrUnauthorized:
 		return e.Code == "UNAUTHORIZED"
+	case ErrRateLimited:
+		return e.Code == "TOOMANYREQUESTS"
 	default:
 		return false
 	}
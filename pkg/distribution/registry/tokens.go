@@ -0,0 +1,235 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// TokenStore persists the OAuth2 refresh tokens, also known as identity
+// tokens, with which the client authenticates to registries that use them,
+// such as registries backed by an SSO provider. Registries are identified as
+// in name.Registry.RegistryStr, as in index.docker.io or ghcr.io.
+type TokenStore interface {
+	// Token returns the refresh token for a registry, or "" if there's none.
+	Token(registry string) (string, error)
+	// SetToken stores the refresh token for a registry, replacing any
+	// previous one.
+	SetToken(registry, token string) error
+	// DeleteToken removes the refresh token for a registry, if any.
+	DeleteToken(registry string) error
+}
+
+// WithTokenStore authenticates to the registries that have a refresh token
+// in store with that token, rather than with the credentials of the
+// keychain. Registries exchange refresh tokens for short-lived access tokens
+// as needed, and when they rotate refresh tokens, the new token is saved to
+// store, so that it's still valid the next time the client is created.
+func WithTokenStore(store TokenStore) ClientOption {
+	return func(c *Client) {
+		if store != nil {
+			c.tokens = store
+		}
+	}
+}
+
+// FileTokenStore is a TokenStore backed by a JSON file that's only readable
+// by its owner.
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// storedToken is a refresh token in a FileTokenStore.
+type storedToken struct {
+	RefreshToken string    `json:"refresh_token"`
+	Updated      time.Time `json:"updated"`
+}
+
+// NewFileTokenStore creates a token store backed by the file at path, which
+// is created when the first token is stored.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Token implements TokenStore.
+func (s *FileTokenStore) Token(registry string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return tokens[registry].RefreshToken, nil
+}
+
+// SetToken implements TokenStore.
+func (s *FileTokenStore) SetToken(registry, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[registry] = storedToken{RefreshToken: token, Updated: time.Now().UTC()}
+	return s.write(tokens)
+}
+
+// DeleteToken implements TokenStore.
+func (s *FileTokenStore) DeleteToken(registry string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[registry]; !ok {
+		return nil
+	}
+	delete(tokens, registry)
+	return s.write(tokens)
+}
+
+// read reads the tokens from the file, which is empty if it doesn't exist.
+func (s *FileTokenStore) read() (map[string]storedToken, error) {
+	tokens := make(map[string]storedToken)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading token store: %w", err)
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing token store %q: %w", s.path, err)
+	}
+	return tokens, nil
+}
+
+// write replaces the file with tokens. The file is created with 0600
+// permissions by os.CreateTemp.
+func (s *FileTokenStore) write(tokens map[string]storedToken) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding tokens: %w", err)
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating token store directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing token store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing token store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replacing token store: %w", err)
+	}
+	return nil
+}
+
+// tokenKeychain resolves the registries that have a refresh token in the
+// token store to it, and others with the fallback keychain.
+type tokenKeychain struct {
+	store    TokenStore
+	fallback authn.Keychain
+
+	mu sync.Mutex
+	// registries maps the refresh tokens handed out to the registries they
+	// belong to, so that rotated tokens are saved for the right registry.
+	registries map[string]string
+}
+
+// Resolve implements authn.Keychain.
+func (k *tokenKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	registry := target.RegistryStr()
+	token, err := k.store.Token(registry)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return k.fallback.Resolve(target)
+	}
+	k.mu.Lock()
+	k.registries[token] = registry
+	k.mu.Unlock()
+	return authn.FromConfig(authn.AuthConfig{IdentityToken: token}), nil
+}
+
+// rotate saves the refresh token a registry issued in exchange for old.
+func (k *tokenKeychain) rotate(old, token string) error {
+	k.mu.Lock()
+	registry, ok := k.registries[old]
+	if ok {
+		k.registries[token] = registry
+	}
+	k.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return k.store.SetToken(registry, token)
+}
+
+// tokenTransport watches the refresh token grants of the OAuth2 token
+// endpoints of registries, saving the rotated refresh tokens they return.
+type tokenTransport struct {
+	base     http.RoundTripper
+	keychain *tokenKeychain
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil ||
+		!strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	form, err := url.ParseQuery(string(body))
+	if err != nil || form.Get("grant_type") != "refresh_token" {
+		return t.base.RoundTrip(req)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	var token struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if json.Unmarshal(data, &token) == nil && token.RefreshToken != "" && token.RefreshToken != form.Get("refresh_token") {
+		// The access token is still usable if saving fails, so the request
+		// succeeds, and the old refresh token is used next time.
+		_ = t.keychain.rotate(form.Get("refresh_token"), token.RefreshToken)
+	}
+	return resp, nil
+}
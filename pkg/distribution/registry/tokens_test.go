@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "tokens.json")
+	store := NewFileTokenStore(path)
+	if token, err := store.Token("ghcr.io"); err != nil || token != "" {
+		t.Fatalf("Expected no token, got %q, %v", token, err)
+	}
+	if err := store.SetToken("ghcr.io", "refresh-1"); err != nil {
+		t.Fatalf("Failed to set token: %v", err)
+	}
+	if err := store.SetToken("registry.example.com", "refresh-2"); err != nil {
+		t.Fatalf("Failed to set token: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat token store: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("Expected token store permissions 0600, got %o", perm)
+		}
+	}
+
+	// Tokens are read back by another store.
+	store = NewFileTokenStore(path)
+	if token, err := store.Token("ghcr.io"); err != nil || token != "refresh-1" {
+		t.Errorf("Expected refresh-1, got %q, %v", token, err)
+	}
+	if err := store.DeleteToken("ghcr.io"); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+	if token, err := store.Token("ghcr.io"); err != nil || token != "" {
+		t.Errorf("Expected the token to be deleted, got %q, %v", token, err)
+	}
+	if token, err := store.Token("registry.example.com"); err != nil || token != "refresh-2" {
+		t.Errorf("Expected refresh-2, got %q, %v", token, err)
+	}
+}
+
+// oauthRegistry serves an in-memory registry that requires access tokens
+// issued by its OAuth2 token endpoint in exchange for refresh tokens, which
+// it rotates on every exchange.
+func oauthRegistry(t *testing.T, refreshToken string) string {
+	t.Helper()
+	handler := registry.New()
+	var mu sync.Mutex
+	var issued int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/token" {
+			if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "refresh_token" ||
+				r.PostFormValue("refresh_token") != refreshToken {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			issued++
+			refreshToken = fmt.Sprintf("refresh-%d", issued+1)
+			json.NewEncoder(w).Encode(map[string]string{
+				"access_token":  fmt.Sprintf("access-%d", issued),
+				"refresh_token": refreshToken,
+			})
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	return u.Host
+}
+
+func TestTokenStoreRotation(t *testing.T) {
+	host := oauthRegistry(t, "refresh-1")
+	tag := host + "/testmodel:v1"
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err := store.SetToken(host, "refresh-1"); err != nil {
+		t.Fatalf("Failed to set token: %v", err)
+	}
+
+	// Pushing authenticates with the stored refresh token and saves the
+	// rotated one.
+	target, err := NewClient(WithTokenStore(store)).NewTarget(tag)
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err := target.Write(t.Context(), newTestModel(t), nil); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+	rotated, err := store.Token(host)
+	if err != nil || rotated == "refresh-1" || rotated == "" {
+		t.Fatalf("Expected a rotated token, got %q, %v", rotated, err)
+	}
+
+	// A new client authenticates with the rotated token, as the old one is
+	// no longer valid.
+	if _, err := NewClient(WithTokenStore(store)).Model(t.Context(), tag); err != nil {
+		t.Fatalf("Failed to get model with the rotated token: %v", err)
+	}
+
+	// Registries without a stored token use the keychain.
+	if _, err := NewClient(WithTokenStore(NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json")))).Model(t.Context(), tag); err == nil {
+		t.Error("Expected anonymous access to be refused")
+	}
+}
+
+func TestDeviceLogin(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != "model-runner" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		switch r.URL.Path {
+		case "/device":
+			// The device code is the requested scope, so that tests
+			// select how the token endpoint responds.
+			json.NewEncoder(w).Encode(map[string]any{
+				"device_code":      r.PostForm.Get("scope"),
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": "https://sso.example.com/device",
+				"expires_in":       600,
+				"interval":         0,
+			})
+		case "/token":
+			switch polls++; {
+			case r.PostForm.Get("device_code") == "denied":
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "access_denied"})
+			case polls < 3:
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+			default:
+				json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "refresh_token": "refresh"})
+			}
+		}
+	}))
+	defer server.Close()
+	auth := DeviceAuth{
+		DeviceAuthorizationURL: server.URL + "/device",
+		TokenURL:               server.URL + "/token",
+		ClientID:               "model-runner",
+		Scopes:                 []string{"offline_access"},
+	}
+
+	if err := NewClient().DeviceLogin(t.Context(), "ghcr.io", auth, func(DeviceCode) {}); !errors.Is(err, ErrNoTokenStore) {
+		t.Errorf("Expected ErrNoTokenStore, got %v", err)
+	}
+
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	client := NewClient(WithTokenStore(store))
+	var code DeviceCode
+	if err := client.DeviceLogin(t.Context(), "docker.io", auth, func(c DeviceCode) { code = c }); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if code.UserCode != "ABCD-EFGH" || code.VerificationURI != "https://sso.example.com/device" {
+		t.Errorf("Unexpected device code %+v", code)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 token requests, got %d", polls)
+	}
+	if token, err := store.Token("index.docker.io"); err != nil || token != "refresh" {
+		t.Errorf("Expected the refresh token to be stored, got %q, %v", token, err)
+	}
+
+	auth.Scopes = []string{"denied"}
+	if err := client.DeviceLogin(t.Context(), "ghcr.io", auth, func(DeviceCode) {}); !errors.Is(err, ErrDeviceLoginDenied) {
+		t.Errorf("Expected ErrDeviceLoginDenied, got %v", err)
+	}
+}
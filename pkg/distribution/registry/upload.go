@@ -0,0 +1,404 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	// DefaultPushChunkSize is the size of the chunks in which layers are
+	// uploaded when pushing models.
+	DefaultPushChunkSize = 64 << 20
+	// DefaultPushRetries is the number of times a failed chunk upload is
+	// retried before the push fails.
+	DefaultPushRetries = 5
+)
+
+// WithPushChunkSize sets the size of the chunks in which layers are uploaded
+// when pushing models. A negative size disables chunked uploads, so that each
+// layer is uploaded in a single request and a failure restarts it.
+func WithPushChunkSize(size int64) ClientOption {
+	return func(c *Client) {
+		if size > 0 {
+			c.pushChunkSize = size
+		} else if size < 0 {
+			c.pushChunkSize = 0
+		}
+	}
+}
+
+// WithPushRetries sets the number of times a failed chunk upload is retried,
+// with exponential backoff, before the push fails.
+func WithPushRetries(retries int) ClientOption {
+	return func(c *Client) {
+		if retries >= 0 {
+			c.pushRetries = retries
+		}
+	}
+}
+
+// pushBackoff returns how long to wait before the given retry (1-based) of a
+// chunk upload.
+func pushBackoff(retry int) time.Duration {
+	return min(time.Second<<(retry-1), 30*time.Second)
+}
+
+// blobUploader uploads blobs to a repository in chunks, resuming interrupted
+// uploads where the registry reports how much it received, and restarting
+// them otherwise.
+type blobUploader struct {
+	client    *http.Client
+	repo      name.Repository
+	chunkSize int64
+	retries   int
+	backoff   func(retry int) time.Duration
+	// progress is called with the number of bytes uploaded.
+	progress func(n int64)
+}
+
+// uploadLayers uploads the layers of a model ahead of remote.Write, which then
+// finds them in the registry and only pushes the config and manifest. Layers
+// read from a registry, as when copying models, are left to remote.Write,
+// which tries to mount them rather than upload them. It returns the total
+// size of the uploaded layers.
+func (t *Target) uploadLayers(ctx context.Context, layers []v1.Layer, updates chan<- v1.Update, total int64) (int64, error) {
+	repo := t.reference.Context()
+	auth := t.auth
+	if auth == nil {
+		var err error
+		if auth, err = t.keychain.Resolve(repo); err != nil {
+			return 0, fmt.Errorf("resolving credentials: %w", err)
+		}
+	}
+	rt, err := transport.NewWithContext(ctx, repo.Registry, auth, transport.NewUserAgent(t.transport, t.userAgent), []string{repo.Scope(transport.PushScope)})
+	if err != nil {
+		return 0, fmt.Errorf("authenticating to registry: %w", err)
+	}
+
+	var complete int64
+	u := &blobUploader{
+		client:    &http.Client{Transport: rt},
+		repo:      repo,
+		chunkSize: t.pushChunkSize,
+		retries:   t.pushRetries,
+		backoff:   t.pushBackoff,
+		progress: func(n int64) {
+			complete += n
+			updates <- v1.Update{Total: total, Complete: complete}
+		},
+	}
+	for _, layer := range layers {
+		if _, ok := layer.(*remote.MountableLayer); ok {
+			continue
+		}
+		if err := u.upload(ctx, layer); err != nil {
+			return complete, err
+		}
+	}
+	return complete, nil
+}
+
+// upload uploads a layer unless the repository already has it.
+func (u *blobUploader) upload(ctx context.Context, layer v1.Layer) error {
+	digest, err := layer.Digest()
+	if err != nil {
+		return fmt.Errorf("getting layer digest: %w", err)
+	}
+	size, err := layer.Size()
+	if err != nil {
+		return fmt.Errorf("getting layer size: %w", err)
+	}
+	src := &chunkSource{layer: layer}
+	defer src.close()
+
+	// acked is the number of bytes the registry acknowledged, and reported
+	// the number reported as progress, which never goes backwards even if
+	// the upload restarts. Failures only reset once the upload gets further
+	// than before, so that an upload that keeps restarting gives up.
+	var location string
+	var acked, reported int64
+	for failures := 0; ; {
+		done := false
+		err := func() error {
+			if location == "" {
+				exists, err := u.exists(ctx, digest)
+				if err != nil || exists {
+					done = exists
+					return err
+				}
+				if location, err = u.start(ctx); err != nil {
+					return err
+				}
+				acked = 0
+			}
+			if acked == size {
+				err := u.commit(ctx, location, digest)
+				done = err == nil
+				return err
+			}
+			chunk, err := src.chunk(acked, min(u.chunkSize, size-acked))
+			if err != nil {
+				return &localError{err}
+			}
+			location, acked, err = u.patch(ctx, location, chunk, acked)
+			return err
+		}()
+		if done {
+			u.progress(size - reported)
+			return nil
+		}
+		if err == nil {
+			if acked > reported {
+				u.progress(acked - reported)
+				reported = acked
+				failures = 0
+			}
+			continue
+		}
+		if !isTransient(err) {
+			return fmt.Errorf("uploading layer %s: %w", digest, err)
+		}
+		if failures++; failures > u.retries {
+			return fmt.Errorf("uploading layer %s: giving up after %d retries: %w", digest, u.retries, err)
+		}
+		if err := sleep(ctx, u.backoff(failures)); err != nil {
+			return err
+		}
+
+		// Resume from what the registry received, or restart the upload if
+		// it can't tell. A failed commit is simply retried.
+		if location == "" || acked == size {
+			continue
+		}
+		if received, ok := u.status(ctx, location); ok {
+			acked = received
+		} else {
+			location = ""
+		}
+	}
+}
+
+// exists reports whether the repository has the blob.
+func (u *blobUploader) exists(ctx context.Context, digest v1.Hash) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.url("blobs/"+digest.String()), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// start starts an upload, returning its location.
+func (u *blobUploader) start(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url("blobs/uploads/"), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusAccepted); err != nil {
+		return "", err
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("getting upload location: %w", err)
+	}
+	return location.String(), nil
+}
+
+// patch uploads a chunk starting at offset, returning the location to which
+// the next chunk is uploaded and the number of bytes the registry received.
+func (u *blobUploader) patch(ctx context.Context, location string, chunk []byte, offset int64) (string, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, location, bytes.NewReader(chunk))
+	if err != nil {
+		return location, offset, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(len(chunk))-1))
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return location, offset, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusAccepted, http.StatusNoContent); err != nil {
+		return location, offset, err
+	}
+	received := offset + int64(len(chunk))
+	if end, ok := parseRange(resp.Header.Get("Range")); ok && end > offset {
+		received = end
+	}
+	if next, err := resp.Location(); err == nil {
+		location = next.String()
+	}
+	return location, received, nil
+}
+
+// status returns the number of bytes the registry received for an upload,
+// reporting false if it doesn't support querying it.
+func (u *blobUploader) status(ctx context.Context, location string) (int64, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return 0, false
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return 0, false
+	}
+	return parseRange(resp.Header.Get("Range"))
+}
+
+// commit completes an upload.
+func (u *blobUploader) commit(ctx context.Context, location string, digest v1.Hash) error {
+	loc, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("parsing upload location: %w", err)
+	}
+	query := loc.Query()
+	query.Set("digest", digest.String())
+	loc.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, loc.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return transport.CheckError(resp, http.StatusCreated)
+}
+
+// url returns the URL of a path below the repository.
+func (u *blobUploader) url(path string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s", u.repo.Registry.Scheme(), u.repo.RegistryStr(), u.repo.RepositoryStr(), path)
+}
+
+// parseRange parses the Range header of an upload response, as in 0-1023,
+// returning the number of bytes received. A range ending at 0 is ambiguous,
+// since registries also report it for empty uploads, so it's taken to mean
+// nothing was received.
+func parseRange(header string) (int64, bool) {
+	var start, end int64
+	if _, err := fmt.Sscanf(strings.TrimPrefix(header, "bytes="), "%d-%d", &start, &end); err != nil || start != 0 {
+		return 0, false
+	}
+	if end == 0 {
+		return 0, true
+	}
+	return end + 1, true
+}
+
+// localError is an error reading the layer being uploaded, which isn't
+// retried.
+type localError struct {
+	err error
+}
+
+func (e *localError) Error() string { return e.err.Error() }
+func (e *localError) Unwrap() error { return e.err }
+
+// isTransient reports whether a failed upload request is worth retrying: on
+// network errors, server errors, rate limiting and range mismatches, which
+// registries report when they received part of a failed chunk.
+func isTransient(err error) bool {
+	var local *localError
+	if errors.As(err, &local) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode >= http.StatusInternalServerError ||
+			terr.StatusCode == http.StatusTooManyRequests ||
+			terr.StatusCode == http.StatusRequestedRangeNotSatisfiable
+	}
+	return true
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chunkSource reads chunks of a layer, reopening it to resume from an
+// earlier offset.
+type chunkSource struct {
+	layer v1.Layer
+	rc    io.ReadCloser
+	// pos is the offset of the next byte read from rc.
+	pos int64
+	// buf holds the last chunk read, starting at bufOffset.
+	buf       []byte
+	bufOffset int64
+}
+
+// chunk returns up to size bytes of the layer starting at offset.
+func (s *chunkSource) chunk(offset, size int64) ([]byte, error) {
+	if offset >= s.bufOffset && offset < s.bufOffset+int64(len(s.buf)) {
+		return s.buf[offset-s.bufOffset:], nil
+	}
+	if s.rc == nil || offset < s.pos {
+		s.close()
+		rc, err := s.layer.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("opening layer: %w", err)
+		}
+		s.rc, s.pos = rc, 0
+	}
+	if _, err := io.CopyN(io.Discard, s.rc, offset-s.pos); err != nil {
+		return nil, fmt.Errorf("reading layer: %w", err)
+	}
+	if int64(cap(s.buf)) < size {
+		s.buf = make([]byte, size)
+	}
+	s.buf = s.buf[:size]
+	if _, err := io.ReadFull(s.rc, s.buf); err != nil {
+		s.buf = nil
+		return nil, fmt.Errorf("reading layer: %w", err)
+	}
+	s.pos, s.bufOffset = offset+size, offset
+	return s.buf, nil
+}
+
+// close closes the layer, if it's open.
+func (s *chunkSource) close() {
+	if s.rc != nil {
+		s.rc.Close()
+		s.rc = nil
+	}
+}
@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/docker/model-runner/pkg/distribution/internal/gguf"
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// flakyRegistry serves an in-memory registry that fails the chunk uploads
+// for which fail returns true, given their 1-based number, with a server
+// error.
+func flakyRegistry(t *testing.T, fail func(n int32) bool) (string, *atomic.Int32) {
+	t.Helper()
+	handler := registry.New()
+	var patches, failures atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch && strings.Contains(r.URL.Path, "/blobs/uploads/") {
+			if fail(patches.Add(1)) {
+				failures.Add(1)
+				http.Error(w, "connection blip", http.StatusBadGateway)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+	return u.Host, &failures
+}
+
+// newTestModel creates a model from the dummy GGUF file.
+func newTestModel(t *testing.T) types.ModelArtifact {
+	t.Helper()
+	mdl, err := gguf.NewModel(filepath.Join("..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+	return mdl
+}
+
+// pushTestModel pushes mdl to tag with a small chunk size and no backoff.
+func pushTestModel(t *testing.T, mdl types.ModelArtifact, tag string, opts ...ClientOption) error {
+	t.Helper()
+	target, err := NewClient(append([]ClientOption{WithPushChunkSize(256)}, opts...)...).NewTarget(tag)
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	target.pushBackoff = func(int) time.Duration { return 0 }
+	return target.Write(t.Context(), mdl, nil)
+}
+
+func TestWriteRetriesFailedChunks(t *testing.T) {
+	host, failures := flakyRegistry(t, func(n int32) bool { return n == 3 || n == 6 })
+	tag := host + "/testmodel:v1"
+	mdl := newTestModel(t)
+	if err := pushTestModel(t, mdl, tag); err != nil {
+		t.Fatalf("Failed to push model: %v", err)
+	}
+	if n := failures.Load(); n != 2 {
+		t.Fatalf("Expected 2 failed chunk uploads, got %d", n)
+	}
+
+	expected, err := mdl.Digest()
+	if err != nil {
+		t.Fatalf("Failed to get model digest: %v", err)
+	}
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("Failed to parse reference: %v", err)
+	}
+	img, err := remote.Image(ref)
+	if err != nil {
+		t.Fatalf("Failed to get pushed model: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Failed to get pushed model digest: %v", err)
+	}
+	if digest != expected {
+		t.Errorf("Expected digest %s, got %s", expected, digest)
+	}
+}
+
+func TestWriteGivesUp(t *testing.T) {
+	host, failures := flakyRegistry(t, func(int32) bool { return true })
+	err := pushTestModel(t, newTestModel(t), host+"/testmodel:v1", WithPushRetries(2))
+	if err == nil {
+		t.Fatal("Expected push to fail")
+	}
+	if !strings.Contains(err.Error(), "giving up after 2 retries") {
+		t.Errorf("Expected push to give up after 2 retries, got %v", err)
+	}
+	if n := failures.Load(); n != 3 {
+		t.Errorf("Expected 3 failed chunk uploads, got %d", n)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header   string
+		received int64
+		ok       bool
+	}{
+		{"0-1023", 1024, true},
+		{"bytes=0-1023", 1024, true},
+		{"0-0", 0, true},
+		{"", 0, false},
+		{"512-1023", 0, false},
+	}
+	for _, tt := range tests {
+		received, ok := parseRange(tt.header)
+		if received != tt.received || ok != tt.ok {
+			t.Errorf("parseRange(%q) = %d, %v, expected %d, %v", tt.header, received, ok, tt.received, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package resolver maps short model names, such as "internal/chat-prod", to
+// full registry references before a model is pulled or inspected. This lets
+// application configs refer to stable names while infrastructure controls
+// where the actual artifacts live.
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotResolved is returned by a Resolver that has no mapping for a name.
+// Callers treat it as "use the name as-is".
+var ErrNotResolved = errors.New("name not resolved")
+
+// Resolver maps a model name to a full registry reference.
+type Resolver interface {
+	// Resolve returns the reference that name maps to, or ErrNotResolved if
+	// the resolver doesn't know about name.
+	Resolve(ctx context.Context, name string) (string, error)
+}
+
+// Resolve resolves name using r. It returns name unchanged if r is nil or
+// doesn't know about name.
+func Resolve(ctx context.Context, r Resolver, name string) (string, error) {
+	if r == nil {
+		return name, nil
+	}
+	reference, err := r.Resolve(ctx, name)
+	if errors.Is(err, ErrNotResolved) {
+		return name, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolving model name %q: %w", name, err)
+	}
+	return reference, nil
+}
+
+// Static resolves names from a fixed table.
+type Static map[string]string
+
+// Resolve implements Resolver.Resolve. Names carrying the implicit ":latest"
+// tag also match entries without a tag.
+func (s Static) Resolve(_ context.Context, name string) (string, error) {
+	if reference, ok := s[name]; ok {
+		return reference, nil
+	}
+	if base, ok := strings.CutSuffix(name, ":latest"); ok {
+		if reference, ok := s[base]; ok {
+			return reference, nil
+		}
+	}
+	return "", ErrNotResolved
+}
+
+// Chain tries each resolver in order and returns the first match.
+type Chain []Resolver
+
+// Resolve implements Resolver.Resolve.
+func (c Chain) Resolve(ctx context.Context, name string) (string, error) {
+	for _, r := range c {
+		reference, err := r.Resolve(ctx, name)
+		if errors.Is(err, ErrNotResolved) {
+			continue
+		}
+		return reference, err
+	}
+	return "", ErrNotResolved
+}
+
+// Catalog resolves names using a catalog service. The service is queried with
+// GET <baseURL>/resolve?name=<name> and must respond with either 200 and a
+// JSON body of the form {"reference": "<registry reference>"}, or 404 if it
+// doesn't know about the name.
+type Catalog struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// catalogResponse is the body returned by a catalog service.
+type catalogResponse struct {
+	Reference string `json:"reference"`
+}
+
+// NewCatalog creates a new Catalog resolver for the service at baseURL using
+// the given transport.
+func NewCatalog(baseURL string, transport http.RoundTripper) *Catalog {
+	return &Catalog{
+		httpClient: &http.Client{Transport: transport},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Resolve implements Resolver.Resolve.
+func (c *Catalog) Resolve(ctx context.Context, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.baseURL+"/resolve?name="+url.QueryEscape(name), nil)
+	if err != nil {
+		return "", fmt.Errorf("creating catalog request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("querying catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrNotResolved
+	default:
+		return "", fmt.Errorf("catalog returned unexpected status: %s", resp.Status)
+	}
+
+	var body catalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding catalog response: %w", err)
+	}
+	if body.Reference == "" {
+		return "", fmt.Errorf("catalog returned an empty reference for %q", name)
+	}
+	return body.Reference, nil
+}
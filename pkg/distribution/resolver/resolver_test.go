@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatic(t *testing.T) {
+	r := Static{
+		"internal/chat-prod":     "registry.example.com/models/llama:v3",
+		"internal/embed:staging": "registry.example.com/models/embed:rc1",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "exact match", input: "internal/chat-prod", expected: "registry.example.com/models/llama:v3"},
+		{name: "implicit latest tag", input: "internal/chat-prod:latest", expected: "registry.example.com/models/llama:v3"},
+		{name: "explicit tag", input: "internal/embed:staging", expected: "registry.example.com/models/embed:rc1"},
+		{name: "unknown tag", input: "internal/chat-prod:v2", err: ErrNotResolved},
+		{name: "unknown name", input: "ai/smollm2:latest", err: ErrNotResolved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(context.Background(), tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolveFallsBackToName(t *testing.T) {
+	got, err := Resolve(context.Background(), Static{}, "ai/smollm2:latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "ai/smollm2:latest" {
+		t.Errorf("Expected name to be returned unchanged, got %q", got)
+	}
+
+	got, err = Resolve(context.Background(), nil, "ai/smollm2:latest")
+	if err != nil || got != "ai/smollm2:latest" {
+		t.Errorf("Expected nil resolver to return name unchanged, got %q, %v", got, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	r := Chain{
+		Static{"a": "registry.example.com/a:1"},
+		Static{"a": "registry.example.com/a:2", "b": "registry.example.com/b:1"},
+	}
+
+	if got, err := r.Resolve(context.Background(), "a"); err != nil || got != "registry.example.com/a:1" {
+		t.Errorf("Expected first resolver to win, got %q, %v", got, err)
+	}
+	if got, err := r.Resolve(context.Background(), "b"); err != nil || got != "registry.example.com/b:1" {
+		t.Errorf("Expected fallback to second resolver, got %q, %v", got, err)
+	}
+	if _, err := r.Resolve(context.Background(), "c"); !errors.Is(err, ErrNotResolved) {
+		t.Errorf("Expected ErrNotResolved, got %v", err)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/resolve" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("name") {
+		case "internal/chat-prod:latest":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"reference":"registry.example.com/models/llama:v3"}`))
+		case "internal/broken:latest":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewCatalog(server.URL+"/", http.DefaultTransport)
+
+	got, err := c.Resolve(context.Background(), "internal/chat-prod:latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "registry.example.com/models/llama:v3" {
+		t.Errorf("Expected resolved reference, got %q", got)
+	}
+
+	if _, err := c.Resolve(context.Background(), "ai/smollm2:latest"); !errors.Is(err, ErrNotResolved) {
+		t.Errorf("Expected ErrNotResolved, got %v", err)
+	}
+
+	if _, err := c.Resolve(context.Background(), "internal/broken:latest"); err == nil || errors.Is(err, ErrNotResolved) {
+		t.Errorf("Expected catalog error, got %v", err)
+	}
+}
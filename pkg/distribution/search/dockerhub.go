@@ -0,0 +1,158 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/model-runner/pkg/distribution/huggingface"
+)
+
+const (
+	// DockerHubName is the name of the Docker Hub source.
+	DockerHubName = "dockerhub"
+	// DefaultDockerHubURL is the base URL of the Docker Hub API.
+	DefaultDockerHubURL = "https://hub.docker.com"
+	// defaultNamespace is the Docker Hub namespace of the model catalog.
+	defaultNamespace = "ai"
+	// maxPages is the maximum number of repository pages fetched per search.
+	maxPages = 10
+)
+
+// DockerHub searches the model catalog on Docker Hub. The catalog is small,
+// so it's listed in full and filtered locally, which allows matching on
+// descriptions as well as names.
+type DockerHub struct {
+	httpClient *http.Client
+	apiURL     string
+	namespace  string
+}
+
+// NewDockerHub creates a new Docker Hub source using the given transport. If
+// apiURL is empty, DefaultDockerHubURL is used, and if namespace is empty, the
+// ai namespace is searched.
+func NewDockerHub(transport http.RoundTripper, apiURL, namespace string) *DockerHub {
+	if apiURL == "" {
+		apiURL = DefaultDockerHubURL
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return &DockerHub{
+		httpClient: &http.Client{Transport: transport},
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		namespace:  namespace,
+	}
+}
+
+// Name implements Source.Name.
+func (d *DockerHub) Name() string {
+	return DockerHubName
+}
+
+// hubRepositories is a page of repositories returned by Docker Hub.
+type hubRepositories struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		PullCount   int64  `json:"pull_count"`
+	} `json:"results"`
+}
+
+// hubTags is a page of tags returned by Docker Hub.
+type hubTags struct {
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+// Search implements Source.Search. Quantizations are derived from the tags of
+// the matching repositories, which are named after them, e.g. "8B-Q4_K_M".
+func (d *DockerHub) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	query = strings.ToLower(query)
+	var results []Result
+	next := fmt.Sprintf("%s/v2/namespaces/%s/repositories?page_size=100", d.apiURL, url.PathEscape(d.namespace))
+	for page := 0; next != "" && page < maxPages; page++ {
+		var repos hubRepositories
+		if err := d.get(ctx, next, &repos); err != nil {
+			return nil, fmt.Errorf("listing repositories: %w", err)
+		}
+		for _, repo := range repos.Results {
+			if !strings.Contains(strings.ToLower(repo.Name), query) &&
+				!strings.Contains(strings.ToLower(repo.Description), query) {
+				continue
+			}
+			results = append(results, Result{
+				Name:        d.namespace + "/" + repo.Name,
+				Description: repo.Description,
+				Downloads:   repo.PullCount,
+				Source:      DockerHubName,
+			})
+		}
+		next = repos.Next
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Downloads > results[j].Downloads
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	// Listing tags is best effort, results without quantizations are still
+	// useful.
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].Quantizations, _ = d.quantizations(ctx, results[i].Name)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// quantizations returns the quantizations available for a repository.
+func (d *DockerHub) quantizations(ctx context.Context, repository string) ([]string, error) {
+	name := strings.TrimPrefix(repository, d.namespace+"/")
+	var tags hubTags
+	endpoint := fmt.Sprintf("%s/v2/namespaces/%s/repositories/%s/tags?page_size=100",
+		d.apiURL, url.PathEscape(d.namespace), url.PathEscape(name))
+	if err := d.get(ctx, endpoint, &tags); err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", repository, err)
+	}
+	var quants []string
+	for _, tag := range tags.Results {
+		quants = addQuantization(quants, huggingface.QuantizationFromFilename(tag.Name))
+	}
+	sort.Strings(quants)
+	return quants, nil
+}
+
+// get fetches a JSON document from Docker Hub.
+func (d *DockerHub) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/huggingface"
+)
+
+// HuggingFaceName is the name of the Hugging Face source.
+const HuggingFaceName = "huggingface"
+
+// HuggingFace searches GGUF models on the Hugging Face Hub.
+type HuggingFace struct {
+	httpClient *http.Client
+	apiURL     string
+}
+
+// NewHuggingFace creates a new Hugging Face source using the given transport.
+// If apiURL is empty, huggingface.DefaultAPIURL is used.
+func NewHuggingFace(transport http.RoundTripper, apiURL string) *HuggingFace {
+	if apiURL == "" {
+		apiURL = huggingface.DefaultAPIURL
+	}
+	return &HuggingFace{
+		httpClient: &http.Client{Transport: transport},
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+	}
+}
+
+// Name implements Source.Name.
+func (h *HuggingFace) Name() string {
+	return HuggingFaceName
+}
+
+// Search implements Source.Search. Quantizations are derived from the names of
+// the GGUF files in each repository.
+func (h *HuggingFace) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	params := url.Values{
+		"search":    {query},
+		"filter":    {"gguf"},
+		"sort":      {"downloads"},
+		"direction": {"-1"},
+		"limit":     {strconv.Itoa(limit)},
+		"full":      {"true"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.apiURL+"/api/models?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var models []struct {
+		ID        string `json:"id"`
+		Downloads int64  `json:"downloads"`
+		Siblings  []struct {
+			Filename string `json:"rfilename"`
+		} `json:"siblings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	results := make([]Result, 0, len(models))
+	for _, model := range models {
+		result := Result{
+			Name:      huggingface.Registry + "/" + strings.ToLower(model.ID),
+			Downloads: model.Downloads,
+			Source:    HuggingFaceName,
+		}
+		for _, sibling := range model.Siblings {
+			lower := strings.ToLower(sibling.Filename)
+			if !strings.HasSuffix(lower, ".gguf") || strings.Contains(lower, "mmproj") {
+				continue
+			}
+			result.Quantizations = addQuantization(result.Quantizations, huggingface.QuantizationFromFilename(sibling.Filename))
+		}
+		sort.Strings(result.Quantizations)
+		results = append(results, result)
+	}
+	return results, nil
+}
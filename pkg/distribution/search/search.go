@@ -0,0 +1,161 @@
+// Package search finds models in the Docker Hub model catalog and on Hugging
+// Face, so that users can discover models without leaving the CLI.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultLimit is the number of results returned if no limit is given.
+	DefaultLimit = 25
+	// MaxLimit is the maximum number of results returned by a search.
+	MaxLimit = 100
+)
+
+// ErrUnknownSource indicates that a search was requested from a source that
+// doesn't exist.
+var ErrUnknownSource = errors.New("unknown search source")
+
+// Result is a model found by a search.
+type Result struct {
+	// Name is the reference under which the model can be pulled.
+	Name string `json:"name"`
+	// Description is a short description of the model, if known.
+	Description string `json:"description,omitempty"`
+	// Downloads is the number of times the model was downloaded.
+	Downloads int64 `json:"downloads"`
+	// Quantizations are the quantizations in which the model is available.
+	Quantizations []string `json:"quantizations,omitempty"`
+	// Source is the name of the source the model was found in.
+	Source string `json:"source"`
+}
+
+// Source is a searchable collection of models.
+type Source interface {
+	// Name returns the name of the source.
+	Name() string
+	// Search returns up to limit models matching query, most downloaded
+	// first.
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// Searcher searches a set of sources.
+type Searcher struct {
+	sources []Source
+}
+
+// NewSearcher creates a new Searcher for the given sources.
+func NewSearcher(sources ...Source) *Searcher {
+	return &Searcher{sources: sources}
+}
+
+// NewDefaultSearcher creates a Searcher for the Docker Hub catalog and Hugging
+// Face using the given transport.
+func NewDefaultSearcher(transport http.RoundTripper) *Searcher {
+	return NewSearcher(
+		NewDockerHub(transport, "", ""),
+		NewHuggingFace(transport, ""),
+	)
+}
+
+// Sources returns the names of the sources that can be searched.
+func (s *Searcher) Sources() []string {
+	names := make([]string, len(s.sources))
+	for i, source := range s.sources {
+		names[i] = source.Name()
+	}
+	return names
+}
+
+// Search searches the sources with the given names, or all sources if names
+// is empty, and returns up to limit results, most downloaded first. Sources are
+// searched concurrently. Failures of individual sources are returned alongside
+// the results of the others, and only if no source succeeds are no results
+// returned.
+func (s *Searcher) Search(ctx context.Context, query string, limit int, names ...string) ([]Result, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	sources := s.sources
+	if len(names) > 0 {
+		sources = nil
+		for _, name := range names {
+			source, ok := s.source(name)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownSource, name)
+			}
+			sources = append(sources, source)
+		}
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]Result, len(sources))
+	errs := make([]error, len(sources))
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = source.Search(ctx, query, limit)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("searching %s: %w", source.Name(), errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var merged []Result
+	succeeded := false
+	for i := range sources {
+		if errs[i] == nil {
+			succeeded = true
+			merged = append(merged, results[i]...)
+		}
+	}
+	err := errors.Join(errs...)
+	if !succeeded && err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Downloads > merged[j].Downloads
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	if merged == nil {
+		merged = []Result{}
+	}
+	return merged, err
+}
+
+func (s *Searcher) source(name string) (Source, bool) {
+	for _, source := range s.sources {
+		if strings.EqualFold(source.Name(), name) {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// addQuantization appends quant to quants if it's not empty and not already
+// present.
+func addQuantization(quants []string, quant string) []string {
+	if quant == "" {
+		return quants
+	}
+	for _, q := range quants {
+		if q == quant {
+			return quants
+		}
+	}
+	return append(quants, quant)
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/namespaces/ai/repositories", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"results": [{"name": "qwen3", "description": "Qwen3 models", "pull_count": 300}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"next": "http://%s/v2/namespaces/ai/repositories?page=2", "results": [
+			{"name": "llama3.2", "description": "Meta's Llama 3.2", "pull_count": 100},
+			{"name": "smollm2", "description": "Small language model", "pull_count": 200}
+		]}`, r.Host)
+	})
+	mux.HandleFunc("GET /v2/namespaces/ai/repositories/llama3.2/tags", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"results": [{"name": "latest"}, {"name": "1B-Q8_0"}, {"name": "3B-Q4_K_M"}, {"name": "1B-Q4_K_M"}]}`)
+	})
+	mux.HandleFunc("GET /v2/namespaces/ai/repositories/qwen3/tags", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("GET /api/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filter") != "gguf" {
+			t.Errorf("Expected gguf filter, got %q", r.URL.Query().Get("filter"))
+		}
+		fmt.Fprint(w, `[{"id": "Org/Llama-GGUF", "downloads": 250, "siblings": [
+			{"rfilename": "README.md"},
+			{"rfilename": "llama-Q4_K_M.gguf"},
+			{"rfilename": "llama-F16.gguf"},
+			{"rfilename": "mmproj-llama-F16.gguf"}
+		]}]`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSearch(t *testing.T) {
+	server := newTestServer(t)
+	searcher := NewSearcher(
+		NewDockerHub(server.Client().Transport, server.URL, ""),
+		NewHuggingFace(server.Client().Transport, server.URL),
+	)
+
+	tests := []struct {
+		name    string
+		query   string
+		limit   int
+		sources []string
+		want    []Result
+	}{
+		{
+			name:    "matches names across pages",
+			query:   "QWEN",
+			sources: []string{DockerHubName},
+			want: []Result{
+				{Name: "ai/qwen3", Description: "Qwen3 models", Downloads: 300, Source: DockerHubName},
+			},
+		},
+		{
+			name:    "matches descriptions",
+			query:   "llama",
+			sources: []string{DockerHubName},
+			want: []Result{
+				{Name: "ai/llama3.2", Description: "Meta's Llama 3.2", Downloads: 100, Quantizations: []string{"Q4_K_M", "Q8_0"}, Source: DockerHubName},
+			},
+		},
+		{
+			name:  "merges sources by downloads",
+			query: "llama",
+			want: []Result{
+				{Name: "hf.co/org/llama-gguf", Downloads: 250, Quantizations: []string{"F16", "Q4_K_M"}, Source: HuggingFaceName},
+				{Name: "ai/llama3.2", Description: "Meta's Llama 3.2", Downloads: 100, Quantizations: []string{"Q4_K_M", "Q8_0"}, Source: DockerHubName},
+			},
+		},
+		{
+			name:  "limits results",
+			query: "l",
+			limit: 2,
+			want: []Result{
+				{Name: "ai/qwen3", Description: "Qwen3 models", Downloads: 300, Source: DockerHubName},
+				{Name: "hf.co/org/llama-gguf", Downloads: 250, Quantizations: []string{"F16", "Q4_K_M"}, Source: HuggingFaceName},
+			},
+		},
+		{
+			name:    "no matches",
+			query:   "mistral",
+			sources: []string{DockerHubName},
+			want:    []Result{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searcher.Search(context.Background(), tt.query, tt.limit, tt.sources...)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	server := newTestServer(t)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	t.Run("unknown source", func(t *testing.T) {
+		searcher := NewSearcher(NewDockerHub(server.Client().Transport, server.URL, ""))
+		if _, err := searcher.Search(context.Background(), "llama", 0, "quay"); !errors.Is(err, ErrUnknownSource) {
+			t.Errorf("Expected ErrUnknownSource, got %v", err)
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		searcher := NewSearcher(
+			NewDockerHub(server.Client().Transport, server.URL, ""),
+			NewHuggingFace(failing.Client().Transport, failing.URL),
+		)
+		results, err := searcher.Search(context.Background(), "llama", 0)
+		if err == nil {
+			t.Error("Expected an error for the failing source")
+		}
+		if len(results) != 1 || results[0].Name != "ai/llama3.2" {
+			t.Errorf("Expected results from the working source, got %+v", results)
+		}
+	})
+
+	t.Run("total failure", func(t *testing.T) {
+		searcher := NewSearcher(NewHuggingFace(failing.Client().Transport, failing.URL))
+		results, err := searcher.Search(context.Background(), "llama", 0)
+		if err == nil || results != nil {
+			t.Errorf("Expected an error and no results, got %+v, %v", results, err)
+		}
+	})
+}
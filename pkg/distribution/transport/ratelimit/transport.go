@@ -0,0 +1,272 @@
+// Package ratelimit provides an http.RoundTripper that tracks the pull quotas
+// registries report in RateLimit headers, as Docker Hub does, and waits for
+// and retries requests rejected with 429 Too Many Requests rather than
+// failing them.
+//
+// Docker Hub reports quotas as "RateLimit-Limit: 100;w=21600" and
+// "RateLimit-Remaining: 76;w=21600", where w is the window in seconds, along
+// with "Docker-RateLimit-Source", which identifies the client the quota is
+// counted for.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxWait is the total time a request waits for a registry's rate
+	// limit to allow it before it fails.
+	DefaultMaxWait = 15 * time.Minute
+)
+
+// Quota is the pull quota of a registry, as last reported by it.
+type Quota struct {
+	// Registry is the host of the registry.
+	Registry string `json:"registry"`
+	// Limit is the number of pulls allowed per window, or zero if the
+	// registry didn't report it.
+	Limit int `json:"limit"`
+	// Remaining is the number of pulls left in the current window.
+	Remaining int `json:"remaining"`
+	// WindowSeconds is the length of the window.
+	WindowSeconds int `json:"window_seconds,omitempty"`
+	// Source identifies the client the quota is counted for, such as its IP
+	// address for anonymous pulls.
+	Source string `json:"source,omitempty"`
+	// Limited indicates that the last request was rejected by the rate
+	// limit.
+	Limited bool `json:"limited"`
+	// Updated is when the quota was reported.
+	Updated time.Time `json:"updated"`
+}
+
+// Tracker records the last quota reported by each registry. It's safe for
+// concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	quotas map[string]Quota
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{quotas: make(map[string]Quota)}
+}
+
+// Quotas returns the last quota reported by each registry, ordered by
+// registry.
+func (t *Tracker) Quotas() []Quota {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	quotas := make([]Quota, 0, len(t.quotas))
+	for _, q := range t.quotas {
+		quotas = append(quotas, q)
+	}
+	slices.SortFunc(quotas, func(a, b Quota) int { return strings.Compare(a.Registry, b.Registry) })
+	return quotas
+}
+
+// update records the quota reported in a response of registry, returning it
+// and whether the response reported one or was rate limited.
+func (t *Tracker) update(registry string, resp *http.Response) (Quota, bool) {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	limit, window, hasLimit := parseQuota(resp.Header.Get("RateLimit-Limit"))
+	remaining, _, hasRemaining := parseQuota(resp.Header.Get("RateLimit-Remaining"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q, known := t.quotas[registry]
+	if !limited && !hasLimit && !hasRemaining {
+		if known && q.Limited {
+			q.Limited = false
+			t.quotas[registry] = q
+		}
+		return Quota{}, false
+	}
+	q.Registry = registry
+	if hasLimit {
+		q.Limit = limit
+		q.WindowSeconds = window
+	}
+	if hasRemaining {
+		q.Remaining = remaining
+	} else if limited {
+		q.Remaining = 0
+	}
+	if source := resp.Header.Get("Docker-RateLimit-Source"); source != "" {
+		q.Source = source
+	}
+	q.Limited = limited
+	q.Updated = time.Now()
+	t.quotas[registry] = q
+	return q, true
+}
+
+// parseQuota parses a RateLimit header value, as in 100;w=21600, returning
+// the quota and the window in seconds, if any.
+func parseQuota(value string) (int, int, bool) {
+	if value == "" {
+		return 0, 0, false
+	}
+	quota, params, _ := strings.Cut(value, ";")
+	n, err := strconv.Atoi(strings.TrimSpace(quota))
+	if err != nil {
+		return 0, 0, false
+	}
+	var window int
+	for _, param := range strings.Split(params, ";") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(param), "w="); ok {
+			window, _ = strconv.Atoi(v)
+		}
+	}
+	return n, window, true
+}
+
+// Event reports the quota of a registry observed by a request.
+type Event struct {
+	Quota Quota
+	// Wait is how long the request waits before it's retried, if it was
+	// rate limited.
+	Wait time.Duration
+}
+
+type observerKey struct{}
+
+// WithObserver returns a copy of ctx with which requests report the quotas
+// they observe, and their waits for rate limits, to observe. It may be called
+// concurrently by concurrent requests.
+func WithObserver(ctx context.Context, observe func(Event)) context.Context {
+	return context.WithValue(ctx, observerKey{}, observe)
+}
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithMaxWait sets the total time a request waits for rate limits before the
+// rate-limited response is returned. Zero disables retries. Default:
+// DefaultMaxWait.
+func WithMaxWait(d time.Duration) Option {
+	return func(t *Transport) { t.maxWait = d }
+}
+
+// BackoffFunc computes the wait before a given retry attempt (0-based) of a
+// request rate limited without a Retry-After header.
+type BackoffFunc func(attempt int) time.Duration
+
+// WithBackoff sets the backoff strategy for rate-limited requests.
+// Default: exponential starting at 5s, capped at 5 minutes.
+func WithBackoff(f BackoffFunc) Option {
+	return func(t *Transport) { t.backoff = f }
+}
+
+// Transport wraps another http.RoundTripper, recording the quotas reported by
+// registries in a Tracker, and retrying requests without a body, or whose
+// body can be replayed, that are rate limited.
+type Transport struct {
+	base    http.RoundTripper
+	tracker *Tracker
+	maxWait time.Duration
+	backoff BackoffFunc
+}
+
+// New returns a Transport wrapping base that records quotas in tracker. If
+// base is nil, http.DefaultTransport is used.
+func New(base http.RoundTripper, tracker *Tracker, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		base:    base,
+		tracker: tracker,
+		maxWait: DefaultMaxWait,
+		backoff: func(attempt int) time.Duration {
+			return min(5*time.Second<<min(attempt, 6), 5*time.Minute)
+		},
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	observe, _ := req.Context().Value(observerKey{}).(func(Event))
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+		quota, ok := t.tracker.update(req.URL.Host, resp)
+		if resp.StatusCode != http.StatusTooManyRequests || !replayable(req) {
+			if ok && observe != nil {
+				observe(Event{Quota: quota})
+			}
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"))
+		if !ok {
+			wait = t.backoff(attempt)
+		}
+		if waited+wait > t.maxWait {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if observe != nil {
+			observe(Event{Quota: quota, Wait: wait})
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		waited += wait
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// replayable reports whether req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as a date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
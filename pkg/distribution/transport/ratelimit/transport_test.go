@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hub serves responses with Docker Hub's rate-limit headers, rejecting the
+// first limited requests with 429 Too Many Requests.
+func hub(t *testing.T, limited int32, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("RateLimit-Limit", "100;w=21600")
+		w.Header().Set("Docker-RateLimit-Source", "192.0.2.1")
+		if n <= limited {
+			w.Header().Set("RateLimit-Remaining", "0;w=21600")
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("RateLimit-Remaining", "76;w=21600")
+		io.WriteString(w, "manifest")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestRoundTripRecordsQuota(t *testing.T) {
+	server, _ := hub(t, 0, "")
+	tracker := NewTracker()
+	client := &http.Client{Transport: New(nil, tracker)}
+
+	var events []Event
+	ctx := WithObserver(t.Context(), func(e Event) { events = append(events, e) })
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	u, _ := url.Parse(server.URL)
+	quotas := tracker.Quotas()
+	if len(quotas) != 1 {
+		t.Fatalf("Expected 1 quota, got %v", quotas)
+	}
+	q := quotas[0]
+	if q.Registry != u.Host || q.Limit != 100 || q.Remaining != 76 || q.WindowSeconds != 21600 || q.Source != "192.0.2.1" || q.Limited {
+		t.Errorf("Unexpected quota %+v", q)
+	}
+	if len(events) != 1 || events[0].Wait != 0 || events[0].Quota.Remaining != 76 {
+		t.Errorf("Expected a quota event, got %+v", events)
+	}
+}
+
+func TestRoundTripRetriesRateLimited(t *testing.T) {
+	server, requests := hub(t, 2, "")
+	tracker := NewTracker()
+	client := &http.Client{Transport: New(nil, tracker, WithBackoff(noBackoff))}
+
+	var waits int
+	ctx := WithObserver(t.Context(), func(e Event) {
+		if e.Quota.Limited {
+			waits++
+		}
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request to succeed after retries, got %s", resp.Status)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+	if waits != 2 {
+		t.Errorf("Expected 2 waits to be reported, got %d", waits)
+	}
+	if q := tracker.Quotas()[0]; q.Limited || q.Remaining != 76 {
+		t.Errorf("Expected the quota to be restored, got %+v", q)
+	}
+}
+
+func TestRoundTripGivesUp(t *testing.T) {
+	// Retry-After beyond the maximum wait returns the rate-limited response.
+	server, requests := hub(t, 10, "3600")
+	client := &http.Client{Transport: New(nil, NewTracker(), WithMaxWait(time.Minute))}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 1 {
+		t.Errorf("Expected a single rate-limited response, got %s after %d requests", resp.Status, requests.Load())
+	}
+
+	// Waiting is interrupted by the request's context.
+	server, _ = hub(t, 10, "")
+	client = &http.Client{Transport: New(nil, NewTracker())}
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if _, err := client.Do(req); err == nil {
+		t.Error("Expected the request to be canceled while waiting")
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		value  string
+		quota  int
+		window int
+		ok     bool
+	}{
+		{"100;w=21600", 100, 21600, true},
+		{"76", 76, 0, true},
+		{" 5 ; w=60", 5, 60, true},
+		{"", 0, 0, false},
+		{"many", 0, 0, false},
+	}
+	for _, tt := range tests {
+		quota, window, ok := parseQuota(tt.value)
+		if quota != tt.quota || window != tt.window || ok != tt.ok {
+			t.Errorf("parseQuota(%q) = %d, %d, %v, expected %d, %d, %v", tt.value, quota, window, ok, tt.quota, tt.window, tt.ok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"strings"
 	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -23,22 +24,89 @@ const (
 	// MediaTypeVLLMConfigArchive indicates a tar archive containing vLLM-specific config files.
 	MediaTypeVLLMConfigArchive = types.MediaType("application/vnd.docker.ai.vllm.config.tar")
 
+	// MediaTypeVLLMConfigArchiveZstd indicates a zstd compressed MediaTypeVLLMConfigArchive.
+	MediaTypeVLLMConfigArchiveZstd = types.MediaType("application/vnd.docker.ai.vllm.config.tar+zstd")
+
 	// MediaTypeDirTar indicates a tar archive containing a directory with its structure preserved.
 	MediaTypeDirTar = types.MediaType("application/vnd.docker.ai.dir.tar")
 
 	// MediaTypeLicense indicates a plain text file containing a license
 	MediaTypeLicense = types.MediaType("application/vnd.docker.ai.license")
 
+	// MediaTypeLicenseZstd indicates a zstd compressed MediaTypeLicense.
+	MediaTypeLicenseZstd = types.MediaType("application/vnd.docker.ai.license+zstd")
+
 	// MediaTypeMultimodalProjector indicates a Multimodal projector file
 	MediaTypeMultimodalProjector = types.MediaType("application/vnd.docker.ai.mmproj")
 
 	// MediaTypeChatTemplate indicates a Jinja chat template
 	MediaTypeChatTemplate = types.MediaType("application/vnd.docker.ai.chat.template.jinja")
 
+	// MediaTypeChatTemplateZstd indicates a zstd compressed MediaTypeChatTemplate.
+	MediaTypeChatTemplateZstd = types.MediaType("application/vnd.docker.ai.chat.template.jinja+zstd")
+
+	// MediaTypeLoRAAdapter indicates a LoRA adapter in GGUF format, applied on top of the base model weights
+	MediaTypeLoRAAdapter = types.MediaType("application/vnd.docker.ai.lora.adapter.gguf")
+
+	// MediaTypeWhisperGGUF indicates a whisper.cpp speech-to-text model in GGUF format
+	MediaTypeWhisperGGUF = types.MediaType("application/vnd.docker.ai.whisper.gguf")
+
+	// MediaTypePiperVoice indicates a piper text-to-speech voice in ONNX format
+	MediaTypePiperVoice = types.MediaType("application/vnd.docker.ai.piper.voice.onnx")
+
+	// MediaTypePiperVoiceConfig indicates the JSON config of a piper voice, such as its sample rate and phonemes
+	MediaTypePiperVoiceConfig = types.MediaType("application/vnd.docker.ai.piper.voice.config+json")
+
+	// MediaTypeDiffusion indicates a stable-diffusion.cpp image generation checkpoint, in GGUF or safetensors format
+	MediaTypeDiffusion = types.MediaType("application/vnd.docker.ai.diffusion")
+
+	// MediaTypeONNX indicates an embedding or classification model in ONNX format, served by ONNX Runtime
+	MediaTypeONNX = types.MediaType("application/vnd.docker.ai.onnx")
+
+	// MediaTypeGGUFDelta indicates a binary delta that reconstructs a GGUF file
+	// from the GGUF file of another model, such as the base of a fine-tune.
+	MediaTypeGGUFDelta = types.MediaType("application/vnd.docker.ai.gguf.v3.delta+zstd")
+
+	// MediaTypeGGUFDeltaConfig is the config media type, and so the artifact
+	// type, of the artifacts that hold deltas, which refer to the model they
+	// reconstruct layers of as their subject.
+	MediaTypeGGUFDeltaConfig = types.MediaType("application/vnd.docker.ai.gguf.delta.config.v0.1+json")
+
+	// AnnotationDeltaBase annotates a delta layer with the DiffID of the layer
+	// it applies to.
+	AnnotationDeltaBase = "org.docker.ai.delta.base"
+
+	// AnnotationDeltaTarget annotates a delta layer with the DiffID of the
+	// layer it reconstructs.
+	AnnotationDeltaTarget = "org.docker.ai.delta.target"
+
 	FormatGGUF        = Format("gguf")
 	FormatSafetensors = Format("safetensors")
+	FormatWhisper     = Format("whisper")
+	FormatPiper       = Format("piper")
+	FormatDiffusion   = Format("diffusion")
+	FormatONNX        = Format("onnx")
 )
 
+// zstdSuffix is the structured syntax suffix of zstd compressed media types,
+// as in the OCI layer media types.
+const zstdSuffix = "+zstd"
+
+// IsZstd reports whether layers of the given media type are zstd compressed.
+func IsZstd(mt types.MediaType) bool {
+	return strings.HasSuffix(string(mt), zstdSuffix)
+}
+
+// Zstd returns the media type of zstd compressed layers of the given media
+// type. Only non-weight layers are compressed, since weights are
+// memory-mapped from the store.
+func Zstd(mt types.MediaType) types.MediaType {
+	if IsZstd(mt) {
+		return mt
+	}
+	return mt + zstdSuffix
+}
+
 type Format string
 
 type ConfigFile struct {
@@ -56,7 +124,44 @@ type Config struct {
 	Size         string            `json:"size,omitempty"`
 	GGUF         map[string]string `json:"gguf,omitempty"`
 	Safetensors  map[string]string `json:"safetensors,omitempty"`
+	Piper        map[string]string `json:"piper,omitempty"`
 	ContextSize  *uint64           `json:"context_size,omitempty"`
+	// QuantizedFrom records the source of a model that was quantized during
+	// packaging.
+	QuantizedFrom *QuantizationInfo `json:"quantized_from,omitempty"`
+	// BaseModel records the model this artifact depends on, e.g. the base
+	// model of a LoRA adapter.
+	BaseModel *BaseModel `json:"base_model,omitempty"`
+	// LicenseAcceptanceRequired indicates that the licenses of the model must
+	// be accepted before it can be pulled.
+	LicenseAcceptanceRequired bool `json:"license_acceptance_required,omitempty"`
+	// RuntimeFlags are the inference server flags configured for the model,
+	// applied before any flags given when the model is loaded.
+	RuntimeFlags []string `json:"runtime_flags,omitempty"`
+	// Reranker indicates that the model scores the relevance of documents to
+	// a query, and is served in reranking mode.
+	Reranker bool `json:"reranker,omitempty"`
+	// Classifier indicates that the model assigns labels to its inputs, and
+	// is served in classification mode.
+	Classifier bool `json:"classifier,omitempty"`
+}
+
+// BaseModel identifies a model that an artifact depends on.
+type BaseModel struct {
+	// Digest is the manifest digest of the base model.
+	Digest string `json:"digest"`
+	// Reference is the reference the base model can be pulled from.
+	Reference string `json:"reference,omitempty"`
+}
+
+// QuantizationInfo describes how a model was quantized during packaging.
+type QuantizationInfo struct {
+	// SourceDigest is the digest of the GGUF file the model was quantized from.
+	SourceDigest string `json:"source_digest"`
+	// SourceQuantization is the quantization of the source GGUF file.
+	SourceQuantization string `json:"source_quantization,omitempty"`
+	// Target is the quantization type requested from the quantize tool.
+	Target string `json:"target"`
 }
 
 // Descriptor provides metadata about the provenance of the model.
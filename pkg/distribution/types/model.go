@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
@@ -14,6 +16,36 @@ type Model interface {
 	Tags() []string
 	Descriptor() (Descriptor, error)
 	ChatTemplatePath() (string, error)
+	LoRAAdapterPaths() ([]string, error)
+	WhisperPath() (string, error)
+	VoicePath() (string, error)
+	VoiceConfigPath() (string, error)
+	DiffusionPath() (string, error)
+	ONNXPath() (string, error)
+	LicensePaths() ([]string, error)
+	// Licenses returns the texts of the licenses, which, unlike the files at
+	// LicensePaths, are decompressed if their layers are zstd compressed.
+	Licenses() ([]string, error)
+	Metadata() Metadata
+	// Annotations returns the manifest annotations, such as labels set when
+	// the model was packaged.
+	Annotations() map[string]string
+}
+
+// Metadata describes the provenance and usage of a model in the local store.
+type Metadata struct {
+	// Created is when the model artifact was created, if known.
+	Created *time.Time `json:"created,omitempty"`
+	// Pulled is when the model was written to the store.
+	Pulled *time.Time `json:"pulled,omitempty"`
+	// Source is the registry reference the model was pulled from, if any.
+	Source string `json:"source,omitempty"`
+	// PullDigest is the digest that Source resolved to when it was pulled.
+	PullDigest string `json:"pull_digest,omitempty"`
+	// Size is the total size of the model's blobs in bytes.
+	Size int64 `json:"size,omitempty"`
+	// LastUsed is when the model was last loaded for inference.
+	LastUsed *time.Time `json:"last_used,omitempty"`
 }
 
 type ModelArtifact interface {
@@ -29,5 +61,10 @@ type ModelBundle interface {
 	SafetensorsPath() string
 	ChatTemplatePath() string
 	MMPROJPath() string
+	LoRAAdapterPaths() []string
+	WhisperPath() string
+	VoicePath() string
+	DiffusionPath() string
+	ONNXPath() string
 	RuntimeConfig() Config
 }
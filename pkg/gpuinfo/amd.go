@@ -0,0 +1,179 @@
+package gpuinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// amdVendorID is the PCI vendor ID of AMD.
+const amdVendorID = "0x1002"
+
+// drmCard matches the names of DRM cards in sysfs, excluding their connectors.
+var drmCard = regexp.MustCompile(`^card(\d+)$`)
+
+// amdGPUMemory is the VRAM of an AMD GPU in bytes.
+type amdGPUMemory struct {
+	total uint64
+	used  uint64
+}
+
+// HasAMDGPU reports whether the system has an AMD GPU.
+func HasAMDGPU() bool {
+	gpus, err := getAMDGPUMemory()
+	return err == nil && len(gpus) > 0
+}
+
+// getAMDGPUMemorySizes returns the VRAM of each AMD GPU in bytes, in DRM card
+// order.
+func getAMDGPUMemorySizes() ([]uint64, error) {
+	gpus, err := getAMDGPUMemory()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]uint64, len(gpus))
+	for i, gpu := range gpus {
+		sizes[i] = gpu.total
+	}
+	return sizes, nil
+}
+
+// getAMDGPUFreeMemorySizes returns the unused VRAM of each AMD GPU in bytes,
+// in DRM card order.
+func getAMDGPUFreeMemorySizes() ([]uint64, error) {
+	gpus, err := getAMDGPUMemory()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]uint64, len(gpus))
+	for i, gpu := range gpus {
+		sizes[i] = gpu.total - min(gpu.used, gpu.total)
+	}
+	return sizes, nil
+}
+
+// getAMDGPUMemory returns the VRAM of each AMD GPU, in DRM card order. It reads
+// the VRAM from sysfs, falling back to rocm-smi.
+func getAMDGPUMemory() ([]amdGPUMemory, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("AMD GPUs are only supported on linux")
+	}
+	if gpus, err := readAMDGPUMemory("/sys/class/drm"); err == nil {
+		return gpus, nil
+	}
+	rocmSMI, err := exec.LookPath("rocm-smi")
+	if err != nil {
+		return nil, errors.New("no AMD GPUs found")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, rocmSMI, "--showmeminfo", "vram", "--json").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseROCmSMIMemory(out)
+}
+
+// readAMDGPUMemory reads the VRAM of each AMD GPU from the DRM cards in the
+// given sysfs directory.
+func readAMDGPUMemory(drmPath string) ([]amdGPUMemory, error) {
+	entries, err := os.ReadDir(drmPath)
+	if err != nil {
+		return nil, err
+	}
+	type card struct {
+		index  int
+		memory amdGPUMemory
+	}
+	var cards []card
+	for _, entry := range entries {
+		match := drmCard.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		device := filepath.Join(drmPath, entry.Name(), "device")
+		vendor, err := os.ReadFile(filepath.Join(device, "vendor"))
+		if err != nil || strings.TrimSpace(string(vendor)) != amdVendorID {
+			continue
+		}
+		total, err := os.ReadFile(filepath.Join(device, "mem_info_vram_total"))
+		if err != nil {
+			continue
+		}
+		vram, err := strconv.ParseUint(strings.TrimSpace(string(total)), 10, 64)
+		if err != nil || vram == 0 {
+			continue
+		}
+		var usedVRAM uint64
+		if used, err := os.ReadFile(filepath.Join(device, "mem_info_vram_used")); err == nil {
+			usedVRAM, _ = strconv.ParseUint(strings.TrimSpace(string(used)), 10, 64)
+		}
+		index, _ := strconv.Atoi(match[1])
+		cards = append(cards, card{index, amdGPUMemory{total: vram, used: usedVRAM}})
+	}
+	if len(cards) == 0 {
+		return nil, errors.New("no AMD GPUs found")
+	}
+	slices.SortFunc(cards, func(a, b card) int { return a.index - b.index })
+	gpus := make([]amdGPUMemory, len(cards))
+	for i, c := range cards {
+		gpus[i] = c.memory
+	}
+	return gpus, nil
+}
+
+// parseROCmSMIMemory parses the VRAM of each GPU from the JSON output of
+// "rocm-smi --showmeminfo vram --json", which maps card names to their memory
+// usage.
+func parseROCmSMIMemory(out []byte) ([]amdGPUMemory, error) {
+	var cards map[string]map[string]string
+	if err := json.Unmarshal(out, &cards); err != nil {
+		return nil, errors.New("unexpected rocm-smi output format")
+	}
+	indices := make([]int, 0, len(cards))
+	gpus := make(map[int]amdGPUMemory, len(cards))
+	for name, info := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(name, "card"))
+		if err != nil {
+			continue
+		}
+		vram, err := strconv.ParseUint(info["VRAM Total Memory (B)"], 10, 64)
+		if err != nil {
+			return nil, errors.New("unexpected rocm-smi output format")
+		}
+		used, _ := strconv.ParseUint(info["VRAM Total Used Memory (B)"], 10, 64)
+		indices = append(indices, index)
+		gpus[index] = amdGPUMemory{total: vram, used: used}
+	}
+	if len(indices) == 0 {
+		return nil, errors.New("no AMD GPUs found")
+	}
+	slices.Sort(indices)
+	result := make([]amdGPUMemory, len(indices))
+	for i, index := range indices {
+		result[i] = gpus[index]
+	}
+	return result, nil
+}
+
+// getAMDVRAMSize returns the total VRAM of all AMD GPUs in bytes.
+func getAMDVRAMSize() (uint64, error) {
+	sizes, err := getAMDGPUMemorySizes()
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, size := range sizes {
+		total += size
+	}
+	return total, nil
+}
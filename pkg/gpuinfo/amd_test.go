@@ -0,0 +1,70 @@
+package gpuinfo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadAMDGPUMemory(t *testing.T) {
+	drmPath := t.TempDir()
+	cards := []struct {
+		name, vendor, vram, used string
+	}{
+		{"card1", "0x1002", "68702699520\n", "11862016\n"},
+		{"card0", "0x1002", "17163091968\n", "27148288\n"},
+		{"card2", "0x10de", "25769803776\n", "0\n"},
+	}
+	for _, card := range cards {
+		device := filepath.Join(drmPath, card.name, "device")
+		if err := os.MkdirAll(device, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(device, "vendor"), []byte(card.vendor+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(device, "mem_info_vram_total"), []byte(card.vram), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(device, "mem_info_vram_used"), []byte(card.used), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Connectors aren't cards.
+	if err := os.MkdirAll(filepath.Join(drmPath, "card0-DP-1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	gpus, err := readAMDGPUMemory(drmPath)
+	if err != nil {
+		t.Fatalf("readAMDGPUMemory() error = %v", err)
+	}
+	want := []amdGPUMemory{{total: 17163091968, used: 27148288}, {total: 68702699520, used: 11862016}}
+	if !slices.Equal(gpus, want) {
+		t.Errorf("readAMDGPUMemory() = %v, want %v", gpus, want)
+	}
+
+	if _, err := readAMDGPUMemory(t.TempDir()); err == nil {
+		t.Error("Expected an error without AMD GPUs")
+	}
+}
+
+func TestParseROCmSMIMemory(t *testing.T) {
+	out := []byte(`{
+		"card1": {"VRAM Total Memory (B)": "68702699520", "VRAM Total Used Memory (B)": "11862016"},
+		"card0": {"VRAM Total Memory (B)": "17163091968", "VRAM Total Used Memory (B)": "27148288"}
+	}`)
+	gpus, err := parseROCmSMIMemory(out)
+	if err != nil {
+		t.Fatalf("parseROCmSMIMemory() error = %v", err)
+	}
+	want := []amdGPUMemory{{total: 17163091968, used: 27148288}, {total: 68702699520, used: 11862016}}
+	if !slices.Equal(gpus, want) {
+		t.Errorf("parseROCmSMIMemory() = %v, want %v", gpus, want)
+	}
+
+	if _, err := parseROCmSMIMemory([]byte("ERROR: No AMD GPUs")); err == nil {
+		t.Error("Expected an error for unexpected output")
+	}
+}
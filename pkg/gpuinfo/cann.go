@@ -0,0 +1,101 @@
+package gpuinfo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NPU is an Ascend NPU chip, as enumerated by the CANN runtime.
+type NPU struct {
+	// Index is the logical device index of the chip, as used in
+	// ASCEND_RT_VISIBLE_DEVICES.
+	Index int
+	// Name is the name of the NPU, such as 910B3.
+	Name string
+	// TotalMemory is the device memory of the chip in bytes.
+	TotalMemory uint64
+	// FreeMemory is the unused device memory of the chip in bytes.
+	FreeMemory uint64
+}
+
+// GetNPUs returns the Ascend NPU chips of the system and their memory, as
+// reported by npu-smi.
+func (g *GPUInfo) GetNPUs() ([]NPU, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("Ascend NPUs are only supported on linux")
+	}
+	npuSMI, err := exec.LookPath("npu-smi")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, npuSMI, "info").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNPUSMIInfo(string(out))
+}
+
+// npuSMIMemoryUsage matches the "used / total" memory usages in MB of an
+// npu-smi chip row.
+var npuSMIMemoryUsage = regexp.MustCompile(`(\d+)\s*/\s*(\d+)`)
+
+// parseNPUSMIInfo parses the chips of the table printed by "npu-smi info". Each
+// NPU has a row with its ID and name, followed by a row for each of its chips
+// with their bus ID and memory usages. The last usage is that of the HBM on
+// chips that have it, and of the DDR memory otherwise.
+func parseNPUSMIInfo(out string) ([]NPU, error) {
+	var npus []NPU
+	var name string
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		cells := strings.Split(sc.Text(), "|")
+		if len(cells) < 4 {
+			continue
+		}
+		first := strings.Fields(cells[1])
+		if len(first) == 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(first[0]); err != nil {
+			continue
+		}
+		if busID := strings.TrimSpace(cells[2]); !strings.Contains(busID, ":") {
+			// An NPU row, whose chips follow.
+			if len(first) > 1 {
+				name = first[1]
+			}
+			continue
+		}
+		usages := npuSMIMemoryUsage.FindAllStringSubmatch(cells[3], -1)
+		if len(usages) == 0 {
+			return nil, errors.New("unexpected npu-smi output format")
+		}
+		usage := usages[len(usages)-1]
+		used, _ := strconv.ParseUint(usage[1], 10, 64)
+		total, _ := strconv.ParseUint(usage[2], 10, 64)
+		if total == 0 {
+			// The chip has no memory of its own, such as an MCU.
+			continue
+		}
+		npus = append(npus, NPU{
+			Index:       len(npus),
+			Name:        name,
+			TotalMemory: total * 1024 * 1024,
+			FreeMemory:  (total - min(used, total)) * 1024 * 1024,
+		})
+	}
+	if len(npus) == 0 {
+		return nil, errors.New("no Ascend NPUs found")
+	}
+	return npus, nil
+}
@@ -0,0 +1,77 @@
+package gpuinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mb = 1024 * 1024
+
+func TestParseNPUSMIInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    []NPU
+		wantErr bool
+	}{
+		{
+			name: "910B with HBM",
+			out: `+------------------------------------------------------------------------------------------------+
+| npu-smi 23.0.rc2                 Version: 23.0.rc2                                             |
++---------------------------+---------------+----------------------------------------------------+
+| NPU   Name                | Health        | Power(W)    Temp(C)           Hugepages-Usage(page)|
+| Chip                      | Bus-Id        | AICore(%)   Memory-Usage(MB)  HBM-Usage(MB)        |
++===========================+===============+====================================================+
+| 0     910B3               | OK            | 93.2        40                0    / 0             |
+| 0                         | 0000:C1:00.0  | 0           0    / 0          3162 / 65536         |
++===========================+===============+====================================================+
+| 1     910B3               | OK            | 89.5        41                0    / 0             |
+| 0                         | 0000:01:00.0  | 0           0    / 0          35000 / 65536        |
++===========================+===============+====================================================+
++---------------------------+---------------+----------------------------------------------------+
+| NPU     Chip              | Process id    | Process name             | Process memory(MB)      |
++===========================+===============+====================================================+
+| No running processes found in NPU 0                                                            |
++===========================+===============+====================================================+
+`,
+			want: []NPU{
+				{Index: 0, Name: "910B3", TotalMemory: 65536 * mb, FreeMemory: (65536 - 3162) * mb},
+				{Index: 1, Name: "910B3", TotalMemory: 65536 * mb, FreeMemory: (65536 - 35000) * mb},
+			},
+		},
+		{
+			name: "310P with two chips",
+			out: `+--------------------------------------------------------------------------------------------------------+
+| npu-smi 23.0.0                                   Version: 23.0.0                                       |
++-------------------------------+-----------------+------------------------------------------------------+
+| NPU     Name                  | Health          | Power(W)     Temp(C)           Hugepages-Usage(page) |
+| Chip    Device                | Bus-Id          | AICore(%)    Memory-Usage(MB)                        |
++===============================+=================+======================================================+
+| 8       310P3                 | OK              | NA           44                0     / 0             |
+| 0       0                     | 0000:82:00.0    | 0            1636 / 21527                            |
+| 1       1                     | 0000:82:00.0    | 0            21527 / 21527                           |
++===============================+=================+======================================================+
+`,
+			want: []NPU{
+				{Index: 0, Name: "310P3", TotalMemory: 21527 * mb, FreeMemory: (21527 - 1636) * mb},
+				{Index: 1, Name: "310P3", TotalMemory: 21527 * mb, FreeMemory: 0},
+			},
+		},
+		{
+			name:    "no NPUs",
+			out:     "dcmi module initialize failed. ret is -8005\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNPUSMIInfo(tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNPUSMIInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNPUSMIInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,8 @@
 package gpuinfo
 
+// maximumGPUs is the maximum number of GPUs whose memory is reported.
+const maximumGPUs = 16
+
 type GPUInfo struct {
 	// modelRuntimeInstallPath is the location where DMR installed it's llama-server
 	// and accompanying tools
@@ -15,3 +18,15 @@ func New(modelRuntimeInstallPath string) *GPUInfo {
 func (g *GPUInfo) GetVRAMSize() (uint64, error) {
 	return getVRAMSize(g.modelRuntimeInstallPath)
 }
+
+// GetGPUMemorySizes returns the memory of each GPU in bytes, in device index
+// order.
+func (g *GPUInfo) GetGPUMemorySizes() ([]uint64, error) {
+	return getGPUMemorySizes(g.modelRuntimeInstallPath)
+}
+
+// GetGPUFreeMemorySizes returns the unused memory of each GPU in bytes, in
+// device index order.
+func (g *GPUInfo) GetGPUFreeMemorySizes() ([]uint64, error) {
+	return getGPUFreeMemorySizes(g.modelRuntimeInstallPath)
+}
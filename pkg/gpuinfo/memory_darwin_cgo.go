@@ -17,3 +17,19 @@ func getVRAMSize(_ string) (uint64, error) {
 	}
 	return uint64(vramSize), nil
 }
+
+// getGPUMemorySizes returns the memory of each GPU in bytes. Apple silicon has
+// a single GPU.
+func getGPUMemorySizes(modelRuntimeInstallPath string) ([]uint64, error) {
+	vramSize, err := getVRAMSize(modelRuntimeInstallPath)
+	if err != nil {
+		return nil, err
+	}
+	return []uint64{vramSize}, nil
+}
+
+// getGPUFreeMemorySizes returns the unused memory of each GPU in bytes. It's
+// unknown on Apple silicon, whose GPU shares the system memory.
+func getGPUFreeMemorySizes(_ string) ([]uint64, error) {
+	return nil, errors.New("unsupported on darwin")
+}
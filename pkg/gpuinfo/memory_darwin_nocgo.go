@@ -8,3 +8,13 @@ import "errors"
 func getVRAMSize(_ string) (uint64, error) {
 	return 0, errors.New("unimplemented without cgo")
 }
+
+// getGPUMemorySizes returns the memory of each GPU in bytes
+func getGPUMemorySizes(_ string) ([]uint64, error) {
+	return nil, errors.New("unimplemented without cgo")
+}
+
+// getGPUFreeMemorySizes returns the unused memory of each GPU in bytes
+func getGPUFreeMemorySizes(_ string) ([]uint64, error) {
+	return nil, errors.New("unimplemented without cgo")
+}
@@ -9,11 +9,58 @@ package gpuinfo
 import "C"
 import "errors"
 
-// getVRAMSize returns total system GPU memory in bytes
+// getVRAMSize returns total system GPU memory in bytes, from NVIDIA GPUs or
+// else AMD GPUs
 func getVRAMSize(_ string) (uint64, error) {
 	vramSize := C.getVRAMSize()
 	if vramSize == 0 {
-		return 0, errors.New("could not get nvidia VRAM size")
+		if amdVRAMSize, err := getAMDVRAMSize(); err == nil {
+			return amdVRAMSize, nil
+		}
+		return 0, errors.New("could not get nvidia or AMD VRAM size")
 	}
 	return uint64(vramSize), nil
 }
+
+// getGPUMemorySizes returns the memory of each NVIDIA GPU, or else AMD GPU, in
+// bytes, in device index order
+func getGPUMemorySizes(_ string) ([]uint64, error) {
+	if sizes := getNVIDIAGPUMemorySizes(false); sizes != nil {
+		return sizes, nil
+	}
+	if amdSizes, err := getAMDGPUMemorySizes(); err == nil {
+		return amdSizes, nil
+	}
+	return nil, errors.New("could not get nvidia or AMD GPU memory sizes")
+}
+
+// getGPUFreeMemorySizes returns the unused memory of each NVIDIA GPU, or else
+// AMD GPU, in bytes, in device index order
+func getGPUFreeMemorySizes(_ string) ([]uint64, error) {
+	if sizes := getNVIDIAGPUMemorySizes(true); sizes != nil {
+		return sizes, nil
+	}
+	if amdSizes, err := getAMDGPUFreeMemorySizes(); err == nil {
+		return amdSizes, nil
+	}
+	return nil, errors.New("could not get nvidia or AMD GPU free memory sizes")
+}
+
+// getNVIDIAGPUMemorySizes returns the total or free memory of each NVIDIA GPU
+// in bytes, in device index order, or nil if it's unknown
+func getNVIDIAGPUMemorySizes(free bool) []uint64 {
+	var sizes [maximumGPUs]C.ulonglong
+	var freeFlag C.int
+	if free {
+		freeFlag = 1
+	}
+	n := int(C.getGPUMemorySizes(&sizes[0], maximumGPUs, freeFlag))
+	if n == 0 {
+		return nil
+	}
+	result := make([]uint64, n)
+	for i := range result {
+		result[i] = uint64(sizes[i])
+	}
+	return result
+}
@@ -2,9 +2,20 @@
 
 package gpuinfo
 
-import "errors"
-
-// getVRAMSize returns total system GPU memory in bytes
+// getVRAMSize returns total system GPU memory in bytes. Without cgo, only AMD
+// GPUs are detected.
 func getVRAMSize(_ string) (uint64, error) {
-	return 0, errors.New("unimplemented without cgo")
+	return getAMDVRAMSize()
+}
+
+// getGPUMemorySizes returns the memory of each GPU in bytes. Without cgo, only
+// AMD GPUs are detected.
+func getGPUMemorySizes(_ string) ([]uint64, error) {
+	return getAMDGPUMemorySizes()
+}
+
+// getGPUFreeMemorySizes returns the unused memory of each GPU in bytes.
+// Without cgo, only AMD GPUs are detected.
+func getGPUFreeMemorySizes(_ string) ([]uint64, error) {
+	return getAMDGPUFreeMemorySizes()
 }
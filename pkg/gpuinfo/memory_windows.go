@@ -38,3 +38,56 @@ func getVRAMSize(modelRuntimeInstallPath string) (uint64, error) {
 	}
 	return 0, errors.New("unexpected nv-gpu-info output format")
 }
+
+// getGPUMemorySizes returns the dedicated memory of each NVIDIA GPU in bytes,
+// in device index order
+func getGPUMemorySizes(modelRuntimeInstallPath string) ([]uint64, error) {
+	if runtime.GOARCH == "arm64" {
+		return nil, errors.New("unsupported on windows/arm64")
+	}
+
+	nvGPUInfoBin := filepath.Join(modelRuntimeInstallPath, "bin", "com.docker.nv-gpu-info.exe")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, nvGPUInfoBin).CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+	return parseNVGPUInfoMemorySizes(string(out))
+}
+
+// parseNVGPUInfoMemorySizes parses the dedicated memory of each GPU from the
+// output of nv-gpu-info, which reports it as "GPU[<index>]: dedicated memory:".
+func parseNVGPUInfoMemorySizes(out string) ([]uint64, error) {
+	var sizes []uint64
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line, found := strings.CutPrefix(sc.Text(), "GPU[")
+		if !found {
+			continue
+		}
+		index, rest, found := strings.Cut(line, "]: dedicated memory:")
+		if !found {
+			continue
+		}
+		if i, err := strconv.Atoi(index); err != nil || i != len(sizes) {
+			return nil, errors.New("unexpected nv-gpu-info output format")
+		}
+		vram, err := strconv.ParseUint(strings.TrimSpace(rest), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, vram)
+	}
+	if len(sizes) == 0 {
+		return nil, errors.New("unexpected nv-gpu-info output format")
+	}
+	return sizes, nil
+}
+
+// getGPUFreeMemorySizes returns the unused memory of each GPU in bytes. It's
+// not reported by nv-gpu-info.
+func getGPUFreeMemorySizes(_ string) ([]uint64, error) {
+	return nil, errors.New("unsupported on windows")
+}
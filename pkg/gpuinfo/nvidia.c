@@ -68,4 +68,61 @@ size_t getVRAMSize() {
     nvmlShutdown();
     dlclose(handle);
     return memory.total;
+}
+
+int getGPUMemorySizes(unsigned long long* sizes, int max, int free) {
+    void* handle;
+    nvmlReturn_t (*nvmlInit)(void);
+    nvmlReturn_t (*nvmlShutdown)(void);
+    nvmlReturn_t (*nvmlDeviceGetCount)(unsigned int* count);
+    nvmlReturn_t (*nvmlDeviceGetHandleByIndex)(unsigned int index, nvmlDevice_t* device);
+    nvmlReturn_t (*nvmlDeviceGetMemoryInfo)(nvmlDevice_t device, nvmlMemory_t* memory);
+
+    nvmlDevice_t device;
+    nvmlMemory_t memory;
+    unsigned int count;
+    int n = 0;
+
+    // Try to load libnvidia-ml.so.1 first, then fallback to libnvidia-ml.so
+    handle = dlopen("libnvidia-ml.so.1", RTLD_LAZY);
+    if (!handle) {
+        handle = dlopen("libnvidia-ml.so", RTLD_LAZY);
+        if (!handle) {
+            return 0;
+        }
+    }
+
+    // Load required functions
+    nvmlInit = dlsym(handle, "nvmlInit");
+    nvmlShutdown = dlsym(handle, "nvmlShutdown");
+    nvmlDeviceGetCount = dlsym(handle, "nvmlDeviceGetCount");
+    nvmlDeviceGetHandleByIndex = dlsym(handle, "nvmlDeviceGetHandleByIndex");
+    nvmlDeviceGetMemoryInfo = dlsym(handle, "nvmlDeviceGetMemoryInfo");
+
+    if (!nvmlInit || !nvmlShutdown || !nvmlDeviceGetCount || !nvmlDeviceGetHandleByIndex || !nvmlDeviceGetMemoryInfo) {
+        dlclose(handle);
+        return 0;
+    }
+
+    if (nvmlInit() != NVML_SUCCESS) {
+        dlclose(handle);
+        return 0;
+    }
+
+    // Devices are enumerated in PCI bus order, which matches CUDA's device
+    // order with CUDA_DEVICE_ORDER=PCI_BUS_ID.
+    if (nvmlDeviceGetCount(&count) == NVML_SUCCESS) {
+        for (unsigned int i = 0; i < count && n < max; i++) {
+            if (nvmlDeviceGetHandleByIndex(i, &device) != NVML_SUCCESS ||
+                nvmlDeviceGetMemoryInfo(device, &memory) != NVML_SUCCESS) {
+                n = 0;
+                break;
+            }
+            sizes[n++] = free ? memory.free : memory.total;
+        }
+    }
+
+    nvmlShutdown();
+    dlclose(handle);
+    return n;
 }
\ No newline at end of file
@@ -3,4 +3,6 @@
 #include <stddef.h>
 #include <dlfcn.h>
 
-size_t getVRAMSize();
\ No newline at end of file
+size_t getVRAMSize();
+
+int getGPUMemorySizes(unsigned long long* sizes, int max, int free);
\ No newline at end of file
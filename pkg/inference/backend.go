@@ -2,7 +2,15 @@ package inference
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 // BackendMode encodes the mode in which a backend should operate.
@@ -15,8 +23,34 @@ const (
 	// BackendModeEmbedding indicates that the backend should run in embedding
 	// mode.
 	BackendModeEmbedding
+	// BackendModeTranscription indicates that the backend should run in
+	// speech-to-text transcription mode.
+	BackendModeTranscription
+	// BackendModeReranking indicates that the backend should run in reranking
+	// mode, scoring the relevance of documents to a query.
+	BackendModeReranking
+	// BackendModeSpeech indicates that the backend should run in
+	// text-to-speech synthesis mode.
+	BackendModeSpeech
+	// BackendModeImageGeneration indicates that the backend should run in
+	// text-to-image generation mode.
+	BackendModeImageGeneration
+	// BackendModeClassification indicates that the backend should run in
+	// classification mode, assigning labels to its inputs.
+	BackendModeClassification
 )
 
+// BackendModes are all the modes in which a backend can operate.
+var BackendModes = []BackendMode{
+	BackendModeCompletion,
+	BackendModeEmbedding,
+	BackendModeTranscription,
+	BackendModeReranking,
+	BackendModeSpeech,
+	BackendModeImageGeneration,
+	BackendModeClassification,
+}
+
 type ErrGGUFParse struct {
 	Err error
 }
@@ -25,6 +59,35 @@ func (e *ErrGGUFParse) Error() string {
 	return "failed to parse GGUF: " + e.Err.Error()
 }
 
+// ErrProcessExited describes a backend process that exited unexpectedly, so
+// that crash reports can be collected for it.
+type ErrProcessExited struct {
+	// Args is the command line of the process.
+	Args []string
+	// ExitCode is the exit code of the process, or -1 if it was terminated by
+	// a signal.
+	ExitCode int
+	// Output is the tail of the standard error of the process.
+	Output string
+	// Err is the error with which the process exited.
+	Err error
+}
+
+func (e *ErrProcessExited) Error() string {
+	msg := "exit status " + strconv.Itoa(e.ExitCode)
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Output == "" {
+		return msg
+	}
+	return msg + "\nwith output: " + e.Output
+}
+
+func (e *ErrProcessExited) Unwrap() error {
+	return e.Err
+}
+
 // String implements Stringer.String for BackendMode.
 func (m BackendMode) String() string {
 	switch m {
@@ -32,6 +95,16 @@ func (m BackendMode) String() string {
 		return "completion"
 	case BackendModeEmbedding:
 		return "embedding"
+	case BackendModeTranscription:
+		return "transcription"
+	case BackendModeReranking:
+		return "reranking"
+	case BackendModeSpeech:
+		return "speech"
+	case BackendModeImageGeneration:
+		return "image-generation"
+	case BackendModeClassification:
+		return "classification"
 	default:
 		return "unknown"
 	}
@@ -43,10 +116,210 @@ type SpeculativeDecodingConfig struct {
 	MinAcceptanceRate float64 `json:"min_acceptance_rate,omitempty"`
 }
 
+// LoRAAdapter is a LoRA adapter applied to a model, from another model that
+// contains it.
+type LoRAAdapter struct {
+	// Model is the model containing the adapter.
+	Model string `json:"model"`
+	// Scale, if set, is the scale at which the adapter is applied, which is
+	// one otherwise. Zero disables the adapter.
+	Scale *float64 `json:"scale,omitempty"`
+}
+
+// AdapterScale returns the scale at which the adapter is applied.
+func (a LoRAAdapter) AdapterScale() float64 {
+	if a.Scale == nil {
+		return 1
+	}
+	return *a.Scale
+}
+
+// OnlyLoRAScalesDiffer reports whether two configurations differ only in the
+// scales of their LoRA adapters, which backends implementing LoRAScaler can
+// change without restarting their runners.
+func OnlyLoRAScalesDiffer(a, b BackendConfiguration) bool {
+	if len(a.LoRAAdapters) != len(b.LoRAAdapters) {
+		return false
+	}
+	adapters := make([]LoRAAdapter, len(b.LoRAAdapters))
+	for i, adapter := range b.LoRAAdapters {
+		if adapter.Model != a.LoRAAdapters[i].Model {
+			return false
+		}
+		adapters[i] = a.LoRAAdapters[i]
+	}
+	b.LoRAAdapters = adapters
+	return reflect.DeepEqual(a, b)
+}
+
+// KVCacheTypes are the data types supported for the keys and values of the
+// KV cache.
+var KVCacheTypes = []string{"f32", "f16", "bf16", "q8_0", "q4_0", "q4_1", "iq4_nl", "q5_0", "q5_1"}
+
+// KVCacheConfig configures the KV cache of a model. Quantized cache types
+// shrink the cache, so that longer contexts fit in the same memory. A
+// quantized value cache requires flash attention.
+type KVCacheConfig struct {
+	// TypeK is the data type of the keys, f16 by default.
+	TypeK string `json:"type-k,omitempty"`
+	// TypeV is the data type of the values, f16 by default.
+	TypeV string `json:"type-v,omitempty"`
+	// NoOffload keeps the KV cache in system RAM rather than VRAM.
+	NoOffload bool `json:"no-offload,omitempty"`
+}
+
+// Validate checks that the cache types are supported.
+func (c *KVCacheConfig) Validate() error {
+	for _, typ := range []struct{ name, value string }{{"type-k", c.TypeK}, {"type-v", c.TypeV}} {
+		if typ.value != "" && !slices.Contains(KVCacheTypes, typ.value) {
+			return fmt.Errorf("invalid KV cache %s %q: must be one of %s", typ.name, typ.value, strings.Join(KVCacheTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// PersistentRuntimeFlags are the runtime flags that can be persisted in the
+// config of a model, as opposed to given when the model is loaded. They tune
+// the inference server without changing which files it reads or writes.
+var PersistentRuntimeFlags = []string{
+	"-fa", "--flash-attn",
+	"-ngl", "--n-gpu-layers", "--gpu-layers",
+	"-np", "--parallel",
+	"-cb", "--cont-batching",
+	"-nocb", "--no-cont-batching",
+}
+
+// ValidatePersistentRuntimeFlags checks that all flags are in
+// PersistentRuntimeFlags. Flag values may be given as the next argument or
+// after an equals sign.
+func ValidatePersistentRuntimeFlags(flags []string) error {
+	for _, arg := range flags {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flag, _, _ := strings.Cut(arg, "=")
+		if _, err := strconv.ParseFloat(flag, 64); err == nil {
+			// A negative value rather than a flag.
+			continue
+		}
+		if !slices.Contains(PersistentRuntimeFlags, flag) {
+			return fmt.Errorf("runtime flag %q cannot be persisted: must be one of %s", flag, strings.Join(PersistentRuntimeFlags, ", "))
+		}
+	}
+	return nil
+}
+
 type BackendConfiguration struct {
-	ContextSize  int64                       `json:"context-size,omitempty"`
-	RuntimeFlags []string                    `json:"runtime-flags,omitempty"`
+	ContextSize  int64                      `json:"context-size,omitempty"`
+	RuntimeFlags []string                   `json:"runtime-flags,omitempty"`
 	Speculative  *SpeculativeDecodingConfig `json:"speculative,omitempty"`
+	KVCache      *KVCacheConfig             `json:"kv-cache,omitempty"`
+	GPUs         []int                      `json:"gpus,omitempty"`
+	TensorSplit  []float64                  `json:"tensor-split,omitempty"`
+	LoRAAdapters []LoRAAdapter              `json:"lora-adapters,omitempty"`
+	Env          map[string]string          `json:"env,omitempty"`
+	Mounts       []string                   `json:"mounts,omitempty"`
+	// Replicas is the number of runners that the scheduler may load for the
+	// model, balancing requests across them. It's one if zero.
+	Replicas int `json:"replicas,omitempty"`
+	// ParallelSlots is the number of requests a runner serves in parallel,
+	// left to the backend if zero.
+	ParallelSlots int `json:"parallel-slots,omitempty"`
+	// BatchSize is the number of tokens a runner processes in a batch, left
+	// to the backend if zero.
+	BatchSize int `json:"batch-size,omitempty"`
+	// GPULayers, if set, is the number of layers to offload to the GPU. The
+	// scheduler sets it when only part of the model fits in VRAM.
+	GPULayers *uint64 `json:"-"`
+	// PromptCacheDir, if set, is the directory in which the backend saves
+	// prompt caches. The scheduler sets it for backends that implement
+	// PromptCacheSaver.
+	PromptCacheDir string `json:"-"`
+	// Output, if set, receives the standard output and error of the
+	// backend's server process, in addition to its logger. The scheduler sets
+	// it to keep the recent output of each runner.
+	Output io.Writer `json:"-"`
+}
+
+// OutputWriter returns a writer that writes the output of the backend's
+// server process both to w and to the configuration's Output, if any. It is
+// safe to call on a nil configuration.
+func (c *BackendConfiguration) OutputWriter(w io.Writer) io.Writer {
+	if c == nil || c.Output == nil {
+		return w
+	}
+	return io.MultiWriter(w, c.Output)
+}
+
+// visibleDevicesVariables are the environment variables with which runtimes
+// restrict the devices visible to a process.
+var visibleDevicesVariables = []string{"ASCEND_RT_VISIBLE_DEVICES", "CUDA_VISIBLE_DEVICES", "HIP_VISIBLE_DEVICES"}
+
+// Environ returns the extra environment variables of the configuration in
+// "key=value" form, sorted by key. If GPUs are selected, the CUDA and HIP
+// devices and Ascend NPUs visible to the backend are restricted to them, unless
+// the configuration selects visible devices itself. It is safe to call on a nil
+// configuration.
+func (c *BackendConfiguration) Environ() []string {
+	if c == nil || (len(c.Env) == 0 && len(c.GPUs) == 0) {
+		return nil
+	}
+	env := make([]string, 0, len(c.Env)+4)
+	selectsDevices := false
+	for k, v := range c.Env {
+		env = append(env, k+"="+v)
+		selectsDevices = selectsDevices || slices.Contains(visibleDevicesVariables, k)
+	}
+	if !selectsDevices && len(c.GPUs) > 0 {
+		devices := make([]string, len(c.GPUs))
+		for i, gpu := range c.GPUs {
+			devices[i] = strconv.Itoa(gpu)
+		}
+		// NVIDIA GPU indices follow the PCI bus order, as reported by NVML,
+		// AMD GPU indices the DRM card order, and NPU indices the logical
+		// device order, as reported by npu-smi. Each runtime ignores the
+		// others' variables.
+		env = append(env,
+			"ASCEND_RT_VISIBLE_DEVICES="+strings.Join(devices, ","),
+			"CUDA_DEVICE_ORDER=PCI_BUS_ID",
+			"CUDA_VISIBLE_DEVICES="+strings.Join(devices, ","),
+			"HIP_VISIBLE_DEVICES="+strings.Join(devices, ","),
+		)
+	}
+	slices.Sort(env)
+	return env
+}
+
+// ValidateGPUs checks the GPU selection and tensor split of the
+// configuration.
+func (c *BackendConfiguration) ValidateGPUs() error {
+	seen := make(map[int]bool, len(c.GPUs))
+	for _, gpu := range c.GPUs {
+		if gpu < 0 {
+			return fmt.Errorf("invalid GPU index %d", gpu)
+		}
+		if seen[gpu] {
+			return fmt.Errorf("GPU %d is selected more than once", gpu)
+		}
+		seen[gpu] = true
+	}
+	if len(c.TensorSplit) == 0 {
+		return nil
+	}
+	if len(c.GPUs) > 0 && len(c.TensorSplit) != len(c.GPUs) {
+		return fmt.Errorf("tensor split has %d proportions for %d GPUs", len(c.TensorSplit), len(c.GPUs))
+	}
+	var total float64
+	for _, proportion := range c.TensorSplit {
+		if proportion < 0 {
+			return fmt.Errorf("invalid tensor split proportion %g", proportion)
+		}
+		total += proportion
+	}
+	if total == 0 {
+		return errors.New("tensor split proportions must not all be zero")
+	}
+	return nil
 }
 
 type RequiredMemory struct {
@@ -54,6 +327,182 @@ type RequiredMemory struct {
 	VRAM uint64 // TODO(p1-0tr): for now assume we are working with single GPU set-ups
 }
 
+// ModelCheck is a backend's assessment of whether a model can run on the
+// current hardware.
+type ModelCheck struct {
+	// Backend is the name of the backend that performed the check.
+	Backend string `json:"backend"`
+	// Architecture, Parameters, and Quantization describe the model.
+	Architecture string `json:"architecture,omitempty"`
+	Parameters   string `json:"parameters,omitempty"`
+	Quantization string `json:"quantization,omitempty"`
+	// ContextSize is the context size the check assumed.
+	ContextSize uint64 `json:"context_size"`
+	// GPUSupported indicates whether the backend can offload to a GPU.
+	GPUSupported bool `json:"gpu_supported"`
+	// TotalLayers is the number of layers in the model.
+	TotalLayers uint64 `json:"total_layers"`
+	// OffloadedLayers is the number of layers that fit in VRAM.
+	OffloadedLayers uint64 `json:"offloaded_layers"`
+	// Required is the memory required with OffloadedLayers offloaded.
+	Required RequiredMemory `json:"required"`
+	// Available is the memory available on the system.
+	Available RequiredMemory `json:"available"`
+	// TokensPerSecond is a rough estimate of the generation speed, based on
+	// the memory bandwidth needed to read the weights for each token.
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	// Fits indicates whether the model fits in the available memory.
+	Fits bool `json:"fits"`
+	// Verdict is a human-readable summary of the check.
+	Verdict string `json:"verdict"`
+}
+
+// ModelChecker is implemented by backends that can assess whether a model
+// (local or remote) can run on the current hardware without pulling it.
+type ModelChecker interface {
+	// CheckModel assesses whether the model can run with the given
+	// configuration in the available memory.
+	CheckModel(ctx context.Context, model string, config *BackendConfiguration, available RequiredMemory) (ModelCheck, error)
+}
+
+// MemoryEstimate is a breakdown of the memory a model requires to run, along
+// with the model and run parameters it was estimated from.
+type MemoryEstimate struct {
+	// Backend is the name of the backend that estimated the memory.
+	Backend string `json:"backend"`
+	// Architecture is the model architecture, such as "llama" or "qwen3".
+	Architecture string `json:"architecture,omitempty"`
+	// Layers is the number of layers (n_layer) of the model.
+	Layers uint64 `json:"layers"`
+	// OffloadedLayers is the number of layers offloaded to the GPU.
+	OffloadedLayers uint64 `json:"offloaded_layers"`
+	// AttentionHeads and KVHeads are the number of attention heads (n_head)
+	// and key/value heads (n_head_kv) of the model.
+	AttentionHeads uint64 `json:"attention_heads"`
+	KVHeads        uint64 `json:"kv_heads"`
+	// EmbeddingLength is the embedding size (n_embd) of the model.
+	EmbeddingLength uint64 `json:"embedding_length"`
+	// ContextSize is the context size the estimate assumed.
+	ContextSize uint64 `json:"context_size"`
+	// BatchSize and MicroBatchSize are the logical and physical batch sizes
+	// the estimate assumed.
+	BatchSize      uint64 `json:"batch_size"`
+	MicroBatchSize uint64 `json:"micro_batch_size"`
+	// FlashAttention indicates whether the estimate assumed flash attention,
+	// which shrinks the compute buffers.
+	FlashAttention bool `json:"flash_attention"`
+	// Weights is the memory holding the model weights.
+	Weights RequiredMemory `json:"weights"`
+	// KVCache is the memory holding the KV cache, which grows with the
+	// context size, number of layers and key/value heads.
+	KVCache RequiredMemory `json:"kv_cache"`
+	// Compute is the memory of the compute graph buffers, which grows with
+	// the batch size and, without flash attention, the context size.
+	Compute RequiredMemory `json:"compute"`
+	// Required is the total memory required.
+	Required RequiredMemory `json:"required"`
+	// Available is the memory available on the system.
+	Available RequiredMemory `json:"available"`
+	// Fits indicates whether the model fits in the available memory.
+	Fits bool `json:"fits"`
+}
+
+// MemoryEstimateReporter is implemented by backends that can break down the
+// memory a model requires.
+type MemoryEstimateReporter interface {
+	// EstimateMemory breaks down the memory the model requires to run with
+	// the given configuration. Available and Fits aren't set.
+	EstimateMemory(ctx context.Context, model string, config *BackendConfiguration) (MemoryEstimate, error)
+}
+
+// PartialOffloader is implemented by backends that can offload only some of
+// the layers of a model to the GPU, running the rest on the CPU.
+type PartialOffloader interface {
+	// FitGPULayers returns the largest number of layers of the model that
+	// can be offloaded with at most vram bytes of VRAM, along with the memory
+	// required to run the model with them offloaded.
+	FitGPULayers(ctx context.Context, model string, config *BackendConfiguration, vram uint64) (uint64, RequiredMemory, error)
+}
+
+// JSONSchemaConstrainer is implemented by backends that can constrain chat
+// completions to a JSON schema, as requested with a json_schema response_format
+// in the OpenAI API. Requests with such a response_format are rejected for
+// other backends.
+type JSONSchemaConstrainer interface {
+	// ConstrainToJSONSchema rewrites a chat completion request body so that
+	// the backend's output conforms to schema.
+	ConstrainToJSONSchema(body []byte, schema json.RawMessage) ([]byte, error)
+}
+
+// PromptCacher is implemented by backends that can reuse the cached prompt of
+// a previous request, so that requests of a conversation routed to the same
+// runner only process the new part of their prompt.
+type PromptCacher interface {
+	// CachePrompt rewrites a completion request body so that the backend
+	// reuses its prompt cache for the request.
+	CachePrompt(body []byte) ([]byte, error)
+}
+
+// PromptCacheSaver is implemented by backends that can save the prompt cache
+// of one of their slots to a named file in their configuration's
+// PromptCacheDir, and restore it into a slot later on, so that a long prompt
+// shared by many requests is only processed once.
+type PromptCacheSaver interface {
+	// SavePromptCache saves the prompt cache of a slot of the backend served
+	// through client to the file with the given name.
+	SavePromptCache(ctx context.Context, client *http.Client, slot int, name string) error
+	// RestorePromptCache restores the prompt cache saved to the file with the
+	// given name into a slot of the backend served through client.
+	RestorePromptCache(ctx context.Context, client *http.Client, slot int, name string) error
+}
+
+// LoRAScaler is implemented by backends that can change the scales of the
+// LoRA adapters of a running runner, so that reconfiguring them needn't
+// restart it. Adapters themselves are only loaded when a runner starts.
+type LoRAScaler interface {
+	// ScaleLoRAAdapters applies the scales of the LoRA adapters of config to
+	// the backend served through client, which was started with the same
+	// adapters.
+	ScaleLoRAAdapters(ctx context.Context, client *http.Client, config *BackendConfiguration) error
+}
+
+// VersionReporter is implemented by backends that can report which version of
+// their server is installed and which accelerator it runs on.
+type VersionReporter interface {
+	// Version returns the version of the installed server, or an empty
+	// string if it's unknown or not installed yet.
+	Version() string
+	// Accelerator returns the kind of accelerator the server runs on, such as
+	// "cpu", "cuda" or "metal", or an empty string if it's unknown.
+	Accelerator() string
+}
+
+// BackendInfo describes a backend and what it can serve, so that clients can
+// present the options it supports.
+type BackendInfo struct {
+	// Name is the backend name.
+	Name string `json:"name"`
+	// Default indicates whether requests that don't name a backend are served
+	// by this backend, unless their model's format calls for another one.
+	Default bool `json:"default,omitempty"`
+	// Version is the version of the backend's server, if it's known.
+	Version string `json:"version,omitempty"`
+	// Formats are the model formats the backend serves.
+	Formats []string `json:"formats"`
+	// Modes are the modes in which the backend serves requests.
+	Modes []string `json:"modes"`
+	// Accelerator is the kind of accelerator the backend runs on, if it's
+	// known.
+	Accelerator string `json:"accelerator,omitempty"`
+	// Health is "installing" until the backend's installation completes, and
+	// then "ready" or, if it failed, "error".
+	Health string `json:"health"`
+	// Error is the error the backend's installation failed with.
+	Error string `json:"error,omitempty"`
+	// Status is the backend's description of its state.
+	Status string `json:"status"`
+}
+
 // Backend is the interface implemented by inference engine backends. Backend
 // implementations need not be safe for concurrent invocation of the following
 // methods, though their underlying server implementations do need to support
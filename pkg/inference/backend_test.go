@@ -0,0 +1,129 @@
+package inference
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKVCacheConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  KVCacheConfig
+		wantErr bool
+	}{
+		{name: "defaults", config: KVCacheConfig{}},
+		{name: "quantized", config: KVCacheConfig{TypeK: "q8_0", TypeV: "q4_0"}},
+		{name: "unsupported key type", config: KVCacheConfig{TypeK: "q2_k"}, wantErr: true},
+		{name: "upper case value type", config: KVCacheConfig{TypeV: "F16"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePersistentRuntimeFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "allowed", flags: []string{"--flash-attn", "on", "-ngl", "-1", "--parallel=4", "-cb"}},
+		{name: "disallowed", flags: []string{"--parallel", "4", "--model", "/tmp/model.gguf"}, wantErr: true},
+		{name: "disallowed with value", flags: []string{"--log-file=/tmp/log"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePersistentRuntimeFlags(tt.flags); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePersistentRuntimeFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendConfigurationEnvironGPUs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *BackendConfiguration
+		want   []string
+	}{
+		{name: "nil", config: nil, want: nil},
+		{name: "no GPUs", config: &BackendConfiguration{}, want: nil},
+		{
+			name:   "GPUs",
+			config: &BackendConfiguration{GPUs: []int{1, 0}},
+			want:   []string{"ASCEND_RT_VISIBLE_DEVICES=1,0", "CUDA_DEVICE_ORDER=PCI_BUS_ID", "CUDA_VISIBLE_DEVICES=1,0", "HIP_VISIBLE_DEVICES=1,0"},
+		},
+		{
+			name:   "explicit devices take precedence",
+			config: &BackendConfiguration{GPUs: []int{1}, Env: map[string]string{"CUDA_VISIBLE_DEVICES": "2"}},
+			want:   []string{"CUDA_VISIBLE_DEVICES=2"},
+		},
+		{
+			name:   "explicit NPUs take precedence",
+			config: &BackendConfiguration{GPUs: []int{1}, Env: map[string]string{"ASCEND_RT_VISIBLE_DEVICES": "2"}},
+			want:   []string{"ASCEND_RT_VISIBLE_DEVICES=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Environ(); !slices.Equal(got, tt.want) {
+				t.Errorf("Environ() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackendConfigurationValidateGPUs(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BackendConfiguration
+		wantErr bool
+	}{
+		{name: "defaults", config: BackendConfiguration{}},
+		{name: "GPUs", config: BackendConfiguration{GPUs: []int{0, 1}}},
+		{name: "tensor split", config: BackendConfiguration{GPUs: []int{0, 1}, TensorSplit: []float64{3, 1}}},
+		{name: "tensor split without GPUs", config: BackendConfiguration{TensorSplit: []float64{1, 1}}},
+		{name: "negative GPU", config: BackendConfiguration{GPUs: []int{-1}}, wantErr: true},
+		{name: "duplicate GPU", config: BackendConfiguration{GPUs: []int{0, 0}}, wantErr: true},
+		{name: "tensor split length", config: BackendConfiguration{GPUs: []int{0, 1}, TensorSplit: []float64{1}}, wantErr: true},
+		{name: "negative proportion", config: BackendConfiguration{TensorSplit: []float64{1, -1}}, wantErr: true},
+		{name: "zero proportions", config: BackendConfiguration{TensorSplit: []float64{0, 0}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.ValidateGPUs(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGPUs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOnlyLoRAScalesDiffer(t *testing.T) {
+	half := 0.5
+	base := BackendConfiguration{ContextSize: 4096, LoRAAdapters: []LoRAAdapter{{Model: "ai/adapter:latest"}}}
+	tests := []struct {
+		name     string
+		config   BackendConfiguration
+		expected bool
+	}{
+		{name: "scale", config: BackendConfiguration{ContextSize: 4096, LoRAAdapters: []LoRAAdapter{{Model: "ai/adapter:latest", Scale: &half}}}, expected: true},
+		{name: "context size", config: BackendConfiguration{ContextSize: 8192, LoRAAdapters: []LoRAAdapter{{Model: "ai/adapter:latest", Scale: &half}}}},
+		{name: "adapter", config: BackendConfiguration{ContextSize: 4096, LoRAAdapters: []LoRAAdapter{{Model: "ai/other:latest"}}}},
+		{name: "added adapter", config: BackendConfiguration{ContextSize: 4096, LoRAAdapters: []LoRAAdapter{{Model: "ai/adapter:latest"}, {Model: "ai/other:latest"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OnlyLoRAScalesDiffer(base, tt.config); got != tt.expected {
+				t.Errorf("OnlyLoRAScalesDiffer() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+	if base.LoRAAdapters[0].AdapterScale() != 1 || base.LoRAAdapters[0].Scale != nil {
+		t.Error("Expected adapters to be applied at scale 1 by default, and left unchanged")
+	}
+}
@@ -0,0 +1,229 @@
+// Package bundled implements the parts shared by the backends whose inference
+// server binary is bundled with the model runner, such as whisper.cpp: looking
+// up the binary, launching it in a sandbox, handling its exit and reporting
+// its disk usage. Each backend only builds the arguments of its server and
+// estimates the memory of its models.
+package bundled
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/internal/utils"
+	"github.com/docker/model-runner/pkg/logging"
+	"github.com/docker/model-runner/pkg/sandbox"
+	"github.com/docker/model-runner/pkg/tailbuffer"
+)
+
+// Server describes the bundled server of a backend.
+type Server struct {
+	// Name is the backend name.
+	Name string
+	// DisplayName is the name of the server in logs and errors, defaulting to
+	// the backend name.
+	DisplayName string
+	// Binary is the file name of the server binary, without the .exe
+	// extension on Windows.
+	Binary string
+	// NotFound is the error returned when the binary isn't installed.
+	NotFound error
+	// Config builds the arguments of the server.
+	Config config.BackendConfig
+}
+
+// Backend runs a bundled server. It implements every method of
+// inference.Backend except GetRequiredMemoryForModel, which backends implement
+// for their models.
+type Backend struct {
+	// server describes the bundled server.
+	server Server
+	// log is the associated logger.
+	log logging.Logger
+	// modelManager is the shared model manager.
+	modelManager *models.Manager
+	// serverLog is the logger to use for the server process.
+	serverLog logging.Logger
+	// serverStoragePath is the parent path of the server binary.
+	serverStoragePath string
+	// status is the state in which the backend is in.
+	status string
+}
+
+// New creates a backend running a bundled server.
+func New(
+	server Server,
+	log logging.Logger,
+	modelManager *models.Manager,
+	serverLog logging.Logger,
+	serverStoragePath string,
+) *Backend {
+	if server.DisplayName == "" {
+		server.DisplayName = server.Name
+	}
+	return &Backend{
+		server:            server,
+		log:               log,
+		modelManager:      modelManager,
+		serverLog:         serverLog,
+		serverStoragePath: serverStoragePath,
+		status:            "not installed",
+	}
+}
+
+// Name implements inference.Backend.Name.
+func (b *Backend) Name() string {
+	return b.server.Name
+}
+
+// UsesExternalModelManagement implements
+// inference.Backend.UsesExternalModelManagement.
+func (b *Backend) UsesExternalModelManagement() bool {
+	return false
+}
+
+// Install implements inference.Backend.Install. The server is bundled with the
+// model runner, so this only checks that it's present.
+func (b *Backend) Install(_ context.Context, _ *http.Client) error {
+	if _, err := os.Stat(b.binaryPath()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			b.status = b.server.NotFound.Error()
+			return b.server.NotFound
+		}
+		return fmt.Errorf("failed to check %s binary: %w", b.server.DisplayName, err)
+	}
+	b.status = "installed"
+	return nil
+}
+
+// Run implements inference.Backend.Run.
+func (b *Backend) Run(ctx context.Context, socket, model string, _ string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	name := b.server.DisplayName
+	bundle, release, err := b.modelManager.AcquireBundle(model)
+	if err != nil {
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+	defer release()
+
+	if err := os.RemoveAll(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		b.log.Warnf("failed to remove socket file %s: %v\n", socket, err)
+		b.log.Warnf("%s may not be able to start", name)
+	}
+
+	args, err := b.server.Config.GetArgs(bundle, socket, mode, config)
+	if err != nil {
+		return fmt.Errorf("failed to get args for %s: %w", name, err)
+	}
+
+	// Sanitize args for safe logging
+	sanitizedArgs := make([]string, len(args))
+	for i, arg := range args {
+		sanitizedArgs[i] = utils.SanitizeForLog(arg)
+	}
+	b.log.Infof("%s args: %v", name, sanitizedArgs)
+	tailBuf := tailbuffer.NewTailBuffer(1024)
+	serverLogStream := b.serverLog.Writer()
+	out := io.MultiWriter(serverLogStream, tailBuf)
+	sandboxConfig := sandbox.ConfigurationBundledServer
+	if config != nil {
+		sandboxConfig = sandbox.AllowReadPaths(sandboxConfig, config.Mounts)
+	}
+	serverSandbox, err := sandbox.Create(
+		ctx,
+		sandboxConfig,
+		func(command *exec.Cmd) {
+			command.Cancel = func() error {
+				if runtime.GOOS == "windows" {
+					return command.Process.Kill()
+				}
+				return command.Process.Signal(os.Interrupt)
+			}
+			command.Stdout = config.OutputWriter(serverLogStream)
+			command.Stderr = config.OutputWriter(out)
+			if env := config.Environ(); len(env) > 0 {
+				command.Env = append(os.Environ(), env...)
+			}
+		},
+		b.serverStoragePath,
+		b.binaryPath(),
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to start %s: %w", name, err)
+	}
+	defer serverSandbox.Close()
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErr := serverSandbox.Command().Wait()
+		serverLogStream.Close()
+
+		errOutput := new(strings.Builder)
+		if _, err := io.Copy(errOutput, tailBuf); err != nil {
+			b.log.Warnf("failed to read server output tail: %v", err)
+		}
+
+		if len(errOutput.String()) != 0 {
+			serverErr = fmt.Errorf("%s exit status: %w\nwith output: %s", name, serverErr, errOutput.String())
+		} else {
+			serverErr = fmt.Errorf("%s exit status: %w", name, serverErr)
+		}
+
+		serverErrors <- serverErr
+		close(serverErrors)
+		if err := os.Remove(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			b.log.Warnf("failed to remove socket file %s on exit: %v\n", socket, err)
+		}
+	}()
+	defer func() {
+		<-serverErrors
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case serverErr := <-serverErrors:
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		return fmt.Errorf("%s terminated unexpectedly: %w", name, serverErr)
+	}
+}
+
+// Status implements inference.Backend.Status.
+func (b *Backend) Status() string {
+	return b.status
+}
+
+// GetDiskUsage implements inference.Backend.GetDiskUsage.
+func (b *Backend) GetDiskUsage() (int64, error) {
+	info, err := os.Stat(b.binaryPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error while getting %s size: %v", b.server.DisplayName, err)
+	}
+	return info.Size(), nil
+}
+
+func (b *Backend) binaryPath() string {
+	name := b.server.Binary
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(b.serverStoragePath, name)
+}
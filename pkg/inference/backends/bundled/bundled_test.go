@@ -0,0 +1,47 @@
+package bundled
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errNotFound = errors.New("test server binary not found")
+
+func TestInstall(t *testing.T) {
+	log := logrus.NewEntry(logrus.StandardLogger())
+	dir := t.TempDir()
+	b := New(Server{Name: "test", Binary: "com.docker.test-server", NotFound: errNotFound}, log, nil, log, dir)
+
+	if err := b.Install(context.Background(), nil); !errors.Is(err, errNotFound) {
+		t.Fatalf("Expected the not found error, got %v", err)
+	}
+	if b.Status() != errNotFound.Error() {
+		t.Errorf("Expected status %q, got %q", errNotFound.Error(), b.Status())
+	}
+	if size, err := b.GetDiskUsage(); err != nil || size != 0 {
+		t.Errorf("Expected no disk usage, got %d, %v", size, err)
+	}
+
+	name := "com.docker.test-server"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 42), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Install(context.Background(), nil); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if b.Status() != "installed" {
+		t.Errorf("Expected status installed, got %q", b.Status())
+	}
+	if size, err := b.GetDiskUsage(); err != nil || size != 42 {
+		t.Errorf("Expected the binary's size as disk usage, got %d, %v", size, err)
+	}
+}
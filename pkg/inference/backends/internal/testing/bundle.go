@@ -0,0 +1,75 @@
+// Package testing provides common test utilities for the backends of bundled
+// servers.
+package testing
+
+import (
+	"github.com/docker/model-runner/pkg/distribution/types"
+)
+
+// FakeBundle is a model bundle with a single weights file, for testing the
+// arguments that backends build for their servers.
+type FakeBundle struct {
+	// Whisper is the path of the whisper.cpp model.
+	Whisper string
+	// Voice is the path of the piper voice.
+	Voice string
+	// Diffusion is the path of the stable-diffusion.cpp model.
+	Diffusion string
+	// ONNX is the path of the ONNX Runtime model.
+	ONNX string
+}
+
+// RootDir implements types.ModelBundle.RootDir.
+func (f *FakeBundle) RootDir() string {
+	panic("shouldn't be called")
+}
+
+// GGUFPath implements types.ModelBundle.GGUFPath.
+func (f *FakeBundle) GGUFPath() string {
+	return ""
+}
+
+// SafetensorsPath implements types.ModelBundle.SafetensorsPath.
+func (f *FakeBundle) SafetensorsPath() string {
+	return ""
+}
+
+// ChatTemplatePath implements types.ModelBundle.ChatTemplatePath.
+func (f *FakeBundle) ChatTemplatePath() string {
+	return ""
+}
+
+// MMPROJPath implements types.ModelBundle.MMPROJPath.
+func (f *FakeBundle) MMPROJPath() string {
+	return ""
+}
+
+// LoRAAdapterPaths implements types.ModelBundle.LoRAAdapterPaths.
+func (f *FakeBundle) LoRAAdapterPaths() []string {
+	return nil
+}
+
+// WhisperPath implements types.ModelBundle.WhisperPath.
+func (f *FakeBundle) WhisperPath() string {
+	return f.Whisper
+}
+
+// VoicePath implements types.ModelBundle.VoicePath.
+func (f *FakeBundle) VoicePath() string {
+	return f.Voice
+}
+
+// DiffusionPath implements types.ModelBundle.DiffusionPath.
+func (f *FakeBundle) DiffusionPath() string {
+	return f.Diffusion
+}
+
+// ONNXPath implements types.ModelBundle.ONNXPath.
+func (f *FakeBundle) ONNXPath() string {
+	return f.ONNX
+}
+
+// RuntimeConfig implements types.ModelBundle.RuntimeConfig.
+func (f *FakeBundle) RuntimeConfig() types.Config {
+	return types.Config{}
+}
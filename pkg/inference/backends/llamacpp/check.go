@@ -0,0 +1,109 @@
+package llamacpp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/go-units"
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+const (
+	// assumedRAMBandwidth is the RAM bandwidth (in bytes per second) used to
+	// estimate generation speed for layers that run on the CPU.
+	assumedRAMBandwidth = 50e9
+	// assumedVRAMBandwidth is the VRAM bandwidth (in bytes per second) used to
+	// estimate generation speed for layers offloaded to the GPU.
+	assumedVRAMBandwidth = 400e9
+)
+
+// CheckModel implements inference.ModelChecker.CheckModel. Remote models are
+// checked by reading only the GGUF header from the registry.
+func (l *llamaCpp) CheckModel(ctx context.Context, model string, config *inference.BackendConfiguration, available inference.RequiredMemory) (inference.ModelCheck, error) {
+	mdlGguf, mdlConfig, err := l.parseModel(ctx, model)
+	if err != nil {
+		return inference.ModelCheck{}, fmt.Errorf("inspecting model: %w", err)
+	}
+	return checkGGUF(mdlGguf, mdlConfig, config, available, l.gpuSupported), nil
+}
+
+// checkGGUF assesses whether a parsed GGUF file can run in the available
+// memory.
+func checkGGUF(mdlGguf *parser.GGUFFile, mdlConfig types.Config, config *inference.BackendConfiguration, available inference.RequiredMemory, gpuSupported bool) inference.ModelCheck {
+	contextSize := GetContextSize(mdlConfig, config)
+	check := inference.ModelCheck{
+		Backend:      Name,
+		Architecture: mdlConfig.Architecture,
+		Parameters:   mdlConfig.Parameters,
+		Quantization: mdlConfig.Quantization,
+		ContextSize:  contextSize,
+		GPUSupported: gpuSupported,
+		TotalLayers:  estimateRun(mdlGguf, contextSize, 999, config).OffloadLayers,
+		Available:    available,
+	}
+
+	estimate := estimateRun(mdlGguf, contextSize, 0, config)
+	if gpuSupported && available.VRAM > 1 {
+		check.OffloadedLayers = fitLayers(mdlGguf, contextSize, config, available.VRAM)
+		estimate = estimateRun(mdlGguf, contextSize, check.OffloadedLayers, config)
+	}
+	check.Required = requiredMemory(estimate)
+	check.TokensPerSecond = estimateTokensPerSecond(estimate)
+
+	// A RAM size of 1 indicates that the system RAM is unknown.
+	check.Fits = available.RAM <= 1 || check.Required.RAM <= available.RAM
+	check.Verdict = verdict(check)
+	return check
+}
+
+// fitLayers returns the largest number of layers of a parsed GGUF file whose
+// VRAM requirement fits in vram. The requirement grows with the number of
+// offloaded layers, so it's found by binary search.
+func fitLayers(mdlGguf *parser.GGUFFile, contextSize uint64, config *inference.BackendConfiguration, vram uint64) uint64 {
+	low, high := uint64(0), estimateRun(mdlGguf, contextSize, 999, config).OffloadLayers
+	for low < high {
+		ngl := (low + high + 1) / 2
+		if requiredMemory(estimateRun(mdlGguf, contextSize, ngl, config)).VRAM <= vram {
+			low = ngl
+		} else {
+			high = ngl - 1
+		}
+	}
+	return low
+}
+
+// estimateTokensPerSecond roughly estimates the generation speed of a run
+// estimate. Generating a token requires reading all weights, so generation is
+// assumed to be bound by the memory bandwidth of the devices holding them.
+func estimateTokensPerSecond(estimate parser.LLaMACppRunEstimate) float64 {
+	seconds := float64(estimate.Devices[0].Weight.Sum()) / assumedRAMBandwidth
+	for _, device := range estimate.Devices[1:] {
+		seconds += float64(device.Weight.Sum()) / assumedVRAMBandwidth
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return 1 / seconds
+}
+
+// verdict summarizes a model check for users.
+func verdict(check inference.ModelCheck) string {
+	if !check.Fits {
+		return fmt.Sprintf("does not fit: requires %s RAM, but only %s is available",
+			units.BytesSize(float64(check.Required.RAM)), units.BytesSize(float64(check.Available.RAM)))
+	}
+	speed := fmt.Sprintf("est. %.0f tok/s", check.TokensPerSecond)
+	switch {
+	case !check.GPUSupported:
+		return "fits in RAM (no GPU support), " + speed
+	case check.OffloadedLayers == 0:
+		return "fits in RAM, but no layers fit in VRAM, " + speed
+	case check.OffloadedLayers == check.TotalLayers:
+		return fmt.Sprintf("fits in VRAM with all %d layers offloaded, %s", check.TotalLayers, speed)
+	default:
+		return fmt.Sprintf("fits in VRAM with %d/%d layers offloaded, %s", check.OffloadedLayers, check.TotalLayers, speed)
+	}
+}
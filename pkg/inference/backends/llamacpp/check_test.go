@@ -0,0 +1,101 @@
+package llamacpp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestCheckGGUF(t *testing.T) {
+	mdlGguf, err := parser.ParseGGUFFile(filepath.Join("..", "..", "..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to parse GGUF: %v", err)
+	}
+	const plenty = 1 << 40
+
+	tests := []struct {
+		name         string
+		available    inference.RequiredMemory
+		gpuSupported bool
+		fits         bool
+		allOffloaded bool
+		verdict      string
+	}{
+		{name: "no GPU support", available: inference.RequiredMemory{RAM: plenty, VRAM: plenty}, fits: true, verdict: "no GPU support"},
+		{name: "fully offloaded", available: inference.RequiredMemory{RAM: plenty, VRAM: plenty}, gpuSupported: true, fits: true, allOffloaded: true, verdict: "fits in VRAM with all"},
+		{name: "insufficient VRAM", available: inference.RequiredMemory{RAM: plenty, VRAM: 2}, gpuSupported: true, fits: true, verdict: "no layers fit in VRAM"},
+		{name: "unknown VRAM", available: inference.RequiredMemory{RAM: plenty, VRAM: 1}, gpuSupported: true, fits: true, verdict: "no layers fit in VRAM"},
+		{name: "insufficient RAM", available: inference.RequiredMemory{RAM: 2, VRAM: plenty}, fits: false, verdict: "does not fit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := checkGGUF(mdlGguf, types.Config{}, &inference.BackendConfiguration{ContextSize: 2048}, tt.available, tt.gpuSupported)
+			if check.ContextSize != 2048 {
+				t.Errorf("Expected context size 2048, got %d", check.ContextSize)
+			}
+			if check.TotalLayers == 0 {
+				t.Fatal("Expected model to have layers")
+			}
+			if check.Fits != tt.fits {
+				t.Errorf("Expected fits %v, got %v", tt.fits, check.Fits)
+			}
+			if allOffloaded := check.OffloadedLayers == check.TotalLayers; allOffloaded != tt.allOffloaded {
+				t.Errorf("Expected all layers offloaded %v, got %d/%d", tt.allOffloaded, check.OffloadedLayers, check.TotalLayers)
+			}
+			if !strings.Contains(check.Verdict, tt.verdict) {
+				t.Errorf("Expected verdict to contain %q, got %q", tt.verdict, check.Verdict)
+			}
+		})
+	}
+}
+
+func TestFitLayers(t *testing.T) {
+	mdlGguf, err := parser.ParseGGUFFile(filepath.Join("..", "..", "..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to parse GGUF: %v", err)
+	}
+	config := &inference.BackendConfiguration{ContextSize: 2048}
+	total := estimateRun(mdlGguf, 2048, 999, config).OffloadLayers
+	if total < 2 {
+		t.Skipf("Model has %d layers, too few to offload partially", total)
+	}
+
+	// With exactly the VRAM of all but one layer, all but one are offloaded.
+	vram := requiredMemory(estimateRun(mdlGguf, 2048, total-1, config)).VRAM
+	if layers := fitLayers(mdlGguf, 2048, config, vram); layers != total-1 {
+		t.Errorf("Expected %d layers to fit, got %d", total-1, layers)
+	}
+	if layers := fitLayers(mdlGguf, 2048, config, 0); layers != 0 {
+		t.Errorf("Expected no layers to fit without VRAM, got %d", layers)
+	}
+}
+
+func TestEstimateRunKVCache(t *testing.T) {
+	mdlGguf, err := parser.ParseGGUFFile(filepath.Join("..", "..", "..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to parse GGUF: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		kvCache        *inference.KVCacheConfig
+		flashAttention bool
+	}{
+		{name: "default cache types"},
+		{name: "quantized keys", kvCache: &inference.KVCacheConfig{TypeK: "q8_0"}},
+		{name: "quantized values", kvCache: &inference.KVCacheConfig{TypeK: "q8_0", TypeV: "q4_0"}, flashAttention: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate := estimateRun(mdlGguf, 2048, 999, &inference.BackendConfiguration{KVCache: tt.kvCache})
+			if estimate.FlashAttention != tt.flashAttention {
+				t.Errorf("Expected flash attention %v, got %v", tt.flashAttention, estimate.FlashAttention)
+			}
+		})
+	}
+}
@@ -95,7 +95,11 @@ func (l *llamaCpp) downloadLatestLlamaCpp(ctx context.Context, log logging.Logge
 	currentVersionFile := filepath.Join(filepath.Dir(llamaCppPath), ".llamacpp_version")
 
 	data, err := os.ReadFile(bundledVersionFile)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) {
+		// The bundled variant may differ, in which case its version isn't
+		// recorded.
+		log.Infof("bundled llama.cpp version unknown, proceeding to download the %s variant", desiredVariant)
+	} else if err != nil {
 		return fmt.Errorf("failed to read bundled llama.cpp version: %w", err)
 	} else if strings.TrimSpace(string(data)) == latest {
 		l.status = fmt.Sprintf("running llama.cpp %s (%s) version: %s",
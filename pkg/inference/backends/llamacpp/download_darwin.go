@@ -11,7 +11,10 @@ func (l *llamaCpp) ensureLatestLlamaCpp(ctx context.Context, log logging.Logger,
 	llamaCppPath, vendoredServerStoragePath string,
 ) error {
 	desiredVersion := GetDesiredServerVersion()
-	desiredVariant := "metal"
+	desiredVariant := VariantMetal
+	if pinned := GetDesiredServerVariant(); pinned != "" {
+		desiredVariant = pinned
+	}
 	return l.downloadLatestLlamaCpp(ctx, log, httpClient, llamaCppPath, vendoredServerStoragePath, desiredVersion,
 		desiredVariant)
 }
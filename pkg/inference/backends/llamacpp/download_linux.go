@@ -4,15 +4,46 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
 
+	"github.com/docker/model-runner/pkg/gpuinfo"
 	"github.com/docker/model-runner/pkg/logging"
 )
 
-func (l *llamaCpp) ensureLatestLlamaCpp(_ context.Context, log logging.Logger, _ *http.Client,
-	_, vendoredServerStoragePath string,
+// ensureLatestLlamaCpp downloads the variant of com.docker.llama-server for the
+// system's accelerator, or the pinned variant, if the bundled variant differs.
+// The bundled variant is otherwise kept, as it's updated with the image.
+func (l *llamaCpp) ensureLatestLlamaCpp(ctx context.Context, log logging.Logger, httpClient *http.Client,
+	llamaCppPath, vendoredServerStoragePath string,
 ) error {
-	l.status = fmt.Sprintf("running llama.cpp version: %s",
-		getLlamaCppVersion(log, filepath.Join(vendoredServerStoragePath, "com.docker.llama-server")))
-	return errLlamaCppUpdateDisabled
+	bundledVariant := getBundledVariant(vendoredServerStoragePath)
+	desiredVariant := GetDesiredServerVariant()
+	if desiredVariant == "" && bundledVariant != "" {
+		desiredVariant = detectServerVariant()
+	}
+	if desiredVariant == "" || desiredVariant == bundledVariant {
+		l.status = fmt.Sprintf("running llama.cpp version: %s",
+			getLlamaCppVersion(log, filepath.Join(vendoredServerStoragePath, "com.docker.llama-server")))
+		return errLlamaCppUpdateDisabled
+	}
+	log.Infof("Bundled llama.cpp variant %q differs from the %s variant for this system", bundledVariant, desiredVariant)
+	l.status = fmt.Sprintf("looking for updates for %s variant", desiredVariant)
+	return l.downloadLatestLlamaCpp(ctx, log, httpClient, llamaCppPath, vendoredServerStoragePath, GetDesiredServerVersion(),
+		desiredVariant)
+}
+
+// detectServerVariant returns the variant of com.docker.llama-server for the
+// system's accelerator.
+func detectServerVariant() string {
+	if _, err := os.Stat("/proc/driver/nvidia/version"); err == nil {
+		return VariantCUDA
+	}
+	if gpuinfo.HasAMDGPU() {
+		return VariantROCm
+	}
+	if _, err := gpuinfo.New("").GetNPUs(); err == nil {
+		return VariantCANN
+	}
+	return VariantCPU
 }
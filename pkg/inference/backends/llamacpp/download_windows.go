@@ -18,7 +18,8 @@ func (l *llamaCpp) ensureLatestLlamaCpp(ctx context.Context, log logging.Logger,
 	var err error
 	ShouldUseGPUVariantLock.Lock()
 	defer ShouldUseGPUVariantLock.Unlock()
-	if ShouldUseGPUVariant {
+	pinnedVariant := GetDesiredServerVariant()
+	if ShouldUseGPUVariant && pinnedVariant == "" {
 		if runtime.GOARCH == "amd64" {
 			canUseCUDA11, err = hasCUDA11CapableGPU(ctx, nvGPUInfoBin)
 			if err != nil {
@@ -34,11 +35,13 @@ func (l *llamaCpp) ensureLatestLlamaCpp(ctx context.Context, log logging.Logger,
 		}
 	}
 	desiredVersion := GetDesiredServerVersion()
-	desiredVariant := "cpu"
-	if canUseCUDA11 {
-		desiredVariant = "cuda"
+	desiredVariant := VariantCPU
+	if pinnedVariant != "" {
+		desiredVariant = pinnedVariant
+	} else if canUseCUDA11 {
+		desiredVariant = VariantCUDA
 	} else if canUseOpenCL {
-		desiredVariant = "opencl"
+		desiredVariant = VariantOpenCL
 	}
 	l.status = fmt.Sprintf("looking for updates for %s variant", desiredVariant)
 	return l.downloadLatestLlamaCpp(ctx, log, httpClient, llamaCppPath, vendoredServerStoragePath, desiredVersion,
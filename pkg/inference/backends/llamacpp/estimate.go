@@ -0,0 +1,216 @@
+package llamacpp
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+const (
+	// defaultBatchSize is the logical batch size (--batch-size) of llama.cpp.
+	defaultBatchSize = 2048
+	// defaultMicroBatchSize is the physical batch size (--ubatch-size) of
+	// llama.cpp.
+	defaultMicroBatchSize = 512
+)
+
+// runParameters are the parameters of a llama.cpp run, besides the context
+// size and offloaded layers, that affect its memory.
+type runParameters struct {
+	// batchSize and microBatchSize are the logical and physical batch sizes.
+	batchSize      uint64
+	microBatchSize uint64
+	// flashAttention indicates whether flash attention is enabled. It's
+	// only assumed if it's forced on, because whether "auto" enables it
+	// depends on the device.
+	flashAttention bool
+}
+
+// getRunParameters returns the run parameters of a backend configuration,
+// reading the batch sizes and flash attention from its runtime flags. It is
+// safe to call on a nil configuration.
+func getRunParameters(config *inference.BackendConfiguration) runParameters {
+	params := runParameters{batchSize: defaultBatchSize, microBatchSize: defaultMicroBatchSize}
+	if config == nil {
+		return params
+	}
+	if config.BatchSize > 0 {
+		params.batchSize = uint64(config.BatchSize)
+	}
+	for i := 0; i < len(config.RuntimeFlags); i++ {
+		flag, value, hasValue := strings.Cut(config.RuntimeFlags[i], "=")
+		next := func() string {
+			if hasValue {
+				return value
+			}
+			if i+1 < len(config.RuntimeFlags) {
+				i++
+				return config.RuntimeFlags[i]
+			}
+			return ""
+		}
+		switch flag {
+		case "-b", "--batch-size":
+			if size, err := strconv.ParseUint(next(), 10, 64); err == nil && size > 0 {
+				params.batchSize = size
+			}
+		case "-ub", "--ubatch-size":
+			if size, err := strconv.ParseUint(next(), 10, 64); err == nil && size > 0 {
+				params.microBatchSize = size
+			}
+		case "-fa", "--flash-attn":
+			// Older versions of llama.cpp take no value.
+			if !hasValue && (i+1 >= len(config.RuntimeFlags) || strings.HasPrefix(config.RuntimeFlags[i+1], "-")) {
+				params.flashAttention = true
+				continue
+			}
+			switch next() {
+			case "on", "1", "true":
+				params.flashAttention = true
+			case "off", "0", "false":
+				params.flashAttention = false
+			}
+		}
+	}
+	// llama.cpp processes at most a logical batch at once.
+	params.microBatchSize = min(params.microBatchSize, params.batchSize)
+	// llama.cpp requires flash attention to quantize the value cache, so it
+	// must be enabled.
+	if config.KVCache != nil {
+		if typ, ok := kvCacheTypes[config.KVCache.TypeV]; ok && typ.IsQuantized() {
+			params.flashAttention = true
+		}
+	}
+	return params
+}
+
+// EstimateMemory implements inference.MemoryEstimateReporter.EstimateMemory.
+// Remote models are estimated by reading only the GGUF header from the
+// registry. The memory of a draft model, if configured, is included.
+func (l *llamaCpp) EstimateMemory(ctx context.Context, model string, config *inference.BackendConfiguration) (inference.MemoryEstimate, error) {
+	mdlGguf, mdlConfig, err := l.parseModel(ctx, model)
+	if err != nil {
+		return inference.MemoryEstimate{}, &inference.ErrGGUFParse{Err: err}
+	}
+
+	ngl := uint64(0)
+	if l.gpuSupported {
+		ngl = 999
+	}
+	contextSize := GetContextSize(mdlConfig, config)
+	config = withModelRuntimeFlags(mdlConfig, config)
+	estimate := estimateGGUF(mdlGguf, contextSize, ngl, config)
+
+	if config != nil && config.Speculative != nil && config.Speculative.DraftModel != "" {
+		draftGguf, _, err := l.parseModel(ctx, config.Speculative.DraftModel)
+		if err != nil {
+			return inference.MemoryEstimate{}, fmt.Errorf("estimating draft model memory: %w", &inference.ErrGGUFParse{Err: err})
+		}
+		draft := estimateGGUF(draftGguf, contextSize, ngl, config)
+		estimate.Weights = addMemory(estimate.Weights, draft.Weights)
+		estimate.KVCache = addMemory(estimate.KVCache, draft.KVCache)
+		estimate.Compute = addMemory(estimate.Compute, draft.Compute)
+		estimate.Required = addMemory(estimate.Required, draft.Required)
+	}
+
+	if runtime.GOOS == "windows" && runtime.GOARCH == "arm64" {
+		estimate.Required.VRAM = 1
+	}
+	return estimate, nil
+}
+
+// FitGPULayers implements inference.PartialOffloader.FitGPULayers. The VRAM
+// of a draft model, if configured, is reserved before fitting the layers of
+// the model, as the draft model is always fully offloaded.
+func (l *llamaCpp) FitGPULayers(ctx context.Context, model string, config *inference.BackendConfiguration, vram uint64) (uint64, inference.RequiredMemory, error) {
+	mdlGguf, mdlConfig, err := l.parseModel(ctx, model)
+	if err != nil {
+		return 0, inference.RequiredMemory{}, &inference.ErrGGUFParse{Err: err}
+	}
+	contextSize := GetContextSize(mdlConfig, config)
+	config = withModelRuntimeFlags(mdlConfig, config)
+
+	var draft inference.RequiredMemory
+	if config != nil && config.Speculative != nil && config.Speculative.DraftModel != "" {
+		draftGguf, _, err := l.parseModel(ctx, config.Speculative.DraftModel)
+		if err != nil {
+			return 0, inference.RequiredMemory{}, fmt.Errorf("estimating draft model memory: %w", &inference.ErrGGUFParse{Err: err})
+		}
+		draft = requiredMemory(estimateRun(draftGguf, contextSize, 999, config))
+	}
+	if !l.gpuSupported || draft.VRAM > vram {
+		return 0, addMemory(requiredMemory(estimateRun(mdlGguf, contextSize, 0, config)), draft), nil
+	}
+
+	layers := fitLayers(mdlGguf, contextSize, config, vram-draft.VRAM)
+	return layers, addMemory(requiredMemory(estimateRun(mdlGguf, contextSize, layers, config)), draft), nil
+}
+
+// withModelRuntimeFlags returns the backend config with the runtime flags
+// persisted in the model config applied before its own, as when the model is
+// loaded.
+func withModelRuntimeFlags(mdlConfig types.Config, config *inference.BackendConfiguration) *inference.BackendConfiguration {
+	if len(mdlConfig.RuntimeFlags) == 0 {
+		return config
+	}
+	merged := inference.BackendConfiguration{}
+	if config != nil {
+		merged = *config
+	}
+	merged.RuntimeFlags = append(slices.Clone(mdlConfig.RuntimeFlags), merged.RuntimeFlags...)
+	return &merged
+}
+
+// estimateGGUF breaks down the memory of running a parsed GGUF file with ngl
+// layers offloaded. The KV cache is sized from the number of layers, key/value
+// heads and their lengths, and the compute buffers from the batch sizes and
+// whether flash attention avoids materializing the attention scores, as per the
+// model architecture.
+func estimateGGUF(ggufFile *parser.GGUFFile, contextSize uint64, ngl uint64, config *inference.BackendConfiguration) inference.MemoryEstimate {
+	arch := ggufFile.Architecture()
+	params := getRunParameters(config)
+	run := estimateRun(ggufFile, contextSize, ngl, config)
+
+	estimate := inference.MemoryEstimate{
+		Backend:         Name,
+		Architecture:    arch.Architecture,
+		Layers:          arch.BlockCount,
+		OffloadedLayers: run.OffloadLayers,
+		AttentionHeads:  arch.AttentionHeadCount,
+		KVHeads:         arch.AttentionHeadCountKV,
+		EmbeddingLength: arch.EmbeddingLength,
+		ContextSize:     run.ContextSize,
+		BatchSize:       params.batchSize,
+		MicroBatchSize:  params.microBatchSize,
+		FlashAttention:  run.FlashAttention,
+		Required:        requiredMemory(run),
+	}
+	if estimate.KVHeads == 0 {
+		estimate.KVHeads = estimate.AttentionHeads
+	}
+	// The first device is the CPU and the second, if any, the GPU.
+	ram := run.Devices[0]
+	estimate.Weights.RAM = uint64(ram.Weight.Sum())
+	estimate.KVCache.RAM = uint64(ram.KVCache.Sum())
+	estimate.Compute.RAM = uint64(ram.Computation.Sum())
+	if len(run.Devices) > 1 {
+		vram := run.Devices[1]
+		estimate.Weights.VRAM = uint64(vram.Weight.Sum())
+		estimate.KVCache.VRAM = uint64(vram.KVCache.Sum())
+		estimate.Compute.VRAM = uint64(vram.Computation.Sum())
+	}
+	return estimate
+}
+
+// addMemory returns the sum of two memory requirements.
+func addMemory(a, b inference.RequiredMemory) inference.RequiredMemory {
+	return inference.RequiredMemory{RAM: a.RAM + b.RAM, VRAM: a.VRAM + b.VRAM}
+}
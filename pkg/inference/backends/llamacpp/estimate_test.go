@@ -0,0 +1,94 @@
+package llamacpp
+
+import (
+	"path/filepath"
+	"testing"
+
+	parser "github.com/gpustack/gguf-parser-go"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestGetRunParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *inference.BackendConfiguration
+		want   runParameters
+	}{
+		{name: "nil config", want: runParameters{batchSize: 2048, microBatchSize: 512}},
+		{
+			name:   "batch sizes",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"-b", "1024", "--ubatch-size=256"}},
+			want:   runParameters{batchSize: 1024, microBatchSize: 256},
+		},
+		{
+			name:   "micro batch capped by batch",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--batch-size", "128"}},
+			want:   runParameters{batchSize: 128, microBatchSize: 128},
+		},
+		{
+			name:   "flash attention on",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--flash-attn", "on"}},
+			want:   runParameters{batchSize: 2048, microBatchSize: 512, flashAttention: true},
+		},
+		{
+			name:   "flash attention without value",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"-fa", "--threads", "4"}},
+			want:   runParameters{batchSize: 2048, microBatchSize: 512, flashAttention: true},
+		},
+		{
+			name:   "flash attention auto",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"-fa", "auto"}},
+			want:   runParameters{batchSize: 2048, microBatchSize: 512},
+		},
+		{
+			name: "quantized value cache",
+			config: &inference.BackendConfiguration{
+				RuntimeFlags: []string{"--flash-attn=off"},
+				KVCache:      &inference.KVCacheConfig{TypeV: "q8_0"},
+			},
+			want: runParameters{batchSize: 2048, microBatchSize: 512, flashAttention: true},
+		},
+		{
+			name:   "invalid batch size",
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"-b", "lots"}},
+			want:   runParameters{batchSize: 2048, microBatchSize: 512},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getRunParameters(tt.config); got != tt.want {
+				t.Errorf("getRunParameters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateGGUF(t *testing.T) {
+	mdlGguf, err := parser.ParseGGUFFile(filepath.Join("..", "..", "..", "..", "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to parse GGUF: %v", err)
+	}
+
+	estimate := estimateGGUF(mdlGguf, 2048, 999, nil)
+	if estimate.Architecture != "llama" || estimate.OffloadedLayers == 0 {
+		t.Errorf("Expected an offloaded llama model, got %+v", estimate)
+	}
+	if estimate.ContextSize != 2048 || estimate.BatchSize != 2048 || estimate.MicroBatchSize != 512 || estimate.FlashAttention {
+		t.Errorf("Expected the default run parameters, got %+v", estimate)
+	}
+	total := addMemory(addMemory(estimate.Weights, estimate.KVCache), estimate.Compute)
+	if total != estimate.Required {
+		t.Errorf("Expected the breakdown %+v to add up to the required memory %+v", total, estimate.Required)
+	}
+
+	// The run parameters of the configuration are assumed.
+	config := &inference.BackendConfiguration{RuntimeFlags: []string{"--flash-attn", "on", "-ub", "256"}}
+	configured := estimateGGUF(mdlGguf, 2048, 999, config)
+	if !configured.FlashAttention || configured.MicroBatchSize != 256 {
+		t.Errorf("Expected flash attention and a micro batch size of 256, got %+v", configured)
+	}
+	if configured.Compute == estimate.Compute {
+		t.Errorf("Expected the compute buffers to depend on the run parameters, got %+v", configured.Compute)
+	}
+}
@@ -0,0 +1,20 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ConstrainToJSONSchema implements inference.JSONSchemaConstrainer. The
+// response_format is replaced by llama-server's json_schema request field,
+// which it converts into a grammar for the request, since not every
+// llama-server version understands json_schema response formats.
+func (l *llamaCpp) ConstrainToJSONSchema(body []byte, schema json.RawMessage) ([]byte, error) {
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("decoding request: %w", err)
+	}
+	delete(request, "response_format")
+	request["json_schema"] = schema
+	return json.Marshal(request)
+}
@@ -0,0 +1,30 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConstrainToJSONSchema(t *testing.T) {
+	l := &llamaCpp{}
+	body := []byte(`{"model": "ai/model", "temperature": 0.1, "response_format": {"type": "json_schema", "json_schema": {"schema": {"type": "object"}}}}`)
+
+	constrained, err := l.ConstrainToJSONSchema(body, json.RawMessage(`{"type": "object"}`))
+	if err != nil {
+		t.Fatalf("ConstrainToJSONSchema failed: %v", err)
+	}
+
+	var got, expected map[string]any
+	if err := json.Unmarshal(constrained, &got); err != nil {
+		t.Fatalf("Constrained request isn't JSON: %v", err)
+	}
+	json.Unmarshal([]byte(`{"model": "ai/model", "temperature": 0.1, "json_schema": {"type": "object"}}`), &expected)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ConstrainToJSONSchema() = %v, want %v", got, expected)
+	}
+
+	if _, err := l.ConstrainToJSONSchema([]byte(`[]`), json.RawMessage(`{}`)); err == nil {
+		t.Error("Expected an error for a request that isn't an object")
+	}
+}
@@ -12,16 +12,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docker/model-runner/pkg/distribution/types"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	parser "github.com/gpustack/gguf-parser-go"
 
 	"github.com/docker/model-runner/pkg/diskusage"
+	"github.com/docker/model-runner/pkg/gpuinfo"
 	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/config"
 	"github.com/docker/model-runner/pkg/inference/models"
@@ -34,6 +37,12 @@ import (
 const (
 	// Name is the backend name.
 	Name = "llama.cpp"
+	// rocmVariantDir is the subdirectory of a server storage path holding the
+	// ROCm variant of com.docker.llama-server, if it's installed.
+	rocmVariantDir = "rocm"
+	// crashOutputSize is the number of bytes at the end of the standard error
+	// of llama.cpp that are kept for crash reports.
+	crashOutputSize = 8 * 1024
 )
 
 // llamaCpp is the llama.cpp-based backend implementation.
@@ -52,10 +61,17 @@ type llamaCpp struct {
 	updatedServerStoragePath string
 	// status is the state in which the llama.cpp backend is in.
 	status string
+	// configLock protects config.
+	configLock sync.Mutex
 	// config is the configuration for the llama.cpp backend.
 	config config.BackendConfig
 	// gpuSupported indicates whether the underlying llama-server is built with GPU support.
 	gpuSupported bool
+	// useROCm indicates whether to run the ROCm variant of llama-server, if
+	// it's installed, because the system has AMD GPUs.
+	useROCm bool
+	// version is the version of the installed llama-server.
+	version string
 }
 
 // New creates a new llama.cpp-based backend.
@@ -82,6 +98,20 @@ func New(
 	}, nil
 }
 
+// SetConfig replaces the configuration used for runners started from now on,
+// restoring the default configuration if conf is nil. It reports whether the
+// configuration changed.
+func (l *llamaCpp) SetConfig(conf config.BackendConfig) bool {
+	if conf == nil {
+		conf = NewDefaultLlamaCppConfig()
+	}
+	l.configLock.Lock()
+	defer l.configLock.Unlock()
+	changed := !reflect.DeepEqual(l.config, conf)
+	l.config = conf
+	return changed
+}
+
 // Name implements inference.Backend.Name.
 func (l *llamaCpp) Name() string {
 	return Name
@@ -128,25 +158,81 @@ func (l *llamaCpp) Install(ctx context.Context, httpClient *http.Client) error {
 		l.updatedLlamaCpp = true
 	}
 
+	l.useROCm = runtime.GOOS == "linux" && gpuinfo.HasAMDGPU()
+	if l.useROCm && l.binPath() != l.storagePath() {
+		l.log.Infof("Using the ROCm variant of llama-server for AMD GPUs")
+	}
+
 	l.gpuSupported = l.checkGPUSupport(ctx)
+	l.version = getLlamaCppVersion(l.log, filepath.Join(l.binPath(), llamaServerBin))
 	l.log.Infof("installed llama-server with gpuSupport=%t", l.gpuSupported)
 
 	return nil
 }
 
+// storagePath returns the path of the installed version of
+// com.docker.llama-server.
+func (l *llamaCpp) storagePath() string {
+	if l.updatedLlamaCpp {
+		return l.updatedServerStoragePath
+	}
+	return l.vendoredServerStoragePath
+}
+
+// binPath returns the directory of the com.docker.llama-server binary to run,
+// which is that of the ROCm variant on systems with AMD GPUs, if the variant is
+// installed.
+func (l *llamaCpp) binPath() string {
+	storagePath := l.storagePath()
+	if l.useROCm {
+		rocmPath := filepath.Join(storagePath, rocmVariantDir)
+		if _, err := os.Stat(filepath.Join(rocmPath, "com.docker.llama-server")); err == nil {
+			return rocmPath
+		}
+	}
+	return storagePath
+}
+
 // Run implements inference.Backend.Run.
 func (l *llamaCpp) Run(ctx context.Context, socket, model string, _ string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
-	bundle, err := l.modelManager.GetBundle(model)
+	bundle, release, err := l.modelManager.AcquireBundle(model)
 	if err != nil {
 		return fmt.Errorf("failed to get model: %w", err)
 	}
+	defer release()
 
 	var draftBundle types.ModelBundle
 	if config != nil && config.Speculative != nil && config.Speculative.DraftModel != "" {
-		draftBundle, err = l.modelManager.GetBundle(config.Speculative.DraftModel)
+		var releaseDraft func()
+		draftBundle, releaseDraft, err = l.modelManager.AcquireBundle(config.Speculative.DraftModel)
 		if err != nil {
 			return fmt.Errorf("failed to get draft model: %w", err)
 		}
+		defer releaseDraft()
+		if draftBundle.GGUFPath() == "" {
+			return fmt.Errorf("draft model %s does not contain a GGUF file", config.Speculative.DraftModel)
+		}
+	}
+
+	// LoRA adapters are loaded with their scales, which can be changed while
+	// the server runs, but the adapters themselves can't.
+	var adapterArgs []string
+	if config != nil {
+		for _, adapter := range config.LoRAAdapters {
+			adapterBundle, releaseAdapter, err := l.modelManager.AcquireBundle(adapter.Model)
+			if err != nil {
+				return fmt.Errorf("failed to get LoRA adapter model: %w", err)
+			}
+			defer releaseAdapter()
+			paths := adapterBundle.LoRAAdapterPaths()
+			if len(paths) == 0 {
+				return fmt.Errorf("model %s does not contain a LoRA adapter", adapter.Model)
+			}
+			scale := strconv.FormatFloat(adapter.AdapterScale(), 'f', -1, 64)
+			for _, path := range paths {
+				adapterArgs = append(adapterArgs, "--lora-scaled", path, scale)
+			}
+		}
 	}
 
 	if err := os.RemoveAll(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
@@ -154,41 +240,44 @@ func (l *llamaCpp) Run(ctx context.Context, socket, model string, _ string, mode
 		l.log.Warnln("llama.cpp may not be able to start")
 	}
 
-	binPath := l.vendoredServerStoragePath
-	if l.updatedLlamaCpp {
-		binPath = l.updatedServerStoragePath
-	}
+	binPath := l.binPath()
 
-	args, err := l.config.GetArgs(bundle, socket, mode, config)
+	l.configLock.Lock()
+	backendConfig := l.config
+	l.configLock.Unlock()
+	args, err := backendConfig.GetArgs(bundle, socket, mode, config)
 	if err != nil {
 		return fmt.Errorf("failed to get args for llama.cpp: %w", err)
 	}
 
-	if draftBundle != nil && config != nil && config.Speculative != nil {
-		draftPath := draftBundle.GGUFPath()
-		if draftPath != "" {
-			args = append(args, "--model-draft", draftPath)
-			if config.Speculative.NumTokens > 0 {
-				args = append(args, "--draft-max", strconv.Itoa(config.Speculative.NumTokens))
-			}
-			if config.Speculative.MinAcceptanceRate > 0 {
-				args = append(args, "--draft-p-min", strconv.FormatFloat(config.Speculative.MinAcceptanceRate, 'f', 2, 64))
-			}
+	if draftBundle != nil {
+		args = append(args, "--model-draft", draftBundle.GGUFPath())
+		if config.Speculative.NumTokens > 0 {
+			args = append(args, "--draft-max", strconv.Itoa(config.Speculative.NumTokens))
+		}
+		if config.Speculative.MinAcceptanceRate > 0 {
+			args = append(args, "--draft-p-min", strconv.FormatFloat(config.Speculative.MinAcceptanceRate, 'f', 2, 64))
 		}
 	}
 
+	args = append(args, adapterArgs...)
+
 	// Sanitize args for safe logging
 	sanitizedArgs := make([]string, len(args))
 	for i, arg := range args {
 		sanitizedArgs[i] = utils.SanitizeForLog(arg)
 	}
 	l.log.Infof("llamaCppArgs: %v", sanitizedArgs)
-	tailBuf := tailbuffer.NewTailBuffer(1024)
+	tailBuf := tailbuffer.NewTailBuffer(crashOutputSize)
 	serverLogStream := l.serverLog.Writer()
 	out := io.MultiWriter(serverLogStream, tailBuf)
+	sandboxConfig := sandbox.ConfigurationLlamaCpp
+	if config != nil {
+		sandboxConfig = sandbox.AllowReadPaths(sandboxConfig, config.Mounts)
+	}
 	llamaCppSandbox, err := sandbox.Create(
 		ctx,
-		sandbox.ConfigurationLlamaCpp,
+		sandboxConfig,
 		func(command *exec.Cmd) {
 			command.Cancel = func() error {
 				if runtime.GOOS == "windows" {
@@ -196,8 +285,11 @@ func (l *llamaCpp) Run(ctx context.Context, socket, model string, _ string, mode
 				}
 				return command.Process.Signal(os.Interrupt)
 			}
-			command.Stdout = serverLogStream
-			command.Stderr = out
+			command.Stdout = config.OutputWriter(serverLogStream)
+			command.Stderr = config.OutputWriter(out)
+			if env := config.Environ(); len(env) > 0 {
+				command.Env = append(os.Environ(), env...)
+			}
 		},
 		binPath,
 		filepath.Join(binPath, "com.docker.llama-server"),
@@ -210,7 +302,8 @@ func (l *llamaCpp) Run(ctx context.Context, socket, model string, _ string, mode
 
 	llamaCppErrors := make(chan error, 1)
 	go func() {
-		llamaCppErr := llamaCppSandbox.Command().Wait()
+		command := llamaCppSandbox.Command()
+		llamaCppErr := command.Wait()
 		serverLogStream.Close()
 
 		errOutput := new(strings.Builder)
@@ -218,11 +311,16 @@ func (l *llamaCpp) Run(ctx context.Context, socket, model string, _ string, mode
 			l.log.Warnf("failed to read server output tail: %w", err)
 		}
 
-		if len(errOutput.String()) != 0 {
-			llamaCppErr = fmt.Errorf("llama.cpp exit status: %w\nwith output: %s", llamaCppErr, errOutput.String())
-		} else {
-			llamaCppErr = fmt.Errorf("llama.cpp exit status: %w", llamaCppErr)
+		exitCode := -1
+		if command.ProcessState != nil {
+			exitCode = command.ProcessState.ExitCode()
 		}
+		llamaCppErr = fmt.Errorf("llama.cpp exit status: %w", &inference.ErrProcessExited{
+			Args:     command.Args,
+			ExitCode: exitCode,
+			Output:   errOutput.String(),
+			Err:      llamaCppErr,
+		})
 
 		llamaCppErrors <- llamaCppErr
 		close(llamaCppErrors)
@@ -251,6 +349,33 @@ func (l *llamaCpp) Status() string {
 	return l.status
 }
 
+// Version implements inference.VersionReporter.Version.
+func (l *llamaCpp) Version() string {
+	return l.version
+}
+
+// Accelerator implements inference.VersionReporter.Accelerator.
+func (l *llamaCpp) Accelerator() string {
+	switch {
+	case l.version == "":
+		return ""
+	case !l.gpuSupported:
+		return VariantCPU
+	case runtime.GOOS == "darwin":
+		return VariantMetal
+	case l.binPath() != l.storagePath():
+		return VariantROCm
+	}
+	if variant := GetDesiredServerVariant(); variant != "" {
+		return variant
+	}
+	// An updated server may have replaced a bundled CPU variant.
+	if variant := getBundledVariant(l.vendoredServerStoragePath); variant != "" && variant != VariantCPU {
+		return variant
+	}
+	return "gpu"
+}
+
 func (l *llamaCpp) GetDiskUsage() (int64, error) {
 	size, err := diskusage.Size(l.updatedServerStoragePath)
 	if err != nil {
@@ -260,38 +385,11 @@ func (l *llamaCpp) GetDiskUsage() (int64, error) {
 }
 
 func (l *llamaCpp) GetRequiredMemoryForModel(ctx context.Context, model string, config *inference.BackendConfiguration) (inference.RequiredMemory, error) {
-	mdlGguf, mdlConfig, err := l.parseModel(ctx, model)
+	estimate, err := l.EstimateMemory(ctx, model, config)
 	if err != nil {
-		return inference.RequiredMemory{}, &inference.ErrGGUFParse{Err: err}
-	}
-
-	contextSize := GetContextSize(mdlConfig, config)
-
-	ngl := uint64(0)
-	if l.gpuSupported {
-		if runtime.GOOS == "windows" && runtime.GOARCH == "arm64" && mdlConfig.Quantization != "Q4_0" {
-			ngl = 0 // only Q4_0 models can be accelerated on Adreno
-		}
-		ngl = 999
-	}
-
-	memory := l.estimateMemoryFromGGUF(mdlGguf, contextSize, ngl)
-
-	if config != nil && config.Speculative != nil && config.Speculative.DraftModel != "" {
-		draftGguf, _, err := l.parseModel(ctx, config.Speculative.DraftModel)
-		if err != nil {
-			return inference.RequiredMemory{}, fmt.Errorf("estimating draft model memory: %w", &inference.ErrGGUFParse{Err: err})
-		}
-		draftMemory := l.estimateMemoryFromGGUF(draftGguf, contextSize, ngl)
-		memory.RAM += draftMemory.RAM
-		memory.VRAM += draftMemory.VRAM
+		return inference.RequiredMemory{}, err
 	}
-
-	if runtime.GOOS == "windows" && runtime.GOARCH == "arm64" {
-		memory.VRAM = 1
-	}
-
-	return memory, nil
+	return estimate.Required, nil
 }
 
 // parseModel parses a model (local or remote) and returns the GGUF file and config.
@@ -306,13 +404,49 @@ func (l *llamaCpp) parseModel(ctx context.Context, model string) (*parser.GGUFFi
 	return l.parseRemoteModel(ctx, model)
 }
 
-// estimateMemoryFromGGUF estimates memory requirements from a parsed GGUF file.
-func (l *llamaCpp) estimateMemoryFromGGUF(ggufFile *parser.GGUFFile, contextSize uint64, ngl uint64) inference.RequiredMemory {
-	estimate := ggufFile.EstimateLLaMACppRun(
+// kvCacheTypes maps the supported KV cache types to their GGML types.
+var kvCacheTypes = map[string]parser.GGMLType{
+	"f32":    parser.GGMLTypeF32,
+	"f16":    parser.GGMLTypeF16,
+	"bf16":   parser.GGMLTypeBF16,
+	"q8_0":   parser.GGMLTypeQ8_0,
+	"q4_0":   parser.GGMLTypeQ4_0,
+	"q4_1":   parser.GGMLTypeQ4_1,
+	"iq4_nl": parser.GGMLTypeIQ4_NL,
+	"q5_0":   parser.GGMLTypeQ5_0,
+	"q5_1":   parser.GGMLTypeQ5_1,
+}
+
+// estimateRun estimates running a parsed GGUF file with ngl layers offloaded,
+// using the run parameters, KV cache types and placement of the backend
+// configuration.
+func estimateRun(ggufFile *parser.GGUFFile, contextSize uint64, ngl uint64, config *inference.BackendConfiguration) parser.LLaMACppRunEstimate {
+	params := getRunParameters(config)
+	options := []parser.GGUFRunEstimateOption{
 		parser.WithLLaMACppContextSize(int32(contextSize)),
-		parser.WithLLaMACppLogicalBatchSize(2048),
+		parser.WithLLaMACppLogicalBatchSize(int32(params.batchSize)),
+		parser.WithLLaMACppPhysicalBatchSize(int32(params.microBatchSize)),
 		parser.WithLLaMACppOffloadLayers(ngl),
-	)
+	}
+	if params.flashAttention {
+		options = append(options, parser.WithFlashAttention())
+	}
+	if config != nil && config.KVCache != nil {
+		if typ, ok := kvCacheTypes[config.KVCache.TypeK]; ok {
+			options = append(options, parser.WithLLaMACppCacheKeyType(typ))
+		}
+		if typ, ok := kvCacheTypes[config.KVCache.TypeV]; ok {
+			options = append(options, parser.WithLLaMACppCacheValueType(typ))
+		}
+		if config.KVCache.NoOffload {
+			options = append(options, parser.WithoutLLaMACppOffloadKVCache())
+		}
+	}
+	return ggufFile.EstimateLLaMACppRun(options...)
+}
+
+// requiredMemory returns the RAM and VRAM required by a run estimate.
+func requiredMemory(estimate parser.LLaMACppRunEstimate) inference.RequiredMemory {
 	ram := uint64(estimate.Devices[0].Weight.Sum() + estimate.Devices[0].KVCache.Sum() + estimate.Devices[0].Computation.Sum())
 	var vram uint64
 	if len(estimate.Devices) > 1 {
@@ -393,10 +527,7 @@ func getGGUFLayers(layers []v1.Layer) []v1.Layer {
 }
 
 func (l *llamaCpp) checkGPUSupport(ctx context.Context) bool {
-	binPath := l.vendoredServerStoragePath
-	if l.updatedLlamaCpp {
-		binPath = l.updatedServerStoragePath
-	}
+	binPath := l.binPath()
 	var output bytes.Buffer
 	llamaCppSandbox, err := sandbox.Create(
 		ctx,
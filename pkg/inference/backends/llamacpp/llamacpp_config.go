@@ -3,12 +3,19 @@ package llamacpp
 import (
 	"fmt"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/docker/model-runner/pkg/distribution/types"
 	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/internal/ggufshard"
 )
 
+// embeddingOnlyArgs are llama-server flags that start the server in embedding
+// mode, in which it refuses to serve chat and text completions.
+var embeddingOnlyArgs = []string{"--embedding", "--embeddings", "--rerank", "--reranking"}
+
 // Config is the configuration for the llama.cpp backend.
 type Config struct {
 	// Args are the base arguments that are always included.
@@ -44,10 +51,16 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 	// Start with the arguments from LlamaCppConfig
 	args := append([]string{}, c.Args...)
 
+	// The bundle's GGUF file is a link to the store's blob, never a copy, so
+	// llama.cpp mmaps the blob directly unless --no-mmap is set.
 	modelPath := bundle.GGUFPath()
 	if modelPath == "" {
 		return nil, fmt.Errorf("GGUF file required by llama.cpp backend")
 	}
+	modelPath, err := firstGGUFShard(modelPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Add model and socket arguments
 	args = append(args, "--model", modelPath, "--host", socket)
@@ -61,6 +74,8 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 		}
 	case inference.BackendModeEmbedding:
 		args = append(args, "--embeddings")
+	case inference.BackendModeReranking:
+		args = append(args, "--reranking")
 	default:
 		return nil, fmt.Errorf("unsupported backend mode %q", mode)
 	}
@@ -68,9 +83,63 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 	// Add context size from model config or backend config
 	args = append(args, "--ctx-size", strconv.FormatUint(GetContextSize(bundle.RuntimeConfig(), config), 10))
 
-	// Add arguments from backend config
+	// Add the number of parallel slots and the batch size, if configured
+	if config != nil && config.ParallelSlots > 0 {
+		args = append(args, "--parallel", strconv.Itoa(config.ParallelSlots))
+	}
+	if config != nil && config.BatchSize > 0 {
+		args = append(args, "--batch-size", strconv.Itoa(config.BatchSize))
+	}
+
+	// Add KV cache types and placement
+	if config != nil && config.KVCache != nil {
+		if config.KVCache.TypeK != "" {
+			args = append(args, "--cache-type-k", config.KVCache.TypeK)
+		}
+		if config.KVCache.TypeV != "" {
+			args = append(args, "--cache-type-v", config.KVCache.TypeV)
+		}
+		if config.KVCache.NoOffload {
+			args = append(args, "--no-kv-offload")
+		}
+	}
+
+	// Add the proportions in which to split the model across GPUs. The GPUs
+	// themselves are selected through the environment.
+	if config != nil && len(config.TensorSplit) > 0 {
+		proportions := make([]string, len(config.TensorSplit))
+		for i, proportion := range config.TensorSplit {
+			proportions[i] = strconv.FormatFloat(proportion, 'g', -1, 64)
+		}
+		args = append(args, "--tensor-split", strings.Join(proportions, ","))
+	}
+
+	// Let llama-server save and restore the prompt caches of its slots
+	if mode == inference.BackendModeCompletion && config != nil && config.PromptCacheDir != "" {
+		args = append(args, "--slot-save-path", config.PromptCacheDir)
+	}
+
+	// Offload only the layers that fit in VRAM, if the scheduler limited them
+	if config != nil && config.GPULayers != nil {
+		args = append(args, "--n-gpu-layers", strconv.FormatUint(*config.GPULayers, 10))
+	}
+
+	// Add arguments from model config, then from backend config, so that the
+	// latter take precedence
+	runtimeFlags := slices.Clone(bundle.RuntimeConfig().RuntimeFlags)
 	if config != nil {
-		args = append(args, config.RuntimeFlags...)
+		runtimeFlags = append(runtimeFlags, config.RuntimeFlags...)
+	}
+	if mode == inference.BackendModeCompletion {
+		runtimeFlags = slices.DeleteFunc(runtimeFlags, func(arg string) bool {
+			return containsArg(embeddingOnlyArgs, arg)
+		})
+	}
+	args = append(args, runtimeFlags...)
+
+	// Add LoRA adapters packaged with the model
+	for _, path := range bundle.LoRAAdapterPaths() {
+		args = append(args, "--lora", path)
 	}
 
 	// Add arguments for Multimodal projector or jinja (they are mutually exclusive)
@@ -83,6 +152,18 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 	return args, nil
 }
 
+// firstGGUFShard returns the path of the first shard of a split GGUF model,
+// which llama.cpp uses to locate the remaining shards. All shards must be
+// present alongside it. Paths that don't follow the split naming scheme are
+// returned unchanged.
+func firstGGUFShard(path string) (string, error) {
+	shards, err := ggufshard.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	return shards[0], nil
+}
+
 func GetContextSize(modelCfg types.Config, backendCfg *inference.BackendConfiguration) uint64 {
 	// Model config takes precedence
 	if modelCfg.ContextSize != nil {
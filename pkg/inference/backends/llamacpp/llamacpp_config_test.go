@@ -1,9 +1,12 @@
 package llamacpp
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/docker/model-runner/pkg/distribution/types"
@@ -127,6 +130,20 @@ func TestGetArgs(t *testing.T) {
 				"--jinja",
 			),
 		},
+		{
+			name: "reranking mode",
+			mode: inference.BackendModeReranking,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--reranking",
+				"--ctx-size", "4096",
+				"--jinja",
+			),
+		},
 		{
 			name: "context size from backend config",
 			mode: inference.BackendModeEmbedding,
@@ -144,6 +161,25 @@ func TestGetArgs(t *testing.T) {
 				"--jinja",
 			),
 		},
+		{
+			name: "parallel slots and batch size from backend config",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				ParallelSlots: 4,
+				BatchSize:     1024,
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--parallel", "4",
+				"--batch-size", "1024",
+				"--jinja",
+			),
+		},
 		{
 			name: "context size from model config",
 			mode: inference.BackendModeEmbedding,
@@ -197,6 +233,132 @@ func TestGetArgs(t *testing.T) {
 				"--jinja",
 			),
 		},
+		{
+			name: "completion mode drops embedding-only flags",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				RuntimeFlags: []string{"--embeddings", "--some", "flag", "--reranking"},
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--some", "flag",
+				"--jinja",
+			),
+		},
+		{
+			name: "KV cache types and placement",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				KVCache: &inference.KVCacheConfig{TypeK: "q8_0", TypeV: "q4_0", NoOffload: true},
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--cache-type-k", "q8_0",
+				"--cache-type-v", "q4_0",
+				"--no-kv-offload",
+				"--jinja",
+			),
+		},
+		{
+			name: "tensor split",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				GPUs:        []int{0, 1},
+				TensorSplit: []float64{3, 1.5},
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--tensor-split", "3,1.5",
+				"--jinja",
+			),
+		},
+		{
+			name: "runtime flags from model config precede backend config",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+				config: types.Config{
+					RuntimeFlags: []string{"--flash-attn", "on", "--parallel", "4"},
+				},
+			},
+			config: &inference.BackendConfiguration{
+				RuntimeFlags: []string{"--parallel", "2"},
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--flash-attn", "on",
+				"--parallel", "4",
+				"--parallel", "2",
+				"--jinja",
+			),
+		},
+		{
+			name: "GPU layers limited by scheduler",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				GPULayers: uint64ptr(12),
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--n-gpu-layers", "12",
+				"--jinja",
+			),
+		},
+		{
+			name: "prompt cache directory set by scheduler",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath: modelPath,
+			},
+			config: &inference.BackendConfiguration{
+				PromptCacheDir: "/tmp/prompt-cache",
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--slot-save-path", "/tmp/prompt-cache",
+				"--jinja",
+			),
+		},
+		{
+			name: "LoRA adapters",
+			mode: inference.BackendModeCompletion,
+			bundle: &fakeBundle{
+				ggufPath:     modelPath,
+				loraAdapters: []string{"/path/to/adapter-00001.lora", "/path/to/adapter-00002.lora"},
+			},
+			expected: append(slices.Clone(baseArgs),
+				"--model", modelPath,
+				"--host", socket,
+				"--ctx-size", "4096",
+				"--lora", "/path/to/adapter-00001.lora",
+				"--lora", "/path/to/adapter-00002.lora",
+				"--jinja",
+			),
+		},
 		{
 			name: "multimodal projector removes jinja",
 			mode: inference.BackendModeCompletion,
@@ -251,6 +413,36 @@ func TestGetArgs(t *testing.T) {
 	}
 }
 
+func TestFirstGGUFShard(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "model-00001-of-00002.gguf")
+	second := filepath.Join(dir, "model-00002-of-00002.gguf")
+	for _, p := range []string{first, second} {
+		if err := os.WriteFile(p, []byte("gguf"), 0644); err != nil {
+			t.Fatalf("Failed to write shard: %v", err)
+		}
+	}
+
+	got, err := firstGGUFShard(second)
+	if err != nil {
+		t.Fatalf("firstGGUFShard() error = %v", err)
+	}
+	if got != first {
+		t.Errorf("firstGGUFShard() = %q, want %q", got, first)
+	}
+
+	if got, err := firstGGUFShard("/path/to/model"); err != nil || got != "/path/to/model" {
+		t.Errorf("firstGGUFShard() = %q, %v; want unchanged path", got, err)
+	}
+
+	if err := os.Remove(second); err != nil {
+		t.Fatalf("Failed to remove shard: %v", err)
+	}
+	if _, err := firstGGUFShard(first); err == nil || !strings.Contains(err.Error(), "model-00002-of-00002.gguf") {
+		t.Errorf("Expected missing shard error, got %v", err)
+	}
+}
+
 func TestContainsArg(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -295,6 +487,7 @@ type fakeBundle struct {
 	config       types.Config
 	templatePath string
 	mmprojPath   string
+	loraAdapters []string
 }
 
 func (f *fakeBundle) ChatTemplatePath() string {
@@ -313,6 +506,26 @@ func (f *fakeBundle) MMPROJPath() string {
 	return f.mmprojPath
 }
 
+func (f *fakeBundle) LoRAAdapterPaths() []string {
+	return f.loraAdapters
+}
+
+func (f *fakeBundle) WhisperPath() string {
+	return ""
+}
+
+func (f *fakeBundle) VoicePath() string {
+	return ""
+}
+
+func (f *fakeBundle) DiffusionPath() string {
+	return ""
+}
+
+func (f *fakeBundle) ONNXPath() string {
+	return ""
+}
+
 func (f *fakeBundle) SafetensorsPath() string {
 	return ""
 }
@@ -0,0 +1,35 @@
+package llamacpp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinPathROCmVariant(t *testing.T) {
+	storagePath := t.TempDir()
+	l := &llamaCpp{vendoredServerStoragePath: storagePath}
+
+	// Without the ROCm variant, the default binary is used even with AMD GPUs.
+	l.useROCm = true
+	if got := l.binPath(); got != storagePath {
+		t.Errorf("binPath() = %q, want %q", got, storagePath)
+	}
+
+	rocmPath := filepath.Join(storagePath, rocmVariantDir)
+	if err := os.MkdirAll(rocmPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rocmPath, "com.docker.llama-server"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.binPath(); got != rocmPath {
+		t.Errorf("binPath() = %q, want %q", got, rocmPath)
+	}
+
+	// Without AMD GPUs, the ROCm variant isn't used.
+	l.useROCm = false
+	if got := l.binPath(); got != storagePath {
+		t.Errorf("binPath() = %q, want %q", got, storagePath)
+	}
+}
@@ -0,0 +1,91 @@
+package llamacpp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// loraAdapter is a LoRA adapter of llama-server's lora-adapters API.
+type loraAdapter struct {
+	ID    int     `json:"id"`
+	Path  string  `json:"path,omitempty"`
+	Scale float64 `json:"scale"`
+}
+
+// ScaleLoRAAdapters implements inference.LoRAScaler.ScaleLoRAAdapters.
+func (l *llamaCpp) ScaleLoRAAdapters(ctx context.Context, client *http.Client, config *inference.BackendConfiguration) error {
+	scales := make(map[string]float64)
+	for _, adapter := range config.LoRAAdapters {
+		bundle, release, err := l.modelManager.AcquireBundle(adapter.Model)
+		if err != nil {
+			return fmt.Errorf("failed to get LoRA adapter model: %w", err)
+		}
+		for _, path := range bundle.LoRAAdapterPaths() {
+			scales[path] = adapter.AdapterScale()
+		}
+		release()
+	}
+	return scaleLoRAAdapters(ctx, client, scales)
+}
+
+// scaleLoRAAdapters sets the scales of the LoRA adapters llama-server was
+// started with, by path. llama-server disables the adapters a request leaves
+// out, so the others keep their current scales.
+func scaleLoRAAdapters(ctx context.Context, client *http.Client, scales map[string]float64) error {
+	var adapters []loraAdapter
+	if err := loraAdaptersRequest(ctx, client, http.MethodGet, nil, &adapters); err != nil {
+		return err
+	}
+	loaded := make(map[string]bool)
+	for i, adapter := range adapters {
+		if scale, ok := scales[adapter.Path]; ok {
+			adapters[i].Scale = scale
+			loaded[adapter.Path] = true
+		}
+		adapters[i].Path = ""
+	}
+	// Adapters can't be added to a running llama-server.
+	for _, path := range slices.Sorted(maps.Keys(scales)) {
+		if !loaded[path] {
+			return fmt.Errorf("LoRA adapter %s isn't loaded", path)
+		}
+	}
+	body, err := json.Marshal(adapters)
+	if err != nil {
+		return err
+	}
+	return loraAdaptersRequest(ctx, client, http.MethodPost, body, nil)
+}
+
+// loraAdaptersRequest performs a request of llama-server's lora-adapters API,
+// decoding its response into response if it isn't nil.
+func loraAdaptersRequest(ctx context.Context, client *http.Client, method string, body []byte, response any) error {
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost/lora-adapters", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting LoRA adapters: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("LoRA adapters request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return fmt.Errorf("decoding LoRA adapters: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package llamacpp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScaleLoRAAdapters(t *testing.T) {
+	var posted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lora-adapters" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			b, _ := io.ReadAll(r.Body)
+			posted = string(b)
+			return
+		}
+		w.Write([]byte(`[{"id":0,"path":"/models/packaged.lora","scale":1},{"id":1,"path":"/models/adapter.lora","scale":1}]`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", server.Listener.Addr().String())
+		},
+	}}
+
+	// Adapters that aren't rescaled keep their scales, since llama-server
+	// disables the adapters a request leaves out.
+	if err := scaleLoRAAdapters(context.Background(), client, map[string]float64{"/models/adapter.lora": 0.25}); err != nil {
+		t.Fatalf("scaleLoRAAdapters failed: %v", err)
+	}
+	if posted != `[{"id":0,"scale":1},{"id":1,"scale":0.25}]` {
+		t.Errorf("Unexpected LoRA adapters request %s", posted)
+	}
+
+	// Adapters can't be added to a running server.
+	posted = ""
+	if err := scaleLoRAAdapters(context.Background(), client, map[string]float64{"/models/other.lora": 1}); err == nil {
+		t.Error("Expected an error for an adapter that isn't loaded")
+	}
+	if posted != "" {
+		t.Errorf("Expected no scales to be set, got %s", posted)
+	}
+}
@@ -0,0 +1,60 @@
+package llamacpp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// CachePrompt implements inference.PromptCacher. It sets llama-server's
+// cache_prompt request field, which older llama-server versions don't enable
+// by default, unless the request sets it itself.
+func (l *llamaCpp) CachePrompt(body []byte) ([]byte, error) {
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("decoding request: %w", err)
+	}
+	if _, ok := request["cache_prompt"]; ok {
+		return body, nil
+	}
+	request["cache_prompt"] = json.RawMessage("true")
+	return json.Marshal(request)
+}
+
+// SavePromptCache implements inference.PromptCacheSaver.SavePromptCache.
+func (l *llamaCpp) SavePromptCache(ctx context.Context, client *http.Client, slot int, name string) error {
+	return slotAction(ctx, client, slot, "save", name)
+}
+
+// RestorePromptCache implements inference.PromptCacheSaver.RestorePromptCache.
+func (l *llamaCpp) RestorePromptCache(ctx context.Context, client *http.Client, slot int, name string) error {
+	return slotAction(ctx, client, slot, "restore", name)
+}
+
+// slotAction performs a save or restore action of llama-server's slots API,
+// which reads and writes files in the directory set with --slot-save-path.
+func slotAction(ctx context.Context, client *http.Client, slot int, action, filename string) error {
+	body, err := json.Marshal(map[string]string{"filename": filename})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://localhost/slots/%d?action=%s", slot, action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting slot %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("slot %s failed with status %d: %s", action, resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCachePrompt(t *testing.T) {
+	l := &llamaCpp{}
+	cached, err := l.CachePrompt([]byte(`{"model": "ai/model", "messages": []}`))
+	if err != nil {
+		t.Fatalf("CachePrompt failed: %v", err)
+	}
+	var got, expected map[string]any
+	if err := json.Unmarshal(cached, &got); err != nil {
+		t.Fatalf("Cached request isn't JSON: %v", err)
+	}
+	json.Unmarshal([]byte(`{"model": "ai/model", "messages": [], "cache_prompt": true}`), &expected)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("CachePrompt() = %v, want %v", got, expected)
+	}
+
+	// Requests that set cache_prompt themselves are left unchanged.
+	body := []byte(`{"model": "ai/model", "cache_prompt": false}`)
+	if cached, err := l.CachePrompt(body); err != nil || string(cached) != string(body) {
+		t.Errorf("CachePrompt() = %s, %v, want the request unchanged", cached, err)
+	}
+
+	if _, err := l.CachePrompt([]byte(`[]`)); err == nil {
+		t.Error("Expected an error for a request that isn't an object")
+	}
+}
+
+func TestSavePromptCache(t *testing.T) {
+	var path, action, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, action = r.URL.Path, r.URL.Query().Get("action")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if path == "/slots/9" {
+			http.Error(w, "Invalid slot ID", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	// Dial the test server whatever the host, like the runner's client dials
+	// its socket.
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", server.Listener.Addr().String())
+		},
+	}}
+
+	l := &llamaCpp{}
+	if err := l.SavePromptCache(context.Background(), client, 1, "system.bin"); err != nil {
+		t.Fatalf("SavePromptCache failed: %v", err)
+	}
+	if path != "/slots/1" || action != "save" || body != `{"filename":"system.bin"}` {
+		t.Errorf("Unexpected slot request %s?action=%s with %s", path, action, body)
+	}
+	if err := l.RestorePromptCache(context.Background(), client, 0, "system.bin"); err != nil {
+		t.Fatalf("RestorePromptCache failed: %v", err)
+	}
+	if path != "/slots/0" || action != "restore" {
+		t.Errorf("Unexpected slot request %s?action=%s", path, action)
+	}
+	if err := l.SavePromptCache(context.Background(), client, 9, "system.bin"); err == nil {
+		t.Error("Expected an error for an invalid slot")
+	}
+}
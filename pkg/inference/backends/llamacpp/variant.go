@@ -0,0 +1,72 @@
+package llamacpp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Variants of com.docker.llama-server, each built for a kind of accelerator.
+const (
+	// VariantCPU runs on the CPU, using AVX2 where available.
+	VariantCPU = "cpu"
+	// VariantCUDA runs on NVIDIA GPUs.
+	VariantCUDA = "cuda"
+	// VariantROCm runs on AMD GPUs.
+	VariantROCm = "rocm"
+	// VariantMetal runs on Apple silicon GPUs.
+	VariantMetal = "metal"
+	// VariantCANN runs on Ascend NPUs.
+	VariantCANN = "cann"
+	// VariantMUSA runs on Moore Threads GPUs.
+	VariantMUSA = "musa"
+	// VariantOpenCL runs on Adreno GPUs of Windows on Arm systems.
+	VariantOpenCL = "opencl"
+)
+
+// Variants are the supported variants of com.docker.llama-server.
+var Variants = []string{VariantCPU, VariantCUDA, VariantROCm, VariantMetal, VariantCANN, VariantMUSA, VariantOpenCL}
+
+// bundledVariantFile is the name of the file recording the variant of a
+// bundled com.docker.llama-server.
+const bundledVariantFile = "com.docker.llama-server.variant"
+
+var (
+	// desiredServerVariant is the variant pinned with SetDesiredServerVariant.
+	// The variant is detected from the hardware if it's empty.
+	desiredServerVariant     string
+	desiredServerVariantLock sync.Mutex
+)
+
+// GetDesiredServerVariant returns the pinned variant of com.docker.llama-server,
+// or an empty string if the variant is detected from the hardware.
+func GetDesiredServerVariant() string {
+	desiredServerVariantLock.Lock()
+	defer desiredServerVariantLock.Unlock()
+	return desiredServerVariant
+}
+
+// SetDesiredServerVariant pins the variant of com.docker.llama-server to
+// download, instead of detecting it from the hardware.
+func SetDesiredServerVariant(variant string) error {
+	if !slices.Contains(Variants, variant) {
+		return fmt.Errorf("invalid llama.cpp variant %q: must be one of %s", variant, strings.Join(Variants, ", "))
+	}
+	desiredServerVariantLock.Lock()
+	defer desiredServerVariantLock.Unlock()
+	desiredServerVariant = variant
+	return nil
+}
+
+// getBundledVariant returns the variant of the bundled com.docker.llama-server,
+// or an empty string if it's unknown.
+func getBundledVariant(vendoredServerStoragePath string) string {
+	data, err := os.ReadFile(filepath.Join(vendoredServerStoragePath, bundledVariantFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
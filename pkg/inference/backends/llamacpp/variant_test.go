@@ -0,0 +1,44 @@
+package llamacpp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDesiredServerVariant(t *testing.T) {
+	t.Cleanup(func() {
+		desiredServerVariantLock.Lock()
+		desiredServerVariant = ""
+		desiredServerVariantLock.Unlock()
+	})
+
+	if got := GetDesiredServerVariant(); got != "" {
+		t.Errorf("Expected no pinned variant by default, got %q", got)
+	}
+	if err := SetDesiredServerVariant(VariantROCm); err != nil {
+		t.Fatalf("SetDesiredServerVariant() error = %v", err)
+	}
+	if got := GetDesiredServerVariant(); got != VariantROCm {
+		t.Errorf("GetDesiredServerVariant() = %q, want %q", got, VariantROCm)
+	}
+	if err := SetDesiredServerVariant("avx512"); err == nil {
+		t.Error("Expected an error for an unsupported variant")
+	}
+	if got := GetDesiredServerVariant(); got != VariantROCm {
+		t.Errorf("Expected an unsupported variant to be ignored, got %q", got)
+	}
+}
+
+func TestGetBundledVariant(t *testing.T) {
+	storagePath := t.TempDir()
+	if got := getBundledVariant(storagePath); got != "" {
+		t.Errorf("Expected an unknown bundled variant, got %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(storagePath, bundledVariantFile), []byte("cuda\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := getBundledVariant(storagePath); got != VariantCUDA {
+		t.Errorf("getBundledVariant() = %q, want %q", got, VariantCUDA)
+	}
+}
@@ -0,0 +1,613 @@
+package mock
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// created is the fixed creation timestamp reported in all responses so that
+// output is fully deterministic.
+const created = 1700000000
+
+// usage mirrors the OpenAI usage object.
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// completionRequest captures the request fields the mock backend cares about
+// for both chat and text completions.
+type completionRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Prompt   any    `json:"prompt"`
+	Messages []struct {
+		Content any `json:"content"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+	ToolChoice any `json:"tool_choice"`
+}
+
+// toolCall returns the name of the tool the mock model calls and the
+// arguments it calls it with, or false if the request offers no tools or
+// forbids calling them. The mock calls the tool forced by tool_choice, or
+// else the first offered tool, with the configured response as argument.
+func (h *handler) toolCall(req completionRequest) (string, string, bool) {
+	if len(req.Tools) == 0 || req.ToolChoice == "none" {
+		return "", "", false
+	}
+	name := req.Tools[0].Function.Name
+	if choice, ok := req.ToolChoice.(map[string]any); ok {
+		if function, ok := choice["function"].(map[string]any); ok {
+			if forced, ok := function["name"].(string); ok {
+				name = forced
+			}
+		}
+	}
+	arguments, _ := json.Marshal(map[string]string{"text": h.config.response()})
+	return name, string(arguments), true
+}
+
+// embeddingRequest captures the request fields of an embeddings request.
+type embeddingRequest struct {
+	Model string `json:"model"`
+	Input any    `json:"input"`
+}
+
+// rerankRequest captures the request fields of a rerank request.
+type rerankRequest struct {
+	Model     string `json:"model"`
+	Query     string `json:"query"`
+	Documents []any  `json:"documents"`
+	TopN      int    `json:"top_n"`
+}
+
+// newHandler creates the HTTP handler serving canned OpenAI API responses for
+// the given model.
+func newHandler(config *Config, model string) http.Handler {
+	h := &handler{config: config, model: model}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", h.handleModels)
+	mux.HandleFunc("POST /v1/chat/completions", h.handleChatCompletions)
+	mux.HandleFunc("POST /v1/completions", h.handleCompletions)
+	mux.HandleFunc("POST /v1/embeddings", h.handleEmbeddings)
+	mux.HandleFunc("POST /v1/audio/transcriptions", h.handleTranscriptions)
+	mux.HandleFunc("POST /v1/audio/speech", h.handleSpeech)
+	mux.HandleFunc("POST /v1/images/generations", h.handleImageGenerations)
+	mux.HandleFunc("POST /v1/rerank", h.handleRerank)
+	mux.HandleFunc("POST /v1/classify", h.handleClassify)
+	return mux
+}
+
+type handler struct {
+	config *Config
+	model  string
+}
+
+func (h *handler) handleModels(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"object": "list",
+		"data": []map[string]any{{
+			"id":       h.model,
+			"object":   "model",
+			"created":  created,
+			"owned_by": Name,
+		}},
+	})
+}
+
+func (h *handler) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		prompt.WriteString(textOf(m.Content))
+		prompt.WriteByte(' ')
+	}
+	u := h.usage(prompt.String())
+	model := h.modelName(req.Model)
+
+	if name, arguments, ok := h.toolCall(req); ok {
+		h.writeToolCall(w, model, name, arguments, req.Stream, u)
+		return
+	}
+
+	if req.Stream {
+		h.stream(w, func(i int, word string, last bool) any {
+			delta := map[string]any{"content": word}
+			if i == 0 {
+				delta["role"] = "assistant"
+			}
+			var finish any
+			if last {
+				finish = "stop"
+			}
+			return map[string]any{
+				"id":      "chatcmpl-mock",
+				"object":  "chat.completion.chunk",
+				"created": created,
+				"model":   model,
+				"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+			}
+		})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion",
+		"created": created,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": h.config.response()},
+			"finish_reason": "stop",
+		}},
+		"usage": u,
+	})
+}
+
+// writeToolCall writes a chat completion calling the given tool. Streamed tool
+// calls send the id and function name first and the arguments in fragments,
+// as llama.cpp does.
+func (h *handler) writeToolCall(w http.ResponseWriter, model, name, arguments string, stream bool, u usage) {
+	const id = "call_mock"
+	if !stream {
+		writeJSON(w, map[string]any{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{
+				"index": 0,
+				"message": map[string]any{
+					"role":    "assistant",
+					"content": nil,
+					"tool_calls": []map[string]any{{
+						"id":       id,
+						"type":     "function",
+						"function": map[string]any{"name": name, "arguments": arguments},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": u,
+		})
+		return
+	}
+
+	fragments := []string{"", arguments[:len(arguments)/2], arguments[len(arguments)/2:]}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	for i, fragment := range fragments {
+		toolCall := map[string]any{"index": 0, "function": map[string]any{"arguments": fragment}}
+		delta := map[string]any{"tool_calls": []map[string]any{toolCall}}
+		if i == 0 {
+			delta["role"] = "assistant"
+			delta["content"] = nil
+			toolCall["id"] = id
+			toolCall["type"] = "function"
+			toolCall["function"] = map[string]any{"name": name, "arguments": fragment}
+		}
+		var finish any
+		if i == len(fragments)-1 {
+			finish = "tool_calls"
+		}
+		data, err := json.Marshal(map[string]any{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (h *handler) handleCompletions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	u := h.usage(textOf(req.Prompt))
+	model := h.modelName(req.Model)
+
+	if req.Stream {
+		h.stream(w, func(_ int, word string, last bool) any {
+			var finish any
+			if last {
+				finish = "stop"
+			}
+			return map[string]any{
+				"id":      "cmpl-mock",
+				"object":  "text_completion",
+				"created": created,
+				"model":   model,
+				"choices": []map[string]any{{"index": 0, "text": word, "finish_reason": finish}},
+			}
+		})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"id":      "cmpl-mock",
+		"object":  "text_completion",
+		"created": created,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"text":          h.config.response(),
+			"finish_reason": "stop",
+		}},
+		"usage": u,
+	})
+}
+
+func (h *handler) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req embeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	inputs, ok := inputsOf(req.Input)
+	if !ok {
+		http.Error(w, "invalid input", http.StatusBadRequest)
+		return
+	}
+
+	data := make([]map[string]any, 0, len(inputs))
+	promptTokens := 0
+	for i, input := range inputs {
+		data = append(data, map[string]any{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": embed(input, h.config.embeddingDimensions()),
+		})
+		promptTokens += countTokens(input)
+	}
+
+	writeJSON(w, map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  h.modelName(req.Model),
+		"usage":  usage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	})
+}
+
+// handleRerank scores each document by the fraction of the query's words it
+// contains, and returns the documents from most to least relevant.
+func (h *handler) handleRerank(w http.ResponseWriter, r *http.Request) {
+	var req rerankRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	queryWords := strings.Fields(strings.ToLower(req.Query))
+	results := make([]map[string]any, 0, len(req.Documents))
+	promptTokens := 0
+	for i, document := range req.Documents {
+		text := textOf(document)
+		if object, ok := document.(map[string]any); ok {
+			text, _ = object["text"].(string)
+		}
+		words := strings.Fields(strings.ToLower(text))
+		matches := 0
+		for _, word := range queryWords {
+			if slices.Contains(words, word) {
+				matches++
+			}
+		}
+		score := 0.0
+		if len(queryWords) > 0 {
+			score = float64(matches) / float64(len(queryWords))
+		}
+		results = append(results, map[string]any{"index": i, "relevance_score": score})
+		promptTokens += len(queryWords) + len(words)
+	}
+	slices.SortStableFunc(results, func(a, b map[string]any) int {
+		return cmp.Compare(b["relevance_score"].(float64), a["relevance_score"].(float64))
+	})
+	if req.TopN > 0 && req.TopN < len(results) {
+		results = results[:req.TopN]
+	}
+
+	writeJSON(w, map[string]any{
+		"object":  "list",
+		"model":   h.modelName(req.Model),
+		"results": results,
+		"usage":   usage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	})
+}
+
+// handleClassify assigns each input one of two labels, with probabilities
+// derived from its text.
+func (h *handler) handleClassify(w http.ResponseWriter, r *http.Request) {
+	var req embeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	inputs, ok := inputsOf(req.Input)
+	if !ok {
+		http.Error(w, "invalid input", http.StatusBadRequest)
+		return
+	}
+
+	data := make([]map[string]any, 0, len(inputs))
+	promptTokens := 0
+	for i, input := range inputs {
+		sum := sha256.Sum256([]byte(input))
+		positive := float64(binary.BigEndian.Uint32(sum[:4])) / float64(^uint32(0))
+		probs := []float64{1 - positive, positive}
+		label := "LABEL_0"
+		if positive > 0.5 {
+			label = "LABEL_1"
+		}
+		data = append(data, map[string]any{
+			"index":       i,
+			"label":       label,
+			"probs":       probs,
+			"num_classes": len(probs),
+		})
+		promptTokens += countTokens(input)
+	}
+
+	writeJSON(w, map[string]any{
+		"id":      "classify-mock",
+		"object":  "list",
+		"created": created,
+		"model":   h.modelName(req.Model),
+		"data":    data,
+		"usage":   usage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	})
+}
+
+func (h *handler) handleTranscriptions(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if _, _, err := r.FormFile("file"); err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.FormValue("response_format") {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, h.config.response())
+	case "", "json":
+		writeJSON(w, map[string]any{"text": h.config.response()})
+	default:
+		http.Error(w, "unsupported response format", http.StatusBadRequest)
+	}
+}
+
+// speechSampleRate is the sample rate of the audio the mock backend
+// synthesizes, in 16-bit mono.
+const speechSampleRate = 16000
+
+// handleSpeech synthesizes a tenth of a second of silence per word of the
+// input, as a WAV file or raw PCM samples.
+func (h *handler) handleSpeech(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input          string `json:"input"`
+		ResponseFormat string `json:"response_format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	words := len(strings.Fields(req.Input))
+	if words == 0 {
+		http.Error(w, "input is required", http.StatusBadRequest)
+		return
+	}
+	samples := make([]byte, words*speechSampleRate/10*2)
+
+	switch req.ResponseFormat {
+	case "pcm":
+		w.Header().Set("Content-Type", "audio/pcm")
+		w.Write(samples)
+	case "", "wav":
+		w.Header().Set("Content-Type", "audio/wav")
+		header := make([]byte, 44)
+		copy(header[0:], "RIFF")
+		binary.LittleEndian.PutUint32(header[4:], uint32(36+len(samples)))
+		copy(header[8:], "WAVEfmt ")
+		binary.LittleEndian.PutUint32(header[16:], 16)
+		binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+		binary.LittleEndian.PutUint16(header[22:], 1) // mono
+		binary.LittleEndian.PutUint32(header[24:], speechSampleRate)
+		binary.LittleEndian.PutUint32(header[28:], speechSampleRate*2)
+		binary.LittleEndian.PutUint16(header[32:], 2)
+		binary.LittleEndian.PutUint16(header[34:], 16)
+		copy(header[36:], "data")
+		binary.LittleEndian.PutUint32(header[40:], uint32(len(samples)))
+		w.Write(header)
+		w.Write(samples)
+	default:
+		http.Error(w, "unsupported response format", http.StatusBadRequest)
+	}
+}
+
+// handleImageGenerations generates images of a single color derived from the
+// prompt, as base64-encoded PNGs.
+func (h *handler) handleImageGenerations(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt string `json:"prompt"`
+		N      int    `json:"n"`
+		Size   string `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Prompt == "" {
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+	width, height := 256, 256
+	if req.Size != "" {
+		if _, err := fmt.Sscanf(req.Size, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+			http.Error(w, "invalid size", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data := make([]map[string]any, max(req.N, 1))
+	for i := range data {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, req.Prompt)))
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: sum[0], G: sum[1], B: sum[2], A: 255}), image.Point{}, draw.Src)
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, img); err != nil {
+			http.Error(w, "unable to encode image", http.StatusInternalServerError)
+			return
+		}
+		data[i] = map[string]any{
+			"b64_json":       base64.StdEncoding.EncodeToString(encoded.Bytes()),
+			"revised_prompt": req.Prompt,
+		}
+	}
+	writeJSON(w, map[string]any{"created": created, "data": data})
+}
+
+// stream writes the configured response as a server-sent event stream, one
+// word per chunk, followed by the [DONE] sentinel.
+func (h *handler) stream(w http.ResponseWriter, chunk func(i int, word string, last bool) any) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	words := strings.SplitAfter(h.config.response(), " ")
+	for i, word := range words {
+		data, err := json.Marshal(chunk(i, word, i == len(words)-1))
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (h *handler) usage(prompt string) usage {
+	promptTokens := countTokens(prompt)
+	completionTokens := countTokens(h.config.response())
+	return usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+func (h *handler) modelName(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return h.model
+}
+
+// textOf extracts the text from a prompt or message content value, which may
+// be a string, an array of strings, or an array of content parts.
+func textOf(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		var parts []string
+		for _, item := range v {
+			if part, ok := item.(map[string]any); ok {
+				if text, ok := part["text"].(string); ok {
+					parts = append(parts, text)
+				}
+				continue
+			}
+			parts = append(parts, textOf(item))
+		}
+		return strings.Join(parts, " ")
+	default:
+		return ""
+	}
+}
+
+// inputsOf extracts the texts of an embeddings or classification input, which
+// may be a string or an array of prompts.
+func inputsOf(v any) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, true
+	case []any:
+		inputs := make([]string, 0, len(v))
+		for _, item := range v {
+			inputs = append(inputs, textOf(item))
+		}
+		return inputs, true
+	default:
+		return nil, false
+	}
+}
+
+// countTokens approximates a token count by counting whitespace-separated
+// words.
+func countTokens(s string) int {
+	return len(strings.Fields(s))
+}
+
+// embed derives a deterministic unit-range vector from the input text.
+func embed(input string, dimensions int) []float64 {
+	vector := make([]float64, dimensions)
+	for i := range vector {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, input)))
+		v := binary.BigEndian.Uint32(sum[:4])
+		vector[i] = float64(v)/float64(^uint32(0))*2 - 1
+	}
+	return vector
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
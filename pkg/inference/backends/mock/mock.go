@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+const (
+	// Name is the backend name.
+	Name = "mock"
+)
+
+// mock is a backend implementation that serves deterministic canned responses
+// without loading any model weights. It's intended for development and CI
+// environments where real inference engines aren't available.
+type mock struct {
+	// log is the associated logger.
+	log logging.Logger
+	// config is the configuration for the mock backend.
+	config *Config
+}
+
+// New creates a new mock backend.
+func New(log logging.Logger, conf *Config) (inference.Backend, error) {
+	// If no config is provided, use the default configuration
+	if conf == nil {
+		conf = NewDefaultConfig()
+	}
+
+	return &mock{
+		log:    log,
+		config: conf,
+	}, nil
+}
+
+// Name implements inference.Backend.Name.
+func (m *mock) Name() string {
+	return Name
+}
+
+// UsesExternalModelManagement implements
+// inference.Backend.UsesExternalModelManagement. The mock backend accepts any
+// model name, so it doesn't require models to be present in the store.
+func (m *mock) UsesExternalModelManagement() bool {
+	return true
+}
+
+// Install implements inference.Backend.Install.
+func (m *mock) Install(_ context.Context, _ *http.Client) error {
+	return nil
+}
+
+// Run implements inference.Backend.Run.
+func (m *mock) Run(ctx context.Context, socket, model string, _ string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	if err := os.RemoveAll(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		m.log.Warnf("failed to remove socket file %s: %v", socket, err)
+	}
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("unable to listen on socket: %w", err)
+	}
+
+	m.log.Infof("Serving canned %s responses for model %s", mode, model)
+	// There's no server process, so report its output as one would.
+	fmt.Fprintf(config.OutputWriter(io.Discard), "Serving canned %s responses for model %s\n", mode, model)
+
+	server := &http.Server{Handler: newHandler(m.config, model)}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(ln)
+	}()
+
+	select {
+	case <-ctx.Done():
+		server.Close()
+		<-serverErr
+		return nil
+	case err := <-serverErr:
+		return fmt.Errorf("mock server exited: %w", err)
+	}
+}
+
+// Status implements inference.Backend.Status.
+func (m *mock) Status() string {
+	return "running mock backend"
+}
+
+// GetDiskUsage implements inference.Backend.GetDiskUsage.
+func (m *mock) GetDiskUsage() (int64, error) {
+	return 0, nil
+}
+
+// GetRequiredMemoryForModel implements
+// inference.Backend.GetRequiredMemoryForModel.
+func (m *mock) GetRequiredMemoryForModel(_ context.Context, _ string, _ *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	return inference.RequiredMemory{RAM: 0, VRAM: 0}, nil
+}
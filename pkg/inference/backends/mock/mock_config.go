@@ -0,0 +1,41 @@
+package mock
+
+const (
+	// defaultResponse is the completion text returned when none is configured.
+	defaultResponse = "This is a canned response from the mock backend."
+	// defaultEmbeddingDimensions is the embedding vector length returned when
+	// none is configured.
+	defaultEmbeddingDimensions = 8
+)
+
+// Config is the configuration for the mock backend.
+type Config struct {
+	// Response is the text returned for every chat and text completion.
+	Response string
+	// EmbeddingDimensions is the length of the returned embedding vectors.
+	EmbeddingDimensions int
+}
+
+// NewDefaultConfig creates a new Config with default values.
+func NewDefaultConfig() *Config {
+	return &Config{
+		Response:            defaultResponse,
+		EmbeddingDimensions: defaultEmbeddingDimensions,
+	}
+}
+
+// response returns the configured completion text or the default.
+func (c *Config) response() string {
+	if c.Response == "" {
+		return defaultResponse
+	}
+	return c.Response
+}
+
+// embeddingDimensions returns the configured embedding size or the default.
+func (c *Config) embeddingDimensions() int {
+	if c.EmbeddingDimensions <= 0 {
+		return defaultEmbeddingDimensions
+	}
+	return c.EmbeddingDimensions
+}
@@ -0,0 +1,313 @@
+package mock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatCompletion(t *testing.T) {
+	h := newHandler(&Config{Response: "hello there"}, "ai/test")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"ai/test","messages":[{"role":"user","content":"hi"}]}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage usage `json:"usage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "hello there" {
+		t.Errorf("unexpected choices: %+v", resp.Choices)
+	}
+	if resp.Usage.PromptTokens != 1 || resp.Usage.CompletionTokens != 2 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	h := newHandler(&Config{Response: "one two three"}, "ai/test")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"ai/test","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+
+	var content strings.Builder
+	var done bool
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if line == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			t.Fatalf("failed to decode chunk: %v", err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	if !done {
+		t.Error("stream did not terminate with [DONE]")
+	}
+	if content.String() != "one two three" {
+		t.Errorf("expected streamed content %q, got %q", "one two three", content.String())
+	}
+}
+
+func TestChatCompletionToolCallStream(t *testing.T) {
+	h := newHandler(&Config{Response: "sunny"}, "ai/test")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(
+		`{"model":"ai/test","stream":true,"messages":[{"role":"user","content":"weather?"}],`+
+			`"tools":[{"type":"function","function":{"name":"get_weather"}}]}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var name, arguments, finish string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || line == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					ToolCalls []struct {
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			t.Fatalf("failed to decode chunk: %v", err)
+		}
+		for _, call := range chunk.Choices[0].Delta.ToolCalls {
+			name += call.Function.Name
+			arguments += call.Function.Arguments
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			finish = chunk.Choices[0].FinishReason
+		}
+	}
+	if name != "get_weather" || arguments != `{"text":"sunny"}` {
+		t.Errorf("unexpected tool call %s(%s)", name, arguments)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("expected finish reason tool_calls, got %q", finish)
+	}
+}
+
+func TestEmbeddingsDeterministic(t *testing.T) {
+	h := newHandler(&Config{EmbeddingDimensions: 4}, "ai/embed")
+
+	get := func() []float64 {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings",
+			strings.NewReader(`{"model":"ai/embed","input":["a","b"]}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp struct {
+			Data []struct {
+				Embedding []float64 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Data) != 2 {
+			t.Fatalf("expected 2 embeddings, got %d", len(resp.Data))
+		}
+		return resp.Data[0].Embedding
+	}
+
+	first, second := get(), get()
+	if len(first) != 4 {
+		t.Fatalf("expected 4 dimensions, got %d", len(first))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("embeddings are not deterministic: %v != %v", first, second)
+		}
+	}
+}
+
+func TestRerank(t *testing.T) {
+	h := newHandler(&Config{}, "ai/rerank")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/rerank", strings.NewReader(
+		`{"model":"ai/rerank","query":"red apple","documents":["a banana","a red apple",{"text":"an apple"}],"top_n":2}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	var resp struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Results))
+	}
+	if resp.Results[0].Index != 1 || resp.Results[0].RelevanceScore != 1 || resp.Results[1].Index != 2 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	h := newHandler(&Config{}, "ai/classifier")
+
+	classify := func() []byte {
+		req := httptest.NewRequest(http.MethodPost, "/v1/classify", strings.NewReader(
+			`{"model":"ai/classifier","input":["great product","awful product"]}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		return w.Body.Bytes()
+	}
+	body := classify()
+	var resp struct {
+		Data []struct {
+			Index      int       `json:"index"`
+			Label      string    `json:"label"`
+			Probs      []float64 `json:"probs"`
+			NumClasses int       `json:"num_classes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Data))
+	}
+	for i, d := range resp.Data {
+		if d.Index != i || d.NumClasses != 2 || len(d.Probs) != 2 || d.Probs[0] != 1-d.Probs[1] {
+			t.Errorf("unexpected result %+v", d)
+		}
+	}
+	if !bytes.Equal(body, classify()) {
+		t.Error("expected classification to be deterministic")
+	}
+}
+
+func TestSpeech(t *testing.T) {
+	h := newHandler(&Config{}, "ai/voice")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(
+		`{"model":"ai/voice","input":"hello there","voice":"alloy"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("expected a WAV file with status 200, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	// Two words are synthesized as 0.2s of 16 kHz 16-bit samples.
+	if body := w.Body.Bytes(); len(body) != 44+6400 || string(body[:4]) != "RIFF" || string(body[8:12]) != "WAVE" {
+		t.Errorf("unexpected WAV file of %d bytes", len(body))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(
+		`{"model":"ai/voice","input":"hello","response_format":"mp3"}`))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an unsupported format to be rejected, got %d", w.Code)
+	}
+}
+
+func TestImageGenerations(t *testing.T) {
+	h := newHandler(&Config{}, "ai/sd")
+
+	generate := func() []byte {
+		req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", strings.NewReader(
+			`{"model":"ai/sd","prompt":"a whale","n":2,"size":"64x32"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.Bytes()
+	}
+	body := generate()
+	if !bytes.Equal(body, generate()) {
+		t.Error("expected deterministic images")
+	}
+
+	var resp struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Data) != 2 {
+		t.Fatalf("expected two images, got %s", body)
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Data[0].B64JSON)
+	if err != nil {
+		t.Fatalf("invalid base64 image: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG image: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 64 || bounds.Dy() != 32 {
+		t.Errorf("expected a 64x32 image, got %v", bounds)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", strings.NewReader(`{"model":"ai/sd"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing prompt to be rejected, got %d", w.Code)
+	}
+}
+
+func TestModels(t *testing.T) {
+	h := newHandler(NewDefaultConfig(), "ai/test")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"ai/test"`) {
+		t.Errorf("expected model in listing, got %s", w.Body.String())
+	}
+}
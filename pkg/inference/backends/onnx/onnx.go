@@ -0,0 +1,81 @@
+package onnx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/diskusage"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/bundled"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+const (
+	// Name is the backend name.
+	Name = "onnx"
+	// computeOverhead is the memory ONNX Runtime needs on top of the model
+	// weights for its tokenizer and the activations of a batch of inputs.
+	computeOverhead = 256 * 1024 * 1024
+)
+
+// StatusNotFound indicates that the ONNX Runtime server binary isn't installed.
+var StatusNotFound = errors.New("ONNX Runtime server binary not found")
+
+// onnxRuntime is the ONNX Runtime-based backend implementation.
+type onnxRuntime struct {
+	// Backend runs com.docker.onnx-server.
+	*bundled.Backend
+	// modelManager is the shared model manager.
+	modelManager *models.Manager
+}
+
+// New creates a new ONNX Runtime-based backend.
+func New(
+	log logging.Logger,
+	modelManager *models.Manager,
+	serverLog logging.Logger,
+	serverStoragePath string,
+	conf config.BackendConfig,
+) (inference.Backend, error) {
+	// If no config is provided, use the default configuration
+	if conf == nil {
+		conf = NewDefaultONNXConfig()
+	}
+
+	return &onnxRuntime{
+		Backend: bundled.New(bundled.Server{
+			Name:        Name,
+			DisplayName: "ONNX Runtime",
+			Binary:      "com.docker.onnx-server",
+			NotFound:    StatusNotFound,
+			Config:      conf,
+		}, log, modelManager, serverLog, serverStoragePath),
+		modelManager: modelManager,
+	}, nil
+}
+
+// GetRequiredMemoryForModel implements
+// inference.Backend.GetRequiredMemoryForModel. Embedding and classification
+// models are small, have no KV cache and run on the CPU execution provider, so
+// the estimate is the size of the model plus a fixed overhead, and no VRAM.
+func (o *onnxRuntime) GetRequiredMemoryForModel(_ context.Context, model string, _ *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	mdl, err := o.modelManager.GetModel(model)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting model(%s): %w", model, err)
+	}
+	path, err := mdl.ONNXPath()
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting ONNX file for model(%s): %w", model, err)
+	}
+	size, err := diskusage.Size(path)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting size of model(%s): %w", model, err)
+	}
+	return inference.RequiredMemory{
+		RAM:  uint64(size) + computeOverhead,
+		VRAM: 0,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package onnx
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// tasks maps the modes the ONNX Runtime server runs in to the task it's told
+// to serve, which determines how the outputs of the model are pooled.
+var tasks = map[inference.BackendMode]string{
+	inference.BackendModeEmbedding:      "embedding",
+	inference.BackendModeClassification: "classification",
+}
+
+// Config is the configuration for the ONNX Runtime backend.
+type Config struct {
+	// Args are the base arguments that are always included.
+	Args []string
+}
+
+// NewDefaultONNXConfig creates a new Config with default values.
+func NewDefaultONNXConfig() *Config {
+	return &Config{
+		Args: []string{},
+	}
+}
+
+// GetArgs implements BackendConfig.GetArgs.
+func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference.BackendMode, config *inference.BackendConfiguration) ([]string, error) {
+	// Start with the arguments from Config
+	args := append([]string{}, c.Args...)
+
+	modelPath := bundle.ONNXPath()
+	if modelPath == "" {
+		return nil, fmt.Errorf("ONNX model file required by ONNX Runtime backend")
+	}
+
+	// ONNX Runtime only serves embeddings and classifications
+	task, ok := tasks[mode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported backend mode %q", mode)
+	}
+
+	// Add model, tokenizer, socket and task arguments. The tokenizer is
+	// extracted from the config archive next to the model.
+	args = append(args,
+		"--model", modelPath,
+		"--tokenizer", filepath.Join(filepath.Dir(modelPath), "tokenizer.json"),
+		"--host", socket,
+		"--task", task,
+	)
+
+	// Add arguments from backend config
+	if config != nil {
+		args = append(args, config.RuntimeFlags...)
+	}
+
+	return args, nil
+}
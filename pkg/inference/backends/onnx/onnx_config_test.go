@@ -0,0 +1,76 @@
+package onnx
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+	testutil "github.com/docker/model-runner/pkg/inference/backends/internal/testing"
+)
+
+func TestGetArgs(t *testing.T) {
+	const socket = "/tmp/onnx.sock"
+	tests := []struct {
+		name        string
+		bundle      *testutil.FakeBundle
+		mode        inference.BackendMode
+		config      *inference.BackendConfiguration
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:   "embedding mode",
+			bundle: &testutil.FakeBundle{ONNX: "/path/to/model.onnx"},
+			mode:   inference.BackendModeEmbedding,
+			expected: []string{
+				"--model", "/path/to/model.onnx",
+				"--tokenizer", "/path/to/tokenizer.json",
+				"--host", socket,
+				"--task", "embedding",
+			},
+		},
+		{
+			name:   "classification mode with runtime flags",
+			bundle: &testutil.FakeBundle{ONNX: "/path/to/model.onnx"},
+			mode:   inference.BackendModeClassification,
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--intra-op-threads", "4"}},
+			expected: []string{
+				"--model", "/path/to/model.onnx",
+				"--tokenizer", "/path/to/tokenizer.json",
+				"--host", socket,
+				"--task", "classification",
+				"--intra-op-threads", "4",
+			},
+		},
+		{
+			name:        "missing ONNX file",
+			bundle:      &testutil.FakeBundle{},
+			mode:        inference.BackendModeEmbedding,
+			expectError: true,
+		},
+		{
+			name:        "completion mode",
+			bundle:      &testutil.FakeBundle{ONNX: "/path/to/model.onnx"},
+			mode:        inference.BackendModeCompletion,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewDefaultONNXConfig().GetArgs(tt.bundle, socket, tt.mode, tt.config)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got args %v", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetArgs failed: %v", err)
+			}
+			if !slices.Equal(args, tt.expected) {
+				t.Errorf("GetArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
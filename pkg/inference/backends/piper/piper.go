@@ -0,0 +1,80 @@
+package piper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/diskusage"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/bundled"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+const (
+	// Name is the backend name.
+	Name = "piper"
+	// computeOverhead is the memory piper needs on top of the voice for the
+	// ONNX runtime, its phonemizer and the synthesized audio.
+	computeOverhead = 128 * 1024 * 1024
+)
+
+// StatusNotFound indicates that the piper server binary isn't installed.
+var StatusNotFound = errors.New("piper server binary not found")
+
+// piper is the piper-based backend implementation.
+type piper struct {
+	// Backend runs com.docker.piper-server.
+	*bundled.Backend
+	// modelManager is the shared model manager.
+	modelManager *models.Manager
+}
+
+// New creates a new piper-based backend.
+func New(
+	log logging.Logger,
+	modelManager *models.Manager,
+	serverLog logging.Logger,
+	serverStoragePath string,
+	conf config.BackendConfig,
+) (inference.Backend, error) {
+	// If no config is provided, use the default configuration
+	if conf == nil {
+		conf = NewDefaultPiperConfig()
+	}
+
+	return &piper{
+		Backend: bundled.New(bundled.Server{
+			Name:     Name,
+			Binary:   "com.docker.piper-server",
+			NotFound: StatusNotFound,
+			Config:   conf,
+		}, log, modelManager, serverLog, serverStoragePath),
+		modelManager: modelManager,
+	}, nil
+}
+
+// GetRequiredMemoryForModel implements
+// inference.Backend.GetRequiredMemoryForModel. Piper voices are small, have
+// no context to size and run on the CPU, so the estimate is the size of the
+// voice plus a fixed overhead, and no VRAM.
+func (p *piper) GetRequiredMemoryForModel(_ context.Context, model string, _ *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	mdl, err := p.modelManager.GetModel(model)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting model(%s): %w", model, err)
+	}
+	path, err := mdl.VoicePath()
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting voice file for model(%s): %w", model, err)
+	}
+	size, err := diskusage.Size(path)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting size of model(%s): %w", model, err)
+	}
+	return inference.RequiredMemory{
+		RAM:  uint64(size) + computeOverhead,
+		VRAM: 0,
+	}, nil
+}
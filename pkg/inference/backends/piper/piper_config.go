@@ -0,0 +1,57 @@
+package piper
+
+import (
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// speechPath is the path on which the piper server is told to serve speech
+// synthesis, so that requests can be forwarded to it unchanged.
+const speechPath = "/v1/audio/speech"
+
+// Config is the configuration for the piper backend.
+type Config struct {
+	// Args are the base arguments that are always included.
+	Args []string
+}
+
+// NewDefaultPiperConfig creates a new Config with default values.
+func NewDefaultPiperConfig() *Config {
+	return &Config{
+		Args: []string{},
+	}
+}
+
+// GetArgs implements BackendConfig.GetArgs.
+func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference.BackendMode, config *inference.BackendConfiguration) ([]string, error) {
+	// Start with the arguments from Config
+	args := append([]string{}, c.Args...)
+
+	modelPath := bundle.VoicePath()
+	if modelPath == "" {
+		return nil, fmt.Errorf("voice file required by piper backend")
+	}
+
+	// piper only synthesizes speech
+	if mode != inference.BackendModeSpeech {
+		return nil, fmt.Errorf("unsupported backend mode %q", mode)
+	}
+
+	// Add model, config, socket and endpoint arguments. The config is next to
+	// the voice in the bundle.
+	args = append(args,
+		"--model", modelPath,
+		"--config", modelPath+".json",
+		"--host", socket,
+		"--inference-path", speechPath,
+	)
+
+	// Add arguments from backend config
+	if config != nil {
+		args = append(args, config.RuntimeFlags...)
+	}
+
+	return args, nil
+}
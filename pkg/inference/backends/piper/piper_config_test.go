@@ -0,0 +1,76 @@
+package piper
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+	testutil "github.com/docker/model-runner/pkg/inference/backends/internal/testing"
+)
+
+func TestGetArgs(t *testing.T) {
+	const socket = "/tmp/piper.sock"
+	tests := []struct {
+		name        string
+		bundle      *testutil.FakeBundle
+		mode        inference.BackendMode
+		config      *inference.BackendConfiguration
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:   "speech mode",
+			bundle: &testutil.FakeBundle{Voice: "/path/to/model.onnx"},
+			mode:   inference.BackendModeSpeech,
+			expected: []string{
+				"--model", "/path/to/model.onnx",
+				"--config", "/path/to/model.onnx.json",
+				"--host", socket,
+				"--inference-path", "/v1/audio/speech",
+			},
+		},
+		{
+			name:   "with runtime flags",
+			bundle: &testutil.FakeBundle{Voice: "/path/to/model.onnx"},
+			mode:   inference.BackendModeSpeech,
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--speaker", "3"}},
+			expected: []string{
+				"--model", "/path/to/model.onnx",
+				"--config", "/path/to/model.onnx.json",
+				"--host", socket,
+				"--inference-path", "/v1/audio/speech",
+				"--speaker", "3",
+			},
+		},
+		{
+			name:        "missing voice file",
+			bundle:      &testutil.FakeBundle{},
+			mode:        inference.BackendModeSpeech,
+			expectError: true,
+		},
+		{
+			name:        "transcription mode",
+			bundle:      &testutil.FakeBundle{Voice: "/path/to/model.onnx"},
+			mode:        inference.BackendModeTranscription,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewDefaultPiperConfig().GetArgs(tt.bundle, socket, tt.mode, tt.config)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got args %v", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetArgs failed: %v", err)
+			}
+			if !slices.Equal(args, tt.expected) {
+				t.Errorf("GetArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
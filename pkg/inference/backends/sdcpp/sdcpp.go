@@ -0,0 +1,85 @@
+package sdcpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/diskusage"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/bundled"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+const (
+	// Name is the backend name.
+	Name = "stable-diffusion.cpp"
+	// hostOverhead is the RAM stable-diffusion.cpp needs for the server and
+	// the encoded images, independently of where the model runs.
+	hostOverhead = 256 * 1024 * 1024
+	// computeOverhead is the memory stable-diffusion.cpp needs on top of the
+	// model weights for the compute buffers of the diffusion model and the VAE
+	// at the maximum supported resolution of 1024x1024 pixels.
+	computeOverhead = 2 * 1024 * 1024 * 1024
+)
+
+// StatusNotFound indicates that the stable-diffusion.cpp server binary isn't installed.
+var StatusNotFound = errors.New("stable-diffusion.cpp server binary not found")
+
+// sdCpp is the stable-diffusion.cpp-based backend implementation.
+type sdCpp struct {
+	// Backend runs com.docker.sd-server.
+	*bundled.Backend
+	// modelManager is the shared model manager.
+	modelManager *models.Manager
+}
+
+// New creates a new stable-diffusion.cpp-based backend.
+func New(
+	log logging.Logger,
+	modelManager *models.Manager,
+	serverLog logging.Logger,
+	serverStoragePath string,
+	conf config.BackendConfig,
+) (inference.Backend, error) {
+	// If no config is provided, use the default configuration
+	if conf == nil {
+		conf = NewDefaultSDCppConfig()
+	}
+
+	return &sdCpp{
+		Backend: bundled.New(bundled.Server{
+			Name:     Name,
+			Binary:   "com.docker.sd-server",
+			NotFound: StatusNotFound,
+			Config:   conf,
+		}, log, modelManager, serverLog, serverStoragePath),
+		modelManager: modelManager,
+	}, nil
+}
+
+// GetRequiredMemoryForModel implements
+// inference.Backend.GetRequiredMemoryForModel. Diffusion models have no
+// context to size, but their compute buffers grow with the image resolution,
+// so the estimate is the size of the checkpoint plus the compute buffers at
+// the maximum resolution, all of which is offloaded to the GPU.
+func (s *sdCpp) GetRequiredMemoryForModel(_ context.Context, model string, _ *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	mdl, err := s.modelManager.GetModel(model)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting model(%s): %w", model, err)
+	}
+	path, err := mdl.DiffusionPath()
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting diffusion file for model(%s): %w", model, err)
+	}
+	size, err := diskusage.Size(path)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting size of model(%s): %w", model, err)
+	}
+	return inference.RequiredMemory{
+		RAM:  hostOverhead,
+		VRAM: uint64(size) + computeOverhead,
+	}, nil
+}
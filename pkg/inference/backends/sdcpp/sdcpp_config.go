@@ -0,0 +1,55 @@
+package sdcpp
+
+import (
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// generationsPath is the path on which sd-server is told to serve image
+// generations, so that requests can be forwarded to it unchanged.
+const generationsPath = "/v1/images/generations"
+
+// Config is the configuration for the stable-diffusion.cpp backend.
+type Config struct {
+	// Args are the base arguments that are always included.
+	Args []string
+}
+
+// NewDefaultSDCppConfig creates a new Config with default values.
+func NewDefaultSDCppConfig() *Config {
+	return &Config{
+		Args: []string{},
+	}
+}
+
+// GetArgs implements BackendConfig.GetArgs.
+func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference.BackendMode, config *inference.BackendConfiguration) ([]string, error) {
+	// Start with the arguments from Config
+	args := append([]string{}, c.Args...)
+
+	modelPath := bundle.DiffusionPath()
+	if modelPath == "" {
+		return nil, fmt.Errorf("diffusion model file required by stable-diffusion.cpp backend")
+	}
+
+	// stable-diffusion.cpp only generates images
+	if mode != inference.BackendModeImageGeneration {
+		return nil, fmt.Errorf("unsupported backend mode %q", mode)
+	}
+
+	// Add model, socket and endpoint arguments
+	args = append(args,
+		"--model", modelPath,
+		"--host", socket,
+		"--inference-path", generationsPath,
+	)
+
+	// Add arguments from backend config
+	if config != nil {
+		args = append(args, config.RuntimeFlags...)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,74 @@
+package sdcpp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+	testutil "github.com/docker/model-runner/pkg/inference/backends/internal/testing"
+)
+
+func TestGetArgs(t *testing.T) {
+	const socket = "/tmp/sd.sock"
+	tests := []struct {
+		name        string
+		bundle      *testutil.FakeBundle
+		mode        inference.BackendMode
+		config      *inference.BackendConfiguration
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:   "image generation mode",
+			bundle: &testutil.FakeBundle{Diffusion: "/path/to/model.diffusion"},
+			mode:   inference.BackendModeImageGeneration,
+			expected: []string{
+				"--model", "/path/to/model.diffusion",
+				"--host", socket,
+				"--inference-path", "/v1/images/generations",
+			},
+		},
+		{
+			name:   "with runtime flags",
+			bundle: &testutil.FakeBundle{Diffusion: "/path/to/model.diffusion"},
+			mode:   inference.BackendModeImageGeneration,
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--steps", "20"}},
+			expected: []string{
+				"--model", "/path/to/model.diffusion",
+				"--host", socket,
+				"--inference-path", "/v1/images/generations",
+				"--steps", "20",
+			},
+		},
+		{
+			name:        "missing diffusion file",
+			bundle:      &testutil.FakeBundle{},
+			mode:        inference.BackendModeImageGeneration,
+			expectError: true,
+		},
+		{
+			name:        "completion mode",
+			bundle:      &testutil.FakeBundle{Diffusion: "/path/to/model.diffusion"},
+			mode:        inference.BackendModeCompletion,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewDefaultSDCppConfig().GetArgs(tt.bundle, socket, tt.mode, tt.config)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got args %v", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetArgs failed: %v", err)
+			}
+			if !slices.Equal(args, tt.expected) {
+				t.Errorf("GetArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
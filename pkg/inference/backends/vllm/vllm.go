@@ -105,17 +105,20 @@ func (v *vLLM) Run(ctx context.Context, socket, model string, modelRef string, m
 		return errors.New("not implemented")
 	}
 
-	bundle, err := v.modelManager.GetBundle(model)
+	bundle, release, err := v.modelManager.AcquireBundle(model)
 	if err != nil {
 		return fmt.Errorf("failed to get model: %w", err)
 	}
+	defer release()
 
 	var draftBundle types.ModelBundle
 	if backendConfig != nil && backendConfig.Speculative != nil && backendConfig.Speculative.DraftModel != "" {
-		draftBundle, err = v.modelManager.GetBundle(backendConfig.Speculative.DraftModel)
+		var releaseDraft func()
+		draftBundle, releaseDraft, err = v.modelManager.AcquireBundle(backendConfig.Speculative.DraftModel)
 		if err != nil {
 			return fmt.Errorf("failed to get draft model: %w", err)
 		}
+		defer releaseDraft()
 	}
 
 	if err := os.RemoveAll(socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
@@ -170,8 +173,11 @@ func (v *vLLM) Run(ctx context.Context, socket, model string, modelRef string, m
 				}
 				return command.Process.Signal(os.Interrupt)
 			}
-			command.Stdout = serverLogStream
-			command.Stderr = out
+			command.Stdout = backendConfig.OutputWriter(serverLogStream)
+			command.Stderr = backendConfig.OutputWriter(out)
+			if env := backendConfig.Environ(); len(env) > 0 {
+				command.Env = append(os.Environ(), env...)
+			}
 		},
 		vllmDir,
 		v.binaryPath(),
@@ -221,6 +227,13 @@ func (v *vLLM) Run(ctx context.Context, socket, model string, modelRef string, m
 	}
 }
 
+// ConstrainToJSONSchema implements inference.JSONSchemaConstrainer. vLLM
+// supports json_schema response formats natively, so the request is forwarded
+// unchanged.
+func (v *vLLM) ConstrainToJSONSchema(body []byte, _ json.RawMessage) ([]byte, error) {
+	return body, nil
+}
+
 func (v *vLLM) Status() string {
 	return v.status
 }
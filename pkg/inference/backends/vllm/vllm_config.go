@@ -46,6 +46,8 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 	case inference.BackendModeEmbedding:
 		// vLLM doesn't have a specific embedding flag like llama.cpp
 		// Embedding models are detected automatically
+	case inference.BackendModeReranking:
+		// Cross-encoder models serve /v1/rerank automatically
 	default:
 		return nil, fmt.Errorf("unsupported backend mode %q", mode)
 	}
@@ -56,6 +58,14 @@ func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference
 	}
 	// If nil, vLLM will automatically derive from the model config
 
+	// Add the maximum number of sequences and batched tokens, if configured
+	if config != nil && config.ParallelSlots > 0 {
+		args = append(args, "--max-num-seqs", strconv.Itoa(config.ParallelSlots))
+	}
+	if config != nil && config.BatchSize > 0 {
+		args = append(args, "--max-num-batched-tokens", strconv.Itoa(config.BatchSize))
+	}
+
 	// Add arguments from backend config
 	if config != nil {
 		args = append(args, config.RuntimeFlags...)
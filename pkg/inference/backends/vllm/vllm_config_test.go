@@ -28,6 +28,26 @@ func (m *mockModelBundle) MMPROJPath() string {
 	return ""
 }
 
+func (m *mockModelBundle) LoRAAdapterPaths() []string {
+	return nil
+}
+
+func (m *mockModelBundle) WhisperPath() string {
+	return ""
+}
+
+func (m *mockModelBundle) VoicePath() string {
+	return ""
+}
+
+func (m *mockModelBundle) DiffusionPath() string {
+	return ""
+}
+
+func (m *mockModelBundle) ONNXPath() string {
+	return ""
+}
+
 func (m *mockModelBundle) RuntimeConfig() types.Config {
 	return m.runtimeConfig
 }
@@ -83,6 +103,26 @@ func TestGetArgs(t *testing.T) {
 				"8192",
 			},
 		},
+		{
+			name: "with parallel slots and batch size",
+			bundle: &mockModelBundle{
+				safetensorsPath: "/path/to/model",
+			},
+			config: &inference.BackendConfiguration{
+				ParallelSlots: 16,
+				BatchSize:     4096,
+			},
+			expected: []string{
+				"serve",
+				"/path/to",
+				"--uds",
+				"/tmp/socket",
+				"--max-num-seqs",
+				"16",
+				"--max-num-batched-tokens",
+				"4096",
+			},
+		},
 		{
 			name: "with runtime flags",
 			bundle: &mockModelBundle{
@@ -0,0 +1,80 @@
+package whispercpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/diskusage"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/bundled"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+const (
+	// Name is the backend name.
+	Name = "whisper.cpp"
+	// computeOverhead is the memory whisper.cpp needs on top of the model
+	// weights for its compute buffers and the decoded audio.
+	computeOverhead = 256 * 1024 * 1024
+)
+
+// StatusNotFound indicates that the whisper.cpp server binary isn't installed.
+var StatusNotFound = errors.New("whisper.cpp server binary not found")
+
+// whisperCpp is the whisper.cpp-based backend implementation.
+type whisperCpp struct {
+	// Backend runs com.docker.whisper-server.
+	*bundled.Backend
+	// modelManager is the shared model manager.
+	modelManager *models.Manager
+}
+
+// New creates a new whisper.cpp-based backend.
+func New(
+	log logging.Logger,
+	modelManager *models.Manager,
+	serverLog logging.Logger,
+	serverStoragePath string,
+	conf config.BackendConfig,
+) (inference.Backend, error) {
+	// If no config is provided, use the default configuration
+	if conf == nil {
+		conf = NewDefaultWhisperCppConfig()
+	}
+
+	return &whisperCpp{
+		Backend: bundled.New(bundled.Server{
+			Name:     Name,
+			Binary:   "com.docker.whisper-server",
+			NotFound: StatusNotFound,
+			Config:   conf,
+		}, log, modelManager, serverLog, serverStoragePath),
+		modelManager: modelManager,
+	}, nil
+}
+
+// GetRequiredMemoryForModel implements
+// inference.Backend.GetRequiredMemoryForModel. Whisper models are small and
+// have no context to size, so the estimate is the size of the weights plus a
+// fixed overhead. GPU offloading isn't estimated.
+func (w *whisperCpp) GetRequiredMemoryForModel(_ context.Context, model string, _ *inference.BackendConfiguration) (inference.RequiredMemory, error) {
+	mdl, err := w.modelManager.GetModel(model)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting model(%s): %w", model, err)
+	}
+	path, err := mdl.WhisperPath()
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting whisper file for model(%s): %w", model, err)
+	}
+	size, err := diskusage.Size(path)
+	if err != nil {
+		return inference.RequiredMemory{}, fmt.Errorf("getting size of model(%s): %w", model, err)
+	}
+	return inference.RequiredMemory{
+		RAM:  uint64(size) + computeOverhead,
+		VRAM: 1,
+	}, nil
+}
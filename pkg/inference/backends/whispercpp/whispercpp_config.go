@@ -0,0 +1,55 @@
+package whispercpp
+
+import (
+	"fmt"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// transcriptionsPath is the path on which whisper-server is told to serve
+// transcriptions, so that requests can be forwarded to it unchanged.
+const transcriptionsPath = "/v1/audio/transcriptions"
+
+// Config is the configuration for the whisper.cpp backend.
+type Config struct {
+	// Args are the base arguments that are always included.
+	Args []string
+}
+
+// NewDefaultWhisperCppConfig creates a new Config with default values.
+func NewDefaultWhisperCppConfig() *Config {
+	return &Config{
+		Args: []string{},
+	}
+}
+
+// GetArgs implements BackendConfig.GetArgs.
+func (c *Config) GetArgs(bundle types.ModelBundle, socket string, mode inference.BackendMode, config *inference.BackendConfiguration) ([]string, error) {
+	// Start with the arguments from Config
+	args := append([]string{}, c.Args...)
+
+	modelPath := bundle.WhisperPath()
+	if modelPath == "" {
+		return nil, fmt.Errorf("whisper model file required by whisper.cpp backend")
+	}
+
+	// whisper.cpp only serves transcriptions
+	if mode != inference.BackendModeTranscription {
+		return nil, fmt.Errorf("unsupported backend mode %q", mode)
+	}
+
+	// Add model, socket and endpoint arguments
+	args = append(args,
+		"--model", modelPath,
+		"--host", socket,
+		"--inference-path", transcriptionsPath,
+	)
+
+	// Add arguments from backend config
+	if config != nil {
+		args = append(args, config.RuntimeFlags...)
+	}
+
+	return args, nil
+}
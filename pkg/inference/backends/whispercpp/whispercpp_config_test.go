@@ -0,0 +1,74 @@
+package whispercpp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+	testutil "github.com/docker/model-runner/pkg/inference/backends/internal/testing"
+)
+
+func TestGetArgs(t *testing.T) {
+	const socket = "/tmp/whisper.sock"
+	tests := []struct {
+		name        string
+		bundle      *testutil.FakeBundle
+		mode        inference.BackendMode
+		config      *inference.BackendConfiguration
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:   "transcription mode",
+			bundle: &testutil.FakeBundle{Whisper: "/path/to/model.whisper"},
+			mode:   inference.BackendModeTranscription,
+			expected: []string{
+				"--model", "/path/to/model.whisper",
+				"--host", socket,
+				"--inference-path", "/v1/audio/transcriptions",
+			},
+		},
+		{
+			name:   "with runtime flags",
+			bundle: &testutil.FakeBundle{Whisper: "/path/to/model.whisper"},
+			mode:   inference.BackendModeTranscription,
+			config: &inference.BackendConfiguration{RuntimeFlags: []string{"--language", "auto"}},
+			expected: []string{
+				"--model", "/path/to/model.whisper",
+				"--host", socket,
+				"--inference-path", "/v1/audio/transcriptions",
+				"--language", "auto",
+			},
+		},
+		{
+			name:        "missing whisper file",
+			bundle:      &testutil.FakeBundle{},
+			mode:        inference.BackendModeTranscription,
+			expectError: true,
+		},
+		{
+			name:        "completion mode",
+			bundle:      &testutil.FakeBundle{Whisper: "/path/to/model.whisper"},
+			mode:        inference.BackendModeCompletion,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewDefaultWhisperCppConfig().GetArgs(tt.bundle, socket, tt.mode, tt.config)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got args %v", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetArgs failed: %v", err)
+			}
+			if !slices.Equal(args, tt.expected) {
+				t.Errorf("GetArgs() = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
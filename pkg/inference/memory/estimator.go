@@ -12,6 +12,7 @@ type MemoryEstimator interface {
 	SetDefaultBackend(MemoryEstimatorBackend)
 	GetRequiredMemoryForModel(context.Context, string, *inference.BackendConfiguration) (inference.RequiredMemory, error)
 	HaveSufficientMemoryForModel(ctx context.Context, model string, config *inference.BackendConfiguration) (bool, inference.RequiredMemory, inference.RequiredMemory, error)
+	EstimateMemoryForModel(ctx context.Context, model string, config *inference.BackendConfiguration) (inference.MemoryEstimate, error)
 }
 
 type MemoryEstimatorBackend interface {
@@ -51,3 +52,26 @@ func (m *memoryEstimator) HaveSufficientMemoryForModel(ctx context.Context, mode
 	}
 	return ok, req, m.systemMemoryInfo.GetTotalMemory(), nil
 }
+
+// EstimateMemoryForModel breaks down the memory required for the model by the
+// default backend and reports whether it fits in the system memory.
+func (m *memoryEstimator) EstimateMemoryForModel(ctx context.Context, model string, config *inference.BackendConfiguration) (inference.MemoryEstimate, error) {
+	if m.defaultBackend == nil {
+		return inference.MemoryEstimate{}, errors.New("default backend not configured")
+	}
+	reporter, ok := m.defaultBackend.(inference.MemoryEstimateReporter)
+	if !ok {
+		return inference.MemoryEstimate{}, errors.New("default backend doesn't support memory estimates")
+	}
+
+	estimate, err := reporter.EstimateMemory(ctx, model, config)
+	if err != nil {
+		return inference.MemoryEstimate{}, fmt.Errorf("estimating memory for model: %w", err)
+	}
+	estimate.Fits, err = m.systemMemoryInfo.HaveSufficientMemory(estimate.Required)
+	if err != nil {
+		return inference.MemoryEstimate{}, fmt.Errorf("checking if system has sufficient memory: %w", err)
+	}
+	estimate.Available = m.systemMemoryInfo.GetTotalMemory()
+	return estimate, nil
+}
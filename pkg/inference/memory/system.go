@@ -12,22 +12,57 @@ import (
 type SystemMemoryInfo interface {
 	HaveSufficientMemory(inference.RequiredMemory) (bool, error)
 	GetTotalMemory() inference.RequiredMemory
+	// GetGPUMemory returns the VRAM of each GPU, in device index order, or nil
+	// if it's unknown.
+	GetGPUMemory() []uint64
+	// GetFreeGPUMemory returns the unused VRAM of each GPU, in device index
+	// order, or nil if it's unknown.
+	GetFreeGPUMemory() []uint64
 }
 
 type systemMemoryInfo struct {
 	log         logging.Logger
 	totalMemory inference.RequiredMemory
+	gpuMemory   []uint64
+	// gpuInfo is used to query the free memory of the GPUs.
+	gpuInfo *gpuinfo.GPUInfo
+	// npus indicates whether the GPUs are Ascend NPUs.
+	npus bool
 }
 
 func NewSystemMemoryInfo(log logging.Logger, gpuInfo *gpuinfo.GPUInfo) (SystemMemoryInfo, error) {
 	// Compute the amount of available memory.
 	// TODO(p1-0tr): improve error handling
 	vramSize, err := gpuInfo.GetVRAMSize()
+	var gpuMemory []uint64
+	haveNPUs := false
 	if err != nil {
-		vramSize = 1
-		log.Warnf("Could not read VRAM size: %s", err)
+		// Fall back to Ascend NPUs, whose device memory plays the part of
+		// VRAM.
+		if npus, npuErr := gpuInfo.GetNPUs(); npuErr == nil {
+			vramSize = 0
+			var freeSize uint64
+			for _, npu := range npus {
+				vramSize += npu.TotalMemory
+				freeSize += npu.FreeMemory
+				gpuMemory = append(gpuMemory, npu.TotalMemory)
+			}
+			haveNPUs = true
+			log.Infof("Running on system with %d Ascend NPUs with %d MB memory (%d MB free)",
+				len(npus), vramSize/1024/1024, freeSize/1024/1024)
+		} else {
+			vramSize = 1
+			log.Warnf("Could not read VRAM size: %s", err)
+			log.Debugf("Could not read Ascend NPU memory: %s", npuErr)
+		}
 	} else {
 		log.Infof("Running on system with %d MB VRAM", vramSize/1024/1024)
+		gpuMemory, err = gpuInfo.GetGPUMemorySizes()
+		if err != nil {
+			log.Debugf("Could not read per-GPU VRAM sizes: %s", err)
+		} else if len(gpuMemory) > 1 {
+			log.Infof("Running on system with %d GPUs", len(gpuMemory))
+		}
 	}
 	ramSize := uint64(1)
 	hostInfo, err := sysinfo.Host()
@@ -45,6 +80,9 @@ func NewSystemMemoryInfo(log logging.Logger, gpuInfo *gpuinfo.GPUInfo) (SystemMe
 	return &systemMemoryInfo{
 		log:         log,
 		totalMemory: inference.RequiredMemory{RAM: ramSize, VRAM: vramSize},
+		gpuMemory:   gpuMemory,
+		gpuInfo:     gpuInfo,
+		npus:        haveNPUs,
 	}, nil
 }
 
@@ -62,3 +100,35 @@ func (s *systemMemoryInfo) HaveSufficientMemory(req inference.RequiredMemory) (b
 func (s *systemMemoryInfo) GetTotalMemory() inference.RequiredMemory {
 	return s.totalMemory
 }
+
+func (s *systemMemoryInfo) GetGPUMemory() []uint64 {
+	return s.gpuMemory
+}
+
+func (s *systemMemoryInfo) GetFreeGPUMemory() []uint64 {
+	if s.gpuInfo == nil || len(s.gpuMemory) == 0 {
+		return nil
+	}
+	var free []uint64
+	if s.npus {
+		npus, err := s.gpuInfo.GetNPUs()
+		if err != nil {
+			s.log.Debugf("Could not read free Ascend NPU memory: %s", err)
+			return nil
+		}
+		for _, npu := range npus {
+			free = append(free, npu.FreeMemory)
+		}
+	} else {
+		var err error
+		if free, err = s.gpuInfo.GetGPUFreeMemorySizes(); err != nil {
+			s.log.Debugf("Could not read free VRAM: %s", err)
+			return nil
+		}
+	}
+	if len(free) != len(s.gpuMemory) {
+		s.log.Debugf("GPUs changed from %d to %d", len(s.gpuMemory), len(free))
+		return nil
+	}
+	return free
+}
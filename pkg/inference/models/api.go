@@ -3,7 +3,9 @@ package models
 import (
 	"fmt"
 
+	"github.com/docker/model-runner/pkg/distribution/search"
 	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
 )
 
 // ModelCreateRequest represents a model create request. It is designed to
@@ -17,6 +19,103 @@ type ModelCreateRequest struct {
 	// IgnoreRuntimeMemoryCheck indicates whether the server should check if it has sufficient
 	// memory to run the given model (assuming default configuration).
 	IgnoreRuntimeMemoryCheck bool `json:"ignore-runtime-memory-check,omitempty"`
+	// AcceptLicense indicates that the user accepts the licenses of the model
+	// if its config requires license acceptance.
+	AcceptLicense bool `json:"accept-license,omitempty"`
+	// Store optionally selects the store, by name, to which the model is
+	// pulled, instead of the designated writable store.
+	Store string `json:"store,omitempty"`
+}
+
+// ModelPullBatchRequest represents a request to pull several models
+// concurrently.
+type ModelPullBatchRequest struct {
+	// Models are the models to pull, each with the options of a
+	// ModelCreateRequest.
+	Models []ModelCreateRequest `json:"models"`
+}
+
+// ModelPullBatchResponse is the last message of the progress stream of a
+// batch pull, which reports the outcome of each pull.
+type ModelPullBatchResponse struct {
+	// Type is "success" if every model was pulled, or "error" otherwise.
+	Type    string            `json:"type"`
+	Message string            `json:"message"`
+	Results []ModelPullResult `json:"results"`
+}
+
+// ModelPullResult is the outcome of pulling one model of a batch.
+type ModelPullResult struct {
+	// Ref is the normalized reference of the model.
+	Ref string `json:"ref"`
+	// Error describes why the pull failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// ModelConfigRequest represents a request to change the runtime configuration
+// of a model. Unset fields are left unchanged.
+type ModelConfigRequest struct {
+	// ChatTemplate is the Jinja chat template to set or replace.
+	ChatTemplate *string `json:"chat-template,omitempty"`
+	// ContextSize is the context size to set.
+	ContextSize *uint64 `json:"context-size,omitempty"`
+	// RuntimeFlags are the runtime flags to set, which must be in
+	// inference.PersistentRuntimeFlags. An empty list clears them.
+	RuntimeFlags *[]string `json:"runtime-flags,omitempty"`
+	// Tag is the tag to apply to the configured model. If empty, the
+	// configured model replaces the original under its name.
+	Tag string `json:"tag,omitempty"`
+}
+
+// ModelPruneRequest represents a request to remove older tags of each
+// repository from the store, along with models left without tags.
+type ModelPruneRequest struct {
+	// KeepLast is the number of newest tags to keep in each repository. It's
+	// required, so that a request can't remove every tag by omission.
+	KeepLast *int `json:"keep-last"`
+	// KeepTags are tags that are always kept, such as "latest" or "stable".
+	KeepTags []string `json:"keep-tags,omitempty"`
+	// Repositories restricts pruning to the given repositories.
+	Repositories []string `json:"repositories,omitempty"`
+	// DryRun reports what would be removed without removing it.
+	DryRun bool `json:"dry-run,omitempty"`
+}
+
+// ModelAliasRequest represents a request to point an alias at a model.
+type ModelAliasRequest struct {
+	// Model is the reference of the model the alias stands for.
+	Model string `json:"model"`
+}
+
+// ModelAlias is an alternative name for a model, which resolves wherever a
+// model reference is accepted.
+type ModelAlias struct {
+	// Alias is the name of the alias.
+	Alias string `json:"alias"`
+	// Model is the reference of the model the alias stands for.
+	Model string `json:"model"`
+}
+
+// ModelChangesResponse is the response to a model listing request with a
+// since cursor. It contains only the models changed since the cursor.
+type ModelChangesResponse struct {
+	// Cursor is the cursor to pass as since in the next listing request.
+	Cursor string `json:"cursor"`
+	// Reset indicates that the since cursor could not be honored, so Models
+	// contains all models and the client should discard its cached listing.
+	Reset bool `json:"reset,omitempty"`
+	// Models are the models created or retagged since the cursor.
+	Models []*Model `json:"models"`
+	// Deleted are the IDs of the models deleted since the cursor.
+	Deleted []string `json:"deleted"`
+}
+
+// ModelSearchResponse is the response to a model search request.
+type ModelSearchResponse struct {
+	// Results are the models found, most downloaded first.
+	Results []search.Result `json:"results"`
+	// Errors describe the sources that could not be searched, if any.
+	Errors []string `json:"errors,omitempty"`
 }
 
 // ToOpenAIList converts the model list to its OpenAI API representation. This function never
@@ -94,8 +193,30 @@ type Model struct {
 	Tags []string `json:"tags,omitempty"`
 	// Created is the Unix epoch timestamp corresponding to the model creation.
 	Created int64 `json:"created"`
+	// Pulled is the Unix epoch timestamp corresponding to when the model was
+	// written to the local store, if known.
+	Pulled int64 `json:"pulled,omitempty"`
+	// LastUsed is the Unix epoch timestamp corresponding to when the model was
+	// last loaded for inference, if ever.
+	LastUsed int64 `json:"last_used,omitempty"`
+	// Size is the total size of the model's files in bytes, if known.
+	Size int64 `json:"size,omitempty"`
+	// Source is the registry reference the model was pulled from, if known.
+	Source string `json:"source,omitempty"`
+	// Multimodal indicates that the model has a multimodal projector, so it
+	// accepts images in chat requests.
+	Multimodal bool `json:"multimodal,omitempty"`
 	// Config describes the model.
 	Config types.Config `json:"config"`
+	// Annotations are the manifest annotations of the model, such as labels
+	// set when it was packaged.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Licenses are the texts of the licenses of the model. They're only
+	// included when getting a single local model.
+	Licenses []string `json:"licenses,omitempty"`
+	// Estimate is a breakdown of the memory the model requires to run. It's
+	// only included when getting a single model with estimate=true.
+	Estimate *inference.MemoryEstimate `json:"estimate,omitempty"`
 }
 
 func ToModel(m types.Model) (*Model, error) {
@@ -119,10 +240,25 @@ func ToModel(m types.Model) (*Model, error) {
 		created = desc.Created.Unix()
 	}
 
-	return &Model{
+	metadata := m.Metadata()
+	model := &Model{
 		ID:      id,
 		Tags:    m.Tags(),
 		Created: created,
+		Size:    metadata.Size,
+		Source:  metadata.Source,
 		Config:  cfg,
-	}, nil
+
+		Annotations: m.Annotations(),
+	}
+	if metadata.Pulled != nil {
+		model.Pulled = metadata.Pulled.Unix()
+	}
+	if metadata.LastUsed != nil {
+		model.LastUsed = metadata.LastUsed.Unix()
+	}
+	if _, err := m.MMPROJPath(); err == nil {
+		model.Multimodal = true
+	}
+	return model, nil
 }
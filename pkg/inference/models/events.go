@@ -0,0 +1,234 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// progressStreamIdleTimeout is how long a pull or push whose progress is
+	// streamed as server-sent events continues without a connected client,
+	// waiting for one to reconnect, before it's canceled.
+	progressStreamIdleTimeout = 30 * time.Second
+	// progressStreamRetention is how long the events of a finished pull or
+	// push are kept for clients that reconnect to receive the last ones.
+	progressStreamRetention = time.Minute
+)
+
+// isEventStream reports whether r asks for progress as server-sent events.
+func isEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// progressStream records the progress messages of a pull or push that runs
+// in the background, so that clients receiving them as server-sent events
+// can reconnect and resume after the last event they received. The ID of an
+// event is its position in the stream, starting from 1.
+type progressStream struct {
+	mu     sync.Mutex
+	events [][]byte
+	// partial holds an incomplete message, up to its terminating newline.
+	partial []byte
+	done    bool
+	// changed is closed, and replaced, when events are added or the stream
+	// is done.
+	changed chan struct{}
+
+	clients     int
+	cancel      context.CancelFunc
+	idleTimeout time.Duration
+	idle        *time.Timer
+}
+
+// newProgressStream creates a stream whose operation is canceled by cancel
+// once no client has been connected for idleTimeout.
+func newProgressStream(cancel context.CancelFunc, idleTimeout time.Duration) *progressStream {
+	return &progressStream{
+		changed:     make(chan struct{}),
+		cancel:      cancel,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Write records the newline-terminated progress messages in p as events.
+func (s *progressStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partial = append(s.partial, p...)
+	var added bool
+	for {
+		line, rest, ok := bytes.Cut(s.partial, []byte("\n"))
+		if !ok {
+			break
+		}
+		s.partial = rest
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.events = append(s.events, bytes.Clone(line))
+			added = true
+		}
+	}
+	if added {
+		s.notify()
+	}
+	return len(p), nil
+}
+
+// finish marks the stream as done, adding an error event for err if the
+// operation didn't report its failure itself.
+func (s *progressStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && !s.reportedError() {
+		msg, _ := json.Marshal(struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}{"error", err.Error()})
+		s.events = append(s.events, msg)
+	}
+	s.done = true
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	s.notify()
+}
+
+// reportedError reports whether the last event is an error message.
+func (s *progressStream) reportedError() bool {
+	if len(s.events) == 0 {
+		return false
+	}
+	var msg struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(s.events[len(s.events)-1], &msg) == nil && msg.Type == "error"
+}
+
+func (s *progressStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// next returns the events after the event with ID after, whether the stream
+// is done, in which case there are no further events, and a channel that's
+// closed when that changes.
+func (s *progressStream) next(after int) ([][]byte, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events [][]byte
+	if after < len(s.events) {
+		events = s.events[after:]
+	}
+	return events, s.done, s.changed
+}
+
+// attach registers a connected client.
+func (s *progressStream) attach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients++
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+}
+
+// detach unregisters a client, canceling the operation after the idle
+// timeout if it was the last one and no other client connects in between.
+func (s *progressStream) detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients--
+	if s.clients == 0 && !s.done {
+		s.idle = time.AfterFunc(s.idleTimeout, s.cancel)
+	}
+}
+
+// streamProgress runs op, the pull or push identified by key, in the
+// background and streams its progress messages to w as server-sent events,
+// each with an ID. A request with a Last-Event-ID header reconnects to the
+// operation with key, if it's running or finished recently, and resumes
+// after that event, rather than starting the operation again. Failures are
+// reported as error events, as the response has started by then, so the
+// only error returned is that of r's context when the client disconnects.
+func (m *Manager) streamProgress(w http.ResponseWriter, r *http.Request, key string, op func(ctx context.Context, w io.Writer) error) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming not supported")
+	}
+
+	var stream *progressStream
+	var after int
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if n, err := strconv.Atoi(id); err == nil && n >= 0 {
+			m.progressStreamsLock.Lock()
+			stream = m.progressStreams[key]
+			m.progressStreamsLock.Unlock()
+			after = n
+		}
+	}
+	if stream == nil {
+		stream = m.startProgressStream(r.Context(), key, op)
+		after = 0
+	}
+	stream.attach()
+	defer stream.detach()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		events, done, changed := stream.next(after)
+		for _, event := range events {
+			after++
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", after, event); err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+	}
+}
+
+// startProgressStream starts op in the background, with the values of ctx
+// but not its cancellation, and registers its stream under key.
+func (m *Manager) startProgressStream(ctx context.Context, key string, op func(ctx context.Context, w io.Writer) error) *progressStream {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stream := newProgressStream(cancel, progressStreamIdleTimeout)
+	m.progressStreamsLock.Lock()
+	m.progressStreams[key] = stream
+	m.progressStreamsLock.Unlock()
+
+	go func() {
+		defer cancel()
+		stream.finish(op(ctx, stream))
+		time.AfterFunc(progressStreamRetention, func() {
+			m.progressStreamsLock.Lock()
+			defer m.progressStreamsLock.Unlock()
+			if m.progressStreams[key] == stream {
+				delete(m.progressStreams, key)
+			}
+		})
+	}()
+	return stream
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+type event struct {
+	id   int
+	data string
+}
+
+// readEvents parses a stream of server-sent events.
+func readEvents(t *testing.T, r io.Reader) []event {
+	t.Helper()
+	var events []event
+	var current event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.data != "" {
+				events = append(events, current)
+			}
+			current = event{}
+		case strings.HasPrefix(line, "id: "):
+			id, err := strconv.Atoi(strings.TrimPrefix(line, "id: "))
+			if err != nil {
+				t.Fatalf("Invalid event ID %q", line)
+			}
+			current.id = id
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Fatalf("Unexpected line %q", line)
+		}
+	}
+	return events
+}
+
+func TestPullModelEventStream(t *testing.T) {
+	m, host := newTestManager(t)
+	tag := host + "/ai/model:v1.0.0"
+
+	pull := func(lastEventID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/create", strings.NewReader(`{"from": "`+tag+`"}`))
+		r.Header.Set("Accept", "text/event-stream")
+		if lastEventID != "" {
+			r.Header.Set("Last-Event-ID", lastEventID)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("Expected Content-Type text/event-stream, got %q", contentType)
+		}
+		return w
+	}
+
+	events := readEvents(t, pull("").Body)
+	if len(events) < 2 {
+		t.Fatalf("Expected progress events, got %+v", events)
+	}
+	for i, e := range events {
+		if e.id != i+1 {
+			t.Errorf("Expected event ID %d, got %d", i+1, e.id)
+		}
+	}
+	var last struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-1].data), &last); err != nil || last.Type != "success" {
+		t.Errorf("Expected the last event to report success, got %q", events[len(events)-1].data)
+	}
+
+	// Reconnecting resumes after the last event received, without pulling
+	// the model again.
+	resumed := readEvents(t, pull("1").Body)
+	if len(resumed) != len(events)-1 {
+		t.Fatalf("Expected %d events after reconnecting, got %+v", len(events)-1, resumed)
+	}
+	for i, e := range resumed {
+		if e != events[i+1] {
+			t.Errorf("Expected event %+v, got %+v", events[i+1], e)
+		}
+	}
+}
+
+func TestProgressStreamIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	stream := newProgressStream(cancel, 10*time.Millisecond)
+	stream.attach()
+	stream.Write([]byte(`{"type":"progress"}` + "\n" + `{"type":`))
+	stream.detach()
+
+	// Operations are canceled once no client reconnects in time.
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the operation to be canceled")
+	}
+
+	stream.Write([]byte(`"progress"}` + "\n"))
+	stream.finish(context.Canceled)
+	events, done, _ := stream.next(1)
+	if !done || len(events) != 2 {
+		t.Fatalf("Expected 2 more events and the stream to be done, got %q, %v", events, done)
+	}
+	var msg struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(events[1], &msg); err != nil || msg.Type != "error" || msg.Message != context.Canceled.Error() {
+		t.Errorf("Expected an error event, got %q", events[1])
+	}
+
+	// Failures the operation reported aren't reported again.
+	stream = newProgressStream(func() {}, time.Minute)
+	stream.Write([]byte(`{"type":"error","message":"Error: boom"}` + "\n"))
+	stream.finish(errors.New("boom"))
+	if events, _, _ := stream.next(0); len(events) != 1 {
+		t.Errorf("Expected a single error event, got %q", events)
+	}
+}
@@ -1,23 +1,31 @@
 package models
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
+	"io"
+	"maps"
 	"net/http"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/docker/model-runner/pkg/diskusage"
 	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/huggingface"
 	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/resolver"
+	"github.com/docker/model-runner/pkg/distribution/search"
 	"github.com/docker/model-runner/pkg/distribution/types"
 	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/memory"
+	"github.com/docker/model-runner/pkg/internal/utils"
 	"github.com/docker/model-runner/pkg/logging"
 	"github.com/docker/model-runner/pkg/middleware"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -48,10 +56,21 @@ type Manager struct {
 	distributionClient *distribution.Client
 	// registryClient is the client for model registry.
 	registryClient *registry.Client
+	// nameResolver maps model names to registry references for remote
+	// lookups. It may be nil.
+	nameResolver resolver.Resolver
+	// searcher searches the Docker Hub catalog and Hugging Face for models.
+	searcher *search.Searcher
 	// lock is used to synchronize access to the models manager's router.
 	lock sync.RWMutex
 	// memoryEstimator is used to calculate runtime memory requirements for models.
 	memoryEstimator memory.MemoryEstimator
+	// progressStreams are the pulls and pushes whose progress is streamed as
+	// server-sent events, by operation and model, so that clients can
+	// reconnect to them.
+	progressStreams map[string]*progressStream
+	// progressStreamsLock guards progressStreams.
+	progressStreamsLock sync.Mutex
 }
 
 type ClientConfig struct {
@@ -63,17 +82,64 @@ type ClientConfig struct {
 	Transport http.RoundTripper
 	// UserAgent is the user agent to use.
 	UserAgent string
+	// NameResolver optionally maps model names to registry references
+	// before models are pulled or inspected remotely.
+	NameResolver resolver.Resolver
+	// RepairStore enables repairing corrupt models in the store on startup.
+	RepairStore bool
+	// Stores are additional stores in which models are looked up, in order,
+	// after the store at StoreRootPath.
+	Stores []distribution.StoreConfig
+	// WritableStore optionally names the store to which models are written,
+	// instead of the store at StoreRootPath.
+	WritableStore string
+	// ReadOnly opens every store read-only, rejecting requests that pull,
+	// load, tag, configure or delete models.
+	ReadOnly bool
+	// BlobCache is an optional directory shared with other stores, through
+	// which blobs are hardlinked between them rather than copied.
+	BlobCache string
+	// RequireDigest refuses models referenced by mutable tags, rather than
+	// by digest or ID, for reproducible deployments.
+	RequireDigest bool
+	// PushChunkSize is the size of the chunks in which layers are pushed.
+	// Zero uses the default and a negative size disables chunked pushes.
+	PushChunkSize int64
+	// TokenStore optionally holds OAuth2 refresh tokens with which registries
+	// are authenticated to.
+	TokenStore registry.TokenStore
+	// DiskHeadroom is the free space, in bytes, that pulls must leave on the
+	// volume of the store.
+	DiskHeadroom uint64
+	// HardlinkBundles requires runtime bundles to hardlink model files.
+	HardlinkBundles bool
 }
 
 // NewManager creates a new model's manager.
 func NewManager(log logging.Logger, c ClientConfig, allowedOrigins []string, memoryEstimator memory.MemoryEstimator) *Manager {
 	// Create the model distribution client.
-	distributionClient, err := distribution.NewClient(
+	clientOpts := []distribution.Option{
 		distribution.WithStoreRootPath(c.StoreRootPath),
 		distribution.WithLogger(c.Logger),
 		distribution.WithTransport(c.Transport),
 		distribution.WithUserAgent(c.UserAgent),
-	)
+		distribution.WithNameResolver(c.NameResolver),
+		distribution.WithRepairOnOpen(c.RepairStore),
+		distribution.WithStores(c.Stores...),
+		distribution.WithWritableStore(c.WritableStore),
+		distribution.WithBlobCache(c.BlobCache),
+		distribution.WithPushChunkSize(c.PushChunkSize),
+		distribution.WithTokenStore(c.TokenStore),
+		distribution.WithDiskHeadroom(c.DiskHeadroom),
+		distribution.WithHardlinkBundles(c.HardlinkBundles),
+	}
+	if c.ReadOnly {
+		clientOpts = append(clientOpts, distribution.WithReadOnlyStore())
+	}
+	if c.RequireDigest {
+		clientOpts = append(clientOpts, distribution.WithRequireDigest())
+	}
+	distributionClient, err := distribution.NewClient(clientOpts...)
 	if err != nil {
 		log.Errorf("Failed to create distribution client: %v", err)
 		// Continue without distribution client. The model manager will still
@@ -84,6 +150,7 @@ func NewManager(log logging.Logger, c ClientConfig, allowedOrigins []string, mem
 	registryClient := registry.NewClient(
 		registry.WithTransport(c.Transport),
 		registry.WithUserAgent(c.UserAgent),
+		registry.WithTokenStore(c.TokenStore),
 	)
 
 	// Create the manager.
@@ -93,7 +160,10 @@ func NewManager(log logging.Logger, c ClientConfig, allowedOrigins []string, mem
 		router:             http.NewServeMux(),
 		distributionClient: distributionClient,
 		registryClient:     registryClient,
+		nameResolver:       c.NameResolver,
+		searcher:           search.NewDefaultSearcher(c.Transport),
 		memoryEstimator:    memoryEstimator,
+		progressStreams:    make(map[string]*progressStream),
 	}
 
 	// Register routes.
@@ -132,9 +202,12 @@ func (m *Manager) RebuildRoutes(allowedOrigins []string) {
 //   - "ai/gemma3:latest" -> "ai/gemma3:latest" (unchanged)
 //   - "hf.co/model" -> "hf.co/model:latest" (unchanged - has registry)
 //   - "hf.co/Model" -> "hf.co/model:latest" (converted to lowercase)
+//   - "hf.co/org/repo/file.gguf" -> "hf.co/org/repo/file.gguf:latest" (file resolved at pull time)
+//   - "gemma3@sha256:<digest>" -> "ai/gemma3@sha256:<digest>" (no tag added)
+//   - "sha256:<digest>" -> "sha256:<digest>" (model IDs are unchanged)
 func NormalizeModelName(model string) string {
-	// If the model is empty, return as-is
-	if model == "" {
+	// If the model is empty or a model ID, return as-is
+	if model == "" || distribution.IsModelID(model) {
 		return model
 	}
 
@@ -143,6 +216,14 @@ func NormalizeModelName(model string) string {
 		model = strings.ToLower(model)
 	}
 
+	// Digest references pin the model, so only the default org is added
+	if repo, digest, ok := strings.Cut(model, "@"); ok {
+		if !strings.Contains(repo, "/") {
+			repo = defaultOrg + "/" + repo
+		}
+		return repo + "@" + digest
+	}
+
 	// Check if model contains a registry (domain with dot before first slash)
 	firstSlash := strings.Index(model, "/")
 	if firstSlash > 0 && strings.Contains(model[:firstSlash], ".") {
@@ -177,7 +258,16 @@ func (m *Manager) routeHandlers() map[string]http.HandlerFunc {
 		"GET " + inference.ModelsPrefix + "/{name...}":                        m.handleGetModel,
 		"DELETE " + inference.ModelsPrefix + "/{name...}":                     m.handleDeleteModel,
 		"POST " + inference.ModelsPrefix + "/{nameAndAction...}":              m.handleModelAction,
+		"PATCH " + inference.ModelsPrefix + "/{nameAndAction...}":             m.handleModelAction,
 		"DELETE " + inference.ModelsPrefix + "/purge":                         m.handlePurge,
+		"POST " + inference.ModelsPrefix + "/prune":                           m.handlePrune,
+		"POST " + inference.ModelsPrefix + "/pull-batch":                      m.handlePullBatch,
+		"GET " + inference.ModelsPrefix + "/_dedup-stats":                     m.handleDedupStats,
+		"GET " + inference.ModelsPrefix + "/_aliases":                         m.handleGetAliases,
+		"PUT " + inference.ModelsPrefix + "/_aliases/{alias...}":              m.handleSetAlias,
+		"DELETE " + inference.ModelsPrefix + "/_aliases/{alias...}":           m.handleRemoveAlias,
+		"GET " + inference.ModelsPrefix + "/ratelimit":                        m.handleRateLimits,
+		"GET " + inference.ModelsPrefix + "/search":                           m.handleSearchModels,
 		"GET " + inference.InferencePrefix + "/{backend}/v1/models":           m.handleOpenAIGetModels,
 		"GET " + inference.InferencePrefix + "/{backend}/v1/models/{name...}": m.handleOpenAIGetModel,
 		"GET " + inference.InferencePrefix + "/v1/models":                     m.handleOpenAIGetModels,
@@ -204,22 +294,11 @@ func (m *Manager) handleCreateModel(w http.ResponseWriter, r *http.Request) {
 
 	// Pull the model. In the future, we may support additional operations here
 	// besides pulling (such as model building).
-	if memory.RuntimeMemoryCheckEnabled() && !request.IgnoreRuntimeMemoryCheck {
-		m.log.Infof("Will estimate memory required for %q", request.From)
-		proceed, req, totalMem, err := m.memoryEstimator.HaveSufficientMemoryForModel(r.Context(), request.From, nil)
-		if err != nil {
-			m.log.Warnf("Failed to validate sufficient system memory for model %q: %s", request.From, err)
-			// Prefer staying functional in case of unexpected estimation errors.
-			proceed = true
-		}
-		if !proceed {
-			errstr := fmt.Sprintf("Runtime memory requirement for model %q exceeds total system memory: required %d RAM %d VRAM, system %d RAM %d VRAM", request.From, req.RAM, req.VRAM, totalMem.RAM, totalMem.VRAM)
-			m.log.Warnf(errstr)
-			http.Error(w, errstr, http.StatusInsufficientStorage)
-			return
-		}
+	if err := m.checkRuntimeMemory(r.Context(), request); err != nil {
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+		return
 	}
-	if err := m.PullModel(request.From, r, w); err != nil {
+	if err := m.PullModel(request.From, r, w, pullOptions(request)...); err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			m.log.Infof("Request canceled/timed out while pulling model %q", request.From)
 			return
@@ -239,6 +318,48 @@ func (m *Manager) handleCreateModel(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Model not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, registry.ErrRateLimited) {
+			m.log.Warnf("Rate limited pulling model %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, distribution.ErrUnknownStore) {
+			m.log.Warnf("Invalid store for model %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, distribution.ErrDigestRequired) {
+			m.log.Warnf("Refused to pull model %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var spaceErr *distribution.DiskSpaceError
+		if errors.As(err, &spaceErr) {
+			m.log.Warnf("Failed to pull model %q: %v", request.From, err)
+			writeDiskSpaceError(w, spaceErr)
+			return
+		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			m.log.Warnf("Failed to pull model %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, huggingface.ErrAmbiguousFile) {
+			m.log.Warnf("Ambiguous model reference %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, huggingface.ErrFileNotFound) {
+			m.log.Warnf("Failed to pull model %q: %v", request.From, err)
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		var licenseErr *distribution.LicenseError
+		if errors.As(err, &licenseErr) {
+			m.log.Infof("License of model %q not accepted", request.From)
+			http.Error(w, strings.Join(licenseErr.Licenses, "\n\n"), http.StatusUnavailableForLegalReasons)
+			return
+		}
 		if errors.Is(err, distribution.ErrUnsupportedFormat) {
 			m.log.Warnf("Unsupported model format for %q: %v", request.From, err)
 			http.Error(w, distribution.ErrUnsupportedFormat.Error(), http.StatusUnsupportedMediaType)
@@ -249,6 +370,149 @@ func (m *Manager) handleCreateModel(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// checkRuntimeMemory returns an error if the runtime memory check is enabled
+// and not disabled by request, and the model of request requires more memory
+// than the system has.
+func (m *Manager) checkRuntimeMemory(ctx context.Context, request ModelCreateRequest) error {
+	if !memory.RuntimeMemoryCheckEnabled() || request.IgnoreRuntimeMemoryCheck {
+		return nil
+	}
+	m.log.Infof("Will estimate memory required for %q", request.From)
+	proceed, req, totalMem, err := m.memoryEstimator.HaveSufficientMemoryForModel(ctx, request.From, nil)
+	if err != nil {
+		m.log.Warnf("Failed to validate sufficient system memory for model %q: %s", request.From, err)
+		// Prefer staying functional in case of unexpected estimation errors.
+		return nil
+	}
+	if !proceed {
+		errstr := fmt.Sprintf("Runtime memory requirement for model %q exceeds total system memory: required %d RAM %d VRAM, system %d RAM %d VRAM", request.From, req.RAM, req.VRAM, totalMem.RAM, totalMem.VRAM)
+		m.log.Warnf(errstr)
+		return errors.New(errstr)
+	}
+	return nil
+}
+
+// writeDiskSpaceError responds with 507 Insufficient Storage and err as JSON,
+// along with a message for display, so that clients can tell how much space
+// a pull requires.
+func writeDiskSpaceError(w http.ResponseWriter, err *distribution.DiskSpaceError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInsufficientStorage)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		*distribution.DiskSpaceError
+	}{"insufficient_disk_space", err.Error(), err})
+}
+
+// pullOptions returns the options with which the model of request is pulled.
+func pullOptions(request ModelCreateRequest) []distribution.PullOption {
+	opts := []distribution.PullOption{distribution.WithAcceptLicense(request.AcceptLicense)}
+	if request.Store != "" {
+		opts = append(opts, distribution.WithPullStore(request.Store))
+	}
+	return opts
+}
+
+// handlePullBatch handles POST <inference-prefix>/models/pull-batch requests.
+// The models are pulled concurrently, sharing the limit on concurrent pulls
+// with other pulls, and their progress messages are interleaved in a single
+// JSON stream, each tagged with the reference of its model in a "ref" field.
+// The stream ends with a ModelPullBatchResponse.
+func (m *Manager) handlePullBatch(w http.ResponseWriter, r *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request ModelPullBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(request.Models) == 0 {
+		http.Error(w, "no models to pull", http.StatusBadRequest)
+		return
+	}
+	var models []ModelCreateRequest
+	seen := make(map[string]bool)
+	for _, model := range request.Models {
+		if model.From == "" {
+			http.Error(w, "model reference is required", http.StatusBadRequest)
+			return
+		}
+		model.From = NormalizeModelName(model.From)
+		if !seen[model.From] {
+			seen[model.From] = true
+			models = append(models, model)
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.Header().Set("Content-Type", "application/json")
+	out := &progressResponseWriter{writer: w, flusher: flusher, isJSON: true}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]ModelPullResult, len(models))
+	for i, model := range models {
+		results[i].Ref = model.From
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			progressWriter := &refWriter{ref: model.From, mu: &mu, writer: out}
+			if err := m.pullBatched(r.Context(), model, progressWriter); err != nil {
+				m.log.Warnf("Failed to pull model %q: %v", utils.SanitizeForLog(model.From), err)
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	response := ModelPullBatchResponse{Type: "success", Results: results}
+	var failed int
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	response.Message = fmt.Sprintf("Pulled %d of %d models", len(results)-failed, len(results))
+	if failed > 0 {
+		response.Type = "error"
+	}
+	if err := json.NewEncoder(out).Encode(response); err != nil {
+		m.log.Warnln("Error while encoding batch pull response:", err)
+	}
+}
+
+// pullBatched pulls the model of request, as one pull of a batch, reporting
+// progress to w.
+func (m *Manager) pullBatched(ctx context.Context, request ModelCreateRequest, w io.Writer) error {
+	if err := m.checkRuntimeMemory(ctx, request); err != nil {
+		return err
+	}
+
+	// Restrict model pull concurrency.
+	select {
+	case <-m.pullTokens:
+	case <-ctx.Done():
+		return context.Canceled
+	}
+	defer func() {
+		m.pullTokens <- struct{}{}
+	}()
+
+	m.log.Infoln("Pulling model:", utils.SanitizeForLog(request.From))
+	return m.distributionClient.PullModel(ctx, request.From, w, pullOptions(request)...)
+}
+
 // handleLoadModel handles POST <inference-prefix>/models/load requests.
 func (m *Manager) handleLoadModel(w http.ResponseWriter, r *http.Request) {
 	if m.distributionClient == nil {
@@ -257,25 +521,44 @@ func (m *Manager) handleLoadModel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if _, err := m.distributionClient.LoadModel(r.Body, w); err != nil {
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	return
 }
 
-// handleGetModels handles GET <inference-prefix>/models requests.
+// handleGetModels handles GET <inference-prefix>/models requests. If a since
+// query parameter is provided, only the models changed since that cursor are
+// returned. Otherwise, models can be selected with filter query parameters,
+// such as filter=label=team=nlp, which must all match.
 func (m *Manager) handleGetModels(w http.ResponseWriter, r *http.Request) {
 	if m.distributionClient == nil {
 		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
+	if r.URL.Query().Has("since") {
+		m.handleGetModelChanges(w, r.URL.Query().Get("since"))
+		return
+	}
+
+	filters, err := distribution.ParseModelFilters(r.URL.Query()["filter"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Query models.
 	models, err := m.distributionClient.ListModels()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	models = distribution.FilterModels(models, filters)
 
 	apiModels := make([]*Model, len(models))
 	for i, model := range models {
@@ -293,6 +576,43 @@ func (m *Manager) handleGetModels(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGetModelChanges handles GET <inference-prefix>/models?since=<cursor>
+// requests.
+func (m *Manager) handleGetModelChanges(w http.ResponseWriter, cursor string) {
+	changes, err := m.distributionClient.ListModelChanges(cursor)
+	if err != nil {
+		if errors.Is(err, distribution.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := ModelChangesResponse{
+		Cursor:  changes.Cursor,
+		Reset:   changes.Reset,
+		Models:  make([]*Model, len(changes.Models)),
+		Deleted: changes.Deleted,
+	}
+	if response.Deleted == nil {
+		response.Deleted = []string{}
+	}
+	for i, model := range changes.Models {
+		response.Models[i], err = ToModel(model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.log.Warnln("Error while encoding model changes response:", err)
+	}
+}
+
 // handleGetModel handles GET <inference-prefix>/models/{name} requests.
 func (m *Manager) handleGetModel(w http.ResponseWriter, r *http.Request) {
 	// Normalize model name
@@ -308,6 +628,16 @@ func (m *Manager) handleGetModel(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse estimate query parameter
+	estimate := false
+	if r.URL.Query().Has("estimate") {
+		if val, err := strconv.ParseBool(r.URL.Query().Get("estimate")); err != nil {
+			m.log.Warnln("Error while parsing estimate query parameter:", err)
+		} else {
+			estimate = val
+		}
+	}
+
 	if remote && m.registryClient == nil {
 		http.Error(w, "registry client unavailable", http.StatusServiceUnavailable)
 		return
@@ -327,11 +657,26 @@ func (m *Manager) handleGetModel(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, distribution.ErrDigestRequired) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Break down the memory the model requires, if requested.
+	if estimate {
+		memoryEstimate, err := m.memoryEstimator.EstimateMemoryForModel(r.Context(), modelName, nil)
+		if err != nil {
+			m.log.Warnf("Failed to estimate memory for model %q: %v", utils.SanitizeForLog(modelName), err)
+			http.Error(w, fmt.Sprintf("unable to estimate memory: %v", err), http.StatusInternalServerError)
+			return
+		}
+		apiModel.Estimate = &memoryEstimate
+	}
+
 	// Write the response.
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(apiModel); err != nil {
@@ -339,6 +684,101 @@ func (m *Manager) handleGetModel(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleSearchModels handles GET <inference-prefix>/models/search requests.
+// The query parameters are:
+// - q: the text to search for in model names and descriptions (required)
+// - source: the source to search, one of dockerhub (the default), huggingface
+// or all
+// - limit: the maximum number of results
+func (m *Manager) handleSearchModels(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "missing q query parameter", http.StatusBadRequest)
+		return
+	}
+
+	var sources []string
+	switch source := r.URL.Query().Get("source"); source {
+	case "":
+		sources = []string{search.DockerHubName}
+	case "all":
+	default:
+		sources = []string{source}
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := m.searcher.Search(r.Context(), query, limit, sources...)
+	if errors.Is(err, search.ErrUnknownSource) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if results == nil {
+		m.log.Warnf("Failed to search models for %q: %v", utils.SanitizeForLog(query), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	response := ModelSearchResponse{Results: results}
+	if err != nil {
+		m.log.Warnf("Failed to search some sources for %q: %v", utils.SanitizeForLog(query), err)
+		response.Errors = []string{err.Error()}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			response.Errors = nil
+			for _, err := range joined.Unwrap() {
+				response.Errors = append(response.Errors, err.Error())
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.log.Warnln("Error while encoding model search response:", err)
+	}
+}
+
+// RunnerSettings returns the runner settings kept in the store.
+func (m *Manager) RunnerSettings() ([]distribution.RunnerSettings, error) {
+	if m.distributionClient == nil {
+		return nil, errors.New("model distribution service unavailable")
+	}
+	return m.distributionClient.RunnerSettings()
+}
+
+// SetRunnerSettings keeps the runner settings of a model in the store, so that
+// they survive restarts.
+func (m *Manager) SetRunnerSettings(settings distribution.RunnerSettings) error {
+	if m.distributionClient == nil {
+		return errors.New("model distribution service unavailable")
+	}
+	return m.distributionClient.SetRunnerSettings(settings)
+}
+
+// MarkModelUsed records that the model with the given reference was used.
+func (m *Manager) MarkModelUsed(ref string) error {
+	if m.distributionClient == nil {
+		return errors.New("model distribution service unavailable")
+	}
+	return m.distributionClient.MarkModelUsed(ref)
+}
+
+// StoreGeneration returns a counter of the changes made to the models, tags
+// and aliases of the store, so that callers can cache what they derive from
+// them until it changes.
+func (m *Manager) StoreGeneration() uint64 {
+	if m.distributionClient == nil {
+		return 0
+	}
+	return m.distributionClient.StoreGeneration()
+}
+
 // ResolveModelID resolves a model reference to a model ID. If resolution fails, it returns the original ref.
 func (m *Manager) ResolveModelID(modelRef string) string {
 	// Sanitize modelRef to prevent log forgery
@@ -371,7 +811,16 @@ func getLocalModel(m *Manager, name string) (*Model, error) {
 		return nil, err
 	}
 
-	return ToModel(model)
+	apiModel, err := ToModel(model)
+	if err != nil {
+		return nil, err
+	}
+	licenses, err := model.Licenses()
+	if err != nil {
+		return nil, fmt.Errorf("get licenses: %w", err)
+	}
+	apiModel.Licenses = append(apiModel.Licenses, licenses...)
+	return apiModel, nil
 }
 
 func getRemoteModel(ctx context.Context, m *Manager, name string) (*Model, error) {
@@ -380,7 +829,11 @@ func getRemoteModel(ctx context.Context, m *Manager, name string) (*Model, error
 	}
 
 	m.log.Infoln("Getting remote model:", name)
-	model, err := m.registryClient.Model(ctx, name)
+	ref, err := resolver.Resolve(ctx, m.nameResolver, name)
+	if err != nil {
+		return nil, err
+	}
+	model, err := m.registryClient.Model(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
@@ -450,6 +903,10 @@ func (m *Manager) handleDeleteModel(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		m.log.Warnln("Error while deleting model:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -523,20 +980,23 @@ func (m *Manager) handleOpenAIGetModel(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleTagModel handles POST <inference-prefix>/models/{nameAndAction} requests.
+// handleTagModel handles POST and PATCH <inference-prefix>/models/{nameAndAction} requests.
 // Action is one of:
 // - tag: tag the model with a repository and tag (e.g. POST <inference-prefix>/models/my-org/my-repo:latest/tag})
 // - push: pushes a tagged model to the registry
+// - config: creates a variant of the model with a new runtime configuration (PATCH only)
 func (m *Manager) handleModelAction(w http.ResponseWriter, r *http.Request) {
 	model, action := path.Split(r.PathValue("nameAndAction"))
 	model = strings.TrimRight(model, "/")
 	// Normalize model name
 	model = NormalizeModelName(model)
-	switch action {
-	case "tag":
+	switch {
+	case r.Method == http.MethodPost && action == "tag":
 		m.handleTagModel(w, r, model)
-	case "push":
+	case r.Method == http.MethodPost && action == "push":
 		m.handlePushModel(w, r, model)
+	case r.Method == http.MethodPatch && action == "config":
+		m.handleConfigureModel(w, r, model)
 	default:
 		http.Error(w, fmt.Sprintf("unknown action %q", action), http.StatusNotFound)
 	}
@@ -573,6 +1033,10 @@ func (m *Manager) handleTagModel(w http.ResponseWriter, r *http.Request, model s
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -612,6 +1076,205 @@ func (m *Manager) handlePushModel(w http.ResponseWriter, r *http.Request, model
 	}
 }
 
+// handleConfigureModel handles PATCH <inference-prefix>/models/{name}/config
+// requests. It creates a lightweight variant of the model with the requested
+// chat template, context size and runtime flags, without copying its weights. The variant is
+// tagged with the requested tag, or otherwise replaces the model under its
+// name.
+func (m *Manager) handleConfigureModel(w http.ResponseWriter, r *http.Request, model string) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request ModelConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if request.ChatTemplate == nil && request.ContextSize == nil && request.RuntimeFlags == nil {
+		http.Error(w, "no configuration changes requested", http.StatusBadRequest)
+		return
+	}
+	if request.RuntimeFlags != nil {
+		if err := inference.ValidatePersistentRuntimeFlags(*request.RuntimeFlags); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Tag the variant with the requested tag, or with the model name unless
+	// the model was referenced by ID.
+	var tags []string
+	if request.Tag != "" {
+		tags = []string{NormalizeModelName(request.Tag)}
+	} else if !strings.HasPrefix(model, "sha256:") {
+		tags = []string{model}
+	}
+
+	id, err := m.distributionClient.ConfigureModel(model, distribution.ModelConfigUpdate{
+		ChatTemplate: request.ChatTemplate,
+		ContextSize:  request.ContextSize,
+		RuntimeFlags: request.RuntimeFlags,
+	}, tags)
+	if err != nil {
+		if errors.Is(err, distribution.ErrModelNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		m.log.Warnf("Failed to configure model %q: %v", model, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	variant, err := m.GetModel(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	apiModel, err := ToModel(variant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(apiModel); err != nil {
+		m.log.Warnln("Error while encoding model response:", err)
+	}
+}
+
+// handleDedupStats handles GET <inference-prefix>/models/_dedup-stats requests.
+// It reports how blobs are shared between models in the store.
+func (m *Manager) handleDedupStats(w http.ResponseWriter, _ *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats, err := m.distributionClient.DedupStats()
+	if err != nil {
+		m.log.Warnf("Failed to compute dedup stats: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		m.log.Warnln("Error while encoding dedup stats response:", err)
+	}
+}
+
+// handleGetAliases handles GET <inference-prefix>/models/_aliases requests,
+// returning the aliases sorted by name.
+func (m *Manager) handleGetAliases(w http.ResponseWriter, _ *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	aliases, err := m.distributionClient.Aliases()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := make([]ModelAlias, 0, len(aliases))
+	for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+		response = append(response, ModelAlias{Alias: alias, Model: aliases[alias]})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.log.Warnln("Error while encoding aliases response:", err)
+	}
+}
+
+// handleSetAlias handles PUT <inference-prefix>/models/_aliases/{alias}
+// requests, pointing the alias at the model in the request body.
+func (m *Manager) handleSetAlias(w http.ResponseWriter, r *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request ModelAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Model == "" {
+		http.Error(w, "model is required", http.StatusBadRequest)
+		return
+	}
+
+	alias := NormalizeModelName(r.PathValue("alias"))
+	model := NormalizeModelName(request.Model)
+	if err := m.distributionClient.SetAlias(alias, model); err != nil {
+		if errors.Is(err, distribution.ErrModelNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, distribution.ErrInvalidAlias) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		m.log.Warnf("Failed to set alias %q: %v", utils.SanitizeForLog(alias), err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ModelAlias{Alias: alias, Model: model}); err != nil {
+		m.log.Warnln("Error while encoding alias response:", err)
+	}
+}
+
+// handleRemoveAlias handles DELETE <inference-prefix>/models/_aliases/{alias}
+// requests.
+func (m *Manager) handleRemoveAlias(w http.ResponseWriter, r *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	alias := NormalizeModelName(r.PathValue("alias"))
+	if err := m.distributionClient.RemoveAlias(alias); err != nil {
+		if errors.Is(err, distribution.ErrAliasNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		m.log.Warnf("Failed to remove alias %q: %v", utils.SanitizeForLog(alias), err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRateLimits handles GET <inference-prefix>/models/ratelimit requests,
+// returning the pull quotas last reported by registries.
+func (m *Manager) handleRateLimits(w http.ResponseWriter, _ *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.distributionClient.RateLimits()); err != nil {
+		m.log.Warnln("Error while encoding rate limits response:", err)
+	}
+}
+
 // handlePurge handles DELETE <inference-prefix>/models/purge requests.
 func (m *Manager) handlePurge(w http.ResponseWriter, _ *http.Request) {
 	if m.distributionClient == nil {
@@ -620,12 +1283,68 @@ func (m *Manager) handlePurge(w http.ResponseWriter, _ *http.Request) {
 	}
 
 	if err := m.distributionClient.ResetStore(); err != nil {
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		m.log.Warnf("Failed to purge models: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
+// handlePrune handles POST <inference-prefix>/models/prune requests.
+func (m *Manager) handlePrune(w http.ResponseWriter, r *http.Request) {
+	if m.distributionClient == nil {
+		http.Error(w, "model distribution service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request ModelPruneRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.KeepLast == nil {
+		http.Error(w, "keep-last is required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := m.distributionClient.Prune(distribution.PrunePolicy{
+		KeepLast:     *request.KeepLast,
+		KeepTags:     request.KeepTags,
+		Repositories: request.Repositories,
+		DryRun:       request.DryRun,
+	})
+	if err != nil {
+		if errors.Is(err, distribution.ErrInvalidPrunePolicy) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, distribution.ErrReadOnlyStore) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		m.log.Warnf("Failed to prune models: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		m.log.Warnln("Error while encoding prune response:", err)
+	}
+}
+
+// StorePath returns the root path of the model store, or an error if the
+// store couldn't be opened.
+func (m *Manager) StorePath() (string, error) {
+	if m.distributionClient == nil {
+		return "", errors.New("model distribution service unavailable")
+	}
+	return m.distributionClient.GetStorePath(), nil
+}
+
 // GetDiskUsage returns the disk usage of the model store.
 func (m *Manager) GetDiskUsage() (int64, error, int) {
 	if m.distributionClient == nil {
@@ -643,9 +1362,12 @@ func (m *Manager) GetDiskUsage() (int64, error, int) {
 
 // ServeHTTP implement net/http.Handler.ServeHTTP.
 func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Release the lock before serving, so that rebuilding routes doesn't wait
+	// for long-running requests.
 	m.lock.RLock()
-	defer m.lock.RUnlock()
-	m.httpHandler.ServeHTTP(w, r)
+	handler := m.httpHandler
+	m.lock.RUnlock()
+	handler.ServeHTTP(w, r)
 }
 
 // IsModelInStore checks if a given model is in the local store.
@@ -653,17 +1375,38 @@ func (m *Manager) IsModelInStore(ref string) (bool, error) {
 	return m.distributionClient.IsModelInStore(ref)
 }
 
-// GetModel returns a single model.
+// GetModel returns a single model. Aliases are kept under normalized names,
+// so a reference that matches no model is also looked up as an alias once
+// normalized, e.g. for the model field of OpenAI requests.
 func (m *Manager) GetModel(ref string) (types.Model, error) {
 	model, err := m.distributionClient.GetModel(ref)
+	if errors.Is(err, distribution.ErrModelNotFound) {
+		if alias := NormalizeModelName(ref); alias != ref && m.isAlias(alias) {
+			model, err = m.distributionClient.GetModel(alias)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error while getting model: %w", err)
 	}
 	return model, err
 }
 
+// isAlias reports whether an alias with the given name exists.
+func (m *Manager) isAlias(name string) bool {
+	aliases, err := m.distributionClient.Aliases()
+	if err != nil {
+		return false
+	}
+	_, ok := aliases[name]
+	return ok
+}
+
 // GetRemoteModel returns a single remote model.
 func (m *Manager) GetRemoteModel(ctx context.Context, ref string) (types.ModelArtifact, error) {
+	ref, err := resolver.Resolve(ctx, m.nameResolver, ref)
+	if err != nil {
+		return nil, err
+	}
 	model, err := m.registryClient.Model(ctx, ref)
 	if err != nil {
 		return nil, fmt.Errorf("error while getting remote model: %w", err)
@@ -673,6 +1416,10 @@ func (m *Manager) GetRemoteModel(ctx context.Context, ref string) (types.ModelAr
 
 // GetRemoteModelBlobURL returns the URL of a given model blob.
 func (m *Manager) GetRemoteModelBlobURL(ref string, digest v1.Hash) (string, error) {
+	ref, err := resolver.Resolve(context.Background(), m.nameResolver, ref)
+	if err != nil {
+		return "", err
+	}
 	blobURL, err := m.registryClient.BlobURL(ref, digest)
 	if err != nil {
 		return "", fmt.Errorf("error while getting remote model blob URL: %w", err)
@@ -682,6 +1429,10 @@ func (m *Manager) GetRemoteModelBlobURL(ref string, digest v1.Hash) (string, err
 
 // BearerTokenForModel returns the bearer token needed to pull a given model.
 func (m *Manager) BearerTokenForModel(ctx context.Context, ref string) (string, error) {
+	ref, err := resolver.Resolve(ctx, m.nameResolver, ref)
+	if err != nil {
+		return "", err
+	}
 	tok, err := m.registryClient.BearerToken(ctx, ref)
 	if err != nil {
 		return "", fmt.Errorf("error while getting bearer token for model: %w", err)
@@ -698,9 +1449,67 @@ func (m *Manager) GetBundle(ref string) (types.ModelBundle, error) {
 	return bundle, err
 }
 
+// AcquireBundle returns the model bundle and holds it until release is
+// called, so that deleting the model while a backend runs it only removes the
+// bundle once the backend exits.
+func (m *Manager) AcquireBundle(ref string) (types.ModelBundle, func(), error) {
+	bundle, release, err := m.distributionClient.AcquireBundle(ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting model bundle: %w", err)
+	}
+	return bundle, release, nil
+}
+
+// EnsureModel pulls a model to local storage if it isn't already there,
+// without reporting progress.
+func (m *Manager) EnsureModel(ctx context.Context, model string) error {
+	if m.distributionClient == nil {
+		return errors.New("model distribution service unavailable")
+	}
+	if inStore, err := m.distributionClient.IsModelInStore(model); err == nil && inStore {
+		return nil
+	}
+
+	// Restrict model pull concurrency.
+	select {
+	case <-m.pullTokens:
+	case <-ctx.Done():
+		return context.Canceled
+	}
+	defer func() {
+		m.pullTokens <- struct{}{}
+	}()
+
+	m.log.Infoln("Pulling model:", utils.SanitizeForLog(model))
+	if err := m.distributionClient.PullModel(ctx, model, io.Discard); err != nil {
+		return fmt.Errorf("error while pulling model: %w", err)
+	}
+	return nil
+}
+
 // PullModel pulls a model to local storage. Any error it returns is suitable
-// for writing back to the client.
-func (m *Manager) PullModel(model string, r *http.Request, w http.ResponseWriter) error {
+// for writing back to the client. If r accepts text/event-stream, progress is
+// streamed as server-sent events, as by streamProgress.
+func (m *Manager) PullModel(model string, r *http.Request, w http.ResponseWriter, opts ...distribution.PullOption) error {
+	if isEventStream(r) {
+		return m.streamProgress(w, r, "pull "+model, func(ctx context.Context, w io.Writer) error {
+			select {
+			case <-m.pullTokens:
+			case <-ctx.Done():
+				return context.Canceled
+			}
+			defer func() {
+				m.pullTokens <- struct{}{}
+			}()
+
+			m.log.Infoln("Pulling model:", model)
+			if err := m.distributionClient.PullModel(ctx, model, w, opts...); err != nil {
+				return fmt.Errorf("error while pulling model: %w", err)
+			}
+			return nil
+		})
+	}
+
 	// Restrict model pull concurrency.
 	select {
 	case <-m.pullTokens:
@@ -742,7 +1551,7 @@ func (m *Manager) PullModel(model string, r *http.Request, w http.ResponseWriter
 
 	// Pull the model using the Docker model distribution client
 	m.log.Infoln("Pulling model:", model)
-	err := m.distributionClient.PullModel(r.Context(), model, progressWriter)
+	err := m.distributionClient.PullModel(r.Context(), model, progressWriter, opts...)
 	if err != nil {
 		return fmt.Errorf("error while pulling model: %w", err)
 	}
@@ -750,8 +1559,20 @@ func (m *Manager) PullModel(model string, r *http.Request, w http.ResponseWriter
 	return nil
 }
 
-// PushModel pushes a model from the store to the registry.
+// PushModel pushes a model from the store to the registry. If r accepts
+// text/event-stream, progress is streamed as server-sent events, as by
+// streamProgress.
 func (m *Manager) PushModel(model string, r *http.Request, w http.ResponseWriter) error {
+	if isEventStream(r) {
+		return m.streamProgress(w, r, "push "+model, func(ctx context.Context, w io.Writer) error {
+			m.log.Infoln("Pushing model:", model)
+			if err := m.distributionClient.PushModel(ctx, model, w); err != nil {
+				return fmt.Errorf("error while pushing model: %w", err)
+			}
+			return nil
+		})
+	}
+
 	// Set up response headers for streaming
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -818,3 +1639,43 @@ func (w *progressResponseWriter) Write(p []byte) (n int, err error) {
 	}
 	return n, nil
 }
+
+// refWriter tags the JSON progress messages of one pull of a batch with the
+// reference of its model, and writes them to a stream shared by the other
+// pulls of the batch.
+type refWriter struct {
+	ref    string
+	mu     *sync.Mutex
+	writer io.Writer
+	// buf holds an incomplete message, up to its terminating newline.
+	buf []byte
+}
+
+func (w *refWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		line, rest, ok := bytes.Cut(w.buf, []byte("\n"))
+		if !ok {
+			return len(p), nil
+		}
+		w.buf = rest
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return 0, fmt.Errorf("invalid progress message: %w", err)
+		}
+		ref, err := json.Marshal(w.ref)
+		if err != nil {
+			return 0, err
+		}
+		msg["ref"] = ref
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := w.writer.Write(append(data, '\n')); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -3,19 +3,24 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/registry"
 
 	"github.com/docker/model-runner/pkg/distribution/builder"
+	"github.com/docker/model-runner/pkg/distribution/distribution"
 	reg "github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/transport/ratelimit"
 	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/memory"
 
@@ -34,6 +39,10 @@ func (me *mockMemoryEstimator) HaveSufficientMemoryForModel(_ context.Context, _
 	return true, inference.RequiredMemory{}, inference.RequiredMemory{}, nil
 }
 
+func (me *mockMemoryEstimator) EstimateMemoryForModel(_ context.Context, model string, _ *inference.BackendConfiguration) (inference.MemoryEstimate, error) {
+	return inference.MemoryEstimate{Backend: "mock", Architecture: "llama", Fits: true}, nil
+}
+
 // getProjectRoot returns the absolute path to the project root directory
 func getProjectRoot(t *testing.T) string {
 	// Start from the current test file's directory
@@ -55,6 +64,97 @@ func getProjectRoot(t *testing.T) string {
 	}
 }
 
+// testManagerConfig configures newTestManager.
+type testManagerConfig struct {
+	// tags are the tags, relative to the registry host, that the model is
+	// pushed to.
+	tags []string
+	// build modifies the model before it's pushed.
+	build func(*builder.Builder) (*builder.Builder, error)
+	// handler wraps the registry's handler.
+	handler func(http.Handler) http.Handler
+	// client is the configuration of the manager's client, whose store and
+	// logger are set by newTestManager.
+	client ClientConfig
+}
+
+// testManagerOption is an option of newTestManager.
+type testManagerOption func(*testManagerConfig)
+
+// withTags pushes the model to the given tags rather than ai/model:v1.0.0.
+func withTags(tags ...string) testManagerOption {
+	return func(c *testManagerConfig) {
+		c.tags = tags
+	}
+}
+
+// withBuild modifies the model before it's pushed.
+func withBuild(build func(*builder.Builder) (*builder.Builder, error)) testManagerOption {
+	return func(c *testManagerConfig) {
+		c.build = build
+	}
+}
+
+// withRegistryHandler wraps the registry's handler.
+func withRegistryHandler(handler func(http.Handler) http.Handler) testManagerOption {
+	return func(c *testManagerConfig) {
+		c.handler = handler
+	}
+}
+
+// withClientConfig sets the configuration of the manager's client.
+func withClientConfig(config ClientConfig) testManagerOption {
+	return func(c *testManagerConfig) {
+		c.client = config
+	}
+}
+
+// newTestManager starts a registry to which the dummy GGUF model is pushed,
+// under ai/model:v1.0.0 by default, and returns a manager with an empty store
+// along with the registry's host.
+func newTestManager(t *testing.T, opts ...testManagerOption) (*Manager, string) {
+	t.Helper()
+	config := testManagerConfig{tags: []string{"ai/model:v1.0.0"}}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var handler http.Handler = registry.New()
+	if config.handler != nil {
+		handler = config.handler(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	model, err := builder.FromGGUF(filepath.Join(getProjectRoot(t), "assets", "dummy.gguf"))
+	if err != nil {
+		t.Fatalf("Failed to create model builder: %v", err)
+	}
+	if config.build != nil {
+		if model, err = config.build(model); err != nil {
+			t.Fatalf("Failed to modify model: %v", err)
+		}
+	}
+	for _, tag := range config.tags {
+		target, err := reg.NewClient().NewTarget(uri.Host + "/" + tag)
+		if err != nil {
+			t.Fatalf("Failed to create model target: %v", err)
+		}
+		if err := model.Build(context.Background(), target, io.Discard); err != nil {
+			t.Fatalf("Failed to build model: %v", err)
+		}
+	}
+
+	log := logrus.NewEntry(logrus.StandardLogger())
+	config.client.StoreRootPath = t.TempDir()
+	config.client.Logger = log.WithFields(logrus.Fields{"component": "model-manager"})
+	return NewManager(log, config.client, nil, &mockMemoryEstimator{}), uri.Host
+}
+
 func TestPullModel(t *testing.T) {
 
 	// Create temp directory for store
@@ -199,6 +299,7 @@ func TestHandleGetModel(t *testing.T) {
 	tests := []struct {
 		name          string
 		remote        bool
+		estimate      bool
 		modelName     string
 		expectedCode  int
 		expectedError string
@@ -209,6 +310,13 @@ func TestHandleGetModel(t *testing.T) {
 			modelName:    tag,
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:         "get local model with estimate - success",
+			remote:       false,
+			estimate:     true,
+			modelName:    tag,
+			expectedCode: http.StatusOK,
+		},
 		{
 			name:          "get local model - not found",
 			remote:        false,
@@ -256,6 +364,8 @@ func TestHandleGetModel(t *testing.T) {
 			path := inference.ModelsPrefix + "/" + tt.modelName
 			if tt.remote {
 				path += "?remote=true"
+			} else if tt.estimate {
+				path += "?estimate=true"
 			}
 			r := httptest.NewRequest("GET", path, nil)
 			w := httptest.NewRecorder()
@@ -281,6 +391,9 @@ func TestHandleGetModel(t *testing.T) {
 				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 					t.Errorf("Failed to decode response body: %v", err)
 				}
+				if (response.Estimate != nil) != tt.estimate {
+					t.Errorf("Expected estimate %v, got %+v", tt.estimate, response.Estimate)
+				}
 			}
 
 			// Clean tempDir after each test
@@ -331,3 +444,475 @@ func TestCors(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleDedupStats(t *testing.T) {
+	log := logrus.NewEntry(logrus.StandardLogger())
+	m := NewManager(log, ClientConfig{
+		StoreRootPath: t.TempDir(),
+		Logger:        log.WithFields(logrus.Fields{"component": "model-manager"}),
+		Transport:     http.DefaultTransport,
+		UserAgent:     "test-agent",
+	}, nil, &mockMemoryEstimator{})
+
+	// Route through the manager to make sure the request isn't treated as a
+	// lookup of a model named "_dedup-stats".
+	r := httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+"/_dedup-stats", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var stats distribution.DedupStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if stats.Models != 0 || stats.PhysicalBytes != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
+
+func TestHandleConfigureModel(t *testing.T) {
+	m, host := newTestManager(t)
+	tag := host + "/ai/model:v1.0.0"
+	if err := m.PullModel(tag, httptest.NewRequest(http.MethodPost, "/models/create", nil), httptest.NewRecorder()); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+	original, err := m.GetModel(tag)
+	if err != nil {
+		t.Fatalf("Failed to get model: %v", err)
+	}
+	originalID, err := original.ID()
+	if err != nil {
+		t.Fatalf("Failed to get model ID: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "no changes", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "set template and context size", body: `{"chat-template": "{{ messages }}", "context-size": 4096}`, expectedStatus: http.StatusOK},
+		{name: "replace template", body: `{"chat-template": "{{ prompt }}"}`, expectedStatus: http.StatusOK},
+		{name: "disallowed runtime flag", body: `{"runtime-flags": ["--model", "/tmp/other.gguf"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "set runtime flags", body: `{"runtime-flags": ["--flash-attn", "on", "--parallel", "2"]}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, inference.ModelsPrefix+"/"+tag+"/config", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The tag now points to the variant, with the latest template and the
+	// context size and runtime flags carried over from earlier changes.
+	variant, err := m.GetModel(tag)
+	if err != nil {
+		t.Fatalf("Failed to get model variant: %v", err)
+	}
+	if id, _ := variant.ID(); id == originalID {
+		t.Fatal("Expected tag to point to a new variant")
+	}
+	cfg, err := variant.Config()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cfg.ContextSize == nil || *cfg.ContextSize != 4096 {
+		t.Errorf("Expected context size 4096, got %v", cfg.ContextSize)
+	}
+	if !slices.Equal(cfg.RuntimeFlags, []string{"--flash-attn", "on", "--parallel", "2"}) {
+		t.Errorf("Expected persisted runtime flags, got %v", cfg.RuntimeFlags)
+	}
+	templatePath, err := variant.ChatTemplatePath()
+	if err != nil {
+		t.Fatalf("Failed to get chat template path: %v", err)
+	}
+	template, err := os.ReadFile(templatePath)
+	if err != nil {
+		t.Fatalf("Failed to read chat template: %v", err)
+	}
+	if string(template) != "{{ prompt }}" {
+		t.Errorf("Expected replaced chat template, got %q", template)
+	}
+
+	// The original model is still available by ID.
+	if _, err := m.GetModel(originalID); err != nil {
+		t.Errorf("Expected original model to remain in store: %v", err)
+	}
+
+	// Unknown models are reported as not found.
+	r := httptest.NewRequest(http.MethodPatch, inference.ModelsPrefix+"/ai/missing/config", strings.NewReader(`{"context-size": 1024}`))
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleGetModelChanges(t *testing.T) {
+	m, host := newTestManager(t)
+	tag := host + "/ai/model:v1.0.0"
+
+	getChanges := func(t *testing.T, cursor string) (int, ModelChangesResponse) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+"?since="+url.QueryEscape(cursor), nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		var response ModelChangesResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w.Code, response
+	}
+
+	code, initial := getChanges(t, "")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if len(initial.Models) != 0 || initial.Cursor == "" {
+		t.Fatalf("Expected empty listing with cursor, got %+v", initial)
+	}
+
+	if err := m.PullModel(tag, httptest.NewRequest(http.MethodPost, "/models/create", nil), httptest.NewRecorder()); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+	_, pulled := getChanges(t, initial.Cursor)
+	if len(pulled.Models) != 1 || len(pulled.Deleted) != 0 {
+		t.Fatalf("Expected pulled model, got %+v", pulled)
+	}
+
+	if _, err := m.distributionClient.DeleteModel(tag, true); err != nil {
+		t.Fatalf("Failed to delete model: %v", err)
+	}
+	_, deleted := getChanges(t, pulled.Cursor)
+	if len(deleted.Models) != 0 || len(deleted.Deleted) != 1 || deleted.Deleted[0] != pulled.Models[0].ID {
+		t.Fatalf("Expected deleted model, got %+v", deleted)
+	}
+
+	if code, _ := getChanges(t, "malformed"); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for malformed cursor, got %d", http.StatusBadRequest, code)
+	}
+}
+
+func TestHandleCreateModelWithLicense(t *testing.T) {
+	licensePath := filepath.Join(getProjectRoot(t), "assets", "license.txt")
+	licenseText, err := os.ReadFile(licensePath)
+	if err != nil {
+		t.Fatalf("Failed to read license: %v", err)
+	}
+	m, host := newTestManager(t, withTags("ai/licensed:v1.0.0"), withBuild(func(model *builder.Builder) (*builder.Builder, error) {
+		model, err := model.WithLicense(licensePath)
+		if err != nil {
+			return nil, err
+		}
+		return model.WithLicenseAcceptanceRequired(), nil
+	}))
+	tag := host + "/ai/licensed:v1.0.0"
+
+	// Without accepting the license, the license is returned.
+	r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/create", strings.NewReader(`{"from": "`+tag+`"}`))
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusUnavailableForLegalReasons, w.Code, w.Body.String())
+	}
+	if strings.TrimSpace(w.Body.String()) != strings.TrimSpace(string(licenseText)) {
+		t.Errorf("Expected license text in response, got %q", w.Body.String())
+	}
+
+	// Accepting the license pulls the model.
+	r = httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/create", strings.NewReader(`{"from": "`+tag+`", "accept-license": true}`))
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// The license is exposed by the model endpoint.
+	r = httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+"/"+tag, nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var apiModel Model
+	if err := json.NewDecoder(w.Body).Decode(&apiModel); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(apiModel.Licenses) != 1 || apiModel.Licenses[0] != string(licenseText) {
+		t.Errorf("Expected model license %q, got %q", licenseText, apiModel.Licenses)
+	}
+	if !apiModel.Config.LicenseAcceptanceRequired {
+		t.Error("Expected config to require license acceptance")
+	}
+}
+
+func TestHandleGetModelsFilter(t *testing.T) {
+	m, host := newTestManager(t, withBuild(func(model *builder.Builder) (*builder.Builder, error) {
+		return model.WithAnnotation("team", "nlp")
+	}))
+	tag := host + "/ai/model:v1.0.0"
+	if err := m.PullModel(tag, httptest.NewRequest(http.MethodPost, "/models/create", nil), httptest.NewRecorder()); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+
+	for query, expected := range map[string]int{
+		"":                                       1,
+		"?filter=label%3Dteam":                   1,
+		"?filter=label%3Dteam%3Dnlp":             1,
+		"?filter=label%3Dteam%3Dcv":              0,
+		"?filter=label%3Dteam&filter=label%3Dcv": 0,
+	} {
+		r := httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+query, nil)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d for %q, got %d", http.StatusOK, query, w.Code)
+		}
+		var models []Model
+		if err := json.NewDecoder(w.Body).Decode(&models); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(models) != expected {
+			t.Errorf("Expected %d models for %q, got %d", expected, query, len(models))
+		}
+		if len(models) == 1 && models[0].Annotations["team"] != "nlp" {
+			t.Errorf("Expected the model's annotations to be listed, got %v", models[0].Annotations)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+"?filter=owner%3Dme", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid filter, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandleRequireDigest(t *testing.T) {
+	m, host := newTestManager(t, withClientConfig(ClientConfig{RequireDigest: true}))
+	tag := host + "/ai/model:v1.0.0"
+	remoteModel, err := reg.NewClient().Model(context.Background(), tag)
+	if err != nil {
+		t.Fatalf("Failed to read model: %v", err)
+	}
+	digest, err := remoteModel.Digest()
+	if err != nil {
+		t.Fatalf("Failed to get model digest: %v", err)
+	}
+	pinned := host + "/ai/model@" + digest.String()
+
+	for _, tt := range []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodPost, inference.ModelsPrefix + "/create", `{"from": "` + tag + `"}`, http.StatusBadRequest},
+		{http.MethodPost, inference.ModelsPrefix + "/create", `{"from": "` + pinned + `"}`, http.StatusOK},
+		{http.MethodGet, inference.ModelsPrefix + "/" + pinned, "", http.StatusOK},
+		{http.MethodGet, inference.ModelsPrefix + "/" + digest.String(), "", http.StatusOK},
+		{http.MethodGet, inference.ModelsPrefix + "/" + tag, "", http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != tt.expected {
+			t.Errorf("Expected status code %d for %s %s, got %d: %s", tt.expected, tt.method, tt.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandlePrune(t *testing.T) {
+	m, host := newTestManager(t, withTags("ai/model:nightly"))
+	tag := host + "/ai/model:nightly"
+	if err := m.PullModel(tag, httptest.NewRequest(http.MethodPost, "/models/create", nil), httptest.NewRecorder()); err != nil {
+		t.Fatalf("Failed to pull model: %v", err)
+	}
+
+	for body, expected := range map[string]int{
+		`{}`:                http.StatusBadRequest,
+		`{"keep-last": -1}`: http.StatusBadRequest,
+		`{"keep-last": 0, "keep-tags": ["nightly"]}`: http.StatusOK,
+	} {
+		r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/prune", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d for %s, got %d", expected, body, w.Code)
+		}
+	}
+	if _, err := m.GetModel(tag); err != nil {
+		t.Fatalf("Expected the kept tag to remain: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/prune", strings.NewReader(`{"keep-last": 0}`))
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var report distribution.PruneReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(report.Untagged) != 1 || len(report.Deleted) != 1 {
+		t.Errorf("Expected the tag and its model to be removed, got %+v", report)
+	}
+	if _, err := m.GetModel(tag); !errors.Is(err, distribution.ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
+
+func TestHandleRateLimits(t *testing.T) {
+	// The registry reports a quota on manifest requests, and rejects them
+	// once it's used up.
+	remaining := 1
+	m, host := newTestManager(t, withTags("ai/model:latest"), withRegistryHandler(func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+				w.Header().Set("RateLimit-Limit", "2;w=21600")
+				w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d;w=21600", remaining))
+				if remaining == 0 {
+					w.Header().Set("Retry-After", "3600")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				remaining--
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}))
+	tag := host + "/ai/model:latest"
+
+	// The first pull uses the last pull of the quota, and the second is
+	// rejected, as the limit resets later than pulls wait for.
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/create", strings.NewReader(`{"from": "`+tag+`"}`))
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d, got %d: %s", expected, w.Code, w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, inference.ModelsPrefix+"/ratelimit", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var quotas []ratelimit.Quota
+	if err := json.NewDecoder(w.Body).Decode(&quotas); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(quotas) != 1 || quotas[0].Registry != host || quotas[0].Limit != 2 || quotas[0].Remaining != 0 || !quotas[0].Limited {
+		t.Errorf("Unexpected quotas %+v", quotas)
+	}
+}
+
+func TestHandlePullBatch(t *testing.T) {
+	m, host := newTestManager(t, withTags("ai/model:a", "ai/model:b", "ai/model:c"))
+	tags := []string{host + "/ai/model:a", host + "/ai/model:b", host + "/ai/model:c"}
+	missing := host + "/ai/missing:latest"
+
+	for body, expected := range map[string]int{
+		`{}`:                         http.StatusBadRequest,
+		`{"models": [{"from": ""}]}`: http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/pull-batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d for %s, got %d", expected, body, w.Code)
+		}
+	}
+
+	request := ModelPullBatchRequest{Models: []ModelCreateRequest{{From: missing}}}
+	for _, tag := range append(tags, tags[0]) {
+		request.Models = append(request.Models, ModelCreateRequest{From: tag})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/pull-batch", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Every message but the last is tagged with its model.
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	succeeded := make(map[string]bool)
+	for _, line := range lines[:len(lines)-1] {
+		var msg struct {
+			Type string `json:"type"`
+			Ref  string `json:"ref"`
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("Failed to decode progress message %q: %v", line, err)
+		}
+		if msg.Ref == "" {
+			t.Errorf("Expected a ref in progress message %q", line)
+		}
+		if msg.Type == "success" {
+			succeeded[msg.Ref] = true
+		}
+	}
+	for _, tag := range tags {
+		if !succeeded[tag] {
+			t.Errorf("Expected a success message for %s", tag)
+		}
+	}
+
+	var response ModelPullBatchResponse
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Type != "error" || len(response.Results) != 4 {
+		t.Fatalf("Expected results for 4 models and an error, got %+v", response)
+	}
+	for _, result := range response.Results {
+		if (result.Error != "") != (result.Ref == missing) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+	for _, tag := range tags {
+		if _, err := m.GetModel(tag); err != nil {
+			t.Errorf("Expected %s to be pulled: %v", tag, err)
+		}
+	}
+}
+
+func TestHandleCreateModelInsufficientDiskSpace(t *testing.T) {
+	// No volume has an exabyte to spare.
+	m, host := newTestManager(t, withClientConfig(ClientConfig{DiskHeadroom: 1 << 60}))
+	tag := host + "/ai/model:v1.0.0"
+
+	r := httptest.NewRequest(http.MethodPost, inference.ModelsPrefix+"/create", strings.NewReader(`{"from": "`+tag+`"}`))
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusInsufficientStorage {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusInsufficientStorage, w.Code, w.Body.String())
+	}
+	var body struct {
+		Error     string `json:"error"`
+		Message   string `json:"message"`
+		Required  uint64 `json:"required"`
+		Available uint64 `json:"available"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Error != "insufficient_disk_space" || body.Message == "" || body.Required <= 1<<60 || body.Available >= body.Required {
+		t.Errorf("Unexpected response %+v", body)
+	}
+}
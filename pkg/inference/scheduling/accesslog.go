@@ -0,0 +1,221 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/metrics"
+)
+
+const (
+	// defaultAccessLogMaxSize is the default size beyond which the access log
+	// is rotated.
+	defaultAccessLogMaxSize = 100 * 1024 * 1024
+	// defaultAccessLogMaxFiles is the default number of rotated access logs
+	// that are kept.
+	defaultAccessLogMaxFiles = 5
+)
+
+// AccessLogConfig configures the access log of inference requests.
+type AccessLogConfig struct {
+	// Path is the path of the log file, which is created if necessary and
+	// appended to.
+	Path string
+	// MaxSize is the size in bytes beyond which the log is rotated. If zero,
+	// the log is rotated beyond 100 MiB.
+	MaxSize int64
+	// MaxFiles is the number of rotated logs that are kept, with the suffixes
+	// .1 (the most recent) to .MaxFiles. If zero, 5 are kept.
+	MaxFiles int
+	// Prompts enables logging the content of requests, which is redacted by
+	// default. It can be overridden for each model with a configure request.
+	Prompts bool
+}
+
+// accessLogEntry is a line of the access log.
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Model     string    `json:"model"`
+	Backend   string    `json:"backend"`
+	Mode      string    `json:"mode"`
+	Client    string    `json:"client,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	TokensIn  uint64    `json:"tokens_in"`
+	TokensOut uint64    `json:"tokens_out"`
+	// Request is the content of the request, if prompts are logged.
+	Request json.RawMessage `json:"request,omitempty"`
+}
+
+// accessLog is a JSON Lines log of inference requests with size-based
+// rotation.
+type accessLog struct {
+	// config is the access log configuration.
+	config AccessLogConfig
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// file is the current log file.
+	file *os.File
+	// size is the size of the current log file.
+	size int64
+	// prompts overrides whether prompts are logged for each model ID.
+	prompts map[string]bool
+}
+
+// openAccessLog opens the access log, applying defaults to its configuration.
+func openAccessLog(config AccessLogConfig) (*accessLog, error) {
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultAccessLogMaxSize
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = defaultAccessLogMaxFiles
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating access log directory: %w", err)
+	}
+	a := &accessLog{config: config, prompts: make(map[string]bool)}
+	if err := a.openLocked(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// openLocked opens the log file for appending. The caller must hold the lock.
+func (a *accessLog) openLocked() error {
+	// The log may contain prompts, so only the user may read it.
+	f, err := os.OpenFile(a.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening access log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening access log: %w", err)
+	}
+	a.file = f
+	a.size = info.Size()
+	return nil
+}
+
+// rotateLocked moves the log file to the .1 suffix, shifting older logs and
+// removing the oldest, and opens a new log file. The caller must hold the lock.
+func (a *accessLog) rotateLocked() error {
+	if err := a.file.Close(); err != nil {
+		return fmt.Errorf("closing access log: %w", err)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", a.config.Path, a.config.MaxFiles))
+	for i := a.config.MaxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.config.Path, i), fmt.Sprintf("%s.%d", a.config.Path, i+1))
+	}
+	if err := os.Rename(a.config.Path, a.config.Path+".1"); err != nil {
+		return fmt.Errorf("rotating access log: %w", err)
+	}
+	return a.openLocked()
+}
+
+// logsPrompts reports whether the content of requests for a model is logged.
+func (a *accessLog) logsPrompts(modelID string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if prompts, ok := a.prompts[modelID]; ok {
+		return prompts
+	}
+	return a.config.Prompts
+}
+
+// setPrompts overrides whether the content of requests for a model is logged.
+func (a *accessLog) setPrompts(modelID string, prompts bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.prompts[modelID] = prompts
+}
+
+// write appends an entry to the log, rotating it first if the entry would take
+// it beyond its maximum size.
+func (a *accessLog) write(entry accessLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding access log entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.file == nil {
+		return os.ErrClosed
+	}
+	if a.size > 0 && a.size+int64(len(line)) > a.config.MaxSize {
+		if err := a.rotateLocked(); err != nil {
+			a.file = nil
+			return err
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing access log: %w", err)
+	}
+	return nil
+}
+
+// close closes the log.
+func (a *accessLog) close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// SetAccessLog enables the access log of inference requests, recording the
+// model, backend, client, latency, token usage and status of each. It must be
+// called before Run, which closes the log when it returns.
+func (s *Scheduler) SetAccessLog(config AccessLogConfig) error {
+	a, err := openAccessLog(config)
+	if err != nil {
+		return err
+	}
+	s.accessLog = a
+	return nil
+}
+
+// logAccess writes an inference request to the access log, if enabled. The
+// request content is only included if prompts are logged for the model.
+func (s *Scheduler) logAccess(r *http.Request, start time.Time, backend inference.Backend, model string, mode inference.BackendMode, body []byte, observer *metrics.ObservedResponseWriter) {
+	if s.accessLog == nil {
+		return
+	}
+	tokensIn, tokensOut := observer.Tokens()
+	entry := accessLogEntry{
+		Time:      start.UTC(),
+		Model:     model,
+		Backend:   backend.Name(),
+		Mode:      mode.String(),
+		Client:    r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    observer.StatusCode(),
+		LatencyMs: time.Since(start).Milliseconds(),
+		TokensIn:  tokensIn,
+		TokensOut: tokensOut,
+	}
+	if s.accessLog.logsPrompts(s.modelManager.ResolveModelID(model)) && json.Valid(body) {
+		entry.Request = body
+	}
+	if err := s.accessLog.write(entry); err != nil {
+		s.log.Warnf("Failed to write access log: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package scheduling
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// readAccessLog reads the entries of an access log file.
+func readAccessLog(t *testing.T, path string) []accessLogEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+	defer f.Close()
+	var entries []accessLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry accessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid access log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.jsonl")
+	entry := accessLogEntry{Model: "ai/smollm2", Backend: "llama.cpp", Mode: "completion", Status: 200}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each log holds two entries.
+	log, err := openAccessLog(AccessLogConfig{Path: path, MaxSize: int64(2*len(line) + 2), MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("openAccessLog() error = %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		entry.TokensOut = uint64(i)
+		if err := log.write(entry); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+	}
+	if err := log.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	// The oldest log, with the first two entries, was removed.
+	for suffix, want := range map[string][]uint64{"": {6}, ".1": {4, 5}, ".2": {2, 3}} {
+		entries := readAccessLog(t, path+suffix)
+		if len(entries) != len(want) {
+			t.Fatalf("Expected %d entries in access log%s, got %d", len(want), suffix, len(entries))
+		}
+		for i, entry := range entries {
+			if entry.TokensOut != want[i] {
+				t.Errorf("Expected entry %d in access log%s, got %d", want[i], suffix, entry.TokensOut)
+			}
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 rotated logs, got %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		t.Errorf("Expected the access log to be private, got %v", perm)
+	}
+}
+
+func TestAccessLogPrompts(t *testing.T) {
+	log, err := openAccessLog(AccessLogConfig{Path: filepath.Join(t.TempDir(), "access.jsonl")})
+	if err != nil {
+		t.Fatalf("openAccessLog() error = %v", err)
+	}
+	defer log.close()
+
+	if log.logsPrompts("model1") {
+		t.Error("Expected prompts to be redacted by default")
+	}
+	log.setPrompts("model1", true)
+	if !log.logsPrompts("model1") || log.logsPrompts("model2") {
+		t.Error("Expected prompts to be logged for model1 only")
+	}
+
+	log, err = openAccessLog(AccessLogConfig{Path: filepath.Join(t.TempDir(), "access.jsonl"), Prompts: true})
+	if err != nil {
+		t.Fatalf("openAccessLog() error = %v", err)
+	}
+	defer log.close()
+	log.setPrompts("model1", false)
+	if log.logsPrompts("model1") || !log.logsPrompts("model2") {
+		t.Error("Expected prompts to be logged for model2 only")
+	}
+}
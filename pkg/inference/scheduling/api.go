@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/metrics"
 )
 
 const (
@@ -13,6 +14,10 @@ const (
 	// enough to encompass any real-world request but also small enough to avoid
 	// DoS attacks.
 	maximumOpenAIInferenceRequestSize = 10 * 1024 * 1024
+	// maximumTranscriptionRequestSize is the maximum transcription request
+	// size that Scheduler will allow. It matches the audio file size limit of
+	// the OpenAI API.
+	maximumTranscriptionRequestSize = 25 * 1024 * 1024
 )
 
 // trimRequestPathToOpenAIRoot trims a request path to start at the first
@@ -33,15 +38,30 @@ func backendModeForRequest(path string) (inference.BackendMode, bool) {
 		return inference.BackendModeCompletion, true
 	} else if strings.HasSuffix(path, "/v1/embeddings") {
 		return inference.BackendModeEmbedding, true
+	} else if strings.HasSuffix(path, "/v1/audio/transcriptions") {
+		return inference.BackendModeTranscription, true
+	} else if strings.HasSuffix(path, "/v1/audio/speech") {
+		return inference.BackendModeSpeech, true
+	} else if strings.HasSuffix(path, "/v1/images/generations") {
+		return inference.BackendModeImageGeneration, true
+	} else if strings.HasSuffix(path, "/v1/classify") {
+		return inference.BackendModeClassification, true
+	} else if strings.HasSuffix(path, "/v1/rerank") {
+		return inference.BackendModeReranking, true
 	}
 	return inference.BackendMode(0), false
 }
 
-// OpenAIInferenceRequest is used to extract the model specification from either
-// a chat completion or embedding request in the OpenAI API.
+// OpenAIInferenceRequest is used to extract the model specification from a chat
+// completion, (legacy) text completion or embedding request in the OpenAI API.
+// Transcription requests are multipart forms rather than JSON, and are decoded
+// by decodeTranscriptionRequest.
 type OpenAIInferenceRequest struct {
 	// Model is the requested model name.
 	Model string `json:"model"`
+	// User identifies the end user of the request, which is used as its
+	// session unless one is set with SessionHeader.
+	User string `json:"user,omitempty"`
 }
 
 // OpenAIErrorResponse is used to format an OpenAI API compatible error response
@@ -62,10 +82,54 @@ type BackendStatus struct {
 	ModelName string `json:"model_name"`
 	// Mode is the mode the backend is operating in
 	Mode string `json:"mode"`
+	// Replica distinguishes the runners loaded for the same model when it's
+	// configured with multiple replicas
+	Replica int `json:"replica,omitempty"`
 	// LastUsed represents when this (backend, model, mode) tuple was last used
 	LastUsed time.Time `json:"last_used,omitempty"`
 	// InUse indicates whether this backend is currently handling a request
 	InUse bool `json:"in_use,omitempty"`
+	// RAMBytes and VRAMBytes are the memory allocated to the runner, if known
+	RAMBytes  uint64 `json:"ram_bytes,omitempty"`
+	VRAMBytes uint64 `json:"vram_bytes,omitempty"`
+	// GPUs are the indices of the GPUs on which the runner's VRAM is allocated
+	GPUs []int `json:"gpus,omitempty"`
+	// LoadedAt is when the runner finished loading
+	LoadedAt time.Time `json:"loaded_at,omitempty"`
+	// LoadSeconds is the time the runner took to load
+	LoadSeconds float64 `json:"load_seconds,omitempty"`
+	// UptimeSeconds is the time since the runner finished loading
+	UptimeSeconds float64 `json:"uptime_seconds,omitempty"`
+	// InFlight is the number of requests being served by the runner
+	InFlight int `json:"in_flight,omitempty"`
+	// Queued is the number of requests waiting for the runner to admit them
+	Queued int `json:"queued,omitempty"`
+	// TokensPerSecond is a moving average of the completion token throughput
+	// of the requests served for the model in this mode
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	// Resources contains the resource usage sampled from the runner's cgroup,
+	// if the runner is running in a dedicated cgroup
+	Resources *metrics.RunnerResourceStats `json:"resources,omitempty"`
+	// Crash describes why the backend exited, if it crashed. Crashed backends
+	// are only listed if requested.
+	Crash *CrashReport `json:"crash,omitempty"`
+	// socket is the runner's socket path, used to sample its resources
+	socket string
+}
+
+// CrashReport describes a backend process that exited unexpectedly.
+type CrashReport struct {
+	// Time is when the crash was detected.
+	Time time.Time `json:"time"`
+	// Error is the error with which the backend exited.
+	Error string `json:"error"`
+	// ExitCode is the exit code of the process, or -1 if it was terminated by
+	// a signal. It's only set if the backend reports it.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Command is the command line of the process.
+	Command []string `json:"command,omitempty"`
+	// Output is the tail of the standard error of the process.
+	Output string `json:"output,omitempty"`
 }
 
 // DiskUsage represents the disk usage of the models and default backend.
@@ -79,18 +143,102 @@ type UnloadRequest struct {
 	All     bool     `json:"all"`
 	Backend string   `json:"backend"`
 	Models  []string `json:"models"`
+	// Mode restricts the unloaded runners to those in the given mode, such
+	// as completion or embedding.
+	Mode string `json:"mode,omitempty"`
+	// GPU restricts the unloaded runners to those with VRAM allocated on the
+	// GPU with the given index.
+	GPU *int `json:"gpu,omitempty"`
+	// IdleOnly restricts the unloaded runners to those that aren't in use.
+	// Otherwise, runners that are in use are unloaded once their requests
+	// complete.
+	IdleOnly bool `json:"idle_only,omitempty"`
 }
 
 // UnloadResponse is used to return the number of unloaded runners (backend, model).
 type UnloadResponse struct {
 	UnloadedRunners int `json:"unloaded_runners"`
+	// Runners are the runners that were unloaded.
+	Runners []BackendStatus `json:"runners,omitempty"`
+	// DrainingRunners is the number of runners that were in use, which are
+	// unloaded once their requests complete.
+	DrainingRunners int `json:"draining_runners,omitempty"`
 }
 
 // ConfigureRequest specifies per-model runtime configuration options.
 type ConfigureRequest struct {
-	Model           string                              `json:"model"`
-	ContextSize     int64                               `json:"context-size,omitempty"`
-	RuntimeFlags    []string                            `json:"runtime-flags,omitempty"`
-	RawRuntimeFlags string                              `json:"raw-runtime-flags,omitempty"`
-	Speculative     *inference.SpeculativeDecodingConfig `json:"speculative,omitempty"`
+	Model            string                               `json:"model"`
+	ContextSize      int64                                `json:"context-size,omitempty"`
+	RuntimeFlags     []string                             `json:"runtime-flags,omitempty"`
+	RawRuntimeFlags  string                               `json:"raw-runtime-flags,omitempty"`
+	Speculative      *inference.SpeculativeDecodingConfig `json:"speculative,omitempty"`
+	KVCache          *inference.KVCacheConfig             `json:"kv-cache,omitempty"`
+	GPUs             []int                                `json:"gpus,omitempty"`
+	TensorSplit      []float64                            `json:"tensor-split,omitempty"`
+	LoRAAdapters     []inference.LoRAAdapter              `json:"lora-adapters,omitempty"`
+	Env              map[string]string                    `json:"env,omitempty"`
+	Mounts           []string                             `json:"mounts,omitempty"`
+	Replicas         int                                  `json:"replicas,omitempty"`
+	AccessLogPrompts *bool                                `json:"access-log-prompts,omitempty"`
+	// ParallelSlots is the number of requests a runner serves in parallel.
+	ParallelSlots int `json:"parallel-slots,omitempty"`
+	// BatchSize is the number of tokens a runner processes in a batch.
+	BatchSize int `json:"batch-size,omitempty"`
+	// TrafficSplit routes a percentage of the requests for the model to
+	// another model. A request that only sets the traffic split leaves the
+	// runner configuration of the model unchanged.
+	TrafficSplit *TrafficSplit `json:"traffic-split,omitempty"`
+}
+
+// TrafficSplit routes a percentage of the inference requests for a model to
+// another model, e.g. to canary a new quantization.
+type TrafficSplit struct {
+	// Model is the model that serves the split requests.
+	Model string `json:"model"`
+	// Percent is the percentage of requests routed to Model, from 1 to 100.
+	// Zero removes the traffic split.
+	Percent int `json:"percent"`
+}
+
+// BenchmarkRequest specifies the benchmark to run against a model. Zero values
+// select the defaults, which are the pp512 and tg128 tests repeated 3 times.
+type BenchmarkRequest struct {
+	// Backend is the backend to load the model with, defaulting to the
+	// default backend.
+	Backend string `json:"backend,omitempty"`
+	// PromptTokens is the length of the prompt of the prompt processing test.
+	PromptTokens int `json:"prompt_tokens,omitempty"`
+	// GenerationTokens is the number of tokens generated by the generation
+	// test.
+	GenerationTokens int `json:"generation_tokens,omitempty"`
+	// Repetitions is the number of times each test is run.
+	Repetitions int `json:"repetitions,omitempty"`
+}
+
+// BenchmarkTest is the result of one of the tests of a benchmark, averaged
+// over its repetitions.
+type BenchmarkTest struct {
+	// Name names the test after its kind and length, e.g. pp512 or tg128.
+	Name string `json:"name"`
+	// Tokens is the mean number of tokens processed or generated per run, as
+	// reported by the backend.
+	Tokens float64 `json:"tokens"`
+	// TokensPerSecond is the mean throughput of the runs, and
+	// TokensPerSecondStdDev its standard deviation.
+	TokensPerSecond       float64 `json:"tokens_per_second"`
+	TokensPerSecondStdDev float64 `json:"tokens_per_second_stddev"`
+}
+
+// BenchmarkResult is the result of a model benchmark.
+type BenchmarkResult struct {
+	Model   string          `json:"model"`
+	Backend string          `json:"backend"`
+	Tests   []BenchmarkTest `json:"tests"`
+	// TTFTMilliseconds is the mean time to first token of the prompt
+	// processing test.
+	TTFTMilliseconds float64 `json:"ttft_ms"`
+	// RAMBytes and VRAMBytes are the memory allocated to the model's runner,
+	// if known.
+	RAMBytes  uint64 `json:"ram_bytes,omitempty"`
+	VRAMBytes uint64 `json:"vram_bytes,omitempty"`
 }
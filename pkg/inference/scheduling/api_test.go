@@ -0,0 +1,33 @@
+package scheduling
+
+import (
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestBackendModeForRequest(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected inference.BackendMode
+		ok       bool
+	}{
+		{path: "/engines/v1/chat/completions", expected: inference.BackendModeCompletion, ok: true},
+		{path: "/engines/llama.cpp/v1/completions", expected: inference.BackendModeCompletion, ok: true},
+		{path: "/engines/v1/embeddings", expected: inference.BackendModeEmbedding, ok: true},
+		{path: "/engines/v1/audio/transcriptions", expected: inference.BackendModeTranscription, ok: true},
+		{path: "/engines/piper/v1/audio/speech", expected: inference.BackendModeSpeech, ok: true},
+		{path: "/engines/v1/images/generations", expected: inference.BackendModeImageGeneration, ok: true},
+		{path: "/engines/onnx/v1/classify", expected: inference.BackendModeClassification, ok: true},
+		{path: "/engines/llama.cpp/v1/rerank", expected: inference.BackendModeReranking, ok: true},
+		{path: "/engines/v1/models", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mode, ok := backendModeForRequest(tt.path)
+			if ok != tt.ok || (ok && mode != tt.expected) {
+				t.Errorf("Expected mode %s (%v), got %s (%v)", tt.expected, tt.ok, mode, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,711 @@
+package scheduling
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/model-runner/pkg/internal/utils"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+const (
+	// maximumBatchFileSize is the maximum size of an uploaded batch input file.
+	maximumBatchFileSize = 200 * 1024 * 1024
+	// maximumBatchFilesSize is the maximum total size of the batch files that
+	// are kept in memory. Uploads are rejected beyond it until files are
+	// deleted.
+	maximumBatchFilesSize = 1024 * 1024 * 1024
+	// maximumBatches is the maximum number of batches that are kept. The
+	// oldest finished batch is forgotten beyond it.
+	maximumBatches = 32
+	// batchConcurrency is the number of requests of a batch that are served at
+	// once.
+	batchConcurrency = 4
+	// batchCompletionWindow is the only supported completion window of
+	// batches, after which they expire.
+	batchCompletionWindow = 24 * time.Hour
+)
+
+var (
+	// errBatchFileNotFound indicates that a batch file doesn't exist.
+	errBatchFileNotFound = errors.New("file not found")
+	// errBatchNotFound indicates that a batch doesn't exist.
+	errBatchNotFound = errors.New("batch not found")
+	// errTooManyBatches indicates that a batch can't be created because the
+	// maximum number of batches are still in progress.
+	errTooManyBatches = errors.New("too many batches in progress")
+	// errBatchFilesTooLarge indicates that an upload would exceed the
+	// maximum total size of the batch files.
+	errBatchFilesTooLarge = errors.New("batch file storage is full, delete files to upload more")
+	// batchEndpoints are the endpoints that batch requests may be sent to.
+	batchEndpoints = []string{"/v1/chat/completions", "/v1/completions", "/v1/embeddings"}
+)
+
+// Batch statuses, as defined by the OpenAI Batch API.
+const (
+	batchValidating = "validating"
+	batchFailed     = "failed"
+	batchInProgress = "in_progress"
+	batchFinalizing = "finalizing"
+	batchCompleted  = "completed"
+	batchExpired    = "expired"
+	batchCancelling = "cancelling"
+	batchCancelled  = "cancelled"
+)
+
+// BatchFile describes an uploaded batch input file or a batch's output or
+// error file.
+type BatchFile struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+// BatchRequestCounts counts the requests of a batch.
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchError describes an invalid line of a batch input file, or a request of
+// a batch that couldn't be served.
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    *int   `json:"line,omitempty"`
+}
+
+// BatchErrors are the errors that made a batch fail validation.
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+// Batch describes a batch in the format of the OpenAI Batch API.
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     string             `json:"output_file_id,omitempty"`
+	ErrorFileID      string             `json:"error_file_id,omitempty"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     int64              `json:"in_progress_at,omitempty"`
+	ExpiresAt        int64              `json:"expires_at"`
+	FinalizingAt     int64              `json:"finalizing_at,omitempty"`
+	CompletedAt      int64              `json:"completed_at,omitempty"`
+	FailedAt         int64              `json:"failed_at,omitempty"`
+	ExpiredAt        int64              `json:"expired_at,omitempty"`
+	CancellingAt     int64              `json:"cancelling_at,omitempty"`
+	CancelledAt      int64              `json:"cancelled_at,omitempty"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata,omitempty"`
+}
+
+// finished returns whether the batch won't change anymore.
+func (b *Batch) finished() bool {
+	switch b.Status {
+	case batchFailed, batchCompleted, batchExpired, batchCancelled:
+		return true
+	}
+	return false
+}
+
+// CreateBatchRequest creates a batch.
+type CreateBatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// batchInputLine is a line of a batch input file.
+type batchInputLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// batchResponse is the response to a request of a batch.
+type batchResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// batchOutputLine is a line of a batch output or error file.
+type batchOutputLine struct {
+	ID       string         `json:"id"`
+	CustomID string         `json:"custom_id"`
+	Response *batchResponse `json:"response"`
+	Error    *BatchError    `json:"error"`
+}
+
+// batchFile is a batch file and its content.
+type batchFile struct {
+	info    BatchFile
+	content []byte
+	// owner is the fingerprint of the API key that uploaded the file, or of
+	// the one that created the batch it's the output of.
+	owner string
+}
+
+// batchJob is a batch and the function cancelling it.
+type batchJob struct {
+	batch  Batch
+	cancel context.CancelFunc
+	// owner is the fingerprint of the API key that created the batch.
+	owner string
+}
+
+// openAIBatches are the files and batches of the OpenAI Batch API. Files and
+// batches belong to the API key that created them, identified by its
+// fingerprint, and are only visible to it. Without API keys, every file and
+// batch has the same empty owner.
+type openAIBatches struct {
+	// lock protects the subsequent fields, including the batches themselves.
+	lock sync.Mutex
+	// files maps file IDs to files.
+	files map[string]*batchFile
+	// filesSize is the total size of the files.
+	filesSize int64
+	// jobs maps batch IDs to batches.
+	jobs map[string]*batchJob
+	// order are the batch IDs, oldest first.
+	order []string
+}
+
+// newOpenAIBatches creates an empty set of batch files and batches.
+func newOpenAIBatches() *openAIBatches {
+	return &openAIBatches{
+		files: make(map[string]*batchFile),
+		jobs:  make(map[string]*batchJob),
+	}
+}
+
+// addFile stores a file belonging to owner. Uploaded files are limited by the
+// maximum total size, while output files are always stored.
+func (b *openAIBatches) addFile(owner, filename, purpose string, content []byte) (BatchFile, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if purpose == "batch" && b.filesSize+int64(len(content)) > maximumBatchFilesSize {
+		return BatchFile{}, errBatchFilesTooLarge
+	}
+	file := &batchFile{
+		info: BatchFile{
+			ID:        newBatchID("file-"),
+			Object:    "file",
+			Bytes:     len(content),
+			CreatedAt: time.Now().Unix(),
+			Filename:  filename,
+			Purpose:   purpose,
+		},
+		content: content,
+		owner:   owner,
+	}
+	b.files[file.info.ID] = file
+	b.filesSize += int64(len(content))
+	return file.info, nil
+}
+
+// file returns a file of owner by ID.
+func (b *openAIBatches) file(owner, id string) (*batchFile, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	file, ok := b.files[id]
+	if !ok || file.owner != owner {
+		return nil, errBatchFileNotFound
+	}
+	return file, nil
+}
+
+// listFiles returns the files of owner, oldest first.
+func (b *openAIBatches) listFiles(owner string) []BatchFile {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	files := make([]BatchFile, 0, len(b.files))
+	for _, file := range b.files {
+		if file.owner == owner {
+			files = append(files, file.info)
+		}
+	}
+	slices.SortFunc(files, func(a, b BatchFile) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return files
+}
+
+// removeFile deletes a file of owner.
+func (b *openAIBatches) removeFile(owner, id string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	file, ok := b.files[id]
+	if !ok || file.owner != owner {
+		return errBatchFileNotFound
+	}
+	b.filesSize -= int64(len(file.content))
+	delete(b.files, id)
+	return nil
+}
+
+// add adds a batch, forgetting the oldest finished batch if there are too
+// many.
+func (b *openAIBatches) add(job *batchJob) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.order) >= maximumBatches {
+		index := slices.IndexFunc(b.order, func(id string) bool {
+			return b.jobs[id].batch.finished()
+		})
+		if index < 0 {
+			return errTooManyBatches
+		}
+		delete(b.jobs, b.order[index])
+		b.order = slices.Delete(b.order, index, index+1)
+	}
+	b.jobs[job.batch.ID] = job
+	b.order = append(b.order, job.batch.ID)
+	return nil
+}
+
+// get returns a copy of a batch of owner by ID.
+func (b *openAIBatches) get(owner, id string) (Batch, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	job, ok := b.jobs[id]
+	if !ok || job.owner != owner {
+		return Batch{}, errBatchNotFound
+	}
+	return job.batch, nil
+}
+
+// update updates a batch and returns a copy of it.
+func (b *openAIBatches) update(id string, update func(batch *Batch)) Batch {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	job := b.jobs[id]
+	if job == nil {
+		return Batch{}
+	}
+	update(&job.batch)
+	return job.batch
+}
+
+// cancel starts cancelling a batch of owner in progress and returns a copy of
+// it.
+func (b *openAIBatches) cancel(owner, id string) (Batch, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	job, ok := b.jobs[id]
+	if !ok || job.owner != owner {
+		return Batch{}, errBatchNotFound
+	}
+	if job.batch.Status != batchInProgress {
+		return Batch{}, fmt.Errorf("can't cancel a batch that is %s", job.batch.Status)
+	}
+	job.batch.Status = batchCancelling
+	job.batch.CancellingAt = time.Now().Unix()
+	job.cancel()
+	return job.batch, nil
+}
+
+// list returns the batches of owner, newest first.
+func (b *openAIBatches) list(owner string) []Batch {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	result := make([]Batch, 0, len(b.order))
+	for i := len(b.order) - 1; i >= 0; i-- {
+		if job := b.jobs[b.order[i]]; job.owner == owner {
+			result = append(result, job.batch)
+		}
+	}
+	return result
+}
+
+// parseBatchInput parses the lines of a batch input file, which must all be
+// POST requests to the batch's endpoint with unique custom IDs.
+func parseBatchInput(content []byte, endpoint string) ([]batchInputLine, []BatchError) {
+	var lines []batchInputLine
+	var errs []BatchError
+	customIDs := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, maximumOpenAIInferenceRequestSize)
+	for number := 1; scanner.Scan(); number++ {
+		lineNumber := number
+		fail := func(code, message string) {
+			errs = append(errs, BatchError{Code: code, Message: message, Line: &lineNumber})
+		}
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchInputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			fail("invalid_json_line", "This line is not parseable as valid JSON.")
+			continue
+		}
+		switch {
+		case line.CustomID == "":
+			fail("missing_required_parameter", "The custom_id parameter is required.")
+		case customIDs[line.CustomID]:
+			fail("duplicate_custom_id", fmt.Sprintf("The custom_id %q is used more than once.", line.CustomID))
+		case line.Method != http.MethodPost:
+			fail("invalid_request", "The method must be POST.")
+		case line.URL != endpoint:
+			fail("mismatched_endpoint", fmt.Sprintf("The url %q doesn't match the batch's endpoint %q.", line.URL, endpoint))
+		case len(line.Body) == 0 || line.Body[0] != '{':
+			fail("invalid_request", "The body must be a JSON object.")
+		default:
+			customIDs[line.CustomID] = true
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, BatchError{Code: "invalid_file", Message: err.Error()})
+	}
+	if len(lines) == 0 && len(errs) == 0 {
+		errs = append(errs, BatchError{Code: "empty_file", Message: "The input file doesn't contain any requests."})
+	}
+	return lines, errs
+}
+
+// UploadBatchFile handles POST <inference-prefix>/v1/files requests, which
+// upload a batch input file as a multipart/form-data "file" with the "batch"
+// purpose.
+func (s *Scheduler) UploadBatchFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maximumBatchFileSize+1024*1024)
+	if err := r.ParseMultipartForm(32 * 1024 * 1024); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "file", fmt.Errorf("invalid upload: %w", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	if purpose := r.FormValue("purpose"); purpose != "batch" {
+		writeOpenAIError(w, http.StatusBadRequest, "purpose", fmt.Errorf("unsupported purpose %q, only batch is supported", purpose))
+		return
+	}
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "file", errors.New("file is required"))
+		return
+	}
+	defer upload.Close()
+	content, err := io.ReadAll(io.LimitReader(upload, maximumBatchFileSize+1))
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "file", fmt.Errorf("unable to read file: %w", err))
+		return
+	}
+	if len(content) > maximumBatchFileSize {
+		writeOpenAIError(w, http.StatusBadRequest, "file", errors.New("file too large"))
+		return
+	}
+	file, err := s.batches.addFile(metrics.APIKeyFingerprint(r), header.Filename, "batch", content)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInsufficientStorage, "file", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+// ListBatchFiles handles GET <inference-prefix>/v1/files requests.
+func (s *Scheduler) ListBatchFiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"data":   s.batches.listFiles(metrics.APIKeyFingerprint(r)),
+	})
+}
+
+// GetBatchFile handles GET <inference-prefix>/v1/files/{id} requests.
+func (s *Scheduler) GetBatchFile(w http.ResponseWriter, r *http.Request) {
+	file, err := s.batches.file(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if err != nil {
+		writeOpenAIError(w, http.StatusNotFound, "file_id", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file.info)
+}
+
+// GetBatchFileContent handles GET <inference-prefix>/v1/files/{id}/content
+// requests, which download a batch file.
+func (s *Scheduler) GetBatchFileContent(w http.ResponseWriter, r *http.Request) {
+	file, err := s.batches.file(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if err != nil {
+		writeOpenAIError(w, http.StatusNotFound, "file_id", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.content)))
+	w.Write(file.content)
+}
+
+// DeleteBatchFile handles DELETE <inference-prefix>/v1/files/{id} requests.
+func (s *Scheduler) DeleteBatchFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.batches.removeFile(metrics.APIKeyFingerprint(r), id); err != nil {
+		writeOpenAIError(w, http.StatusNotFound, "file_id", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "object": "file", "deleted": true})
+}
+
+// CreateBatch handles POST <inference-prefix>/{backend}/v1/batches requests,
+// which create a batch of the requests in an uploaded input file and serve
+// them in the background, at low priority. Batches whose input file is invalid
+// are created as failed, as with the OpenAI Batch API.
+func (s *Scheduler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	backendName := r.PathValue("backend")
+	if backendName != "" && s.backends[backendName] == nil {
+		writeOpenAIError(w, http.StatusNotFound, "", ErrBackendNotFound)
+		return
+	}
+
+	var request CreateBatchRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumOpenAIInferenceRequestSize))
+	if err != nil || json.Unmarshal(body, &request) != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "", errors.New("invalid request"))
+		return
+	}
+	if !slices.Contains(batchEndpoints, request.Endpoint) {
+		writeOpenAIError(w, http.StatusBadRequest, "endpoint", fmt.Errorf("unsupported endpoint %q", request.Endpoint))
+		return
+	}
+	if request.CompletionWindow != "24h" {
+		writeOpenAIError(w, http.StatusBadRequest, "completion_window", fmt.Errorf("unsupported completion window %q, only 24h is supported", request.CompletionWindow))
+		return
+	}
+	owner := metrics.APIKeyFingerprint(r)
+	input, err := s.batches.file(owner, request.InputFileID)
+	if err != nil {
+		writeOpenAIError(w, http.StatusNotFound, "input_file_id", err)
+		return
+	}
+	if input.info.Purpose != "batch" {
+		writeOpenAIError(w, http.StatusBadRequest, "input_file_id", errors.New("the input file must have the batch purpose"))
+		return
+	}
+
+	// The requests of the batch are made on behalf of its owner, so that
+	// their usage is accounted to its API key.
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(middleware.WithAPIKeyFingerprint(context.Background(), owner), now.Add(batchCompletionWindow))
+	job := &batchJob{
+		batch: Batch{
+			ID:               newBatchID("batch_"),
+			Object:           "batch",
+			Endpoint:         request.Endpoint,
+			InputFileID:      request.InputFileID,
+			CompletionWindow: request.CompletionWindow,
+			Status:           batchValidating,
+			CreatedAt:        now.Unix(),
+			ExpiresAt:        now.Add(batchCompletionWindow).Unix(),
+			Metadata:         request.Metadata,
+		},
+		cancel: cancel,
+		owner:  owner,
+	}
+	lines, errs := parseBatchInput(input.content, request.Endpoint)
+	if len(errs) > 0 {
+		cancel()
+		job.batch.Status = batchFailed
+		job.batch.FailedAt = now.Unix()
+		job.batch.Errors = &BatchErrors{Object: "list", Data: errs}
+	} else {
+		job.batch.Status = batchInProgress
+		job.batch.InProgressAt = now.Unix()
+		job.batch.RequestCounts.Total = len(lines)
+	}
+	if err := s.batches.add(job); err != nil {
+		cancel()
+		writeOpenAIError(w, http.StatusTooManyRequests, "", err)
+		return
+	}
+	if len(errs) == 0 {
+		s.log.Infof("Created batch %s of %d requests to %s", job.batch.ID, len(lines), utils.SanitizeForLog(request.Endpoint))
+		go s.runBatch(ctx, backendName, owner, job.batch.ID, request.Endpoint, lines)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.batch)
+}
+
+// runBatch serves the requests of a batch, a few at a time, and stores their
+// responses in the batch's output file, or its error file if they failed. The
+// files belong to the batch's owner.
+func (s *Scheduler) runBatch(ctx context.Context, backendName, owner, id, endpoint string, lines []batchInputLine) {
+	var lock sync.Mutex
+	var output, failures bytes.Buffer
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, batchConcurrency)
+	for _, line := range lines {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			result := batchOutputLine{ID: newBatchID("batch_req_"), CustomID: line.CustomID}
+			response, err := s.serveBatchRequest(ctx, backendName, endpoint, line.Body)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				result.Error = &BatchError{Code: "invalid_request", Message: err.Error()}
+			} else {
+				body := response.body.Bytes()
+				if !json.Valid(body) {
+					body, _ = json.Marshal(map[string]any{"error": map[string]string{"message": string(bytes.TrimSpace(body))}})
+				}
+				result.Response = &batchResponse{
+					StatusCode: response.statusCode,
+					RequestID:  newBatchID("req_"),
+					Body:       body,
+				}
+			}
+			encoded, _ := json.Marshal(result)
+			succeeded := result.Error == nil && result.Response.StatusCode == http.StatusOK
+
+			lock.Lock()
+			defer lock.Unlock()
+			if succeeded {
+				output.Write(encoded)
+				output.WriteByte('\n')
+			} else {
+				failures.Write(encoded)
+				failures.WriteByte('\n')
+			}
+			s.batches.update(id, func(batch *Batch) {
+				if succeeded {
+					batch.RequestCounts.Completed++
+				} else {
+					batch.RequestCounts.Failed++
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now().Unix()
+	s.batches.update(id, func(batch *Batch) {
+		if batch.Status == batchInProgress {
+			batch.Status = batchFinalizing
+			batch.FinalizingAt = now
+		}
+	})
+	var outputFileID, errorFileID string
+	if output.Len() > 0 {
+		if file, err := s.batches.addFile(owner, id+"_output.jsonl", "batch_output", output.Bytes()); err == nil {
+			outputFileID = file.ID
+		}
+	}
+	if failures.Len() > 0 {
+		if file, err := s.batches.addFile(owner, id+"_error.jsonl", "batch_output", failures.Bytes()); err == nil {
+			errorFileID = file.ID
+		}
+	}
+	batch := s.batches.update(id, func(batch *Batch) {
+		batch.OutputFileID = outputFileID
+		batch.ErrorFileID = errorFileID
+		switch {
+		case batch.Status == batchCancelling:
+			batch.Status = batchCancelled
+			batch.CancelledAt = now
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			batch.Status = batchExpired
+			batch.ExpiredAt = now
+		default:
+			batch.Status = batchCompleted
+			batch.CompletedAt = now
+		}
+	})
+	s.log.Infof("Batch %s is %s", id, batch.Status)
+}
+
+// ListBatches handles GET <inference-prefix>/v1/batches requests, which list
+// batches newest first, paginated by the "after" and "limit" query
+// parameters.
+func (s *Scheduler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > 100 {
+			writeOpenAIError(w, http.StatusBadRequest, "limit", errors.New("limit must be between 1 and 100"))
+			return
+		}
+	}
+	batches := s.batches.list(metrics.APIKeyFingerprint(r))
+	if after := r.URL.Query().Get("after"); after != "" {
+		index := slices.IndexFunc(batches, func(batch Batch) bool { return batch.ID == after })
+		batches = batches[index+1:]
+	}
+	hasMore := len(batches) > limit
+	batches = batches[:min(limit, len(batches))]
+	response := map[string]any{
+		"object":   "list",
+		"data":     batches,
+		"has_more": hasMore,
+	}
+	if len(batches) > 0 {
+		response["first_id"] = batches[0].ID
+		response["last_id"] = batches[len(batches)-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBatch handles GET <inference-prefix>/v1/batches/{id} requests, which
+// report the status and progress of a batch.
+func (s *Scheduler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.batches.get(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if err != nil {
+		writeOpenAIError(w, http.StatusNotFound, "batch_id", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// CancelBatch handles POST <inference-prefix>/v1/batches/{id}/cancel requests.
+// The batch is cancelling until the requests being served are abandoned, and
+// then cancelled, with the responses received so far in its output file.
+func (s *Scheduler) CancelBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := s.batches.cancel(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if errors.Is(err, errBatchNotFound) {
+		writeOpenAIError(w, http.StatusNotFound, "batch_id", err)
+		return
+	} else if err != nil {
+		writeOpenAIError(w, http.StatusConflict, "batch_id", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
@@ -0,0 +1,355 @@
+package scheduling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+func TestParseBatchInput(t *testing.T) {
+	input := strings.Join([]string{
+		`{"custom_id": "a", "method": "POST", "url": "/v1/embeddings", "body": {"model": "ai/model", "input": "a"}}`,
+		``,
+		`not json`,
+		`{"custom_id": "a", "method": "POST", "url": "/v1/embeddings", "body": {}}`,
+		`{"custom_id": "b", "method": "GET", "url": "/v1/embeddings", "body": {}}`,
+		`{"custom_id": "c", "method": "POST", "url": "/v1/chat/completions", "body": {}}`,
+		`{"custom_id": "d", "method": "POST", "url": "/v1/embeddings"}`,
+		`{"method": "POST", "url": "/v1/embeddings", "body": {}}`,
+	}, "\n")
+	lines, errs := parseBatchInput([]byte(input), "/v1/embeddings")
+	if len(lines) != 1 || lines[0].CustomID != "a" {
+		t.Errorf("Expected one valid line, got %+v", lines)
+	}
+	expected := map[int]string{
+		3: "invalid_json_line",
+		4: "duplicate_custom_id",
+		5: "invalid_request",
+		6: "mismatched_endpoint",
+		7: "invalid_request",
+		8: "missing_required_parameter",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %+v", len(expected), errs)
+	}
+	for _, err := range errs {
+		if err.Line == nil || expected[*err.Line] != err.Code {
+			t.Errorf("Unexpected error %+v", err)
+		}
+	}
+
+	if _, errs := parseBatchInput(nil, "/v1/embeddings"); len(errs) != 1 || errs[0].Code != "empty_file" {
+		t.Errorf("Expected an empty file error, got %+v", errs)
+	}
+}
+
+// uploadBatchFile uploads a batch input file and returns its ID.
+func uploadBatchFile(t *testing.T, s *Scheduler, content string) string {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("purpose", "batch")
+	file, _ := form.CreateFormFile("file", "input.jsonl")
+	file.Write([]byte(content))
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/engines/v1/files", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to upload file: %d %s", w.Code, w.Body.String())
+	}
+	var uploaded BatchFile
+	if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("Failed to decode file: %v", err)
+	}
+	if uploaded.Purpose != "batch" || uploaded.Bytes != len(content) || uploaded.Filename != "input.jsonl" {
+		t.Errorf("Unexpected file %+v", uploaded)
+	}
+	return uploaded.ID
+}
+
+// createBatch creates a batch of the requests in an input file.
+func createBatch(t *testing.T, s *Scheduler, fileID string) Batch {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/engines/test-backend/v1/batches", strings.NewReader(
+		`{"input_file_id": "`+fileID+`", "endpoint": "/v1/embeddings", "completion_window": "24h"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to create batch: %d %s", w.Code, w.Body.String())
+	}
+	var batch Batch
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("Failed to decode batch: %v", err)
+	}
+	return batch
+}
+
+func TestBatchLifecycle(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	fileID := uploadBatchFile(t, s, strings.Join([]string{
+		`{"custom_id": "a", "method": "POST", "url": "/v1/embeddings", "body": {"model": "ai/model", "input": "a"}}`,
+		`{"custom_id": "b", "method": "POST", "url": "/v1/embeddings", "body": {"model": "ai/model", "input": "b"}}`,
+	}, "\n"))
+	batch := createBatch(t, s, fileID)
+	if batch.Status != batchInProgress || batch.RequestCounts.Total != 2 || batch.InputFileID != fileID {
+		t.Fatalf("Unexpected batch %+v", batch)
+	}
+
+	// The installer isn't running, so every request fails.
+	deadline := time.Now().Add(5 * time.Second)
+	for !batch.finished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/test-backend/v1/batches/"+batch.ID, nil))
+		if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+			t.Fatalf("Failed to decode batch: %v", err)
+		}
+	}
+	if batch.Status != batchCompleted || batch.RequestCounts.Failed != 2 || batch.OutputFileID != "" || batch.ErrorFileID == "" {
+		t.Fatalf("Expected every request to fail, got %+v", batch)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/files/"+batch.ErrorFileID+"/content", nil))
+	var customIDs []string
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var output batchOutputLine
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatalf("Failed to decode error line %q: %v", line, err)
+		}
+		if output.Response == nil || output.Response.StatusCode != http.StatusServiceUnavailable || !json.Valid(output.Response.Body) {
+			t.Errorf("Unexpected error line %q", line)
+		}
+		customIDs = append(customIDs, output.CustomID)
+	}
+	if len(customIDs) != 2 {
+		t.Errorf("Expected two error lines, got %v", customIDs)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/engines/v1/batches/"+batch.ID+"/cancel", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected a finished batch not to be cancellable, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/batches?limit=1", nil))
+	var list struct {
+		Data    []Batch `json:"data"`
+		HasMore bool    `json:"has_more"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Data) != 1 || list.Data[0].ID != batch.ID || list.HasMore {
+		t.Errorf("Unexpected batch list %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/engines/v1/files/"+fileID, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Failed to delete file: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/files/"+fileID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the deleted file not to be found, got %d", w.Code)
+	}
+}
+
+func TestBatchFailsValidation(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	fileID := uploadBatchFile(t, s, `{"custom_id": "a", "method": "POST", "url": "/v1/chat/completions", "body": {}}`)
+	batch := createBatch(t, s, fileID)
+	if batch.Status != batchFailed || batch.Errors == nil || len(batch.Errors.Data) != 1 || batch.Errors.Data[0].Code != "mismatched_endpoint" {
+		t.Errorf("Expected the batch to fail validation, got %+v", batch)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/engines/v1/batches", strings.NewReader(
+		`{"input_file_id": "`+fileID+`", "endpoint": "/v1/embeddings", "completion_window": "1h"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unsupported completion window to be rejected, got %d", w.Code)
+	}
+}
+
+func TestBatchOwnership(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+	keys, err := middleware.ParseAPIKeys(strings.NewReader("sk-a inference\nsk-b inference\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	handler := middleware.AuthMiddleware(keys, s.InferenceRoutes(), s)
+	serve := func(key, method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	form.WriteField("purpose", "batch")
+	file, _ := form.CreateFormFile("file", "input.jsonl")
+	file.Write([]byte(`{"custom_id": "a", "method": "POST", "url": "/v1/embeddings", "body": {"model": "ai/model", "input": "a"}}`))
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, "/engines/v1/files", &upload)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer sk-a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var uploaded BatchFile
+	if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("Failed to upload file: %d %s", w.Code, w.Body.String())
+	}
+
+	// Other keys can neither see nor use the file.
+	if w := serve("sk-b", http.MethodGet, "/engines/v1/files/"+uploaded.ID+"/content", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's file not to be found, got %d", w.Code)
+	}
+	if w := serve("sk-b", http.MethodGet, "/engines/v1/files", ""); strings.Contains(w.Body.String(), uploaded.ID) {
+		t.Errorf("Expected another key's file not to be listed, got %s", w.Body.String())
+	}
+	if w := serve("sk-b", http.MethodDelete, "/engines/v1/files/"+uploaded.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's file not to be deletable, got %d", w.Code)
+	}
+	create := `{"input_file_id": "` + uploaded.ID + `", "endpoint": "/v1/embeddings", "completion_window": "24h"}`
+	if w := serve("sk-b", http.MethodPost, "/engines/test-backend/v1/batches", create); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's file not to be usable, got %d", w.Code)
+	}
+
+	w = serve("sk-a", http.MethodPost, "/engines/test-backend/v1/batches", create)
+	var batch Batch
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil || batch.ID == "" {
+		t.Fatalf("Failed to create batch: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve("sk-b", http.MethodGet, "/engines/v1/batches/"+batch.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's batch not to be found, got %d", w.Code)
+	}
+	if w := serve("sk-b", http.MethodGet, "/engines/v1/batches", ""); strings.Contains(w.Body.String(), batch.ID) {
+		t.Errorf("Expected another key's batch not to be listed, got %s", w.Body.String())
+	}
+	if w := serve("sk-a", http.MethodGet, "/engines/v1/batches/"+batch.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the batch to be found by its owner, got %d", w.Code)
+	}
+}
+
+// usageBackend is a backend whose runners answer every request with an
+// embeddings response reporting 3 prompt tokens.
+type usageBackend struct {
+	mockBackend
+}
+
+func (b *usageBackend) Run(ctx context.Context, socket, model string, modelRef string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[],"usage":{"prompt_tokens":3,"total_tokens":3}}`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+	<-ctx.Done()
+	return nil
+}
+
+// newServingTestScheduler returns a scheduler whose backend is installed and
+// whose runners serve every request, with its loads enabled.
+func newServingTestScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+	socketDir := t.TempDir()
+	defaultSocketPath := RunnerSocketPath
+	RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(socketDir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	t.Cleanup(func() { RunnerSocketPath = defaultSocketPath })
+
+	manager := models.NewManager(createTestLogger(), models.ClientConfig{
+		StoreRootPath: t.TempDir(),
+		Logger:        createTestLogger(),
+	}, nil, nil)
+	backend := &usageBackend{mockBackend{name: "test-backend", usesExternalModelMgmt: true}}
+	sysMemInfo := &mockSystemMemoryInfo{totalMemory: inference.RequiredMemory{RAM: 8 * GB, VRAM: 8 * GB}}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, manager, nil, nil, nil, sysMemInfo)
+	s.installer.run(context.Background())
+	s.loader.lock(context.Background())
+	s.loader.loadsEnabled = true
+	s.loader.unlock()
+	t.Cleanup(func() { s.loader.Unload(context.Background(), UnloadRequest{All: true}) })
+	return s
+}
+
+func TestBatchUsageAccountedToOwner(t *testing.T) {
+	s := newServingTestScheduler(t)
+	keys, err := middleware.ParseAPIKeys(strings.NewReader("sk-a inference\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	handler := middleware.AuthMiddleware(keys, s.InferenceRoutes(), s)
+
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	form.WriteField("purpose", "batch")
+	file, _ := form.CreateFormFile("file", "input.jsonl")
+	file.Write([]byte(`{"custom_id": "a", "method": "POST", "url": "/v1/embeddings", "body": {"model": "ai/model", "input": "a"}}`))
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, "/engines/v1/files", &upload)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer sk-a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var uploaded BatchFile
+	if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("Failed to upload file: %d %s", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/engines/test-backend/v1/batches", strings.NewReader(
+		`{"input_file_id": "`+uploaded.ID+`", "endpoint": "/v1/embeddings", "completion_window": "24h"}`))
+	r.Header.Set("Authorization", "Bearer sk-a")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var batch Batch
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil || batch.ID == "" {
+		t.Fatalf("Failed to create batch: %d %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !batch.finished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		batch, _ = s.batches.get(middleware.Fingerprint("sk-a"), batch.ID)
+	}
+	if batch.Status != batchCompleted || batch.RequestCounts.Completed != 1 {
+		t.Fatalf("Expected the request of the batch to be served, got %+v", batch)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/usage", nil))
+	var usage metrics.UsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	if len(usage.Usage) != 1 || usage.Usage[0].APIKey != middleware.Fingerprint("sk-a") || usage.Usage[0].PromptTokens != 3 {
+		t.Errorf("Expected the usage of the batch to be accounted to its key, got %+v", usage.Usage)
+	}
+}
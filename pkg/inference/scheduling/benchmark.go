@@ -0,0 +1,279 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+const (
+	// defaultBenchmarkPromptTokens is the default prompt length of the prompt
+	// processing test.
+	defaultBenchmarkPromptTokens = 512
+	// defaultBenchmarkGenerationTokens is the default number of tokens
+	// generated by the generation test.
+	defaultBenchmarkGenerationTokens = 128
+	// defaultBenchmarkRepetitions is the default number of runs of each test.
+	defaultBenchmarkRepetitions = 3
+	// maximumBenchmarkTokens is the maximum length of a test.
+	maximumBenchmarkTokens = 32768
+	// maximumBenchmarkRepetitions is the maximum number of runs of each test.
+	maximumBenchmarkRepetitions = 100
+	// maximumBenchmarkRequestSize is the maximum size of a benchmark request.
+	maximumBenchmarkRequestSize = 4 * 1024
+)
+
+// benchmarkWords are the words that synthetic benchmark prompts are made of.
+// They're common enough to be single tokens for most tokenizers.
+var benchmarkWords = strings.Fields("the quick brown fox jumps over a lazy dog while birds sing in tall green trees near an old river")
+
+// handleModelAction handles POST <inference-prefix>/{backend}/{nameAndAction...}
+// requests, which are either requests to load a model with a backend or, for
+// paths ending with /benchmark or /cache, requests to benchmark the model
+// named by the rest of the path or to save or restore its prompt caches.
+func (s *Scheduler) handleModelAction(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.PathValue("nameAndAction"), "/benchmark") || r.PathValue("nameAndAction") == "benchmark" {
+		s.Benchmark(w, r)
+		return
+	}
+	if strings.HasSuffix(r.PathValue("nameAndAction"), "/cache") || r.PathValue("nameAndAction") == "cache" {
+		s.PromptCacheAction(w, r)
+		return
+	}
+	s.Load(w, r)
+}
+
+// Benchmark handles POST <inference-prefix>/{model}/benchmark requests, which
+// pull the model if necessary, load it, and measure its prompt processing and
+// generation throughput with synthetic prompts, in the style of llama-bench's
+// pp512 and tg128 tests. The body is an optional BenchmarkRequest.
+func (s *Scheduler) Benchmark(w http.ResponseWriter, r *http.Request) {
+	model, ok := strings.CutSuffix(r.PathValue("backend")+"/"+r.PathValue("nameAndAction"), "/benchmark")
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	model = models.NormalizeModelName(model)
+
+	var request BenchmarkRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumBenchmarkRequestSize))
+	if err != nil {
+		http.Error(w, "request too large", http.StatusBadRequest)
+		return
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	if request.PromptTokens == 0 {
+		request.PromptTokens = defaultBenchmarkPromptTokens
+	}
+	if request.GenerationTokens == 0 {
+		request.GenerationTokens = defaultBenchmarkGenerationTokens
+	}
+	if request.Repetitions == 0 {
+		request.Repetitions = defaultBenchmarkRepetitions
+	}
+	if request.PromptTokens < 0 || request.PromptTokens > maximumBenchmarkTokens ||
+		request.GenerationTokens < 0 || request.GenerationTokens > maximumBenchmarkTokens {
+		http.Error(w, fmt.Sprintf("prompt_tokens and generation_tokens must be between 1 and %d", maximumBenchmarkTokens), http.StatusBadRequest)
+		return
+	}
+	if request.Repetitions < 0 || request.Repetitions > maximumBenchmarkRepetitions {
+		http.Error(w, fmt.Sprintf("repetitions must be between 1 and %d", maximumBenchmarkRepetitions), http.StatusBadRequest)
+		return
+	}
+
+	backend := s.defaultBackend
+	if request.Backend != "" {
+		backend = s.backends[request.Backend]
+	}
+	backend, mode, err := s.warmLoad(r.Context(), backend, model, inference.BackendModeCompletion)
+	if err != nil {
+		s.log.Warnf("Failed to load %s for benchmarking: %v", utils.SanitizeForLog(model), err)
+		http.Error(w, err.Error(), loadErrorStatus(err))
+		return
+	}
+	if mode != inference.BackendModeCompletion {
+		http.Error(w, fmt.Sprintf("model %s serves %s requests, only completion models can be benchmarked", model, mode), http.StatusBadRequest)
+		return
+	}
+
+	s.log.Infof("Benchmarking %s with %s", utils.SanitizeForLog(model), backend.Name())
+	result, err := s.benchmark(r.Context(), backend.Name(), model, request)
+	if err != nil {
+		s.log.Warnf("Failed to benchmark %s: %v", utils.SanitizeForLog(model), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		s.log.Warnln("Error while encoding benchmark response:", err)
+	}
+}
+
+// benchmark runs the tests of a benchmark against a loaded model, after a
+// warm-up request whose results are discarded.
+func (s *Scheduler) benchmark(ctx context.Context, backendName, model string, request BenchmarkRequest) (BenchmarkResult, error) {
+	if _, _, err := s.benchmarkRun(ctx, backendName, model, 0, 1, 1); err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	var promptTokens, promptRates, generatedTokens, generationRates []float64
+	var ttft time.Duration
+	for i := range request.Repetitions {
+		// Each run's prompt starts differently, so that none is served from
+		// the prompt cache of the previous ones.
+		tokens, elapsed, err := s.benchmarkRun(ctx, backendName, model, 2*i+1, request.PromptTokens, 1)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+		promptTokens = append(promptTokens, float64(tokens.PromptTokens))
+		promptRates = append(promptRates, float64(tokens.PromptTokens)/elapsed.Seconds())
+		ttft += elapsed
+
+		tokens, elapsed, err = s.benchmarkRun(ctx, backendName, model, 2*i+2, 1, request.GenerationTokens)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+		generatedTokens = append(generatedTokens, float64(tokens.CompletionTokens))
+		generationRates = append(generationRates, float64(tokens.CompletionTokens)/elapsed.Seconds())
+	}
+
+	result := BenchmarkResult{
+		Model:            model,
+		Backend:          backendName,
+		TTFTMilliseconds: float64(ttft) / float64(time.Millisecond) / float64(request.Repetitions),
+	}
+	for _, test := range []struct {
+		name   string
+		tokens []float64
+		rates  []float64
+	}{
+		{"pp" + strconv.Itoa(request.PromptTokens), promptTokens, promptRates},
+		{"tg" + strconv.Itoa(request.GenerationTokens), generatedTokens, generationRates},
+	} {
+		mean, stdDev := meanAndStdDev(test.rates)
+		tokens, _ := meanAndStdDev(test.tokens)
+		result.Tests = append(result.Tests, BenchmarkTest{
+			Name:                  test.name,
+			Tokens:                tokens,
+			TokensPerSecond:       mean,
+			TokensPerSecondStdDev: stdDev,
+		})
+	}
+	result.RAMBytes, result.VRAMBytes = s.runnerMemory(ctx, backendName, model, inference.BackendModeCompletion)
+	return result, nil
+}
+
+// benchmarkUsage is the token usage reported in a completion response.
+type benchmarkUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// benchmarkRun sends a completion request with a synthetic prompt of about
+// promptTokens tokens, generating maxTokens tokens, and returns the token usage
+// reported by the backend and the time taken to serve the request.
+func (s *Scheduler) benchmarkRun(ctx context.Context, backendName, model string, run, promptTokens, maxTokens int) (benchmarkUsage, time.Duration, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":       model,
+		"prompt":      benchmarkPrompt(run, promptTokens),
+		"max_tokens":  maxTokens,
+		"temperature": 0,
+		"ignore_eos":  true,
+	})
+	if err != nil {
+		return benchmarkUsage{}, 0, err
+	}
+	start := time.Now()
+	w, err := s.serveInternalRequest(ctx, backendName, "/v1/completions", body, priorityNormal)
+	if err != nil {
+		return benchmarkUsage{}, 0, err
+	}
+	elapsed := time.Since(start)
+	if w.statusCode != http.StatusOK {
+		return benchmarkUsage{}, 0, fmt.Errorf("completion request failed with status %d: %s", w.statusCode, strings.TrimSpace(w.body.String()))
+	}
+	var response struct {
+		Usage benchmarkUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &response); err != nil {
+		return benchmarkUsage{}, 0, fmt.Errorf("invalid completion response: %w", err)
+	}
+	// Assume the requested lengths if the backend doesn't report its usage.
+	if response.Usage.PromptTokens == 0 {
+		response.Usage.PromptTokens = promptTokens
+	}
+	if response.Usage.CompletionTokens == 0 {
+		response.Usage.CompletionTokens = maxTokens
+	}
+	return response.Usage, elapsed, nil
+}
+
+// benchmarkPrompt returns a synthetic prompt of about the given number of
+// tokens, starting with the number of the run.
+func benchmarkPrompt(run, tokens int) string {
+	words := make([]string, 0, tokens)
+	words = append(words, strconv.Itoa(run))
+	for i := 1; i < tokens; i++ {
+		words = append(words, benchmarkWords[(run+i)%len(benchmarkWords)])
+	}
+	return strings.Join(words, " ")
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of values.
+func meanAndStdDev(values []float64) (mean, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stdDev / float64(len(values)-1))
+}
+
+// runnerMemory returns the memory allocated to the runner of a model, or zero
+// sizes if unknown.
+func (s *Scheduler) runnerMemory(ctx context.Context, backendName, model string, mode inference.BackendMode) (ram, vram uint64) {
+	modelID := s.modelManager.ResolveModelID(model)
+	if !s.loader.lock(ctx) {
+		return 0, 0
+	}
+	defer s.loader.unlock()
+	for key, info := range s.loader.runners {
+		if key.backend != backendName || key.modelID != modelID || key.mode != mode || key.replica != 0 {
+			continue
+		}
+		// Allocations of 1 byte stand for unknown memory sizes.
+		allocation := s.loader.allocations[info.slot]
+		if allocation.RAM > 1 {
+			ram = allocation.RAM
+		}
+		if allocation.VRAM > 1 {
+			vram = allocation.VRAM
+		}
+		break
+	}
+	return ram, vram
+}
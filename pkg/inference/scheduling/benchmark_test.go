@@ -0,0 +1,50 @@
+package scheduling
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestBenchmarkPrompt(t *testing.T) {
+	prompt := benchmarkPrompt(3, 512)
+	if words := strings.Fields(prompt); len(words) != 512 || words[0] != "3" {
+		t.Errorf("Expected 512 words starting with the run number, got %d starting with %q", len(words), words[0])
+	}
+	if benchmarkPrompt(1, 512) == benchmarkPrompt(2, 512) {
+		t.Error("Expected the prompts of different runs to differ")
+	}
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	mean, stdDev := meanAndStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(stdDev-2.138) > 0.001 {
+		t.Errorf("Expected mean 5 and standard deviation 2.138, got %f and %f", mean, stdDev)
+	}
+	if mean, stdDev := meanAndStdDev([]float64{42}); mean != 42 || stdDev != 0 {
+		t.Errorf("Expected mean 42 and no deviation for a single value, got %f and %f", mean, stdDev)
+	}
+}
+
+func TestBenchmarkValidation(t *testing.T) {
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	for _, body := range []string{
+		`{`,
+		`{"prompt_tokens":-1}`,
+		`{"generation_tokens":100000}`,
+		`{"repetitions":1000}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/engines/ai/modelX/benchmark", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for request %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
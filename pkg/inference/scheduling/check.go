@@ -0,0 +1,82 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// CheckModel assesses whether a model can run on the current hardware without
+// pulling it. The model is specified by the model query parameter, and the
+// context size to assume can be specified by the context-size query parameter.
+func (s *Scheduler) CheckModel(w http.ResponseWriter, r *http.Request) {
+	// Determine the requested backend and ensure that it's valid.
+	var backend inference.Backend
+	if b := r.PathValue("backend"); b == "" {
+		backend = s.defaultBackend
+	} else {
+		backend = s.backends[b]
+	}
+	if backend == nil {
+		http.Error(w, ErrBackendNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	checker, ok := backend.(inference.ModelChecker)
+	if !ok {
+		http.Error(w, "model checks not supported by "+backend.Name(), http.StatusNotImplemented)
+		return
+	}
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		http.Error(w, "model is required", http.StatusBadRequest)
+		return
+	}
+	var config *inference.BackendConfiguration
+	if v := r.URL.Query().Get("context-size"); v != "" {
+		contextSize, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || contextSize <= 0 {
+			http.Error(w, "invalid context size", http.StatusBadRequest)
+			return
+		}
+		config = &inference.BackendConfiguration{ContextSize: contextSize}
+	}
+
+	// GPU support is only known once the backend is installed.
+	if err := s.installer.wait(r.Context(), backend.Name()); err != nil {
+		if errors.Is(err, context.Canceled) {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, "backend installation failed: "+err.Error(), http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	check, err := checker.CheckModel(r.Context(), model, config, s.loader.totalMemory)
+	if err != nil {
+		s.log.Warnf("Failed to check model %s: %v", utils.SanitizeForLog(model), err)
+		switch {
+		case errors.Is(err, registry.ErrModelNotFound) || errors.Is(err, distribution.ErrModelNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, registry.ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, registry.ErrInvalidReference):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(check); err != nil {
+		s.log.Warnln("Error while encoding model check response:", err)
+	}
+}
@@ -0,0 +1,73 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// checkingBackend is a backend that supports model checks.
+type checkingBackend struct {
+	mockBackend
+	config *inference.BackendConfiguration
+}
+
+func (b *checkingBackend) CheckModel(_ context.Context, model string, config *inference.BackendConfiguration, available inference.RequiredMemory) (inference.ModelCheck, error) {
+	b.config = config
+	return inference.ModelCheck{Backend: b.name, Available: available, Fits: true, Verdict: "fits " + model}, nil
+}
+
+func TestCheckModel(t *testing.T) {
+	checker := &checkingBackend{mockBackend: mockBackend{name: "checking"}}
+	plain := &mockBackend{name: "plain"}
+	backends := map[string]inference.Backend{"checking": checker, "plain": plain}
+	s := NewScheduler(createTestLogger(), backends, checker, nil, nil, nil, nil, systemMemoryInfo{})
+
+	// The mock backends install immediately.
+	s.installer.run(context.Background())
+
+	tests := []struct {
+		name           string
+		backend        string
+		query          string
+		expectedStatus int
+	}{
+		{name: "default backend", query: "?model=ai/model", expectedStatus: http.StatusOK},
+		{name: "explicit backend", backend: "checking", query: "?model=ai/model&context-size=8192", expectedStatus: http.StatusOK},
+		{name: "unknown backend", backend: "unknown", query: "?model=ai/model", expectedStatus: http.StatusNotFound},
+		{name: "unsupported backend", backend: "plain", query: "?model=ai/model", expectedStatus: http.StatusNotImplemented},
+		{name: "missing model", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "invalid context size", query: "?model=ai/model&context-size=-1", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/engines/_check"+tt.query, nil)
+			if tt.backend != "" {
+				r.SetPathValue("backend", tt.backend)
+			}
+			w := httptest.NewRecorder()
+			s.CheckModel(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var check inference.ModelCheck
+			if err := json.NewDecoder(w.Body).Decode(&check); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if check.Verdict != "fits ai/model" || check.Available != s.loader.totalMemory {
+				t.Errorf("Unexpected check result: %+v", check)
+			}
+		})
+	}
+
+	if checker.config == nil || checker.config.ContextSize != 8192 {
+		t.Errorf("Expected context size to be passed to backend, got %+v", checker.config)
+	}
+}
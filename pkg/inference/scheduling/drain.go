@@ -0,0 +1,129 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultDrainTimeout is the default time for which a drain waits for
+// in-flight requests.
+const defaultDrainTimeout = 30 * time.Second
+
+// errDraining indicates that the loader is draining, so new requests are
+// rejected. If returned in conjunction with an HTTP request, it should be
+// paired with a 503 response status.
+var errDraining = errors.New("draining: not accepting new requests")
+
+// DrainStatus reports the progress of a drain.
+type DrainStatus struct {
+	// Draining indicates that new inference requests are rejected.
+	Draining bool `json:"draining"`
+	// InFlight is the number of inference requests still being served.
+	InFlight int `json:"in_flight"`
+	// Runners is the number of runners still loaded.
+	Runners int `json:"runners"`
+	// Drained indicates that no requests are in flight and all runners are
+	// unloaded.
+	Drained bool `json:"drained"`
+}
+
+// drainStatus returns the progress of a drain. The caller must hold the loader
+// lock.
+func (l *loader) drainStatus() DrainStatus {
+	status := DrainStatus{Draining: l.draining, Runners: len(l.runners)}
+	for _, info := range l.runners {
+		status.InFlight += int(l.references[info.slot])
+	}
+	status.Drained = l.draining && status.InFlight == 0 && status.Runners == 0
+	return status
+}
+
+// drain stops the loader from accepting new requests, waits for in-flight
+// requests to complete until ctx is cancelled, and then unloads all unused
+// runners. Draining can't be undone.
+func (l *loader) drain(ctx context.Context) DrainStatus {
+	l.lock(context.Background())
+	defer l.unlock()
+
+	if !l.draining {
+		l.log.Infoln("Draining: rejecting new requests")
+		l.draining = true
+		// Waiting loads are rejected.
+		l.broadcast()
+	}
+
+	// Create a polling channel that we can use to detect released runners
+	// and ensure that it's deregistered by the time we return.
+	poll := make(chan struct{}, 1)
+	l.waiters[poll] = true
+	defer func() {
+		delete(l.waiters, poll)
+	}()
+
+	// Wait for in-flight requests to complete.
+	for status := l.drainStatus(); status.InFlight > 0; status = l.drainStatus() {
+		l.log.Infof("Draining: waiting for %d in-flight request(s)", status.InFlight)
+		l.unlock()
+		select {
+		case <-poll:
+		case <-ctx.Done():
+		}
+		l.lock(context.Background())
+		if ctx.Err() != nil {
+			l.log.Warnf("Draining: timed out with %d request(s) in flight", l.drainStatus().InFlight)
+			break
+		}
+	}
+
+	l.evict(false)
+	return l.drainStatus()
+}
+
+// Drain stops the scheduler from accepting new inference requests, waits for
+// in-flight requests to complete until ctx is cancelled, and then unloads all
+// unused runners. Draining can't be undone, so it's meant to precede shutdown.
+func (s *Scheduler) Drain(ctx context.Context) DrainStatus {
+	return s.loader.drain(ctx)
+}
+
+// handleDrain handles POST <inference-prefix>/drain requests, which drain the
+// scheduler and report the resulting status. The timeout query parameter is
+// the maximum time to wait for in-flight requests, and defaults to 30s.
+func (s *Scheduler) handleDrain(w http.ResponseWriter, r *http.Request) {
+	timeout := defaultDrainTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil || timeout < 0 {
+			http.Error(w, fmt.Sprintf("invalid timeout %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	status := s.Drain(ctx)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		s.log.Warnln("Error while encoding drain status:", err)
+	}
+}
+
+// handleGetDrainStatus handles GET <inference-prefix>/drain requests.
+func (s *Scheduler) handleGetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.loader.lock(r.Context()) {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	status := s.loader.drainStatus()
+	s.loader.unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		s.log.Warnln("Error while encoding drain status:", err)
+	}
+}
@@ -0,0 +1,87 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// newDrainTestLoader creates a loader with loads enabled and a runner serving
+// a single in-flight request.
+func newDrainTestLoader(t *testing.T) (*loader, *runner) {
+	t.Helper()
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend", requiredMemory: inference.RequiredMemory{RAM: 1 * GB, VRAM: 1 * GB}}
+	sysMemInfo := &mockSystemMemoryInfo{totalMemory: inference.RequiredMemory{RAM: 8 * GB, VRAM: 8 * GB}}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock")
+	}
+	loader.loadsEnabled = true
+	r := createAliveTerminableMockRunner(log, backend)
+	slot := 0
+	loader.slots[slot] = r
+	loader.runners[makeRunnerKey("test-backend", r.model, "", r.mode)] = runnerInfo{slot: slot, modelRef: "modelX:latest"}
+	loader.references[slot] = 1
+	loader.allocations[slot] = inference.RequiredMemory{RAM: 1 * GB, VRAM: 1 * GB}
+	loader.availableMemory.RAM -= 1 * GB
+	loader.availableMemory.VRAM -= 1 * GB
+	loader.unlock()
+	return loader, r
+}
+
+func TestDrainWaitsForInFlightRequests(t *testing.T) {
+	loader, r := newDrainTestLoader(t)
+
+	drained := make(chan DrainStatus, 1)
+	go func() {
+		drained <- loader.drain(context.Background())
+	}()
+
+	// New requests are rejected, even for the loaded model, once draining.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := loader.load(context.Background(), "test-backend", r.model, "modelX:latest", r.mode, "")
+		if errors.Is(err, errDraining) {
+			break
+		}
+		if err == nil {
+			loader.release(r)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected errDraining, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case status := <-drained:
+		t.Fatalf("Expected the drain to wait for the in-flight request, got %+v", status)
+	default:
+	}
+
+	// Completing the in-flight request completes the drain.
+	loader.release(r)
+	select {
+	case status := <-drained:
+		if !status.Drained || status.InFlight != 0 || status.Runners != 0 {
+			t.Errorf("Expected a complete drain, got %+v", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain didn't complete after the in-flight request")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	loader, _ := newDrainTestLoader(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	status := loader.drain(ctx)
+	if !status.Draining || status.Drained || status.InFlight != 1 || status.Runners != 1 {
+		t.Errorf("Expected the in-flight request to remain after the timeout, got %+v", status)
+	}
+}
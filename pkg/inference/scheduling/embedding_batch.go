@@ -0,0 +1,469 @@
+package scheduling
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/internal/utils"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+const (
+	// defaultEmbeddingBatchSize is the number of inputs of an embedding batch
+	// job sent to the runner per request, unless the job sets one.
+	defaultEmbeddingBatchSize = 64
+	// maximumEmbeddingBatchSize is the maximum number of inputs sent to the
+	// runner per request.
+	maximumEmbeddingBatchSize = 2048
+	// maximumEmbeddingBatchRequestSize is the maximum size of a request
+	// creating an embedding batch job.
+	maximumEmbeddingBatchRequestSize = 256 * 1024 * 1024
+	// maximumEmbeddingBatchJobs is the maximum number of embedding batch jobs
+	// that are kept. The oldest finished job is forgotten beyond it.
+	maximumEmbeddingBatchJobs = 32
+)
+
+// errTooManyEmbeddingBatches indicates that an embedding batch job can't be
+// created because the maximum number of jobs are still in progress.
+var errTooManyEmbeddingBatches = errors.New("too many embedding batch jobs in progress")
+
+// EmbeddingBatchRequest creates an embedding batch job.
+type EmbeddingBatchRequest struct {
+	// Model is the embedding model.
+	Model string `json:"model"`
+	// Input are the inputs to embed.
+	Input []string `json:"input,omitempty"`
+	// InputFileID is the ID of a file uploaded by the caller with the batch
+	// purpose, with one input per line. It's used instead of Input.
+	InputFileID string `json:"input_file_id,omitempty"`
+	// BatchSize is the number of inputs sent to the runner per request.
+	BatchSize int `json:"batch_size,omitempty"`
+}
+
+// EmbeddingBatchStatus is the status of an embedding batch job.
+type EmbeddingBatchStatus string
+
+const (
+	// EmbeddingBatchInProgress is the status of jobs being processed.
+	EmbeddingBatchInProgress EmbeddingBatchStatus = "in_progress"
+	// EmbeddingBatchCompleted is the status of jobs whose results are ready.
+	EmbeddingBatchCompleted EmbeddingBatchStatus = "completed"
+	// EmbeddingBatchFailed is the status of jobs that failed.
+	EmbeddingBatchFailed EmbeddingBatchStatus = "failed"
+	// EmbeddingBatchCancelled is the status of jobs cancelled by clients.
+	EmbeddingBatchCancelled EmbeddingBatchStatus = "cancelled"
+)
+
+// EmbeddingBatchJob describes an embedding batch job.
+type EmbeddingBatchJob struct {
+	// ID identifies the job.
+	ID string `json:"id"`
+	// Model is the embedding model.
+	Model string `json:"model"`
+	// Status is the status of the job.
+	Status EmbeddingBatchStatus `json:"status"`
+	// Total is the number of inputs.
+	Total int `json:"total"`
+	// Completed is the number of inputs embedded so far.
+	Completed int `json:"completed"`
+	// Error describes why the job failed.
+	Error string `json:"error,omitempty"`
+	// CreatedAt is when the job was created.
+	CreatedAt time.Time `json:"created_at"`
+	// FinishedAt is when the job completed, failed or was cancelled.
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// embeddingBatch is an embedding batch job and its results.
+type embeddingBatch struct {
+	// cancel cancels the job.
+	cancel context.CancelFunc
+	// owner is the fingerprint of the API key that created the job, which is
+	// only visible to it.
+	owner string
+	// inputs are the inputs to embed.
+	inputs []string
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// job describes the job.
+	job EmbeddingBatchJob
+	// embeddings are the embeddings of the inputs, by input index.
+	embeddings []json.RawMessage
+	// promptTokens is the number of tokens of the inputs embedded so far.
+	promptTokens uint64
+}
+
+// status returns a description of the job.
+func (b *embeddingBatch) status() EmbeddingBatchJob {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.job
+}
+
+// finish records the end of the job with the given status.
+func (b *embeddingBatch) finish(status EmbeddingBatchStatus, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	now := time.Now()
+	b.job.Status = status
+	b.job.FinishedAt = &now
+	if err != nil {
+		b.job.Error = err.Error()
+	}
+}
+
+// embeddingBatches are the embedding batch jobs of a scheduler.
+type embeddingBatches struct {
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// batches maps job IDs to jobs.
+	batches map[string]*embeddingBatch
+	// order are the job IDs, oldest first.
+	order []string
+}
+
+// newEmbeddingBatches creates an empty set of embedding batch jobs.
+func newEmbeddingBatches() *embeddingBatches {
+	return &embeddingBatches{batches: make(map[string]*embeddingBatch)}
+}
+
+// add adds a job, forgetting the oldest finished job if there are too many.
+func (e *embeddingBatches) add(batch *embeddingBatch) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.order) >= maximumEmbeddingBatchJobs {
+		index := slices.IndexFunc(e.order, func(id string) bool {
+			return e.batches[id].status().Status != EmbeddingBatchInProgress
+		})
+		if index < 0 {
+			return errTooManyEmbeddingBatches
+		}
+		delete(e.batches, e.order[index])
+		e.order = slices.Delete(e.order, index, index+1)
+	}
+	e.batches[batch.job.ID] = batch
+	e.order = append(e.order, batch.job.ID)
+	return nil
+}
+
+// get returns a job of owner by ID.
+func (e *embeddingBatches) get(owner, id string) (*embeddingBatch, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	batch, ok := e.batches[id]
+	if !ok || batch.owner != owner {
+		return nil, false
+	}
+	return batch, true
+}
+
+// remove forgets a job.
+func (e *embeddingBatches) remove(id string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.batches, id)
+	e.order = slices.DeleteFunc(e.order, func(other string) bool { return other == id })
+}
+
+// newBatchID returns a random ID with the given prefix for a batch job or
+// one of its files.
+func newBatchID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("generating batch ID: %v", err))
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
+// readEmbeddingBatchInputs reads the inputs of a job from the lines of an
+// uploaded file, skipping empty lines.
+func readEmbeddingBatchInputs(content []byte) ([]string, error) {
+	var inputs []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, maximumOpenAIInferenceRequestSize)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			inputs = append(inputs, line)
+		}
+	}
+	return inputs, scanner.Err()
+}
+
+// bufferedResponseWriter is a response writer that buffers the responses to
+// the requests of embedding batch jobs.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.Header.
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// Write implements http.ResponseWriter.Write.
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.WriteHeader.
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+// serveBatchRequest serves an inference request of a batch job at low
+// priority, as if it had been sent to the given endpoint of a backend (or the
+// default backend if backendName is empty), and returns the buffered response.
+func (s *Scheduler) serveBatchRequest(ctx context.Context, backendName, endpoint string, body []byte) (*bufferedResponseWriter, error) {
+	return s.serveInternalRequest(ctx, backendName, endpoint, body, priorityLow)
+}
+
+// serveInternalRequest serves an inference request made by the model runner
+// itself at the given priority, as if it had been sent to the given endpoint
+// of a backend (or the default backend if backendName is empty), and returns
+// the buffered response.
+func (s *Scheduler) serveInternalRequest(ctx context.Context, backendName, endpoint string, body []byte, p priority) (*bufferedResponseWriter, error) {
+	path := inference.InferencePrefix + endpoint
+	if backendName != "" {
+		path = inference.InferencePrefix + "/" + backendName + endpoint
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(PriorityHeader, p.String())
+	request.SetPathValue("backend", backendName)
+	w := &bufferedResponseWriter{header: make(http.Header)}
+	s.handleOpenAIInference(w, request)
+	return w, nil
+}
+
+// CreateEmbeddingBatch handles POST <inference-prefix>/{backend}/v1/embeddings/batch
+// requests, which create a job embedding a large set of inputs in the
+// background, at low priority.
+func (s *Scheduler) CreateEmbeddingBatch(w http.ResponseWriter, r *http.Request) {
+	backendName := r.PathValue("backend")
+	if backendName != "" && s.backends[backendName] == nil {
+		http.Error(w, ErrBackendNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	var request EmbeddingBatchRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumEmbeddingBatchRequestSize))
+	if err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			http.Error(w, "request too large", http.StatusBadRequest)
+		} else {
+			http.Error(w, "unknown error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if request.Model == "" {
+		http.Error(w, "model is required", http.StatusBadRequest)
+		return
+	}
+	if request.BatchSize == 0 {
+		request.BatchSize = defaultEmbeddingBatchSize
+	}
+	if request.BatchSize < 0 || request.BatchSize > maximumEmbeddingBatchSize {
+		http.Error(w, fmt.Sprintf("batch_size must be between 1 and %d", maximumEmbeddingBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	owner := metrics.APIKeyFingerprint(r)
+	inputs := request.Input
+	if request.InputFileID != "" {
+		if len(inputs) > 0 {
+			http.Error(w, "input and input_file_id are mutually exclusive", http.StatusBadRequest)
+			return
+		}
+		input, err := s.batches.file(owner, request.InputFileID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if input.info.Purpose != "batch" {
+			http.Error(w, "the input file must have the batch purpose", http.StatusBadRequest)
+			return
+		}
+		if inputs, err = readEmbeddingBatchInputs(input.content); err != nil {
+			http.Error(w, fmt.Sprintf("unable to read input file: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(inputs) == 0 {
+		http.Error(w, "input is required", http.StatusBadRequest)
+		return
+	}
+
+	// The requests of the job are made on behalf of its owner, so that their
+	// usage is accounted to its API key.
+	ctx, cancel := context.WithCancel(middleware.WithAPIKeyFingerprint(context.Background(), owner))
+	batch := &embeddingBatch{
+		cancel: cancel,
+		owner:  owner,
+		inputs: inputs,
+		job: EmbeddingBatchJob{
+			ID:        newBatchID("batch_"),
+			Model:     request.Model,
+			Status:    EmbeddingBatchInProgress,
+			Total:     len(inputs),
+			CreatedAt: time.Now(),
+		},
+		embeddings: make([]json.RawMessage, len(inputs)),
+	}
+	if err := s.embeddingBatches.add(batch); err != nil {
+		cancel()
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	s.log.Infof("Created embedding batch %s of %d inputs for %s", batch.job.ID, len(inputs), utils.SanitizeForLog(request.Model))
+	go s.runEmbeddingBatch(ctx, backendName, batch, request.BatchSize)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(batch.status())
+}
+
+// runEmbeddingBatch embeds the inputs of a job in batches, serving each batch
+// like an embedding request at low priority.
+func (s *Scheduler) runEmbeddingBatch(ctx context.Context, backendName string, batch *embeddingBatch, batchSize int) {
+	model := batch.job.Model
+	for start := 0; start < len(batch.inputs); start += batchSize {
+		inputs := batch.inputs[start:min(start+batchSize, len(batch.inputs))]
+		body, err := json.Marshal(map[string]any{"model": model, "input": inputs})
+		if err != nil {
+			batch.finish(EmbeddingBatchFailed, err)
+			return
+		}
+		w, err := s.serveBatchRequest(ctx, backendName, "/v1/embeddings", body)
+		if err != nil {
+			batch.finish(EmbeddingBatchFailed, err)
+			return
+		}
+		if ctx.Err() != nil {
+			batch.finish(EmbeddingBatchCancelled, nil)
+			return
+		}
+		if w.statusCode != http.StatusOK {
+			batch.finish(EmbeddingBatchFailed, fmt.Errorf("embedding request failed with status %d: %s",
+				w.statusCode, strings.TrimSpace(w.body.String())))
+			return
+		}
+
+		var response struct {
+			Data []struct {
+				Index     int             `json:"index"`
+				Embedding json.RawMessage `json:"embedding"`
+			} `json:"data"`
+			Usage struct {
+				PromptTokens uint64 `json:"prompt_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal(w.body.Bytes(), &response); err != nil {
+			batch.finish(EmbeddingBatchFailed, fmt.Errorf("decoding embedding response: %w", err))
+			return
+		}
+		batch.lock.Lock()
+		for _, data := range response.Data {
+			if data.Index >= 0 && data.Index < len(inputs) {
+				batch.embeddings[start+data.Index] = data.Embedding
+			}
+		}
+		batch.promptTokens += response.Usage.PromptTokens
+		batch.job.Completed = start + len(inputs)
+		batch.lock.Unlock()
+	}
+	batch.finish(EmbeddingBatchCompleted, nil)
+	s.log.Infof("Completed embedding batch %s", batch.job.ID)
+}
+
+// GetEmbeddingBatch handles GET <inference-prefix>/v1/embeddings/batch/{id}
+// requests, which report the progress of an embedding batch job.
+func (s *Scheduler) GetEmbeddingBatch(w http.ResponseWriter, r *http.Request) {
+	batch, ok := s.embeddingBatches.get(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if !ok {
+		http.Error(w, "embedding batch not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch.status())
+}
+
+// GetEmbeddingBatchResults handles GET
+// <inference-prefix>/v1/embeddings/batch/{id}/results requests, which return
+// the embeddings of a completed job in the format of an OpenAI embeddings
+// response.
+func (s *Scheduler) GetEmbeddingBatchResults(w http.ResponseWriter, r *http.Request) {
+	batch, ok := s.embeddingBatches.get(metrics.APIKeyFingerprint(r), r.PathValue("id"))
+	if !ok {
+		http.Error(w, "embedding batch not found", http.StatusNotFound)
+		return
+	}
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+	if batch.job.Status != EmbeddingBatchCompleted {
+		http.Error(w, fmt.Sprintf("embedding batch is %s", batch.job.Status), http.StatusConflict)
+		return
+	}
+
+	type embedding struct {
+		Object    string          `json:"object"`
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	}
+	data := make([]embedding, len(batch.embeddings))
+	for i, e := range batch.embeddings {
+		data[i] = embedding{Object: "embedding", Index: i, Embedding: e}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"model":  batch.job.Model,
+		"data":   data,
+		"usage": map[string]uint64{
+			"prompt_tokens": batch.promptTokens,
+			"total_tokens":  batch.promptTokens,
+		},
+	})
+}
+
+// DeleteEmbeddingBatch handles DELETE <inference-prefix>/v1/embeddings/batch/{id}
+// requests, which cancel an embedding batch job if it's in progress and
+// forget it.
+func (s *Scheduler) DeleteEmbeddingBatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	batch, ok := s.embeddingBatches.get(metrics.APIKeyFingerprint(r), id)
+	if !ok {
+		http.Error(w, "embedding batch not found", http.StatusNotFound)
+		return
+	}
+	batch.cancel()
+	s.embeddingBatches.remove(id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+func TestCreateEmbeddingBatchValidation(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	fileID := uploadBatchFile(t, s, "a\nb\n")
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "unknown backend", path: "/engines/unknown/v1/embeddings/batch", body: `{"model": "ai/model", "input": ["a"]}`, expectedStatus: http.StatusNotFound},
+		{name: "missing model", path: "/engines/v1/embeddings/batch", body: `{"input": ["a"]}`, expectedStatus: http.StatusBadRequest},
+		{name: "missing input", path: "/engines/v1/embeddings/batch", body: `{"model": "ai/model"}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative batch size", path: "/engines/v1/embeddings/batch", body: `{"model": "ai/model", "input": ["a"], "batch_size": -1}`, expectedStatus: http.StatusBadRequest},
+		{name: "input and input file", path: "/engines/v1/embeddings/batch", body: `{"model": "ai/model", "input": ["a"], "input_file_id": "` + fileID + `"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown input file", path: "/engines/v1/embeddings/batch", body: `{"model": "ai/model", "input_file_id": "file-unknown"}`, expectedStatus: http.StatusNotFound},
+		{name: "local input file", path: "/engines/v1/embeddings/batch", body: `{"model": "ai/model", "input_file": "/etc/hostname"}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEmbeddingBatchLifecycle(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	fileID := uploadBatchFile(t, s, "a\n\nb\nc\n")
+
+	r := httptest.NewRequest(http.MethodPost, "/engines/v1/embeddings/batch", strings.NewReader(
+		`{"model": "ai/model", "input_file_id": "`+fileID+`", "batch_size": 2}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var job EmbeddingBatchJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if job.Total != 3 {
+		t.Errorf("Expected 3 inputs, got %d", job.Total)
+	}
+
+	// The installer isn't running, so the first batch fails.
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == EmbeddingBatchInProgress && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/embeddings/batch/"+job.ID, nil))
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatalf("Failed to decode job: %v", err)
+		}
+	}
+	if job.Status != EmbeddingBatchFailed || !strings.Contains(job.Error, "503") || job.FinishedAt == nil {
+		t.Fatalf("Expected the job to fail with status 503, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/embeddings/batch/"+job.ID+"/results", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d for results, got %d", http.StatusConflict, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/engines/v1/embeddings/batch/"+job.ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d for deletion, got %d", http.StatusNoContent, w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/embeddings/batch/"+job.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d after deletion, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestEmbeddingBatchesForgetFinishedJobs(t *testing.T) {
+	batches := newEmbeddingBatches()
+	add := func(status EmbeddingBatchStatus) (string, error) {
+		batch := &embeddingBatch{job: EmbeddingBatchJob{ID: newBatchID("batch_"), Status: status}}
+		return batch.job.ID, batches.add(batch)
+	}
+
+	oldest, _ := add(EmbeddingBatchCompleted)
+	for range maximumEmbeddingBatchJobs - 1 {
+		if _, err := add(EmbeddingBatchInProgress); err != nil {
+			t.Fatalf("Failed to add job: %v", err)
+		}
+	}
+	if _, err := add(EmbeddingBatchInProgress); err != nil {
+		t.Fatalf("Expected the finished job to be forgotten, got %v", err)
+	}
+	if _, ok := batches.get("", oldest); ok {
+		t.Error("Expected the finished job to be forgotten")
+	}
+	if _, err := add(EmbeddingBatchInProgress); !errors.Is(err, errTooManyEmbeddingBatches) {
+		t.Errorf("Expected %v, got %v", errTooManyEmbeddingBatches, err)
+	}
+}
+
+func TestEmbeddingBatchUsageAccountedToOwner(t *testing.T) {
+	s := newServingTestScheduler(t)
+	keys, err := middleware.ParseAPIKeys(strings.NewReader("sk-a inference\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	handler := middleware.AuthMiddleware(keys, s.InferenceRoutes(), s)
+
+	r := httptest.NewRequest(http.MethodPost, "/engines/test-backend/v1/embeddings/batch", strings.NewReader(
+		`{"model": "ai/model", "input": ["a", "b"]}`))
+	r.Header.Set("Authorization", "Bearer sk-a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var job EmbeddingBatchJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || job.ID == "" {
+		t.Fatalf("Failed to create job: %d %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == EmbeddingBatchInProgress && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if batch, ok := s.embeddingBatches.get(middleware.Fingerprint("sk-a"), job.ID); ok {
+			job = batch.status()
+		}
+	}
+	if job.Status != EmbeddingBatchCompleted {
+		t.Fatalf("Expected the job to complete, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/usage", nil))
+	var usage metrics.UsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	if len(usage.Usage) != 1 || usage.Usage[0].APIKey != middleware.Fingerprint("sk-a") {
+		t.Errorf("Expected the usage of the job to be accounted to its key, got %+v", usage.Usage)
+	}
+}
@@ -0,0 +1,70 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// GetEngines returns the capabilities of each backend, sorted by name, so that
+// clients can present the options each of them supports:
+// - GET <inference-prefix>
+func (s *Scheduler) GetEngines(w http.ResponseWriter, _ *http.Request) {
+	engines := make([]inference.BackendInfo, 0, len(s.backends))
+	for name, backend := range s.backends {
+		engines = append(engines, s.backendInfo(name, backend))
+	}
+	slices.SortFunc(engines, func(a, b inference.BackendInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(engines)
+}
+
+// backendInfo describes a backend's capabilities and health.
+func (s *Scheduler) backendInfo(name string, backend inference.Backend) inference.BackendInfo {
+	info := inference.BackendInfo{
+		Name:    name,
+		Default: s.defaultBackend != nil && s.defaultBackend.Name() == name,
+		Formats: backendFormats(backend),
+		Modes:   []string{},
+		Status:  backend.Status(),
+	}
+	for _, mode := range inference.BackendModes {
+		if supportsMode(name, mode) {
+			info.Modes = append(info.Modes, mode.String())
+		}
+	}
+	if reporter, ok := backend.(inference.VersionReporter); ok {
+		info.Version = reporter.Version()
+		info.Accelerator = reporter.Accelerator()
+	}
+	var err error
+	info.Health, err = s.installer.health(name)
+	if err != nil {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// backendFormats returns the model formats a backend serves, which are the
+// formats dedicated to it in formatBackends, or GGUF for the other backends
+// using the shared model manager.
+func backendFormats(backend inference.Backend) []string {
+	formats := []string{}
+	for format, name := range formatBackends {
+		if name == backend.Name() {
+			formats = append(formats, string(format))
+		}
+	}
+	if len(formats) == 0 && !backend.UsesExternalModelManagement() {
+		formats = append(formats, string(types.FormatGGUF))
+	}
+	slices.Sort(formats)
+	return formats
+}
@@ -0,0 +1,103 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/whispercpp"
+)
+
+// versionedBackend is a backend that reports its version and accelerator.
+type versionedBackend struct {
+	mockBackend
+}
+
+func (b *versionedBackend) Version() string {
+	return "b1234"
+}
+
+func (b *versionedBackend) Accelerator() string {
+	return "cuda"
+}
+
+// failingBackend is a backend whose installation fails.
+type failingBackend struct {
+	mockBackend
+}
+
+func (b *failingBackend) Install(context.Context, *http.Client) error {
+	return errors.New("platform not supported")
+}
+
+func TestGetEngines(t *testing.T) {
+	versioned := &versionedBackend{mockBackend: mockBackend{name: "versioned"}}
+	backends := map[string]inference.Backend{
+		"versioned":     versioned,
+		whispercpp.Name: &mockBackend{name: whispercpp.Name},
+		"external":      &mockBackend{name: "external", usesExternalModelMgmt: true},
+		"failing":       &failingBackend{mockBackend: mockBackend{name: "failing"}},
+	}
+	s := NewScheduler(createTestLogger(), backends, versioned, nil, nil, nil, nil, systemMemoryInfo{})
+
+	getEngines := func() map[string]inference.BackendInfo {
+		w := httptest.NewRecorder()
+		s.GetEngines(w, httptest.NewRequest(http.MethodGet, "/engines", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code 200, got %d", w.Code)
+		}
+		var engines []inference.BackendInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &engines); err != nil {
+			t.Fatalf("Failed to decode engines: %v", err)
+		}
+		if len(engines) != len(backends) {
+			t.Fatalf("Expected %d engines, got %d", len(backends), len(engines))
+		}
+		byName := make(map[string]inference.BackendInfo, len(engines))
+		for i, engine := range engines {
+			if i > 0 && engines[i-1].Name > engine.Name {
+				t.Errorf("Expected engines sorted by name, got %s before %s", engines[i-1].Name, engine.Name)
+			}
+			byName[engine.Name] = engine
+		}
+		return byName
+	}
+
+	// Backends are installing until the installer runs.
+	if health := getEngines()["versioned"].Health; health != healthInstalling {
+		t.Errorf("Expected health %q before installation, got %q", healthInstalling, health)
+	}
+	s.installer.run(context.Background())
+	engines := getEngines()
+
+	versionedInfo := engines["versioned"]
+	if !versionedInfo.Default || versionedInfo.Version != "b1234" || versionedInfo.Accelerator != "cuda" || versionedInfo.Health != healthReady {
+		t.Errorf("Unexpected default backend info %+v", versionedInfo)
+	}
+	if !reflect.DeepEqual(versionedInfo.Formats, []string{"gguf"}) {
+		t.Errorf("Expected the default backend to serve GGUF models, got %v", versionedInfo.Formats)
+	}
+	if !reflect.DeepEqual(versionedInfo.Modes, []string{"completion", "embedding", "reranking"}) {
+		t.Errorf("Unexpected default backend modes %v", versionedInfo.Modes)
+	}
+
+	whisperInfo := engines[whispercpp.Name]
+	if whisperInfo.Default || whisperInfo.Version != "" ||
+		!reflect.DeepEqual(whisperInfo.Formats, []string{"whisper"}) ||
+		!reflect.DeepEqual(whisperInfo.Modes, []string{"transcription"}) {
+		t.Errorf("Unexpected whisper.cpp backend info %+v", whisperInfo)
+	}
+
+	if formats := engines["external"].Formats; len(formats) != 0 {
+		t.Errorf("Expected no formats for a backend with external model management, got %v", formats)
+	}
+
+	if failing := engines["failing"]; failing.Health != healthError || failing.Error != "platform not supported" {
+		t.Errorf("Expected a failed installation to be reported, got %+v", failing)
+	}
+}
@@ -0,0 +1,87 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// ModelHeader is the response header reporting the model that served an
+// inference request, set when a fallback model or the model of a traffic
+// split served it in place of the requested one.
+const ModelHeader = "X-DMR-Model"
+
+// SetFallbackModels sets, for models, the models that requests are retried
+// against, in order, when the requested model fails to load, e.g. because it's
+// too big or its backend crashes. Fallback models must be served by the same
+// backend as the requested model. It must be called before Run.
+func (s *Scheduler) SetFallbackModels(fallbacks map[string][]string) {
+	s.fallbackModels = make(map[string][]string, len(fallbacks))
+	for model, chain := range fallbacks {
+		normalized := make([]string, 0, len(chain))
+		for _, fallback := range chain {
+			normalized = append(normalized, models.NormalizeModelName(fallback))
+		}
+		s.fallbackModels[models.NormalizeModelName(model)] = normalized
+	}
+}
+
+// canFallBack reports whether a request can be retried against a fallback
+// model after its model failed to load with err. Failures that would equally
+// affect any model, such as draining or the request being cancelled, aren't
+// retried.
+func canFallBack(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, errDraining) && !errors.Is(err, errLoadsDisabled)
+}
+
+// loadFallback loads a runner for the first fallback model of model that
+// loads, after model failed to load with err. It returns the fallback model
+// along with its runner, or err if no fallback model could be loaded.
+func (s *Scheduler) loadFallback(ctx context.Context, backend inference.Backend, model string, mode inference.BackendMode, session string, err error) (string, *runner, error) {
+	// Uploaded audio isn't JSON, so the model of transcription requests
+	// can't be replaced.
+	if backend.UsesExternalModelManagement() || mode == inference.BackendModeTranscription {
+		return "", nil, err
+	}
+	for _, fallback := range s.fallbackModels[models.NormalizeModelName(model)] {
+		if !canFallBack(ctx, err) {
+			break
+		}
+		fallbackModel, getErr := s.modelManager.GetModel(fallback)
+		if getErr != nil {
+			s.log.Warnf("Skipping fallback model %s: %v", utils.SanitizeForLog(fallback), getErr)
+			continue
+		}
+		if s.selectBackendForModel(fallbackModel, backend, fallback) != backend {
+			s.log.Warnf("Skipping fallback model %s, which isn't served by %s", utils.SanitizeForLog(fallback), backend.Name())
+			continue
+		}
+		s.log.Infof("Falling back from %s to %s: %v", utils.SanitizeForLog(model), utils.SanitizeForLog(fallback), err)
+		modelID := s.modelManager.ResolveModelID(fallback)
+		runner, loadErr := s.loader.load(ctx, backend.Name(), modelID, fallback, mode, session)
+		if loadErr == nil {
+			return fallback, runner, nil
+		}
+		err = loadErr
+	}
+	return "", nil, err
+}
+
+// withModel returns the body of an OpenAI request with its model replaced,
+// or the body as-is if it can't be decoded.
+func withModel(body []byte, model string) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	fields["model"], _ = json.Marshal(model)
+	replaced, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return replaced
+}
@@ -0,0 +1,66 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestSetFallbackModels(t *testing.T) {
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+	s.SetFallbackModels(map[string][]string{"llama3.3": {"ai/llama3.2", "smollm2:360M"}})
+
+	expected := map[string][]string{"ai/llama3.3:latest": {"ai/llama3.2:latest", "ai/smollm2:360M"}}
+	if !reflect.DeepEqual(s.fallbackModels, expected) {
+		t.Errorf("Expected normalized fallback models %v, got %v", expected, s.fallbackModels)
+	}
+}
+
+func TestCanFallBack(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expected bool
+	}{
+		{name: "too big", ctx: context.Background(), err: errModelTooBig, expected: true},
+		{name: "insufficient VRAM", ctx: context.Background(), err: errInsufficientVRAM, expected: true},
+		{name: "crashed", ctx: context.Background(), err: fmt.Errorf("error waiting for runner to be ready: %w", errors.New("exit status 1")), expected: true},
+		{name: "draining", ctx: context.Background(), err: errDraining, expected: false},
+		{name: "loads disabled", ctx: context.Background(), err: errLoadsDisabled, expected: false},
+		{name: "cancelled", ctx: cancelled, err: context.Canceled, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canFallBack(tt.ctx, tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithModel(t *testing.T) {
+	body := withModel([]byte(`{"model":"ai/llama3.3","messages":[{"role":"user","content":"Hi"}],"stream":true}`), "ai/llama3.2:latest")
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if request["model"] != "ai/llama3.2:latest" {
+		t.Errorf("Expected the model to be replaced, got %v", request["model"])
+	}
+	if request["stream"] != true || len(request["messages"].([]any)) != 1 {
+		t.Errorf("Expected the other fields to be kept, got %v", request)
+	}
+
+	if invalid := []byte("not json"); string(withModel(invalid, "ai/llama3.2")) != string(invalid) {
+		t.Error("Expected an undecodable body to be kept as-is")
+	}
+}
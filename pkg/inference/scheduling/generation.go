@@ -0,0 +1,82 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maximumImageDimension is the maximum width and height of generated
+	// images, to which the memory estimate of stable-diffusion.cpp is sized.
+	maximumImageDimension = 1024
+	// maximumGeneratedImages is the maximum number of images generated by a
+	// request, as in the OpenAI API.
+	maximumGeneratedImages = 10
+)
+
+// imageGenerationParameters are the parameters of an image generation request
+// that are validated before the request is passed, unchanged, to the backend.
+type imageGenerationParameters struct {
+	N              json.RawMessage `json:"n"`
+	Size           json.RawMessage `json:"size"`
+	ResponseFormat json.RawMessage `json:"response_format"`
+}
+
+// validateImageGenerationParameters validates the parameters of an image
+// generation request, returning the first invalid parameter and its error.
+func validateImageGenerationParameters(body []byte) (string, error) {
+	var params imageGenerationParameters
+	if err := json.Unmarshal(body, &params); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	if !isNull(params.N) {
+		if message := integerAtLeast(1)(params.N); message != "" {
+			return "n", fmt.Errorf("n %s", message)
+		}
+		var n int64
+		json.Unmarshal(params.N, &n)
+		if n > maximumGeneratedImages {
+			return "n", fmt.Errorf("n must be at most %d", maximumGeneratedImages)
+		}
+	}
+	if !isNull(params.Size) {
+		var size string
+		if err := json.Unmarshal(params.Size, &size); err != nil {
+			return "size", fmt.Errorf("size must be a string")
+		}
+		if err := validateImageSize(size); err != nil {
+			return "size", err
+		}
+	}
+	if !isNull(params.ResponseFormat) {
+		// Generated images are only returned inline, as the model runner
+		// doesn't host them.
+		var format string
+		if err := json.Unmarshal(params.ResponseFormat, &format); err != nil || format != "b64_json" {
+			return "response_format", fmt.Errorf("response_format must be b64_json")
+		}
+	}
+	return "", nil
+}
+
+// validateImageSize validates an image size of the form WIDTHxHEIGHT. Diffusion
+// models work on latents an eighth of the image size, so both dimensions must
+// be multiples of 8.
+func validateImageSize(size string) error {
+	width, height, ok := strings.Cut(size, "x")
+	if !ok {
+		return fmt.Errorf("size must be of the form WIDTHxHEIGHT")
+	}
+	for _, dimension := range []string{width, height} {
+		value, err := strconv.Atoi(dimension)
+		if err != nil {
+			return fmt.Errorf("size must be of the form WIDTHxHEIGHT")
+		}
+		if value < 64 || value > maximumImageDimension || value%8 != 0 {
+			return fmt.Errorf("size dimensions must be multiples of 8 between 64 and %d", maximumImageDimension)
+		}
+	}
+	return nil
+}
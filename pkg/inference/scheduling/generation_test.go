@@ -0,0 +1,37 @@
+package scheduling
+
+import "testing"
+
+func TestValidateImageGenerationParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		param string
+	}{
+		{name: "defaults", body: `{"model": "ai/sd", "prompt": "a cat"}`},
+		{name: "valid", body: `{"n": 2, "size": "768x512", "response_format": "b64_json"}`},
+		{name: "null parameters", body: `{"n": null, "size": null}`},
+		{name: "too many images", body: `{"n": 11}`, param: "n"},
+		{name: "no images", body: `{"n": 0}`, param: "n"},
+		{name: "fractional images", body: `{"n": 1.5}`, param: "n"},
+		{name: "too large", body: `{"size": "1792x1024"}`, param: "size"},
+		{name: "not a multiple of 8", body: `{"size": "500x500"}`, param: "size"},
+		{name: "malformed size", body: `{"size": "large"}`, param: "size"},
+		{name: "size not a string", body: `{"size": 512}`, param: "size"},
+		{name: "url response format", body: `{"response_format": "url"}`, param: "response_format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param, err := validateImageGenerationParameters([]byte(tt.body))
+			if tt.param == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || param != tt.param {
+				t.Errorf("Expected an error for %s, got %q: %v", tt.param, param, err)
+			}
+		})
+	}
+}
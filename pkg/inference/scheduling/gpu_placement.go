@@ -0,0 +1,131 @@
+package scheduling
+
+import (
+	"runtime"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// gpuPlacement is the placement of a runner on the GPUs of a multi-GPU system.
+type gpuPlacement struct {
+	// gpus are the GPUs selected for the runner, or nil if the runner may use
+	// all GPUs.
+	gpus []int
+	// vram is the VRAM allocated to the runner on each GPU, by GPU index.
+	vram []uint64
+}
+
+// placeOnGPUs places a runner requiring the given VRAM on GPUs with the given
+// free VRAM. Runners configured with GPUs are split across them in the
+// proportions of the configured tensor split, or else in proportion to their
+// free VRAM, as llama.cpp does. Runners configured with only a tensor split
+// are split across all GPUs. Other runners are placed on the GPU with the
+// most free VRAM, so that runners spread over the GPUs rather than compete
+// for the first one, or else split across all GPUs if no single GPU can hold
+// them. It returns false if the runner doesn't fit.
+func placeOnGPUs(vram uint64, config *inference.BackendConfiguration, free []uint64) (gpuPlacement, bool) {
+	if config != nil && len(config.GPUs) > 0 {
+		var proportions []float64
+		if len(config.TensorSplit) == len(config.GPUs) {
+			proportions = config.TensorSplit
+		}
+		return splitAcrossGPUs(vram, config.GPUs, proportions, free)
+	}
+
+	var proportions []float64
+	if config != nil && len(config.TensorSplit) > 0 {
+		if len(config.TensorSplit) != len(free) {
+			return gpuPlacement{}, false
+		}
+		proportions = config.TensorSplit
+	} else {
+		best := -1
+		for gpu, available := range free {
+			if available >= vram && (best < 0 || available > free[best]) {
+				best = gpu
+			}
+		}
+		if best >= 0 {
+			placement := gpuPlacement{gpus: []int{best}, vram: make([]uint64, len(free))}
+			placement.vram[best] = vram
+			return placement, true
+		}
+	}
+
+	all := make([]int, len(free))
+	for gpu := range free {
+		all[gpu] = gpu
+	}
+	placement, ok := splitAcrossGPUs(vram, all, proportions, free)
+	placement.gpus = nil
+	return placement, ok
+}
+
+// splitAcrossGPUs splits the given VRAM across GPUs in the given proportions,
+// or in proportion to their free VRAM if there are none. It returns false if
+// a GPU doesn't exist or can't hold its share.
+func splitAcrossGPUs(vram uint64, gpus []int, proportions []float64, free []uint64) (gpuPlacement, bool) {
+	placement := gpuPlacement{gpus: gpus, vram: make([]uint64, len(free))}
+	if proportions == nil {
+		proportions = make([]float64, len(gpus))
+		for i, gpu := range gpus {
+			if gpu >= len(free) {
+				return gpuPlacement{}, false
+			}
+			proportions[i] = float64(free[gpu])
+		}
+	}
+	var total float64
+	for _, proportion := range proportions {
+		total += proportion
+	}
+	if total == 0 {
+		return gpuPlacement{}, false
+	}
+	for i, gpu := range gpus {
+		if gpu >= len(free) {
+			return gpuPlacement{}, false
+		}
+		share := uint64(float64(vram) * proportions[i] / total)
+		if share > free[gpu] {
+			return gpuPlacement{}, false
+		}
+		placement.vram[gpu] = share
+	}
+	return placement, true
+}
+
+// placeOnGPUs places a runner requiring the given VRAM on the GPUs with the
+// given free VRAM, if the loader tracks VRAM per GPU. Runners with unknown
+// VRAM requirements are not placed. On Windows, runners that don't fit spill
+// into shared GPU memory, so they are loaded without a placement.
+func (l *loader) placeOnGPUs(vram uint64, config *inference.BackendConfiguration, free []uint64) (gpuPlacement, bool) {
+	if len(l.gpuMemory) == 0 || vram <= 1 {
+		return gpuPlacement{}, true
+	}
+	placement, ok := placeOnGPUs(vram, config, free)
+	if !ok && runtime.GOOS == "windows" {
+		return gpuPlacement{}, true
+	}
+	return placement, ok
+}
+
+// allocatedGPUs returns the indices of the GPUs on which VRAM is allocated to
+// the runner in the given slot. On systems where VRAM isn't tracked per GPU,
+// runners with VRAM allocated are reported on GPU 0. The caller must hold the
+// loader lock.
+func (l *loader) allocatedGPUs(slot int) []int {
+	if l.gpuAllocations[slot] == nil {
+		if l.allocations[slot].VRAM > 1 {
+			return []int{0}
+		}
+		return nil
+	}
+	var gpus []int
+	for gpu, vram := range l.gpuAllocations[slot] {
+		if vram > 0 {
+			gpus = append(gpus, gpu)
+		}
+	}
+	return gpus
+}
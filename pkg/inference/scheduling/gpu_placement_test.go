@@ -0,0 +1,171 @@
+package scheduling
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestPlaceOnGPUs(t *testing.T) {
+	tests := []struct {
+		name     string
+		vram     uint64
+		config   *inference.BackendConfiguration
+		free     []uint64
+		wantGPUs []int
+		wantVRAM []uint64
+		wantOK   bool
+	}{
+		{
+			name:     "most free GPU",
+			vram:     4 * GB,
+			free:     []uint64{6 * GB, 8 * GB},
+			wantGPUs: []int{1},
+			wantVRAM: []uint64{0, 4 * GB},
+			wantOK:   true,
+		},
+		{
+			name:     "only GPU that fits",
+			vram:     7 * GB,
+			free:     []uint64{8 * GB, 2 * GB},
+			wantGPUs: []int{0},
+			wantVRAM: []uint64{7 * GB, 0},
+			wantOK:   true,
+		},
+		{
+			name:     "split across all GPUs",
+			vram:     12 * GB,
+			free:     []uint64{8 * GB, 8 * GB},
+			wantVRAM: []uint64{6 * GB, 6 * GB},
+			wantOK:   true,
+		},
+		{
+			name:   "too big for all GPUs",
+			vram:   20 * GB,
+			free:   []uint64{8 * GB, 8 * GB},
+			wantOK: false,
+		},
+		{
+			name:     "configured GPU",
+			vram:     4 * GB,
+			config:   &inference.BackendConfiguration{GPUs: []int{0}},
+			free:     []uint64{6 * GB, 8 * GB},
+			wantGPUs: []int{0},
+			wantVRAM: []uint64{4 * GB, 0},
+			wantOK:   true,
+		},
+		{
+			name:     "configured tensor split",
+			vram:     8 * GB,
+			config:   &inference.BackendConfiguration{GPUs: []int{0, 1}, TensorSplit: []float64{3, 1}},
+			free:     []uint64{8 * GB, 8 * GB},
+			wantGPUs: []int{0, 1},
+			wantVRAM: []uint64{6 * GB, 2 * GB},
+			wantOK:   true,
+		},
+		{
+			name:   "configured tensor split too big for a GPU",
+			vram:   8 * GB,
+			config: &inference.BackendConfiguration{GPUs: []int{0, 1}, TensorSplit: []float64{3, 1}},
+			free:   []uint64{4 * GB, 8 * GB},
+			wantOK: false,
+		},
+		{
+			name:     "configured tensor split across all GPUs",
+			vram:     4 * GB,
+			config:   &inference.BackendConfiguration{TensorSplit: []float64{1, 1}},
+			free:     []uint64{8 * GB, 8 * GB},
+			wantVRAM: []uint64{2 * GB, 2 * GB},
+			wantOK:   true,
+		},
+		{
+			name:   "configured GPU not found",
+			vram:   4 * GB,
+			config: &inference.BackendConfiguration{GPUs: []int{2}},
+			free:   []uint64{8 * GB, 8 * GB},
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placement, ok := placeOnGPUs(tt.vram, tt.config, tt.free)
+			if ok != tt.wantOK {
+				t.Fatalf("placeOnGPUs() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !slices.Equal(placement.gpus, tt.wantGPUs) {
+				t.Errorf("placeOnGPUs() gpus = %v, want %v", placement.gpus, tt.wantGPUs)
+			}
+			if !slices.Equal(placement.vram, tt.wantVRAM) {
+				t.Errorf("placeOnGPUs() vram = %v, want %v", placement.vram, tt.wantVRAM)
+			}
+		})
+	}
+}
+
+func TestLoaderPlacesRunnersOnDifferentGPUs(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 16 * GB},
+		gpuMemory:   []uint64{8 * GB, 8 * GB},
+	}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+
+	// The first model goes on the first GPU with the most free VRAM.
+	first, ok := loader.placeOnGPUs(6*GB, nil, loader.availableGPUMemory)
+	if !ok || !slices.Equal(first.gpus, []int{0}) {
+		t.Fatalf("Expected first model on GPU 0, got %v (ok=%v)", first.gpus, ok)
+	}
+
+	// Register it as loaded on that GPU.
+	slot := 0
+	key := makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)
+	loader.slots[slot] = createAliveTerminableMockRunner(log, backend)
+	loader.runners[key] = runnerInfo{slot: slot, modelRef: "model1:latest"}
+	loader.allocations[slot] = inference.RequiredMemory{RAM: 1 * GB, VRAM: 6 * GB}
+	loader.availableMemory.RAM -= 1 * GB
+	loader.availableMemory.VRAM -= 6 * GB
+	for gpu, vram := range first.vram {
+		loader.availableGPUMemory[gpu] -= vram
+	}
+	loader.gpuAllocations[slot] = first.vram
+	loader.timestamps[slot] = time.Now()
+
+	// The second model no longer fits next to the first, so it goes on the
+	// other GPU instead of competing for GPU 0.
+	second, ok := loader.placeOnGPUs(6*GB, nil, loader.availableGPUMemory)
+	if !ok || !slices.Equal(second.gpus, []int{1}) {
+		t.Fatalf("Expected second model on GPU 1, got %v (ok=%v)", second.gpus, ok)
+	}
+
+	// A model configured for GPU 0 doesn't fit until the first is evicted.
+	if _, ok := loader.placeOnGPUs(6*GB, &inference.BackendConfiguration{GPUs: []int{0}}, loader.availableGPUMemory); ok {
+		t.Fatal("Expected GPU 0 to be full")
+	}
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock")
+	}
+	loader.freeRunnerSlot(slot, key)
+	loader.unlock()
+	if !slices.Equal(loader.availableGPUMemory, sysMemInfo.gpuMemory) {
+		t.Errorf("Expected all GPU memory to be available after eviction, got %v", loader.availableGPUMemory)
+	}
+}
+
+func TestLoaderIgnoresGPUsOnSingleGPUSystems(t *testing.T) {
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 8 * GB},
+		gpuMemory:   []uint64{8 * GB},
+	}
+	loader := newLoader(createTestLogger(), nil, nil, nil, sysMemInfo)
+	placement, ok := loader.placeOnGPUs(6*GB, nil, loader.availableGPUMemory)
+	if !ok || placement.gpus != nil || placement.vram != nil {
+		t.Errorf("Expected no placement on a single-GPU system, got %+v (ok=%v)", placement, ok)
+	}
+}
@@ -0,0 +1,438 @@
+package scheduling
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/docker/model-runner/pkg/inference/models"
+)
+
+// ErrGuardrailRejected indicates that the prompt of an inference request was
+// rejected by a guardrail. If returned in conjunction with an HTTP request, it
+// should be paired with a 400 response status.
+var ErrGuardrailRejected = errors.New("rejected by guardrail")
+
+// Guardrail is a content filter applied to the prompts of inference requests
+// and to the outputs of the chat and text completions generated for them.
+type Guardrail interface {
+	// Name identifies the guardrail in errors and metrics.
+	Name() string
+	// FilterPrompt returns the texts of the prompt of a request, possibly
+	// rewritten, or an error wrapping ErrGuardrailRejected if the request
+	// must be rejected.
+	FilterPrompt(texts []string) ([]string, error)
+	// FilterOutput returns generated text, possibly rewritten. Streamed
+	// outputs are filtered a line at a time.
+	FilterOutput(text string) string
+}
+
+// GuardrailConfig configures the built-in guardrails applied to the requests
+// for a set of models.
+type GuardrailConfig struct {
+	// Models are the models to whose requests the guardrails apply, or all
+	// models if empty.
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Blocklist are regular expressions that reject the prompts that match
+	// them and are redacted from outputs.
+	Blocklist []string `json:"blocklist,omitempty" yaml:"blocklist,omitempty"`
+	// BlocklistFile is a file of further blocklist regular expressions, one
+	// per line. Empty lines and lines starting with # are ignored.
+	BlocklistFile string `json:"blocklist-file,omitempty" yaml:"blocklist-file,omitempty"`
+	// MaxPromptLength is the maximum length of prompts, in characters. Zero
+	// disables the limit.
+	MaxPromptLength int `json:"max-prompt-length,omitempty" yaml:"max-prompt-length,omitempty"`
+	// ScrubPII replaces email addresses, phone numbers, credit card numbers
+	// and social security numbers in prompts and outputs with placeholders.
+	ScrubPII bool `json:"scrub-pii,omitempty" yaml:"scrub-pii,omitempty"`
+}
+
+// Validate checks the configuration, except for the blocklist file, which is
+// read by NewGuardrails.
+func (c GuardrailConfig) Validate() error {
+	if slices.Contains(c.Models, "") {
+		return errors.New("models can't be empty")
+	}
+	if len(c.Blocklist) == 0 && c.BlocklistFile == "" && c.MaxPromptLength == 0 && !c.ScrubPII {
+		return errors.New("no guardrail is configured")
+	}
+	if c.MaxPromptLength < 0 {
+		return fmt.Errorf("invalid max-prompt-length %d: must be non-negative", c.MaxPromptLength)
+	}
+	for _, pattern := range c.Blocklist {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid blocklist pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// NewGuardrails returns the built-in guardrails configured by c, in the order
+// in which they're applied.
+func NewGuardrails(c GuardrailConfig) ([]Guardrail, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var guardrails []Guardrail
+	if c.MaxPromptLength > 0 {
+		guardrails = append(guardrails, maxPromptLength(c.MaxPromptLength))
+	}
+	patterns := slices.Clone(c.Blocklist)
+	if c.BlocklistFile != "" {
+		filePatterns, err := readBlocklistFile(c.BlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	if len(patterns) > 0 {
+		b := &blocklist{}
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid blocklist pattern: %w", err)
+			}
+			b.patterns = append(b.patterns, re)
+		}
+		guardrails = append(guardrails, b)
+	}
+	if c.ScrubPII {
+		guardrails = append(guardrails, piiScrubber{})
+	}
+	return guardrails, nil
+}
+
+// readBlocklistFile reads the regular expressions of a blocklist file.
+func readBlocklistFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading blocklist file: %w", err)
+	}
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// maxPromptLength rejects prompts longer than a number of characters.
+type maxPromptLength int
+
+func (m maxPromptLength) Name() string {
+	return "max-prompt-length"
+}
+
+func (m maxPromptLength) FilterPrompt(texts []string) ([]string, error) {
+	length := 0
+	for _, text := range texts {
+		length += utf8.RuneCountInString(text)
+	}
+	if length > int(m) {
+		return nil, fmt.Errorf("%w %s: prompt has %d characters, more than %d", ErrGuardrailRejected, m.Name(), length, int(m))
+	}
+	return texts, nil
+}
+
+func (m maxPromptLength) FilterOutput(text string) string {
+	return text
+}
+
+// blocklist rejects prompts that match any of its patterns, and redacts its
+// patterns from outputs.
+type blocklist struct {
+	patterns []*regexp.Regexp
+}
+
+func (b *blocklist) Name() string {
+	return "blocklist"
+}
+
+func (b *blocklist) FilterPrompt(texts []string) ([]string, error) {
+	for _, text := range texts {
+		for _, pattern := range b.patterns {
+			if pattern.MatchString(text) {
+				// The pattern isn't reported, so that the blocklist can't be
+				// probed.
+				return nil, fmt.Errorf("%w %s: prompt contains blocked content", ErrGuardrailRejected, b.Name())
+			}
+		}
+	}
+	return texts, nil
+}
+
+func (b *blocklist) FilterOutput(text string) string {
+	for _, pattern := range b.patterns {
+		text = pattern.ReplaceAllString(text, "[REDACTED]")
+	}
+	return text
+}
+
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	ssnPattern        = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	phonePattern      = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]\d{4}\b`)
+)
+
+// piiScrubber replaces personally identifiable information in prompts and
+// outputs with placeholders.
+type piiScrubber struct{}
+
+func (piiScrubber) Name() string {
+	return "pii"
+}
+
+func (p piiScrubber) FilterPrompt(texts []string) ([]string, error) {
+	scrubbed := make([]string, len(texts))
+	for i, text := range texts {
+		scrubbed[i] = p.FilterOutput(text)
+	}
+	return scrubbed, nil
+}
+
+func (piiScrubber) FilterOutput(text string) string {
+	text = emailPattern.ReplaceAllString(text, "[EMAIL]")
+	text = creditCardPattern.ReplaceAllStringFunc(text, func(number string) string {
+		if !luhnValid(number) {
+			return number
+		}
+		return "[CREDIT_CARD]"
+	})
+	text = ssnPattern.ReplaceAllString(text, "[SSN]")
+	return phonePattern.ReplaceAllString(text, "[PHONE]")
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum of
+// credit card numbers, which rules out most other long numbers.
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// registeredGuardrail is a guardrail along with the models it applies to.
+type registeredGuardrail struct {
+	// models are the normalized names of the models the guardrail applies
+	// to, or nil for all models.
+	models    []string
+	guardrail Guardrail
+}
+
+// guardrailModels are the IDs of the models the guardrails apply to, resolved
+// from their names when the store changes rather than for every request.
+type guardrailModels struct {
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// ids are the IDs of the models in the store that each guardrail applies
+	// to, by guardrail index.
+	ids [][]string
+	// generation is the store generation the IDs were resolved at.
+	generation uint64
+}
+
+// AddGuardrail registers a guardrail for the requests for the named models, or
+// for all models if none is named. Guardrails are applied in the order in which
+// they're registered. It must be called before Run.
+func (s *Scheduler) AddGuardrail(guardrail Guardrail, names ...string) {
+	registered := registeredGuardrail{guardrail: guardrail}
+	for _, name := range names {
+		registered.models = append(registered.models, models.NormalizeModelName(name))
+	}
+	s.guardrails = append(s.guardrails, registered)
+	s.guardrailModels.lock.Lock()
+	defer s.guardrailModels.lock.Unlock()
+	s.resolveGuardrailModels()
+}
+
+// resolveGuardrailModels resolves the IDs of the models the guardrails apply
+// to. The caller must hold the guardrail models lock.
+func (s *Scheduler) resolveGuardrailModels() {
+	s.guardrailModels.generation = s.storeGeneration()
+	s.guardrailModels.ids = make([][]string, len(s.guardrails))
+	for i, registered := range s.guardrails {
+		for _, model := range registered.models {
+			if id := s.storedModelID(model); id != "" {
+				s.guardrailModels.ids[i] = append(s.guardrailModels.ids[i], id)
+			}
+		}
+	}
+}
+
+// guardrailModelIDs returns the IDs of the models each guardrail applies to,
+// resolving them again if the store changed since they were resolved.
+func (s *Scheduler) guardrailModelIDs() [][]string {
+	s.guardrailModels.lock.Lock()
+	defer s.guardrailModels.lock.Unlock()
+	if s.guardrailModels.generation != s.storeGeneration() {
+		s.resolveGuardrailModels()
+	}
+	return s.guardrailModels.ids
+}
+
+// guardrailsFor returns the guardrails that apply to the requests for a model.
+// A guardrail applies to a model it was registered for under any name, so the
+// guardrails of a model also apply to the requests for its aliases and tags.
+func (s *Scheduler) guardrailsFor(model string) []Guardrail {
+	if len(s.guardrails) == 0 {
+		return nil
+	}
+	name := models.NormalizeModelName(model)
+	ids := s.guardrailModelIDs()
+	var id string
+	var resolved bool
+	var guardrails []Guardrail
+	for i, registered := range s.guardrails {
+		applies := registered.models == nil || slices.Contains(registered.models, name)
+		if !applies && len(ids[i]) > 0 {
+			if !resolved {
+				id, resolved = s.storedModelID(model), true
+			}
+			applies = id != "" && slices.Contains(ids[i], id)
+		}
+		if applies {
+			guardrails = append(guardrails, registered.guardrail)
+		}
+	}
+	return guardrails
+}
+
+// storeGeneration returns the generation of the model store, which changes
+// whenever the models, tags or aliases in it do.
+func (s *Scheduler) storeGeneration() uint64 {
+	if s.modelManager == nil {
+		return 0
+	}
+	return s.modelManager.StoreGeneration()
+}
+
+// storedModelID returns the ID of a model in the store, or an empty string if
+// it isn't in it, as is the case for the models of backends that manage their
+// own.
+func (s *Scheduler) storedModelID(model string) string {
+	if s.modelManager == nil {
+		return ""
+	}
+	stored, err := s.modelManager.GetModel(model)
+	if err != nil {
+		return ""
+	}
+	id, err := stored.ID()
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// filterPrompt applies guardrails to the prompt of an OpenAI request for a
+// model, returning the body with the prompt rewritten, or an error wrapping
+// ErrGuardrailRejected if a guardrail rejected it, which is recorded in the
+// inference metrics.
+func (s *Scheduler) filterPrompt(model string, guardrails []Guardrail, body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var request map[string]any
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	var texts []string
+	visitPromptTexts(request, func(text string) string {
+		texts = append(texts, text)
+		return text
+	})
+	filtered := texts
+	for _, guardrail := range guardrails {
+		var err error
+		if filtered, err = guardrail.FilterPrompt(filtered); err != nil {
+			if errors.Is(err, ErrGuardrailRejected) {
+				s.inferenceMetrics.RecordGuardrailRejection(model, guardrail.Name())
+			}
+			return nil, err
+		}
+		if len(filtered) != len(texts) {
+			return nil, fmt.Errorf("guardrail %s returned %d prompt texts for %d", guardrail.Name(), len(filtered), len(texts))
+		}
+	}
+	if slices.Equal(filtered, texts) {
+		return body, nil
+	}
+
+	i := 0
+	visitPromptTexts(request, func(string) string {
+		i++
+		return filtered[i-1]
+	})
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(request); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// visitPromptTexts replaces each text of the prompt of an OpenAI request,
+// which are the text content of its messages, and its prompt, input, query
+// and documents, with the result of visit.
+func visitPromptTexts(request map[string]any, visit func(string) string) {
+	visitMessages := func(messages []any) {
+		for _, message := range messages {
+			message, _ := message.(map[string]any)
+			switch content := message["content"].(type) {
+			case string:
+				message["content"] = visit(content)
+			case []any:
+				for _, part := range content {
+					part, _ := part.(map[string]any)
+					if text, ok := part["text"].(string); ok {
+						part["text"] = visit(text)
+					}
+				}
+			}
+		}
+	}
+	if messages, ok := request["messages"].([]any); ok {
+		visitMessages(messages)
+	}
+	for _, field := range []string{"prompt", "input", "query", "documents"} {
+		switch value := request[field].(type) {
+		case string:
+			request[field] = visit(value)
+		case []any:
+			for i, item := range value {
+				switch item := item.(type) {
+				case string:
+					value[i] = visit(item)
+				case map[string]any:
+					// Items of the input of the Responses API are messages.
+					visitMessages([]any{item})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/distribution/builder"
+	reg "github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestNewGuardrails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("# Internal code names\n\n(?i)project\\s+falcon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	guardrails, err := NewGuardrails(GuardrailConfig{
+		Blocklist:       []string{"(?i)password"},
+		BlocklistFile:   path,
+		MaxPromptLength: 100,
+		ScrubPII:        true,
+	})
+	if err != nil {
+		t.Fatalf("NewGuardrails failed: %v", err)
+	}
+	var names []string
+	for _, guardrail := range guardrails {
+		names = append(names, guardrail.Name())
+	}
+	if strings.Join(names, ",") != "max-prompt-length,blocklist,pii" {
+		t.Errorf("Expected the guardrails in the order they're applied, got %v", names)
+	}
+	if output := guardrails[1].FilterOutput("Project  Falcon launches soon"); output != "[REDACTED] launches soon" {
+		t.Errorf("Expected patterns of the blocklist file to be redacted, got %q", output)
+	}
+
+	for _, config := range []GuardrailConfig{
+		{},
+		{Models: []string{""}, ScrubPII: true},
+		{MaxPromptLength: -1},
+		{Blocklist: []string{"("}},
+		{BlocklistFile: filepath.Join(t.TempDir(), "missing.txt")},
+	} {
+		if _, err := NewGuardrails(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestScrubPII(t *testing.T) {
+	scrubbed := piiScrubber{}.FilterOutput("Mail jane.doe@example.com or call (555) 123-4567 about card " +
+		"4111 1111 1111 1111, SSN 123-45-6789, order 1234567890123")
+	expected := "Mail [EMAIL] or call [PHONE] about card [CREDIT_CARD], SSN [SSN], order 1234567890123"
+	if scrubbed != expected {
+		t.Errorf("Expected %q, got %q", expected, scrubbed)
+	}
+}
+
+func TestFilterPrompt(t *testing.T) {
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+	guardrails, err := NewGuardrails(GuardrailConfig{Blocklist: []string{"(?i)password"}, MaxPromptLength: 40, ScrubPII: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, guardrail := range guardrails {
+		s.AddGuardrail(guardrail, "smollm2")
+	}
+	if len(s.guardrailsFor("ai/llama3.2")) != 0 || len(s.guardrailsFor("ai/smollm2:latest")) != 3 {
+		t.Fatal("Expected guardrails to apply to the normalized models they're added for")
+	}
+	guardrails = s.guardrailsFor("ai/smollm2")
+
+	// PII is scrubbed from the text of messages, including content parts.
+	body, err := s.filterPrompt("ai/smollm2", guardrails, []byte(`{"model":"ai/smollm2","messages":[`+
+		`{"role":"system","content":"Be brief."},`+
+		`{"role":"user","content":[{"type":"text","text":"I'm bob@example.com"}]}]}`))
+	if err != nil {
+		t.Fatalf("filterPrompt failed: %v", err)
+	}
+	var request struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("Failed to decode filtered body: %v", err)
+	}
+	if string(request.Messages[0].Content) != `"Be brief."` || string(request.Messages[1].Content) != `[{"text":"I'm [EMAIL]","type":"text"}]` {
+		t.Errorf("Expected the email address to be scrubbed, got %s", body)
+	}
+
+	// Prompts that are too long or contain blocked content are rejected.
+	for _, prompt := range []string{
+		`{"model":"ai/smollm2","prompt":"` + strings.Repeat("a", 41) + `"}`,
+		`{"model":"ai/smollm2","input":["What's the admin password?"]}`,
+	} {
+		if _, err := s.filterPrompt("ai/smollm2", guardrails, []byte(prompt)); !errors.Is(err, ErrGuardrailRejected) {
+			t.Errorf("Expected %s to be rejected, got %v", prompt, err)
+		}
+	}
+}
+
+func TestGuardrailWriterStreamed(t *testing.T) {
+	guardrails, err := NewGuardrails(GuardrailConfig{Blocklist: []string{"top secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", "text/event-stream")
+	w := newGuardrailWriter(recorder, guardrails)
+
+	// The blocked phrase spans several chunks, and a chunk spans two writes.
+	for _, data := range []string{
+		`data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"It's "}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"content":"top"}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"content":" sec`,
+		`ret.\nOk"}}]}` + "\n\n",
+		`data: {"choices":[{"index":0,"delta":{"content":"?"},"finish_reason":"stop"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	} {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	var output strings.Builder
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Failed to decode chunk %q: %v", data, err)
+		}
+		output.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	if output.String() != "It's [REDACTED].\nOk?" {
+		t.Errorf("Expected the blocked phrase to be redacted from the stream, got %q", output.String())
+	}
+	if !strings.HasSuffix(recorder.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("Expected the stream to end as it did, got %q", recorder.Body.String())
+	}
+}
+
+func TestGuardrailWriterBuffered(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", "application/json")
+	recorder.Header().Set("Content-Length", "93")
+	w := newGuardrailWriter(recorder, []Guardrail{piiScrubber{}})
+	w.Write([]byte(`{"created":1750000000,"choices":[{"index":0,"message":{"role":"assistant","content":"Call 555-123-4567"}}]}`))
+	if err := w.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	expected := `{"choices":[{"index":0,"message":{"content":"Call [PHONE]","role":"assistant"}}],"created":1750000000}`
+	if recorder.Body.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, recorder.Body.String())
+	}
+	if recorder.Header().Get("Content-Length") != "" {
+		t.Error("Expected the length of the unfiltered response to be dropped")
+	}
+
+	// Errors are written as-is.
+	recorder = httptest.NewRecorder()
+	w = newGuardrailWriter(recorder, []Guardrail{piiScrubber{}})
+	w.WriteHeader(500)
+	w.Write([]byte("failed for bob@example.com"))
+	w.close()
+	if recorder.Body.String() != "failed for bob@example.com" {
+		t.Errorf("Expected the error to be written as-is, got %q", recorder.Body.String())
+	}
+}
+
+// newGuardrailsTestScheduler returns a scheduler whose model manager has an
+// unguarded model and a model guarded by a blocklist, along with their tags.
+// The guarded model is also named by the alias my-model.
+func newGuardrailsTestScheduler(t *testing.T) (*Scheduler, string, string) {
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse registry URL: %v", err)
+	}
+
+	manager := models.NewManager(createTestLogger(), models.ClientConfig{
+		StoreRootPath: t.TempDir(),
+		Logger:        createTestLogger(),
+	}, nil, nil)
+	var tags []string
+	// The context sizes tell the two models apart.
+	for i, name := range []string{"ai/unguarded:latest", "ai/guarded:latest"} {
+		model, err := builder.FromGGUF(filepath.Join("..", "..", "..", "assets", "dummy.gguf"))
+		if err != nil {
+			t.Fatalf("Failed to create model builder: %v", err)
+		}
+		tag := uri.Host + "/" + name
+		target, err := reg.NewClient().NewTarget(tag)
+		if err != nil {
+			t.Fatalf("Failed to create model target: %v", err)
+		}
+		if err := model.WithContextSize(uint64(1024*(i+1))).Build(context.Background(), target, io.Discard); err != nil {
+			t.Fatalf("Failed to build model: %v", err)
+		}
+		if err := manager.PullModel(tag, httptest.NewRequest(http.MethodPost, "/models/create", nil), httptest.NewRecorder()); err != nil {
+			t.Fatalf("Failed to pull model: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+	w := httptest.NewRecorder()
+	manager.ServeHTTP(w, httptest.NewRequest(http.MethodPut, inference.ModelsPrefix+"/_aliases/my-model",
+		strings.NewReader(`{"model":"`+tags[1]+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to set alias: %s", w.Body.String())
+	}
+
+	backend := &mockBackend{name: "test-backend"}
+	tracker := metrics.NewTracker(http.DefaultClient, createTestLogger(), "", true)
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, manager, nil, nil, tracker, systemMemoryInfo{})
+	guardrails, err := NewGuardrails(GuardrailConfig{Blocklist: []string{"(?i)password"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddGuardrail(guardrails[0], tags[1])
+	return s, tags[0], tags[1]
+}
+
+// postBlockedPrompt posts a chat completion request with a blocked prompt for
+// a model, returning the response status.
+func postBlockedPrompt(s *Scheduler, model string) int {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, inference.InferencePrefix+"/v1/chat/completions", strings.NewReader(
+		`{"model":"`+model+`","messages":[{"role":"user","content":"What's the admin password?"}]}`)))
+	return w.Code
+}
+
+func TestGuardrailsForAlias(t *testing.T) {
+	s, unguarded, guarded := newGuardrailsTestScheduler(t)
+	if len(s.guardrailsFor(unguarded)) != 0 || len(s.guardrailsFor(guarded)) != 1 || len(s.guardrailsFor("my-model")) != 1 {
+		t.Fatal("Expected the guardrails of a model to apply to its alias only")
+	}
+
+	// The installer isn't started, so requests that pass the guardrails
+	// can't be served.
+	if status := postBlockedPrompt(s, "my-model"); status != http.StatusBadRequest {
+		t.Errorf("Expected the prompt for the alias to be rejected, got status %d", status)
+	}
+	if status := postBlockedPrompt(s, unguarded); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the prompt for the unguarded model to pass, got status %d", status)
+	}
+}
+
+func TestGuardrailsForStoreChanges(t *testing.T) {
+	s, unguarded, guarded := newGuardrailsTestScheduler(t)
+	guardrails, err := NewGuardrails(GuardrailConfig{MaxPromptLength: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddGuardrail(guardrails[0], "my-model")
+	if len(s.guardrailsFor(unguarded)) != 0 || len(s.guardrailsFor(guarded)) != 2 {
+		t.Fatal("Expected the guardrail of the alias to apply to the model it names")
+	}
+
+	// Pointing the alias at the unguarded model moves the guardrail with it.
+	w := httptest.NewRecorder()
+	s.modelManager.ServeHTTP(w, httptest.NewRequest(http.MethodPut, inference.ModelsPrefix+"/_aliases/my-model",
+		strings.NewReader(`{"model":"`+unguarded+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to set alias: %s", w.Body.String())
+	}
+	if len(s.guardrailsFor(unguarded)) != 1 || len(s.guardrailsFor(guarded)) != 1 {
+		t.Fatal("Expected the guardrail of the alias to follow it to the model it now names")
+	}
+}
+
+func TestGuardrailsForTrafficSplit(t *testing.T) {
+	s, unguarded, guarded := newGuardrailsTestScheduler(t)
+	if err := s.trafficSplits.set(unguarded, TrafficSplit{Model: guarded, Percent: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if status := postBlockedPrompt(s, unguarded); status != http.StatusBadRequest {
+		t.Errorf("Expected the prompt routed to the guarded model to be rejected, got status %d", status)
+	}
+
+	if err := s.trafficSplits.set(guarded, TrafficSplit{Model: unguarded, Percent: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if status := postBlockedPrompt(s, guarded); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the prompt routed to the unguarded model to pass, got status %d", status)
+	}
+}
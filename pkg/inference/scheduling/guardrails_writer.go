@@ -0,0 +1,292 @@
+package scheduling
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maximumHeldBackOutput is the length of the streamed output of a choice
+// beyond which it's filtered up to its last word rather than its last line,
+// so that long lines don't stall streams.
+const maximumHeldBackOutput = 1024
+
+// guardrailWriterMode is how a guardrailWriter filters a response.
+type guardrailWriterMode int
+
+const (
+	// guardrailWriterUndecided is the mode until the response status is
+	// written.
+	guardrailWriterUndecided guardrailWriterMode = iota
+	// guardrailWriterPassthrough writes responses that aren't completions,
+	// such as errors, as-is.
+	guardrailWriterPassthrough
+	// guardrailWriterBuffered filters JSON responses once they're complete.
+	guardrailWriterBuffered
+	// guardrailWriterStreamed filters streamed responses a line of output at
+	// a time.
+	guardrailWriterStreamed
+)
+
+// guardrailWriter applies the output filters of guardrails to the choices of
+// chat and text completions. Streamed outputs are held back until a line is
+// complete, so that patterns spanning several tokens are matched.
+type guardrailWriter struct {
+	http.ResponseWriter
+	guardrails []Guardrail
+	mode       guardrailWriterMode
+	// buffer is the response body if buffered, or the incomplete line of the
+	// stream if streamed.
+	buffer bytes.Buffer
+	// pending is the output held back for each choice of a stream.
+	pending map[int]string
+	// chat is whether the stream is of chat completion chunks, whose output
+	// is in the delta of their choices rather than their text.
+	chat bool
+}
+
+// newGuardrailWriter returns a writer applying guardrails to the completions
+// written to w. It must be closed once the response is complete.
+func newGuardrailWriter(w http.ResponseWriter, guardrails []Guardrail) *guardrailWriter {
+	return &guardrailWriter{ResponseWriter: w, guardrails: guardrails, pending: make(map[int]string)}
+}
+
+func (g *guardrailWriter) WriteHeader(statusCode int) {
+	if g.mode != guardrailWriterUndecided {
+		return
+	}
+	contentType := g.Header().Get("Content-Type")
+	switch {
+	case statusCode != http.StatusOK:
+		g.mode = guardrailWriterPassthrough
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		g.mode = guardrailWriterStreamed
+	case strings.HasPrefix(contentType, "application/json"):
+		g.mode = guardrailWriterBuffered
+	default:
+		g.mode = guardrailWriterPassthrough
+	}
+	// Filtering changes the length of the response.
+	if g.mode != guardrailWriterPassthrough {
+		g.Header().Del("Content-Length")
+	}
+	g.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (g *guardrailWriter) Write(p []byte) (int, error) {
+	g.WriteHeader(http.StatusOK)
+	switch g.mode {
+	case guardrailWriterBuffered:
+		return g.buffer.Write(p)
+	case guardrailWriterStreamed:
+		g.buffer.Write(p)
+		for {
+			line, err := g.buffer.ReadBytes('\n')
+			if err != nil {
+				// Keep the incomplete line for the next write.
+				g.buffer.Write(line)
+				break
+			}
+			if err := g.writeStreamLine(line); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	default:
+		return g.ResponseWriter.Write(p)
+	}
+}
+
+func (g *guardrailWriter) Flush() {
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close writes what was held back of the response.
+func (g *guardrailWriter) close() error {
+	switch g.mode {
+	case guardrailWriterBuffered:
+		_, err := g.ResponseWriter.Write(g.filterResponse(g.buffer.Bytes()))
+		return err
+	case guardrailWriterStreamed:
+		if g.buffer.Len() > 0 {
+			if err := g.writeStreamLine(g.buffer.Bytes()); err != nil {
+				return err
+			}
+		}
+		return g.flushPending()
+	}
+	return nil
+}
+
+// writeStreamLine writes a line of a stream, filtering the output of the
+// chunk it carries, if any.
+func (g *guardrailWriter) writeStreamLine(line []byte) error {
+	data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r\n"), []byte("data: "))
+	if !ok {
+		_, err := g.ResponseWriter.Write(line)
+		return err
+	}
+	if string(data) == "[DONE]" {
+		if err := g.flushPending(); err != nil {
+			return err
+		}
+		_, err := g.ResponseWriter.Write(line)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(append(append([]byte("data: "), g.filterChunk(data)...), '\n'))
+	return err
+}
+
+// filterChunk filters the output of the choices of a streamed chunk, holding
+// back what follows their last complete line until their next chunk.
+func (g *guardrailWriter) filterChunk(data []byte) []byte {
+	chunk, ok := decodeCompletion(data)
+	if !ok {
+		return data
+	}
+	choices, _ := chunk["choices"].([]any)
+	for _, choice := range choices {
+		choice, _ := choice.(map[string]any)
+		if choice == nil {
+			continue
+		}
+		index := choiceIndex(choice)
+		delta, isChat := choice["delta"].(map[string]any)
+		g.chat = g.chat || isChat
+		var content string
+		var hasContent bool
+		if isChat {
+			content, hasContent = delta["content"].(string)
+		} else {
+			content, hasContent = choice["text"].(string)
+		}
+		finished := choice["finish_reason"] != nil
+
+		output := g.pending[index] + content
+		var held string
+		if !finished {
+			output, held = splitHeldBackOutput(output)
+		}
+		g.pending[index] = held
+		if !hasContent && output == "" {
+			continue
+		}
+		output = g.filterOutput(output)
+		if isChat {
+			delta["content"] = output
+		} else {
+			choice["text"] = output
+		}
+	}
+	return encodeCompletion(chunk, data)
+}
+
+// flushPending writes the output held back for the choices of a stream whose
+// chunks ended without a finish reason.
+func (g *guardrailWriter) flushPending() error {
+	for _, index := range slices.Sorted(maps.Keys(g.pending)) {
+		output := g.pending[index]
+		delete(g.pending, index)
+		if output == "" {
+			continue
+		}
+		choice := map[string]any{"index": index}
+		if g.chat {
+			choice["delta"] = map[string]any{"content": g.filterOutput(output)}
+		} else {
+			choice["text"] = g.filterOutput(output)
+		}
+		data, err := json.Marshal(map[string]any{"choices": []any{choice}})
+		if err != nil {
+			return err
+		}
+		if _, err := g.ResponseWriter.Write(append(append([]byte("data: "), data...), '\n', '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterResponse filters the output of the choices of a complete response.
+func (g *guardrailWriter) filterResponse(data []byte) []byte {
+	response, ok := decodeCompletion(data)
+	if !ok {
+		return data
+	}
+	choices, _ := response["choices"].([]any)
+	for _, choice := range choices {
+		choice, _ := choice.(map[string]any)
+		if message, ok := choice["message"].(map[string]any); ok {
+			if content, ok := message["content"].(string); ok {
+				message["content"] = g.filterOutput(content)
+			}
+		}
+		if text, ok := choice["text"].(string); ok {
+			choice["text"] = g.filterOutput(text)
+		}
+	}
+	return encodeCompletion(response, data)
+}
+
+// filterOutput applies the output filters of the guardrails to text.
+func (g *guardrailWriter) filterOutput(text string) string {
+	for _, guardrail := range g.guardrails {
+		text = guardrail.FilterOutput(text)
+	}
+	return text
+}
+
+// splitHeldBackOutput splits streamed output into the part that can be
+// filtered and the part that must be held back until more is streamed.
+func splitHeldBackOutput(output string) (string, string) {
+	if i := strings.LastIndexByte(output, '\n'); i >= 0 {
+		return output[:i+1], output[i+1:]
+	}
+	if len(output) <= maximumHeldBackOutput {
+		return "", output
+	}
+	if i := strings.LastIndexAny(output, " \t"); i >= 0 {
+		return output[:i+1], output[i+1:]
+	}
+	return output, ""
+}
+
+// choiceIndex returns the index of a choice of a completion.
+func choiceIndex(choice map[string]any) int {
+	if number, ok := choice["index"].(json.Number); ok {
+		if index, err := strconv.Atoi(number.String()); err == nil {
+			return index
+		}
+	}
+	return 0
+}
+
+// decodeCompletion decodes a completion response or chunk, keeping numbers
+// as they are.
+func decodeCompletion(data []byte) (map[string]any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var completion map[string]any
+	if err := decoder.Decode(&completion); err != nil {
+		return nil, false
+	}
+	return completion, true
+}
+
+// encodeCompletion encodes a completion response or chunk, or returns the
+// original data if it can't be encoded.
+func encodeCompletion(completion map[string]any, data []byte) []byte {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(completion); err != nil {
+		return data
+	}
+	return bytes.TrimSuffix(encoded.Bytes(), []byte("\n"))
+}
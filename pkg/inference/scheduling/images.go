@@ -0,0 +1,341 @@
+package scheduling
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder.
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// maximumImagePixels is the maximum number of pixels of an image, which is
+	// checked before the image is decoded so that huge images don't cause
+	// memory spikes.
+	maximumImagePixels = 64 * 1024 * 1024
+	// maximumImageRedirects is the maximum number of redirects followed when
+	// an image is fetched.
+	maximumImageRedirects = 5
+)
+
+// ErrImageNotAllowed indicates that an image URL of a request isn't permitted
+// by the image policy. If returned in conjunction with an HTTP request, it
+// should be paired with a 400 response status.
+var ErrImageNotAllowed = errors.New("not allowed by image policy")
+
+// ImagePolicy configures how the image_url content of chat completion requests
+// to vision-language models, which have a multimodal projector, is fetched and
+// downscaled before it's passed to the backend as data URLs.
+type ImagePolicy struct {
+	// AllowedSchemes are the schemes of image URLs that are accepted, such as
+	// data for inline images, and http or https for images the model runner
+	// fetches.
+	AllowedSchemes []string
+	// MaxSize is the maximum size of an image, in bytes.
+	MaxSize int64
+	// MaxDimension is the maximum width and height of images, beyond which
+	// they're downscaled. Zero disables downscaling.
+	MaxDimension int
+	// AllowPrivateNetworks permits fetching images from loopback, private and
+	// link-local addresses, and through the proxy configured by the
+	// environment. Otherwise, images are fetched directly, and only from
+	// public addresses, so that clients can't reach the model runner's own
+	// API or internal services through it.
+	AllowPrivateNetworks bool
+}
+
+// DefaultImagePolicy accepts inline images and images fetched over HTTPS of
+// up to 20 MiB, and downscales images to at most 2048 pixels wide and high.
+var DefaultImagePolicy = ImagePolicy{
+	AllowedSchemes: []string{"data", "https"},
+	MaxSize:        20 * 1024 * 1024,
+	MaxDimension:   2048,
+}
+
+// SetImagePolicy sets the policy with which the images of requests to
+// vision-language models are preprocessed. It must be called before the
+// scheduler starts serving requests.
+func (s *Scheduler) SetImagePolicy(policy ImagePolicy) {
+	s.imagePolicy = policy
+	s.imageClient = newImageClient(policy)
+}
+
+// newImageClient returns a client fetching images under policy. Redirects
+// must stay within the allowed schemes, and connections to non-public
+// addresses are refused unless the policy allows private networks. Addresses
+// are checked once resolved, so that host names resolving to them are refused
+// too.
+func newImageClient(policy ImagePolicy) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !policy.AllowPrivateNetworks {
+		// Proxies would connect to the image's address on our behalf.
+		transport.Proxy = nil
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   refuseNonPublicAddresses,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maximumImageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maximumImageRedirects)
+			}
+			if scheme := request.URL.Scheme; !slices.Contains(policy.AllowedSchemes, scheme) {
+				return fmt.Errorf("redirect to image URL scheme %q: %w", scheme, ErrImageNotAllowed)
+			}
+			return nil
+		},
+	}
+}
+
+// refuseNonPublicAddresses is a dialer control function refusing connections
+// to loopback, private, link-local and unspecified addresses.
+func refuseNonPublicAddresses(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("image address %q: %w", address, ErrImageNotAllowed)
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("image address %s: %w", addr, ErrImageNotAllowed)
+	}
+	return nil
+}
+
+// preprocessImages replaces the image URLs in the messages of a chat
+// completion request with data URLs of the fetched and, if necessary,
+// downscaled images. The body is returned unchanged if it has no images.
+func (s *Scheduler) preprocessImages(ctx context.Context, body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var request map[string]any
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	messages, _ := request["messages"].([]any)
+	changed := false
+	for _, message := range messages {
+		message, _ := message.(map[string]any)
+		parts, _ := message["content"].([]any)
+		for _, part := range parts {
+			part, _ := part.(map[string]any)
+			if part["type"] != "image_url" {
+				continue
+			}
+			// The image URL is either an object with a url field or, in older
+			// clients, the URL itself.
+			imageURL, isObject := part["image_url"].(map[string]any)
+			var rawURL string
+			if isObject {
+				rawURL, _ = imageURL["url"].(string)
+			} else {
+				rawURL, _ = part["image_url"].(string)
+			}
+			if rawURL == "" {
+				return nil, errors.New("image_url must have a url")
+			}
+			dataURL, err := s.preprocessImage(ctx, rawURL)
+			if err != nil {
+				return nil, err
+			}
+			if dataURL == rawURL {
+				continue
+			}
+			if isObject {
+				imageURL["url"] = dataURL
+			} else {
+				part["image_url"] = dataURL
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return body, nil
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(request); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// preprocessImage returns a data URL of the image at a URL, downscaled if
+// it's larger than the maximum dimension.
+func (s *Scheduler) preprocessImage(ctx context.Context, rawURL string) (string, error) {
+	scheme, _, ok := strings.Cut(rawURL, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid image URL %q", rawURL)
+	}
+	scheme = strings.ToLower(scheme)
+	if !slices.Contains(s.imagePolicy.AllowedSchemes, scheme) {
+		return "", fmt.Errorf("image URL scheme %q: %w", scheme, ErrImageNotAllowed)
+	}
+
+	var data []byte
+	var mediaType string
+	var err error
+	if scheme == "data" {
+		if mediaType, data, err = decodeDataURL(rawURL, s.imagePolicy.MaxSize); err != nil {
+			return "", err
+		}
+	} else if mediaType, data, err = s.fetchImage(ctx, rawURL); err != nil {
+		return "", err
+	}
+
+	// Check the dimensions before decoding the image. Images in formats that
+	// can't be decoded are passed to the backend as they are.
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if scheme == "data" {
+			return rawURL, nil
+		}
+		return encodeDataURL(mediaType, data), nil
+	}
+	if config.Width*config.Height > maximumImagePixels {
+		return "", fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	maxDimension := s.imagePolicy.MaxDimension
+	if maxDimension <= 0 || max(config.Width, config.Height) <= maxDimension {
+		if scheme == "data" {
+			return rawURL, nil
+		}
+		return encodeDataURL("image/"+format, data), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("unable to decode image: %w", err)
+	}
+	img = downscaleImage(img, maxDimension)
+	var encoded bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90})
+	} else {
+		format = "png"
+		err = png.Encode(&encoded, img)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to encode image: %w", err)
+	}
+	return encodeDataURL("image/"+format, encoded.Bytes()), nil
+}
+
+// fetchImage fetches an image, up to the maximum size.
+func (s *Scheduler) fetchImage(ctx context.Context, rawURL string) (string, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid image URL: %w", err)
+	}
+	response, err := s.imageClient.Do(request)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to fetch image: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("unable to fetch image: %s", response.Status)
+	}
+	if response.ContentLength > s.imagePolicy.MaxSize {
+		return "", nil, fmt.Errorf("image of %d bytes is too large", response.ContentLength)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, s.imagePolicy.MaxSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to fetch image: %w", err)
+	}
+	if int64(len(data)) > s.imagePolicy.MaxSize {
+		return "", nil, errors.New("image is too large")
+	}
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	return mediaType, data, nil
+}
+
+// decodeDataURL decodes a base64-encoded data URL, up to the maximum size.
+func decodeDataURL(rawURL string, maxSize int64) (string, []byte, error) {
+	header, encoded, ok := strings.Cut(rawURL[len("data:"):], ",")
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !ok || !isBase64 {
+		return "", nil, errors.New("image data URLs must be base64-encoded")
+	}
+	if int64(base64.StdEncoding.DecodedLen(len(encoded))) > maxSize+2 {
+		return "", nil, errors.New("image is too large")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid image data URL: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return "", nil, errors.New("image is too large")
+	}
+	return mediaType, data, nil
+}
+
+// encodeDataURL encodes an image as a base64-encoded data URL.
+func encodeDataURL(mediaType string, data []byte) string {
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// downscaleImage shrinks an image to fit within a square of the given
+// dimension, preserving its aspect ratio, by averaging the source pixels that
+// cover each destination pixel.
+func downscaleImage(src image.Image, dimension int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := float64(dimension) / float64(max(width, height))
+	dstWidth := max(1, int(float64(width)*scale))
+	dstHeight := max(1, int(float64(height)*scale))
+
+	// Convert the image once, rather than converting every pixel as it's read.
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := range dstHeight {
+		y0, y1 := y*height/dstHeight, max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		for x := range dstWidth {
+			x0, x1 := x*width/dstWidth, max((x+1)*width/dstWidth, x*width/dstWidth+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(rgba.Rect.Min.X+x0, rgba.Rect.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(rgba.Pix[offset])
+					g += uint64(rgba.Pix[offset+1])
+					b += uint64(rgba.Pix[offset+2])
+					a += uint64(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
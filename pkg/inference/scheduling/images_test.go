@@ -0,0 +1,185 @@
+package scheduling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// encodeTestImage encodes a blank image of the given dimensions as a PNG.
+func encodeTestImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return encoded.Bytes()
+}
+
+// decodedDimensions returns the dimensions of the image of a data URL.
+func decodedDimensions(t *testing.T, dataURL string) (int, int) {
+	t.Helper()
+	_, data, err := decodeDataURL(dataURL, DefaultImagePolicy.MaxSize)
+	if err != nil {
+		t.Fatalf("Failed to decode data URL: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode image: %v", err)
+	}
+	return config.Width, config.Height
+}
+
+func TestDownscaleImage(t *testing.T) {
+	tests := []struct {
+		width, height, dimension      int
+		expectedWidth, expectedHeight int
+	}{
+		{width: 4000, height: 2000, dimension: 1000, expectedWidth: 1000, expectedHeight: 500},
+		{width: 300, height: 900, dimension: 300, expectedWidth: 100, expectedHeight: 300},
+		{width: 5000, height: 1, dimension: 100, expectedWidth: 100, expectedHeight: 1},
+	}
+	for _, tt := range tests {
+		img := downscaleImage(image.NewGray(image.Rect(0, 0, tt.width, tt.height)), tt.dimension)
+		if bounds := img.Bounds(); bounds.Dx() != tt.expectedWidth || bounds.Dy() != tt.expectedHeight {
+			t.Errorf("Expected %dx%d to be downscaled to %dx%d, got %dx%d", tt.width, tt.height,
+				tt.expectedWidth, tt.expectedHeight, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
+
+func TestPreprocessImages(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+	s.SetImagePolicy(ImagePolicy{AllowedSchemes: []string{"data"}, MaxSize: 1024 * 1024, MaxDimension: 64})
+
+	small := encodeDataURL("image/png", encodeTestImage(t, 32, 16))
+	large := encodeDataURL("image/png", encodeTestImage(t, 256, 128))
+
+	// Requests without images, or with small images, are passed on unchanged.
+	for _, body := range []string{
+		`{"model": "ai/model", "messages": [{"role": "user", "content": "Hello <world>"}]}`,
+		`{"model": "ai/model", "messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "` + small + `"}}]}]}`,
+	} {
+		processed, err := s.preprocessImages(context.Background(), []byte(body))
+		if err != nil || string(processed) != body {
+			t.Errorf("Expected %s to be unchanged, got %s (%v)", body, processed, err)
+		}
+	}
+
+	body := `{"model": "ai/model", "messages": [{"role": "user", "content": [{"type": "text", "text": "<describe>"}, {"type": "image_url", "image_url": {"url": "` + large + `"}}, {"type": "image_url", "image_url": "` + large + `"}]}]}`
+	processed, err := s.preprocessImages(context.Background(), []byte(body))
+	if err != nil {
+		t.Fatalf("Failed to preprocess images: %v", err)
+	}
+	if !strings.Contains(string(processed), `"text":"<describe>"`) {
+		t.Errorf("Expected the text to be preserved, got %s", processed)
+	}
+	urls := strings.Split(string(processed), `data:image/png;base64,`)
+	if len(urls) != 3 {
+		t.Fatalf("Expected two images, got %s", processed)
+	}
+	for _, url := range urls[1:] {
+		encoded, _, _ := strings.Cut(url, `"`)
+		if width, height := decodedDimensions(t, "data:image/png;base64,"+encoded); width != 64 || height != 32 {
+			t.Errorf("Expected the image to be downscaled to 64x32, got %dx%d", width, height)
+		}
+	}
+}
+
+func TestPreprocessImagesPolicy(t *testing.T) {
+	image := encodeTestImage(t, 32, 32)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/image.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image)
+	}))
+	defer server.Close()
+
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+	// The test server listens on a loopback address, with a certificate only
+	// its own client trusts.
+	setImagePolicy := func(policy ImagePolicy) {
+		policy.AllowPrivateNetworks = true
+		s.SetImagePolicy(policy)
+		s.imageClient.Transport.(*http.Transport).TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	}
+	request := func(url string) string {
+		return `{"model": "ai/model", "messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "` + url + `"}}]}]}`
+	}
+
+	setImagePolicy(ImagePolicy{AllowedSchemes: []string{"data"}, MaxSize: 1024 * 1024})
+	if _, err := s.preprocessImages(context.Background(), []byte(request(server.URL+"/image.png"))); !errors.Is(err, ErrImageNotAllowed) {
+		t.Errorf("Expected %v, got %v", ErrImageNotAllowed, err)
+	}
+
+	setImagePolicy(ImagePolicy{AllowedSchemes: []string{"https"}, MaxSize: 1024 * 1024})
+	processed, err := s.preprocessImages(context.Background(), []byte(request(server.URL+"/image.png")))
+	if err != nil {
+		t.Fatalf("Failed to fetch image: %v", err)
+	}
+	if !strings.Contains(string(processed), `"url":"`+encodeDataURL("image/png", image)+`"`) {
+		t.Errorf("Expected the image to be inlined, got %s", processed)
+	}
+	if _, err := s.preprocessImages(context.Background(), []byte(request(server.URL+"/missing.png"))); err == nil {
+		t.Error("Expected a missing image to fail")
+	}
+
+	setImagePolicy(ImagePolicy{AllowedSchemes: []string{"data", "https"}, MaxSize: int64(len(image)) - 1})
+	for _, url := range []string{server.URL + "/image.png", encodeDataURL("image/png", image)} {
+		if _, err := s.preprocessImages(context.Background(), []byte(request(url))); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("Expected the image to be too large, got %v", err)
+		}
+	}
+}
+
+func TestFetchImageRestrictions(t *testing.T) {
+	image := encodeTestImage(t, 32, 32)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+		case "/downgrade":
+			http.Redirect(w, r, "http://"+r.Host+"/image.png", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	// Loopback addresses are refused unless private networks are allowed.
+	s.SetImagePolicy(ImagePolicy{AllowedSchemes: []string{"https"}, MaxSize: 1024 * 1024})
+	if _, _, err := s.fetchImage(context.Background(), server.URL+"/image.png"); !errors.Is(err, ErrImageNotAllowed) {
+		t.Errorf("Expected a loopback address to be refused with %v, got %v", ErrImageNotAllowed, err)
+	}
+
+	s.SetImagePolicy(ImagePolicy{AllowedSchemes: []string{"https"}, MaxSize: 1024 * 1024, AllowPrivateNetworks: true})
+	s.imageClient.Transport.(*http.Transport).TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	if _, _, err := s.fetchImage(context.Background(), server.URL+"/image.png"); err != nil {
+		t.Errorf("Expected a loopback address to be allowed, got %v", err)
+	}
+	if _, _, err := s.fetchImage(context.Background(), server.URL+"/downgrade"); !errors.Is(err, ErrImageNotAllowed) {
+		t.Errorf("Expected a redirect to a disallowed scheme to fail with %v, got %v", ErrImageNotAllowed, err)
+	}
+	if _, _, err := s.fetchImage(context.Background(), server.URL+"/loop"); err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Errorf("Expected redirects to be capped, got %v", err)
+	}
+}
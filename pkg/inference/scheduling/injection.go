@@ -0,0 +1,107 @@
+package scheduling
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrInjectionNotAllowed indicates that a configure request specified an
+// environment variable or mount that isn't permitted by the injection policy.
+// If returned in conjunction with an HTTP request, it should be paired with a
+// 403 response status.
+var ErrInjectionNotAllowed = errors.New("not allowed by injection policy")
+
+// envNameMatcher matches valid environment variable names.
+var envNameMatcher = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// InjectionPolicy is the allow-list against which the environment variables
+// and mounts of configure requests are validated before they're passed to
+// backend processes.
+type InjectionPolicy struct {
+	// EnvPrefixes are the allowed prefixes of environment variable names.
+	EnvPrefixes []string
+	// MountRoots are the directories under which mounted paths must reside.
+	MountRoots []string
+}
+
+// DefaultInjectionPolicy allows the environment variables that configure
+// llama.cpp and ggml, and doesn't allow any mounts.
+var DefaultInjectionPolicy = InjectionPolicy{
+	EnvPrefixes: []string{"LLAMA_ARG_", "GGML_"},
+}
+
+// validateEnv checks that all environment variable names are allowed.
+func (p InjectionPolicy) validateEnv(env map[string]string) error {
+	for name := range env {
+		if !envNameMatcher.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		allowed := false
+		for _, prefix := range p.EnvPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("environment variable %q: %w", name, ErrInjectionNotAllowed)
+		}
+	}
+	return nil
+}
+
+// resolveMounts checks that all mounts exist and reside under an allowed
+// root, and returns their resolved paths.
+func (p InjectionPolicy) resolveMounts(mounts []string) ([]string, error) {
+	resolved := make([]string, 0, len(mounts))
+	for _, mount := range mounts {
+		if !filepath.IsAbs(mount) {
+			return nil, fmt.Errorf("mount %q is not an absolute path", mount)
+		}
+		// Resolve symbolic links so that they can't escape the roots.
+		path, err := filepath.EvalSymlinks(mount)
+		if err != nil {
+			return nil, fmt.Errorf("mount %q: %w", mount, err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("mount %q: %w", mount, err)
+		}
+		allowed := false
+		for _, root := range p.MountRoots {
+			root, err := filepath.EvalSymlinks(root)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("mount %q: %w", mount, ErrInjectionNotAllowed)
+		}
+		resolved = append(resolved, path)
+	}
+	return resolved, nil
+}
+
+// injectionErrorStatus returns the HTTP status for an injection validation
+// error.
+func injectionErrorStatus(err error) int {
+	if errors.Is(err, ErrInjectionNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// SetInjectionPolicy sets the allow-list against which the environment
+// variables and mounts of configure requests are validated. It must be called
+// before the scheduler starts serving requests.
+func (s *Scheduler) SetInjectionPolicy(policy InjectionPolicy) {
+	s.injectionPolicy = policy
+}
@@ -0,0 +1,111 @@
+package scheduling
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestInjectionPolicyValidateEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		expectErr  bool
+		notAllowed bool
+	}{
+		{name: "empty", env: nil},
+		{name: "allowed prefixes", env: map[string]string{"LLAMA_ARG_GRAMMAR_FILE": "/g.gbnf", "GGML_CUDA_NO_PINNED": "1"}},
+		{name: "disallowed name", env: map[string]string{"LD_PRELOAD": "/tmp/evil.so"}, expectErr: true, notAllowed: true},
+		{name: "invalid name", env: map[string]string{"LLAMA_ARG_X=Y": "1"}, expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultInjectionPolicy.validateEnv(tt.env)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, got %v", tt.expectErr, err)
+			}
+			if errors.Is(err, ErrInjectionNotAllowed) != tt.notAllowed {
+				t.Errorf("Expected ErrInjectionNotAllowed: %v, got %v", tt.notAllowed, err)
+			}
+		})
+	}
+}
+
+func TestInjectionPolicyResolveMounts(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	grammar := filepath.Join(root, "json.gbnf")
+	if err := os.WriteFile(grammar, []byte("root ::= object"), 0644); err != nil {
+		t.Fatalf("Failed to write grammar: %v", err)
+	}
+	escape := filepath.Join(root, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	policy := InjectionPolicy{MountRoots: []string{root}}
+
+	tests := []struct {
+		name       string
+		mount      string
+		expectErr  bool
+		notAllowed bool
+	}{
+		{name: "file under root", mount: grammar},
+		{name: "root itself", mount: root},
+		{name: "relative path", mount: "json.gbnf", expectErr: true},
+		{name: "missing file", mount: filepath.Join(root, "missing"), expectErr: true},
+		{name: "outside root", mount: outside, expectErr: true, notAllowed: true},
+		{name: "symlink escaping root", mount: escape, expectErr: true, notAllowed: true},
+		{name: "parent traversal", mount: root + string(filepath.Separator) + ".." + string(filepath.Separator) + filepath.Base(outside), expectErr: true, notAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := policy.resolveMounts([]string{tt.mount})
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, got %v", tt.expectErr, err)
+			}
+			if errors.Is(err, ErrInjectionNotAllowed) != tt.notAllowed {
+				t.Errorf("Expected ErrInjectionNotAllowed: %v, got %v", tt.notAllowed, err)
+			}
+			if err == nil && len(resolved) != 1 {
+				t.Errorf("Expected 1 resolved mount, got %v", resolved)
+			}
+		})
+	}
+
+	// No mounts are allowed by default.
+	if _, err := DefaultInjectionPolicy.resolveMounts([]string{grammar}); !errors.Is(err, ErrInjectionNotAllowed) {
+		t.Errorf("Expected default policy to reject mounts, got %v", err)
+	}
+}
+
+func TestConfigureRejectsDisallowedInjection(t *testing.T) {
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "disallowed env", body: `{"model": "ai/model", "env": {"LD_PRELOAD": "x"}}`, expectedStatus: http.StatusForbidden},
+		{name: "disallowed mount", body: `{"model": "ai/model", "mounts": ["` + filepath.ToSlash(t.TempDir()) + `"]}`, expectedStatus: http.StatusForbidden},
+		{name: "invalid mount", body: `{"model": "ai/model", "mounts": ["relative"]}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/engines/_configure", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			s.Configure(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -44,6 +44,9 @@ type installer struct {
 	started atomic.Bool
 	// statuses maps backend names to their installation statuses.
 	statuses map[string]*installStatus
+	// onInstalled is called, if non-nil, when a backend's installation
+	// completes, with a non-nil error if it failed.
+	onInstalled func(backend string, err error)
 }
 
 // newInstaller creates a new backend installer.
@@ -110,6 +113,9 @@ func (i *installer) run(ctx context.Context) {
 		} else {
 			close(status.installed)
 		}
+		if i.onInstalled != nil {
+			i.onInstalled(name, status.err)
+		}
 	}
 }
 
@@ -138,3 +144,27 @@ func (i *installer) wait(ctx context.Context, backend string) error {
 		return status.err
 	}
 }
+
+// Installation health states, as reported by health.
+const (
+	healthInstalling = "installing"
+	healthReady      = "ready"
+	healthError      = "error"
+)
+
+// health returns the installation health of the specified backend, along with
+// the error its installation failed with, if any.
+func (i *installer) health(backend string) (string, error) {
+	status, ok := i.statuses[backend]
+	if !ok {
+		return healthError, ErrBackendNotFound
+	}
+	select {
+	case <-status.installed:
+		return healthReady, nil
+	case <-status.failed:
+		return healthError, status.err
+	default:
+		return healthInstalling, nil
+	}
+}
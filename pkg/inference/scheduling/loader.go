@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
 	"reflect"
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/docker/model-runner/pkg/environment"
@@ -26,6 +29,12 @@ const (
 	// defaultRunnerIdleTimeout is the default maximum amount of time that a
 	// runner can sit idle (i.e. without any requests) before being terminated.
 	defaultRunnerIdleTimeout = 5 * time.Minute
+	// maximumCrashReports is the number of crash reports kept for crashed
+	// runners, the oldest being discarded first.
+	maximumCrashReports = 16
+	// loraScaleTimeout is the maximum amount of time that applying the scales
+	// of the LoRA adapters of a configuration to a runner's replicas can take.
+	loraScaleTimeout = 30 * time.Second
 )
 
 var (
@@ -49,6 +58,9 @@ type runnerKey struct {
 	draftModelID string
 	// mode is the operation mode associated with the runner.
 	mode inference.BackendMode
+	// replica distinguishes the runners loaded for the same model, backend
+	// and mode when it's configured with multiple replicas.
+	replica int
 }
 
 // makeConfigKey creates a runnerKey for configuration storage.
@@ -75,12 +87,31 @@ func makeRunnerKey(backendName, modelID, draftModelID string, mode inference.Bac
 	}
 }
 
+// replicas returns the keys of the loaded replicas of the runner with the
+// given key, regardless of its replica. The caller must hold the loader lock.
+func (l *loader) replicas(key runnerKey) []runnerKey {
+	var keys []runnerKey
+	for k := range l.runners {
+		if k.backend == key.backend && k.modelID == key.modelID && k.draftModelID == key.draftModelID && k.mode == key.mode {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
 // runnerInfo holds information about a runner including its slot and the original model reference used to load it.
 type runnerInfo struct {
 	// slot is the slot index where the runner is stored.
 	slot int
 	// modelRef is the original model reference (tag) used to load the runner.
 	modelRef string
+	// stale indicates that the configuration of the runner's backend has
+	// changed since it started, so that it's evicted once it's unused.
+	stale bool
+	// loaded is when the runner became ready.
+	loaded time.Time
+	// loadDuration is the time the runner took to become ready.
+	loadDuration time.Duration
 }
 
 // loader manages the loading and unloading of backend runners. It regulates
@@ -97,10 +128,34 @@ type loader struct {
 	modelManager *models.Manager
 	// runnerIdleTimeout is the loader-specific default runner idle timeout.
 	runnerIdleTimeout time.Duration
+	// defaultRunnerIdleTimeout is the runner idle timeout restored when a
+	// custom timeout is cleared.
+	defaultRunnerIdleTimeout time.Duration
+	// deepSleepTimeout is the global idle period after which the loader enters
+	// deep sleep. Deep sleep is disabled if it is zero.
+	deepSleepTimeout time.Duration
+	// sleepers are the components paused during deep sleep.
+	sleepers []Sleeper
+	// maxConcurrentRequests is the maximum number of requests each runner
+	// serves concurrently. It is unlimited if zero.
+	maxConcurrentRequests int
+	// maxRunnerRestarts is the number of times the backend of a runner is
+	// restarted after exiting unexpectedly, while requests are waiting for
+	// it. Restarts are disabled if zero.
+	maxRunnerRestarts int
 	// totalMemory is the total system memory allocated to the loader.
 	totalMemory inference.RequiredMemory
+	// gpuMemory is the VRAM of each GPU. It is only tracked on systems with
+	// multiple GPUs, and is nil otherwise.
+	gpuMemory []uint64
+	// sysMemInfo is used to poll the free VRAM.
+	sysMemInfo memory.SystemMemoryInfo
+	// vramPoller polls the free VRAM, or is nil if it can't be polled.
+	vramPoller *vramPoller
 	// idleCheck is used to signal the run loop when timestamps have updated.
 	idleCheck chan struct{}
+	// ready is closed once the run loop has enabled loads.
+	ready chan struct{}
 	// guard is a sempahore controlling access to all subsequent fields. It is
 	// buffered (with size 1) and contains a single element that must be held in
 	// order to operate on those fields. We use a channel (instead of a
@@ -108,8 +163,21 @@ type loader struct {
 	guard chan struct{}
 	// loadsEnabled signals that loads are currently enabled.
 	loadsEnabled bool
+	// draining signals that the loader is draining, so it rejects new
+	// requests.
+	draining bool
 	// availableMemory is the available portion of the loader's total memory.
 	availableMemory inference.RequiredMemory
+	// availableGPUMemory is the available portion of each GPU's VRAM.
+	availableGPUMemory []uint64
+	// externalVRAM is the VRAM used by other processes, as of the last poll.
+	externalVRAM uint64
+	// externalGPUMemory is the VRAM of each GPU used by other processes, as
+	// of the last poll, on systems with multiple GPUs.
+	externalGPUMemory []uint64
+	// allocationGeneration is incremented whenever memory is allocated to or
+	// reclaimed from a runner.
+	allocationGeneration uint64
 	// waiters is the set of signal channels associated with waiting loaders. We
 	// use a set of signaling channels (instead of a sync.Cond) to enable
 	// polling. Each signaling channel should be buffered (with size 1).
@@ -123,13 +191,30 @@ type loader struct {
 	references []uint
 	// allocations maps slot indices to memory allocation sizes.
 	allocations []inference.RequiredMemory
+	// gpuAllocations maps slot indices to per-GPU VRAM allocation sizes.
+	gpuAllocations [][]uint64
 	// timestamps maps slot indices to last usage times. Values in this slice
 	// are only valid if the corresponding reference count is zero.
 	timestamps []time.Time
 	// runnerConfigs maps model names to runner configurations
 	runnerConfigs map[runnerKey]inference.BackendConfiguration
+	// promptCaches are the prompt caches saved by runners.
+	promptCaches map[promptCacheKey]promptCacheEntry
+	// promptCacheSize is the total size of promptCaches.
+	promptCacheSize int64
+	// sessions maps sessions to the replicas serving their requests.
+	sessions map[string]sessionAffinity
+	// crashes are the statuses of the most recently crashed runners, oldest
+	// first.
+	crashes []BackendStatus
 	// openAIRecorder is used to record OpenAI API inference requests and responses.
 	openAIRecorder *metrics.OpenAIRecorder
+	// inferenceMetrics records runner evictions.
+	inferenceMetrics *metrics.InferenceMetrics
+	// lastActive is the time of the most recent load or release.
+	lastActive time.Time
+	// asleep indicates whether or not the loader is in deep sleep.
+	asleep bool
 }
 
 // newLoader creates a new loader.
@@ -162,26 +247,48 @@ func newLoader(
 	// Compute the amount of available memory.
 	totalMemory := sysMemInfo.GetTotalMemory()
 
+	// Track VRAM per GPU on multi-GPU systems, so that runners can be placed
+	// on different GPUs.
+	sizes := sysMemInfo.GetGPUMemory()
+	var gpuMemory []uint64
+	if len(sizes) > 1 {
+		gpuMemory = sizes
+	}
+
 	// Create the loader.
 	l := &loader{
-		log:               log,
-		backends:          backends,
-		modelManager:      modelManager,
-		runnerIdleTimeout: runnerIdleTimeout,
-		totalMemory:       totalMemory,
-		idleCheck:         make(chan struct{}, 1),
-		guard:             make(chan struct{}, 1),
-		availableMemory:   totalMemory,
-		waiters:           make(map[chan<- struct{}]bool),
-		runners:           make(map[runnerKey]runnerInfo, nSlots),
-		slots:             make([]*runner, nSlots),
-		references:        make([]uint, nSlots),
-		allocations:       make([]inference.RequiredMemory, nSlots),
-		timestamps:        make([]time.Time, nSlots),
-		runnerConfigs:     make(map[runnerKey]inference.BackendConfiguration),
-		openAIRecorder:    openAIRecorder,
+		log:                log,
+		backends:           backends,
+		modelManager:       modelManager,
+		runnerIdleTimeout:  runnerIdleTimeout,
+		totalMemory:        totalMemory,
+		gpuMemory:          gpuMemory,
+		sysMemInfo:         sysMemInfo,
+		idleCheck:          make(chan struct{}, 1),
+		ready:              make(chan struct{}),
+		guard:              make(chan struct{}, 1),
+		availableMemory:    totalMemory,
+		availableGPUMemory: slices.Clone(gpuMemory),
+		waiters:            make(map[chan<- struct{}]bool),
+		runners:            make(map[runnerKey]runnerInfo, nSlots),
+		slots:              make([]*runner, nSlots),
+		references:         make([]uint, nSlots),
+		allocations:        make([]inference.RequiredMemory, nSlots),
+		gpuAllocations:     make([][]uint64, nSlots),
+		timestamps:         make([]time.Time, nSlots),
+		runnerConfigs:      make(map[runnerKey]inference.BackendConfiguration),
+		promptCaches:       make(map[promptCacheKey]promptCacheEntry),
+		sessions:           make(map[string]sessionAffinity),
+		openAIRecorder:     openAIRecorder,
 	}
+	l.defaultRunnerIdleTimeout = runnerIdleTimeout
 	l.guard <- struct{}{}
+
+	// Account for VRAM that's already in use by other processes, if known.
+	if free := sysMemInfo.GetFreeGPUMemory(); len(free) > 0 && len(free) == len(sizes) {
+		l.vramPoller = newVRAMPoller(l)
+		l.updateExternalVRAM(free)
+	}
 	return l
 }
 
@@ -223,15 +330,73 @@ func formatMemorySize(bytes uint64) string {
 // freeRunnerSlot frees a runner slot and reclaims its memory.
 // The caller must hold the loader lock.
 func (l *loader) freeRunnerSlot(slot int, key runnerKey) {
+	l.inferenceMetrics.RecordEviction(key.backend, l.runners[key].modelRef, key.mode.String())
+	select {
+	case <-l.slots[slot].done:
+		l.recordCrash(key, l.runners[key].modelRef, l.slots[slot].err)
+	default:
+	}
 	l.slots[slot].terminate()
 	l.slots[slot] = nil
 	l.availableMemory.RAM += l.allocations[slot].RAM
 	l.availableMemory.VRAM += l.allocations[slot].VRAM
 	l.allocations[slot] = inference.RequiredMemory{RAM: 0, VRAM: 0}
+	for gpu, vram := range l.gpuAllocations[slot] {
+		l.availableGPUMemory[gpu] += vram
+	}
+	l.gpuAllocations[slot] = nil
+	l.allocationGeneration++
 	l.timestamps[slot] = time.Time{}
+	l.forgetSessions(key)
+	l.removePromptCaches(slot)
 	delete(l.runners, key)
 }
 
+// recordCrash records a crash report for a runner that exited before it was
+// evicted. The caller must hold the loader lock.
+func (l *loader) recordCrash(key runnerKey, modelRef string, err error) {
+	report := &CrashReport{Time: time.Now(), Error: errBackendQuitUnexpectedly.Error()}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	var exited *inference.ErrProcessExited
+	if errors.As(err, &exited) {
+		report.ExitCode = &exited.ExitCode
+		report.Command = exited.Args
+		report.Output = exited.Output
+	}
+	l.log.Warnf("%s backend runner with model %s (%s) in %s mode crashed: %s",
+		key.backend, key.modelID, modelRef, key.mode, report.Error)
+	l.crashes = append(l.crashes, BackendStatus{
+		BackendName: key.backend,
+		ModelName:   modelRef,
+		Mode:        key.mode.String(),
+		Replica:     key.replica,
+		Crash:       report,
+	})
+	if len(l.crashes) > maximumCrashReports {
+		l.crashes = slices.Delete(l.crashes, 0, len(l.crashes)-maximumCrashReports)
+	}
+}
+
+// restartPolicy returns the function with which the runner in the given slot
+// asks whether its backend should be restarted after exiting with err. It's
+// restarted if requests are waiting for it, in which case the exit is recorded
+// as a crash, and otherwise left to be evicted.
+func (l *loader) restartPolicy(slot int, key runnerKey, modelRef string) func(context.Context, error) bool {
+	return func(ctx context.Context, err error) bool {
+		if !l.lock(ctx) {
+			return false
+		}
+		defer l.unlock()
+		if l.references[slot] == 0 {
+			return false
+		}
+		l.recordCrash(key, modelRef, err)
+		return true
+	}
+}
+
 // evict evicts all unused runners from the loader. If idleOnly is true, then
 // only those unused, but functioning, runners which are considered "idle" (based
 // on usage timestamp) are evicted. Defunct (e.g. crashed) runners will be evicted
@@ -288,34 +453,76 @@ func (l *loader) evictRunner(backend, model string, mode inference.BackendMode)
 	return len(l.runners)
 }
 
-// Unload unloads runners and returns the number of unloaded runners.
-func (l *loader) Unload(ctx context.Context, unload UnloadRequest) int {
+// evictReplica evicts a specific replica of a runner, which must be unused.
+// The caller must hold the loader lock.
+func (l *loader) evictReplica(key runnerKey, info runnerInfo) {
+	l.log.Infof("Evicting %s backend runner with model %s (%s) in %s mode",
+		key.backend, key.modelID, info.modelRef, key.mode,
+	)
+	l.freeRunnerSlot(info.slot, key)
+}
+
+// Unload unloads the runners selected by an unload request. Runners that are
+// in use are unloaded once their requests complete, unless the request is
+// restricted to idle runners. It returns the unloaded runners and the number
+// of runners that will be unloaded once they're unused.
+func (l *loader) Unload(ctx context.Context, unload UnloadRequest) ([]BackendStatus, int) {
 	if !l.lock(ctx) {
-		return 0
+		return nil, 0
 	}
 	defer l.unlock()
 
-	return len(l.runners) - func() int {
-		if unload.All {
-			l.runnerConfigs = make(map[runnerKey]inference.BackendConfiguration)
-			return l.evict(false)
-		} else {
-			for _, model := range unload.Models {
-				modelID := l.modelManager.ResolveModelID(model)
-				// Delete all runner configs for this model (including with different draft models)
-				for key := range l.runnerConfigs {
-					if key.backend == unload.Backend && key.modelID == modelID {
-						delete(l.runnerConfigs, key)
-					}
-				}
-				// Evict both, completion and embedding models. We should consider
-				// accepting a mode parameter in unload requests.
-				l.evictRunner(unload.Backend, modelID, inference.BackendModeCompletion)
-				l.evictRunner(unload.Backend, modelID, inference.BackendModeEmbedding)
+	modelIDs := make([]string, len(unload.Models))
+	for i, model := range unload.Models {
+		modelIDs[i] = l.modelManager.ResolveModelID(model)
+	}
+	modeSelected := func(key runnerKey) bool {
+		return unload.Mode == "" || key.mode.String() == unload.Mode
+	}
+
+	// Forget the configuration of the unloaded models (including with
+	// different draft models), unless only some of their runners may be
+	// unloaded.
+	if !unload.IdleOnly && unload.GPU == nil {
+		for key := range l.runnerConfigs {
+			if (unload.All || (key.backend == unload.Backend && slices.Contains(modelIDs, key.modelID))) && modeSelected(key) {
+				delete(l.runnerConfigs, key)
 			}
-			return len(l.runners)
 		}
-	}()
+	}
+
+	var unloaded []BackendStatus
+	draining := 0
+	for key, info := range l.runners {
+		if !unload.All && !slices.Contains(modelIDs, key.modelID) ||
+			unload.Backend != "" && key.backend != unload.Backend || !modeSelected(key) ||
+			unload.GPU != nil && !slices.Contains(l.allocatedGPUs(info.slot), *unload.GPU) {
+			continue
+		}
+		if l.references[info.slot] > 0 {
+			if !unload.IdleOnly {
+				info.stale = true
+				l.runners[key] = info
+				draining++
+			}
+			continue
+		}
+		l.log.Infof("Unloading %s backend runner with model %s (%s) in %s mode",
+			key.backend, key.modelID, info.modelRef, key.mode,
+		)
+		unloaded = append(unloaded, BackendStatus{
+			BackendName: key.backend,
+			ModelName:   info.modelRef,
+			Mode:        key.mode.String(),
+			Replica:     key.replica,
+			GPUs:        l.allocatedGPUs(info.slot),
+		})
+		l.freeRunnerSlot(info.slot, key)
+	}
+	if len(unloaded) > 0 {
+		l.broadcast()
+	}
+	return unloaded, draining
 }
 
 // stopAndDrainTimer stops and drains a timer without knowing if it was running.
@@ -374,7 +581,14 @@ func (l *loader) run(ctx context.Context) {
 		return
 	}
 	l.loadsEnabled = true
+	l.lastActive = time.Now()
 	l.unlock()
+	close(l.ready)
+
+	// Track the VRAM used by other processes, if it can be polled.
+	if l.vramPoller != nil {
+		go l.vramPoller.run(ctx)
+	}
 
 	// Defer disablement of loads and wait for complete eviction.
 	defer func() {
@@ -404,6 +618,18 @@ func (l *loader) run(ctx context.Context) {
 	}
 	defer idleTimer.Stop()
 
+	// Create a similar timer to drive deep sleep and arm it for the case
+	// where no runner is ever loaded.
+	sleepTimer := time.NewTimer(0)
+	stopAndDrainTimer(sleepTimer)
+	defer sleepTimer.Stop()
+	if l.lock(ctx) {
+		if nextSleep := l.deepSleepDuration(); nextSleep >= 0 {
+			sleepTimer.Reset(nextSleep)
+		}
+		l.unlock()
+	}
+
 	// Evict idle runners.
 	for {
 		select {
@@ -418,13 +644,30 @@ func (l *loader) run(ctx context.Context) {
 				}
 				l.unlock()
 			}
+		case <-sleepTimer.C:
+			// Enter deep sleep if there has been no activity since the timer
+			// was armed, otherwise re-arm it.
+			if l.lock(ctx) {
+				if nextSleep := l.deepSleepDuration(); nextSleep == 0 {
+					l.sleep()
+					// Idle eviction pauses too, until the next load.
+					stopAndDrainTimer(idleTimer)
+				} else if nextSleep > 0 {
+					sleepTimer.Reset(nextSleep)
+				}
+				l.unlock()
+			}
 		case <-l.idleCheck:
-			// Compute the next idle check time.
+			// Compute the next idle check and deep sleep times.
 			if l.lock(ctx) {
 				stopAndDrainTimer(idleTimer)
 				if nextCheck := l.idleCheckDuration(); nextCheck >= 0 {
 					idleTimer.Reset(nextCheck)
 				}
+				stopAndDrainTimer(sleepTimer)
+				if nextSleep := l.deepSleepDuration(); nextSleep >= 0 {
+					sleepTimer.Reset(nextSleep)
+				}
 				l.unlock()
 			}
 		}
@@ -433,8 +676,9 @@ func (l *loader) run(ctx context.Context) {
 
 // load allocates a runner using the specified backend and modelID. If allocated,
 // it should be released by the caller using the release mechanism (once the
-// runner is no longer needed).
-func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string, mode inference.BackendMode) (*runner, error) {
+// runner is no longer needed). Requests of the same session, if not empty, are
+// served by the same replica of the runner while it's loaded.
+func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string, mode inference.BackendMode, session string) (*runner, error) {
 	// Grab the backend.
 	backend, ok := l.backends[backendName]
 	if !ok {
@@ -486,9 +730,45 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 	if runtime.GOOS == "windows" {
 		totalVRAM += l.totalMemory.RAM / 2
 	}
+	// If the model doesn't fit in VRAM entirely, offload only the layers that
+	// fit, if the backend supports it, rather than refusing to load it.
+	if partial, ok := backend.(inference.PartialOffloader); ok && memory.VRAM > totalVRAM && l.totalMemory.VRAM > 1 &&
+		(runnerConfig == nil || runnerConfig.GPULayers == nil) {
+		layers, partialMemory, err := partial.FitGPULayers(ctx, modelID, runnerConfig, l.totalMemory.VRAM)
+		if err != nil {
+			return nil, err
+		}
+		l.log.Infof("Offloading %d layers of %s to fit in %s VRAM, which will require %s RAM and %s VRAM",
+			layers, modelID, formatMemorySize(l.totalMemory.VRAM),
+			formatMemorySize(partialMemory.RAM), formatMemorySize(partialMemory.VRAM))
+		partialConfig := inference.BackendConfiguration{}
+		if runnerConfig != nil {
+			partialConfig = *runnerConfig
+		}
+		partialConfig.GPULayers = &layers
+		runnerConfig = &partialConfig
+		memory = partialMemory
+	}
 	if memory.RAM > l.totalMemory.RAM || memory.VRAM > totalVRAM {
 		return nil, errModelTooBig
 	}
+	if runnerConfig != nil && len(l.gpuMemory) > 0 {
+		for _, gpu := range runnerConfig.GPUs {
+			if gpu >= len(l.gpuMemory) {
+				return nil, fmt.Errorf("GPU %d not found: system has %d GPUs", gpu, len(l.gpuMemory))
+			}
+		}
+		if len(runnerConfig.GPUs) == 0 && len(runnerConfig.TensorSplit) > 0 && len(runnerConfig.TensorSplit) != len(l.gpuMemory) {
+			return nil, fmt.Errorf("tensor split has %d proportions for %d GPUs", len(runnerConfig.TensorSplit), len(l.gpuMemory))
+		}
+	}
+	if _, ok := l.placeOnGPUs(memory.VRAM, runnerConfig, l.gpuMemory); !ok {
+		return nil, errModelTooBig
+	}
+	replicaCount := 1
+	if runnerConfig != nil && runnerConfig.Replicas > 1 {
+		replicaCount = runnerConfig.Replicas
+	}
 
 	// Acquire the loader lock and defer its release.
 	if !l.lock(ctx) {
@@ -496,6 +776,9 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 	}
 	defer l.unlock()
 
+	// Wake from deep sleep, if necessary. The runner is loaded as usual.
+	l.wake()
+
 	// Create a polling channel that we can use to detect state changes and
 	// ensure that it's deregistered by the time we return.
 	poll := make(chan struct{}, 1)
@@ -507,7 +790,7 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 	// Loop until we can satisfy the request or an error occurs.
 	for {
 		slot := -1
-		availableVRAM := l.availableMemory.VRAM
+		availableVRAM := l.freeVRAM()
 		if runtime.GOOS == "windows" {
 			sharedRAM := l.totalMemory.RAM / 2
 			if l.availableMemory.RAM < sharedRAM {
@@ -516,39 +799,100 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 			availableVRAM += sharedRAM
 		}
 
+		// Place the runner on GPUs with sufficient free VRAM, if tracked.
+		placement, placed := l.placeOnGPUs(memory.VRAM, runnerConfig, l.freeGPUMemory())
+
 		// If loads are disabled, then there's nothing we can do.
 		if !l.loadsEnabled {
 			return nil, errLoadsDisabled
 		}
 
-		// See if we can satisfy the request with an existing runner.
-		existing, ok := l.runners[makeRunnerKey(backendName, modelID, draftModelID, mode)]
-		if ok {
+		// If the loader is draining, then no new requests are accepted, even
+		// for existing runners.
+		if l.draining {
+			return nil, errDraining
+		}
+
+		// Evict defunct replicas of the runner that are unused, and retry
+		// loading without them.
+		key := makeRunnerKey(backendName, modelID, draftModelID, mode)
+		replicas := l.replicas(key)
+		evictedDefunct := false
+		for _, r := range replicas {
+			info := l.runners[r]
 			select {
-			case <-l.slots[existing.slot].done:
-				l.log.Warnf("%s runner for %s is defunct. Waiting for it to be evicted.", backendName, existing.modelRef)
-				if l.references[existing.slot] == 0 {
-					l.evictRunner(backendName, modelID, mode)
-					// Continue the loop to retry loading after evicting the defunct runner
-					continue
-				} else {
-					goto WaitForChange
+			case <-l.slots[info.slot].done:
+				l.log.Warnf("%s runner for %s is defunct. Waiting for it to be evicted.", backendName, info.modelRef)
+				if l.references[info.slot] == 0 {
+					l.freeRunnerSlot(info.slot, r)
+					evictedDefunct = true
 				}
 			default:
-				l.references[existing.slot] += 1
-				l.timestamps[existing.slot] = time.Time{}
-				return l.slots[existing.slot], nil
 			}
 		}
+		if evictedDefunct {
+			continue
+		}
+
+		// See if we can satisfy the request with an existing replica of the
+		// runner, choosing the one serving the request's session, or else the
+		// one serving the fewest requests. Defunct replicas that are in use
+		// and stale replicas, which must complete their requests and be
+		// evicted to be restarted with the new configuration, aren't
+		// available.
+		selected, unavailable, affine := -1, false, false
+		sessionKey, sessionOK := l.sessionReplica(session, key)
+		for i, r := range replicas {
+			info := l.runners[r]
+			select {
+			case <-l.slots[info.slot].done:
+				unavailable = true
+				continue
+			default:
+			}
+			if info.stale {
+				unavailable = true
+				continue
+			}
+			if sessionOK && r == sessionKey {
+				selected, affine = i, true
+				break
+			}
+			if selected < 0 || l.references[info.slot] < l.references[l.runners[replicas[selected]].slot] {
+				selected = i
+			}
+		}
+		if selected >= 0 {
+			// Load another replica rather than sharing a busy one, if the
+			// runner is configured with more replicas and there's room for
+			// one without evicting other runners. Sessions stay with their
+			// replica regardless.
+			info := l.runners[replicas[selected]]
+			if affine || l.references[info.slot] == 0 || len(replicas) >= replicaCount ||
+				memory.RAM > l.availableMemory.RAM || memory.VRAM > availableVRAM || !placed || len(l.runners) == len(l.slots) {
+				l.references[info.slot] += 1
+				l.timestamps[info.slot] = time.Time{}
+				l.recordSession(session, replicas[selected])
+				return l.slots[info.slot], nil
+			}
+		} else if unavailable {
+			goto WaitForChange
+		}
 
 		// If there's not sufficient memory or all slots are full, then try
 		// evicting unused runners.
-		if memory.RAM > l.availableMemory.RAM || memory.VRAM > availableVRAM || len(l.runners) == len(l.slots) {
+		if memory.RAM > l.availableMemory.RAM || memory.VRAM > availableVRAM || !placed || len(l.runners) == len(l.slots) {
 			l.log.Infof("Evicting to make room: need %s RAM, %s VRAM; have %s RAM, %s VRAM available; %d/%d slots used",
 				formatMemorySize(memory.RAM), formatMemorySize(memory.VRAM),
 				formatMemorySize(l.availableMemory.RAM),
 				formatMemorySize(availableVRAM),
 				len(l.runners), len(l.slots))
+			// Prompt caches are deleted before runners are evicted, if that
+			// frees enough RAM.
+			if memory.RAM > l.availableMemory.RAM && memory.VRAM <= availableVRAM && placed &&
+				len(l.runners) < len(l.slots) && l.evictPromptCaches(memory.RAM) {
+				continue
+			}
 			runnerCountAtLoopStart := len(l.runners)
 			remainingRunners := l.evict(false)
 			// Restart the loop if eviction happened to recompute availableVRAM
@@ -559,7 +903,7 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 		}
 
 		// If there's sufficient memory and a free slot, then find the slot.
-		if memory.RAM <= l.availableMemory.RAM && memory.VRAM <= availableVRAM && len(l.runners) < len(l.slots) {
+		if memory.RAM <= l.availableMemory.RAM && memory.VRAM <= availableVRAM && placed && len(l.runners) < len(l.slots) {
 			for s, runner := range l.slots {
 				if runner == nil {
 					slot = s
@@ -574,20 +918,78 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 				formatMemorySize(l.availableMemory.RAM),
 				formatMemorySize(availableVRAM),
 				len(l.runners), len(l.slots))
+
+			// If no runners are left to evict and the model would only fit
+			// without the VRAM used by other processes, then refuse to load
+			// it rather than waiting for them indefinitely.
+			if len(l.runners) == 0 && memory.RAM <= l.availableMemory.RAM && l.externalVRAM > 0 &&
+				memory.VRAM <= availableVRAM+l.externalVRAM {
+				l.log.Warnf("Cannot load %s: other processes are using %s VRAM",
+					modelID, formatMemorySize(l.externalVRAM))
+				return nil, errInsufficientVRAM
+			}
 		}
 
 		// If we've identified a slot, then we're ready to start a runner.
 		if slot >= 0 {
 			// runnerConfig was already retrieved earlier (lines 401-405), no need to look it up again
-			// Create the runner.
-			l.log.Infof("Loading %s backend runner with model %s in %s mode", backendName, modelID, mode)
-			runner, err := run(l.log, backend, modelID, modelRef, mode, slot, runnerConfig, l.openAIRecorder)
+			// Restrict runners without configured GPUs to the GPUs they were
+			// placed on.
+			config := runnerConfig
+			if placement.gpus != nil && (config == nil || len(config.GPUs) == 0) {
+				placedConfig := inference.BackendConfiguration{}
+				if config != nil {
+					placedConfig = *config
+				}
+				placedConfig.GPUs = placement.gpus
+				config = &placedConfig
+				l.log.Infof("Placing %s on GPUs %v", modelID, placement.gpus)
+			}
+
+			// Give backends that save prompt caches a directory for them.
+			if _, ok := backend.(inference.PromptCacheSaver); ok && mode == inference.BackendModeCompletion {
+				dir, err := preparePromptCacheDir(slot)
+				if err != nil {
+					return nil, fmt.Errorf("unable to create prompt cache directory: %w", err)
+				}
+				cacheConfig := inference.BackendConfiguration{}
+				if config != nil {
+					cacheConfig = *config
+				}
+				cacheConfig.PromptCacheDir = dir
+				config = &cacheConfig
+			}
+
+			// Keep the recent output of the runner's backend.
+			output := newRunnerOutput()
+			outputConfig := inference.BackendConfiguration{}
+			if config != nil {
+				outputConfig = *config
+			}
+			outputConfig.Output = output
+			config = &outputConfig
+
+			// Create the runner as the first replica that isn't loaded.
+			newKey := key
+			for _, ok := l.runners[newKey]; ok; _, ok = l.runners[newKey] {
+				newKey.replica++
+			}
+			if newKey.replica > 0 {
+				l.log.Infof("Loading replica %d of %s backend runner with model %s in %s mode", newKey.replica, backendName, modelID, mode)
+			} else {
+				l.log.Infof("Loading %s backend runner with model %s in %s mode", backendName, modelID, mode)
+			}
+			loadStart := time.Now()
+			runner, err := run(l.log, backend, modelID, modelRef, mode, slot, config, l.openAIRecorder,
+				l.maxRunnerRestarts, l.restartPolicy(slot, newKey, modelRef))
 			if err != nil {
 				l.log.Warnf("Unable to start %s backend runner with model %s in %s mode: %v",
 					backendName, modelID, mode, err,
 				)
 				return nil, fmt.Errorf("unable to start runner: %w", err)
 			}
+			runner.queue = newRequestQueue(l.maxConcurrentRequests)
+			runner.output = output
 
 			// Wait for the runner to be ready. In theory it's a little
 			// inefficient to block all other loaders (including those that
@@ -606,11 +1008,32 @@ func (l *loader) load(ctx context.Context, backendName, modelID, modelRef string
 			// Perform registration and return the runner.
 			l.availableMemory.RAM -= memory.RAM
 			l.availableMemory.VRAM -= memory.VRAM
-			l.runners[makeRunnerKey(backendName, modelID, draftModelID, mode)] = runnerInfo{slot, modelRef}
+			l.runners[newKey] = runnerInfo{
+				slot:         slot,
+				modelRef:     modelRef,
+				loaded:       time.Now(),
+				loadDuration: time.Since(loadStart),
+			}
+			l.recordSession(session, newKey)
 			l.slots[slot] = runner
 			l.references[slot] = 1
 			l.allocations[slot].RAM = memory.RAM
 			l.allocations[slot].VRAM = memory.VRAM
+			if placement.vram != nil {
+				for gpu, vram := range placement.vram {
+					l.availableGPUMemory[gpu] -= vram
+				}
+				l.gpuAllocations[slot] = placement.vram
+			}
+			l.allocationGeneration++
+			if l.modelManager != nil {
+				// Record the use without holding up the loader on the store.
+				go func() {
+					if err := l.modelManager.MarkModelUsed(modelID); err != nil {
+						l.log.Warnf("Failed to record use of model %s: %v", modelID, err)
+					}
+				}()
+			}
 			return runner, nil
 		}
 
@@ -635,32 +1058,39 @@ func (l *loader) release(runner *runner) {
 	l.lock(context.Background())
 	defer l.unlock()
 
-	// Find the runner's slot by iterating through runners
+	// Find the runner's slot by iterating through runners, since replicas
+	// share the same backend, model and mode.
+	var slotKey runnerKey
 	var slotInfo runnerInfo
 	for key, info := range l.runners {
-		if key.backend == runner.backend.Name() && key.modelID == runner.model && key.mode == runner.mode {
-			slotInfo = info
+		if l.slots[info.slot] == runner {
+			slotKey, slotInfo = key, info
 			break
 		}
 	}
 
 	// Decrement the runner's reference count.
 	l.references[slotInfo.slot] -= 1
+	l.lastActive = time.Now()
 
 	// If the runner's reference count is now zero, then check if it is still
-	// active, and record now as its idle start time and signal the idle
-	// checker.
+	// active, and record now as its idle start time. Either way, signal the
+	// idle checker, which also schedules deep sleep.
 	if l.references[slotInfo.slot] == 0 {
 		select {
 		case <-runner.done:
-			l.evictRunner(runner.backend.Name(), runner.model, runner.mode)
+			l.evictReplica(slotKey, slotInfo)
 		default:
-			l.timestamps[slotInfo.slot] = time.Now()
-			select {
-			case l.idleCheck <- struct{}{}:
-			default:
+			if slotInfo.stale {
+				l.evictReplica(slotKey, slotInfo)
+			} else {
+				l.timestamps[slotInfo.slot] = time.Now()
 			}
 		}
+		select {
+		case l.idleCheck <- struct{}{}:
+		default:
+		}
 	}
 
 	// Signal waiters.
@@ -668,7 +1098,9 @@ func (l *loader) release(runner *runner) {
 }
 
 func (l *loader) setRunnerConfig(ctx context.Context, backendName, modelID string, mode inference.BackendMode, runnerConfig inference.BackendConfiguration) error {
-	l.lock(ctx)
+	if !l.lock(ctx) {
+		return context.Canceled
+	}
 	defer l.unlock()
 
 	// Configuration key should NOT include draftModelID since that's part of the config itself
@@ -687,15 +1119,36 @@ func (l *loader) setRunnerConfig(ctx context.Context, backendName, modelID strin
 	}
 	rKey := makeRunnerKey(backendName, modelID, draftModelID, mode)
 
-	// If there's an active runner whose configuration we want to override, then
-	// try evicting it (because it may not be in use).
-	if _, ok := l.runners[rKey]; ok {
+	// If only the scales of the LoRA adapters change, then apply them to the
+	// active replicas of the runner, if their backend supports it, rather
+	// than restarting them. The replicas are called without holding the lock,
+	// and the configuration is only committed if neither it nor the replicas
+	// changed in the meantime.
+	if existingConfig, ok := l.runnerConfigs[configKey]; ok && len(l.replicas(rKey)) > 0 && inference.OnlyLoRAScalesDiffer(existingConfig, runnerConfig) {
+		if scaler, ok := l.backends[backendName].(inference.LoRAScaler); ok {
+			replicas := l.replicaClients(rKey)
+			l.unlock()
+			err := scaleLoRAAdapters(ctx, scaler, replicas, runnerConfig)
+			l.lock(context.Background())
+			if err != nil {
+				l.log.Warnf("Failed to scale LoRA adapters of %s runner for %s: %v", backendName, modelID, err)
+			} else if reflect.DeepEqual(l.runnerConfigs[configKey], existingConfig) && maps.Equal(l.replicaClients(rKey), replicas) {
+				l.log.Infof("Scaled LoRA adapters of %s runner for %s", backendName, modelID)
+				l.runnerConfigs[configKey] = runnerConfig
+				return nil
+			}
+		}
+	}
+
+	// If there are active replicas of the runner whose configuration we want
+	// to override, then try evicting them (because they may not be in use).
+	if len(l.replicas(rKey)) > 0 {
 		l.evictRunner(backendName, modelID, mode)
 	}
 
-	// If there's still then active runner, then we can't (or at least
+	// If there are still active replicas, then we can't (or at least
 	// shouldn't) change the configuration.
-	if _, ok := l.runners[rKey]; ok {
+	if len(l.replicas(rKey)) > 0 {
 		return errRunnerAlreadyActive
 	}
 
@@ -703,3 +1156,27 @@ func (l *loader) setRunnerConfig(ctx context.Context, backendName, modelID strin
 	l.runnerConfigs[configKey] = runnerConfig
 	return nil
 }
+
+// replicaClients returns the clients of the active replicas of a runner, by
+// replica key. The caller must hold the loader lock.
+func (l *loader) replicaClients(key runnerKey) map[runnerKey]*http.Client {
+	clients := make(map[runnerKey]*http.Client)
+	for _, replica := range l.replicas(key) {
+		clients[replica] = l.slots[l.runners[replica].slot].client
+	}
+	return clients
+}
+
+// scaleLoRAAdapters applies the scales of the LoRA adapters of a configuration
+// to the replicas of a runner, giving up after loraScaleTimeout. It's called
+// without holding the loader lock, since it makes requests to the replicas.
+func scaleLoRAAdapters(ctx context.Context, scaler inference.LoRAScaler, replicas map[runnerKey]*http.Client, runnerConfig inference.BackendConfiguration) error {
+	ctx, cancel := context.WithTimeout(ctx, loraScaleTimeout)
+	defer cancel()
+	for _, client := range replicas {
+		if err := scaler.ScaleLoRAAdapters(ctx, client, &runnerConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,8 +3,14 @@ package scheduling
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -57,6 +63,8 @@ func (b *fastFailBackend) Run(ctx context.Context, socket, model string, modelRe
 // mockSystemMemoryInfo implements memory.SystemMemoryInfo for testing
 type mockSystemMemoryInfo struct {
 	totalMemory inference.RequiredMemory
+	gpuMemory   []uint64
+	freeMemory  []uint64
 }
 
 func (m *mockSystemMemoryInfo) HaveSufficientMemory(req inference.RequiredMemory) (bool, error) {
@@ -67,6 +75,14 @@ func (m *mockSystemMemoryInfo) GetTotalMemory() inference.RequiredMemory {
 	return m.totalMemory
 }
 
+func (m *mockSystemMemoryInfo) GetGPUMemory() []uint64 {
+	return m.gpuMemory
+}
+
+func (m *mockSystemMemoryInfo) GetFreeGPUMemory() []uint64 {
+	return m.freeMemory
+}
+
 // createTestLogger creates a logger for testing
 func createTestLogger() *logrus.Entry {
 	log := logrus.New()
@@ -413,7 +429,7 @@ func TestDefunctRunnerEvictionTriggersRetry(t *testing.T) {
 	loader.unlock()
 
 	// Attempt to load - with fastFail backend, this should return quickly after eviction+retry
-	_, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion)
+	_, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, "")
 
 	// We expect an error (backend fails fast), but not a timeout/hang
 	if errors.Is(err, context.DeadlineExceeded) {
@@ -478,7 +494,7 @@ func TestUnusedRunnerEvictionTriggersRetry(t *testing.T) {
 	loader.unlock()
 
 	// Attempt to load a different model; eviction should occur and loop should retry immediately
-	_, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion)
+	_, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, "")
 
 	if errors.Is(err, context.DeadlineExceeded) {
 		t.Error("load() timed out - eviction of unused runner did not trigger retry")
@@ -487,3 +503,480 @@ func TestUnusedRunnerEvictionTriggersRetry(t *testing.T) {
 		t.Error("Unexpected success; acceptable but unusual with fastFail backend")
 	}
 }
+
+// TestLoaderAccountsForUsedVRAM tests that VRAM already in use by other
+// processes, such as on Ascend NPUs, isn't available to runners.
+func TestLoaderAccountsForUsedVRAM(t *testing.T) {
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 128 * GB},
+		gpuMemory:   []uint64{64 * GB, 64 * GB},
+		freeMemory:  []uint64{60 * GB, 30 * GB},
+	}
+	loader := newLoader(createTestLogger(), nil, nil, nil, sysMemInfo)
+	if loader.freeVRAM() != 90*GB {
+		t.Errorf("Expected 90 GB of free VRAM, got %s", formatMemorySize(loader.freeVRAM()))
+	}
+	if free := loader.freeGPUMemory(); !slices.Equal(free, sysMemInfo.freeMemory) {
+		t.Errorf("Expected free GPU memory %v, got %v", sysMemInfo.freeMemory, free)
+	}
+	if loader.totalMemory.VRAM != 128*GB {
+		t.Errorf("Expected total VRAM to be unchanged, got %s", formatMemorySize(loader.totalMemory.VRAM))
+	}
+}
+
+// partialOffloadBackend is a backend that fits layers into VRAM at 1GB each
+// and fails fast on Run, reporting the configuration it was run with.
+type partialOffloadBackend struct {
+	mockBackend
+	configs chan *inference.BackendConfiguration
+}
+
+func (b *partialOffloadBackend) FitGPULayers(ctx context.Context, model string, config *inference.BackendConfiguration, vram uint64) (uint64, inference.RequiredMemory, error) {
+	layers := vram / GB
+	return layers, inference.RequiredMemory{RAM: b.requiredMemory.RAM + b.requiredMemory.VRAM - layers*GB, VRAM: layers * GB}, nil
+}
+
+func (b *partialOffloadBackend) Run(ctx context.Context, socket, model string, modelRef string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	b.configs <- config
+	return errors.New("boom")
+}
+
+func TestLoaderOffloadsLayersThatFitInVRAM(t *testing.T) {
+	backend := &partialOffloadBackend{
+		mockBackend: mockBackend{
+			name:           "test-backend",
+			requiredMemory: inference.RequiredMemory{RAM: 1 * GB, VRAM: 12 * GB},
+		},
+		configs: make(chan *inference.BackendConfiguration, 1),
+	}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 8 * GB},
+	}
+	loader := newLoader(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock to enable loads")
+	}
+	loader.loadsEnabled = true
+	loader.unlock()
+
+	// The model needs more VRAM than the system has, so only the layers that
+	// fit are offloaded instead of refusing to load it.
+	_, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, "")
+	if errors.Is(err, errModelTooBig) {
+		t.Fatalf("Expected model to be partially offloaded, got %v", err)
+	}
+	select {
+	case config := <-backend.configs:
+		if config == nil || config.GPULayers == nil || *config.GPULayers != 8 {
+			t.Errorf("Expected 8 layers to be offloaded, got config %+v", config)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the runner to be started")
+	}
+}
+
+func TestLoaderRecordsCrashReports(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 8 * GB, VRAM: 8 * GB},
+	}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock")
+	}
+	defer loader.unlock()
+
+	crashed := createDefunctMockRunner(log, backend)
+	crashed.err = errors.Join(errors.New("llama.cpp terminated unexpectedly"), &inference.ErrProcessExited{
+		Args:     []string{"llama-server", "--model", "/models/model1.gguf"},
+		ExitCode: 139,
+		Output:   "segmentation fault",
+		Err:      errors.New("signal: segmentation fault"),
+	})
+	loader.slots[0] = crashed
+	crashedKey := makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)
+	loader.runners[crashedKey] = runnerInfo{slot: 0, modelRef: "model1:latest"}
+	loader.evict(false)
+
+	// Evicting a runner that's still alive isn't a crash.
+	loader.slots[0] = createAliveTerminableMockRunner(log, backend)
+	aliveKey := makeRunnerKey("test-backend", "modelX", "", inference.BackendModeCompletion)
+	loader.runners[aliveKey] = runnerInfo{slot: 0, modelRef: "modelX:latest"}
+	loader.evict(false)
+
+	if len(loader.crashes) != 1 {
+		t.Fatalf("Expected 1 crash report, got %d", len(loader.crashes))
+	}
+	status := loader.crashes[0]
+	if status.ModelName != "model1:latest" || status.Crash == nil {
+		t.Fatalf("Expected crash report for model1:latest, got %+v", status)
+	}
+	if status.Crash.ExitCode == nil || *status.Crash.ExitCode != 139 {
+		t.Errorf("Expected exit code 139, got %v", status.Crash.ExitCode)
+	}
+	if !slices.Equal(status.Crash.Command, []string{"llama-server", "--model", "/models/model1.gguf"}) {
+		t.Errorf("Expected command line to be reported, got %v", status.Crash.Command)
+	}
+	if status.Crash.Output != "segmentation fault" {
+		t.Errorf("Expected output to be reported, got %q", status.Crash.Output)
+	}
+
+	// Only the most recent crash reports are kept.
+	for i := 0; i < maximumCrashReports; i++ {
+		loader.recordCrash(crashedKey, "model2:latest", nil)
+	}
+	if len(loader.crashes) != maximumCrashReports || loader.crashes[0].ModelName != "model2:latest" {
+		t.Errorf("Expected only the %d most recent crash reports, got %d starting with %s",
+			maximumCrashReports, len(loader.crashes), loader.crashes[0].ModelName)
+	}
+}
+
+func TestLoaderUnloadSelectsRunners(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 8 * GB, VRAM: 8 * GB},
+	}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	loader.slots = make([]*runner, 4)
+	loader.references = make([]uint, 4)
+	loader.allocations = make([]inference.RequiredMemory, 4)
+	loader.gpuAllocations = make([][]uint64, 4)
+	loader.timestamps = make([]time.Time, 4)
+
+	// A runner per slot, with the runner in slot 2 in use and the runner in
+	// slot 0 allocated on the second GPU.
+	keys := []runnerKey{
+		makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion),
+		makeRunnerKey("test-backend", "model1", "", inference.BackendModeEmbedding),
+		makeRunnerKey("test-backend", "model2", "", inference.BackendModeCompletion),
+		makeRunnerKey("test-backend", "model3", "", inference.BackendModeCompletion),
+	}
+	for slot, key := range keys {
+		loader.slots[slot] = createAliveTerminableMockRunner(log, backend)
+		loader.runners[key] = runnerInfo{slot: slot, modelRef: key.modelID + ":latest"}
+	}
+	loader.gpuMemory = []uint64{4 * GB, 4 * GB}
+	loader.availableGPUMemory = []uint64{4 * GB, 3 * GB}
+	loader.gpuAllocations[0] = []uint64{0, 1 * GB}
+	loader.references[2] = 1
+
+	gpu := 1
+	for _, test := range []struct {
+		name     string
+		request  UnloadRequest
+		unloaded []string
+		draining int
+	}{
+		{"mode", UnloadRequest{All: true, Mode: "embedding"}, []string{"model1:latest"}, 0},
+		{"GPU", UnloadRequest{All: true, GPU: &gpu}, []string{"model1:latest"}, 0},
+		{"idle only", UnloadRequest{All: true, IdleOnly: true}, []string{"model3:latest"}, 0},
+		{"in use", UnloadRequest{All: true}, nil, 1},
+	} {
+		unloaded, draining := loader.Unload(context.Background(), test.request)
+		var names []string
+		for _, status := range unloaded {
+			names = append(names, status.ModelName)
+		}
+		if !slices.Equal(names, test.unloaded) || draining != test.draining {
+			t.Errorf("%s: expected %v to be unloaded and %d draining, got %v and %d", test.name, test.unloaded, test.draining, names, draining)
+		}
+	}
+	if info, ok := loader.runners[keys[2]]; !ok || !info.stale {
+		t.Errorf("Expected the runner in use to be kept until its requests complete, got %+v", info)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for restart, want := range expected {
+		if got := restartBackoff(restart); got != want {
+			t.Errorf("restartBackoff(%d) = %s, want %s", restart, got, want)
+		}
+	}
+}
+
+// crashingBackend is a backend that serves every request with a 200 status,
+// and whose first run crashes once crash is closed.
+type crashingBackend struct {
+	mockBackend
+	crash chan struct{}
+	runs  atomic.Int32
+}
+
+func (b *crashingBackend) Run(ctx context.Context, socket, model string, modelRef string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go server.Serve(listener)
+	defer server.Close()
+	crash := b.crash
+	if b.runs.Add(1) > 1 {
+		crash = nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-crash:
+		return errors.New("crashed")
+	}
+}
+
+func TestRunnerRestartsCrashedBackend(t *testing.T) {
+	socketDir := t.TempDir()
+	defaultSocketPath := RunnerSocketPath
+	RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(socketDir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	defer func() { RunnerSocketPath = defaultSocketPath }()
+
+	backend := &crashingBackend{mockBackend: mockBackend{name: "test-backend"}, crash: make(chan struct{})}
+	var restartErr error
+	r, err := run(createTestLogger(), backend, "model1", "model1:latest", inference.BackendModeCompletion, 0, nil, nil,
+		1, func(_ context.Context, err error) bool {
+			restartErr = err
+			return true
+		})
+	if err != nil {
+		t.Fatalf("Failed to run runner: %v", err)
+	}
+	defer r.terminate()
+	if err := r.wait(context.Background()); err != nil {
+		t.Fatalf("Runner didn't become ready: %v", err)
+	}
+
+	// Requests made while the backend restarts are held until it's ready.
+	close(backend.crash)
+	deadline := time.Now().Add(5 * time.Second)
+	for backend.runs.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the backend to be restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/engines/v1/models", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected request to be served by the restarted backend, got status %d", w.Code)
+	}
+	select {
+	case <-r.done:
+		t.Error("Expected the restarted runner to be running")
+	default:
+	}
+	if restartErr == nil || restartErr.Error() != "crashed" {
+		t.Errorf("Expected the restart to be asked for with the crash error, got %v", restartErr)
+	}
+}
+
+// streamingBackend is a backend that streams responses until their request is
+// cancelled, which it reports on cancelled.
+type streamingBackend struct {
+	mockBackend
+	cancelled chan struct{}
+}
+
+func (b *streamingBackend) Run(ctx context.Context, socket, model string, modelRef string, mode inference.BackendMode, config *inference.BackendConfiguration) error {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {}\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(b.cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+	<-ctx.Done()
+	return nil
+}
+
+func TestRunnerCancelsAbandonedRequests(t *testing.T) {
+	socketDir := t.TempDir()
+	defaultSocketPath := RunnerSocketPath
+	RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(socketDir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	defer func() { RunnerSocketPath = defaultSocketPath }()
+
+	backend := &streamingBackend{mockBackend: mockBackend{name: "test-backend"}, cancelled: make(chan struct{})}
+	r, err := run(createTestLogger(), backend, "model1", "model1:latest", inference.BackendModeCompletion, 0, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to run runner: %v", err)
+	}
+	defer r.terminate()
+	if err := r.wait(context.Background()); err != nil {
+		t.Fatalf("Runner didn't become ready: %v", err)
+	}
+
+	// Disconnect the client once the stream has started.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/engines/v1/chat/completions", nil).WithContext(ctx)
+	w := &cancellingRecorder{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+	served := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(served)
+	}()
+	select {
+	case <-backend.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the upstream request to be cancelled")
+	}
+	<-served
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the stream's status to be preserved, got %d", w.Code)
+	}
+}
+
+// cancellingRecorder is a response recorder that cancels its request once the
+// response body is first written to, as if the client disconnected.
+type cancellingRecorder struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+}
+
+func (w *cancellingRecorder) Write(b []byte) (int, error) {
+	defer w.cancel()
+	return w.ResponseRecorder.Write(b)
+}
+
+// newReplicaTestLoader creates a loader with enough slots and memory for the
+// given number of replicas of model1, whose backend serves every request.
+func newReplicaTestLoader(t *testing.T, replicas int) *loader {
+	socketDir := t.TempDir()
+	defaultSocketPath := RunnerSocketPath
+	RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(socketDir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	t.Cleanup(func() { RunnerSocketPath = defaultSocketPath })
+
+	backend := &crashingBackend{mockBackend: mockBackend{
+		name:           "test-backend",
+		requiredMemory: inference.RequiredMemory{RAM: 1 * GB, VRAM: 1 * GB},
+	}}
+	sysMemInfo := &mockSystemMemoryInfo{totalMemory: inference.RequiredMemory{RAM: 4 * GB, VRAM: 4 * GB}}
+	loader := newLoader(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	loader.lock(context.Background())
+	loader.loadsEnabled = true
+	// Provide enough slots for the replicas, regardless of the CPU count.
+	loader.slots = make([]*runner, replicas+1)
+	loader.references = make([]uint, replicas+1)
+	loader.allocations = make([]inference.RequiredMemory, replicas+1)
+	loader.gpuAllocations = make([][]uint64, replicas+1)
+	loader.timestamps = make([]time.Time, replicas+1)
+	loader.runnerConfigs[makeConfigKey("test-backend", "model1", inference.BackendModeCompletion)] = inference.BackendConfiguration{Replicas: replicas}
+	loader.unlock()
+	t.Cleanup(func() { loader.Unload(context.Background(), UnloadRequest{All: true}) })
+	return loader
+}
+
+func TestLoaderBalancesRequestsAcrossReplicas(t *testing.T) {
+	loader := newReplicaTestLoader(t, 2)
+
+	load := func() *runner {
+		r, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, "")
+		if err != nil {
+			t.Fatalf("Failed to load runner: %v", err)
+		}
+		return r
+	}
+
+	// A busy replica causes another one to be loaded, up to the configured
+	// number of replicas, after which requests share the least used one.
+	first := load()
+	second := load()
+	if second == first {
+		t.Fatal("Expected a second replica to be loaded while the first is busy")
+	}
+	third := load()
+	if third != first && third != second {
+		t.Fatal("Expected no more than 2 replicas to be loaded")
+	}
+	loader.release(third)
+	loader.release(first)
+	if r := load(); r != first {
+		t.Error("Expected the unused replica to be chosen")
+	}
+
+	loader.lock(context.Background())
+	var replicas []int
+	for _, key := range loader.replicas(makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)) {
+		replicas = append(replicas, key.replica)
+	}
+	loader.unlock()
+	slices.Sort(replicas)
+	if !slices.Equal(replicas, []int{0, 1}) {
+		t.Errorf("Expected replicas 0 and 1, got %v", replicas)
+	}
+	loader.release(first)
+	loader.release(second)
+}
+
+// scalingBackend is a backend that can scale the LoRA adapters of its runners.
+type scalingBackend struct {
+	mockBackend
+	// loader is the loader whose lock must not be held while scaling.
+	loader *loader
+	scaled []inference.BackendConfiguration
+}
+
+func (b *scalingBackend) ScaleLoRAAdapters(ctx context.Context, _ *http.Client, config *inference.BackendConfiguration) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("scaling without a deadline")
+	}
+	select {
+	case <-b.loader.guard:
+		b.loader.unlock()
+	default:
+		return errors.New("scaling while holding the loader lock")
+	}
+	b.scaled = append(b.scaled, *config)
+	return nil
+}
+
+func TestSetRunnerConfigScalesLoRAAdapters(t *testing.T) {
+	log := createTestLogger()
+	backend := &scalingBackend{mockBackend: mockBackend{name: "test-backend"}}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, &mockSystemMemoryInfo{})
+	backend.loader = loader
+	configured := inference.BackendConfiguration{LoRAAdapters: []inference.LoRAAdapter{{Model: "ai/adapter:latest"}}}
+	if err := loader.setRunnerConfig(context.Background(), "test-backend", "model1", inference.BackendModeCompletion, configured); err != nil {
+		t.Fatalf("setRunnerConfig failed: %v", err)
+	}
+
+	// Install a runner in use, which can't be evicted.
+	loader.slots[0] = createAliveTerminableMockRunner(log, backend)
+	defer loader.slots[0].terminate()
+	loader.runners[makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)] = runnerInfo{slot: 0, modelRef: "model1:latest"}
+	loader.references[0] = 1
+
+	// Changing the scale of its adapter applies to it in place.
+	half := 0.5
+	scaled := inference.BackendConfiguration{LoRAAdapters: []inference.LoRAAdapter{{Model: "ai/adapter:latest", Scale: &half}}}
+	if err := loader.setRunnerConfig(context.Background(), "test-backend", "model1", inference.BackendModeCompletion, scaled); err != nil {
+		t.Fatalf("setRunnerConfig failed: %v", err)
+	}
+	if len(backend.scaled) != 1 || backend.scaled[0].LoRAAdapters[0].AdapterScale() != 0.5 {
+		t.Errorf("Expected the adapter to be scaled to 0.5, got %+v", backend.scaled)
+	}
+
+	// Adding an adapter requires restarting it.
+	added := inference.BackendConfiguration{LoRAAdapters: append(scaled.LoRAAdapters, inference.LoRAAdapter{Model: "ai/other:latest"})}
+	if err := loader.setRunnerConfig(context.Background(), "test-backend", "model1", inference.BackendModeCompletion, added); !errors.Is(err, errRunnerAlreadyActive) {
+		t.Errorf("Expected errRunnerAlreadyActive, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package scheduling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maximumRunnerOutputLines is the number of lines of output kept for
+	// each runner.
+	maximumRunnerOutputLines = 1000
+	// maximumRunnerOutputLineSize is the size beyond which an incomplete line
+	// of output is kept as a line of its own.
+	maximumRunnerOutputLineSize = 64 * 1024
+	// runnerOutputFollowerBuffer is the number of lines buffered for each
+	// follower of a runner's output. Lines are dropped for followers that
+	// fall further behind, so that they never hold up the backend.
+	runnerOutputFollowerBuffer = 256
+)
+
+// runnerOutput keeps the most recent lines of output of a runner's backend in
+// a ring buffer, and passes new lines on to its followers. It is written to by
+// the backend through its configuration's Output.
+type runnerOutput struct {
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// lines are the most recent lines of output, starting at first once the
+	// buffer is full.
+	lines []string
+	// first is the index of the oldest line in lines.
+	first int
+	// partial is the incomplete last line of output.
+	partial []byte
+	// followers are the channels to which new lines are passed.
+	followers map[chan string]struct{}
+	// closed indicates that the runner terminated, so no more output follows.
+	closed bool
+}
+
+// newRunnerOutput creates a new runner output buffer.
+func newRunnerOutput() *runnerOutput {
+	return &runnerOutput{followers: make(map[chan string]struct{})}
+}
+
+// Write implements io.Writer.Write.
+func (o *runnerOutput) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return len(p), nil
+	}
+	data := append(o.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		o.appendLocked(string(data[:i]))
+		data = data[i+1:]
+	}
+	if len(data) > maximumRunnerOutputLineSize {
+		o.appendLocked(string(data))
+		data = nil
+	}
+	o.partial = bytes.Clone(data)
+	return len(p), nil
+}
+
+// appendLocked adds a line of output. The caller must hold the lock.
+func (o *runnerOutput) appendLocked(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	if len(o.lines) < maximumRunnerOutputLines {
+		o.lines = append(o.lines, line)
+	} else {
+		o.lines[o.first] = line
+		o.first = (o.first + 1) % maximumRunnerOutputLines
+	}
+	for follower := range o.followers {
+		select {
+		case follower <- line:
+		default:
+		}
+	}
+}
+
+// follow returns the lines of output kept so far. If follow is set, it also
+// returns a channel receiving the subsequent lines, which is closed once the
+// runner terminates or stop is called. The returned stop function must be
+// called once the caller is done.
+func (o *runnerOutput) follow(follow bool) (lines []string, updates <-chan string, stop func()) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	lines = append(slices.Clone(o.lines[o.first:]), o.lines[:o.first]...)
+	if !follow {
+		return lines, nil, func() {}
+	}
+	follower := make(chan string, runnerOutputFollowerBuffer)
+	if o.closed {
+		close(follower)
+		return lines, follower, func() {}
+	}
+	o.followers[follower] = struct{}{}
+	return lines, follower, func() {
+		o.lock.Lock()
+		defer o.lock.Unlock()
+		if _, ok := o.followers[follower]; ok {
+			delete(o.followers, follower)
+			close(follower)
+		}
+	}
+}
+
+// close marks the runner as terminated, keeping its incomplete last line of
+// output and ending the updates of its followers. It is safe to call on a nil
+// runnerOutput.
+func (o *runnerOutput) close() {
+	if o == nil {
+		return
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return
+	}
+	if len(o.partial) > 0 {
+		o.appendLocked(string(o.partial))
+		o.partial = nil
+	}
+	for follower := range o.followers {
+		close(follower)
+	}
+	clear(o.followers)
+	o.closed = true
+}
+
+// labeledOutput is the output of a runner, labeled with the backend, mode and
+// replica of the runner.
+type labeledOutput struct {
+	label  string
+	output *runnerOutput
+}
+
+// runnerOutputs returns the outputs of the runners loaded for a model, sorted
+// by label.
+func (s *Scheduler) runnerOutputs(ctx context.Context, model string) []labeledOutput {
+	modelID := model
+	if s.modelManager != nil {
+		modelID = s.modelManager.ResolveModelID(model)
+	}
+	if !s.loader.lock(ctx) {
+		return nil
+	}
+	defer s.loader.unlock()
+
+	var outputs []labeledOutput
+	for key, info := range s.loader.runners {
+		runner := s.loader.slots[info.slot]
+		if runner == nil || runner.output == nil || (key.modelID != modelID && info.modelRef != model) {
+			continue
+		}
+		label := key.backend + " " + key.mode.String()
+		if key.replica > 0 {
+			label += " replica " + strconv.Itoa(key.replica)
+		}
+		outputs = append(outputs, labeledOutput{label: label, output: runner.output})
+	}
+	slices.SortFunc(outputs, func(a, b labeledOutput) int {
+		return strings.Compare(a.label, b.label)
+	})
+	return outputs
+}
+
+// GetRunnerLogs writes the recent output of the backends of the runners loaded
+// for a model, and keeps streaming their output until they're unloaded if the
+// follow query parameter is true. Lines are prefixed with the backend, mode
+// and replica of their runner if several runners are loaded for the model:
+// - GET <inference-prefix>/{model}/logs
+func (s *Scheduler) GetRunnerLogs(w http.ResponseWriter, r *http.Request) {
+	model, ok := strings.CutSuffix(r.PathValue("nameAndAction"), "/logs")
+	if !ok || model == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	follow := false
+	if r.URL.Query().Has("follow") {
+		val, err := strconv.ParseBool(r.URL.Query().Get("follow"))
+		if err != nil {
+			http.Error(w, "invalid follow parameter", http.StatusBadRequest)
+			return
+		}
+		follow = val
+	}
+
+	outputs := s.runnerOutputs(r.Context(), model)
+	if len(outputs) == 0 {
+		http.Error(w, fmt.Sprintf("no runner is loaded for model %s", model), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	prefix := func(label string) string {
+		if len(outputs) == 1 {
+			return ""
+		}
+		return label + " | "
+	}
+
+	// Write the lines kept so far, and merge the updates of all runners.
+	merged := make(chan string)
+	var wg sync.WaitGroup
+	for _, o := range outputs {
+		lines, updates, stop := o.output.follow(follow)
+		defer stop()
+		for _, line := range lines {
+			fmt.Fprintln(w, prefix(o.label)+line)
+		}
+		if updates == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(label string) {
+			defer wg.Done()
+			for line := range updates {
+				select {
+				case merged <- prefix(label) + line:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}(o.label)
+	}
+	if !follow {
+		return
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case line, ok := <-merged:
+			if !ok {
+				return
+			}
+			fmt.Fprintln(w, line)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
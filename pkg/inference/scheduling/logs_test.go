@@ -0,0 +1,106 @@
+package scheduling
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestRunnerOutput(t *testing.T) {
+	o := newRunnerOutput()
+
+	// Lines are split across writes, and only the most recent ones are kept.
+	for i := range maximumRunnerOutputLines + 1 {
+		fmt.Fprintf(o, "line %d\r\n", i)
+	}
+	o.Write([]byte("partial "))
+	lines, updates, stop := o.follow(true)
+	defer stop()
+	if len(lines) != maximumRunnerOutputLines || lines[0] != "line 1" || lines[len(lines)-1] != fmt.Sprintf("line %d", maximumRunnerOutputLines) {
+		t.Fatalf("Unexpected lines kept: %d lines from %q to %q", len(lines), lines[0], lines[len(lines)-1])
+	}
+
+	// Followers receive new lines, including the incomplete last line once
+	// the runner terminates, after which their updates end.
+	o.Write([]byte("line\n"))
+	o.Write([]byte("last"))
+	o.close()
+	var followed []string
+	for line := range updates {
+		followed = append(followed, line)
+	}
+	if strings.Join(followed, ",") != "partial line,last" {
+		t.Errorf("Unexpected followed lines %q", followed)
+	}
+
+	// Following a terminated runner returns its last lines only.
+	lines, updates, stop = o.follow(true)
+	defer stop()
+	if _, ok := <-updates; ok || lines[len(lines)-1] != "last" {
+		t.Errorf("Expected no updates after termination, got last line %q", lines[len(lines)-1])
+	}
+}
+
+func TestGetRunnerLogs(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(log, map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	r := createAliveTerminableMockRunner(log, backend)
+	r.output = newRunnerOutput()
+	fmt.Fprintln(r.output, "server listening")
+	s.loader.slots[0] = r
+	s.loader.runners[makeRunnerKey("test-backend", r.model, "", r.mode)] = runnerInfo{slot: 0, modelRef: "ai/modelX"}
+
+	getLogs := func(ctx context.Context, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := getLogs(context.Background(), "/engines/ai/modelX/logs"); w.Code != http.StatusOK || w.Body.String() != "server listening\n" {
+		t.Errorf("Expected the runner's output with status 200, got %d: %q", w.Code, w.Body.String())
+	}
+	if w := getLogs(context.Background(), "/engines/ai/other/logs"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a model without runners, got %d", w.Code)
+	}
+	if w := getLogs(context.Background(), "/engines/ai/modelX/logs?follow=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid follow parameter, got %d", w.Code)
+	}
+
+	// Following streams new output until the runner is unloaded.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- getLogs(context.Background(), "/engines/ai/modelX/logs?follow=true")
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.output.lock.Lock()
+		followers := len(r.output.followers)
+		r.output.lock.Unlock()
+		if followers > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the follower")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Fprintln(r.output, "request served")
+	r.output.close()
+	select {
+	case w := <-done:
+		if w.Body.String() != "server listening\nrequest served\n" {
+			t.Errorf("Unexpected followed output %q", w.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Following didn't end once the runner terminated")
+	}
+}
@@ -0,0 +1,162 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/onnx"
+	"github.com/docker/model-runner/pkg/inference/backends/piper"
+	"github.com/docker/model-runner/pkg/inference/backends/sdcpp"
+	"github.com/docker/model-runner/pkg/inference/backends/vllm"
+	"github.com/docker/model-runner/pkg/inference/backends/whispercpp"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// SetPreloadModels sets the models to pull, if necessary, and load with the
+// default backend on startup, so that their first requests don't wait for
+// them to load. It must be called before Run.
+func (s *Scheduler) SetPreloadModels(models []string) {
+	s.preloadModels = models
+}
+
+// preload pulls and loads the preload models in turn, once loads are enabled.
+// Failures are logged rather than fatal, as the models can still be loaded on
+// demand.
+func (s *Scheduler) preload(ctx context.Context) {
+	s.preloadList(ctx, s.preloadModels)
+}
+
+// preloadList pulls and loads models in turn, once loads are enabled.
+func (s *Scheduler) preloadList(ctx context.Context, preloadModels []string) {
+	select {
+	case <-s.loader.ready:
+	case <-ctx.Done():
+		return
+	}
+	for _, model := range preloadModels {
+		model = models.NormalizeModelName(model)
+		s.log.Infof("Preloading %s", utils.SanitizeForLog(model))
+		backend, mode, err := s.warmLoad(ctx, s.defaultBackend, model, inference.BackendModeCompletion)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.log.Warnf("Failed to preload %s: %v", utils.SanitizeForLog(model), err)
+			continue
+		}
+		s.log.Infof("Preloaded %s with %s in %s mode", utils.SanitizeForLog(model), backend.Name(), mode)
+	}
+}
+
+// warmLoad pulls a model if necessary and loads a runner for it, which is
+// released straight away so that it stays loaded until it idles out. Like
+// inference requests, safetensors models, whisper models, piper voices,
+// diffusion models and ONNX models are loaded with vLLM, whisper.cpp, piper,
+// stable-diffusion.cpp and ONNX Runtime, the latter four in transcription,
+// speech, image generation and embedding mode, rerankers in reranking mode and
+// classifiers in classification mode. It returns the backend and mode the model was loaded with.
+func (s *Scheduler) warmLoad(ctx context.Context, backend inference.Backend, model string, mode inference.BackendMode) (inference.Backend, inference.BackendMode, error) {
+	if backend == nil {
+		return nil, mode, ErrBackendNotFound
+	}
+	if !backend.UsesExternalModelManagement() {
+		if err := s.modelManager.EnsureModel(ctx, model); err != nil {
+			return backend, mode, err
+		}
+		mdl, err := s.modelManager.GetModel(model)
+		if err != nil {
+			return backend, mode, err
+		}
+		backend = s.selectBackendForModel(mdl, backend, model)
+		mode = defaultMode(backend.Name(), mode)
+		if isReranker(mdl) {
+			mode = inference.BackendModeReranking
+		} else if isClassifier(mdl) {
+			mode = inference.BackendModeClassification
+		} else if !supportsMode(backend.Name(), mode) {
+			return backend, mode, fmt.Errorf("model %s does not support %s requests", model, mode)
+		}
+	}
+	if err := s.installer.wait(ctx, backend.Name()); err != nil {
+		return backend, mode, fmt.Errorf("backend installation failed: %w", err)
+	}
+
+	runner, err := s.loader.load(ctx, backend.Name(), s.modelManager.ResolveModelID(model), model, mode, "")
+	if err != nil {
+		return backend, mode, fmt.Errorf("unable to load runner: %w", err)
+	}
+	s.loader.release(runner)
+	return backend, mode, nil
+}
+
+// Load handles POST <inference-prefix>/{backend}/{model}/load requests, which
+// pull the model if necessary and load a runner for it ahead of its first
+// request. The mode query parameter selects the mode to load the model in,
+// and defaults to completion.
+func (s *Scheduler) Load(w http.ResponseWriter, r *http.Request) {
+	model, ok := strings.CutSuffix(r.PathValue("nameAndAction"), "/load")
+	if !ok || model == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	model = models.NormalizeModelName(model)
+
+	backend, ok := s.backends[r.PathValue("backend")]
+	if !ok {
+		http.Error(w, ErrBackendNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	mode := inference.BackendModeCompletion
+	if name := r.URL.Query().Get("mode"); name != "" {
+		// parseBackendMode falls back to completion for unknown modes.
+		if mode = parseBackendMode(name); mode.String() != name {
+			http.Error(w, fmt.Sprintf("invalid mode %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	backend, mode, err := s.warmLoad(r.Context(), backend, model, mode)
+	if err != nil {
+		s.log.Warnf("Failed to load %s: %v", utils.SanitizeForLog(model), err)
+		http.Error(w, err.Error(), loadErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(BackendStatus{
+		BackendName: backend.Name(),
+		ModelName:   model,
+		Mode:        mode.String(),
+	}); err != nil {
+		s.log.Warnln("Error while encoding load response:", err)
+	}
+}
+
+// loadErrorStatus returns the HTTP status code for an error returned by
+// warmLoad.
+func loadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, distribution.ErrModelNotFound), errors.Is(err, registry.ErrModelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBackendNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, vllm.StatusNotFound), errors.Is(err, whispercpp.StatusNotFound),
+		errors.Is(err, piper.StatusNotFound), errors.Is(err, sdcpp.StatusNotFound),
+		errors.Is(err, onnx.StatusNotFound):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errInstallerNotStarted), errors.Is(err, errInsufficientVRAM),
+		errors.Is(err, errDraining), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
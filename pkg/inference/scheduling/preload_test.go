@@ -0,0 +1,36 @@
+package scheduling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestLoad(t *testing.T) {
+	backend := &mockBackend{name: "test-backend", usesExternalModelMgmt: true}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "not a load", path: "/engines/test-backend/ai/model/unload", expectedStatus: http.StatusNotFound},
+		{name: "unknown backend", path: "/engines/missing/ai/model/load", expectedStatus: http.StatusNotFound},
+		{name: "invalid mode", path: "/engines/test-backend/ai/model/load?mode=bogus", expectedStatus: http.StatusBadRequest},
+		{name: "installer not started", path: "/engines/test-backend/ai/model/load?mode=embedding", expectedStatus: http.StatusServiceUnavailable},
+		{name: "reranking mode", path: "/engines/test-backend/ai/model/load?mode=reranking", expectedStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
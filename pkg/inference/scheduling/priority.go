@@ -0,0 +1,165 @@
+package scheduling
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PriorityHeader is the header with which clients set the priority of an
+// inference request.
+const PriorityHeader = "X-DMR-Priority"
+
+// priority is the priority of an inference request. Requests waiting for a
+// runner are admitted in order of priority, and in arrival order within a
+// priority.
+type priority int
+
+const (
+	// priorityLow is for batch jobs, such as bulk embeddings.
+	priorityLow priority = iota
+	// priorityNormal is the priority of requests that don't set one.
+	priorityNormal
+	// priorityHigh is for interactive requests, such as chats.
+	priorityHigh
+	// priorityLevels is the number of priorities.
+	priorityLevels
+)
+
+// String implements Stringer.String for priority.
+func (p priority) String() string {
+	switch p {
+	case priorityLow:
+		return "low"
+	case priorityNormal:
+		return "normal"
+	case priorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// parsePriority parses the value of the priority header. An empty value is
+// the normal priority.
+func parsePriority(value string) (priority, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "low":
+		return priorityLow, nil
+	case "", "normal":
+		return priorityNormal, nil
+	case "high":
+		return priorityHigh, nil
+	default:
+		return priorityNormal, fmt.Errorf("invalid %s %q: must be one of high, normal, low", PriorityHeader, value)
+	}
+}
+
+// requestQueue limits the number of requests a runner serves concurrently.
+// Requests beyond the limit wait, and are admitted by priority as requests
+// complete. A zero limit admits every request immediately.
+type requestQueue struct {
+	// limit is the maximum number of concurrent requests.
+	limit int
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// active is the number of requests being served.
+	active int
+	// waiting holds, for each priority, the admission channels of waiting
+	// requests in arrival order.
+	waiting [priorityLevels][]chan struct{}
+}
+
+// newRequestQueue creates a request queue admitting up to limit concurrent
+// requests.
+func newRequestQueue(limit int) *requestQueue {
+	return &requestQueue{limit: limit}
+}
+
+// acquire waits until a request with the given priority is admitted. Admitted
+// requests must be released once served.
+func (q *requestQueue) acquire(ctx context.Context, p priority) error {
+	if q == nil || q.limit <= 0 {
+		return nil
+	}
+
+	q.lock.Lock()
+	if q.active < q.limit {
+		q.active++
+		q.lock.Unlock()
+		return nil
+	}
+	admitted := make(chan struct{})
+	q.waiting[p] = append(q.waiting[p], admitted)
+	q.lock.Unlock()
+
+	select {
+	case <-admitted:
+		return nil
+	case <-ctx.Done():
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		select {
+		case <-admitted:
+			// The request was admitted while being cancelled, so hand its
+			// place to the next request.
+			q.admitNextLocked()
+		default:
+			for i, waiter := range q.waiting[p] {
+				if waiter == admitted {
+					q.waiting[p] = append(q.waiting[p][:i], q.waiting[p][i+1:]...)
+					break
+				}
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// depth returns the number of waiting requests.
+func (q *requestQueue) depth() int {
+	if q == nil {
+		return 0
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var waiting int
+	for _, requests := range q.waiting {
+		waiting += len(requests)
+	}
+	return waiting
+}
+
+// release releases an admitted request, admitting the next waiting request of
+// the highest priority.
+func (q *requestQueue) release() {
+	if q == nil || q.limit <= 0 {
+		return
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.admitNextLocked()
+}
+
+// admitNextLocked passes the place of a completed request to the next waiting
+// request of the highest priority, if any. Callers must hold the lock.
+func (q *requestQueue) admitNextLocked() {
+	for p := priorityLevels - 1; p >= priorityLow; p-- {
+		if len(q.waiting[p]) > 0 {
+			close(q.waiting[p][0])
+			q.waiting[p] = q.waiting[p][1:]
+			return
+		}
+	}
+	q.active--
+}
+
+// SetMaxConcurrentRequests sets the maximum number of requests each runner
+// serves concurrently. Further requests wait and are admitted by the priority
+// set with PriorityHeader, so that interactive requests can overtake batch
+// jobs. Zero, the default, leaves concurrency to the backends, in which case
+// priorities have no effect. It must be called before Run.
+func (s *Scheduler) SetMaxConcurrentRequests(limit int) {
+	s.loader.maxConcurrentRequests = limit
+}
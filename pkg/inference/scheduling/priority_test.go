@@ -0,0 +1,125 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected priority
+		wantErr  bool
+	}{
+		{value: "", expected: priorityNormal},
+		{value: "high", expected: priorityHigh},
+		{value: "Normal", expected: priorityNormal},
+		{value: " low ", expected: priorityLow},
+		{value: "urgent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			p, err := parsePriority(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error for %q", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePriority failed: %v", err)
+			}
+			if p != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, p)
+			}
+		})
+	}
+}
+
+func TestRequestQueueAdmitsByPriority(t *testing.T) {
+	q := newRequestQueue(1)
+	if err := q.acquire(context.Background(), priorityNormal); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	// Queue a low, a normal and a high priority request while the runner is
+	// busy, waiting for each to be queued so that arrival order is fixed.
+	admitted := make(chan priority, 3)
+	for _, p := range []priority{priorityLow, priorityNormal, priorityHigh} {
+		go func() {
+			if err := q.acquire(context.Background(), p); err != nil {
+				t.Errorf("acquire failed: %v", err)
+				return
+			}
+			admitted <- p
+			q.release()
+		}()
+		waitForQueued(t, q, p)
+	}
+
+	q.release()
+	for _, expected := range []priority{priorityHigh, priorityNormal, priorityLow} {
+		select {
+		case p := <-admitted:
+			if p != expected {
+				t.Errorf("Expected %s priority request to be admitted, got %s", expected, p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for admission")
+		}
+	}
+}
+
+func TestRequestQueueCancellation(t *testing.T) {
+	q := newRequestQueue(1)
+	if err := q.acquire(context.Background(), priorityNormal); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- q.acquire(ctx, priorityHigh)
+	}()
+	waitForQueued(t, q, priorityHigh)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+
+	// The cancelled request no longer holds a place in the queue.
+	q.release()
+	if err := q.acquire(context.Background(), priorityLow); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+}
+
+func TestRequestQueueUnlimited(t *testing.T) {
+	q := newRequestQueue(0)
+	for range 10 {
+		if err := q.acquire(context.Background(), priorityLow); err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	}
+}
+
+// waitForQueued waits until a request with the given priority is queued.
+func waitForQueued(t *testing.T, q *requestQueue, p priority) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.lock.Lock()
+		queued := len(q.waiting[p]) > 0
+		q.lock.Unlock()
+		if queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for a %s priority request to be queued", p)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
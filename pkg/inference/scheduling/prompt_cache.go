@@ -0,0 +1,373 @@
+package scheduling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+var (
+	// ErrPromptCacheUnsupported indicates that a backend can't save and
+	// restore prompt caches.
+	ErrPromptCacheUnsupported = errors.New("backend doesn't support prompt caches")
+	// errPromptCacheNotFound indicates that a prompt cache wasn't saved for
+	// the loaded runner of a model.
+	errPromptCacheNotFound = errors.New("prompt cache not found")
+	// errInsufficientPromptCacheMemory indicates that a saved prompt cache
+	// doesn't fit in the memory left by loaded runners.
+	errInsufficientPromptCacheMemory = errors.New("insufficient memory for prompt cache")
+	// promptCacheNamePattern matches valid prompt cache names, which are used
+	// as file names.
+	promptCacheNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+)
+
+// PromptCacheRequest is used to save the prompt cache of a slot of a model's
+// runner, or restore a saved one into a slot.
+type PromptCacheRequest struct {
+	// Action is either "save" or "restore".
+	Action string `json:"action"`
+	// Backend is the backend to load the model with, defaulting to the
+	// default backend.
+	Backend string `json:"backend,omitempty"`
+	// Name is the name of the prompt cache.
+	Name string `json:"name"`
+	// Slot is the slot of the backend, in which it processes one request at
+	// a time, whose prompt cache is saved or restored.
+	Slot int `json:"slot"`
+}
+
+// PromptCache describes a saved prompt cache.
+type PromptCache struct {
+	// BackendName is the name of the backend of the runner.
+	BackendName string `json:"backend_name"`
+	// ModelName is the name of the model of the runner.
+	ModelName string `json:"model_name"`
+	// Name is the name of the prompt cache.
+	Name string `json:"name"`
+	// Size is the size of the prompt cache, in bytes.
+	Size int64 `json:"size"`
+	// LastUsed is when the prompt cache was last saved or restored.
+	LastUsed time.Time `json:"last_used"`
+}
+
+// promptCacheKey identifies a prompt cache saved by the runner in a slot.
+type promptCacheKey struct {
+	// slot is the runner's slot.
+	slot int
+	// name is the name of the prompt cache.
+	name string
+}
+
+// promptCacheEntry is a prompt cache tracked by the loader.
+type promptCacheEntry struct {
+	// size is the size of the prompt cache file.
+	size int64
+	// lastUsed is when the prompt cache was last saved or restored.
+	lastUsed time.Time
+}
+
+// promptCacheDir returns the directory in which the runner in a slot saves
+// its prompt caches, alongside the runner's socket.
+func promptCacheDir(slot int) (string, error) {
+	socket, err := RunnerSocketPath(slot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSuffix(socket, filepath.Ext(socket)) + "-prompt-cache")
+}
+
+// preparePromptCacheDir creates an empty prompt cache directory for the
+// runner in a slot.
+func preparePromptCacheDir(slot int) (string, error) {
+	dir, err := promptCacheDir(slot)
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// promptCacheMemory returns the RAM charged for a prompt cache of a given
+// size. Like models, prompt caches are assumed to fit if the RAM size is
+// unknown.
+func (l *loader) promptCacheMemory(size int64) uint64 {
+	if l.totalMemory.RAM == 1 {
+		return 0
+	}
+	return uint64(size)
+}
+
+// deletePromptCache deletes a prompt cache and reclaims its memory. The
+// caller must hold the loader lock.
+func (l *loader) deletePromptCache(key promptCacheKey) {
+	entry := l.promptCaches[key]
+	if dir, err := promptCacheDir(key.slot); err == nil {
+		os.Remove(filepath.Join(dir, key.name))
+	}
+	l.availableMemory.RAM += l.promptCacheMemory(entry.size)
+	l.promptCacheSize -= entry.size
+	delete(l.promptCaches, key)
+}
+
+// evictPromptCaches deletes the least recently used prompt caches of any
+// runner until the given amount of RAM is available, and reports whether it
+// is. Nothing is deleted if deleting every prompt cache wouldn't suffice. The
+// caller must hold the loader lock.
+func (l *loader) evictPromptCaches(ram uint64) bool {
+	if ram > l.availableMemory.RAM+l.promptCacheMemory(l.promptCacheSize) {
+		return false
+	}
+	for ram > l.availableMemory.RAM {
+		var oldest promptCacheKey
+		found := false
+		for k, e := range l.promptCaches {
+			if !found || e.lastUsed.Before(l.promptCaches[oldest].lastUsed) {
+				oldest, found = k, true
+			}
+		}
+		if !found {
+			return false
+		}
+		l.log.Infof("Deleting prompt cache %s of slot %d to free %s RAM", oldest.name, oldest.slot,
+			formatMemorySize(l.promptCacheMemory(l.promptCaches[oldest].size)))
+		l.deletePromptCache(oldest)
+	}
+	return true
+}
+
+// removePromptCaches deletes the prompt caches of the runner in a slot and
+// reclaims their memory. The caller must hold the loader lock.
+func (l *loader) removePromptCaches(slot int) {
+	for key, entry := range l.promptCaches {
+		if key.slot == slot {
+			l.availableMemory.RAM += l.promptCacheMemory(entry.size)
+			l.promptCacheSize -= entry.size
+			delete(l.promptCaches, key)
+		}
+	}
+	if dir, err := promptCacheDir(slot); err == nil {
+		if err := os.RemoveAll(dir); err != nil {
+			l.log.Warnf("Failed to remove prompt caches in %s: %v", dir, err)
+		}
+	}
+}
+
+// recordPromptCache records a prompt cache saved by the runner in a slot and
+// charges its size to the loader's available RAM, deleting the least recently
+// used prompt caches of any runner if it doesn't fit. The prompt cache is
+// deleted if it doesn't fit even then. The caller must hold the loader lock.
+func (l *loader) recordPromptCache(slot int, name string) (promptCacheEntry, error) {
+	dir, err := promptCacheDir(slot)
+	if err != nil {
+		return promptCacheEntry{}, err
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return promptCacheEntry{}, fmt.Errorf("unable to find saved prompt cache: %w", err)
+	}
+
+	// A prompt cache saved again under the same name replaces the previous
+	// one's file, so only its memory is reclaimed.
+	key := promptCacheKey{slot: slot, name: name}
+	if previous, ok := l.promptCaches[key]; ok {
+		l.availableMemory.RAM += l.promptCacheMemory(previous.size)
+		l.promptCacheSize -= previous.size
+		delete(l.promptCaches, key)
+	}
+
+	entry := promptCacheEntry{size: info.Size(), lastUsed: time.Now()}
+	memory := l.promptCacheMemory(entry.size)
+	if !l.evictPromptCaches(memory) {
+		os.Remove(path)
+		return promptCacheEntry{}, fmt.Errorf("%w: need %s RAM, have %s RAM available", errInsufficientPromptCacheMemory,
+			formatMemorySize(memory), formatMemorySize(l.availableMemory.RAM))
+	}
+	l.availableMemory.RAM -= memory
+	l.promptCaches[key] = entry
+	l.promptCacheSize += entry.size
+	return entry, nil
+}
+
+// promptCacheStatus returns the prompt caches saved by loaded runners. The
+// caller must hold the loader lock.
+func (l *loader) promptCacheStatus() []PromptCache {
+	result := make([]PromptCache, 0, len(l.promptCaches))
+	for key, entry := range l.promptCaches {
+		for runnerKey, info := range l.runners {
+			if info.slot == key.slot {
+				result = append(result, PromptCache{
+					BackendName: runnerKey.backend,
+					ModelName:   info.modelRef,
+					Name:        key.name,
+					Size:        entry.size,
+					LastUsed:    entry.lastUsed,
+				})
+				break
+			}
+		}
+	}
+	return result
+}
+
+// slotOf returns the slot of a loaded runner. The caller must hold the loader
+// lock.
+func (l *loader) slotOf(runner *runner) int {
+	for _, info := range l.runners {
+		if l.slots[info.slot] == runner {
+			return info.slot
+		}
+	}
+	return -1
+}
+
+// GetPromptCaches handles GET <inference-prefix>/_cache requests, which list
+// the prompt caches saved by loaded runners.
+func (s *Scheduler) GetPromptCaches(w http.ResponseWriter, r *http.Request) {
+	if !s.loader.lock(r.Context()) {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	caches := s.loader.promptCacheStatus()
+	s.loader.unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(caches)
+}
+
+// PromptCacheAction handles POST <inference-prefix>/{model}/cache requests,
+// which save the prompt cache of a slot of a model's runner, loading the
+// runner if necessary, or restore a prompt cache saved by the model's loaded
+// runner into one of its slots. The body is a PromptCacheRequest.
+func (s *Scheduler) PromptCacheAction(w http.ResponseWriter, r *http.Request) {
+	model, ok := strings.CutSuffix(r.PathValue("backend")+"/"+r.PathValue("nameAndAction"), "/cache")
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	model = models.NormalizeModelName(model)
+
+	var request PromptCacheRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumOpenAIInferenceRequestSize))
+	if err != nil || json.Unmarshal(body, &request) != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if request.Action != "save" && request.Action != "restore" {
+		http.Error(w, fmt.Sprintf("invalid action %q, expected save or restore", request.Action), http.StatusBadRequest)
+		return
+	}
+	save := request.Action == "save"
+	if !promptCacheNamePattern.MatchString(request.Name) {
+		http.Error(w, fmt.Sprintf("invalid prompt cache name %q", request.Name), http.StatusBadRequest)
+		return
+	}
+	if request.Slot < 0 {
+		http.Error(w, "slot must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	backend := s.defaultBackend
+	if request.Backend != "" {
+		backend = s.backends[request.Backend]
+	}
+	if backend == nil {
+		http.Error(w, ErrBackendNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if !backend.UsesExternalModelManagement() {
+		bundle, err := s.modelManager.GetModel(model)
+		if err != nil {
+			if errors.Is(err, distribution.ErrModelNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, "model unavailable", http.StatusInternalServerError)
+			}
+			return
+		}
+		backend = s.selectBackendForModel(bundle, backend, model)
+	}
+	saver, ok := backend.(inference.PromptCacheSaver)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s %s", ErrPromptCacheUnsupported, backend.Name()), http.StatusBadRequest)
+		return
+	}
+	if err := s.installer.wait(r.Context(), backend.Name()); err != nil {
+		http.Error(w, fmt.Errorf("backend installation failed: %w", err).Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	modelID := s.modelManager.ResolveModelID(model)
+	runner, err := s.loader.load(r.Context(), backend.Name(), modelID, model, inference.BackendModeCompletion, "")
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errInsufficientVRAM) || errors.Is(err, errDraining) || errors.Is(err, context.Canceled) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Errorf("unable to load runner: %w", err).Error(), status)
+		return
+	}
+	defer s.loader.release(runner)
+
+	// Prompt caches are only restored by the runner that saved them, since
+	// they depend on its model and configuration.
+	if !save {
+		if !s.loader.lock(r.Context()) {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, ok := s.loader.promptCaches[promptCacheKey{slot: s.loader.slotOf(runner), name: request.Name}]
+		s.loader.unlock()
+		if !ok {
+			http.Error(w, errPromptCacheNotFound.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	if save {
+		err = saver.SavePromptCache(r.Context(), runner.client, request.Slot, request.Name)
+	} else {
+		err = saver.RestorePromptCache(r.Context(), runner.client, request.Slot, request.Name)
+	}
+	if err != nil {
+		s.log.Warnf("Failed to save or restore prompt cache %s of %s: %v", request.Name, utils.SanitizeForLog(model), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	s.loader.lock(context.Background())
+	entry, err := s.loader.recordPromptCache(s.loader.slotOf(runner), request.Name)
+	s.loader.unlock()
+	if errors.Is(err, errInsufficientPromptCacheMemory) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PromptCache{
+		BackendName: backend.Name(),
+		ModelName:   model,
+		Name:        request.Name,
+		Size:        entry.size,
+		LastUsed:    entry.lastUsed,
+	})
+}
@@ -0,0 +1,178 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestPromptCacheNamePattern(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"system.bin":      true,
+		"agent-v2_prompt": true,
+		"":                false,
+		".hidden":         false,
+		"../escape":       false,
+		"dir/file":        false,
+	} {
+		if promptCacheNamePattern.MatchString(name) != valid {
+			t.Errorf("Expected validity of %q to be %v", name, valid)
+		}
+	}
+}
+
+func TestPromptCacheValidation(t *testing.T) {
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	for _, body := range []string{
+		`{`,
+		`{"action":"delete","name":"system.bin"}`,
+		`{"action":"save","name":"../escape"}`,
+		`{"action":"restore","name":"system.bin","slot":-1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/engines/ai/modelX/cache", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for request %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/engines/ai/modelX/cache", strings.NewReader(`{"action":"save","name":"system.bin","backend":"unknown"}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown backend, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoaderPromptCacheAccounting(t *testing.T) {
+	socketDir := t.TempDir()
+	defaultSocketPath := RunnerSocketPath
+	RunnerSocketPath = func(slot int) (string, error) {
+		return filepath.Join(socketDir, fmt.Sprintf("runner-%d.sock", slot)), nil
+	}
+	defer func() { RunnerSocketPath = defaultSocketPath }()
+
+	// Prompt caches are charged to the loader's RAM.
+	loader := newLoader(createTestLogger(), nil, nil, nil, &mockSystemMemoryInfo{totalMemory: inference.RequiredMemory{RAM: 25, VRAM: GB}})
+	dir, err := preparePromptCacheDir(0)
+	if err != nil {
+		t.Fatalf("Failed to prepare prompt cache directory: %v", err)
+	}
+	write := func(name string, size int) {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save := func(name string, size int) {
+		write(name, size)
+		if _, err := loader.recordPromptCache(0, name); err != nil {
+			t.Fatalf("Failed to record prompt cache %s: %v", name, err)
+		}
+	}
+
+	// The least recently used prompt caches are deleted when RAM runs out.
+	save("first", 10)
+	save("second", 10)
+	save("first", 10)
+	save("third", 10)
+	if _, ok := loader.promptCaches[promptCacheKey{0, "second"}]; ok {
+		t.Error("Expected the least recently used prompt cache to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second")); !os.IsNotExist(err) {
+		t.Errorf("Expected the deleted prompt cache's file to be removed, got %v", err)
+	}
+	if loader.promptCacheSize != 20 || len(loader.promptCaches) != 2 {
+		t.Errorf("Expected 2 prompt caches of 20 bytes, got %d of %d bytes", len(loader.promptCaches), loader.promptCacheSize)
+	}
+	if loader.availableMemory.RAM != 5 {
+		t.Errorf("Expected 5 bytes of RAM to be available, got %d", loader.availableMemory.RAM)
+	}
+	if _, err := loader.recordPromptCache(0, "missing"); err == nil {
+		t.Error("Expected an error for a prompt cache that wasn't saved")
+	}
+
+	// Prompt caches that don't fit at all are deleted, and others are kept.
+	write("huge", 30)
+	if _, err := loader.recordPromptCache(0, "huge"); !errors.Is(err, errInsufficientPromptCacheMemory) {
+		t.Errorf("Expected errInsufficientPromptCacheMemory, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "huge")); !os.IsNotExist(err) {
+		t.Errorf("Expected the prompt cache that doesn't fit to be removed, got %v", err)
+	}
+	if len(loader.promptCaches) != 2 || loader.availableMemory.RAM != 5 {
+		t.Errorf("Expected the other prompt caches to be kept, got %d with %d bytes of RAM available", len(loader.promptCaches), loader.availableMemory.RAM)
+	}
+
+	// Runners reclaim the RAM of prompt caches.
+	if !loader.evictPromptCaches(15) || len(loader.promptCaches) != 1 || loader.availableMemory.RAM != 15 {
+		t.Errorf("Expected one prompt cache to be deleted for 15 bytes of RAM, got %d with %d bytes of RAM available", len(loader.promptCaches), loader.availableMemory.RAM)
+	}
+
+	// Prompt caches are deleted with their runner.
+	loader.removePromptCaches(0)
+	if loader.promptCacheSize != 0 || len(loader.promptCaches) != 0 || loader.availableMemory.RAM != 25 {
+		t.Errorf("Expected no prompt caches and all RAM available, got %d of %d bytes with %d bytes of RAM available",
+			len(loader.promptCaches), loader.promptCacheSize, loader.availableMemory.RAM)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the prompt cache directory to be removed, got %v", err)
+	}
+}
+
+// TestLoaderEvictsPromptCachesBeforeRunners tests that the RAM of prompt caches
+// is reclaimed for new runners before other runners are evicted.
+func TestLoaderEvictsPromptCachesBeforeRunners(t *testing.T) {
+	log := createTestLogger()
+	backend := &fastFailBackend{mockBackend: mockBackend{
+		name:           "test-backend",
+		requiredMemory: inference.RequiredMemory{RAM: GB / 2},
+	}}
+	sysMemInfo := &mockSystemMemoryInfo{totalMemory: inference.RequiredMemory{RAM: GB, VRAM: GB}}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	// The new runner has a free slot regardless of the number of CPUs.
+	loader.slots = make([]*runner, 2)
+	loader.references = make([]uint, 2)
+	loader.allocations = make([]inference.RequiredMemory, 2)
+	loader.gpuAllocations = make([][]uint64, 2)
+	loader.timestamps = make([]time.Time, 2)
+
+	// An unused runner and one of its prompt caches take up all RAM.
+	loader.lock(context.Background())
+	loader.loadsEnabled = true
+	loader.slots[0] = createAliveTerminableMockRunner(log, backend)
+	loader.runners[makeRunnerKey("test-backend", "modelX", "", inference.BackendModeCompletion)] = runnerInfo{
+		slot:     0,
+		modelRef: "modelX:latest",
+	}
+	loader.allocations[0] = inference.RequiredMemory{RAM: GB / 2}
+	loader.timestamps[0] = time.Now()
+	loader.promptCaches[promptCacheKey{0, "system.bin"}] = promptCacheEntry{size: GB / 2, lastUsed: time.Now()}
+	loader.promptCacheSize = GB / 2
+	loader.availableMemory.RAM = 0
+	loader.unlock()
+
+	if _, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, ""); err == nil {
+		t.Error("Unexpected success with fastFail backend")
+	}
+
+	loader.lock(context.Background())
+	defer loader.unlock()
+	if len(loader.promptCaches) != 0 {
+		t.Error("Expected the prompt cache to be deleted")
+	}
+	if _, ok := loader.runners[makeRunnerKey("test-backend", "modelX", "", inference.BackendModeCompletion)]; !ok {
+		t.Error("Expected the unused runner not to be evicted")
+	}
+}
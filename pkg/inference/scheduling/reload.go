@@ -0,0 +1,65 @@
+package scheduling
+
+import (
+	"context"
+	"time"
+)
+
+// UpdateIdleTimeouts replaces the runner idle timeout, restoring the default
+// if it's zero, and the deep sleep timeout, disabling deep sleep if it's zero,
+// while the scheduler runs. Loaded runners are kept, and idle out according to
+// the new timeouts.
+func (s *Scheduler) UpdateIdleTimeouts(ctx context.Context, runnerIdleTimeout, deepSleepTimeout time.Duration) {
+	if !s.loader.lock(ctx) {
+		return
+	}
+	defer s.loader.unlock()
+	if runnerIdleTimeout <= 0 {
+		runnerIdleTimeout = s.loader.defaultRunnerIdleTimeout
+	}
+	s.loader.runnerIdleTimeout = runnerIdleTimeout
+	s.loader.deepSleepTimeout = deepSleepTimeout
+
+	// Signal the run loop to reschedule eviction and deep sleep.
+	select {
+	case s.loader.idleCheck <- struct{}{}:
+	default:
+	}
+}
+
+// RestartRunners evicts the runners of a backend, for example once its
+// configuration has changed, so that they're restarted on their next request.
+// Runners that are in use are evicted once their requests complete. It returns
+// the number of runners that were evicted or marked for eviction.
+func (s *Scheduler) RestartRunners(ctx context.Context, backend string) int {
+	if !s.loader.lock(ctx) {
+		return 0
+	}
+	defer s.loader.unlock()
+	count := 0
+	for key, info := range s.loader.runners {
+		if key.backend != backend {
+			continue
+		}
+		count++
+		if s.loader.references[info.slot] == 0 {
+			s.log.Infof("Restarting %s backend runner with model %s (%s) in %s mode",
+				key.backend, key.modelID, info.modelRef, key.mode)
+			s.loader.freeRunnerSlot(info.slot, key)
+		} else {
+			info.stale = true
+			s.loader.runners[key] = info
+		}
+	}
+	if count > 0 {
+		s.loader.broadcast()
+	}
+	return count
+}
+
+// Preload pulls, if necessary, and loads models with the default backend in
+// turn, like the preload models set with SetPreloadModels on startup. It
+// returns once the models are loaded or ctx is cancelled.
+func (s *Scheduler) Preload(ctx context.Context, models []string) {
+	s.preloadList(ctx, models)
+}
@@ -0,0 +1,70 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRestartRunnersInUse(t *testing.T) {
+	loader, r := newDrainTestLoader(t)
+	s := &Scheduler{log: createTestLogger(), loader: loader}
+
+	if count := s.RestartRunners(context.Background(), "other-backend"); count != 0 {
+		t.Errorf("Expected no runners of another backend to be restarted, got %d", count)
+	}
+
+	// A runner that's in use is kept until its requests complete.
+	if count := s.RestartRunners(context.Background(), "test-backend"); count != 1 {
+		t.Fatalf("Expected 1 runner to be restarted, got %d", count)
+	}
+	if len(loader.runners) != 1 {
+		t.Fatalf("Expected the in-use runner to be kept, got %d runners", len(loader.runners))
+	}
+
+	// New requests wait for the stale runner, rather than using it.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := loader.load(ctx, "test-backend", r.model, "modelX:latest", r.mode, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a new request to wait for the stale runner, got %v", err)
+	}
+
+	loader.release(r)
+	if len(loader.runners) != 0 {
+		t.Errorf("Expected the stale runner to be evicted once released, got %d runners", len(loader.runners))
+	}
+}
+
+func TestRestartRunnersUnused(t *testing.T) {
+	loader, _ := newDrainTestLoader(t)
+	loader.references[0] = 0
+	s := &Scheduler{log: createTestLogger(), loader: loader}
+
+	if count := s.RestartRunners(context.Background(), "test-backend"); count != 1 {
+		t.Fatalf("Expected 1 runner to be restarted, got %d", count)
+	}
+	if len(loader.runners) != 0 {
+		t.Errorf("Expected the unused runner to be evicted, got %d runners", len(loader.runners))
+	}
+}
+
+func TestUpdateIdleTimeouts(t *testing.T) {
+	loader, _ := newDrainTestLoader(t)
+	s := &Scheduler{log: createTestLogger(), loader: loader}
+
+	s.UpdateIdleTimeouts(context.Background(), time.Minute, time.Hour)
+	if loader.runnerIdleTimeout != time.Minute || loader.deepSleepTimeout != time.Hour {
+		t.Errorf("Expected 1m and 1h timeouts, got %v and %v", loader.runnerIdleTimeout, loader.deepSleepTimeout)
+	}
+	if len(loader.runners) != 1 {
+		t.Errorf("Expected loaded runners to be kept, got %d runners", len(loader.runners))
+	}
+
+	// A zero runner idle timeout restores the default.
+	s.UpdateIdleTimeouts(context.Background(), 0, 0)
+	if loader.runnerIdleTimeout != defaultRunnerIdleTimeout || loader.deepSleepTimeout != 0 {
+		t.Errorf("Expected the default runner idle timeout and no deep sleep, got %v and %v",
+			loader.runnerIdleTimeout, loader.deepSleepTimeout)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/model-runner/pkg/inference"
@@ -26,6 +28,12 @@ const (
 	// readinessRetryInterval is the interval at which a runner will retry
 	// readiness checks for a backend.
 	readinessRetryInterval = 500 * time.Millisecond
+	// initialRestartBackoff is the delay before a runner's backend is first
+	// restarted after exiting unexpectedly. It doubles with each restart.
+	initialRestartBackoff = 1 * time.Second
+	// maximumRestartBackoff is the maximum delay between restarts of a
+	// runner's backend.
+	maximumRestartBackoff = 30 * time.Second
 )
 
 // errBackendNotReadyInTime indicates that an inference backend took too
@@ -69,6 +77,29 @@ type runner struct {
 	openAIRecorder *metrics.OpenAIRecorder
 	// err is the error returned by the runner's backend, only valid after done is closed.
 	err error
+	// queue limits the requests served concurrently by the runner, admitting
+	// waiting requests by priority.
+	queue *requestQueue
+	// output keeps the recent output of the runner's backend.
+	output *runnerOutput
+	// started is set once the backend first becomes ready. Backends that
+	// never became ready aren't restarted.
+	started atomic.Bool
+	// restartLock guards restarting.
+	restartLock sync.Mutex
+	// restarting is closed once a restarted backend is ready, or nil if the
+	// backend isn't restarting. Requests are held while it's open.
+	restarting chan struct{}
+}
+
+// restartBackoff returns the delay before the given restart (counting from
+// zero) of a runner's backend.
+func restartBackoff(restart int) time.Duration {
+	backoff := initialRestartBackoff
+	for i := 0; i < restart && backoff < maximumRestartBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maximumRestartBackoff)
 }
 
 // run creates a new runner instance.
@@ -81,6 +112,8 @@ func run(
 	slot int,
 	runnerConfig *inference.BackendConfiguration,
 	openAIRecorder *metrics.OpenAIRecorder,
+	maxRestarts int,
+	shouldRestart func(ctx context.Context, err error) bool,
 ) (*runner, error) {
 	// Create a dialer / transport that target backend on the specified slot.
 	socket, err := RunnerSocketPath(slot)
@@ -150,6 +183,12 @@ func run(
 	}
 
 	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		// If the client disconnected, the upstream request has already been
+		// cancelled and there's nobody to respond to.
+		if req.Context().Err() != nil {
+			r.log.Debugf("Cancelled request to %s abandoned by its client: %v", req.URL.Path, err)
+			return
+		}
 		// If the error is EOF, the underlying runner likely bailed, and closed its socket
 		// unexpectedly. Wait for the runner process to complete, but time out in case
 		// the runner process only killed its comms and is stuck.
@@ -182,15 +221,43 @@ func run(
 		r.log.Warnf("OpenAI recorder is nil for model %s", modelID)
 	}
 
-	// Start the backend run loop.
+	// Start the backend run loop. If the backend exits unexpectedly after it
+	// became ready, it's restarted with exponential backoff, up to maxRestarts
+	// times, as long as shouldRestart approves, e.g. because requests are
+	// waiting for it.
 	go func() {
-		if err := backend.Run(runCtx, socket, modelID, modelRef, mode, runnerConfig); err != nil {
-			log.Warnf("Backend %s running model %s exited with error: %v",
-				backend.Name(), utils.SanitizeForLog(modelRef), err,
+		defer close(runDone)
+		for restarts := 0; ; restarts++ {
+			err := backend.Run(runCtx, socket, modelID, modelRef, mode, runnerConfig)
+			if err != nil {
+				log.Warnf("Backend %s running model %s exited with error: %v",
+					backend.Name(), utils.SanitizeForLog(modelRef), err,
+				)
+			}
+			if runCtx.Err() != nil || !r.started.Load() || restarts >= maxRestarts || !shouldRestart(runCtx, err) {
+				r.err = err
+				return
+			}
+			backoff := restartBackoff(restarts)
+			log.Warnf("Restarting backend %s for model %s in %s (restart %d of %d)",
+				backend.Name(), utils.SanitizeForLog(modelRef), backoff, restarts+1, maxRestarts,
 			)
-			r.err = err
+			r.pause()
+			select {
+			case <-time.After(backoff):
+			case <-runCtx.Done():
+				r.err = err
+				return
+			}
+			go func() {
+				if err := r.wait(runCtx); err != nil {
+					log.Warnf("Restarted backend %s for model %s isn't ready: %v",
+						backend.Name(), utils.SanitizeForLog(modelRef), err,
+					)
+				}
+				r.resume()
+			}()
 		}
-		close(runDone)
 	}()
 
 	// Create the runner.
@@ -233,6 +300,7 @@ func (r *runner) wait(ctx context.Context) error {
 		}
 
 		// The backend responded successfully.
+		r.started.Store(true)
 		return nil
 	}
 
@@ -255,6 +323,9 @@ func (r *runner) terminate() {
 		r.log.Warnf("Unable to close reverse proxy log writer: %v", err)
 	}
 
+	// End the streams of the backend's output.
+	r.output.close()
+
 	if r.openAIRecorder != nil {
 		r.openAIRecorder.RemoveModel(r.model)
 	} else {
@@ -262,8 +333,42 @@ func (r *runner) terminate() {
 	}
 }
 
+// pause holds requests until resume is called, while the backend restarts.
+func (r *runner) pause() {
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+	if r.restarting == nil {
+		r.restarting = make(chan struct{})
+	}
+}
+
+// resume releases the requests held by pause.
+func (r *runner) resume() {
+	r.restartLock.Lock()
+	defer r.restartLock.Unlock()
+	if r.restarting != nil {
+		close(r.restarting)
+		r.restarting = nil
+	}
+}
+
 // ServeHTTP implements net/http.Handler.ServeHTTP. It forwards requests to the
-// backend's HTTP server.
+// backend's HTTP server, holding them while the backend restarts. Requests are
+// forwarded with the context of req, so a client disconnecting cancels the
+// upstream request and closes its connection to the backend, which llama.cpp
+// treats as a cancellation of the generation, releasing its slot.
 func (r *runner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.restartLock.Lock()
+	restarting := r.restarting
+	r.restartLock.Unlock()
+	if restarting != nil {
+		select {
+		case <-restarting:
+		case <-r.done:
+		case <-req.Context().Done():
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
 	r.proxy.ServeHTTP(w, req)
 }
@@ -0,0 +1,73 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/types"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/internal/utils"
+)
+
+// trainedContextLength returns the context length a GGUF model was trained
+// with, as recorded in its metadata, or zero if it's unknown.
+func trainedContextLength(model types.Model) uint64 {
+	config, err := model.Config()
+	if err != nil {
+		return 0
+	}
+	architecture := config.GGUF["general.architecture"]
+	if architecture == "" {
+		return 0
+	}
+	length, err := strconv.ParseUint(config.GGUF[architecture+".context_length"], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return length
+}
+
+// keepRunnerSettings keeps the context size, parallel slots and batch size of
+// a runner configuration in the store, so that they survive restarts. A
+// configuration without them removes those kept. Failures are logged, as the
+// configuration still applies until the model runner restarts.
+func (s *Scheduler) keepRunnerSettings(backend, modelID string, mode inference.BackendMode, config inference.BackendConfiguration) {
+	err := s.modelManager.SetRunnerSettings(distribution.RunnerSettings{
+		Backend:       backend,
+		Model:         modelID,
+		Mode:          mode.String(),
+		ContextSize:   config.ContextSize,
+		ParallelSlots: config.ParallelSlots,
+		BatchSize:     config.BatchSize,
+	})
+	if err != nil && !errors.Is(err, distribution.ErrReadOnlyStore) {
+		s.log.Warnf("Failed to keep the runner settings of %s: %v", utils.SanitizeForLog(modelID), err)
+	}
+}
+
+// restoreRunnerSettings configures the runners of the models whose settings
+// are kept in the store with them.
+func (s *Scheduler) restoreRunnerSettings(ctx context.Context) {
+	settings, err := s.modelManager.RunnerSettings()
+	if err != nil {
+		s.log.Warnf("Failed to read the runner settings kept in the store: %v", err)
+		return
+	}
+	for _, kept := range settings {
+		if _, ok := s.backends[kept.Backend]; !ok {
+			s.log.Warnf("Ignoring the runner settings of %s for unknown backend %s",
+				utils.SanitizeForLog(kept.Model), utils.SanitizeForLog(kept.Backend))
+			continue
+		}
+		config := inference.BackendConfiguration{
+			ContextSize:   kept.ContextSize,
+			ParallelSlots: kept.ParallelSlots,
+			BatchSize:     kept.BatchSize,
+		}
+		if err := s.loader.setRunnerConfig(ctx, kept.Backend, kept.Model, parseBackendMode(kept.Mode), config); err != nil {
+			s.log.Warnf("Failed to restore the runner settings of %s: %v", utils.SanitizeForLog(kept.Model), err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// samplingParameterError is a validation error for a sampling parameter of a
+// completion request.
+type samplingParameterError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// samplingParameters are the per-request sampling parameters of a completion
+// request that are validated before the request is passed, unchanged, to the
+// backend. Absent and null parameters are left to the backend's defaults.
+type samplingParameters struct {
+	Temperature   json.RawMessage `json:"temperature"`
+	TopP          json.RawMessage `json:"top_p"`
+	TopK          json.RawMessage `json:"top_k"`
+	MinP          json.RawMessage `json:"min_p"`
+	RepeatPenalty json.RawMessage `json:"repeat_penalty"`
+	Seed          json.RawMessage `json:"seed"`
+	Stop          json.RawMessage `json:"stop"`
+}
+
+// validateSamplingParameters validates the sampling parameters of a completion
+// request, returning an error for each invalid parameter.
+func validateSamplingParameters(body []byte) []samplingParameterError {
+	var params samplingParameters
+	if err := json.Unmarshal(body, &params); err != nil {
+		return nil
+	}
+
+	var errs []samplingParameterError
+	check := func(param string, value json.RawMessage, validate func(json.RawMessage) string) {
+		if isNull(value) {
+			return
+		}
+		if message := validate(value); message != "" {
+			errs = append(errs, samplingParameterError{Param: param, Message: message})
+		}
+	}
+	check("temperature", params.Temperature, numberBetween(0, 2))
+	check("top_p", params.TopP, numberBetween(0, 1))
+	check("top_k", params.TopK, integerAtLeast(0))
+	check("min_p", params.MinP, numberBetween(0, 1))
+	check("repeat_penalty", params.RepeatPenalty, numberAtLeast(0))
+	check("seed", params.Seed, validateInteger)
+	check("stop", params.Stop, validateStop)
+	return errs
+}
+
+// isNull reports whether a raw JSON value is absent or null.
+func isNull(value json.RawMessage) bool {
+	return len(value) == 0 || string(value) == "null"
+}
+
+// numberBetween returns a validator for numbers in the inclusive range
+// [lo, hi].
+func numberBetween(lo, hi float64) func(json.RawMessage) string {
+	return func(value json.RawMessage) string {
+		var number float64
+		if err := json.Unmarshal(value, &number); err != nil {
+			return "must be a number"
+		}
+		if number < lo || number > hi {
+			return fmt.Sprintf("must be between %g and %g", lo, hi)
+		}
+		return ""
+	}
+}
+
+// numberAtLeast returns a validator for numbers no less than lo.
+func numberAtLeast(lo float64) func(json.RawMessage) string {
+	return func(value json.RawMessage) string {
+		var number float64
+		if err := json.Unmarshal(value, &number); err != nil {
+			return "must be a number"
+		}
+		if number < lo {
+			return fmt.Sprintf("must be at least %g", lo)
+		}
+		return ""
+	}
+}
+
+// validateInteger validates that a value is an integer.
+func validateInteger(value json.RawMessage) string {
+	var number json.Number
+	if err := json.Unmarshal(value, &number); err != nil {
+		return "must be an integer"
+	}
+	if _, err := number.Int64(); err != nil {
+		return "must be an integer"
+	}
+	return ""
+}
+
+// integerAtLeast returns a validator for integers no less than lo.
+func integerAtLeast(lo int64) func(json.RawMessage) string {
+	return func(value json.RawMessage) string {
+		if message := validateInteger(value); message != "" {
+			return message
+		}
+		var integer int64
+		json.Unmarshal(value, &integer)
+		if integer < lo {
+			return fmt.Sprintf("must be at least %d", lo)
+		}
+		return ""
+	}
+}
+
+// validateStop validates stop sequences, which are either a string or an
+// array of strings. Empty stop sequences would end every completion
+// immediately, so they are rejected.
+func validateStop(value json.RawMessage) string {
+	var sequence string
+	if err := json.Unmarshal(value, &sequence); err == nil {
+		if sequence == "" {
+			return "must not be empty"
+		}
+		return ""
+	}
+	var sequences []string
+	if err := json.Unmarshal(value, &sequences); err != nil {
+		return "must be a string or an array of strings"
+	}
+	for _, sequence := range sequences {
+		if sequence == "" {
+			return "must not contain empty sequences"
+		}
+	}
+	return ""
+}
+
+// writeSamplingParameterErrors writes the validation errors of sampling
+// parameters as an OpenAI error with status 422. The error names the first
+// invalid parameter, and lists all of them in its fields.
+func writeSamplingParameterErrors(w http.ResponseWriter, errs []samplingParameterError) {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Param + " " + err.Message
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"message": "invalid sampling parameters: " + strings.Join(messages, "; "),
+			"type":    "invalid_request_error",
+			"param":   errs[0].Param,
+			"code":    nil,
+			"fields":  errs,
+		},
+	})
+}
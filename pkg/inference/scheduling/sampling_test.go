@@ -0,0 +1,101 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestValidateSamplingParameters(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []samplingParameterError
+	}{
+		{
+			name: "no sampling parameters",
+			body: `{"model": "ai/model"}`,
+		},
+		{
+			name: "valid sampling parameters",
+			body: `{"temperature": 0.7, "top_p": 0.9, "top_k": 40, "min_p": 0.05, "repeat_penalty": 1.1, "seed": -1, "stop": ["\n", "###"]}`,
+		},
+		{
+			name: "null sampling parameters",
+			body: `{"temperature": null, "stop": null}`,
+		},
+		{
+			name: "single stop sequence",
+			body: `{"stop": "END"}`,
+		},
+		{
+			name: "out of range",
+			body: `{"temperature": 3, "top_p": -0.1, "min_p": 1.5, "repeat_penalty": -1}`,
+			expected: []samplingParameterError{
+				{Param: "temperature", Message: "must be between 0 and 2"},
+				{Param: "top_p", Message: "must be between 0 and 1"},
+				{Param: "min_p", Message: "must be between 0 and 1"},
+				{Param: "repeat_penalty", Message: "must be at least 0"},
+			},
+		},
+		{
+			name: "wrong types",
+			body: `{"temperature": "hot", "top_k": 1.5, "seed": "x", "stop": 42}`,
+			expected: []samplingParameterError{
+				{Param: "temperature", Message: "must be a number"},
+				{Param: "top_k", Message: "must be an integer"},
+				{Param: "seed", Message: "must be an integer"},
+				{Param: "stop", Message: "must be a string or an array of strings"},
+			},
+		},
+		{
+			name: "negative top_k and empty stop sequence",
+			body: `{"top_k": -1, "stop": ["a", ""]}`,
+			expected: []samplingParameterError{
+				{Param: "top_k", Message: "must be at least 0"},
+				{Param: "stop", Message: "must not contain empty sequences"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateSamplingParameters([]byte(tt.body))
+			if !reflect.DeepEqual(errs, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, errs)
+			}
+		})
+	}
+}
+
+func TestInvalidSamplingParametersRejected(t *testing.T) {
+	plain := &mockBackend{name: "plain", usesExternalModelMgmt: true}
+	backends := map[string]inference.Backend{"plain": plain}
+	s := NewScheduler(createTestLogger(), backends, plain, nil, nil, nil, nil, systemMemoryInfo{})
+
+	req := httptest.NewRequest(http.MethodPost, inference.InferencePrefix+"/v1/completions", strings.NewReader(
+		`{"model": "ai/model", "prompt": "hi", "temperature": -1, "stop": ""}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Error struct {
+			Param  string                   `json:"param"`
+			Fields []samplingParameterError `json:"fields"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected an OpenAI error, got %s", w.Body.String())
+	}
+	if response.Error.Param != "temperature" || len(response.Error.Fields) != 2 || response.Error.Fields[1].Param != "stop" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
+}
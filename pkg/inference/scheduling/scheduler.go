@@ -7,8 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,17 +19,29 @@ import (
 	"github.com/docker/model-runner/pkg/distribution/types"
 	"github.com/docker/model-runner/pkg/inference"
 	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
+	"github.com/docker/model-runner/pkg/inference/backends/onnx"
+	"github.com/docker/model-runner/pkg/inference/backends/piper"
+	"github.com/docker/model-runner/pkg/inference/backends/sdcpp"
 	"github.com/docker/model-runner/pkg/inference/backends/vllm"
+	"github.com/docker/model-runner/pkg/inference/backends/whispercpp"
 	"github.com/docker/model-runner/pkg/inference/memory"
 	"github.com/docker/model-runner/pkg/inference/models"
 	"github.com/docker/model-runner/pkg/internal/utils"
 	"github.com/docker/model-runner/pkg/logging"
 	"github.com/docker/model-runner/pkg/metrics"
 	"github.com/docker/model-runner/pkg/middleware"
+	"github.com/docker/model-runner/pkg/tracing"
 	"github.com/mattn/go-shellwords"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// tracer traces inference requests.
+var tracer = otel.Tracer("github.com/docker/model-runner/pkg/inference/scheduling")
+
 // Scheduler is used to coordinate inference scheduling across multiple backends
 // and models.
 type Scheduler struct {
@@ -51,6 +66,33 @@ type Scheduler struct {
 	tracker *metrics.Tracker
 	// openAIRecorder is used to record OpenAI API inference requests and responses.
 	openAIRecorder *metrics.OpenAIRecorder
+	// inferenceMetrics records the metrics of inference requests and runners.
+	inferenceMetrics *metrics.InferenceMetrics
+	// injectionPolicy is the allow-list for per-model environment variables
+	// and mounts.
+	injectionPolicy InjectionPolicy
+	// imagePolicy configures the preprocessing of the images of requests to
+	// vision-language models.
+	imagePolicy ImagePolicy
+	// imageClient fetches the images of requests to vision-language models.
+	imageClient *http.Client
+	// embeddingBatches are the embedding batch jobs.
+	embeddingBatches *embeddingBatches
+	// batches are the files and batches of the OpenAI Batch API.
+	batches *openAIBatches
+	// accessLog is the access log of inference requests, if enabled.
+	accessLog *accessLog
+	// preloadModels are the models loaded on startup.
+	preloadModels []string
+	// fallbackModels map models to the models that requests are retried
+	// against when they fail to load.
+	fallbackModels map[string][]string
+	// trafficSplits are the traffic splits configured for models.
+	trafficSplits trafficSplits
+	// guardrails are the content filters applied to inference requests.
+	guardrails []registeredGuardrail
+	// guardrailModels are the IDs of the models the guardrails apply to.
+	guardrailModels guardrailModels
 	// lock is used to synchronize access to the scheduler's router.
 	lock sync.RWMutex
 }
@@ -67,18 +109,29 @@ func NewScheduler(
 	sysMemInfo memory.SystemMemoryInfo,
 ) *Scheduler {
 	openAIRecorder := metrics.NewOpenAIRecorder(log.WithField("component", "openai-recorder"), modelManager)
+	inferenceMetrics := metrics.NewInferenceMetrics()
 
 	// Create the scheduler.
 	s := &Scheduler{
-		log:            log,
-		backends:       backends,
-		defaultBackend: defaultBackend,
-		modelManager:   modelManager,
-		installer:      newInstaller(log, backends, httpClient),
-		loader:         newLoader(log, backends, modelManager, openAIRecorder, sysMemInfo),
-		router:         http.NewServeMux(),
-		tracker:        tracker,
-		openAIRecorder: openAIRecorder,
+		log:              log,
+		backends:         backends,
+		defaultBackend:   defaultBackend,
+		modelManager:     modelManager,
+		installer:        newInstaller(log, backends, httpClient),
+		loader:           newLoader(log, backends, modelManager, openAIRecorder, sysMemInfo),
+		router:           http.NewServeMux(),
+		tracker:          tracker,
+		openAIRecorder:   openAIRecorder,
+		inferenceMetrics: inferenceMetrics,
+		injectionPolicy:  DefaultInjectionPolicy,
+		imagePolicy:      DefaultImagePolicy,
+		imageClient:      newImageClient(DefaultImagePolicy),
+		embeddingBatches: newEmbeddingBatches(),
+		batches:          newOpenAIBatches(),
+	}
+	s.loader.inferenceMetrics = inferenceMetrics
+	if s.loader.vramPoller != nil {
+		s.RegisterSleeper(s.loader.vramPoller)
 	}
 
 	// Register routes.
@@ -103,14 +156,33 @@ func (s *Scheduler) RebuildRoutes(allowedOrigins []string) {
 	s.httpHandler = middleware.CorsMiddleware(allowedOrigins, s.router)
 }
 
-func (s *Scheduler) routeHandlers() map[string]http.HandlerFunc {
+// InferenceRoutes returns the patterns of the routes of the OpenAI-compatible
+// API, which clients granted only the inference scope may use.
+func (s *Scheduler) InferenceRoutes() []string {
+	routes := slices.Collect(maps.Keys(s.inferenceRouteHandlers()))
+	slices.Sort(routes)
+	return routes
+}
+
+// inferenceRouteHandlers returns the routes of the OpenAI-compatible API.
+func (s *Scheduler) inferenceRouteHandlers() map[string]http.HandlerFunc {
 	openAIRoutes := []string{
 		"POST " + inference.InferencePrefix + "/{backend}/v1/chat/completions",
 		"POST " + inference.InferencePrefix + "/{backend}/v1/completions",
 		"POST " + inference.InferencePrefix + "/{backend}/v1/embeddings",
+		"POST " + inference.InferencePrefix + "/{backend}/v1/audio/transcriptions",
+		"POST " + inference.InferencePrefix + "/{backend}/v1/audio/speech",
+		"POST " + inference.InferencePrefix + "/{backend}/v1/images/generations",
+		"POST " + inference.InferencePrefix + "/{backend}/v1/classify",
+		"POST " + inference.InferencePrefix + "/{backend}/v1/rerank",
 		"POST " + inference.InferencePrefix + "/v1/chat/completions",
 		"POST " + inference.InferencePrefix + "/v1/completions",
 		"POST " + inference.InferencePrefix + "/v1/embeddings",
+		"POST " + inference.InferencePrefix + "/v1/audio/transcriptions",
+		"POST " + inference.InferencePrefix + "/v1/audio/speech",
+		"POST " + inference.InferencePrefix + "/v1/images/generations",
+		"POST " + inference.InferencePrefix + "/v1/classify",
+		"POST " + inference.InferencePrefix + "/v1/rerank",
 	}
 	m := make(map[string]http.HandlerFunc)
 	for _, route := range openAIRoutes {
@@ -123,23 +195,97 @@ func (s *Scheduler) routeHandlers() map[string]http.HandlerFunc {
 	m["GET "+inference.InferencePrefix+"/v1/models"] = s.handleModels
 	m["GET "+inference.InferencePrefix+"/v1/models/{name...}"] = s.handleModels
 
+	m["POST "+inference.InferencePrefix+"/{backend}/v1/embeddings/batch"] = s.CreateEmbeddingBatch
+	m["POST "+inference.InferencePrefix+"/v1/embeddings/batch"] = s.CreateEmbeddingBatch
+	m["GET "+inference.InferencePrefix+"/v1/embeddings/batch/{id}"] = s.GetEmbeddingBatch
+	m["GET "+inference.InferencePrefix+"/v1/embeddings/batch/{id}/results"] = s.GetEmbeddingBatchResults
+	m["DELETE "+inference.InferencePrefix+"/v1/embeddings/batch/{id}"] = s.DeleteEmbeddingBatch
+	m["POST "+inference.InferencePrefix+"/{backend}/v1/batches"] = s.CreateBatch
+	m["POST "+inference.InferencePrefix+"/v1/batches"] = s.CreateBatch
+	// The remaining Batch API routes ignore the backend, so that clients can
+	// use the same base URL as for inference.
+	for _, prefix := range []string{inference.InferencePrefix + "/{backend}", inference.InferencePrefix} {
+		m["GET "+prefix+"/v1/batches"] = s.ListBatches
+		m["GET "+prefix+"/v1/batches/{id}"] = s.GetBatch
+		m["POST "+prefix+"/v1/batches/{id}/cancel"] = s.CancelBatch
+		m["POST "+prefix+"/v1/files"] = s.UploadBatchFile
+		m["GET "+prefix+"/v1/files"] = s.ListBatchFiles
+		m["GET "+prefix+"/v1/files/{id}"] = s.GetBatchFile
+		m["GET "+prefix+"/v1/files/{id}/content"] = s.GetBatchFileContent
+		m["DELETE "+prefix+"/v1/files/{id}"] = s.DeleteBatchFile
+	}
+	return m
+}
+
+func (s *Scheduler) routeHandlers() map[string]http.HandlerFunc {
+	m := s.inferenceRouteHandlers()
+	m["GET "+inference.InferencePrefix] = s.GetEngines
+	m["GET "+inference.InferencePrefix+"/{$}"] = s.GetEngines
 	m["GET "+inference.InferencePrefix+"/status"] = s.GetBackendStatus
 	m["GET "+inference.InferencePrefix+"/ps"] = s.GetRunningBackends
 	m["GET "+inference.InferencePrefix+"/df"] = s.GetDiskUsage
 	m["POST "+inference.InferencePrefix+"/unload"] = s.Unload
+	m["POST "+inference.InferencePrefix+"/drain"] = s.handleDrain
+	m["GET "+inference.InferencePrefix+"/drain"] = s.handleGetDrainStatus
 	m["POST "+inference.InferencePrefix+"/{backend}/_configure"] = s.Configure
 	m["POST "+inference.InferencePrefix+"/_configure"] = s.Configure
+	m["GET "+inference.InferencePrefix+"/{backend}/_check"] = s.CheckModel
+	m["GET "+inference.InferencePrefix+"/_check"] = s.CheckModel
+	m["GET "+inference.InferencePrefix+"/_cache"] = s.GetPromptCaches
+	m["POST "+inference.InferencePrefix+"/{backend}/{nameAndAction...}"] = s.handleModelAction
+	m["GET "+inference.InferencePrefix+"/{nameAndAction...}"] = s.GetRunnerLogs
 	m["GET "+inference.InferencePrefix+"/requests"] = s.openAIRecorder.GetRecordsHandler()
+	m["GET "+inference.InferencePrefix+"/requests/export"] = s.openAIRecorder.GetExportHandler()
+	m["GET "+inference.InferencePrefix+"/usage"] = s.openAIRecorder.GetUsageHandler()
 	return m
 }
 
+// SetRunnerIdleTimeout sets the period after which idle runners are evicted,
+// overriding the default. It must be called before Run.
+func (s *Scheduler) SetRunnerIdleTimeout(timeout time.Duration) {
+	s.loader.runnerIdleTimeout = timeout
+}
+
+// SetMaxRunnerRestarts sets the number of times the backend of a runner is
+// restarted, with exponential backoff, after exiting unexpectedly while
+// requests are waiting for it, rather than failing them. Zero, the default,
+// disables restarts. It must be called before Run.
+func (s *Scheduler) SetMaxRunnerRestarts(restarts int) {
+	s.loader.maxRunnerRestarts = restarts
+}
+
+// SetRequestCapture enables the capture of inference request and response
+// pairs to files per model, from which they can be exported and replayed. It
+// must be called before Run, which stops the capture when it returns.
+func (s *Scheduler) SetRequestCapture(config metrics.CaptureConfig) error {
+	return s.openAIRecorder.SetCapture(config)
+}
+
+// SetBackendInstalledHook sets a function to call when the installation of a
+// backend completes, with a non-nil error if it failed. It must be called
+// before the scheduler is run.
+func (s *Scheduler) SetBackendInstalledHook(hook func(backend string, err error)) {
+	s.installer.onInstalled = hook
+}
+
+// Ready returns a channel that's closed once Run has enabled loads, before
+// which inference requests fail.
+func (s *Scheduler) Ready() <-chan struct{} {
+	return s.loader.ready
+}
+
 // Run is the scheduler's main run loop. By the time it returns, all inference
 // backends will have been unloaded from memory.
 func (s *Scheduler) Run(ctx context.Context) error {
 	// Create an error group to track worker Goroutines.
 	workers, workerCtx := errgroup.WithContext(ctx)
 
-	// Start the installer.
+	// Restore the runner settings kept in the store before any runner loads.
+	s.restoreRunnerSettings(ctx)
+
+	// Start the installer. It's marked as started up front, so that requests
+	// made once the scheduler is ready wait for installations rather than fail.
+	s.installer.started.Store(true)
 	workers.Go(func() error {
 		s.installer.run(workerCtx)
 		return nil
@@ -151,12 +297,30 @@ func (s *Scheduler) Run(ctx context.Context) error {
 		return nil
 	})
 
+	// Preload models, if configured.
+	if len(s.preloadModels) > 0 {
+		workers.Go(func() error {
+			s.preload(workerCtx)
+			return nil
+		})
+	}
+
 	// Wait for all workers to exit.
-	return workers.Wait()
+	err := workers.Wait()
+	if s.accessLog != nil {
+		if closeErr := s.accessLog.close(); closeErr != nil {
+			s.log.Warnf("Failed to close access log: %v", closeErr)
+		}
+	}
+	s.openAIRecorder.CloseCapture()
+	return err
 }
 
 // selectBackendForModel selects the appropriate backend for a model based on its format.
-// If the model is in safetensors format, it will prefer vLLM if available.
+// Models in a format listed in formatBackends are served by its backend if
+// it's available, so safetensors models prefer vLLM, and whisper models, piper
+// voices, diffusion models and ONNX models are served by whisper.cpp, piper,
+// stable-diffusion.cpp and ONNX Runtime.
 func (s *Scheduler) selectBackendForModel(model types.Model, backend inference.Backend, modelRef string) inference.Backend {
 	config, err := model.Config()
 	if err != nil {
@@ -164,24 +328,101 @@ func (s *Scheduler) selectBackendForModel(model types.Model, backend inference.B
 		return backend
 	}
 
-	if config.Format == types.FormatSafetensors {
-		if vllmBackend, ok := s.backends[vllm.Name]; ok && vllmBackend != nil {
-			return vllmBackend
+	if name, ok := formatBackends[config.Format]; ok {
+		if formatBackend, ok := s.backends[name]; ok && formatBackend != nil {
+			return formatBackend
 		}
-		s.log.Warnf("Model %s is in safetensors format but vLLM backend is not available. "+
+		s.log.Warnf("Model %s is in %s format but %s backend is not available. "+
 			"Backend %s may not support this format and could fail at runtime.",
-			utils.SanitizeForLog(modelRef), backend.Name())
+			utils.SanitizeForLog(modelRef), config.Format, name, backend.Name())
 	}
 
 	return backend
 }
 
+// formatBackends maps the model formats that are served by a dedicated backend
+// to that backend. Models in other formats are served by the requested or
+// default backend.
+var formatBackends = map[types.Format]string{
+	types.FormatSafetensors: vllm.Name,
+	types.FormatWhisper:     whispercpp.Name,
+	types.FormatPiper:       piper.Name,
+	types.FormatDiffusion:   sdcpp.Name,
+	types.FormatONNX:        onnx.Name,
+}
+
+// backendModes maps the backends that serve requests in only some modes to
+// those modes, the first of which is their default.
+var backendModes = map[string][]inference.BackendMode{
+	whispercpp.Name: {inference.BackendModeTranscription},
+	piper.Name:      {inference.BackendModeSpeech},
+	sdcpp.Name:      {inference.BackendModeImageGeneration},
+	onnx.Name:       {inference.BackendModeEmbedding, inference.BackendModeClassification},
+}
+
+// exclusiveModes are the modes in which only the backends that list them in
+// backendModes serve requests.
+var exclusiveModes = []inference.BackendMode{
+	inference.BackendModeTranscription,
+	inference.BackendModeSpeech,
+	inference.BackendModeImageGeneration,
+	inference.BackendModeClassification,
+}
+
+// supportsMode returns whether a backend serves requests in a mode. Only
+// whisper.cpp serves transcriptions, only piper synthesizes speech, only
+// stable-diffusion.cpp generates images and only ONNX Runtime classifies, and
+// none of them serves anything else but ONNX Runtime, which also serves
+// embeddings.
+func supportsMode(backend string, mode inference.BackendMode) bool {
+	if modes, ok := backendModes[backend]; ok {
+		return slices.Contains(modes, mode)
+	}
+	return !slices.Contains(exclusiveModes, mode)
+}
+
+// defaultMode returns the mode in which a backend serves a model requested in
+// a mode, which is the backend's default mode if it doesn't support the
+// requested one.
+func defaultMode(backend string, mode inference.BackendMode) inference.BackendMode {
+	if modes, ok := backendModes[backend]; ok && !slices.Contains(modes, mode) {
+		return modes[0]
+	}
+	return mode
+}
+
+// isReranker returns whether a model is marked as a reranker in its config.
+func isReranker(model types.Model) bool {
+	config, err := model.Config()
+	return err == nil && config.Reranker
+}
+
+// isClassifier returns whether a model is marked as a classifier in its
+// config.
+func isClassifier(model types.Model) bool {
+	config, err := model.Config()
+	return err == nil && config.Classifier
+}
+
 // handleOpenAIInference handles scheduling and responding to OpenAI inference
 // requests, including:
 // - POST <inference-prefix>/{backend}/v1/chat/completions
 // - POST <inference-prefix>/{backend}/v1/completions
 // - POST <inference-prefix>/{backend}/v1/embeddings
+// - POST <inference-prefix>/{backend}/v1/audio/transcriptions
+// - POST <inference-prefix>/{backend}/v1/audio/speech
+// - POST <inference-prefix>/{backend}/v1/images/generations
+// - POST <inference-prefix>/{backend}/v1/classify
+// - POST <inference-prefix>/{backend}/v1/rerank
 func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	// Trace the request, as part of the client's trace if it propagated one.
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, "scheduler.Inference", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	// Determine the requested backend and ensure that it's valid.
 	var backend inference.Backend
 	if b := r.PathValue("backend"); b == "" {
@@ -194,9 +435,20 @@ func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Determine the backend operation mode.
+	backendMode, ok := backendModeForRequest(r.URL.Path)
+	if !ok {
+		http.Error(w, "unknown request path", http.StatusInternalServerError)
+		return
+	}
+
 	// Read the entire request body. We put some basic size constraints in place
 	// to avoid DoS attacks. We do this early to avoid client write timeouts.
-	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumOpenAIInferenceRequestSize))
+	maximumRequestSize := int64(maximumOpenAIInferenceRequestSize)
+	if backendMode == inference.BackendModeTranscription {
+		maximumRequestSize = maximumTranscriptionRequestSize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumRequestSize))
 	if err != nil {
 		if _, ok := err.(*http.MaxBytesError); ok {
 			http.Error(w, "request too large", http.StatusBadRequest)
@@ -206,16 +458,18 @@ func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Determine the backend operation mode.
-	backendMode, ok := backendModeForRequest(r.URL.Path)
-	if !ok {
-		http.Error(w, "unknown request path", http.StatusInternalServerError)
-		return
-	}
-
-	// Decode the model specification portion of the request body.
+	// Decode the model specification portion of the request body. Uploaded
+	// audio isn't recorded, so transcription requests are recorded as a
+	// summary of their form.
 	var request OpenAIInferenceRequest
-	if err := json.Unmarshal(body, &request); err != nil {
+	recordedBody := body
+	if backendMode == inference.BackendModeTranscription {
+		request, recordedBody, err = decodeTranscriptionRequest(r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else if err := json.Unmarshal(body, &request); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
@@ -224,22 +478,126 @@ func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Route the configured share of the requests for a model with a traffic
+	// split to the other model. Uploaded audio isn't JSON, so the model of
+	// transcription requests can't be replaced.
+	if backendMode != inference.BackendModeTranscription {
+		if routed, ok := s.trafficSplits.route(request.Model); ok {
+			s.inferenceMetrics.RecordTrafficSplit(request.Model, routed)
+			if routed != request.Model {
+				request.Model = routed
+				body = withModel(body, routed)
+				w.Header().Set(ModelHeader, routed)
+			}
+		}
+	}
+
+	requestPriority, err := parsePriority(r.Header.Get(PriorityHeader))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Reject invalid sampling parameters before they reach the backend, which
+	// would otherwise fail without saying which parameter is at fault.
+	if backendMode == inference.BackendModeCompletion {
+		if errs := validateSamplingParameters(body); len(errs) > 0 {
+			writeSamplingParameterErrors(w, errs)
+			return
+		}
+	}
+
+	// Reject images the backend can't generate, or that would exceed the
+	// memory it was loaded with.
+	if backendMode == inference.BackendModeImageGeneration {
+		if param, err := validateImageGenerationParameters(body); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, param, err)
+			return
+		}
+	}
+
 	// Check if the shared model manager has the requested model available.
+	// Usage is attributed to the model rather than to the reference it was
+	// requested by, so that aliases and short names are billed as one model.
+	vision := false
+	usageModel := models.NormalizeModelName(request.Model)
 	if !backend.UsesExternalModelManagement() {
 		model, err := s.modelManager.GetModel(request.Model)
 		if err != nil {
 			if errors.Is(err, distribution.ErrModelNotFound) {
 				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, distribution.ErrDigestRequired) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
 			} else {
 				http.Error(w, "model unavailable", http.StatusInternalServerError)
 			}
 			return
 		}
+		if tags := model.Tags(); len(tags) > 0 {
+			usageModel = tags[0]
+		}
 		// Non-blocking call to track the model usage.
 		s.tracker.TrackModel(model, r.UserAgent(), "inference/"+backendMode.String())
 
-		// Automatically identify models for vLLM.
+		// Automatically identify models for vLLM, whisper.cpp, piper,
+		// stable-diffusion.cpp and ONNX Runtime.
 		backend = s.selectBackendForModel(model, backend, request.Model)
+
+		// Speech and image models are served by backends that serve nothing
+		// else.
+		// Likewise, rerankers serve nothing but reranking requests, and
+		// classifiers nothing but classification requests.
+		if !supportsMode(backend.Name(), backendMode) ||
+			(isReranker(model) && backendMode != inference.BackendModeReranking) ||
+			isClassifier(model) != (backendMode == inference.BackendModeClassification) {
+			http.Error(w, fmt.Sprintf("model %s does not support %s requests", request.Model, backendMode), http.StatusBadRequest)
+			return
+		}
+
+		// Vision-language models have a multimodal projector.
+		path, err := model.MMPROJPath()
+		vision = err == nil && path != ""
+	}
+
+	// Apply the guardrails of the model serving the request, which may have
+	// been requested by an alias or routed to by a traffic split, to the
+	// prompt, so that rewritten prompts are what's recorded. Uploaded audio
+	// has no text to filter.
+	guardrails := s.guardrailsFor(request.Model)
+	if len(guardrails) > 0 && backendMode != inference.BackendModeTranscription {
+		if body, err = s.filterPrompt(request.Model, guardrails, body); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "", err)
+			return
+		}
+		recordedBody = body
+	}
+
+	// Fetch and downscale the images passed to vision-language models, so
+	// that clients needn't inline them and huge images don't reach the
+	// backend.
+	if vision && strings.HasSuffix(r.URL.Path, "/v1/chat/completions") {
+		if body, err = s.preprocessImages(r.Context(), body); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "messages", err)
+			return
+		}
+	}
+
+	// Translate JSON schema response formats into the backend's constraints.
+	if strings.HasSuffix(r.URL.Path, "/v1/chat/completions") {
+		if body, err = applyResponseFormat(backend, body); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "response_format", err)
+			return
+		}
+	}
+
+	// Route requests of a session to the same replica, and have backends that
+	// support it reuse the prompt cache of the session's previous request.
+	session := sessionForRequest(r, request)
+	if cacher, ok := backend.(inference.PromptCacher); ok && session != "" && backendMode == inference.BackendModeCompletion {
+		if body, err = cacher.CachePrompt(body); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
 	}
 
 	// Wait for the corresponding backend installation to complete or fail. We
@@ -256,7 +614,9 @@ func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request
 			// shutting down (since that will also cancel the request context).
 			// Either way, provide a response, even if it's ignored.
 			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
-		} else if errors.Is(err, vllm.StatusNotFound) {
+		} else if errors.Is(err, vllm.StatusNotFound) || errors.Is(err, whispercpp.StatusNotFound) ||
+			errors.Is(err, piper.StatusNotFound) || errors.Is(err, sdcpp.StatusNotFound) ||
+			errors.Is(err, onnx.StatusNotFound) {
 			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		} else {
 			http.Error(w, fmt.Errorf("backend installation failed: %w", err).Error(), http.StatusServiceUnavailable)
@@ -266,25 +626,109 @@ func (s *Scheduler) handleOpenAIInference(w http.ResponseWriter, r *http.Request
 
 	modelID := s.modelManager.ResolveModelID(request.Model)
 
+	// Observe the request from here on, so that its time to first token
+	// includes loading and queueing.
+	observer := s.inferenceMetrics.ObserveRequest(w, backend.Name(), request.Model, backendMode.String())
+	defer func() {
+		// The request context is cancelled when the client disconnects, which
+		// also cancels the request to the backend.
+		if r.Context().Err() != nil {
+			observer.Cancel()
+			span.SetAttributes(attribute.Bool("cancelled", true))
+		}
+		observer.Done()
+		span.SetAttributes(attribute.Int("http.response.status_code", observer.StatusCode()))
+		if observer.StatusCode() < http.StatusBadRequest {
+			promptTokens, completionTokens := observer.Tokens()
+			s.openAIRecorder.RecordUsage(metrics.APIKeyFingerprint(r), usageModel, promptTokens, completionTokens)
+		}
+		s.logAccess(r, start, backend, request.Model, backendMode, recordedBody, observer)
+	}()
+	w = observer
+	span.SetAttributes(
+		attribute.String("backend", backend.Name()),
+		attribute.String("model", request.Model),
+		attribute.String("mode", backendMode.String()),
+	)
+
 	// Request a runner to execute the request and defer its release.
-	runner, err := s.loader.load(r.Context(), backend.Name(), modelID, request.Model, backendMode)
+	loadCtx, loadSpan := tracer.Start(r.Context(), "scheduler.LoadBackend")
+	runner, err := s.loader.load(loadCtx, backend.Name(), modelID, request.Model, backendMode, session)
+	var fallback string
+	if err != nil {
+		fallback, runner, err = s.loadFallback(loadCtx, backend, request.Model, backendMode, session, err)
+	}
+	tracing.End(loadSpan, err)
 	if err != nil {
-		http.Error(w, fmt.Errorf("unable to load runner: %w", err).Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, errInsufficientVRAM) || errors.Is(err, errDraining) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Errorf("unable to load runner: %w", err).Error(), status)
 		return
 	}
+	if fallback != "" {
+		body = withModel(body, fallback)
+		w.Header().Set(ModelHeader, fallback)
+		span.SetAttributes(attribute.String("fallback_model", fallback))
+	}
 	defer s.loader.release(runner)
 
+	// A fallback model is served with its own guardrails, which are applied
+	// to the prompt again.
+	if fallback != "" {
+		guardrails = s.guardrailsFor(fallback)
+		if len(guardrails) > 0 && backendMode != inference.BackendModeTranscription {
+			if body, err = s.filterPrompt(fallback, guardrails, body); err != nil {
+				writeOpenAIError(w, http.StatusBadRequest, "", err)
+				return
+			}
+			if recordedBody, err = s.filterPrompt(fallback, guardrails, recordedBody); err != nil {
+				writeOpenAIError(w, http.StatusBadRequest, "", err)
+				return
+			}
+		}
+	}
+
+	// Wait for the runner to admit the request.
+	queueCtx, queueSpan := tracer.Start(r.Context(), "scheduler.Queue",
+		trace.WithAttributes(attribute.String("priority", requestPriority.String())))
+	err = runner.queue.acquire(queueCtx, requestPriority)
+	tracing.End(queueSpan, err)
+	if err != nil {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer runner.queue.release()
+
 	// Record the request in the OpenAI recorder.
-	recordID := s.openAIRecorder.RecordRequest(request.Model, r, body)
+	recordID := s.openAIRecorder.RecordRequest(request.Model, r, recordedBody)
 	w = s.openAIRecorder.NewResponseRecorder(w)
 	defer func() {
 		// Record the response in the OpenAI recorder.
 		s.openAIRecorder.RecordResponse(recordID, request.Model, w)
 	}()
 
-	// Create a request with the body replaced for forwarding upstream.
-	upstreamRequest := r.Clone(r.Context())
+	// Create a request with the body replaced for forwarding upstream, and
+	// propagate the trace to backends that support it.
+	generateCtx, generateSpan := tracer.Start(r.Context(), "scheduler.GenerateTokens")
+	defer generateSpan.End()
+	upstreamRequest := r.Clone(generateCtx)
 	upstreamRequest.Body = io.NopCloser(bytes.NewReader(body))
+	otel.GetTextMapPropagator().Inject(generateCtx, propagation.HeaderCarrier(upstreamRequest.Header))
+
+	// Apply the guardrails of the model that answers to the completions,
+	// before they're recorded.
+	if len(guardrails) > 0 && backendMode == inference.BackendModeCompletion {
+		guarded := newGuardrailWriter(w, guardrails)
+		defer func() {
+			if err := guarded.close(); err != nil {
+				s.log.Warnf("Failed to write filtered completion: %v", err)
+			}
+		}()
+		runner.ServeHTTP(guarded, upstreamRequest)
+		return
+	}
 
 	// Perform the request.
 	runner.ServeHTTP(w, upstreamRequest)
@@ -303,9 +747,33 @@ func (s *Scheduler) ResetInstaller(httpClient *http.Client) {
 	s.installer = newInstaller(s.log, s.backends, httpClient)
 }
 
-// GetRunningBackends returns information about all running backends
+// GetRunningBackends returns information about all running backends, followed
+// by the most recently crashed ones if the include_crashes query parameter is
+// true.
 func (s *Scheduler) GetRunningBackends(w http.ResponseWriter, r *http.Request) {
-	runningBackends := s.getLoaderStatus(r.Context())
+	includeCrashes := false
+	if r.URL.Query().Has("include_crashes") {
+		val, err := strconv.ParseBool(r.URL.Query().Get("include_crashes"))
+		if err != nil {
+			http.Error(w, "invalid include_crashes parameter", http.StatusBadRequest)
+			return
+		}
+		includeCrashes = val
+	}
+	runningBackends := s.getLoaderStatus(r.Context(), includeCrashes)
+
+	// Sample runner resource usage outside of the loader lock, since it
+	// requires connecting to each runner.
+	for i := range runningBackends {
+		if runningBackends[i].socket == "" {
+			continue
+		}
+		stats, err := metrics.SampleRunnerResources(runningBackends[i].socket)
+		if err != nil {
+			continue
+		}
+		runningBackends[i].Resources = stats
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(runningBackends); err != nil {
@@ -314,8 +782,10 @@ func (s *Scheduler) GetRunningBackends(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getLoaderStatus returns information about all running backends managed by the loader
-func (s *Scheduler) getLoaderStatus(ctx context.Context) []BackendStatus {
+// getLoaderStatus returns information about all running backends managed by
+// the loader, followed by the most recently crashed ones if includeCrashes is
+// set.
+func (s *Scheduler) getLoaderStatus(ctx context.Context, includeCrashes bool) []BackendStatus {
 	if !s.loader.lock(ctx) {
 		return []BackendStatus{}
 	}
@@ -323,23 +793,52 @@ func (s *Scheduler) getLoaderStatus(ctx context.Context) []BackendStatus {
 
 	result := make([]BackendStatus, 0, len(s.loader.runners))
 
+	now := time.Now()
 	for key, runnerInfo := range s.loader.runners {
-		if s.loader.slots[runnerInfo.slot] != nil {
+		if runner := s.loader.slots[runnerInfo.slot]; runner != nil {
 			status := BackendStatus{
-				BackendName: key.backend,
-				ModelName:   runnerInfo.modelRef,
-				Mode:        key.mode.String(),
-				LastUsed:    time.Time{},
-				InUse:       s.loader.references[runnerInfo.slot] > 0,
+				BackendName:     key.backend,
+				ModelName:       runnerInfo.modelRef,
+				Mode:            key.mode.String(),
+				Replica:         key.replica,
+				LastUsed:        time.Time{},
+				InUse:           s.loader.references[runnerInfo.slot] > 0,
+				GPUs:            s.loader.allocatedGPUs(runnerInfo.slot),
+				LoadedAt:        runnerInfo.loaded,
+				LoadSeconds:     runnerInfo.loadDuration.Seconds(),
+				Queued:          runner.queue.depth(),
+				TokensPerSecond: s.inferenceMetrics.TokensPerSecond(key.backend, runnerInfo.modelRef, key.mode.String()),
+			}
+
+			// Allocations of 1 byte stand for unknown memory sizes.
+			allocation := s.loader.allocations[runnerInfo.slot]
+			if allocation.RAM > 1 {
+				status.RAMBytes = allocation.RAM
 			}
+			if allocation.VRAM > 1 {
+				status.VRAMBytes = allocation.VRAM
+			}
+			if !runnerInfo.loaded.IsZero() {
+				status.UptimeSeconds = now.Sub(runnerInfo.loaded).Seconds()
+			}
+			// Requests hold a reference to the runner while they wait to be
+			// admitted as well as while they're served.
+			status.InFlight = max(int(s.loader.references[runnerInfo.slot])-status.Queued, 0)
 
 			if s.loader.references[runnerInfo.slot] == 0 {
 				status.LastUsed = s.loader.timestamps[runnerInfo.slot]
 			}
 
+			if socket, err := RunnerSocketPath(runnerInfo.slot); err == nil {
+				status.socket = socket
+			}
+
 			result = append(result, status)
 		}
 	}
+	if includeCrashes {
+		result = append(result, s.loader.crashes...)
+	}
 
 	return result
 }
@@ -367,7 +866,8 @@ func (s *Scheduler) GetDiskUsage(w http.ResponseWriter, _ *http.Request) {
 }
 
 // Unload unloads the specified runners (backend, model) from the backend.
-// Currently, this doesn't work for runners that are handling an OpenAI request.
+// Runners that are handling requests are unloaded once they complete, unless
+// only idle runners are to be unloaded.
 func (s *Scheduler) Unload(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumOpenAIInferenceRequestSize))
 	if err != nil {
@@ -385,7 +885,21 @@ func (s *Scheduler) Unload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	unloadedRunners := UnloadResponse{s.loader.Unload(r.Context(), unloadRequest)}
+	if unloadRequest.Mode != "" && parseBackendMode(unloadRequest.Mode).String() != unloadRequest.Mode {
+		http.Error(w, fmt.Sprintf("unknown mode %q", unloadRequest.Mode), http.StatusBadRequest)
+		return
+	}
+	if unloadRequest.GPU != nil && *unloadRequest.GPU < 0 {
+		http.Error(w, "GPU index must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	runners, draining := s.loader.Unload(r.Context(), unloadRequest)
+	unloadedRunners := UnloadResponse{
+		UnloadedRunners: len(runners),
+		Runners:         runners,
+		DrainingRunners: draining,
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(unloadedRunners); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
@@ -423,6 +937,36 @@ func (s *Scheduler) Configure(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
+
+	// Split the traffic of the model, if requested. The model that serves the
+	// split requests must be in the store.
+	if split := configureRequest.TrafficSplit; split != nil {
+		if split.Percent > 0 && !backend.UsesExternalModelManagement() {
+			if _, err := s.modelManager.GetModel(split.Model); err != nil {
+				if errors.Is(err, distribution.ErrModelNotFound) {
+					http.Error(w, fmt.Sprintf("traffic split model %s not found", split.Model), http.StatusNotFound)
+				} else {
+					http.Error(w, "traffic split model unavailable", http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+		if err := s.trafficSplits.set(configureRequest.Model, *split); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if split.Percent == 0 {
+			s.log.Infof("Removed the traffic split of %s", utils.SanitizeForLog(configureRequest.Model))
+		} else {
+			s.log.Infof("Routing %d%% of the requests for %s to %s", split.Percent,
+				utils.SanitizeForLog(configureRequest.Model), utils.SanitizeForLog(split.Model))
+		}
+		if onlyTrafficSplit(configureRequest) {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
 	var runtimeFlags []string
 	if len(configureRequest.RuntimeFlags) > 0 {
 		runtimeFlags = configureRequest.RuntimeFlags
@@ -435,24 +979,106 @@ func (s *Scheduler) Configure(w http.ResponseWriter, r *http.Request) {
 		runtimeFlags = rawFlags
 	}
 
+	// Validate the environment variables and mounts for the backend process.
+	if err := s.injectionPolicy.validateEnv(configureRequest.Env); err != nil {
+		s.log.Warnf("Rejected configuration for %s: %v", utils.SanitizeForLog(configureRequest.Model), err)
+		http.Error(w, err.Error(), injectionErrorStatus(err))
+		return
+	}
+	mounts, err := s.injectionPolicy.resolveMounts(configureRequest.Mounts)
+	if err != nil {
+		s.log.Warnf("Rejected configuration for %s: %v", utils.SanitizeForLog(configureRequest.Model), err)
+		http.Error(w, err.Error(), injectionErrorStatus(err))
+		return
+	}
+
+	if configureRequest.KVCache != nil {
+		if err := configureRequest.KVCache.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The draft model for speculative decoding must be in the store, as it's
+	// loaded alongside the model.
+	if configureRequest.Speculative != nil && configureRequest.Speculative.DraftModel != "" && !backend.UsesExternalModelManagement() {
+		if _, err := s.modelManager.GetModel(configureRequest.Speculative.DraftModel); err != nil {
+			if errors.Is(err, distribution.ErrModelNotFound) {
+				http.Error(w, fmt.Sprintf("draft model %s not found", configureRequest.Speculative.DraftModel), http.StatusNotFound)
+			} else if errors.Is(err, distribution.ErrDigestRequired) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "draft model unavailable", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
 	var runnerConfig inference.BackendConfiguration
 	runnerConfig.ContextSize = configureRequest.ContextSize
 	runnerConfig.RuntimeFlags = runtimeFlags
 	runnerConfig.Speculative = configureRequest.Speculative
+	runnerConfig.KVCache = configureRequest.KVCache
+	runnerConfig.GPUs = configureRequest.GPUs
+	runnerConfig.TensorSplit = configureRequest.TensorSplit
+	runnerConfig.LoRAAdapters = configureRequest.LoRAAdapters
+	runnerConfig.Env = configureRequest.Env
+	runnerConfig.Mounts = mounts
+	if configureRequest.Replicas < 0 {
+		http.Error(w, "replicas must not be negative", http.StatusBadRequest)
+		return
+	}
+	runnerConfig.Replicas = configureRequest.Replicas
+	if configureRequest.ParallelSlots < 0 {
+		http.Error(w, "parallel slots must not be negative", http.StatusBadRequest)
+		return
+	}
+	runnerConfig.ParallelSlots = configureRequest.ParallelSlots
+	if configureRequest.BatchSize < 0 {
+		http.Error(w, "batch size must not be negative", http.StatusBadRequest)
+		return
+	}
+	runnerConfig.BatchSize = configureRequest.BatchSize
+	if err := runnerConfig.ValidateGPUs(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	mode := inference.BackendModeCompletion
 	if slices.Contains(runnerConfig.RuntimeFlags, "--embeddings") {
 		mode = inference.BackendModeEmbedding
+	} else if slices.Contains(runnerConfig.RuntimeFlags, "--rerank") || slices.Contains(runnerConfig.RuntimeFlags, "--reranking") {
+		mode = inference.BackendModeReranking
 	}
 
 	if model, err := s.modelManager.GetModel(configureRequest.Model); err == nil {
+		if length := trainedContextLength(model); length > 0 && configureRequest.ContextSize > int64(length) {
+			http.Error(w, fmt.Sprintf("context size %d exceeds the context length the model was trained with (%d)",
+				configureRequest.ContextSize, length), http.StatusBadRequest)
+			return
+		}
+		if isReranker(model) {
+			mode = inference.BackendModeReranking
+		} else if isClassifier(model) {
+			mode = inference.BackendModeClassification
+		}
+
 		// Configure is called by compose for each model.
 		s.tracker.TrackModel(model, r.UserAgent(), "configure/"+mode.String())
 
-		// Automatically identify models for vLLM.
+		// Automatically identify models for vLLM, whisper.cpp, piper,
+		// stable-diffusion.cpp and ONNX Runtime.
 		backend = s.selectBackendForModel(model, backend, configureRequest.Model)
 	}
+	mode = defaultMode(backend.Name(), mode)
 	modelID := s.modelManager.ResolveModelID(configureRequest.Model)
+
+	// Override whether the model's prompts are logged, which applies even if
+	// its runner is already active.
+	if configureRequest.AccessLogPrompts != nil && s.accessLog != nil {
+		s.accessLog.setPrompts(modelID, *configureRequest.AccessLogPrompts)
+	}
+
 	if err := s.loader.setRunnerConfig(r.Context(), backend.Name(), modelID, mode, runnerConfig); err != nil {
 		s.log.Warnf("Failed to configure %s runner for %s (%s): %s", backend.Name(), configureRequest.Model, modelID, err)
 		if errors.Is(err, errRunnerAlreadyActive) {
@@ -462,13 +1088,13 @@ func (s *Scheduler) Configure(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	s.keepRunnerSettings(backend.Name(), modelID, mode, runnerConfig)
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
 // GetAllActiveRunners returns information about all active runners
 func (s *Scheduler) GetAllActiveRunners() []metrics.ActiveRunner {
-	runningBackends := s.getLoaderStatus(context.Background())
 	var activeRunners []metrics.ActiveRunner
 
 	if !s.loader.lock(context.Background()) {
@@ -476,35 +1102,43 @@ func (s *Scheduler) GetAllActiveRunners() []metrics.ActiveRunner {
 	}
 	defer s.loader.unlock()
 
-	for _, backend := range runningBackends {
-		mode := parseBackendMode(backend.Mode)
-		// Find the runner slot for this backend/model combination
-		// We iterate through all runners since we don't know the draftModelID
-		for key, runnerInfo := range s.loader.runners {
-			if key.backend == backend.BackendName && key.modelID == backend.ModelName && key.mode == mode {
-				socket, err := RunnerSocketPath(runnerInfo.slot)
-				if err != nil {
-					s.log.Warnf("Failed to get socket path for runner %s/%s (%s): %v", backend.BackendName, backend.ModelName, key.modelID, err)
-					continue
-				}
-
-				activeRunners = append(activeRunners, metrics.ActiveRunner{
-					BackendName: backend.BackendName,
-					ModelName:   backend.ModelName,
-					Mode:        backend.Mode,
-					Socket:      socket,
-				})
-				break // Found the runner, no need to continue iterating
-			}
+	for key, runnerInfo := range s.loader.runners {
+		runner := s.loader.slots[runnerInfo.slot]
+		if runner == nil {
+			continue
+		}
+		socket, err := RunnerSocketPath(runnerInfo.slot)
+		if err != nil {
+			s.log.Warnf("Failed to get socket path for runner %s/%s (%s): %v", key.backend, runnerInfo.modelRef, key.modelID, err)
+			continue
 		}
+		// References are held by requests being served and those waiting to
+		// be admitted.
+		queued := runner.queue.depth()
+		activeRunners = append(activeRunners, metrics.ActiveRunner{
+			BackendName: key.backend,
+			ModelName:   runnerInfo.modelRef,
+			Mode:        key.mode.String(),
+			Socket:      socket,
+			Replica:     key.replica,
+			RAM:         s.loader.allocations[runnerInfo.slot].RAM,
+			VRAM:        s.loader.allocations[runnerInfo.slot].VRAM,
+			InFlight:    max(int(s.loader.references[runnerInfo.slot])-queued, 0),
+			Queued:      queued,
+		})
 	}
 
 	return activeRunners
 }
 
+// GetInferenceMetrics returns the metrics of inference requests and runners.
+func (s *Scheduler) GetInferenceMetrics() *metrics.InferenceMetrics {
+	return s.inferenceMetrics
+}
+
 // GetLlamaCppSocket returns the Unix socket path for an active llama.cpp runner
 func (s *Scheduler) GetLlamaCppSocket() (string, error) {
-	runningBackends := s.getLoaderStatus(context.Background())
+	runningBackends := s.getLoaderStatus(context.Background(), false)
 
 	if !s.loader.lock(context.Background()) {
 		return "", errors.New("failed to acquire loader lock")
@@ -536,6 +1170,16 @@ func parseBackendMode(mode string) inference.BackendMode {
 		return inference.BackendModeCompletion
 	case "embedding":
 		return inference.BackendModeEmbedding
+	case "transcription":
+		return inference.BackendModeTranscription
+	case "reranking":
+		return inference.BackendModeReranking
+	case "speech":
+		return inference.BackendModeSpeech
+	case "image-generation":
+		return inference.BackendModeImageGeneration
+	case "classification":
+		return inference.BackendModeClassification
 	default:
 		return inference.BackendModeCompletion
 	}
@@ -549,7 +1193,10 @@ func (s *Scheduler) handleModels(w http.ResponseWriter, r *http.Request) {
 
 // ServeHTTP implements net/http.Handler.ServeHTTP.
 func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Release the lock before serving, so that rebuilding routes doesn't wait
+	// for long-running requests.
 	s.lock.RLock()
-	defer s.lock.RUnlock()
-	s.httpHandler.ServeHTTP(w, r)
+	handler := s.httpHandler
+	s.lock.RUnlock()
+	handler.ServeHTTP(w, r)
 }
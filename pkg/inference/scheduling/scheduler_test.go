@@ -1,12 +1,21 @@
 package scheduling
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
+	"github.com/docker/model-runner/pkg/inference/backends/onnx"
+	"github.com/docker/model-runner/pkg/inference/backends/piper"
+	"github.com/docker/model-runner/pkg/inference/backends/sdcpp"
+	"github.com/docker/model-runner/pkg/inference/backends/whispercpp"
+	"github.com/docker/model-runner/pkg/middleware"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,6 +29,14 @@ func (i systemMemoryInfo) GetTotalMemory() inference.RequiredMemory {
 	return inference.RequiredMemory{}
 }
 
+func (i systemMemoryInfo) GetGPUMemory() []uint64 {
+	return nil
+}
+
+func (i systemMemoryInfo) GetFreeGPUMemory() []uint64 {
+	return nil
+}
+
 func TestCors(t *testing.T) {
 	// Verify that preflight requests work against non-existing handlers or
 	// method-specific handlers that do not support OPTIONS
@@ -56,3 +73,127 @@ func TestCors(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportsMode(t *testing.T) {
+	tests := []struct {
+		backend  string
+		mode     inference.BackendMode
+		expected bool
+	}{
+		{backend: llamacpp.Name, mode: inference.BackendModeCompletion, expected: true},
+		{backend: llamacpp.Name, mode: inference.BackendModeReranking, expected: true},
+		{backend: llamacpp.Name, mode: inference.BackendModeTranscription, expected: false},
+		{backend: llamacpp.Name, mode: inference.BackendModeSpeech, expected: false},
+		{backend: whispercpp.Name, mode: inference.BackendModeTranscription, expected: true},
+		{backend: whispercpp.Name, mode: inference.BackendModeSpeech, expected: false},
+		{backend: piper.Name, mode: inference.BackendModeSpeech, expected: true},
+		{backend: piper.Name, mode: inference.BackendModeCompletion, expected: false},
+		{backend: sdcpp.Name, mode: inference.BackendModeImageGeneration, expected: true},
+		{backend: sdcpp.Name, mode: inference.BackendModeEmbedding, expected: false},
+		{backend: llamacpp.Name, mode: inference.BackendModeImageGeneration, expected: false},
+		{backend: llamacpp.Name, mode: inference.BackendModeEmbedding, expected: true},
+		{backend: llamacpp.Name, mode: inference.BackendModeClassification, expected: false},
+		{backend: onnx.Name, mode: inference.BackendModeEmbedding, expected: true},
+		{backend: onnx.Name, mode: inference.BackendModeClassification, expected: true},
+		{backend: onnx.Name, mode: inference.BackendModeCompletion, expected: false},
+	}
+	for _, tt := range tests {
+		if supportsMode(tt.backend, tt.mode) != tt.expected {
+			t.Errorf("Expected supportsMode(%s, %s) to be %t", tt.backend, tt.mode, tt.expected)
+		}
+	}
+}
+
+func TestDefaultMode(t *testing.T) {
+	tests := []struct {
+		backend  string
+		mode     inference.BackendMode
+		expected inference.BackendMode
+	}{
+		{backend: llamacpp.Name, mode: inference.BackendModeEmbedding, expected: inference.BackendModeEmbedding},
+		{backend: whispercpp.Name, mode: inference.BackendModeCompletion, expected: inference.BackendModeTranscription},
+		{backend: onnx.Name, mode: inference.BackendModeCompletion, expected: inference.BackendModeEmbedding},
+		{backend: onnx.Name, mode: inference.BackendModeClassification, expected: inference.BackendModeClassification},
+	}
+	for _, tt := range tests {
+		if mode := defaultMode(tt.backend, tt.mode); mode != tt.expected {
+			t.Errorf("Expected defaultMode(%s, %s) to be %s, got %s", tt.backend, tt.mode, tt.expected, mode)
+		}
+	}
+}
+
+func TestGetLoaderStatus(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(log, map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, systemMemoryInfo{})
+
+	// A runner serving one request with another one queued, split across two
+	// GPUs.
+	r := createAliveTerminableMockRunner(log, backend)
+	r.queue = newRequestQueue(1)
+	r.queue.active = 1
+	r.queue.waiting[priorityNormal] = []chan struct{}{make(chan struct{})}
+	loaded := time.Now().Add(-time.Minute)
+	s.loader.slots[0] = r
+	s.loader.runners[makeRunnerKey("test-backend", r.model, "", r.mode)] = runnerInfo{
+		slot:         0,
+		modelRef:     "modelX:latest",
+		loaded:       loaded,
+		loadDuration: 5 * time.Second,
+	}
+	s.loader.references[0] = 2
+	s.loader.allocations[0] = inference.RequiredMemory{RAM: 1 * GB, VRAM: 1}
+	s.loader.gpuAllocations[0] = []uint64{2 * GB, 0, 1 * GB}
+
+	statuses := s.getLoaderStatus(context.Background(), false)
+	if len(statuses) != 1 {
+		t.Fatalf("Expected 1 runner, got %d", len(statuses))
+	}
+	status := statuses[0]
+	if status.RAMBytes != 1*GB || status.VRAMBytes != 0 {
+		t.Errorf("Expected 1 GB of RAM and unknown VRAM, got %d and %d", status.RAMBytes, status.VRAMBytes)
+	}
+	if len(status.GPUs) != 2 || status.GPUs[0] != 0 || status.GPUs[1] != 2 {
+		t.Errorf("Expected GPUs 0 and 2, got %v", status.GPUs)
+	}
+	if !status.LoadedAt.Equal(loaded) || status.LoadSeconds != 5 || status.UptimeSeconds < 60 {
+		t.Errorf("Unexpected load time %v, load duration %v and uptime %v", status.LoadedAt, status.LoadSeconds, status.UptimeSeconds)
+	}
+	if status.InFlight != 1 || status.Queued != 1 || !status.InUse {
+		t.Errorf("Expected 1 in-flight and 1 queued request, got %d and %d", status.InFlight, status.Queued)
+	}
+}
+
+// TestInferenceRoutesScope checks that every route of the OpenAI-compatible
+// API is granted to the inference scope, and that no other route is.
+func TestInferenceRoutesScope(t *testing.T) {
+	discard := logrus.New()
+	discard.SetOutput(io.Discard)
+	s := NewScheduler(logrus.NewEntry(discard), nil, nil, nil, nil, nil, nil, systemMemoryInfo{})
+
+	keys, err := middleware.ParseAPIKeys(strings.NewReader("sk-ci inference\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	handler := middleware.AuthMiddleware(keys, s.InferenceRoutes(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	wildcards := strings.NewReplacer("{backend}", "llama.cpp", "{id}", "batch_1", "{name...}", "ai/smollm2",
+		"{nameAndAction...}", "ai/smollm2/load", "{$}", "")
+
+	for route := range s.routeHandlers() {
+		method, pattern, _ := strings.Cut(route, " ")
+		path := wildcards.Replace(pattern)
+		want := http.StatusForbidden
+		if strings.Contains(pattern, "/v1/") {
+			want = http.StatusOK
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer sk-ci")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", route, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package scheduling
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SessionHeader is the header with which clients identify the conversation an
+// inference request belongs to, so that its requests are routed to the same
+// runner replica and reuse its prompt cache.
+const SessionHeader = "X-DMR-Session"
+
+// maximumSessions is the maximum number of sessions whose replica is
+// remembered. The least recently used session is forgotten beyond it.
+const maximumSessions = 1024
+
+// sessionAffinity is the replica that serves the requests of a session.
+type sessionAffinity struct {
+	// key is the key of the replica.
+	key runnerKey
+	// lastUsed is the time of the session's most recent request.
+	lastUsed time.Time
+}
+
+// sessionForRequest returns the session of an inference request, which is
+// set with SessionHeader or, failing that, with the user field of OpenAI
+// requests. It's empty if the request doesn't belong to a session.
+func sessionForRequest(r *http.Request, request OpenAIInferenceRequest) string {
+	if session := strings.TrimSpace(r.Header.Get(SessionHeader)); session != "" {
+		return session
+	}
+	return request.User
+}
+
+// sessionReplica returns the key of the replica that served the previous
+// request of a session to the runner with the given key, regardless of its
+// replica, if it's still loaded. The caller must hold the loader lock.
+func (l *loader) sessionReplica(session string, key runnerKey) (runnerKey, bool) {
+	affinity, ok := l.sessions[session]
+	if !ok {
+		return runnerKey{}, false
+	}
+	replica := key
+	replica.replica = affinity.key.replica
+	if affinity.key != replica {
+		return runnerKey{}, false
+	}
+	if _, ok := l.runners[replica]; !ok {
+		return runnerKey{}, false
+	}
+	return replica, true
+}
+
+// recordSession records the replica serving a request of a session, if any.
+// The caller must hold the loader lock.
+func (l *loader) recordSession(session string, key runnerKey) {
+	if session == "" {
+		return
+	}
+	if _, ok := l.sessions[session]; !ok && len(l.sessions) >= maximumSessions {
+		var oldest string
+		for s, affinity := range l.sessions {
+			if oldest == "" || affinity.lastUsed.Before(l.sessions[oldest].lastUsed) {
+				oldest = s
+			}
+		}
+		delete(l.sessions, oldest)
+	}
+	l.sessions[session] = sessionAffinity{key: key, lastUsed: time.Now()}
+}
+
+// forgetSessions forgets the sessions served by the replica with the given
+// key. The caller must hold the loader lock.
+func (l *loader) forgetSessions(key runnerKey) {
+	for session, affinity := range l.sessions {
+		if affinity.key == key {
+			delete(l.sessions, session)
+		}
+	}
+}
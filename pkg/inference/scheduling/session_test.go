@@ -0,0 +1,67 @@
+package scheduling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestSessionForRequest(t *testing.T) {
+	request := OpenAIInferenceRequest{Model: "ai/smollm2", User: "user-1"}
+
+	r := httptest.NewRequest(http.MethodPost, "/engines/v1/chat/completions", nil)
+	if session := sessionForRequest(r, request); session != "user-1" {
+		t.Errorf("Expected the user to be the session, got %q", session)
+	}
+	r.Header.Set(SessionHeader, " chat-1 ")
+	if session := sessionForRequest(r, request); session != "chat-1" {
+		t.Errorf("Expected the header to take precedence, got %q", session)
+	}
+	r.Header.Del(SessionHeader)
+	if session := sessionForRequest(r, OpenAIInferenceRequest{Model: "ai/smollm2"}); session != "" {
+		t.Errorf("Expected no session, got %q", session)
+	}
+}
+
+func TestLoaderRoutesSessionsToTheirReplica(t *testing.T) {
+	loader := newReplicaTestLoader(t, 2)
+	load := func(session string) *runner {
+		r, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, session)
+		if err != nil {
+			t.Fatalf("Failed to load runner: %v", err)
+		}
+		return r
+	}
+
+	a := load("a")
+	b := load("b")
+	if a == b {
+		t.Fatal("Expected a second replica to be loaded while the first is busy")
+	}
+	loader.release(a)
+
+	// Sessions stay with their replica, even if it's busier than another.
+	for i := 0; i < 2; i++ {
+		if r := load("b"); r != b {
+			t.Fatal("Expected session b to be routed to its replica")
+		}
+	}
+	if r := load("a"); r != a {
+		t.Error("Expected session a to be routed to its replica")
+	}
+	loader.release(a)
+	for i := 0; i < 3; i++ {
+		loader.release(b)
+	}
+
+	// Evicted replicas are forgotten by their sessions.
+	loader.Unload(context.Background(), UnloadRequest{All: true})
+	loader.lock(context.Background())
+	defer loader.unlock()
+	if len(loader.sessions) != 0 {
+		t.Errorf("Expected sessions to be forgotten, got %v", loader.sessions)
+	}
+}
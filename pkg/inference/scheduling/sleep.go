@@ -0,0 +1,78 @@
+package scheduling
+
+import (
+	"time"
+)
+
+// Sleeper is implemented by background components, such as pollers, that
+// should be paused while the loader is in deep sleep. Sleep and Wake are
+// invoked with the loader lock held and must not block.
+type Sleeper interface {
+	// Sleep pauses the component.
+	Sleep()
+	// Wake resumes the component.
+	Wake()
+}
+
+// deepSleepDuration computes the duration until the loader should enter deep
+// sleep. The caller must hold the loader lock. If deep sleep is disabled, the
+// loader is already asleep, or any runner is in use, then -1 seconds is
+// returned. If the idle period has already elapsed, then 0 seconds is returned.
+func (l *loader) deepSleepDuration() time.Duration {
+	if l.deepSleepTimeout <= 0 || l.asleep {
+		return -1 * time.Second
+	}
+	for _, runnerInfo := range l.runners {
+		if l.references[runnerInfo.slot] > 0 {
+			return -1 * time.Second
+		}
+	}
+	if remaining := l.deepSleepTimeout - time.Since(l.lastActive); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// sleep evicts all runners, releasing the memory (including GPU contexts) held
+// by them, and pauses registered sleepers. The caller must hold the loader
+// lock.
+func (l *loader) sleep() {
+	l.log.Infof("No activity for %s, entering deep sleep", l.deepSleepTimeout)
+	l.evict(false)
+	l.asleep = true
+	for _, s := range l.sleepers {
+		s.Sleep()
+	}
+}
+
+// wake records activity and, if the loader is in deep sleep, resumes
+// registered sleepers and signals the run loop to schedule the next deep
+// sleep. The caller must hold the loader lock.
+func (l *loader) wake() {
+	l.lastActive = time.Now()
+	if !l.asleep {
+		return
+	}
+	l.log.Infoln("Waking from deep sleep")
+	l.asleep = false
+	for _, s := range l.sleepers {
+		s.Wake()
+	}
+	select {
+	case l.idleCheck <- struct{}{}:
+	default:
+	}
+}
+
+// SetDeepSleepTimeout sets the global idle period after which all runners are
+// evicted and registered sleepers are paused until the next inference request.
+// A zero timeout disables deep sleep. It must be called before Run.
+func (s *Scheduler) SetDeepSleepTimeout(timeout time.Duration) {
+	s.loader.deepSleepTimeout = timeout
+}
+
+// RegisterSleeper registers a component to be paused while the scheduler is in
+// deep sleep. It must be called before Run.
+func (s *Scheduler) RegisterSleeper(sleeper Sleeper) {
+	s.loader.sleepers = append(s.loader.sleepers, sleeper)
+}
@@ -0,0 +1,137 @@
+package scheduling
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// countingSleeper counts calls to Sleep and Wake.
+type countingSleeper struct {
+	sleeps, wakes int
+}
+
+func (s *countingSleeper) Sleep() { s.sleeps++ }
+func (s *countingSleeper) Wake()  { s.wakes++ }
+
+func TestDeepSleepDuration(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, &mockSystemMemoryInfo{})
+
+	// Disabled by default.
+	if d := loader.deepSleepDuration(); d >= 0 {
+		t.Errorf("Expected deep sleep to be disabled, got %s", d)
+	}
+
+	loader.deepSleepTimeout = time.Minute
+	loader.lastActive = time.Now()
+	if d := loader.deepSleepDuration(); d <= 0 || d > time.Minute {
+		t.Errorf("Expected deep sleep within a minute, got %s", d)
+	}
+
+	loader.lastActive = time.Now().Add(-2 * time.Minute)
+	if d := loader.deepSleepDuration(); d != 0 {
+		t.Errorf("Expected immediate deep sleep, got %s", d)
+	}
+
+	// A runner in use prevents deep sleep.
+	loader.slots[0] = createAliveTerminableMockRunner(log, backend)
+	loader.runners[makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)] = runnerInfo{slot: 0, modelRef: "model1:latest"}
+	loader.references[0] = 1
+	if d := loader.deepSleepDuration(); d >= 0 {
+		t.Errorf("Expected no deep sleep while a runner is in use, got %s", d)
+	}
+}
+
+func TestDeepSleep(t *testing.T) {
+	log := createTestLogger()
+	backend := &fastFailBackend{mockBackend: mockBackend{name: "test-backend"}}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, &mockSystemMemoryInfo{})
+	loader.deepSleepTimeout = 50 * time.Millisecond
+	sleeper := &countingSleeper{}
+	loader.sleepers = append(loader.sleepers, sleeper)
+
+	// Install an unused runner that should be evicted on deep sleep.
+	loader.slots[0] = createAliveTerminableMockRunner(log, backend)
+	loader.runners[makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)] = runnerInfo{slot: 0, modelRef: "model1:latest"}
+	loader.timestamps[0] = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		loader.run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	asleep := func() bool {
+		loader.lock(context.Background())
+		defer loader.unlock()
+		return loader.asleep
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !asleep() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for deep sleep")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	loader.lock(context.Background())
+	if len(loader.runners) != 0 {
+		t.Errorf("Expected all runners to be evicted, %d remaining", len(loader.runners))
+	}
+	if sleeper.sleeps != 1 || sleeper.wakes != 0 {
+		t.Errorf("Expected 1 sleep and 0 wakes, got %d and %d", sleeper.sleeps, sleeper.wakes)
+	}
+	loader.unlock()
+
+	// The next load wakes the loader, even though the runner fails to start.
+	if _, err := loader.load(context.Background(), "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, ""); err == nil {
+		t.Error("Expected load to fail with fastFail backend")
+	}
+	loader.lock(context.Background())
+	if loader.asleep || sleeper.wakes != 1 {
+		t.Errorf("Expected loader to wake, asleep=%v, wakes=%d", loader.asleep, sleeper.wakes)
+	}
+	loader.unlock()
+
+	// And it goes back to sleep after another idle period.
+	deadline = time.Now().Add(5 * time.Second)
+	for !asleep() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for second deep sleep")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVRAMPollerSleeps(t *testing.T) {
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 16 * GB},
+		gpuMemory:   []uint64{16 * GB},
+		freeMemory:  []uint64{16 * GB},
+	}
+	backend := &mockBackend{name: "test-backend"}
+	s := NewScheduler(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, backend, nil, nil, nil, nil, sysMemInfo)
+	poller := s.loader.vramPoller
+	if poller == nil || !slices.Contains(s.loader.sleepers, Sleeper(poller)) {
+		t.Fatal("Expected the VRAM poller to be registered as a sleeper")
+	}
+
+	// Polling stops during deep sleep.
+	poller.ticker.Reset(time.Millisecond)
+	poller.Sleep()
+	select {
+	case <-poller.ticker.C:
+		t.Fatal("Expected no polls during deep sleep")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
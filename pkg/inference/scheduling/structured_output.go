@@ -0,0 +1,76 @@
+package scheduling
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// ErrStructuredOutputUnsupported indicates that a chat completion requested a
+// JSON schema response format from a backend that can't constrain its output.
+var ErrStructuredOutputUnsupported = errors.New("json_schema response format is not supported by backend")
+
+// chatResponseFormat is the response_format of a chat completion request.
+type chatResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+// jsonSchemaForRequest returns the JSON schema requested by a chat completion
+// request with a json_schema response_format, or nil if there is none.
+func jsonSchemaForRequest(body []byte) (json.RawMessage, error) {
+	var request struct {
+		ResponseFormat *chatResponseFormat `json:"response_format"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("invalid response_format: %w", err)
+	}
+	if request.ResponseFormat == nil || request.ResponseFormat.Type != "json_schema" {
+		return nil, nil
+	}
+	if request.ResponseFormat.JSONSchema == nil || len(request.ResponseFormat.JSONSchema.Schema) == 0 {
+		return nil, errors.New("response_format.json_schema.schema is required")
+	}
+	schema := bytes.TrimSpace(request.ResponseFormat.JSONSchema.Schema)
+	if schema[0] != '{' {
+		return nil, errors.New("response_format.json_schema.schema must be an object")
+	}
+	return schema, nil
+}
+
+// applyResponseFormat translates a json_schema response_format of a chat
+// completion request into the backend's native constraint. Requests without a
+// JSON schema are returned unchanged.
+func applyResponseFormat(backend inference.Backend, body []byte) ([]byte, error) {
+	schema, err := jsonSchemaForRequest(body)
+	if err != nil || schema == nil {
+		return body, err
+	}
+	constrainer, ok := backend.(inference.JSONSchemaConstrainer)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrStructuredOutputUnsupported, backend.Name())
+	}
+	return constrainer.ConstrainToJSONSchema(body, schema)
+}
+
+// writeOpenAIError writes an error in the format of the OpenAI API, so that
+// OpenAI clients can surface it, for errors concerning a request parameter.
+func writeOpenAIError(w http.ResponseWriter, status int, param string, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"message": err.Error(),
+			"type":    "invalid_request_error",
+			"param":   param,
+			"code":    nil,
+		},
+	})
+}
@@ -0,0 +1,116 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// constrainingBackend is a backend that supports JSON schema constraints.
+type constrainingBackend struct {
+	mockBackend
+}
+
+func (b *constrainingBackend) ConstrainToJSONSchema(_ []byte, schema json.RawMessage) ([]byte, error) {
+	return append([]byte("constrained:"), schema...), nil
+}
+
+func TestApplyResponseFormat(t *testing.T) {
+	constraining := &constrainingBackend{mockBackend{name: "constraining"}}
+	plain := &mockBackend{name: "plain"}
+
+	tests := []struct {
+		name        string
+		backend     inference.Backend
+		body        string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "no response format",
+			backend:  plain,
+			body:     `{"model": "ai/model"}`,
+			expected: `{"model": "ai/model"}`,
+		},
+		{
+			name:     "json object response format",
+			backend:  plain,
+			body:     `{"response_format": {"type": "json_object"}}`,
+			expected: `{"response_format": {"type": "json_object"}}`,
+		},
+		{
+			name:     "json schema",
+			backend:  constraining,
+			body:     `{"response_format": {"type": "json_schema", "json_schema": {"name": "x", "schema": {"type": "object"}}}}`,
+			expected: `constrained:{"type": "object"}`,
+		},
+		{
+			name:        "unsupported backend",
+			backend:     plain,
+			body:        `{"response_format": {"type": "json_schema", "json_schema": {"schema": {"type": "object"}}}}`,
+			expectedErr: ErrStructuredOutputUnsupported,
+		},
+		{
+			name:        "missing schema",
+			backend:     constraining,
+			body:        `{"response_format": {"type": "json_schema", "json_schema": {"name": "x"}}}`,
+			expectedErr: errors.New("response_format.json_schema.schema is required"),
+		},
+		{
+			name:        "schema isn't an object",
+			backend:     constraining,
+			body:        `{"response_format": {"type": "json_schema", "json_schema": {"schema": "object"}}}`,
+			expectedErr: errors.New("response_format.json_schema.schema must be an object"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := applyResponseFormat(tt.backend, []byte(tt.body))
+			if tt.expectedErr != nil {
+				if err == nil || (!errors.Is(err, tt.expectedErr) && err.Error() != tt.expectedErr.Error()) {
+					t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyResponseFormat failed: %v", err)
+			}
+			if string(body) != tt.expected {
+				t.Errorf("Expected body %s, got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestStructuredOutputRejected(t *testing.T) {
+	plain := &mockBackend{name: "plain", usesExternalModelMgmt: true}
+	backends := map[string]inference.Backend{"plain": plain}
+	s := NewScheduler(createTestLogger(), backends, plain, nil, nil, nil, nil, systemMemoryInfo{})
+
+	req := httptest.NewRequest(http.MethodPost, inference.InferencePrefix+"/v1/chat/completions", strings.NewReader(
+		`{"model": "ai/model", "response_format": {"type": "json_schema", "json_schema": {"schema": {"type": "object"}}}}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Error struct {
+			Type  string `json:"type"`
+			Param string `json:"param"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected an OpenAI error, got %s", w.Body.String())
+	}
+	if response.Error.Type != "invalid_request_error" || response.Error.Param != "response_format" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
+}
@@ -0,0 +1,76 @@
+package scheduling
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+
+	"github.com/docker/model-runner/pkg/inference/models"
+)
+
+// errInvalidTrafficSplit indicates that a traffic split is invalid.
+var errInvalidTrafficSplit = errors.New("invalid traffic split")
+
+// trafficSplits are the traffic splits configured for models, keyed by the
+// normalized name of the model whose requests they split. Since aliases are
+// kept under normalized names, the requests for an alias can be split too.
+type trafficSplits struct {
+	// lock protects splits.
+	lock sync.Mutex
+	// splits are the traffic splits by model.
+	splits map[string]TrafficSplit
+}
+
+// set sets the traffic split of a model, removing it if its percentage is
+// zero.
+func (t *trafficSplits) set(model string, split TrafficSplit) error {
+	model = models.NormalizeModelName(model)
+	split.Model = models.NormalizeModelName(split.Model)
+	if split.Percent < 0 || split.Percent > 100 {
+		return fmt.Errorf("%w: percent must be between 0 and 100", errInvalidTrafficSplit)
+	}
+	if split.Percent > 0 && split.Model == model {
+		return fmt.Errorf("%w: a model can't split its traffic with itself", errInvalidTrafficSplit)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if split.Percent == 0 {
+		delete(t.splits, model)
+		return nil
+	}
+	if t.splits == nil {
+		t.splits = make(map[string]TrafficSplit)
+	}
+	t.splits[model] = split
+	return nil
+}
+
+// route returns the model that serves a request for model, which is the
+// model of its traffic split for the configured percentage of requests. It
+// reports whether model has a traffic split.
+func (t *trafficSplits) route(model string) (string, bool) {
+	t.lock.Lock()
+	split, ok := t.splits[models.NormalizeModelName(model)]
+	t.lock.Unlock()
+	if !ok {
+		return model, false
+	}
+	if rand.IntN(100) < split.Percent {
+		return split.Model, true
+	}
+	return model, true
+}
+
+// onlyTrafficSplit reports whether a configure request only sets the traffic
+// split of its model, leaving its runner configuration unchanged.
+func onlyTrafficSplit(request ConfigureRequest) bool {
+	if request.TrafficSplit == nil {
+		return false
+	}
+	request.Model = ""
+	request.TrafficSplit = nil
+	return reflect.DeepEqual(request, ConfigureRequest{ContextSize: -1})
+}
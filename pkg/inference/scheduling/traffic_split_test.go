@@ -0,0 +1,60 @@
+package scheduling
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrafficSplits(t *testing.T) {
+	var splits trafficSplits
+	if err := splits.set("llama3.2", TrafficSplit{Model: "llama3.2:Q8_0", Percent: 100}); err != nil {
+		t.Fatalf("Failed to set traffic split: %v", err)
+	}
+
+	// Requests are routed by normalized name.
+	if routed, ok := splits.route("ai/llama3.2:latest"); !ok || routed != "ai/llama3.2:Q8_0" {
+		t.Errorf("Expected requests to be routed to ai/llama3.2:Q8_0, got %s (%v)", routed, ok)
+	}
+	if routed, ok := splits.route("ai/smollm2"); ok || routed != "ai/smollm2" {
+		t.Errorf("Expected requests for other models to be left as is, got %s (%v)", routed, ok)
+	}
+
+	// A percentage of zero removes the split.
+	if err := splits.set("llama3.2", TrafficSplit{}); err != nil {
+		t.Fatalf("Failed to remove traffic split: %v", err)
+	}
+	if routed, ok := splits.route("llama3.2"); ok || routed != "llama3.2" {
+		t.Errorf("Expected the traffic split to be removed, got %s (%v)", routed, ok)
+	}
+
+	for _, split := range []TrafficSplit{
+		{Model: "ai/llama3.2:Q8_0", Percent: 101},
+		{Model: "ai/llama3.2:Q8_0", Percent: -1},
+		{Model: "llama3.2", Percent: 10},
+	} {
+		if err := splits.set("ai/llama3.2", split); !errors.Is(err, errInvalidTrafficSplit) {
+			t.Errorf("Expected errInvalidTrafficSplit for %+v, got %v", split, err)
+		}
+	}
+}
+
+func TestOnlyTrafficSplit(t *testing.T) {
+	split := &TrafficSplit{Model: "ai/llama3.2:Q8_0", Percent: 10}
+	tests := []struct {
+		name     string
+		request  ConfigureRequest
+		expected bool
+	}{
+		{name: "traffic split", request: ConfigureRequest{Model: "ai/llama3.2", ContextSize: -1, TrafficSplit: split}, expected: true},
+		{name: "with context size", request: ConfigureRequest{Model: "ai/llama3.2", ContextSize: 8192, TrafficSplit: split}, expected: false},
+		{name: "with runtime flags", request: ConfigureRequest{Model: "ai/llama3.2", ContextSize: -1, RuntimeFlags: []string{"--jinja"}, TrafficSplit: split}, expected: false},
+		{name: "no traffic split", request: ConfigureRequest{Model: "ai/llama3.2", ContextSize: -1}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onlyTrafficSplit(tt.request); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package scheduling
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+)
+
+// maximumTranscriptionFieldSize is the maximum size of a non-file field in a
+// transcription request.
+const maximumTranscriptionFieldSize = 64 * 1024
+
+// transcriptionFile summarizes the audio file uploaded in a transcription
+// request.
+type transcriptionFile struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// decodeTranscriptionRequest extracts the model specification from an OpenAI
+// API transcription request, which is a multipart/form-data upload. It also
+// returns a JSON summary of the form for the OpenAI recorder, in which the
+// audio file is replaced by its name and size.
+func decodeTranscriptionRequest(contentType string, body []byte) (OpenAIInferenceRequest, []byte, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return OpenAIInferenceRequest{}, nil, errors.New("expected a multipart/form-data request")
+	}
+
+	var request OpenAIInferenceRequest
+	summary := make(map[string]any)
+	hasFile := false
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return OpenAIInferenceRequest{}, nil, fmt.Errorf("reading form: %w", err)
+		}
+		name := part.FormName()
+		if part.FileName() != "" {
+			size, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return OpenAIInferenceRequest{}, nil, fmt.Errorf("reading form file %q: %w", name, err)
+			}
+			summary[name] = transcriptionFile{Filename: part.FileName(), Size: size}
+			hasFile = hasFile || name == "file"
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maximumTranscriptionFieldSize+1))
+		if err != nil {
+			return OpenAIInferenceRequest{}, nil, fmt.Errorf("reading form field %q: %w", name, err)
+		}
+		if len(value) > maximumTranscriptionFieldSize {
+			return OpenAIInferenceRequest{}, nil, fmt.Errorf("form field %q too large", name)
+		}
+		if name == "model" {
+			request.Model = string(value)
+		}
+		// Fields such as timestamp_granularities[] may be repeated.
+		switch previous := summary[name].(type) {
+		case string:
+			summary[name] = []string{previous, string(value)}
+		case []string:
+			summary[name] = append(previous, string(value))
+		default:
+			summary[name] = string(value)
+		}
+	}
+	if !hasFile {
+		return OpenAIInferenceRequest{}, nil, errors.New("file is required")
+	}
+
+	recorded, err := json.Marshal(summary)
+	if err != nil {
+		return OpenAIInferenceRequest{}, nil, fmt.Errorf("summarizing form: %w", err)
+	}
+	return request, recorded, nil
+}
@@ -0,0 +1,82 @@
+package scheduling
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+	"testing"
+)
+
+func newTranscriptionForm(t *testing.T, fields [][2]string, file []byte) (string, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			t.Fatalf("WriteField failed: %v", err)
+		}
+	}
+	if file != nil {
+		part, err := writer.CreateFormFile("file", "speech.wav")
+		if err != nil {
+			t.Fatalf("CreateFormFile failed: %v", err)
+		}
+		part.Write(file)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return writer.FormDataContentType(), body.Bytes()
+}
+
+func TestDecodeTranscriptionRequest(t *testing.T) {
+	contentType, body := newTranscriptionForm(t, [][2]string{
+		{"model", "ai/whisper"},
+		{"timestamp_granularities[]", "word"},
+		{"timestamp_granularities[]", "segment"},
+	}, []byte("RIFF audio"))
+
+	request, recorded, err := decodeTranscriptionRequest(contentType, body)
+	if err != nil {
+		t.Fatalf("decodeTranscriptionRequest failed: %v", err)
+	}
+	if request.Model != "ai/whisper" {
+		t.Errorf("Expected model ai/whisper, got %q", request.Model)
+	}
+
+	var summary map[string]any
+	if err := json.Unmarshal(recorded, &summary); err != nil {
+		t.Fatalf("Recorded body isn't JSON: %v", err)
+	}
+	expected := map[string]any{
+		"model":                     "ai/whisper",
+		"timestamp_granularities[]": []any{"word", "segment"},
+		"file":                      map[string]any{"filename": "speech.wav", "size": float64(10)},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Recorded body = %v, want %v", summary, expected)
+	}
+}
+
+func TestDecodeTranscriptionRequestErrors(t *testing.T) {
+	withoutFile, withoutFileBody := newTranscriptionForm(t, [][2]string{{"model", "ai/whisper"}}, nil)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{name: "JSON body", contentType: "application/json", body: []byte(`{"model": "ai/whisper"}`)},
+		{name: "missing boundary", contentType: "multipart/form-data", body: withoutFileBody},
+		{name: "missing file", contentType: withoutFile, body: withoutFileBody},
+		{name: "truncated form", contentType: withoutFile, body: withoutFileBody[:len(withoutFileBody)/2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeTranscriptionRequest(tt.contentType, tt.body); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
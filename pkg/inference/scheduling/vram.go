@@ -0,0 +1,144 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// vramPollInterval is the interval at which the free VRAM is polled.
+const vramPollInterval = 10 * time.Second
+
+// errInsufficientVRAM indicates that a model doesn't fit into the VRAM left
+// by other processes, even with no runners loaded.
+var errInsufficientVRAM = errors.New("insufficient VRAM: in use by other processes")
+
+// freeVRAM returns the portion of the available VRAM that isn't used by other
+// processes. The caller must hold the loader lock.
+func (l *loader) freeVRAM() uint64 {
+	return l.availableMemory.VRAM - min(l.externalVRAM, l.availableMemory.VRAM)
+}
+
+// freeGPUMemory returns the portion of the available VRAM of each GPU that
+// isn't used by other processes. The caller must hold the loader lock.
+func (l *loader) freeGPUMemory() []uint64 {
+	if l.externalGPUMemory == nil {
+		return l.availableGPUMemory
+	}
+	free := make([]uint64, len(l.availableGPUMemory))
+	for gpu, available := range l.availableGPUMemory {
+		free[gpu] = available - min(l.externalGPUMemory[gpu], available)
+	}
+	return free
+}
+
+// updateExternalVRAM updates the VRAM used by other processes, such as games or
+// other inference stacks, from the free VRAM of each GPU. It's the VRAM in use
+// beyond what's allocated to runners. Waiting loads are signalled if it
+// changes. The caller must hold the loader lock.
+func (l *loader) updateExternalVRAM(free []uint64) {
+	if l.totalMemory.VRAM <= 1 {
+		return
+	}
+	var externalVRAM uint64
+	var externalGPUMemory []uint64
+	if l.gpuMemory != nil {
+		if len(free) != len(l.gpuMemory) {
+			return
+		}
+		externalGPUMemory = make([]uint64, len(free))
+		for gpu := range free {
+			used := l.gpuMemory[gpu] - min(free[gpu], l.gpuMemory[gpu])
+			allocated := l.gpuMemory[gpu] - l.availableGPUMemory[gpu]
+			externalGPUMemory[gpu] = used - min(allocated, used)
+			externalVRAM += externalGPUMemory[gpu]
+		}
+	} else {
+		var freeVRAM uint64
+		for _, vram := range free {
+			freeVRAM += vram
+		}
+		used := l.totalMemory.VRAM - min(freeVRAM, l.totalMemory.VRAM)
+		allocated := l.totalMemory.VRAM - l.availableMemory.VRAM
+		externalVRAM = used - min(allocated, used)
+	}
+
+	if externalVRAM == l.externalVRAM && slices.Equal(externalGPUMemory, l.externalGPUMemory) {
+		return
+	}
+	if externalVRAM != l.externalVRAM {
+		l.log.Infof("Other processes are using %s VRAM", formatMemorySize(externalVRAM))
+	}
+	l.externalVRAM = externalVRAM
+	l.externalGPUMemory = externalGPUMemory
+	l.broadcast()
+}
+
+// vramPoller polls the free VRAM of a loader, so that VRAM taken or released
+// by other processes is accounted for by loads. It's registered as a Sleeper,
+// so that polling pauses during deep sleep.
+type vramPoller struct {
+	// loader is the loader whose VRAM is polled.
+	loader *loader
+	// ticker drives the polls. It's stopped until the poller runs and while
+	// the loader is in deep sleep.
+	ticker *time.Ticker
+}
+
+// newVRAMPoller creates a poller of the free VRAM of a loader.
+func newVRAMPoller(l *loader) *vramPoller {
+	ticker := time.NewTicker(vramPollInterval)
+	ticker.Stop()
+	return &vramPoller{loader: l, ticker: ticker}
+}
+
+// Sleep implements Sleeper.Sleep.
+func (p *vramPoller) Sleep() {
+	p.ticker.Stop()
+}
+
+// Wake implements Sleeper.Wake.
+func (p *vramPoller) Wake() {
+	p.ticker.Reset(vramPollInterval)
+}
+
+// run polls the free VRAM until ctx is cancelled.
+func (p *vramPoller) run(ctx context.Context) {
+	l := p.loader
+	if !l.lock(ctx) {
+		return
+	}
+	if !l.asleep {
+		p.ticker.Reset(vramPollInterval)
+	}
+	l.unlock()
+	defer p.ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.ticker.C:
+		}
+
+		// Query the free VRAM without holding the lock, as it may take a
+		// while, and discard it if runners were loaded or evicted meanwhile.
+		if !l.lock(ctx) {
+			return
+		}
+		generation := l.allocationGeneration
+		l.unlock()
+		free := l.sysMemInfo.GetFreeGPUMemory()
+		if free == nil {
+			continue
+		}
+		if !l.lock(ctx) {
+			return
+		}
+		if l.allocationGeneration == generation {
+			l.updateExternalVRAM(free)
+		}
+		l.unlock()
+	}
+}
@@ -0,0 +1,98 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+func TestUpdateExternalVRAM(t *testing.T) {
+	log := createTestLogger()
+	backend := &mockBackend{name: "test-backend"}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 16 * GB},
+		gpuMemory:   []uint64{8 * GB, 8 * GB},
+	}
+	loader := newLoader(log, map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	if loader.externalVRAM != 0 {
+		t.Fatalf("Expected no external VRAM without free memory information, got %s", formatMemorySize(loader.externalVRAM))
+	}
+
+	// Load a runner on GPU 0.
+	slot := 0
+	loader.slots[slot] = createAliveTerminableMockRunner(log, backend)
+	loader.runners[makeRunnerKey("test-backend", "model1", "", inference.BackendModeCompletion)] = runnerInfo{slot: slot, modelRef: "model1:latest"}
+	loader.allocations[slot] = inference.RequiredMemory{RAM: 1 * GB, VRAM: 4 * GB}
+	loader.availableMemory.VRAM -= 4 * GB
+	loader.availableGPUMemory[0] -= 4 * GB
+	loader.gpuAllocations[slot] = []uint64{4 * GB, 0}
+
+	// VRAM used by the runner isn't external, but the VRAM used beyond it is.
+	loader.updateExternalVRAM([]uint64{3 * GB, 6 * GB})
+	if loader.externalVRAM != 3*GB {
+		t.Errorf("Expected 3 GB of external VRAM, got %s", formatMemorySize(loader.externalVRAM))
+	}
+	if !slices.Equal(loader.externalGPUMemory, []uint64{1 * GB, 2 * GB}) {
+		t.Errorf("Expected external GPU memory [1 GB, 2 GB], got %v", loader.externalGPUMemory)
+	}
+	if free := loader.freeGPUMemory(); !slices.Equal(free, []uint64{3 * GB, 6 * GB}) {
+		t.Errorf("Expected free GPU memory [3 GB, 6 GB], got %v", free)
+	}
+	if loader.freeVRAM() != 9*GB {
+		t.Errorf("Expected 9 GB of free VRAM, got %s", formatMemorySize(loader.freeVRAM()))
+	}
+
+	// VRAM released by other processes is available again.
+	loader.updateExternalVRAM([]uint64{4 * GB, 8 * GB})
+	if loader.externalVRAM != 0 || loader.freeVRAM() != 12*GB {
+		t.Errorf("Expected no external VRAM, got %s", formatMemorySize(loader.externalVRAM))
+	}
+
+	// Free memory for a different number of GPUs is ignored.
+	loader.updateExternalVRAM([]uint64{1 * GB})
+	if loader.externalVRAM != 0 {
+		t.Errorf("Expected mismatched free memory to be ignored, got %s", formatMemorySize(loader.externalVRAM))
+	}
+}
+
+func TestLoaderRefusesWhenVRAMInUse(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("VRAM is shared with RAM on Windows")
+	}
+	backend := &fastFailBackend{mockBackend: mockBackend{
+		name:           "test-backend",
+		requiredMemory: inference.RequiredMemory{RAM: 1 * GB, VRAM: 6 * GB},
+	}}
+	sysMemInfo := &mockSystemMemoryInfo{
+		totalMemory: inference.RequiredMemory{RAM: 32 * GB, VRAM: 8 * GB},
+		gpuMemory:   []uint64{8 * GB},
+		freeMemory:  []uint64{4 * GB},
+	}
+	loader := newLoader(createTestLogger(), map[string]inference.Backend{"test-backend": backend}, nil, nil, sysMemInfo)
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock")
+	}
+	loader.loadsEnabled = true
+	loader.unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := loader.load(ctx, "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, ""); !errors.Is(err, errInsufficientVRAM) {
+		t.Errorf("Expected errInsufficientVRAM, got %v", err)
+	}
+
+	// Once other processes release the VRAM, the load proceeds.
+	if !loader.lock(context.Background()) {
+		t.Fatal("Failed to acquire loader lock")
+	}
+	loader.updateExternalVRAM([]uint64{8 * GB})
+	loader.unlock()
+	if _, err := loader.load(ctx, "test-backend", "model1", "model1:latest", inference.BackendModeCompletion, ""); errors.Is(err, errInsufficientVRAM) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the load to proceed, got %v", err)
+	}
+}
@@ -167,8 +167,21 @@ func blobPath(dir string, digest digest.Digest) string {
 	return filepath.Join(dir, "blobs", digest.Algorithm().String(), digest.Hex())
 }
 
+// readBlob reads a blob, verifying that its content matches its digest.
 func readBlob(dir string, digest digest.Digest) ([]byte, error) {
-	return os.ReadFile(blobPath(dir, digest))
+	if err := digest.Validate(); err != nil {
+		return nil, err
+	}
+	bs, err := os.ReadFile(blobPath(dir, digest))
+	if err != nil {
+		return nil, err
+	}
+	verifier := digest.Verifier()
+	verifier.Write(bs)
+	if !verifier.Verified() {
+		return nil, fmt.Errorf("blob %s does not match its digest", digest.String())
+	}
+	return bs, nil
 }
 
 func extractFromOCI(dir string, digest digest.Digest, destination, OS, architecture string) error {
@@ -210,15 +223,31 @@ func extractLayer(dir string, layer v1.Descriptor, destination string) error {
 	if layer.MediaType != mediaTypeLayer && layer.MediaType != mediaTypeOCILayer {
 		return fmt.Errorf("expected layer %s to have media type %s or %s, received %s", layer.Digest.String(), mediaTypeLayer, mediaTypeOCILayer, layer.MediaType)
 	}
+	if err := layer.Digest.Validate(); err != nil {
+		return err
+	}
 	f, err := os.Open(blobPath(dir, layer.Digest))
 	if err != nil {
 		return fmt.Errorf("reading blob %s: %w", layer.Digest.String(), err)
 	}
 	defer f.Close()
-	gz, err := gzip.NewReader(f)
+
+	// Verify the layer against its digest as it's extracted.
+	verifier := layer.Digest.Verifier()
+	blob := io.TeeReader(f, verifier)
+	gz, err := gzip.NewReader(blob)
 	if err != nil {
 		return fmt.Errorf("decompressing %s: %w", layer.Digest.String(), err)
 	}
 	defer gz.Close()
-	return unTar(gz, destination)
+	if err := unTar(gz, destination); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, blob); err != nil {
+		return fmt.Errorf("reading blob %s: %w", layer.Digest.String(), err)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("blob %s does not match its digest", layer.Digest.String())
+	}
+	return nil
 }
@@ -0,0 +1,86 @@
+package dockerhub
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// writeLayer writes a gzipped tar layer containing a single file to the blobs
+// of dir, returning its descriptor.
+func writeLayer(t *testing.T, dir, name, content string) v1.Descriptor {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+
+	layer := v1.Descriptor{MediaType: mediaTypeOCILayer, Digest: digest.FromBytes(buf.Bytes()), Size: int64(buf.Len())}
+	path := blobPath(dir, layer.Digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return layer
+}
+
+func TestExtractLayerVerifiesDigest(t *testing.T) {
+	dir := t.TempDir()
+	layer := writeLayer(t, dir, "com.docker.llama-server", "server")
+
+	destination := t.TempDir()
+	if err := extractLayer(dir, layer, destination); err != nil {
+		t.Fatalf("extractLayer() error = %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(destination, "com.docker.llama-server")); err != nil || string(content) != "server" {
+		t.Errorf("Expected the extracted server, got %q (%v)", content, err)
+	}
+
+	// A layer that doesn't match its digest, such as one tampered with, is
+	// rejected.
+	tampered := writeLayer(t, dir, "com.docker.llama-server", "tampered")
+	if err := os.Rename(blobPath(dir, tampered.Digest), blobPath(dir, layer.Digest)); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractLayer(dir, layer, t.TempDir()); err == nil {
+		t.Error("Expected an error for a layer that doesn't match its digest")
+	}
+}
+
+func TestReadBlobVerifiesDigest(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(`{"schemaVersion": 2}`)
+	d := digest.FromBytes(content)
+	path := blobPath(dir, d)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readBlob(dir, d); err != nil {
+		t.Fatalf("readBlob() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"schemaVersion": 3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readBlob(dir, d); err == nil {
+		t.Error("Expected an error for a blob that doesn't match its digest")
+	}
+}
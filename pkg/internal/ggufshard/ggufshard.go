@@ -0,0 +1,39 @@
+// Package ggufshard resolves the shards of split GGUF models, so that models
+// are checked the same way when they're packaged and when they're run.
+package ggufshard
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	parser "github.com/gpustack/gguf-parser-go"
+)
+
+// Resolve returns the paths of every shard of the split GGUF model that path
+// belongs to, in shard order. Paths that don't follow the split naming scheme
+// are returned on their own. Every shard must be present next to the others,
+// as llama.cpp refuses to load a split model with a gap.
+func Resolve(path string) ([]string, error) {
+	shards := parser.CompleteShardGGUFFilename(path)
+	if len(shards) == 0 {
+		return []string{path}, nil
+	}
+	var missing []string
+	for _, shard := range shards {
+		if _, err := os.Stat(shard); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("stat GGUF shard %q: %w", shard, err)
+			}
+			missing = append(missing, filepath.Base(shard))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("split GGUF model is missing %d of %d shard(s): %s",
+			len(missing), len(shards), strings.Join(missing, ", "))
+	}
+	return shards, nil
+}
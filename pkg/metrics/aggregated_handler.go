@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,16 +40,16 @@ func (h *AggregatedMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Collect and aggregate metrics from all runners, alongside the native
+	// metrics of the scheduler.
 	runners := h.scheduler.GetAllActiveRunners()
-	if len(runners) == 0 {
-		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "# No active runners\n")
-		return
-	}
-
-	// Collect and aggregate metrics from all runners
 	allFamilies := h.collectAndAggregateMetrics(r.Context(), runners)
+	for name, family := range runnerMetricFamilies(runners) {
+		allFamilies[name] = family
+	}
+	for name, family := range h.scheduler.GetInferenceMetrics().families() {
+		allFamilies[name] = family
+	}
 
 	// Write aggregated response using Prometheus encoder
 	h.writeAggregatedMetrics(w, allFamilies)
@@ -66,6 +69,16 @@ func (h *AggregatedMetricsHandler) collectAndAggregateMetrics(ctx context.Contex
 			families, err := h.fetchRunnerMetrics(ctx, runner)
 			if err != nil {
 				h.log.Warnf("Failed to fetch metrics from runner %s/%s: %v", runner.BackendName, runner.ModelName, err)
+				families = make(map[string]*dto.MetricFamily)
+			}
+
+			// Attach cgroup resource usage if the runner has its own cgroup.
+			if stats, err := SampleRunnerResources(runner.Socket); err == nil {
+				for name, family := range resourceMetricFamilies(stats) {
+					families[name] = family
+				}
+			}
+			if len(families) == 0 {
 				return
 			}
 
@@ -75,6 +88,9 @@ func (h *AggregatedMetricsHandler) collectAndAggregateMetrics(ctx context.Contex
 				"model":   runner.ModelName,
 				"mode":    runner.Mode,
 			}
+			if runner.Replica > 0 {
+				labels["replica"] = strconv.Itoa(runner.Replica)
+			}
 
 			mu.Lock()
 			h.addLabelsAndMerge(families, labels, allFamilies)
@@ -163,7 +179,9 @@ func (h *AggregatedMetricsHandler) writeAggregatedMetrics(w http.ResponseWriter,
 
 	// Use Prometheus encoder to write metrics
 	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
-	for _, family := range families {
+	names := slices.Sorted(maps.Keys(families))
+	for _, name := range names {
+		family := families[name]
 		if err := encoder.Encode(family); err != nil {
 			h.log.Errorf("Failed to encode metric family %s: %v", *family.Name, err)
 			continue
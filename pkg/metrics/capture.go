@@ -0,0 +1,376 @@
+package metrics
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/logging"
+)
+
+// captureFileReplacer maps the characters of model references that can't
+// appear in file names.
+var captureFileReplacer = strings.NewReplacer("/", "_", ":", "_", "@", "_")
+
+// captureFile returns the name of the file to which the requests for a model
+// are captured.
+func captureFile(model string) string {
+	return captureFileReplacer.Replace(models.NormalizeModelName(model)) + ".jsonl"
+}
+
+const (
+	// defaultCaptureMaxSize is the default size beyond which the capture file
+	// of a model is rotated.
+	defaultCaptureMaxSize = 100 * 1024 * 1024
+	// defaultCaptureMaxFiles is the default number of rotated capture files
+	// that are kept for each model.
+	defaultCaptureMaxFiles = 5
+	// captureQueueLength is the number of pairs waiting to be captured beyond
+	// which new pairs are dropped rather than holding up responses.
+	captureQueueLength = 1024
+)
+
+// CaptureConfig configures the capture of request and response pairs to disk.
+type CaptureConfig struct {
+	// Dir is the directory of the capture files, which is created if
+	// necessary.
+	Dir string
+	// MaxSize is the size in bytes beyond which the capture file of a model is
+	// rotated. If zero, capture files are rotated beyond 100 MiB.
+	MaxSize int64
+	// MaxFiles is the number of rotated capture files that are kept for each
+	// model, with the suffixes .1 (the most recent) to .MaxFiles. If zero, 5
+	// are kept.
+	MaxFiles int
+}
+
+// openCaptureFile is an open capture file.
+type openCaptureFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	// size is the size of the file, including buffered pairs.
+	size int64
+	// flushed is the size of the file written to it, which only includes
+	// whole pairs.
+	flushed int64
+}
+
+// captureWriter appends captured pairs to the capture files of their models
+// from a single goroutine, rotating the files by size.
+type captureWriter struct {
+	config CaptureConfig
+	log    logging.Logger
+	// records are the pairs waiting to be captured.
+	records chan RequestResponsePair
+	// done is closed once the pairs are all captured after records is closed.
+	done chan struct{}
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// files are the open capture files by path.
+	files map[string]*openCaptureFile
+}
+
+// SetCapture enables the capture of every request and response pair to files
+// in the directory of config, in addition to the recent pairs kept in memory,
+// so that they can be exported and replayed, e.g. to regression-test a new
+// version of a model. Pairs are appended as JSON lines to a file per model,
+// which is rotated by size. Like recorded pairs, captured pairs are sanitized:
+// they don't include request headers, and large media are truncated.
+func (r *OpenAIRecorder) SetCapture(config CaptureConfig) error {
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultCaptureMaxSize
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = defaultCaptureMaxFiles
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return fmt.Errorf("creating capture directory: %w", err)
+	}
+	w := &captureWriter{
+		config:  config,
+		log:     r.log,
+		records: make(chan RequestResponsePair, captureQueueLength),
+		done:    make(chan struct{}),
+		files:   make(map[string]*openCaptureFile),
+	}
+	go w.run()
+
+	r.captureMutex.Lock()
+	previous := r.captureWriter
+	r.captureWriter = w
+	r.captureMutex.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+	return nil
+}
+
+// CloseCapture stops capturing pairs, writing those waiting to be captured
+// and closing the capture files.
+func (r *OpenAIRecorder) CloseCapture() {
+	r.captureMutex.Lock()
+	w := r.captureWriter
+	r.captureWriter = nil
+	r.captureMutex.Unlock()
+	if w != nil {
+		w.close()
+	}
+}
+
+// capture queues a completed request and response pair to be appended to the
+// capture file of its model, if capture is enabled. The pair is dropped if too
+// many are waiting to be captured.
+func (r *OpenAIRecorder) capture(record RequestResponsePair) {
+	r.captureMutex.Lock()
+	defer r.captureMutex.Unlock()
+	if r.captureWriter == nil {
+		return
+	}
+	select {
+	case r.captureWriter.records <- record:
+	default:
+		r.log.Warnf("Dropped captured request %s: too many requests waiting to be captured", record.ID)
+	}
+}
+
+// run captures the queued pairs until records is closed, flushing the capture
+// files whenever the queue is empty.
+func (w *captureWriter) run() {
+	defer close(w.done)
+	for record := range w.records {
+		w.write(record)
+		if len(w.records) == 0 {
+			w.flush()
+		}
+	}
+	w.flush()
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for path, f := range w.files {
+		f.file.Close()
+		delete(w.files, path)
+	}
+}
+
+// close stops capturing once the queued pairs are captured.
+func (w *captureWriter) close() {
+	close(w.records)
+	<-w.done
+}
+
+// write appends a pair to the capture file of its model, rotating it first if
+// the pair would take it beyond its maximum size.
+func (w *captureWriter) write(record RequestResponsePair) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		w.log.Warnf("Failed to encode captured request %s: %v", record.ID, err)
+		return
+	}
+	data = append(data, '\n')
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	path := filepath.Join(w.config.Dir, captureFile(record.Model))
+	f, err := w.openLocked(path)
+	if err != nil {
+		w.log.Warnf("Failed to open capture file: %v", err)
+		return
+	}
+	if f.size > 0 && f.size+int64(len(data)) > w.config.MaxSize {
+		if f, err = w.rotateLocked(path); err != nil {
+			w.log.Warnf("Failed to rotate capture file: %v", err)
+			return
+		}
+	}
+	n, err := f.writer.Write(data)
+	f.size += int64(n)
+	if err != nil {
+		w.log.Warnf("Failed to capture request %s: %v", record.ID, err)
+	}
+}
+
+// openLocked returns the capture file at path, opening it for appending if
+// necessary. The caller must hold the lock.
+func (w *captureWriter) openLocked(path string) (*openCaptureFile, error) {
+	if f, ok := w.files[path]; ok {
+		return f, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f := &openCaptureFile{file: file, writer: bufio.NewWriter(file), size: info.Size(), flushed: info.Size()}
+	w.files[path] = f
+	return f, nil
+}
+
+// rotateLocked moves the capture file at path to the .1 suffix, shifting older
+// files and removing the oldest, and opens a new capture file. The caller must
+// hold the lock.
+func (w *captureWriter) rotateLocked(path string) (*openCaptureFile, error) {
+	f := w.files[path]
+	delete(w.files, path)
+	flushErr := f.writer.Flush()
+	if err := errors.Join(flushErr, f.file.Close()); err != nil {
+		return nil, fmt.Errorf("closing capture file: %w", err)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", path, w.config.MaxFiles))
+	for i := w.config.MaxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return nil, fmt.Errorf("rotating capture file: %w", err)
+	}
+	return w.openLocked(path)
+}
+
+// flush writes the buffered pairs to the capture files.
+func (w *captureWriter) flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, f := range w.files {
+		if err := f.writer.Flush(); err != nil {
+			w.log.Warnf("Failed to write capture file: %v", err)
+			continue
+		}
+		f.flushed = f.size
+	}
+}
+
+// captureSection is the part of a capture file that is exported.
+type captureSection struct {
+	file *os.File
+	size int64
+}
+
+// snapshot opens the capture files of a model, or of all models if model is
+// empty, oldest first, along with their sizes. The sizes only cover whole
+// pairs, so the files can be exported while pairs are appended or the files
+// rotated.
+func (w *captureWriter) snapshot(model string) ([]captureSection, error) {
+	var paths []string
+	if model != "" {
+		paths = []string{filepath.Join(w.config.Dir, captureFile(model))}
+	} else {
+		var err error
+		if paths, err = filepath.Glob(filepath.Join(w.config.Dir, "*.jsonl")); err != nil {
+			return nil, err
+		}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var sections []captureSection
+	for _, path := range paths {
+		for i := w.config.MaxFiles; i >= 0; i-- {
+			rotated := path
+			if i > 0 {
+				rotated = fmt.Sprintf("%s.%d", path, i)
+			}
+			file, err := os.Open(rotated)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				closeCaptureSections(sections)
+				return nil, err
+			}
+			var size int64
+			if f, ok := w.files[rotated]; ok {
+				size = f.flushed
+			} else if info, err := file.Stat(); err == nil {
+				size = info.Size()
+			}
+			sections = append(sections, captureSection{file: file, size: size})
+		}
+	}
+	return sections, nil
+}
+
+// closeCaptureSections closes the files of capture sections.
+func closeCaptureSections(sections []captureSection) {
+	for _, section := range sections {
+		section.file.Close()
+	}
+}
+
+// GetExportHandler returns the handler of GET <inference-prefix>/requests/export
+// requests, which export request and response pairs as JSON lines, optionally
+// restricted to those of the model given by the model query parameter. The
+// captured pairs are exported if capture is enabled, and the recent pairs
+// kept in memory otherwise.
+func (r *OpenAIRecorder) GetExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		model := req.URL.Query().Get("model")
+
+		r.captureMutex.Lock()
+		capture := r.captureWriter
+		r.captureMutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if capture == nil {
+			encoder := json.NewEncoder(w)
+			for _, record := range r.recentRecords(model) {
+				if err := encoder.Encode(record); err != nil {
+					r.log.Warnf("Failed to export requests: %v", err)
+					return
+				}
+			}
+			return
+		}
+
+		sections, err := capture.snapshot(model)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer closeCaptureSections(sections)
+		for _, section := range sections {
+			if _, err := io.Copy(w, io.LimitReader(bufio.NewReader(section.file), section.size)); err != nil {
+				r.log.Warnf("Failed to export requests: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// recentRecords returns copies of the completed pairs kept in memory, oldest
+// first, optionally restricted to those of a model.
+func (r *OpenAIRecorder) recentRecords(model string) []RequestResponsePair {
+	var modelID string
+	if model != "" {
+		modelID = r.modelManager.ResolveModelID(model)
+	}
+
+	r.m.RLock()
+	defer r.m.RUnlock()
+	var records []RequestResponsePair
+	for id, modelData := range r.records {
+		if modelID != "" && id != modelID {
+			continue
+		}
+		for _, record := range modelData.Records {
+			if record.StatusCode != 0 {
+				records = append(records, *record)
+			}
+		}
+	}
+	slices.SortStableFunc(records, func(a, b RequestResponsePair) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+	return records
+}
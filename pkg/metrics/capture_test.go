@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCaptureExport(t *testing.T) {
+	recorder := NewOpenAIRecorder(logrus.New(), &models.Manager{})
+	dir := filepath.Join(t.TempDir(), "capture")
+	if err := recorder.SetCapture(CaptureConfig{Dir: dir}); err != nil {
+		t.Fatalf("SetCapture failed: %v", err)
+	}
+
+	smollm2 := RequestResponsePair{ID: "1", Model: "ai/smollm2", Method: "POST", URL: "/engines/v1/chat/completions", Request: `{"model":"ai/smollm2"}`, Response: `{"choices":[]}`, StatusCode: 200}
+	gemma3 := RequestResponsePair{ID: "2", Model: "ai/gemma3:4B", Method: "POST", URL: "/engines/v1/completions", Request: `{"model":"ai/gemma3:4B"}`, Error: "failed", StatusCode: 500}
+	recorder.capture(smollm2)
+	recorder.capture(gemma3)
+	// Closing the capture writes the queued pairs, and capture can resume.
+	recorder.CloseCapture()
+	if err := recorder.SetCapture(CaptureConfig{Dir: dir}); err != nil {
+		t.Fatalf("SetCapture failed: %v", err)
+	}
+	defer recorder.CloseCapture()
+
+	// Pairs are captured to a file per model, readable only by its owner.
+	info, err := os.Stat(filepath.Join(dir, "ai_smollm2_latest.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected a capture file for ai/smollm2: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected capture file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	export := func(query string) []RequestResponsePair {
+		return exportCapture(t, recorder, query)
+	}
+
+	if records := export("?model=ai/gemma3:4B"); !reflect.DeepEqual(records, []RequestResponsePair{gemma3}) {
+		t.Errorf("Expected the pairs of ai/gemma3:4B, got %+v", records)
+	}
+	if records := export("?model=ai/qwen3"); len(records) != 0 {
+		t.Errorf("Expected no pairs for a model without any, got %+v", records)
+	}
+	if records := export(""); len(records) != 2 {
+		t.Errorf("Expected the pairs of all models, got %+v", records)
+	}
+}
+
+// exportCapture exports the pairs of a recorder with a query string.
+func exportCapture(t *testing.T, recorder *OpenAIRecorder, query string) []RequestResponsePair {
+	t.Helper()
+	w := httptest.NewRecorder()
+	recorder.GetExportHandler()(w, httptest.NewRequest("GET", "/engines/requests/export"+query, nil))
+	var records []RequestResponsePair
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		var record RequestResponsePair
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to decode exported pair %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestCaptureRotation(t *testing.T) {
+	recorder := NewOpenAIRecorder(logrus.New(), &models.Manager{})
+	dir := t.TempDir()
+	// Every pair exceeds the maximum size, so each is captured to a new file.
+	if err := recorder.SetCapture(CaptureConfig{Dir: dir, MaxSize: 1, MaxFiles: 2}); err != nil {
+		t.Fatalf("SetCapture failed: %v", err)
+	}
+	defer recorder.CloseCapture()
+
+	writer := recorder.captureWriter
+	for _, id := range []string{"1", "2", "3", "4"} {
+		writer.write(RequestResponsePair{ID: id, Model: "ai/smollm2", StatusCode: 200})
+	}
+	writer.flush()
+	// Pairs that are still buffered aren't exported, though the files are
+	// rotated for them, dropping the oldest.
+	writer.write(RequestResponsePair{ID: "5", Model: "ai/smollm2", StatusCode: 200})
+
+	var ids []string
+	for _, record := range exportCapture(t, recorder, "?model=ai/smollm2") {
+		ids = append(ids, record.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"3", "4"}) {
+		t.Errorf("Expected the written pairs of the 2 rotated files, oldest first, got %v", ids)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ai_smollm2_latest.jsonl.3")); !os.IsNotExist(err) {
+		t.Errorf("Expected no more than 2 rotated files, got %v", err)
+	}
+}
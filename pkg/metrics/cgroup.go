@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// ErrNoRunnerCgroup indicates that a runner isn't running in a dedicated cgroup,
+// so its resource usage can't be distinguished from that of model-runner itself.
+var ErrNoRunnerCgroup = errors.New("runner is not running in a dedicated cgroup")
+
+// cgroupRoot is the mount point of the unified (v2) cgroup hierarchy.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// RunnerResourceStats contains resource usage sampled from a runner's cgroup.
+type RunnerResourceStats struct {
+	// Cgroup is the runner's cgroup path relative to the cgroup root.
+	Cgroup string `json:"cgroup"`
+	// CPUUsageSeconds is the total CPU time consumed by the cgroup.
+	CPUUsageSeconds float64 `json:"cpu_usage_seconds"`
+	// MemoryBytes is the current memory usage of the cgroup.
+	MemoryBytes uint64 `json:"memory_bytes"`
+	// IOReadBytes is the total number of bytes read from block devices.
+	IOReadBytes uint64 `json:"io_read_bytes"`
+	// IOWriteBytes is the total number of bytes written to block devices.
+	IOWriteBytes uint64 `json:"io_write_bytes"`
+}
+
+// cgroupFromProcFile extracts the unified hierarchy path from the contents of
+// a /proc/<pid>/cgroup file.
+func cgroupFromProcFile(content string) (string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("cgroup v2 hierarchy not found")
+}
+
+// readCgroupStats reads resource statistics from the cgroup at the given path
+// relative to root.
+func readCgroupStats(root, cgroup string) (*RunnerResourceStats, error) {
+	dir := filepath.Join(root, filepath.FromSlash(cgroup))
+	stats := &RunnerResourceStats{Cgroup: cgroup}
+
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, fmt.Errorf("reading cpu stats: %w", err)
+	}
+	stats.CPUUsageSeconds = float64(cpu["usage_usec"]) / 1e6
+
+	memory, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return nil, fmt.Errorf("reading memory stats: %w", err)
+	}
+	stats.MemoryBytes, err = strconv.ParseUint(strings.TrimSpace(string(memory)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing memory stats: %w", err)
+	}
+
+	// The io controller isn't always enabled, so treat its absence as zero
+	// usage rather than an error.
+	ioFile, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err == nil {
+		defer ioFile.Close()
+		scanner := bufio.NewScanner(ioFile)
+		for scanner.Scan() {
+			// Lines have the form "<major>:<minor> rbytes=N wbytes=N ..."
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					continue
+				}
+				n, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					continue
+				}
+				switch key {
+				case "rbytes":
+					stats.IOReadBytes += n
+				case "wbytes":
+					stats.IOWriteBytes += n
+				}
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading io stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// readKeyedFile parses a cgroup file consisting of "key value" lines.
+func readKeyedFile(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		values[key] = n
+	}
+	return values, scanner.Err()
+}
+
+// resourceMetricFamilies converts runner resource statistics into Prometheus
+// metric families.
+func resourceMetricFamilies(stats *RunnerResourceStats) map[string]*dto.MetricFamily {
+	gauge := func(name, help string, value float64) *dto.MetricFamily {
+		return &dto.MetricFamily{
+			Name: &name,
+			Help: &help,
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Gauge: &dto.Gauge{Value: &value},
+			}},
+		}
+	}
+	counter := func(name, help string, value float64) *dto.MetricFamily {
+		return &dto.MetricFamily{
+			Name: &name,
+			Help: &help,
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Counter: &dto.Counter{Value: &value},
+			}},
+		}
+	}
+
+	families := []*dto.MetricFamily{
+		counter("model_runner_runner_cpu_seconds_total", "Total CPU time consumed by the runner cgroup.", stats.CPUUsageSeconds),
+		gauge("model_runner_runner_memory_bytes", "Current memory usage of the runner cgroup.", float64(stats.MemoryBytes)),
+		counter("model_runner_runner_io_read_bytes_total", "Total bytes read from block devices by the runner cgroup.", float64(stats.IOReadBytes)),
+		counter("model_runner_runner_io_write_bytes_total", "Total bytes written to block devices by the runner cgroup.", float64(stats.IOWriteBytes)),
+	}
+	result := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		result[family.GetName()] = family
+	}
+	return result
+}
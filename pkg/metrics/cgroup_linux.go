@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// SampleRunnerResources samples the resource usage of the runner process
+// listening on the given Unix socket from its cgroup. It returns
+// ErrNoRunnerCgroup if the runner shares model-runner's own cgroup.
+func SampleRunnerResources(socket string) (*RunnerResourceStats, error) {
+	pid, err := socketPeerPID(socket)
+	if err != nil {
+		return nil, fmt.Errorf("identifying runner process: %w", err)
+	}
+
+	runnerCgroup, err := processCgroup(pid)
+	if err != nil {
+		return nil, err
+	}
+	selfCgroup, err := processCgroup(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+	if runnerCgroup == selfCgroup {
+		return nil, ErrNoRunnerCgroup
+	}
+
+	return readCgroupStats(cgroupRoot, runnerCgroup)
+}
+
+// socketPeerPID returns the PID of the process listening on a Unix socket.
+func socketPeerPID(socket string) (int, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.UnixConn).SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+	return int(cred.Pid), nil
+}
+
+// processCgroup returns the unified hierarchy cgroup path of a process.
+func processCgroup(pid int) (string, error) {
+	content, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", fmt.Errorf("reading cgroup for process %d: %w", pid, err)
+	}
+	return cgroupFromProcFile(string(content))
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package metrics
+
+import "errors"
+
+// SampleRunnerResources samples the resource usage of the runner process
+// listening on the given Unix socket from its cgroup. Cgroups are only
+// supported on Linux.
+func SampleRunnerResources(_ string) (*RunnerResourceStats, error) {
+	return nil, errors.New("runner resource sampling is only supported on Linux")
+}
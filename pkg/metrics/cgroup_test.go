@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupFromProcFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "unified hierarchy",
+			content: "0::/model-runner/runner-1\n",
+			want:    "/model-runner/runner-1",
+		},
+		{
+			name:    "hybrid hierarchy",
+			content: "12:memory:/legacy\n0::/runner\n",
+			want:    "/runner",
+		},
+		{
+			name:    "legacy hierarchy only",
+			content: "12:memory:/legacy\n11:cpu:/legacy\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cgroupFromProcFile(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cgroupFromProcFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cgroupFromProcFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCgroupStats(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "runner")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+		"memory.current": "1048576\n",
+		"io.stat":        "8:0 rbytes=100 wbytes=200 rios=1 wios=2\n8:16 rbytes=50 wbytes=25\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := readCgroupStats(root, "/runner")
+	if err != nil {
+		t.Fatalf("readCgroupStats() error = %v", err)
+	}
+	if stats.CPUUsageSeconds != 2.5 {
+		t.Errorf("CPUUsageSeconds = %v, want 2.5", stats.CPUUsageSeconds)
+	}
+	if stats.MemoryBytes != 1048576 {
+		t.Errorf("MemoryBytes = %d, want 1048576", stats.MemoryBytes)
+	}
+	if stats.IOReadBytes != 150 || stats.IOWriteBytes != 225 {
+		t.Errorf("IO bytes = %d/%d, want 150/225", stats.IOReadBytes, stats.IOWriteBytes)
+	}
+
+	families := resourceMetricFamilies(stats)
+	if len(families) != 4 {
+		t.Errorf("expected 4 metric families, got %d", len(families))
+	}
+}
+
+func TestReadCgroupStatsWithoutIOController(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cpu.stat"), []byte("usage_usec 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "memory.current"), []byte("42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := readCgroupStats(root, "/")
+	if err != nil {
+		t.Fatalf("readCgroupStats() error = %v", err)
+	}
+	if stats.MemoryBytes != 42 || stats.IOReadBytes != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,521 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maximumUsageBodySize is the maximum size of a non-streamed response body
+// that's buffered to read its token usage.
+const maximumUsageBodySize = 16 * 1024 * 1024
+
+// throughputSmoothing is the weight of each new observation in the moving
+// average of the output token throughput.
+const throughputSmoothing = 0.3
+
+var (
+	// timeToFirstTokenBuckets are the buckets of the time to first token
+	// histogram, in seconds.
+	timeToFirstTokenBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	// requestDurationBuckets are the buckets of the request duration
+	// histogram, in seconds.
+	requestDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	// tokensPerSecondBuckets are the buckets of the output token throughput
+	// histogram.
+	tokensPerSecondBuckets = []float64{1, 5, 10, 20, 50, 100, 200, 500, 1000}
+)
+
+// runnerLabels are the labels identifying the runner a metric relates to.
+type runnerLabels struct {
+	backend string
+	model   string
+	mode    string
+}
+
+// requestLabels are the labels of the request counter.
+type requestLabels struct {
+	runnerLabels
+	code string
+}
+
+// trafficSplitLabels are the labels of the traffic split counter.
+type trafficSplitLabels struct {
+	model   string
+	variant string
+}
+
+// guardrailLabels are the labels of the guardrail rejection counter.
+type guardrailLabels struct {
+	model     string
+	guardrail string
+}
+
+// tokenLabels are the labels of the token counter.
+type tokenLabels struct {
+	runnerLabels
+	typ string
+}
+
+// histogram is a Prometheus histogram with fixed buckets.
+type histogram struct {
+	// buckets are the upper bounds of the buckets.
+	buckets []float64
+	// counts are the number of observations in each bucket, not cumulatively.
+	counts []uint64
+	// count and sum are the number and sum of all observations.
+	count uint64
+	sum   float64
+}
+
+// observe adds an observation to the histogram.
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// InferenceMetrics records metrics of the inference requests served by the
+// scheduler and of its runners, which are exposed in the Prometheus format
+// alongside the metrics of the runners themselves. Its methods are safe to
+// call on a nil InferenceMetrics, in which case nothing is recorded.
+type InferenceMetrics struct {
+	// lock protects the subsequent fields.
+	lock sync.Mutex
+	// requests counts requests by runner and status code.
+	requests map[requestLabels]uint64
+	// tokens counts prompt and completion tokens by runner.
+	tokens map[tokenLabels]uint64
+	// timeToFirstToken is the time until the first token of streamed responses.
+	timeToFirstToken map[runnerLabels]*histogram
+	// requestDuration is the time taken to serve requests.
+	requestDuration map[runnerLabels]*histogram
+	// tokensPerSecond is the output token throughput of completions.
+	tokensPerSecond map[runnerLabels]*histogram
+	// throughput is an exponential moving average of the output token
+	// throughput of completions.
+	throughput map[runnerLabels]float64
+	// evictions counts runner evictions.
+	evictions map[runnerLabels]uint64
+	// cancelled counts requests abandoned by their clients.
+	cancelled map[runnerLabels]uint64
+	// trafficSplits counts the requests for models with a traffic split by
+	// the model that served them.
+	trafficSplits map[trafficSplitLabels]uint64
+	// guardrailRejections counts the requests rejected by guardrails.
+	guardrailRejections map[guardrailLabels]uint64
+}
+
+// NewInferenceMetrics creates a new set of inference metrics.
+func NewInferenceMetrics() *InferenceMetrics {
+	return &InferenceMetrics{
+		requests:            make(map[requestLabels]uint64),
+		tokens:              make(map[tokenLabels]uint64),
+		timeToFirstToken:    make(map[runnerLabels]*histogram),
+		requestDuration:     make(map[runnerLabels]*histogram),
+		tokensPerSecond:     make(map[runnerLabels]*histogram),
+		throughput:          make(map[runnerLabels]float64),
+		evictions:           make(map[runnerLabels]uint64),
+		cancelled:           make(map[runnerLabels]uint64),
+		trafficSplits:       make(map[trafficSplitLabels]uint64),
+		guardrailRejections: make(map[guardrailLabels]uint64),
+	}
+}
+
+// observeLocked adds an observation to the histogram of a runner, creating it
+// if necessary. The caller must hold the lock.
+func observeLocked(histograms map[runnerLabels]*histogram, labels runnerLabels, buckets []float64, value float64) {
+	h, ok := histograms[labels]
+	if !ok {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		histograms[labels] = h
+	}
+	h.observe(value)
+}
+
+// RecordEviction records the eviction of a runner.
+func (m *InferenceMetrics) RecordEviction(backend, model, mode string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.evictions[runnerLabels{backend, model, mode}]++
+}
+
+// RecordTrafficSplit records that a request for a model with a traffic split
+// was routed to variant, which is either the model itself or the model of its
+// traffic split.
+func (m *InferenceMetrics) RecordTrafficSplit(model, variant string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.trafficSplits[trafficSplitLabels{model, variant}]++
+}
+
+// RecordGuardrailRejection records that a request for a model was rejected by
+// a guardrail.
+func (m *InferenceMetrics) RecordGuardrailRejection(model, guardrail string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.guardrailRejections[guardrailLabels{model, guardrail}]++
+}
+
+// TokensPerSecond returns a moving average of the output token throughput of
+// the completions served by a runner, or zero if none were observed.
+func (m *InferenceMetrics) TokensPerSecond(backend, model, mode string) float64 {
+	if m == nil {
+		return 0
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.throughput[runnerLabels{backend, model, mode}]
+}
+
+// ObserveRequest wraps the response writer of an inference request served by
+// a runner, so that its status, duration, time to first token and token usage
+// are recorded once Done is called on the returned writer. Token usage is read
+// from the usage object of OpenAI responses, which llama.cpp includes in the
+// last chunk of streamed responses.
+func (m *InferenceMetrics) ObserveRequest(w http.ResponseWriter, backend, model, mode string) *ObservedResponseWriter {
+	return &ObservedResponseWriter{
+		ResponseWriter: w,
+		metrics:        m,
+		labels:         runnerLabels{backend, model, mode},
+		start:          time.Now(),
+	}
+}
+
+// ObservedResponseWriter is a response writer observed by InferenceMetrics.
+type ObservedResponseWriter struct {
+	http.ResponseWriter
+	// metrics are the metrics to record the request in.
+	metrics *InferenceMetrics
+	// labels identify the runner serving the request.
+	labels runnerLabels
+	// start is the time at which the request started.
+	start time.Time
+	// firstWrite is the time of the first write of the response body.
+	firstWrite time.Time
+	// statusCode is the status code of the response.
+	statusCode int
+	// streaming indicates whether the response is a stream of events.
+	streaming bool
+	// body buffers the non-streamed response body, or the incomplete last
+	// line of a streamed one.
+	body bytes.Buffer
+	// usage is the token usage reported by the response, if any.
+	usage *tokenUsage
+	// cancelled indicates whether the client abandoned the request.
+	cancelled bool
+}
+
+// tokenUsage is the token usage of an OpenAI response.
+type tokenUsage struct {
+	PromptTokens     uint64 `json:"prompt_tokens"`
+	CompletionTokens uint64 `json:"completion_tokens"`
+}
+
+// WriteHeader implements http.ResponseWriter.WriteHeader.
+func (w *ObservedResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+		w.streaming = strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.Write.
+func (w *ObservedResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.firstWrite.IsZero() && len(b) > 0 {
+		w.firstWrite = time.Now()
+	}
+	if w.metrics != nil {
+		if w.streaming {
+			w.scanEvents(b)
+		} else if w.body.Len()+len(b) <= maximumUsageBodySize {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// StatusCode returns the status code of the response. If none was written,
+// the request was cancelled or failed before the response, which is reported
+// as a request timeout.
+func (w *ObservedResponseWriter) StatusCode() int {
+	if w.statusCode == 0 {
+		return http.StatusRequestTimeout
+	}
+	return w.statusCode
+}
+
+// Cancel marks the request as abandoned by its client, so that it's counted
+// as cancelled once Done is called.
+func (w *ObservedResponseWriter) Cancel() {
+	w.cancelled = true
+}
+
+// Tokens returns the prompt and completion tokens reported by the response.
+// It's only valid after Done is called.
+func (w *ObservedResponseWriter) Tokens() (prompt, completion uint64) {
+	if w.usage == nil {
+		return 0, 0
+	}
+	return w.usage.PromptTokens, w.usage.CompletionTokens
+}
+
+// Flush implements http.Flusher.Flush.
+func (w *ObservedResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// scanEvents reads the token usage from the complete lines of a streamed
+// response, keeping the incomplete last line for the next write.
+func (w *ObservedResponseWriter) scanEvents(b []byte) {
+	w.body.Write(b)
+	for {
+		line, err := w.body.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line.
+			remaining := bytes.Clone(line)
+			w.body.Reset()
+			w.body.Write(remaining)
+			return
+		}
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok || !bytes.Contains(data, []byte(`"usage"`)) {
+			continue
+		}
+		if usage := parseUsage(data); usage != nil {
+			w.usage = usage
+		}
+	}
+}
+
+// parseUsage parses the token usage of an OpenAI response or response chunk.
+func parseUsage(data []byte) *tokenUsage {
+	var response struct {
+		Usage *tokenUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &response); err != nil {
+		return nil
+	}
+	return response.Usage
+}
+
+// Done records the observed request.
+func (w *ObservedResponseWriter) Done() {
+	m := w.metrics
+	if m == nil {
+		return
+	}
+	end := time.Now()
+	statusCode := w.StatusCode()
+	if !w.streaming && statusCode < http.StatusBadRequest {
+		w.usage = parseUsage(w.body.Bytes())
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests[requestLabels{w.labels, strconv.Itoa(statusCode)}]++
+	if w.cancelled {
+		m.cancelled[w.labels]++
+	}
+	observeLocked(m.requestDuration, w.labels, requestDurationBuckets, end.Sub(w.start).Seconds())
+	if statusCode >= http.StatusBadRequest {
+		return
+	}
+	if w.streaming && !w.firstWrite.IsZero() {
+		observeLocked(m.timeToFirstToken, w.labels, timeToFirstTokenBuckets, w.firstWrite.Sub(w.start).Seconds())
+	}
+	if w.usage == nil {
+		return
+	}
+	m.tokens[tokenLabels{w.labels, "prompt"}] += w.usage.PromptTokens
+	m.tokens[tokenLabels{w.labels, "completion"}] += w.usage.CompletionTokens
+	// Measure the throughput of streams from their first token, so that
+	// prompt processing isn't included.
+	generationStart := w.start
+	if w.streaming && !w.firstWrite.IsZero() {
+		generationStart = w.firstWrite
+	}
+	if elapsed := end.Sub(generationStart).Seconds(); w.usage.CompletionTokens > 0 && elapsed > 0 {
+		tokensPerSecond := float64(w.usage.CompletionTokens) / elapsed
+		observeLocked(m.tokensPerSecond, w.labels, tokensPerSecondBuckets, tokensPerSecond)
+		if average, ok := m.throughput[w.labels]; ok {
+			tokensPerSecond = average + throughputSmoothing*(tokensPerSecond-average)
+		}
+		m.throughput[w.labels] = tokensPerSecond
+	}
+}
+
+// labelPairs converts labels into Prometheus label pairs.
+func labelPairs(labels runnerLabels, extra ...string) []*dto.LabelPair {
+	names := []string{"backend", "model", "mode"}
+	values := []string{labels.backend, labels.model, labels.mode}
+	for i := 0; i+1 < len(extra); i += 2 {
+		names = append(names, extra[i])
+		values = append(values, extra[i+1])
+	}
+	pairs := make([]*dto.LabelPair, len(names))
+	for i := range names {
+		pairs[i] = &dto.LabelPair{Name: &names[i], Value: &values[i]}
+	}
+	return pairs
+}
+
+// newMetricFamily creates a metric family with the given metrics.
+func newMetricFamily(name, help string, typ dto.MetricType, metrics []*dto.Metric) *dto.MetricFamily {
+	return &dto.MetricFamily{Name: &name, Help: &help, Type: typ.Enum(), Metric: metrics}
+}
+
+// counterMetric creates a counter metric.
+func counterMetric(value float64, labels []*dto.LabelPair) *dto.Metric {
+	return &dto.Metric{Label: labels, Counter: &dto.Counter{Value: &value}}
+}
+
+// gaugeMetric creates a gauge metric.
+func gaugeMetric(value float64, labels []*dto.LabelPair) *dto.Metric {
+	return &dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: &value}}
+}
+
+// histogramMetric creates a histogram metric with cumulative buckets.
+func histogramMetric(h *histogram, labels []*dto.LabelPair) *dto.Metric {
+	buckets := make([]*dto.Bucket, len(h.buckets))
+	var cumulative uint64
+	for i := range h.buckets {
+		cumulative += h.counts[i]
+		count, bound := cumulative, h.buckets[i]
+		buckets[i] = &dto.Bucket{CumulativeCount: &count, UpperBound: &bound}
+	}
+	count, sum := h.count, h.sum
+	return &dto.Metric{Label: labels, Histogram: &dto.Histogram{
+		SampleCount: &count,
+		SampleSum:   &sum,
+		Bucket:      buckets,
+	}}
+}
+
+// histogramFamily creates a histogram metric family from histograms by runner.
+func histogramFamily(name, help string, histograms map[runnerLabels]*histogram) *dto.MetricFamily {
+	metrics := make([]*dto.Metric, 0, len(histograms))
+	for labels, h := range histograms {
+		metrics = append(metrics, histogramMetric(h, labelPairs(labels)))
+	}
+	return newMetricFamily(name, help, dto.MetricType_HISTOGRAM, metrics)
+}
+
+// families returns the recorded metrics as Prometheus metric families. Metric
+// families without any metrics are omitted.
+func (m *InferenceMetrics) families() map[string]*dto.MetricFamily {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	requests := make([]*dto.Metric, 0, len(m.requests))
+	for labels, count := range m.requests {
+		requests = append(requests, counterMetric(float64(count), labelPairs(labels.runnerLabels, "code", labels.code)))
+	}
+	tokens := make([]*dto.Metric, 0, len(m.tokens))
+	for labels, count := range m.tokens {
+		tokens = append(tokens, counterMetric(float64(count), labelPairs(labels.runnerLabels, "type", labels.typ)))
+	}
+	evictions := make([]*dto.Metric, 0, len(m.evictions))
+	for labels, count := range m.evictions {
+		evictions = append(evictions, counterMetric(float64(count), labelPairs(labels)))
+	}
+	cancelled := make([]*dto.Metric, 0, len(m.cancelled))
+	for labels, count := range m.cancelled {
+		cancelled = append(cancelled, counterMetric(float64(count), labelPairs(labels)))
+	}
+	trafficSplits := make([]*dto.Metric, 0, len(m.trafficSplits))
+	for labels, count := range m.trafficSplits {
+		names := []string{"model", "variant"}
+		values := []string{labels.model, labels.variant}
+		pairs := []*dto.LabelPair{{Name: &names[0], Value: &values[0]}, {Name: &names[1], Value: &values[1]}}
+		trafficSplits = append(trafficSplits, counterMetric(float64(count), pairs))
+	}
+	guardrailRejections := make([]*dto.Metric, 0, len(m.guardrailRejections))
+	for labels, count := range m.guardrailRejections {
+		names := []string{"model", "guardrail"}
+		values := []string{labels.model, labels.guardrail}
+		pairs := []*dto.LabelPair{{Name: &names[0], Value: &values[0]}, {Name: &names[1], Value: &values[1]}}
+		guardrailRejections = append(guardrailRejections, counterMetric(float64(count), pairs))
+	}
+
+	result := make(map[string]*dto.MetricFamily)
+	for _, family := range []*dto.MetricFamily{
+		newMetricFamily("model_runner_requests_total", "Total inference requests served by runners, by status code.", dto.MetricType_COUNTER, requests),
+		newMetricFamily("model_runner_tokens_total", "Total prompt and completion tokens processed by runners.", dto.MetricType_COUNTER, tokens),
+		histogramFamily("model_runner_time_to_first_token_seconds", "Time from the start of streamed inference requests to their first token.", m.timeToFirstToken),
+		histogramFamily("model_runner_request_duration_seconds", "Time taken to serve inference requests.", m.requestDuration),
+		histogramFamily("model_runner_output_tokens_per_second", "Completion token throughput of inference requests.", m.tokensPerSecond),
+		newMetricFamily("model_runner_evictions_total", "Total runner evictions.", dto.MetricType_COUNTER, evictions),
+		newMetricFamily("model_runner_cancelled_requests_total", "Total inference requests abandoned by their clients.", dto.MetricType_COUNTER, cancelled),
+		newMetricFamily("model_runner_traffic_split_requests_total", "Total inference requests for models with a traffic split, by the variant that served them.", dto.MetricType_COUNTER, trafficSplits),
+		newMetricFamily("model_runner_guardrail_rejections_total", "Total inference requests rejected by guardrails, by the guardrail that rejected them.", dto.MetricType_COUNTER, guardrailRejections),
+	} {
+		if len(family.Metric) > 0 {
+			result[family.GetName()] = family
+		}
+	}
+	return result
+}
+
+// runnerMetricFamilies returns the state of the active runners as Prometheus
+// metric families.
+func runnerMetricFamilies(runners []ActiveRunner) map[string]*dto.MetricFamily {
+	var ram, vram, inFlight, queued []*dto.Metric
+	for _, runner := range runners {
+		var extra []string
+		if runner.Replica > 0 {
+			extra = []string{"replica", strconv.Itoa(runner.Replica)}
+		}
+		labels := labelPairs(runnerLabels{runner.BackendName, runner.ModelName, runner.Mode}, extra...)
+		ram = append(ram, gaugeMetric(float64(runner.RAM), labels))
+		vram = append(vram, gaugeMetric(float64(runner.VRAM), labels))
+		inFlight = append(inFlight, gaugeMetric(float64(runner.InFlight), labels))
+		queued = append(queued, gaugeMetric(float64(runner.Queued), labels))
+	}
+
+	result := map[string]*dto.MetricFamily{
+		"model_runner_runners_loaded": newMetricFamily("model_runner_runners_loaded", "Number of loaded runners.",
+			dto.MetricType_GAUGE, []*dto.Metric{gaugeMetric(float64(len(runners)), nil)}),
+	}
+	if len(runners) == 0 {
+		return result
+	}
+	for _, family := range []*dto.MetricFamily{
+		newMetricFamily("model_runner_runner_ram_allocated_bytes", "RAM allocated to the runner by the scheduler.", dto.MetricType_GAUGE, ram),
+		newMetricFamily("model_runner_runner_vram_allocated_bytes", "VRAM allocated to the runner by the scheduler.", dto.MetricType_GAUGE, vram),
+		newMetricFamily("model_runner_runner_requests_in_flight", "Inference requests being served by the runner.", dto.MetricType_GAUGE, inFlight),
+		newMetricFamily("model_runner_runner_queue_depth", "Inference requests waiting for the runner to admit them.", dto.MetricType_GAUGE, queued),
+	} {
+		result[family.GetName()] = family
+	}
+	return result
+}
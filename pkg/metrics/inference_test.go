@@ -0,0 +1,254 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mockScheduler is a SchedulerInterface with a fixed set of runners.
+type mockScheduler struct {
+	runners []ActiveRunner
+	metrics *InferenceMetrics
+}
+
+func (s *mockScheduler) GetRunningBackends(http.ResponseWriter, *http.Request) {}
+
+func (s *mockScheduler) GetLlamaCppSocket() (string, error) {
+	return "", nil
+}
+
+func (s *mockScheduler) GetAllActiveRunners() []ActiveRunner {
+	return s.runners
+}
+
+func (s *mockScheduler) GetInferenceMetrics() *InferenceMetrics {
+	return s.metrics
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := NewInferenceMetrics()
+
+	// A streamed completion, with its usage in the last chunk, which is split
+	// across writes.
+	w := m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/smollm2", "completion")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+	w.Write([]byte(`data: {"choices":[],"usage":{"prompt_tokens":12,`))
+	w.Write([]byte("\"completion_tokens\":34}}\n\ndata: [DONE]\n\n"))
+	w.Done()
+
+	// A non-streamed embedding.
+	w = m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/mxbai-embed-large", "embedding")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"data":[],"usage":{"prompt_tokens":5,"total_tokens":5}}`))
+	w.Done()
+
+	// A failed request.
+	w = m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/smollm2", "completion")
+	http.Error(w, "unable to load runner", http.StatusServiceUnavailable)
+	w.Done()
+
+	m.RecordEviction("llama.cpp", "ai/smollm2", "completion")
+
+	families := m.families()
+	requests := families["model_runner_requests_total"].GetMetric()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 request counters, got %d", len(requests))
+	}
+	tokens := make(map[string]float64)
+	for _, metric := range families["model_runner_tokens_total"].GetMetric() {
+		var model, typ string
+		for _, label := range metric.GetLabel() {
+			switch label.GetName() {
+			case "model":
+				model = label.GetValue()
+			case "type":
+				typ = label.GetValue()
+			}
+		}
+		tokens[model+"/"+typ] = metric.GetCounter().GetValue()
+	}
+	want := map[string]float64{
+		"ai/smollm2/prompt":               12,
+		"ai/smollm2/completion":           34,
+		"ai/mxbai-embed-large/prompt":     5,
+		"ai/mxbai-embed-large/completion": 0,
+	}
+	for key, value := range want {
+		if tokens[key] != value {
+			t.Errorf("Expected %v %s tokens, got %v", value, key, tokens[key])
+		}
+	}
+	if ttft := families["model_runner_time_to_first_token_seconds"].GetMetric(); len(ttft) != 1 || ttft[0].GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("Expected a time to first token for the streamed request only, got %v", ttft)
+	}
+	if durations := families["model_runner_request_duration_seconds"].GetMetric(); len(durations) != 2 {
+		t.Errorf("Expected request durations for 2 runners, got %d", len(durations))
+	}
+	if evictions := families["model_runner_evictions_total"].GetMetric(); len(evictions) != 1 || evictions[0].GetCounter().GetValue() != 1 {
+		t.Errorf("Expected 1 eviction, got %v", evictions)
+	}
+}
+
+func TestInferenceMetricsCancelledRequests(t *testing.T) {
+	m := NewInferenceMetrics()
+
+	// A stream abandoned by its client after its first chunk.
+	w := m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/smollm2", "completion")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+	w.Cancel()
+	w.Done()
+
+	// A completed request.
+	w = m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/smollm2", "completion")
+	w.Write([]byte(`{"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	w.Done()
+
+	families := m.families()
+	if cancelled := families["model_runner_cancelled_requests_total"].GetMetric(); len(cancelled) != 1 || cancelled[0].GetCounter().GetValue() != 1 {
+		t.Errorf("Expected 1 cancelled request, got %v", cancelled)
+	}
+	if requests := families["model_runner_requests_total"].GetMetric(); len(requests) != 1 || requests[0].GetCounter().GetValue() != 2 {
+		t.Errorf("Expected cancelled requests to still be counted as requests, got %v", requests)
+	}
+}
+
+func TestInferenceMetricsTrafficSplits(t *testing.T) {
+	m := NewInferenceMetrics()
+	m.RecordTrafficSplit("ai/llama3.2", "ai/llama3.2")
+	m.RecordTrafficSplit("ai/llama3.2", "ai/llama3.2")
+	m.RecordTrafficSplit("ai/llama3.2", "ai/llama3.2:Q8_0")
+
+	counts := make(map[string]float64)
+	for _, metric := range m.families()["model_runner_traffic_split_requests_total"].GetMetric() {
+		labels := make(map[string]string)
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if labels["model"] != "ai/llama3.2" {
+			t.Errorf("Expected the requested model as model label, got %v", labels)
+		}
+		counts[labels["variant"]] = metric.GetCounter().GetValue()
+	}
+	if counts["ai/llama3.2"] != 2 || counts["ai/llama3.2:Q8_0"] != 1 {
+		t.Errorf("Expected requests to be counted by variant, got %v", counts)
+	}
+}
+
+func TestInferenceMetricsGuardrailRejections(t *testing.T) {
+	m := NewInferenceMetrics()
+	m.RecordGuardrailRejection("ai/smollm2", "blocklist")
+	m.RecordGuardrailRejection("ai/smollm2", "blocklist")
+	m.RecordGuardrailRejection("ai/smollm2", "max-prompt-length")
+
+	counts := make(map[string]float64)
+	for _, metric := range m.families()["model_runner_guardrail_rejections_total"].GetMetric() {
+		labels := make(map[string]string)
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if labels["model"] != "ai/smollm2" {
+			t.Errorf("Expected the requested model as model label, got %v", labels)
+		}
+		counts[labels["guardrail"]] = metric.GetCounter().GetValue()
+	}
+	if counts["blocklist"] != 2 || counts["max-prompt-length"] != 1 {
+		t.Errorf("Expected rejections to be counted by guardrail, got %v", counts)
+	}
+}
+
+func TestTokensPerSecond(t *testing.T) {
+	m := NewInferenceMetrics()
+	complete := func(completionTokens string) {
+		w := m.ObserveRequest(httptest.NewRecorder(), "llama.cpp", "ai/smollm2", "completion")
+		w.start = time.Now().Add(-time.Second)
+		w.Write([]byte(`{"usage":{"prompt_tokens":1,"completion_tokens":` + completionTokens + `}}`))
+		w.Done()
+	}
+
+	if tps := m.TokensPerSecond("llama.cpp", "ai/smollm2", "completion"); tps != 0 {
+		t.Errorf("Expected no throughput before any completion, got %v", tps)
+	}
+	// The first completion sets the average, and later ones move it by 30%
+	// of their difference to it.
+	complete("10")
+	if tps := m.TokensPerSecond("llama.cpp", "ai/smollm2", "completion"); tps < 9 || tps > 10 {
+		t.Errorf("Expected about 10 tokens per second, got %v", tps)
+	}
+	complete("20")
+	if tps := m.TokensPerSecond("llama.cpp", "ai/smollm2", "completion"); tps < 12 || tps > 13 {
+		t.Errorf("Expected about 13 tokens per second, got %v", tps)
+	}
+	if tps := m.TokensPerSecond("llama.cpp", "ai/smollm2", "embedding"); tps != 0 {
+		t.Errorf("Expected no throughput for another mode, got %v", tps)
+	}
+}
+
+func TestNilInferenceMetrics(t *testing.T) {
+	var m *InferenceMetrics
+	recorder := httptest.NewRecorder()
+	w := m.ObserveRequest(recorder, "llama.cpp", "ai/smollm2", "completion")
+	w.Write([]byte(`{"usage":{"prompt_tokens":1}}`))
+	w.Done()
+	m.RecordEviction("llama.cpp", "ai/smollm2", "completion")
+	if tps := m.TokensPerSecond("llama.cpp", "ai/smollm2", "completion"); tps != 0 {
+		t.Errorf("Expected no throughput, got %v", tps)
+	}
+	if recorder.Body.String() != `{"usage":{"prompt_tokens":1}}` {
+		t.Errorf("Expected the response to be written through, got %q", recorder.Body.String())
+	}
+	if families := m.families(); len(families) != 0 {
+		t.Errorf("Expected no metrics, got %v", families)
+	}
+}
+
+func TestAggregatedMetricsHandlerNativeMetrics(t *testing.T) {
+	m := NewInferenceMetrics()
+	m.RecordEviction("llama.cpp", "ai/smollm2", "completion")
+	handler := NewAggregatedMetricsHandler(logrus.New(), &mockScheduler{
+		runners: []ActiveRunner{{
+			BackendName: "llama.cpp",
+			ModelName:   "ai/qwen3",
+			Mode:        "completion",
+			Socket:      "/nonexistent.sock",
+			RAM:         1024,
+			VRAM:        4096,
+			InFlight:    2,
+			Queued:      3,
+		}},
+		metrics: m,
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"model_runner_runners_loaded 1",
+		`model_runner_runner_vram_allocated_bytes{backend="llama.cpp",model="ai/qwen3",mode="completion"} 4096`,
+		`model_runner_runner_requests_in_flight{backend="llama.cpp",model="ai/qwen3",mode="completion"} 2`,
+		`model_runner_runner_queue_depth{backend="llama.cpp",model="ai/qwen3",mode="completion"} 3`,
+		`model_runner_evictions_total{backend="llama.cpp",model="ai/smollm2",mode="completion"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in metrics:\n%s", line, body)
+		}
+	}
+
+	// Native metrics are served without any runners.
+	handler = NewAggregatedMetricsHandler(logrus.New(), &mockScheduler{metrics: NewInferenceMetrics()})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), "model_runner_runners_loaded 0\n") {
+		t.Errorf("Expected no loaded runners, got:\n%s", recorder.Body.String())
+	}
+}
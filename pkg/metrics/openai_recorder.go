@@ -105,6 +105,15 @@ type OpenAIRecorder struct {
 	// streaming
 	subscribers map[string]chan []ModelRecordsResponse
 	subMutex    sync.RWMutex
+
+	// usage accounting
+	usage       map[usageKey]*Usage
+	usageMutex  sync.Mutex
+	usagePruned time.Time
+
+	// capture to disk
+	captureWriter *captureWriter
+	captureMutex  sync.Mutex
 }
 
 func NewOpenAIRecorder(log logging.Logger, modelManager *models.Manager) *OpenAIRecorder {
@@ -113,6 +122,7 @@ func NewOpenAIRecorder(log logging.Logger, modelManager *models.Manager) *OpenAI
 		modelManager: modelManager,
 		records:      make(map[string]*ModelData),
 		subscribers:  make(map[string]chan []ModelRecordsResponse),
+		usage:        make(map[usageKey]*Usage),
 	}
 }
 
@@ -179,6 +189,31 @@ func (r *OpenAIRecorder) truncateMediaFields(requestBody []byte) []byte {
 	return modifiedBody
 }
 
+// truncateGeneratedImages truncates the base64 data of the images in an image
+// generation response, which are otherwise recorded in full.
+func (r *OpenAIRecorder) truncateGeneratedImages(responseBody string) string {
+	var responseData map[string]interface{}
+	if err := json.Unmarshal([]byte(responseBody), &responseData); err != nil {
+		return responseBody
+	}
+	images, ok := responseData["data"].([]interface{})
+	if !ok {
+		return responseBody
+	}
+	for _, img := range images {
+		if image, ok := img.(map[string]interface{}); ok {
+			if data, ok := image["b64_json"].(string); ok {
+				image["b64_json"] = r.truncateBase64Data(data)
+			}
+		}
+	}
+	modifiedBody, err := json.Marshal(responseData)
+	if err != nil {
+		return responseBody
+	}
+	return string(modifiedBody)
+}
+
 // truncateBase64Data truncates base64 data strings to a manageable length
 func (r *OpenAIRecorder) truncateBase64Data(data string) string {
 	if len(data) <= maxMediaDataLength {
@@ -348,7 +383,13 @@ func (r *OpenAIRecorder) RecordResponse(id, model string, rw http.ResponseWriter
 
 	var response string
 	var streamingErr error
-	if strings.Contains(responseBody, "data: ") {
+	if contentType := rr.Header().Get("Content-Type"); strings.HasPrefix(contentType, "audio/") && statusCode < http.StatusBadRequest {
+		// Synthesized speech isn't recorded, only its format and size.
+		summary, _ := json.Marshal(map[string]any{"content_type": contentType, "size": rr.body.Len()})
+		response = string(summary)
+	} else if strings.Contains(responseBody, `"b64_json"`) {
+		response = r.truncateGeneratedImages(responseBody)
+	} else if strings.Contains(responseBody, "data: ") {
 		response, streamingErr = r.convertStreamingResponse(responseBody)
 	} else {
 		response = responseBody
@@ -376,6 +417,7 @@ func (r *OpenAIRecorder) RecordResponse(id, model string, rw http.ResponseWriter
 					},
 				}}
 				go r.broadcastToSubscribers(modelResponse)
+				r.capture(*record)
 				return
 			}
 		}
@@ -385,15 +427,63 @@ func (r *OpenAIRecorder) RecordResponse(id, model string, rw http.ResponseWriter
 	}
 }
 
+// mergeToolCallDeltas merges streamed tool call deltas into the tool calls
+// accumulated so far. The first delta of a tool call carries its id, type and
+// function name, and the arguments are streamed in fragments, so deltas are
+// matched by index and their arguments concatenated.
+func mergeToolCallDeltas(toolCalls []map[string]interface{}, deltas []interface{}) []map[string]interface{} {
+	for _, d := range deltas {
+		delta, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := len(toolCalls)
+		if i, ok := delta["index"].(float64); ok {
+			index = int(i)
+		}
+		if index < 0 {
+			continue
+		}
+		for len(toolCalls) <= index {
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": "", "arguments": ""},
+			})
+		}
+		toolCall := toolCalls[index]
+		if id, ok := delta["id"].(string); ok && id != "" {
+			toolCall["id"] = id
+		}
+		if typ, ok := delta["type"].(string); ok && typ != "" {
+			toolCall["type"] = typ
+		}
+		if function, ok := delta["function"].(map[string]interface{}); ok {
+			accumulated := toolCall["function"].(map[string]interface{})
+			if name, ok := function["name"].(string); ok && name != "" {
+				accumulated["name"] = name
+			}
+			if arguments, ok := function["arguments"].(string); ok {
+				accumulated["arguments"] = accumulated["arguments"].(string) + arguments
+			}
+		}
+	}
+	return toolCalls
+}
+
 // convertStreamingResponse converts a streaming response body into a standard JSON response.
-// It handles both successful streaming completions and streaming errors.
+// It handles both successful streaming completions and streaming errors. Streamed
+// chat completions are converted to a chat.completion object, and streamed legacy
+// text completions to a text_completion object.
 // If a streaming error is detected, it returns the original streaming body and the error.
 // If successful, it reconstructs the final response in standard JSON format.
 func (r *OpenAIRecorder) convertStreamingResponse(streamingBody string) (string, error) {
 	lines := strings.Split(streamingBody, "\n")
 	var contentBuilder strings.Builder
 	var reasoningContentBuilder strings.Builder
+	var textBuilder strings.Builder
+	var toolCalls []map[string]interface{}
 	var lastChoice, lastChunk map[string]interface{}
+	isTextCompletion := false
 
 	for _, line := range lines {
 		// Check for error lines in the streaming format
@@ -452,6 +542,10 @@ func (r *OpenAIRecorder) convertStreamingResponse(streamingBody string) (string,
 			if choices, ok := chunk["choices"].([]interface{}); ok && len(choices) > 0 {
 				if choice, ok := choices[0].(map[string]interface{}); ok {
 					lastChoice = choice
+					if text, ok := choice["text"].(string); ok {
+						isTextCompletion = true
+						textBuilder.WriteString(text)
+					}
 					if delta, ok := choice["delta"].(map[string]interface{}); ok {
 						if content, ok := delta["content"].(string); ok {
 							contentBuilder.WriteString(content)
@@ -459,6 +553,9 @@ func (r *OpenAIRecorder) convertStreamingResponse(streamingBody string) (string,
 						if content, ok := delta["reasoning_content"].(string); ok {
 							reasoningContentBuilder.WriteString(content)
 						}
+						if deltas, ok := delta["tool_calls"].([]interface{}); ok {
+							toolCalls = mergeToolCallDeltas(toolCalls, deltas)
+						}
 					}
 				}
 			}
@@ -476,6 +573,21 @@ func (r *OpenAIRecorder) convertStreamingResponse(streamingBody string) (string,
 	}
 	finalResponse["choices"] = []interface{}{lastChoice}
 
+	if isTextCompletion {
+		if lastChoice != nil {
+			lastChoice["text"] = textBuilder.String()
+			if _, ok := lastChoice["finish_reason"]; !ok {
+				lastChoice["finish_reason"] = "stop"
+			}
+		}
+		finalResponse["object"] = "text_completion"
+		jsonResult, err := json.Marshal(finalResponse)
+		if err != nil {
+			return streamingBody, nil
+		}
+		return string(jsonResult), nil
+	}
+
 	if choices, ok := finalResponse["choices"].([]interface{}); ok && len(choices) > 0 {
 		if choice, ok := choices[0].(map[string]interface{}); ok {
 			message := map[string]interface{}{
@@ -485,6 +597,12 @@ func (r *OpenAIRecorder) convertStreamingResponse(streamingBody string) (string,
 			if reasoningContentBuilder.Len() > 0 {
 				message["reasoning_content"] = reasoningContentBuilder.String()
 			}
+			if len(toolCalls) > 0 {
+				message["tool_calls"] = toolCalls
+				if contentBuilder.Len() == 0 {
+					message["content"] = nil
+				}
+			}
 			choice["message"] = message
 			delete(choice, "delta")
 
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/docker/model-runner/pkg/inference/models"
@@ -205,6 +206,126 @@ func TestTruncateBase64Data(t *testing.T) {
 	}
 }
 
+func TestTruncateGeneratedImages(t *testing.T) {
+	recorder := NewOpenAIRecorder(logrus.New(), &models.Manager{})
+
+	input := `{"created":1700000000,"data":[{"b64_json":"` + generateLongString(200) + `"},{"b64_json":"abc123"}]}`
+	var result struct {
+		Created int `json:"created"`
+		Data    []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(recorder.truncateGeneratedImages(input)), &result); err != nil {
+		t.Fatalf("Failed to parse truncated response: %v", err)
+	}
+	if result.Created != 1700000000 || len(result.Data) != 2 {
+		t.Fatalf("Unexpected truncated response %+v", result)
+	}
+	if expected := generateLongString(100) + "...[truncated 100 chars]"; result.Data[0].B64JSON != expected {
+		t.Errorf("Expected %q, got %q", expected, result.Data[0].B64JSON)
+	}
+	if result.Data[1].B64JSON != "abc123" {
+		t.Errorf("Expected short image data to be kept, got %q", result.Data[1].B64JSON)
+	}
+
+	if invalid := `{"data": "b64_json"`; recorder.truncateGeneratedImages(invalid) != invalid {
+		t.Error("Expected invalid responses to be kept")
+	}
+}
+
+func TestConvertStreamingResponse(t *testing.T) {
+	recorder := NewOpenAIRecorder(logrus.New(), &models.Manager{})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected map[string]any
+	}{
+		{
+			name: "chat completion",
+			body: `data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}
+data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}
+data: [DONE]
+`,
+			expected: map[string]any{
+				"id":     "1",
+				"object": "chat.completion",
+				"choices": []any{map[string]any{
+					"index":         float64(0),
+					"message":       map[string]any{"role": "assistant", "content": "Hello world"},
+					"finish_reason": "stop",
+				}},
+			},
+		},
+		{
+			name: "chat completion with tool calls",
+			body: `data: {"id":"3","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}
+data: {"id":"3","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}
+data: {"id":"3","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}
+data: {"id":"3","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}
+data: [DONE]
+`,
+			expected: map[string]any{
+				"id":     "3",
+				"object": "chat.completion",
+				"choices": []any{map[string]any{
+					"index": float64(0),
+					"message": map[string]any{
+						"role":    "assistant",
+						"content": nil,
+						"tool_calls": []any{
+							map[string]any{
+								"id":       "call_1",
+								"type":     "function",
+								"function": map[string]any{"name": "get_weather", "arguments": `{"city":"Paris"}`},
+							},
+							map[string]any{
+								"id":       "call_2",
+								"type":     "function",
+								"function": map[string]any{"name": "get_time", "arguments": "{}"},
+							},
+						},
+					},
+					"finish_reason": "tool_calls",
+				}},
+			},
+		},
+		{
+			name: "text completion",
+			body: `data: {"id":"2","object":"text_completion","choices":[{"index":0,"text":"def ","finish_reason":null}]}
+data: {"id":"2","object":"text_completion","choices":[{"index":0,"text":"main():","finish_reason":"length"}]}
+data: [DONE]
+`,
+			expected: map[string]any{
+				"id":     "2",
+				"object": "text_completion",
+				"choices": []any{map[string]any{
+					"index":         float64(0),
+					"text":          "def main():",
+					"finish_reason": "length",
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := recorder.convertStreamingResponse(tt.body)
+			if err != nil {
+				t.Fatalf("convertStreamingResponse failed: %v", err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal([]byte(response), &got); err != nil {
+				t.Fatalf("Response is not valid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 // Helper function to generate a string of specified length
 func generateLongString(length int) string {
 	result := make([]byte, length)
@@ -20,6 +20,7 @@ type SchedulerInterface interface {
 	GetRunningBackends(w http.ResponseWriter, r *http.Request)
 	GetLlamaCppSocket() (string, error)
 	GetAllActiveRunners() []ActiveRunner
+	GetInferenceMetrics() *InferenceMetrics
 }
 
 // ActiveRunner contains information about an active runner
@@ -28,6 +29,16 @@ type ActiveRunner struct {
 	ModelName   string
 	Mode        string
 	Socket      string
+	// Replica distinguishes the runners loaded for the same model, backend
+	// and mode. Replicas after the first carry a replica label.
+	Replica int
+	// RAM and VRAM are the memory allocated to the runner.
+	RAM  uint64
+	VRAM uint64
+	// InFlight is the number of requests being served by the runner, and
+	// Queued the number waiting to be admitted.
+	InFlight int
+	Queued   int
 }
 
 // ServeHTTP implements http.Handler for metrics proxying via scheduler
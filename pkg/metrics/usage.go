@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+const (
+	// usageBucketDuration is the resolution at which token usage is
+	// aggregated, and so the resolution of the since parameter of usage
+	// queries.
+	usageBucketDuration = time.Hour
+	// usageRetention is how long aggregated token usage is kept.
+	usageRetention = 31 * 24 * time.Hour
+)
+
+// usageKey identifies the token usage of an API key and model in a bucket.
+type usageKey struct {
+	bucket int64
+	apiKey string
+	model  string
+}
+
+// Usage is the token usage of an API key and model. Models are identified by
+// their first tag, whichever reference they were requested by.
+type Usage struct {
+	// APIKey is the fingerprint of the API key, or empty for requests
+	// without a validated one.
+	APIKey           string `json:"api_key"`
+	Model            string `json:"model"`
+	Requests         uint64 `json:"requests"`
+	PromptTokens     uint64 `json:"prompt_tokens"`
+	CompletionTokens uint64 `json:"completion_tokens"`
+	TotalTokens      uint64 `json:"total_tokens"`
+}
+
+// UsageResponse is the response to a usage query.
+type UsageResponse struct {
+	// Since is the start of the aggregation period, rounded down to the
+	// aggregation resolution.
+	Since time.Time `json:"since"`
+	Usage []Usage   `json:"usage"`
+}
+
+// APIKeyFingerprint identifies the API key of a request without exposing it.
+// Only keys validated by middleware.AuthMiddleware are identified, so that
+// made-up tokens can't add entries to the usage totals. It returns an empty
+// string if the request has no validated API key.
+func APIKeyFingerprint(req *http.Request) string {
+	return middleware.APIKeyFingerprint(req.Context())
+}
+
+// RecordUsage adds the token usage of a request to the totals of its API key
+// and model. Usage is kept for 31 days.
+func (r *OpenAIRecorder) RecordUsage(apiKey, model string, promptTokens, completionTokens uint64) {
+	now := time.Now()
+	key := usageKey{
+		bucket: now.Truncate(usageBucketDuration).Unix(),
+		apiKey: apiKey,
+		model:  model,
+	}
+
+	r.usageMutex.Lock()
+	defer r.usageMutex.Unlock()
+
+	usage := r.usage[key]
+	if usage == nil {
+		usage = &Usage{APIKey: apiKey, Model: model}
+		r.usage[key] = usage
+	}
+	usage.Requests++
+	usage.PromptTokens += promptTokens
+	usage.CompletionTokens += completionTokens
+	usage.TotalTokens += promptTokens + completionTokens
+
+	// Drop expired buckets, at most once per bucket.
+	if now.Sub(r.usagePruned) >= usageBucketDuration {
+		oldest := now.Add(-usageRetention).Truncate(usageBucketDuration).Unix()
+		for k := range r.usage {
+			if k.bucket < oldest {
+				delete(r.usage, k)
+			}
+		}
+		r.usagePruned = now
+	}
+}
+
+// getUsage returns the token usage of each API key and model since a time,
+// sorted by API key and model.
+func (r *OpenAIRecorder) getUsage(since time.Time) []Usage {
+	oldest := since.Truncate(usageBucketDuration).Unix()
+	totals := make(map[usageKey]*Usage)
+
+	r.usageMutex.Lock()
+	for key, usage := range r.usage {
+		if key.bucket < oldest {
+			continue
+		}
+		key.bucket = 0
+		total := totals[key]
+		if total == nil {
+			total = &Usage{APIKey: usage.APIKey, Model: usage.Model}
+			totals[key] = total
+		}
+		total.Requests += usage.Requests
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+	}
+	r.usageMutex.Unlock()
+
+	result := make([]Usage, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].APIKey != result[j].APIKey {
+			return result[i].APIKey < result[j].APIKey
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
+
+// parseUsageSince parses the since parameter of a usage query, which is either
+// an RFC 3339 time or a duration before now. It defaults to the retention
+// period.
+func parseUsageSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now.Add(-usageRetention), nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: expected an RFC 3339 time or a duration such as 24h", value)
+}
+
+// GetUsageHandler returns a handler reporting the token usage of each API key
+// and model, optionally since the time given by the since query parameter.
+func (r *OpenAIRecorder) GetUsageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		now := time.Now()
+		since, err := parseUsageSince(req.URL.Query().Get("since"), now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if oldest := now.Add(-usageRetention); since.Before(oldest) {
+			since = oldest
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(UsageResponse{
+			Since: since.Truncate(usageBucketDuration).UTC(),
+			Usage: r.getUsage(since),
+		}); err != nil {
+			r.log.Errorf("Failed to encode usage: %v", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAPIKeyFingerprint(t *testing.T) {
+	keys, err := middleware.ParseAPIKeys(strings.NewReader("sk-test inference\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	var got string
+	handler := middleware.AuthMiddleware(keys, []string{"POST /engines/v1/chat/completions"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = APIKeyFingerprint(r)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      string
+	}{
+		{"Bearer token", "Bearer sk-test", "sha256:f3abf2a6cc4f0098"},
+		{"Lowercase scheme", "bearer sk-test", "sha256:f3abf2a6cc4f0098"},
+		{"Unknown token", "Bearer sk-made-up", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest(http.MethodPost, "/engines/v1/chat/completions", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.expected {
+				t.Errorf("APIKeyFingerprint() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	// Without API keys, tokens aren't validated, so they aren't identified.
+	req := httptest.NewRequest(http.MethodPost, "/engines/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer sk-test")
+	if got := APIKeyFingerprint(req); got != "" {
+		t.Errorf("APIKeyFingerprint() = %q, want no fingerprint", got)
+	}
+}
+
+func TestUsageHandler(t *testing.T) {
+	recorder := NewOpenAIRecorder(logrus.New(), &models.Manager{})
+	recorder.RecordUsage("sha256:aaaa", "ai/smollm2", 10, 20)
+	recorder.RecordUsage("sha256:aaaa", "ai/smollm2", 1, 2)
+	recorder.RecordUsage("sha256:bbbb", "ai/smollm2", 5, 0)
+	recorder.RecordUsage("sha256:aaaa", "ai/qwen3", 3, 4)
+
+	// Usage from before the retention period has been dropped.
+	recorder.usageMutex.Lock()
+	old := usageKey{bucket: time.Now().Add(-usageRetention - 2*usageBucketDuration).Unix(), apiKey: "sha256:aaaa", model: "ai/smollm2"}
+	recorder.usage[old] = &Usage{APIKey: "sha256:aaaa", Model: "ai/smollm2", Requests: 1, PromptTokens: 100}
+	recorder.usageMutex.Unlock()
+
+	w := httptest.NewRecorder()
+	recorder.GetUsageHandler()(w, httptest.NewRequest(http.MethodGet, "/engines/usage?since=24h", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response UsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	expected := []Usage{
+		{APIKey: "sha256:aaaa", Model: "ai/qwen3", Requests: 1, PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+		{APIKey: "sha256:aaaa", Model: "ai/smollm2", Requests: 2, PromptTokens: 11, CompletionTokens: 22, TotalTokens: 33},
+		{APIKey: "sha256:bbbb", Model: "ai/smollm2", Requests: 1, PromptTokens: 5, TotalTokens: 5},
+	}
+	if len(response.Usage) != len(expected) {
+		t.Fatalf("Expected %d usage entries, got %+v", len(expected), response.Usage)
+	}
+	for i := range expected {
+		if response.Usage[i] != expected[i] {
+			t.Errorf("Expected usage %+v, got %+v", expected[i], response.Usage[i])
+		}
+	}
+
+	// Usage is reported from the start of the hour.
+	w = httptest.NewRecorder()
+	since := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	recorder.GetUsageHandler()(w, httptest.NewRequest(http.MethodGet, "/engines/usage?since="+since, nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	if len(response.Usage) != len(expected) {
+		t.Errorf("Expected %d usage entries, got %+v", len(expected), response.Usage)
+	}
+
+	w = httptest.NewRecorder()
+	recorder.GetUsageHandler()(w, httptest.NewRequest(http.MethodGet, "/engines/usage?since=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid since, got %d", w.Code)
+	}
+}
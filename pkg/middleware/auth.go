@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/model-runner/pkg/inference"
+)
+
+// Scope is an access scope granted to an API key.
+type Scope string
+
+const (
+	// ScopeInference grants access to the OpenAI-compatible inference API,
+	// including model listing.
+	ScopeInference Scope = "inference"
+	// ScopeModelsAdmin grants access to the whole API, including pulling,
+	// deleting and configuring models and managing runners.
+	ScopeModelsAdmin Scope = "models-admin"
+)
+
+// APIKeys are the API keys accepted by AuthMiddleware, with their scopes.
+type APIKeys struct {
+	// scopes are the scopes of each key, indexed by the SHA-256 digest of the
+	// key so that lookups don't leak key prefixes through timing.
+	scopes map[[sha256.Size]byte]map[Scope]bool
+}
+
+// LoadAPIKeys reads API keys from a key file. See ParseAPIKeys for its format.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening API key file: %w", err)
+	}
+	defer f.Close()
+	keys, err := ParseAPIKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading API key file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseAPIKeys parses API keys, one per line, each followed by whitespace and
+// a comma-separated list of scopes, such as "sk-ci inference". Blank lines and
+// lines starting with # are ignored.
+func ParseAPIKeys(r io.Reader) (*APIKeys, error) {
+	keys := &APIKeys{scopes: make(map[[sha256.Size]byte]map[Scope]bool)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key and its scopes", line)
+		}
+		scopes := make(map[Scope]bool)
+		for _, scope := range strings.Split(fields[1], ",") {
+			switch Scope(scope) {
+			case ScopeInference, ScopeModelsAdmin:
+				scopes[Scope(scope)] = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown scope %q (expected %s or %s)", line, scope, ScopeInference, ScopeModelsAdmin)
+			}
+		}
+		keys.scopes[sha256.Sum256([]byte(fields[0]))] = scopes
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys.scopes) == 0 {
+		return nil, fmt.Errorf("no API keys")
+	}
+	return keys, nil
+}
+
+// Len returns the number of API keys.
+func (k *APIKeys) Len() int {
+	return len(k.scopes)
+}
+
+// allows reports whether a key is valid and whether it grants a scope. The
+// models-admin scope grants every scope.
+func (k *APIKeys) allows(key string, scope Scope) (valid, allowed bool) {
+	scopes, ok := k.scopes[sha256.Sum256([]byte(key))]
+	if !ok {
+		return false, false
+	}
+	return true, scopes[ScopeModelsAdmin] || scopes[scope]
+}
+
+// Fingerprint identifies an API key without exposing it, as "sha256:"
+// followed by the first 16 hex digits of the SHA-256 digest of the key.
+func Fingerprint(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(digest[:8])
+}
+
+type apiKeyFingerprintKey struct{}
+
+// APIKeyFingerprint returns the fingerprint of the API key AuthMiddleware
+// validated for a request, or an empty string if no key was validated, as
+// when API keys aren't configured.
+func APIKeyFingerprint(ctx context.Context) string {
+	fingerprint, _ := ctx.Value(apiKeyFingerprintKey{}).(string)
+	return fingerprint
+}
+
+// WithAPIKeyFingerprint returns a context carrying the fingerprint of a
+// validated API key, for requests made on behalf of its holder outside of
+// AuthMiddleware, such as the requests of a batch.
+func WithAPIKeyFingerprint(ctx context.Context, fingerprint string) context.Context {
+	return context.WithValue(ctx, apiKeyFingerprintKey{}, fingerprint)
+}
+
+// newRouteMatcher returns a mux matching the routes of the OpenAI-compatible
+// API, as registered by the scheduler, which the inference scope grants
+// access to. Other routes under /engines, such as loading, benchmarking or the
+// logs of a model, share their prefix, so requests are matched against the
+// exact routes rather than the prefix.
+func newRouteMatcher(inferenceRoutes []string) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range inferenceRoutes {
+		mux.Handle(route, http.NotFoundHandler())
+	}
+	return mux
+}
+
+// requiredScope returns the scope required for a request: inference for the
+// routes of the OpenAI-compatible API under /v1/, /engines/v1/ and
+// /engines/{backend}/v1/, and models-admin for everything else. The path is
+// cleaned first, as the router does, so that dot segments can't escape the
+// inference API.
+func requiredScope(inferenceRoutes *http.ServeMux, r *http.Request) Scope {
+	cleaned := path.Clean("/" + r.URL.Path)
+	// The /v1/ alias serves the routes under /engines/v1/.
+	if strings.HasPrefix(cleaned, "/v1/") {
+		cleaned = inference.InferencePrefix + cleaned
+	}
+	route := &http.Request{Method: r.Method, Host: r.Host, URL: &url.URL{Path: cleaned}}
+	if _, pattern := inferenceRoutes.Handler(route); pattern != "" {
+		return ScopeInference
+	}
+	return ScopeModelsAdmin
+}
+
+// AuthMiddleware requires a bearer token from keys on every request, with a
+// scope granting access to the requested path. The inference scope grants
+// access to inferenceRoutes, the patterns of the OpenAI-compatible API
+// returned by Scheduler.InferenceRoutes. Requests without a valid key are
+// rejected with 401 Unauthorized, and those whose key lacks the required scope
+// with 403 Forbidden. The fingerprint of the key of accepted requests is
+// available to handlers through APIKeyFingerprint. CORS preflight requests,
+// which carry no credentials, are passed through.
+func AuthMiddleware(keys *APIKeys, inferenceRoutes []string, next http.Handler) http.Handler {
+	routes := newRouteMatcher(inferenceRoutes)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="model-runner"`)
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+		scope := requiredScope(routes, r)
+		valid, allowed := keys.allows(token, scope)
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="model-runner", error="invalid_token"`)
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if !allowed {
+			http.Error(w, fmt.Sprintf("API key lacks the %s scope", scope), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithAPIKeyFingerprint(r.Context(), Fingerprint(token))))
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	keys, err := ParseAPIKeys(strings.NewReader(`
+# CI jobs only run inference.
+sk-ci     inference
+sk-admin  inference,models-admin
+`))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	if keys.Len() != 2 {
+		t.Errorf("Expected 2 keys, got %d", keys.Len())
+	}
+
+	for name, input := range map[string]string{
+		"Empty":         "# no keys\n",
+		"MissingScopes": "sk-ci\n",
+		"UnknownScope":  "sk-ci admin\n",
+	} {
+		if _, err := ParseAPIKeys(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	keys, err := ParseAPIKeys(strings.NewReader("sk-ci inference\nsk-admin models-admin\n"))
+	if err != nil {
+		t.Fatalf("ParseAPIKeys() error = %v", err)
+	}
+	inferenceRoutes := []string{
+		"POST /engines/v1/chat/completions",
+		"POST /engines/{backend}/v1/embeddings",
+		"GET /engines/v1/models",
+		"GET /engines/{backend}/v1/models/{name...}",
+		"POST /engines/v1/batches/{id}/cancel",
+	}
+	handler := AuthMiddleware(keys, inferenceRoutes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"MissingKey", "POST", "/engines/v1/chat/completions", "", http.StatusUnauthorized},
+		{"InvalidKey", "POST", "/engines/v1/chat/completions", "Bearer sk-wrong", http.StatusUnauthorized},
+		{"BasicAuth", "POST", "/engines/v1/chat/completions", "Basic c2stY2k6", http.StatusUnauthorized},
+		{"InferenceKeyInference", "POST", "/engines/v1/chat/completions", "Bearer sk-ci", http.StatusOK},
+		{"InferenceKeyBackendInference", "POST", "/engines/llama.cpp/v1/embeddings", "Bearer sk-ci", http.StatusOK},
+		{"InferenceKeyAlias", "GET", "/v1/models", "Bearer sk-ci", http.StatusOK},
+		{"InferenceKeyPull", "POST", "/models/create", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyDeleteModel", "DELETE", "/models/v1/model", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyConfigure", "POST", "/engines/llama.cpp/_configure", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyDotSegments", "POST", "/v1//../engines/unload", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyMetrics", "GET", "/metrics", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyLoad", "POST", "/engines/llama.cpp/v1/foo/load", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyLoadModelsPath", "POST", "/engines/llama.cpp/v1/models/foo/load", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyBenchmark", "POST", "/engines/llama.cpp/v1/foo/benchmark", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyLogs", "GET", "/engines/v1/foo/logs", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyAliasLogs", "GET", "/v1/foo/logs", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyWrongMethod", "DELETE", "/engines/v1/chat/completions", "Bearer sk-ci", http.StatusForbidden},
+		{"InferenceKeyModel", "GET", "/engines/llama.cpp/v1/models/ai/smollm2", "Bearer sk-ci", http.StatusOK},
+		{"InferenceKeyBatch", "POST", "/v1/batches/batch_1/cancel", "Bearer sk-ci", http.StatusOK},
+		{"AdminKeyPull", "POST", "/models/create", "Bearer sk-admin", http.StatusOK},
+		{"AdminKeyInference", "POST", "/engines/v1/chat/completions", "Bearer sk-admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate header")
+			}
+		})
+	}
+
+	// CORS preflight requests carry no credentials.
+	req := httptest.NewRequest(http.MethodOptions, "/engines/v1/chat/completions", nil)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected preflight requests to pass through, got %d", w.Code)
+	}
+}
@@ -1,30 +1,39 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 )
 
 // CorsMiddleware handles CORS and OPTIONS preflight requests with optional allowedOrigins.
-// If allowedOrigins is nil or empty, it falls back to getAllowedOrigins().
+// If allowedOrigins is nil, it falls back to getAllowedOrigins(), while an empty
+// non-nil slice allows no origins. Origins of the form scheme://*.domain allow
+// any subdomain of domain.
 // This middleware intercepts OPTIONS requests only if the Origin header is present and valid,
 // otherwise passing the request to the router (allowing 405/404 responses as appropriate).
 func CorsMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
-	if len(allowedOrigins) == 0 {
+	if allowedOrigins == nil {
 		allowedOrigins = getAllowedOrigins()
 	}
 
 	allowAll := len(allowedOrigins) == 1 && allowedOrigins[0] == "*"
 	allowedSet := make(map[string]struct{}, len(allowedOrigins))
+	var wildcards []string
 	for _, o := range allowedOrigins {
-		allowedSet[o] = struct{}{}
+		if strings.Contains(o, "://*.") {
+			wildcards = append(wildcards, o)
+		} else {
+			allowedSet[o] = struct{}{}
+		}
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
-		allowed := allowAll || originAllowed(origin, allowedSet)
+		allowed := allowAll || originAllowed(origin, allowedSet) || originMatchesWildcard(origin, wildcards)
 
 		if origin != "" && !allowed {
 			http.Error(w, "Origin not allowed", http.StatusForbidden)
@@ -63,23 +72,55 @@ func originAllowed(origin string, allowedSet map[string]struct{}) bool {
 	return ok
 }
 
-// getAllowedOrigins retrieves allowed origins from the DMR_ORIGINS environment variable.
-// If the variable is not set it returns nil, indicating no origins are allowed.
-func getAllowedOrigins() (origins []string) {
-	dmrOrigins := os.Getenv("DMR_ORIGINS")
-	if dmrOrigins == "" {
-		return nil
+// originMatchesWildcard reports whether origin is a subdomain matching one of
+// the scheme://*.domain patterns.
+func originMatchesWildcard(origin string, wildcards []string) bool {
+	for _, pattern := range wildcards {
+		scheme, domain, _ := strings.Cut(pattern, "://*.")
+		host, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(host, "."+domain) && len(host) > len(domain)+1 {
+			return true
+		}
 	}
+	return false
+}
 
-	for _, o := range strings.Split(dmrOrigins, ",") {
+// ParseOrigins parses a comma-separated list of origins.
+func ParseOrigins(value string) (origins []string) {
+	for _, o := range strings.Split(value, ",") {
 		if trimmed := strings.TrimSpace(o); trimmed != "" {
 			origins = append(origins, trimmed)
 		}
 	}
+	return origins
+}
 
-	if len(origins) == 0 {
-		return nil
+// ValidateOrigins checks that each origin is "*" on its own, or of the form
+// scheme://host[:port], where the host may start with "*." to allow any
+// subdomain.
+func ValidateOrigins(origins []string) error {
+	for _, o := range origins {
+		if o == "*" {
+			if len(origins) != 1 {
+				return fmt.Errorf("origin %q must be the only origin", o)
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" ||
+			strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+			return fmt.Errorf("invalid origin %q: expected scheme://host[:port], optionally with a *. subdomain wildcard", o)
+		}
+		if u.Path == "/" {
+			return fmt.Errorf("invalid origin %q: origins have no trailing slash", o)
+		}
 	}
+	return nil
+}
 
-	return origins
+// getAllowedOrigins retrieves allowed origins from the DMR_ORIGINS environment variable.
+// If the variable is not set it returns nil, indicating no origins are allowed.
+func getAllowedOrigins() (origins []string) {
+	return ParseOrigins(os.Getenv("DMR_ORIGINS"))
 }
@@ -69,6 +69,46 @@ func TestCorsMiddleware(t *testing.T) {
 			wantStatus:     http.StatusOK,
 			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
 		},
+		{
+			name:           "AllowWildcardSubdomain",
+			allowedOrigins: []string{"https://*.example.com"},
+			method:         "GET",
+			origin:         "https://app.eu.example.com",
+			wantStatus:     http.StatusOK,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": "https://app.eu.example.com"},
+		},
+		{
+			name:           "WildcardExcludesDomain",
+			allowedOrigins: []string{"https://*.example.com"},
+			method:         "GET",
+			origin:         "https://example.com",
+			wantStatus:     http.StatusForbidden,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "WildcardRequiresScheme",
+			allowedOrigins: []string{"https://*.example.com"},
+			method:         "GET",
+			origin:         "http://app.example.com",
+			wantStatus:     http.StatusForbidden,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "WildcardExcludesSuffix",
+			allowedOrigins: []string{"https://*.example.com"},
+			method:         "GET",
+			origin:         "https://app.example.com.evil.org",
+			wantStatus:     http.StatusForbidden,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "EmptyOrigins",
+			allowedOrigins: []string{},
+			method:         "GET",
+			origin:         "http://foo.com",
+			wantStatus:     http.StatusForbidden,
+			wantHeaders:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
 		{
 			name:           "DisableAllOrigins",
 			allowedOrigins: nil,
@@ -117,3 +157,27 @@ func TestOriginAllowed(t *testing.T) {
 		t.Errorf("expected originAllowed to return false")
 	}
 }
+
+func TestValidateOrigins(t *testing.T) {
+	t.Parallel()
+	for _, origins := range [][]string{
+		nil,
+		{"*"},
+		{"http://localhost:3000", "https://*.example.com"},
+	} {
+		if err := ValidateOrigins(origins); err != nil {
+			t.Errorf("ValidateOrigins(%q) error = %v", origins, err)
+		}
+	}
+	for _, origins := range [][]string{
+		{"*", "http://foo.com"},
+		{"foo.com"},
+		{"http://foo.com/"},
+		{"http://foo.com/app"},
+		{"https://app.*.example.com"},
+	} {
+		if err := ValidateOrigins(origins); err == nil {
+			t.Errorf("ValidateOrigins(%q) expected an error", origins)
+		}
+	}
+}
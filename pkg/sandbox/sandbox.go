@@ -4,6 +4,12 @@ import (
 	"os/exec"
 )
 
+// ConfigurationBundledServer is the sandbox configuration for the inference
+// servers other than llama.cpp that are bundled with the model runner, such as
+// whisper.cpp. They listen on the same IPC sockets and need the same access to
+// devices as llama.cpp, so they share its configuration for now.
+const ConfigurationBundledServer = ConfigurationLlamaCpp
+
 // Sandbox encapsulates a single running sandboxed process.
 type Sandbox interface {
 	// Command returns the sandboxed process handle.
@@ -92,6 +92,25 @@ const ConfigurationLlamaCpp = `(version 1)
     (subpath "[WORKDIR]"))
 `
 
+// sbplStringEscaper escapes strings for inclusion in a sandbox profile.
+var sbplStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// AllowReadPaths returns a copy of configuration that additionally allows
+// the sandboxed process to read the given paths.
+func AllowReadPaths(configuration string, paths []string) string {
+	if len(paths) == 0 {
+		return configuration
+	}
+	var profile strings.Builder
+	profile.WriteString(configuration)
+	profile.WriteString("\n;;; Allow read access to per-model mounts.\n(allow file-read*")
+	for _, path := range paths {
+		profile.WriteString("\n    (subpath \"" + sbplStringEscaper.Replace(path) + "\")")
+	}
+	profile.WriteString(")\n")
+	return profile.String()
+}
+
 // sandbox is the Darwin sandbox implementation.
 type sandbox struct {
 	// cancel cancels the context associated with the process.
@@ -11,6 +11,13 @@ import (
 // ConfigurationLlamaCpp is the sandbox configuration for llama.cpp processes.
 const ConfigurationLlamaCpp = ``
 
+// AllowReadPaths returns a copy of configuration that additionally allows
+// the sandboxed process to read the given paths. File access isn't restricted
+// on this platform, so configuration is returned unchanged.
+func AllowReadPaths(configuration string, paths []string) string {
+	return configuration
+}
+
 // sandbox is the non-Darwin POSIX sandbox implementation.
 type sandbox struct {
 	// cancel cancels the context associated with the process.
@@ -41,6 +41,13 @@ const ConfigurationLlamaCpp = `(WithDesktopLimit)
 (WithWriteClipboardLimit)
 `
 
+// AllowReadPaths returns a copy of configuration that additionally allows
+// the sandboxed process to read the given paths. File access isn't restricted
+// on this platform, so configuration is returned unchanged.
+func AllowReadPaths(configuration string, paths []string) string {
+	return configuration
+}
+
 // sandbox is the Windows sandbox implementation.
 type sandbox struct {
 	// job is the Windows Job object that encapsulates the process.
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/docker/model-runner/pkg/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// maximumConfigRequestSize is the maximum size of a configuration request.
+const maximumConfigRequestSize = 64 * 1024
+
+// corsRouteRebuilder is a component whose routes depend on the allowed
+// origins.
+type corsRouteRebuilder interface {
+	RebuildRoutes(allowedOrigins []string)
+}
+
+// CORSConfig is the CORS policy reported and set by the /config/cors endpoint.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests.
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// corsHandler serves the /config/cors endpoint, rebuilding the routes of
+// components when the allowed origins change.
+type corsHandler struct {
+	// log is the associated logger.
+	log *logrus.Logger
+	// components are the components whose routes depend on the allowed
+	// origins.
+	components []corsRouteRebuilder
+	// lock protects allowedOrigins.
+	lock sync.Mutex
+	// allowedOrigins are the currently allowed origins.
+	allowedOrigins []string
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.lock.Lock()
+		config := CORSConfig{AllowedOrigins: h.allowedOrigins}
+		h.lock.Unlock()
+		if config.AllowedOrigins == nil {
+			config.AllowedOrigins = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	case http.MethodPut:
+		var config CORSConfig
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maximumConfigRequestSize)).Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("invalid CORS configuration: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := middleware.ValidateOrigins(config.AllowedOrigins); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if config.AllowedOrigins == nil {
+			config.AllowedOrigins = []string{}
+		}
+		h.setAllowedOrigins(config.AllowedOrigins)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// setAllowedOrigins replaces the allowed origins, rebuilding the routes of
+// components if they've changed.
+func (h *corsHandler) setAllowedOrigins(allowedOrigins []string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if slices.Equal(h.allowedOrigins, allowedOrigins) && (h.allowedOrigins == nil) == (allowedOrigins == nil) {
+		return
+	}
+	h.allowedOrigins = allowedOrigins
+	for _, component := range h.components {
+		component.RebuildRoutes(allowedOrigins)
+	}
+	h.log.Infof("Allowed origins set to %v", allowedOrigins)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mockRouteRebuilder records the origins its routes were rebuilt with.
+type mockRouteRebuilder struct {
+	allowedOrigins []string
+}
+
+func (m *mockRouteRebuilder) RebuildRoutes(allowedOrigins []string) {
+	m.allowedOrigins = allowedOrigins
+}
+
+func TestCORSHandler(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	component := &mockRouteRebuilder{}
+	handler := &corsHandler{
+		log:            log,
+		components:     []corsRouteRebuilder{component},
+		allowedOrigins: []string{"http://localhost:3000"},
+	}
+
+	get := func() CORSConfig {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/config/cors", nil))
+		var config CORSConfig
+		if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+			t.Fatalf("Failed to decode CORS configuration: %v", err)
+		}
+		return config
+	}
+	put := func(body string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/config/cors", strings.NewReader(body)))
+		return w.Code
+	}
+
+	if config := get(); !reflect.DeepEqual(config.AllowedOrigins, []string{"http://localhost:3000"}) {
+		t.Errorf("Expected the initial origins, got %v", config.AllowedOrigins)
+	}
+
+	if code := put(`{"allowed_origins": ["https://*.example.com"]}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	want := []string{"https://*.example.com"}
+	if !reflect.DeepEqual(component.allowedOrigins, want) {
+		t.Errorf("Expected routes to be rebuilt with %v, got %v", want, component.allowedOrigins)
+	}
+	if config := get(); !reflect.DeepEqual(config.AllowedOrigins, want) {
+		t.Errorf("Expected %v, got %v", want, config.AllowedOrigins)
+	}
+
+	// Invalid origins are rejected without changing the policy.
+	if code := put(`{"allowed_origins": ["example.com"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", code)
+	}
+	if !reflect.DeepEqual(component.allowedOrigins, want) {
+		t.Errorf("Expected routes to be unchanged, got %v", component.allowedOrigins)
+	}
+
+	// An empty list disallows all origins, rather than restoring the defaults.
+	if code := put(`{"allowed_origins": []}`); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if component.allowedOrigins == nil || len(component.allowedOrigins) != 0 {
+		t.Errorf("Expected routes to be rebuilt with no origins, got %#v", component.allowedOrigins)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/middleware"
+)
+
+// errReloadUnsupported indicates that the server wasn't configured with a
+// way to re-read its configuration.
+var errReloadUnsupported = errors.New("configuration reload is not supported")
+
+// configurableBackend is a backend whose configuration can be replaced at
+// runtime.
+type configurableBackend interface {
+	// SetConfig replaces the backend configuration, reporting whether it
+	// changed.
+	SetConfig(conf config.BackendConfig) bool
+}
+
+// defaultAllowedOrigins returns the allowed origins, falling back to
+// DMR_ORIGINS if they're nil.
+func defaultAllowedOrigins(allowedOrigins []string) []string {
+	if allowedOrigins == nil {
+		return middleware.ParseOrigins(os.Getenv("DMR_ORIGINS"))
+	}
+	return allowedOrigins
+}
+
+// Reload re-reads the configuration with Config.Reload and applies the
+// settings that can change at runtime: the llama.cpp configuration, allowed
+// origins, idle timeouts and preloaded models. The reported settings are
+// replaced by the reloaded Config.Settings, which should describe only those
+// changes. Loaded runners are kept unless their backend configuration
+// changed, in which case they're restarted once idle. Other settings require
+// a restart. If the configuration can't be read, the current configuration is
+// kept.
+func (s *Server) Reload(ctx context.Context) error {
+	if s.reload == nil {
+		return errReloadUnsupported
+	}
+
+	// Reloads are serialized, including reading the configuration, so that
+	// Config.Reload needn't be safe for concurrent use.
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	cfg, err := s.reload()
+	if err != nil {
+		return err
+	}
+
+	if backend, ok := s.llamaCppBackend.(configurableBackend); ok && backend.SetConfig(cfg.LlamaCppConfig) {
+		restarted := s.scheduler.RestartRunners(ctx, llamacpp.Name)
+		s.log.Infof("llama.cpp configuration changed, restarting %d runner(s)", restarted)
+	}
+	s.cors.setAllowedOrigins(defaultAllowedOrigins(cfg.AllowedOrigins))
+	s.scheduler.UpdateIdleTimeouts(ctx, cfg.RunnerIdleTimeout, cfg.DeepSleepTimeout)
+	if s.runContext != nil {
+		if len(cfg.PreloadModels) > 0 {
+			go s.scheduler.Preload(s.runContext, cfg.PreloadModels)
+		}
+	} else {
+		s.scheduler.SetPreloadModels(cfg.PreloadModels)
+	}
+	if cfg.Settings != nil {
+		s.settings = cfg.Settings
+	}
+
+	s.log.Infoln("Configuration reloaded")
+	return nil
+}
+
+// handleGetConfig reports the effective configuration.
+func (s *Server) handleGetConfig(w http.ResponseWriter, _ *http.Request) {
+	s.reloadLock.Lock()
+	settings := s.settings
+	s.reloadLock.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// handleReloadConfig reloads the configuration and reports the result.
+func (s *Server) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if err := s.Reload(r.Context()); err != nil {
+		s.log.Warnf("Failed to reload configuration: %v", err)
+		http.Error(w, "failed to reload configuration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.handleGetConfig(w, r)
+}
@@ -0,0 +1,629 @@
+// Package server wires the model manager, inference backends and scheduler of
+// Docker Model Runner into an HTTP server, so that it can be embedded as a
+// library as well as run as a standalone binary.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/model-runner/pkg/distribution/distribution"
+	"github.com/docker/model-runner/pkg/distribution/registry"
+	"github.com/docker/model-runner/pkg/distribution/resolver"
+	"github.com/docker/model-runner/pkg/distribution/transport/resumable"
+	"github.com/docker/model-runner/pkg/gpuinfo"
+	"github.com/docker/model-runner/pkg/inference"
+	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
+	"github.com/docker/model-runner/pkg/inference/backends/mock"
+	"github.com/docker/model-runner/pkg/inference/backends/onnx"
+	"github.com/docker/model-runner/pkg/inference/backends/piper"
+	"github.com/docker/model-runner/pkg/inference/backends/sdcpp"
+	"github.com/docker/model-runner/pkg/inference/backends/vllm"
+	"github.com/docker/model-runner/pkg/inference/backends/whispercpp"
+	"github.com/docker/model-runner/pkg/inference/config"
+	"github.com/docker/model-runner/pkg/inference/memory"
+	"github.com/docker/model-runner/pkg/inference/models"
+	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/docker/model-runner/pkg/metrics"
+	"github.com/docker/model-runner/pkg/middleware"
+	"github.com/docker/model-runner/pkg/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// Config configures a Server.
+type Config struct {
+	// Log is the logger to use. If nil, the standard logger is used.
+	Log *logrus.Logger
+	// ModelPath is the root path of the model store.
+	ModelPath string
+	// LlamaServerPath is the directory containing the bundled llama.cpp
+	// server binary.
+	LlamaServerPath string
+	// LlamaServerUpdatePath is the directory to which updated llama.cpp server
+	// binaries are downloaded.
+	LlamaServerUpdatePath string
+	// LlamaCppConfig optionally overrides the default llama.cpp configuration.
+	LlamaCppConfig config.BackendConfig
+	// WhisperServerPath is the directory containing the bundled whisper.cpp
+	// server binary. If empty, LlamaServerPath is used.
+	WhisperServerPath string
+	// PiperServerPath is the directory containing the bundled piper server
+	// binary. If empty, LlamaServerPath is used.
+	PiperServerPath string
+	// SDServerPath is the directory containing the bundled stable-diffusion.cpp
+	// server binary. If empty, LlamaServerPath is used.
+	SDServerPath string
+	// ONNXServerPath is the directory containing the bundled ONNX Runtime
+	// server binary. If empty, LlamaServerPath is used.
+	ONNXServerPath string
+	// CatalogURL optionally specifies a catalog service used to map short
+	// model names to registry references.
+	CatalogURL string
+	// RepairStore enables repairing corrupt models in the store on startup.
+	RepairStore bool
+	// Stores are additional model stores in which models are looked up, in
+	// order, after the store at ModelPath.
+	Stores []distribution.StoreConfig
+	// WritableStore optionally names the store to which models are written,
+	// instead of the store at ModelPath.
+	WritableStore string
+	// ReadOnlyStore opens the model stores read-only, so that models are
+	// served but can't be pulled, loaded, tagged, configured or deleted.
+	ReadOnlyStore bool
+	// BlobCache is an optional directory shared with other model stores on
+	// the same file system, through which blobs are hardlinked between them
+	// rather than copied.
+	BlobCache string
+	// RequireDigest refuses models referenced by mutable tags, so that they
+	// must be pulled and used by digest or ID.
+	RequireDigest bool
+	// HardlinkBundles requires runtime bundles to hardlink model files from
+	// the store, so that backends mmap the blobs directly, rather than fall
+	// back to symlinks across file systems.
+	HardlinkBundles bool
+	// PushChunkSize is the size of the chunks in which model layers are
+	// pushed, so that interrupted pushes resume. Zero uses the default and a
+	// negative size disables chunked pushes.
+	PushChunkSize int64
+	// TokenStore is an optional JSON file of OAuth2 refresh tokens with which
+	// registries are authenticated to, as saved by the login command of the
+	// model distribution tool.
+	TokenStore string
+	// DiskHeadroom is the free space, in bytes, that model pulls must leave
+	// on the volume of the store, so that they fail before downloading
+	// rather than running out of space.
+	DiskHeadroom uint64
+	// MockBackend enables the mock backend and makes it the default backend.
+	MockBackend bool
+	// DeepSleepTimeout is the global idle period after which the model runner
+	// enters deep sleep. Zero disables deep sleep.
+	DeepSleepTimeout time.Duration
+	// MaxConcurrentRequests is the maximum number of requests each runner
+	// serves concurrently, beyond which requests are queued by priority. Zero
+	// leaves concurrency to the backends.
+	MaxConcurrentRequests int
+	// MaxRunnerRestarts is the number of times the backend of a runner is
+	// restarted after exiting unexpectedly while requests are waiting for it.
+	// Zero disables restarts.
+	MaxRunnerRestarts int
+	// InjectionPolicy is the allow-list for per-model environment variables
+	// and mounts.
+	InjectionPolicy scheduling.InjectionPolicy
+	// ImagePolicy configures the preprocessing of the images of requests to
+	// vision-language models.
+	ImagePolicy scheduling.ImagePolicy
+	// DisableMetrics disables the /metrics endpoint.
+	DisableMetrics bool
+	// PreloadModels are the models to pull, if necessary, and load on
+	// startup.
+	PreloadModels []string
+	// FallbackModels map models to the models that inference requests are
+	// retried against, in order, when they fail to load.
+	FallbackModels map[string][]string
+	// Guardrails configure the content filters applied to the prompts and
+	// completions of inference requests.
+	Guardrails []scheduling.GuardrailConfig
+	// DrainTimeout is the maximum time for which Serve waits for in-flight
+	// inference requests to complete on shutdown, after it stops accepting
+	// new ones. Zero disables draining, so requests are cut off.
+	DrainTimeout time.Duration
+	// AccessLog configures the access log of inference requests, which is
+	// disabled if its path is empty.
+	AccessLog scheduling.AccessLogConfig
+	// Capture configures the capture of inference request and response pairs
+	// for export and replay, which is disabled if its directory is empty.
+	Capture metrics.CaptureConfig
+	// APIKeys optionally requires every request to carry one of these API keys
+	// as a bearer token, with a scope granting access to the requested path.
+	APIKeys *middleware.APIKeys
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// which can be changed at runtime with the /config/cors endpoint. If nil,
+	// they're read from DMR_ORIGINS.
+	AllowedOrigins []string
+	// RunnerIdleTimeout optionally overrides the period after which idle
+	// runners are evicted.
+	RunnerIdleTimeout time.Duration
+	// Settings optionally describes the effective configuration, which is
+	// reported as JSON by GET /config.
+	Settings any
+	// Reload optionally re-reads the configuration, enabling Server.Reload
+	// and the POST /config/reload endpoint. Only the settings listed by
+	// Server.Reload are applied, so the Settings it returns should keep the
+	// others as they were. It's never called concurrently.
+	Reload func() (Config, error)
+}
+
+// Hooks are lifecycle callbacks for embedders. All hooks are optional.
+type Hooks struct {
+	// OnStoreReady is called by Serve with the root path of the model store
+	// once the store has been opened (and repaired, if enabled), before any
+	// requests are served.
+	OnStoreReady func(storePath string)
+	// OnBackendInstalled is called when the installation of a backend
+	// completes, with a non-nil error if it failed. It may be called
+	// concurrently with request handling.
+	OnBackendInstalled func(backend string, err error)
+	// OnBeforeShutdown is called when Serve's context is cancelled, before the
+	// server stops accepting connections and runners are unloaded.
+	OnBeforeShutdown func()
+}
+
+// Server is a Docker Model Runner server.
+type Server struct {
+	// log is the associated logger.
+	log *logrus.Logger
+	// hooks are the lifecycle hooks.
+	hooks Hooks
+	// modelManager is the model manager.
+	modelManager *models.Manager
+	// scheduler is the inference scheduler.
+	scheduler *scheduling.Scheduler
+	// httpServer is the HTTP server.
+	httpServer *http.Server
+	// drainTimeout is the maximum time to wait for in-flight requests on
+	// shutdown.
+	drainTimeout time.Duration
+	// llamaCppBackend is the llama.cpp backend, whose configuration can be
+	// reloaded.
+	llamaCppBackend inference.Backend
+	// cors serves the /config/cors endpoint.
+	cors *corsHandler
+	// reload re-reads the configuration, if supported.
+	reload func() (Config, error)
+	// reloadLock serializes reloads and protects settings and runContext.
+	reloadLock sync.Mutex
+	// settings describes the effective configuration.
+	settings any
+	// runContext is the context of the running scheduler, used to preload
+	// models on reload.
+	runContext context.Context
+}
+
+// New creates a new server. The model store is opened and backends are
+// created, but nothing is served or installed until Serve is called.
+func New(cfg Config, hooks Hooks) (*Server, error) {
+	log := cfg.Log
+	if log == nil {
+		log = logrus.StandardLogger()
+	}
+
+	gpuInfo := gpuinfo.New(cfg.LlamaServerPath)
+
+	sysMemInfo, err := memory.NewSystemMemoryInfo(log, gpuInfo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize system memory info: %w", err)
+	}
+
+	memEstimator := memory.NewEstimator(sysMemInfo)
+
+	// Create a proxy-aware HTTP transport
+	// Use a safe type assertion with fallback, and explicitly set Proxy to http.ProxyFromEnvironment
+	var baseTransport *http.Transport
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		baseTransport = t.Clone()
+	} else {
+		baseTransport = &http.Transport{}
+	}
+	baseTransport.Proxy = http.ProxyFromEnvironment
+
+	// Optionally map short model names to registry references using a
+	// catalog service.
+	var nameResolver resolver.Resolver
+	if cfg.CatalogURL != "" {
+		log.Infof("MODEL_CATALOG_URL: %s", cfg.CatalogURL)
+		nameResolver = resolver.NewCatalog(cfg.CatalogURL, baseTransport)
+	}
+
+	// Optionally authenticate to registries with stored OAuth2 refresh
+	// tokens.
+	var tokenStore registry.TokenStore
+	if cfg.TokenStore != "" {
+		tokenStore = registry.NewFileTokenStore(cfg.TokenStore)
+	}
+	modelManager := models.NewManager(
+		log,
+		models.ClientConfig{
+			StoreRootPath:   cfg.ModelPath,
+			Logger:          log.WithFields(logrus.Fields{"component": "model-manager"}),
+			Transport:       resumable.New(baseTransport),
+			NameResolver:    nameResolver,
+			RepairStore:     cfg.RepairStore,
+			Stores:          cfg.Stores,
+			WritableStore:   cfg.WritableStore,
+			ReadOnly:        cfg.ReadOnlyStore,
+			BlobCache:       cfg.BlobCache,
+			RequireDigest:   cfg.RequireDigest,
+			HardlinkBundles: cfg.HardlinkBundles,
+			PushChunkSize:   cfg.PushChunkSize,
+			TokenStore:      tokenStore,
+			DiskHeadroom:    cfg.DiskHeadroom,
+		},
+		cfg.AllowedOrigins,
+		memEstimator,
+	)
+
+	log.Infof("LLAMA_SERVER_PATH: %s", cfg.LlamaServerPath)
+
+	llamaCppBackend, err := llamacpp.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": llamacpp.Name}),
+		cfg.LlamaServerPath,
+		cfg.LlamaServerUpdatePath,
+		cfg.LlamaCppConfig,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", llamacpp.Name, err)
+	}
+
+	memEstimator.SetDefaultBackend(llamaCppBackend)
+
+	vllmBackend, err := vllm.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": vllm.Name}),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", vllm.Name, err)
+	}
+
+	whisperServerPath := cfg.WhisperServerPath
+	if whisperServerPath == "" {
+		whisperServerPath = cfg.LlamaServerPath
+	}
+	log.Infof("WHISPER_SERVER_PATH: %s", whisperServerPath)
+
+	whisperCppBackend, err := whispercpp.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": whispercpp.Name}),
+		whisperServerPath,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", whispercpp.Name, err)
+	}
+
+	piperServerPath := cfg.PiperServerPath
+	if piperServerPath == "" {
+		piperServerPath = cfg.LlamaServerPath
+	}
+	log.Infof("PIPER_SERVER_PATH: %s", piperServerPath)
+
+	piperBackend, err := piper.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": piper.Name}),
+		piperServerPath,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", piper.Name, err)
+	}
+
+	sdServerPath := cfg.SDServerPath
+	if sdServerPath == "" {
+		sdServerPath = cfg.LlamaServerPath
+	}
+	log.Infof("SD_SERVER_PATH: %s", sdServerPath)
+
+	sdCppBackend, err := sdcpp.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": sdcpp.Name}),
+		sdServerPath,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", sdcpp.Name, err)
+	}
+
+	onnxServerPath := cfg.ONNXServerPath
+	if onnxServerPath == "" {
+		onnxServerPath = cfg.LlamaServerPath
+	}
+	log.Infof("ONNX_SERVER_PATH: %s", onnxServerPath)
+
+	onnxBackend, err := onnx.New(
+		log,
+		modelManager,
+		log.WithFields(logrus.Fields{"component": onnx.Name}),
+		onnxServerPath,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize %s backend: %w", onnx.Name, err)
+	}
+
+	backends := map[string]inference.Backend{
+		llamacpp.Name:   llamaCppBackend,
+		vllm.Name:       vllmBackend,
+		whispercpp.Name: whisperCppBackend,
+		piper.Name:      piperBackend,
+		sdcpp.Name:      sdCppBackend,
+		onnx.Name:       onnxBackend,
+	}
+	defaultBackend := llamaCppBackend
+
+	// The mock backend serves canned responses without any model weights and
+	// is intended for development and CI environments.
+	if cfg.MockBackend {
+		mockBackend, err := mock.New(
+			log.WithFields(logrus.Fields{"component": mock.Name}),
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize %s backend: %w", mock.Name, err)
+		}
+		backends[mock.Name] = mockBackend
+		defaultBackend = mockBackend
+		log.Infof("Using %s backend as the default backend", mock.Name)
+	}
+
+	scheduler := scheduling.NewScheduler(
+		log,
+		backends,
+		defaultBackend,
+		modelManager,
+		http.DefaultClient,
+		cfg.AllowedOrigins,
+		metrics.NewTracker(
+			http.DefaultClient,
+			log.WithField("component", "metrics"),
+			"",
+			false,
+		),
+		sysMemInfo,
+	)
+
+	// Evict idle runners after a custom period, if configured.
+	if cfg.RunnerIdleTimeout > 0 {
+		scheduler.SetRunnerIdleTimeout(cfg.RunnerIdleTimeout)
+		log.Infof("Evicting runners after %s of inactivity", cfg.RunnerIdleTimeout)
+	}
+
+	// Enter deep sleep after a global idle period, if configured.
+	if cfg.DeepSleepTimeout > 0 {
+		scheduler.SetDeepSleepTimeout(cfg.DeepSleepTimeout)
+		log.Infof("Deep sleep enabled after %s of inactivity", cfg.DeepSleepTimeout)
+	}
+
+	// Queue requests beyond the concurrency limit by priority, if configured.
+	if cfg.MaxConcurrentRequests > 0 {
+		scheduler.SetMaxConcurrentRequests(cfg.MaxConcurrentRequests)
+		log.Infof("Runners serve up to %d concurrent requests", cfg.MaxConcurrentRequests)
+	}
+
+	// Restart crashed runners that requests are waiting for, if configured.
+	if cfg.MaxRunnerRestarts > 0 {
+		scheduler.SetMaxRunnerRestarts(cfg.MaxRunnerRestarts)
+		log.Infof("Restarting crashed runners up to %d times", cfg.MaxRunnerRestarts)
+	}
+
+	scheduler.SetInjectionPolicy(cfg.InjectionPolicy)
+	if cfg.ImagePolicy.MaxSize > 0 {
+		scheduler.SetImagePolicy(cfg.ImagePolicy)
+	}
+
+	// Log inference requests, if configured.
+	if cfg.AccessLog.Path != "" {
+		if err := scheduler.SetAccessLog(cfg.AccessLog); err != nil {
+			return nil, fmt.Errorf("enabling access log: %w", err)
+		}
+		log.Infof("Logging inference requests to %s", cfg.AccessLog.Path)
+	}
+
+	// Capture inference requests and responses, if configured.
+	if cfg.Capture.Dir != "" {
+		if err := scheduler.SetRequestCapture(cfg.Capture); err != nil {
+			return nil, fmt.Errorf("enabling request capture: %w", err)
+		}
+		log.Infof("Capturing inference requests to %s", cfg.Capture.Dir)
+	}
+
+	// Preload models on startup, if configured.
+	if len(cfg.PreloadModels) > 0 {
+		scheduler.SetPreloadModels(cfg.PreloadModels)
+		log.Infof("Preloading models: %s", strings.Join(cfg.PreloadModels, ", "))
+	}
+
+	// Retry requests for models that fail to load against fallback models, if
+	// configured.
+	if len(cfg.FallbackModels) > 0 {
+		scheduler.SetFallbackModels(cfg.FallbackModels)
+		log.Infof("Falling back to other models for %d model(s) that fail to load", len(cfg.FallbackModels))
+	}
+
+	// Filter the prompts and completions of inference requests, if
+	// configured.
+	for _, config := range cfg.Guardrails {
+		guardrails, err := scheduling.NewGuardrails(config)
+		if err != nil {
+			return nil, fmt.Errorf("configuring guardrails: %w", err)
+		}
+		for _, guardrail := range guardrails {
+			scheduler.AddGuardrail(guardrail, config.Models...)
+		}
+	}
+	if len(cfg.Guardrails) > 0 {
+		log.Infof("Applying %d guardrail configuration(s) to inference requests", len(cfg.Guardrails))
+	}
+	if hooks.OnBackendInstalled != nil {
+		scheduler.SetBackendInstalledHook(hooks.OnBackendInstalled)
+	}
+
+	router := routing.NewNormalizedServeMux()
+
+	// Register path prefixes to forward all HTTP methods (including OPTIONS) to components
+	// Components handle method routing internally
+	// Register both with and without trailing slash to avoid redirects
+	router.Handle(inference.ModelsPrefix, modelManager)
+	router.Handle(inference.ModelsPrefix+"/", modelManager)
+	router.Handle(inference.InferencePrefix, scheduler)
+	router.Handle(inference.InferencePrefix+"/", scheduler)
+	// Add /v1 as an alias for /engines/v1
+	router.Handle("/v1/", &V1AliasHandler{scheduler: scheduler})
+
+	s := &Server{
+		log:             log,
+		hooks:           hooks,
+		modelManager:    modelManager,
+		scheduler:       scheduler,
+		drainTimeout:    cfg.DrainTimeout,
+		llamaCppBackend: llamaCppBackend,
+		reload:          cfg.Reload,
+		settings:        cfg.Settings,
+	}
+
+	// Report the effective configuration, if provided, and reload it, if
+	// supported.
+	if cfg.Settings != nil {
+		router.HandleFunc("GET /config", s.handleGetConfig)
+	}
+	if cfg.Reload != nil {
+		router.HandleFunc("POST /config/reload", s.handleReloadConfig)
+	}
+
+	// Report and set the allowed origins.
+	s.cors = &corsHandler{
+		log:            log,
+		components:     []corsRouteRebuilder{modelManager, scheduler},
+		allowedOrigins: defaultAllowedOrigins(cfg.AllowedOrigins),
+	}
+	router.Handle("/config/cors", s.cors)
+
+	// Add metrics endpoint if enabled
+	if !cfg.DisableMetrics {
+		metricsHandler := metrics.NewAggregatedMetricsHandler(
+			log.WithField("component", "metrics"),
+			scheduler,
+		)
+		router.Handle("/metrics", metricsHandler)
+		log.Info("Metrics endpoint enabled at /metrics")
+	} else {
+		log.Info("Metrics endpoint disabled")
+	}
+
+	// Require API keys, if configured.
+	var handler http.Handler = router
+	if cfg.APIKeys != nil {
+		handler = middleware.AuthMiddleware(cfg.APIKeys, scheduler.InferenceRoutes(), router)
+		log.Infof("API key authentication enabled with %d key(s)", cfg.APIKeys.Len())
+	}
+
+	s.httpServer = &http.Server{Handler: handler}
+	return s, nil
+}
+
+// Serve serves requests on the listener and runs the scheduler until the
+// context is cancelled or serving fails. Once the context is cancelled, new
+// inference requests are rejected while in-flight ones complete, for up to the
+// drain timeout. By the time it returns, all runners have been unloaded.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.hooks.OnStoreReady != nil {
+		storePath, err := s.modelManager.StorePath()
+		if err != nil {
+			return fmt.Errorf("model store unavailable: %w", err)
+		}
+		s.hooks.OnStoreReady(storePath)
+	}
+
+	// The scheduler outlives ctx while draining, so that in-flight requests
+	// complete.
+	schedulerCtx, cancelScheduler := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelScheduler()
+	s.reloadLock.Lock()
+	s.runContext = schedulerCtx
+	s.reloadLock.Unlock()
+	schedulerErrors := make(chan error, 1)
+	go func() {
+		schedulerErrors <- s.scheduler.Run(schedulerCtx)
+	}()
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- s.httpServer.Serve(ln)
+	}()
+
+	var serveErr error
+	select {
+	case err := <-serverErrors:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr = fmt.Errorf("server error: %w", err)
+		}
+	case <-ctx.Done():
+		s.log.Infoln("Shutdown signal received")
+		if s.hooks.OnBeforeShutdown != nil {
+			s.hooks.OnBeforeShutdown()
+		}
+		if s.drainTimeout > 0 {
+			s.log.Infof("Draining for up to %s", s.drainTimeout)
+			drainCtx, cancelDrain := context.WithTimeout(context.Background(), s.drainTimeout)
+			status := s.scheduler.Drain(drainCtx)
+			cancelDrain()
+			if status.InFlight > 0 {
+				s.log.Warnf("Cutting off %d in-flight request(s)", status.InFlight)
+			}
+		}
+		s.log.Infoln("Shutting down the server")
+		if err := s.httpServer.Close(); err != nil {
+			s.log.Errorf("Server shutdown error: %v", err)
+		}
+	}
+
+	s.log.Infoln("Waiting for the scheduler to stop")
+	cancelScheduler()
+	if err := <-schedulerErrors; err != nil && !errors.Is(err, context.Canceled) {
+		return errors.Join(serveErr, fmt.Errorf("scheduler error: %w", err))
+	}
+	return serveErr
+}
+
+// V1AliasHandler provides an alias from /v1/ to /engines/v1/ paths
+type V1AliasHandler struct {
+	scheduler http.Handler
+}
+
+func (h *V1AliasHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Modify the URL path to prepend /engines/ before /v1/
+	originalPath := r.URL.Path
+	newPath := inference.InferencePrefix + originalPath // originalPath is like "/v1/models", so result is "/engines/v1/models"
+
+	// Create a clone of the request with the modified path
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = newPath
+
+	// Pass the modified request to the scheduler
+	h.scheduler.ServeHTTP(w, r2)
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/model-runner/pkg/inference/backends/llamacpp"
+	"github.com/docker/model-runner/pkg/inference/backends/mock"
+	"github.com/docker/model-runner/pkg/inference/scheduling"
+	"github.com/sirupsen/logrus"
+)
+
+func TestServerHooks(t *testing.T) {
+	// Avoid downloading llama.cpp during the test.
+	llamacpp.ShouldUpdateServerLock.Lock()
+	shouldUpdate := llamacpp.ShouldUpdateServer
+	llamacpp.ShouldUpdateServer = false
+	llamacpp.ShouldUpdateServerLock.Unlock()
+	defer func() {
+		llamacpp.ShouldUpdateServerLock.Lock()
+		llamacpp.ShouldUpdateServer = shouldUpdate
+		llamacpp.ShouldUpdateServerLock.Unlock()
+	}()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	modelPath := t.TempDir()
+
+	var lock sync.Mutex
+	var storePath string
+	var beforeShutdown bool
+	mockInstalled := make(chan error, 1)
+	srv, err := New(Config{
+		Log:                   log,
+		ModelPath:             modelPath,
+		LlamaServerPath:       t.TempDir(),
+		LlamaServerUpdatePath: t.TempDir(),
+		MockBackend:           true,
+		InjectionPolicy:       scheduling.DefaultInjectionPolicy,
+		DisableMetrics:        true,
+	}, Hooks{
+		OnStoreReady: func(path string) {
+			lock.Lock()
+			defer lock.Unlock()
+			storePath = path
+		},
+		OnBackendInstalled: func(backend string, err error) {
+			if backend == mock.Name {
+				mockInstalled <- err
+			}
+		},
+		OnBeforeShutdown: func() {
+			lock.Lock()
+			defer lock.Unlock()
+			beforeShutdown = true
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- srv.Serve(ctx, ln)
+	}()
+
+	select {
+	case err := <-mockInstalled:
+		if err != nil {
+			t.Errorf("Expected mock backend to install, got %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Timed out waiting for backend installation")
+	}
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/models")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Legacy text completions are routed through the /v1 alias and recorded.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/v1/completions", "application/json",
+		strings.NewReader(`{"model": "mock-model", "prompt": "def main():", "stream": true}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/requests?model=mock-model")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	records, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(records), `\"object\":\"text_completion\"`) {
+		t.Errorf("Expected a recorded text completion, got %s", records)
+	}
+
+	// Tools are passed through to the backend, and streamed tool call deltas
+	// are forwarded as-is and recorded as complete tool calls.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model": "mock-model", "stream": true, "messages": [{"role": "user", "content": "Weather?"}], `+
+			`"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}], "tool_choice": "auto"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	stream, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(stream), `"tool_calls":[{"function":{"arguments":"","name":"get_weather"}`) {
+		t.Errorf("Expected streamed tool call deltas with status 200, got %d: %s", resp.StatusCode, stream)
+	}
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/requests?model=mock-model")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	records, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(records), `\"arguments\":\"{\\\"text\\\":`) || !strings.Contains(string(records), `\"finish_reason\":\"tool_calls\"`) {
+		t.Errorf("Expected a recorded tool call, got %s", records)
+	}
+
+	// Transcriptions are multipart uploads, recorded without the audio.
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("model", "mock-model")
+	writer.WriteField("response_format", "text")
+	file, _ := writer.CreateFormFile("file", "speech.wav")
+	file.Write([]byte("RIFF audio"))
+	writer.Close()
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/audio/transcriptions", writer.FormDataContentType(), &form)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	transcript, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(transcript) == 0 {
+		t.Errorf("Expected a transcript with status 200, got %d: %s", resp.StatusCode, transcript)
+	}
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/requests?model=mock-model")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	records, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(records), `\"filename\":\"speech.wav\"`) || strings.Contains(string(records), "RIFF audio") {
+		t.Errorf("Expected a recorded transcription without audio, got %s", records)
+	}
+
+	// Synthesized speech is recorded as its format and size.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/audio/speech", "application/json",
+		strings.NewReader(`{"model": "mock-model", "input": "Hello there", "response_format": "wav"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	speech, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "audio/wav" || !bytes.HasPrefix(speech, []byte("RIFF")) {
+		t.Errorf("Expected a WAV file with status 200, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/requests?model=mock-model")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	records, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(records), fmt.Sprintf(`\"size\":%d`, len(speech))) || strings.Contains(string(records), "RIFF") {
+		t.Errorf("Expected a recorded speech response without audio, got %s", records)
+	}
+
+	// Generated images are recorded with their data truncated, and sizes the
+	// backend wasn't sized for are rejected.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/images/generations", "application/json",
+		strings.NewReader(`{"model": "mock-model", "prompt": "A whale", "size": "512x512"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for an image generation, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/requests?model=mock-model")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	records, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(records), "b64_json") || !strings.Contains(string(records), "[truncated") {
+		t.Errorf("Expected a recorded image generation with truncated images, got %s", records)
+	}
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/images/generations", "application/json",
+		strings.NewReader(`{"model": "mock-model", "prompt": "A whale", "size": "2048x2048"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an oversized image, got %d", resp.StatusCode)
+	}
+
+	// Classification requests are routed to the classify endpoint.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/v1/classify", "application/json",
+		strings.NewReader(`{"model": "mock-model", "input": ["Hello there"]}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	classification, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(classification), `"label"`) {
+		t.Errorf("Expected a classification with status 200, got %d: %s", resp.StatusCode, classification)
+	}
+
+	// The output of the runner serving the model is kept.
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines/mock-model/logs")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	logs, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(logs), "Serving canned") {
+		t.Errorf("Expected the mock runner's output with status 200, got %d: %s", resp.StatusCode, logs)
+	}
+
+	// Models can be benchmarked, with the mock backend's usage reports.
+	resp, err = http.Post("http://"+ln.Addr().String()+"/engines/mock-model/benchmark", "application/json",
+		strings.NewReader(`{"prompt_tokens":64,"generation_tokens":16,"repetitions":2}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	benchmark, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(benchmark), `"name":"pp64"`) || !strings.Contains(string(benchmark), `"name":"tg16"`) {
+		t.Errorf("Expected pp64 and tg16 benchmark results with status 200, got %d: %s", resp.StatusCode, benchmark)
+	}
+
+	// Backend capabilities are listed at the inference prefix itself.
+	resp, err = http.Get("http://" + ln.Addr().String() + "/engines")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	engines, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(engines), `"name":"mock"`) {
+		t.Errorf("Expected the mock backend to be listed with status 200, got %d: %s", resp.StatusCode, engines)
+	}
+
+	cancel()
+	select {
+	case err := <-serveErrors:
+		if err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Timed out waiting for shutdown")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if storePath != modelPath {
+		t.Errorf("Expected OnStoreReady with %s, got %q", modelPath, storePath)
+	}
+	if !beforeShutdown {
+		t.Error("Expected OnBeforeShutdown to be called")
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate is a certificate issued for tests.
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// issueTestCertificate issues a certificate, signed by parent or else
+// self-signed, and writes it and its key to PEM files.
+func issueTestCertificate(t *testing.T, name string, isCA bool, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{cert: cert, key: key, certFile: certFile, keyFile: keyFile}
+}
+
+func TestTLSConfig(t *testing.T) {
+	ca := issueTestCertificate(t, "ca", true, nil)
+	serverCert := issueTestCertificate(t, "server", false, ca)
+	clientCert := issueTestCertificate(t, "client", false, ca)
+	otherClientCert := issueTestCertificate(t, "other", false, nil)
+
+	if _, err := TLSConfig(serverCert.certFile, "", ""); err == nil {
+		t.Error("Expected an error without a key")
+	}
+	if _, err := TLSConfig(serverCert.certFile, serverCert.keyFile, serverCert.keyFile); err == nil {
+		t.Error("Expected an error for a client CA without certificates")
+	}
+
+	config, err := TLSConfig(serverCert.certFile, serverCert.keyFile, ca.certFile)
+	if err != nil {
+		t.Fatalf("TLSConfig() error = %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	httpServer := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go httpServer.Serve(tls.NewListener(ln, config))
+	defer httpServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(client *testCertificate) error {
+		clientConfig := &tls.Config{RootCAs: roots}
+		if client != nil {
+			clientConfig.Certificates = []tls.Certificate{{
+				Certificate: [][]byte{client.cert.Raw},
+				PrivateKey:  client.key,
+			}}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		resp, err := httpClient.Get("https://" + ln.Addr().String() + "/")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err := get(clientCert); err != nil {
+		t.Errorf("Expected a client certificate signed by the CA to be accepted, got %v", err)
+	}
+	if err := get(nil); err == nil {
+		t.Error("Expected a client without a certificate to be rejected")
+	}
+	if err := get(otherClientCert); err == nil {
+		t.Error("Expected a client certificate not signed by the CA to be rejected")
+	}
+}
@@ -0,0 +1,18 @@
+// Package tracing holds helpers for the OpenTelemetry spans of the model
+// runner. Spans are exported once a tracer provider is installed with
+// otlp.Setup.
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// End ends a span, recording err as its status if it isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}